@@ -0,0 +1,285 @@
+// Package agent runs the attester side of remote attestation with a verifier
+// service (see the verifier package): it gets a challenge from the verifier,
+// attests to the TPM's state with the challenge's nonce, and returns the
+// verifier's signed attestation result, which can be presented to relying
+// parties. After an affirming attestation, it can also get the secrets which
+// the verifier releases to the TPM (see verifier.Secret), decrypting them with
+// the TPM's Endorsement Key (EK).
+//
+// The Agent calls the verifier through a Transport. verifier.Client is a
+// Transport over HTTP or HTTPS; other transports (such as a queue between the
+// attester and the verifier) can be used by implementing the interface.
+package agent
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	tpmpb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/verifier"
+	"github.com/ThalesIgnite/go-tpm-tools/verifier"
+)
+
+// Transport sends the requests of the verifier protocol.
+type Transport interface {
+	Challenge() (*pb.ChallengeResponse, error)
+	SubmitEvidence(req *pb.SubmitEvidenceRequest) (*pb.SubmitEvidenceResponse, error)
+	GetResult(challengeID string, format pb.ResultFormat) ([]byte, error)
+	GetCredential(challengeID string) (*pb.GetCredentialResponse, error)
+	ReleaseSecret(challengeID, secretID string, credential []byte) (*tpmpb.ImportBlob, error)
+}
+
+var _ Transport = (*verifier.Client)(nil)
+
+// The default retry settings of a Config.
+const (
+	DefaultMaxAttempts = 3
+	DefaultRetryDelay  = time.Second
+)
+
+// The bounds of the challenge nonce size. Shorter nonces do not guarantee
+// freshness, and the nonces of EATs are at most 64 bytes.
+const (
+	minNonceSize = 8
+	maxNonceSize = 64
+)
+
+// Config configures an Agent.
+type Config struct {
+	// NewAK returns the Attestation Key (AK) used to attest. It is called for
+	// each attestation, and the key is closed afterwards. It is also called to
+	// activate the credentials of the verifier before releasing secrets, so it
+	// must return the same AK each time (as the AKs of the client package
+	// do). If nil, client.AttestationKeyECC is used.
+	NewAK func(rw io.ReadWriter) (*client.Key, error)
+	// Platform is the platform of the TPM, giving its EK certificate. If nil,
+	// client.BareMetal is used.
+	Platform client.Platform
+	// AttestConfig is the configuration of the attestations (see
+	// client.Key.AttestWithConfig).
+	AttestConfig client.AttestConfig
+	// EAT, if true, sends the evidence as an Entity Attestation Token (see
+	// client.COSESigner.SignEAT), rather than as an Attestation.
+	EAT bool
+	// ResultFormat is the format of the attestation result.
+	ResultFormat pb.ResultFormat
+	// MaxAttempts is the number of times the exchange with the verifier is
+	// attempted, each with a new challenge. If zero, DefaultMaxAttempts is
+	// used.
+	MaxAttempts int
+	// RetryDelay is the time waited before the first retry, which is doubled
+	// before each following retry. If zero, DefaultRetryDelay is used.
+	RetryDelay time.Duration
+}
+
+// Agent attests to the state of a TPM with a verifier.
+type Agent struct {
+	rw        io.ReadWriter
+	transport Transport
+	config    Config
+}
+
+// New returns an Agent attesting to the state of the TPM rw with the verifier
+// called by transport.
+func New(rw io.ReadWriter, transport Transport, config Config) *Agent {
+	if config.NewAK == nil {
+		config.NewAK = client.AttestationKeyECC
+	}
+	if config.Platform == nil {
+		config.Platform = client.BareMetal
+	}
+	if config.MaxAttempts == 0 {
+		config.MaxAttempts = DefaultMaxAttempts
+	}
+	if config.RetryDelay == 0 {
+		config.RetryDelay = DefaultRetryDelay
+	}
+	return &Agent{rw: rw, transport: transport, config: config}
+}
+
+// Result is the outcome of an attestation with the verifier.
+type Result struct {
+	ChallengeID string
+	// The status of the appraisal ("affirming", "warning", "contraindicated"
+	// or "none"), and the reason the evidence was not verified, if it was not.
+	Status string
+	Error  string
+	// The attestation result signed by the verifier, in the
+	// Config.ResultFormat (see server.AttestationResult.SignCWT and SignJWT).
+	Token []byte
+}
+
+// A permanent error, which is not retried.
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Attest runs the exchange with the verifier: it gets a challenge, attests
+// with the challenge's nonce, submits the evidence, and gets the result.
+//
+// The exchange is retried with a new challenge if the verifier cannot be
+// reached, if it fails, or if the challenge expires before the evidence is
+// submitted. Errors of the TPM, and HTTP client errors of the verifier (other
+// than for unknown or expired challenges) are not retried. The appraisal not
+// being "affirming" is not an error: the Result is then returned with the
+// verifier's reason.
+func (a *Agent) Attest() (*Result, error) {
+	delay := a.config.RetryDelay
+	var err error
+	for attempt := 1; ; attempt++ {
+		var result *Result
+		if result, err = a.attestOnce(); err == nil {
+			return result, nil
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) || !retryable(err) || attempt >= a.config.MaxAttempts {
+			break
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+	return nil, err
+}
+
+// retryable reports whether an error of the Transport can be retried.
+func retryable(err error) bool {
+	var httpErr *verifier.HTTPError
+	if !errors.As(err, &httpErr) {
+		return true
+	}
+	switch httpErr.StatusCode {
+	case http.StatusNotFound, http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests:
+		return true
+	}
+	return httpErr.StatusCode >= http.StatusInternalServerError
+}
+
+func (a *Agent) attestOnce() (*Result, error) {
+	challenge, err := a.transport.Challenge()
+	if err != nil {
+		return nil, fmt.Errorf("getting challenge: %w", err)
+	}
+	nonce := challenge.GetNonce()
+	if len(nonce) < minNonceSize || len(nonce) > maxNonceSize {
+		return nil, &permanentError{fmt.Errorf("verifier nonce has %d bytes, expected between %d and %d", len(nonce), minNonceSize, maxNonceSize)}
+	}
+
+	req, err := a.evidence(nonce)
+	if err != nil {
+		return nil, &permanentError{err}
+	}
+	// Attesting can take a while, so check that the challenge is still fresh.
+	if expires := challenge.GetExpires(); expires != 0 && time.Now().Unix() >= expires {
+		return nil, fmt.Errorf("challenge %q expired before the evidence was created", challenge.GetChallengeId())
+	}
+	req.ChallengeId = challenge.GetChallengeId()
+	resp, err := a.transport.SubmitEvidence(req)
+	if err != nil {
+		return nil, fmt.Errorf("submitting evidence: %w", err)
+	}
+	token, err := a.transport.GetResult(challenge.GetChallengeId(), a.config.ResultFormat)
+	if err != nil {
+		return nil, fmt.Errorf("getting result: %w", err)
+	}
+	return &Result{
+		ChallengeID: challenge.GetChallengeId(),
+		Status:      resp.GetStatus(),
+		Error:       resp.GetError(),
+		Token:       token,
+	}, nil
+}
+
+// Create the evidence for the nonce, with a new AK.
+func (a *Agent) evidence(nonce []byte) (*pb.SubmitEvidenceRequest, error) {
+	ak, err := a.config.NewAK(a.rw)
+	if err != nil {
+		return nil, fmt.Errorf("creating AK: %w", err)
+	}
+	defer ak.Close()
+	attestation, err := ak.AttestWithConfig(nonce, a.config.AttestConfig)
+	if err != nil {
+		return nil, fmt.Errorf("creating attestation: %w", err)
+	}
+	if ekCert, err := a.config.Platform.GetEKCert(a.rw); err == nil {
+		attestation.EkCert = ekCert.Raw
+	}
+	if !a.config.EAT {
+		return &pb.SubmitEvidenceRequest{Evidence: &pb.SubmitEvidenceRequest_Attestation{Attestation: attestation}}, nil
+	}
+	signer, err := ak.COSESigner()
+	if err != nil {
+		return nil, err
+	}
+	token, err := signer.SignEAT(attestation, nonce)
+	if err != nil {
+		return nil, fmt.Errorf("signing EAT: %w", err)
+	}
+	return &pb.SubmitEvidenceRequest{Evidence: &pb.SubmitEvidenceRequest_Eat{Eat: token}}, nil
+}
+
+// ReleaseSecret gets the secret with the identifier from the verifier, for the
+// challenge of an affirming Result of Attest, and decrypts it with the EK of
+// the TPM. The EK is the one certified by the EK certificate sent in the
+// attestation (see Config.Platform), which the verifier encrypts the secret
+// to. First, the AK activates a credential of the verifier, proving that it
+// is in the same TPM as the EK. It is not retried, as the challenge of the
+// Result cannot be renewed.
+func (a *Agent) ReleaseSecret(result *Result, secretID string) ([]byte, error) {
+	ekCert, err := a.config.Platform.GetEKCert(a.rw)
+	if err != nil {
+		return nil, err
+	}
+	var newEK func(io.ReadWriter) (*client.Key, error)
+	switch ekCert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		newEK = client.EndorsementKeyRSA
+	case *ecdsa.PublicKey:
+		newEK = client.EndorsementKeyECC
+	default:
+		return nil, fmt.Errorf("unsupported EK certificate key type %T", ekCert.PublicKey)
+	}
+	ek, err := newEK(a.rw)
+	if err != nil {
+		return nil, fmt.Errorf("creating EK: %w", err)
+	}
+	defer ek.Close()
+
+	credential, err := a.transport.GetCredential(result.ChallengeID)
+	if err != nil {
+		return nil, fmt.Errorf("getting credential: %w", err)
+	}
+	activated, err := a.activateCredential(ek, credential)
+	if err != nil {
+		return nil, err
+	}
+	blob, err := a.transport.ReleaseSecret(result.ChallengeID, secretID, activated)
+	if err != nil {
+		return nil, fmt.Errorf("getting secret %q: %w", secretID, err)
+	}
+	secret, err := ek.Import(blob)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting secret %q: %w", secretID, err)
+	}
+	return secret, nil
+}
+
+// Activate the credential of the verifier with the AK and the EK.
+func (a *Agent) activateCredential(ek *client.Key, credential *pb.GetCredentialResponse) ([]byte, error) {
+	ak, err := a.config.NewAK(a.rw)
+	if err != nil {
+		return nil, fmt.Errorf("creating AK: %w", err)
+	}
+	defer ak.Close()
+	activated, err := ak.ActivateCredential(ek, credential.GetCredBlob(), credential.GetEncSecret())
+	if err != nil {
+		return nil, fmt.Errorf("activating credential: %w", err)
+	}
+	return activated, nil
+}
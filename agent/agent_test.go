@@ -0,0 +1,257 @@
+package agent_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+
+	"github.com/ThalesIgnite/go-tpm-tools/agent"
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/verifier"
+	"github.com/ThalesIgnite/go-tpm-tools/server"
+	"github.com/ThalesIgnite/go-tpm-tools/verifier"
+)
+
+// Serve a verifier with the config, trusting the AK of tpm, returning a Client
+// of it.
+func serveVerifier(t *testing.T, tpm io.ReadWriter, config verifier.Config) *verifier.Client {
+	t.Helper()
+	ak, err := client.AttestationKeyECC(tpm)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer ak.Close()
+	resultKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Opts = server.VerifyOpts{TrustedAKs: []crypto.PublicKey{ak.PublicKey()}}
+	config.Signer = resultKey
+	s, err := verifier.NewServer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(s)
+	t.Cleanup(httpServer.Close)
+	c, err := verifier.NewClient(httpServer.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// flakyTransport fails the first calls to Challenge with err, or returns
+// expired challenges.
+type flakyTransport struct {
+	agent.Transport
+	failures   int
+	err        error
+	expire     bool
+	challenges int
+}
+
+func (f *flakyTransport) Challenge() (*pb.ChallengeResponse, error) {
+	f.challenges++
+	if f.challenges > f.failures {
+		return f.Transport.Challenge()
+	}
+	if f.expire {
+		challenge, err := f.Transport.Challenge()
+		if err == nil {
+			challenge.Expires = time.Now().Add(-time.Second).Unix()
+		}
+		return challenge, err
+	}
+	return nil, f.err
+}
+
+func TestAgent(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	transport := serveVerifier(t, rwc, verifier.Config{})
+
+	for _, eat := range []bool{false, true} {
+		result, err := agent.New(rwc, transport, agent.Config{EAT: eat}).Attest()
+		if err != nil {
+			t.Fatalf("failed to attest (EAT: %v): %v", eat, err)
+		}
+		if result.Status != "affirming" || result.Error != "" || len(result.Token) == 0 {
+			t.Errorf("unexpected result (EAT: %v): %+v", eat, result)
+		}
+	}
+
+	// An AK which is not trusted by the verifier.
+	untrusted := agent.Config{NewAK: client.AttestationKeyRSA}
+	result, err := agent.New(rwc, transport, untrusted).Attest()
+	if err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+	if result.Status != "contraindicated" || result.Error == "" {
+		t.Errorf("unexpected result with an untrusted AK: %+v", result)
+	}
+}
+
+func TestAgentRetry(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	transport := serveVerifier(t, rwc, verifier.Config{})
+
+	tests := []struct {
+		name       string
+		transport  *flakyTransport
+		succeeds   bool
+		challenges int
+	}{
+		{"NetworkError", &flakyTransport{failures: 2, err: errors.New("connection refused")}, true, 3},
+		{"ServerError", &flakyTransport{failures: 1, err: &verifier.HTTPError{StatusCode: http.StatusServiceUnavailable}}, true, 2},
+		{"ExpiredChallenge", &flakyTransport{failures: 1, expire: true}, true, 2},
+		{"TooManyFailures", &flakyTransport{failures: 3, err: errors.New("connection refused")}, false, 3},
+		{"ClientError", &flakyTransport{failures: 1, err: &verifier.HTTPError{StatusCode: http.StatusBadRequest}}, false, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.transport.Transport = transport
+			config := agent.Config{MaxAttempts: 3, RetryDelay: time.Millisecond}
+			_, err := agent.New(rwc, test.transport, config).Attest()
+			if test.succeeds && err != nil {
+				t.Errorf("failed to attest: %v", err)
+			}
+			if !test.succeeds && err == nil {
+				t.Error("expected attesting to fail")
+			}
+			if test.transport.challenges != test.challenges {
+				t.Errorf("got %d challenges, want %d", test.transport.challenges, test.challenges)
+			}
+		})
+	}
+}
+
+// shortNonceTransport issues challenges with too short nonces.
+type shortNonceTransport struct {
+	agent.Transport
+}
+
+func (shortNonceTransport) Challenge() (*pb.ChallengeResponse, error) {
+	return &pb.ChallengeResponse{ChallengeId: "id", Nonce: []byte("short")}, nil
+}
+
+func TestAgentShortNonce(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	if _, err := agent.New(rwc, shortNonceTransport{}, agent.Config{}).Attest(); err == nil {
+		t.Error("expected attesting with a short nonce to fail")
+	}
+}
+
+// Write an EK certificate for the EK of tpm, issued by a new test CA, to its
+// NV index, returning the CA.
+func writeEKCert(t *testing.T, tpm io.ReadWriter, newEK func(io.ReadWriter) (*client.Key, error), index uint32) *x509.Certificate {
+	t.Helper()
+	ek, err := newEK(tpm)
+	if err != nil {
+		t.Fatalf("failed to generate EK: %v", err)
+	}
+	defer ek.Close()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test TPM Manufacturer CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ekTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageKeyEncipherment,
+	}
+	ekDER, err := x509.CreateCertificate(rand.Reader, ekTemplate, ca, ek.PublicKey(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx := tpmutil.Handle(index)
+	attrs := tpm2.AttrOwnerRead | tpm2.AttrOwnerWrite | tpm2.AttrNoDA
+	if err = client.DefineNV(tpm, idx, uint16(len(ekDER)), attrs, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.UndefineNV(tpm, idx) })
+	if err = client.NVWrite(tpm, idx, &client.NVAuth{Handle: tpm2.HandleOwner}, ekDER); err != nil {
+		t.Fatal(err)
+	}
+	return ca
+}
+
+func TestAgentReleaseSecret(t *testing.T) {
+	tests := []struct {
+		name  string
+		newEK func(io.ReadWriter) (*client.Key, error)
+		index uint32
+	}{
+		{"RSA", client.EndorsementKeyRSA, client.EKCertNVIndexRSA},
+		{"ECC", client.EndorsementKeyECC, client.EKCertNVIndexECC},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rwc := test.GetTPM(t)
+			defer client.CheckedClose(t, rwc)
+			ca := writeEKCert(t, rwc, tc.newEK, tc.index)
+			passphrase := []byte("luks passphrase")
+			transport := serveVerifier(t, rwc, verifier.Config{
+				Secrets: map[string]verifier.Secret{"disk": {Value: passphrase}},
+				EKOpts:  &server.VerifyEKOpts{Roots: []*x509.Certificate{ca}},
+			})
+
+			a := agent.New(rwc, transport, agent.Config{EAT: true})
+			result, err := a.Attest()
+			if err != nil {
+				t.Fatalf("failed to attest: %v", err)
+			}
+			secret, err := a.ReleaseSecret(result, "disk")
+			if err != nil {
+				t.Fatalf("failed to release secret: %v", err)
+			}
+			if !bytes.Equal(secret, passphrase) {
+				t.Errorf("got secret %q, want %q", secret, passphrase)
+			}
+
+			// The secret is not released to an AK which is not trusted.
+			untrusted := agent.New(rwc, transport, agent.Config{NewAK: client.AttestationKeyRSA})
+			if result, err = untrusted.Attest(); err != nil {
+				t.Fatalf("failed to attest: %v", err)
+			}
+			if _, err = untrusted.ReleaseSecret(result, "disk"); err == nil {
+				t.Error("expected releasing a secret after a contraindicated appraisal to fail")
+			}
+		})
+	}
+}
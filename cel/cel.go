@@ -0,0 +1,321 @@
+// Package cel implements the TCG Canonical Event Log (CEL) format, in its TLV
+// encoding, for application-level measurements.
+//
+// Applications record measurements with (*CEL).AppendEvent, which extends the
+// digests of the event into a PCR and appends a record to the log. Verifiers
+// parse the log with Parse, and check it with (*CEL).Replay against PCR values
+// they trust (e.g. from a verified quote).
+//
+// The launch of a container can be measured with (*CEL).AppendContainerLaunch,
+// and is then verified by server.VerifyAttestation.
+package cel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+// The number of PCRs which records can be appended to. This package does not
+// depend on the client package, so that verifiers only need the TPM-less parts
+// of this module.
+const numPCRs = 24
+
+// The types of the top-level TLVs of a CEL record. The other types are those
+// of the record's content, which are defined by the application measuring the
+// events.
+const (
+	RecnumType  uint8 = 0
+	PCRType     uint8 = 1
+	NVIndexType uint8 = 2
+	DigestsType uint8 = 3
+)
+
+// Sizes of the TLV fields, and of the recnum and pcr values.
+const (
+	tlvTypeSize   = 1
+	tlvLengthSize = 4
+	recnumSize    = 8
+	pcrSize       = 1
+)
+
+// Sanity limit on the size of a TLV value.
+const maxValueSize = 1 << 24
+
+// TLV is a Type-Length-Value field of a CEL record. The type is one byte and
+// the length is a 4 byte big-endian integer.
+type TLV struct {
+	Type  uint8
+	Value []byte
+}
+
+// MarshalBinary encodes the TLV.
+func (t TLV) MarshalBinary() ([]byte, error) {
+	if len(t.Value) > maxValueSize {
+		return nil, fmt.Errorf("TLV of type %d has a value of size %d, the maximum is %d", t.Type, len(t.Value), maxValueSize)
+	}
+	data := make([]byte, tlvTypeSize+tlvLengthSize, tlvTypeSize+tlvLengthSize+len(t.Value))
+	data[0] = t.Type
+	binary.BigEndian.PutUint32(data[tlvTypeSize:], uint32(len(t.Value)))
+	return append(data, t.Value...), nil
+}
+
+func readTLV(buf *bytes.Reader) (TLV, error) {
+	var header [tlvTypeSize + tlvLengthSize]byte
+	if _, err := io.ReadFull(buf, header[:]); err != nil {
+		return TLV{}, fmt.Errorf("reading TLV header: %w", err)
+	}
+	length := binary.BigEndian.Uint32(header[tlvTypeSize:])
+	if length > maxValueSize || int64(length) > int64(buf.Len()) {
+		return TLV{}, fmt.Errorf("TLV of type %d has length %d, but only %d bytes remain", header[0], length, buf.Len())
+	}
+	value := make([]byte, length)
+	if _, err := io.ReadFull(buf, value); err != nil {
+		return TLV{}, err
+	}
+	return TLV{Type: header[0], Value: value}, nil
+}
+
+// Record is a single CEL event.
+type Record struct {
+	// The (zero-based) record number, which increases by one for each record
+	// in the log.
+	RecNum uint64
+	// The PCR the event was extended into.
+	PCR uint8
+	// The digests extended into the PCR banks, by bank.
+	Digests map[tpm2.Algorithm][]byte
+	// The content of the event. Its type is defined by the application.
+	Content TLV
+}
+
+// contentDigest returns the digest of the encoded content, which is what (in
+// the bank of the hash) is extended into the PCR.
+func (r *Record) contentDigest(hash tpm2.Algorithm) ([]byte, error) {
+	cryptoHash, err := hash.Hash()
+	if err != nil {
+		return nil, err
+	}
+	content, err := r.Content.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	hasher := cryptoHash.New()
+	hasher.Write(content)
+	return hasher.Sum(nil), nil
+}
+
+func (r *Record) marshal(w *bytes.Buffer) error {
+	recnum := make([]byte, recnumSize)
+	binary.BigEndian.PutUint64(recnum, r.RecNum)
+
+	// Use a deterministic order for the digests.
+	algs := make([]tpm2.Algorithm, 0, len(r.Digests))
+	for alg := range r.Digests {
+		algs = append(algs, alg)
+	}
+	sort.Slice(algs, func(i, j int) bool { return algs[i] < algs[j] })
+	var digests []byte
+	for _, alg := range algs {
+		if alg > 0xff {
+			return fmt.Errorf("record %d has a digest for algorithm %v, which does not fit a TLV type", r.RecNum, alg)
+		}
+		digest, err := TLV{uint8(alg), r.Digests[alg]}.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		digests = append(digests, digest...)
+	}
+
+	for _, field := range []TLV{
+		{RecnumType, recnum},
+		{PCRType, []byte{r.PCR}},
+		{DigestsType, digests},
+		r.Content,
+	} {
+		data, err := field.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		w.Write(data)
+	}
+	return nil
+}
+
+func readRecord(buf *bytes.Reader) (Record, error) {
+	var record Record
+	recnum, err := readTLV(buf)
+	if err != nil {
+		return record, err
+	}
+	if recnum.Type != RecnumType || len(recnum.Value) != recnumSize {
+		return record, fmt.Errorf("expected a recnum TLV of size %d, got type %d and size %d", recnumSize, recnum.Type, len(recnum.Value))
+	}
+	record.RecNum = binary.BigEndian.Uint64(recnum.Value)
+
+	index, err := readTLV(buf)
+	if err != nil {
+		return record, err
+	}
+	switch {
+	case index.Type == NVIndexType:
+		return record, errors.New("NV index records are not supported")
+	case index.Type != PCRType || len(index.Value) != pcrSize:
+		return record, fmt.Errorf("expected a pcr TLV of size %d, got type %d and size %d", pcrSize, index.Type, len(index.Value))
+	}
+	record.PCR = index.Value[0]
+
+	digests, err := readTLV(buf)
+	if err != nil {
+		return record, err
+	}
+	if digests.Type != DigestsType {
+		return record, fmt.Errorf("expected a digests TLV, got type %d", digests.Type)
+	}
+	record.Digests = make(map[tpm2.Algorithm][]byte)
+	digestsBuf := bytes.NewReader(digests.Value)
+	for digestsBuf.Len() > 0 {
+		digest, err := readTLV(digestsBuf)
+		if err != nil {
+			return record, fmt.Errorf("reading digest: %w", err)
+		}
+		alg := tpm2.Algorithm(digest.Type)
+		if _, ok := record.Digests[alg]; ok {
+			return record, fmt.Errorf("duplicate digest for algorithm %v", alg)
+		}
+		record.Digests[alg] = digest.Value
+	}
+
+	if record.Content, err = readTLV(buf); err != nil {
+		return record, fmt.Errorf("reading content: %w", err)
+	}
+	return record, nil
+}
+
+// CEL is a Canonical Event Log.
+type CEL struct {
+	Records []Record
+}
+
+// AppendEvent measures the content into the PCR of each of the given banks,
+// and appends the corresponding record to the log. The digest extended into
+// each bank is the hash of the encoded content TLV. The PCR should only be
+// extended through this log (e.g. the application or debug PCR), as Replay
+// starts from a PCR value of all zeros.
+func (c *CEL) AppendEvent(rw io.ReadWriter, pcr int, banks []tpm2.Algorithm, content TLV) error {
+	if pcr < 0 || pcr >= numPCRs {
+		return fmt.Errorf("PCR %d out of range", pcr)
+	}
+	if len(banks) == 0 {
+		return errors.New("no PCR banks to extend")
+	}
+	record := Record{
+		RecNum:  uint64(len(c.Records)),
+		PCR:     uint8(pcr),
+		Digests: make(map[tpm2.Algorithm][]byte),
+		Content: content,
+	}
+	if _, err := content.MarshalBinary(); err != nil {
+		return err
+	}
+	for _, bank := range banks {
+		digest, err := record.contentDigest(bank)
+		if err != nil {
+			return fmt.Errorf("not a valid hash type: %v", bank)
+		}
+		record.Digests[bank] = digest
+	}
+	for _, bank := range banks {
+		if err := tpm2.PCRExtend(rw, tpmutil.Handle(pcr), bank, record.Digests[bank], ""); err != nil {
+			return fmt.Errorf("extending PCR %d: %w", pcr, err)
+		}
+	}
+	c.Records = append(c.Records, record)
+	return nil
+}
+
+// MarshalBinary encodes the log as the concatenation of its TLV records.
+func (c *CEL) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	for i := range c.Records {
+		if err := c.Records[i].marshal(&buf); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Parse decodes a TLV encoded Canonical Event Log. The record numbers must
+// start at zero and increase by one for each record.
+func Parse(data []byte) (*CEL, error) {
+	buf := bytes.NewReader(data)
+	c := &CEL{}
+	for buf.Len() > 0 {
+		record, err := readRecord(buf)
+		if err != nil {
+			return nil, fmt.Errorf("CEL record %d: %w", len(c.Records), err)
+		}
+		if record.RecNum != uint64(len(c.Records)) {
+			return nil, fmt.Errorf("CEL record %d has record number %d", len(c.Records), record.RecNum)
+		}
+		c.Records = append(c.Records, record)
+	}
+	return c, nil
+}
+
+// Replay checks that the digests of the records, in the bank of the given PCR
+// values, match their content, and replays them against the PCR values. All
+// PCRs measured by the records must be present in pcrs.
+//
+// It is the caller's responsibility to ensure that the passed PCR values can
+// be trusted, e.g. by verifying them with a quote.
+func (c *CEL) Replay(pcrs *pb.PCRs) error {
+	bank := tpm2.Algorithm(pcrs.GetHash())
+	hash, err := bank.Hash()
+	if err != nil {
+		return fmt.Errorf("received bad PCR proto: %w", err)
+	}
+
+	replayed := make(map[uint32][]byte)
+	for _, record := range c.Records {
+		digest, ok := record.Digests[bank]
+		if !ok {
+			return fmt.Errorf("CEL record %d has no %v digest", record.RecNum, bank)
+		}
+		contentDigest, err := record.contentDigest(bank)
+		if err != nil {
+			return fmt.Errorf("CEL record %d: %w", record.RecNum, err)
+		}
+		if !bytes.Equal(digest, contentDigest) {
+			return fmt.Errorf("CEL record %d: %v digest does not match the content", record.RecNum, bank)
+		}
+
+		pcr, ok := replayed[uint32(record.PCR)]
+		if !ok {
+			pcr = make([]byte, hash.Size())
+		}
+		hasher := hash.New()
+		hasher.Write(pcr)
+		hasher.Write(digest)
+		replayed[uint32(record.PCR)] = hasher.Sum(nil)
+	}
+
+	for idx, value := range replayed {
+		expected, ok := pcrs.GetPcrs()[idx]
+		if !ok {
+			return fmt.Errorf("CEL records measured into PCR%d, which was not provided", idx)
+		}
+		if !bytes.Equal(value, expected) {
+			return fmt.Errorf("CEL replay for PCR%d does not match the provided value", idx)
+		}
+	}
+	return nil
+}
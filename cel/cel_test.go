@@ -0,0 +1,122 @@
+package cel
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+// An application-defined content type.
+const testContentType uint8 = 0x80
+
+func TestAppendAndReplay(t *testing.T) {
+	// Replaying starts from PCRs of all zeros.
+	test.SkipOnRealTPM(t)
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	banks := []tpm2.Algorithm{tpm2.AlgSHA1, tpm2.AlgSHA256}
+	var log CEL
+	for _, event := range []struct {
+		pcr     int
+		content string
+	}{
+		{test.DebugPCR, "first event"},
+		{test.ApplicationPCR, "second event"},
+		{test.DebugPCR, ""},
+	} {
+		content := TLV{testContentType, []byte(event.content)}
+		if err := log.AppendEvent(rwc, event.pcr, banks, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	encoded, err := log.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Parse(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Records) != 3 {
+		t.Fatalf("got %d records, want 3", len(decoded.Records))
+	}
+	if string(decoded.Records[1].Content.Value) != "second event" || decoded.Records[1].PCR != uint8(test.ApplicationPCR) {
+		t.Errorf("got record %v", decoded.Records[1])
+	}
+
+	for _, bank := range banks {
+		sel := tpm2.PCRSelection{Hash: bank, PCRs: []int{test.DebugPCR, test.ApplicationPCR}}
+		pcrs, err := client.ReadPCRs(rwc, sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = decoded.Replay(pcrs); err != nil {
+			t.Errorf("replaying the %v bank failed: %v", bank, err)
+		}
+		// The replay fails if a PCR is missing.
+		missing := &pb.PCRs{Hash: pcrs.GetHash(), Pcrs: map[uint32][]byte{uint32(test.DebugPCR): pcrs.GetPcrs()[uint32(test.DebugPCR)]}}
+		if err = decoded.Replay(missing); err == nil {
+			t.Errorf("replaying the %v bank without PCR %d should fail", bank, test.ApplicationPCR)
+		}
+	}
+
+	pcrs, err := client.ReadPCRs(rwc, tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{test.DebugPCR, test.ApplicationPCR}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := &CEL{Records: append([]Record(nil), decoded.Records...)}
+	tampered.Records[0].Content = TLV{testContentType, []byte("other event")}
+	if err = tampered.Replay(pcrs); err == nil {
+		t.Error("replaying a record with modified content should fail")
+	}
+	dropped := &CEL{Records: decoded.Records[1:]}
+	if err = dropped.Replay(pcrs); err == nil {
+		t.Error("replaying a log with a missing record should fail")
+	}
+	if err = log.AppendEvent(rwc, numPCRs, banks, TLV{}); err == nil {
+		t.Error("appending an event to an invalid PCR should fail")
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	log := CEL{Records: []Record{{
+		RecNum:  0,
+		PCR:     uint8(test.ApplicationPCR),
+		Digests: map[tpm2.Algorithm][]byte{tpm2.AlgSHA256: make([]byte, 32)},
+		Content: TLV{testContentType, []byte("event")},
+	}}}
+	valid, err := log.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Parse(valid); err != nil {
+		t.Fatal(err)
+	}
+
+	log.Records[0].RecNum = 1
+	badRecnum, err := log.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nvIndex := append([]byte(nil), valid...)
+	nvIndex[tlvTypeSize+tlvLengthSize+recnumSize] = NVIndexType
+	for name, data := range map[string][]byte{
+		"Truncated":     valid[:len(valid)-1],
+		"TrailingBytes": append(append([]byte(nil), valid...), 0),
+		"BadRecnum":     badRecnum,
+		"NVIndex":       nvIndex,
+		"HugeLength":    {RecnumType, 0xff, 0xff, 0xff, 0xff},
+		"EmptyRecnum":   bytes.Repeat([]byte{0}, 5),
+	} {
+		if _, err := Parse(data); err == nil {
+			t.Errorf("%s: expected parsing the CEL to fail", name)
+		}
+	}
+}
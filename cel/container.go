@@ -0,0 +1,133 @@
+package cel
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+)
+
+// ContainerContentType is the content type of the CEL records measuring the
+// launch of a container.
+const ContainerContentType uint8 = 0x50
+
+// ContainerPCR is the PCR container launch events are measured into.
+const ContainerPCR = 13
+
+// ContainerEventType is the type of a container launch event.
+type ContainerEventType uint8
+
+// The container launch events. LaunchSeparatorType is measured last, just
+// before the container is started.
+const (
+	ImageRefType ContainerEventType = iota
+	ImageDigestType
+	EntrypointType
+	ArgType
+	EnvPolicyHashType
+	LaunchSeparatorType
+)
+
+var containerEventNames = map[ContainerEventType]string{
+	ImageRefType:        "image reference",
+	ImageDigestType:     "image digest",
+	EntrypointType:      "entrypoint",
+	ArgType:             "argument",
+	EnvPolicyHashType:   "environment policy hash",
+	LaunchSeparatorType: "launch separator",
+}
+
+func (t ContainerEventType) String() string {
+	if name, ok := containerEventNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("ContainerEventType(%d)", uint8(t))
+}
+
+// ContainerEvent is the content of a container launch record. It is encoded
+// as a ContainerContentType TLV, whose value is a TLV of the event type.
+type ContainerEvent struct {
+	Type  ContainerEventType
+	Value []byte
+}
+
+// TLV returns the content TLV of the event.
+func (e ContainerEvent) TLV() (TLV, error) {
+	value, err := TLV{uint8(e.Type), e.Value}.MarshalBinary()
+	if err != nil {
+		return TLV{}, err
+	}
+	return TLV{ContainerContentType, value}, nil
+}
+
+// ParseContainerEvent decodes the content TLV of a container launch record.
+func ParseContainerEvent(content TLV) (ContainerEvent, error) {
+	if content.Type != ContainerContentType {
+		return ContainerEvent{}, fmt.Errorf("content of type %d is not a container event", content.Type)
+	}
+	buf := bytes.NewReader(content.Value)
+	event, err := readTLV(buf)
+	if err != nil {
+		return ContainerEvent{}, fmt.Errorf("reading container event: %w", err)
+	}
+	if buf.Len() != 0 {
+		return ContainerEvent{}, fmt.Errorf("container event has %d trailing bytes", buf.Len())
+	}
+	eventType := ContainerEventType(event.Type)
+	if _, ok := containerEventNames[eventType]; !ok {
+		return ContainerEvent{}, fmt.Errorf("unknown container event type %d", event.Type)
+	}
+	return ContainerEvent{eventType, event.Value}, nil
+}
+
+// ContainerLaunch describes the container being launched.
+type ContainerLaunch struct {
+	// The image reference, such as "gcr.io/project/image:tag".
+	ImageRef string
+	// The digest of the image, such as "sha256:<hex digest>".
+	ImageDigest string
+	// The entrypoint and the arguments of the container's command.
+	Entrypoint []string
+	Args       []string
+	// The hash of the policy deciding which environment variables the
+	// container can be launched with. The values of the variables are not
+	// measured, as they could contain secrets.
+	EnvPolicyHash []byte
+}
+
+// AppendContainerLaunch measures the launch of a container into ContainerPCR
+// of the given banks, followed by a launch separator. It should be called once,
+// just before the container is started, as verifiers reject logs containing
+// container events after the separator.
+func (c *CEL) AppendContainerLaunch(rw io.ReadWriter, banks []tpm2.Algorithm, launch ContainerLaunch) error {
+	if launch.ImageRef == "" || launch.ImageDigest == "" {
+		return errors.New("the image reference and digest of the container are required")
+	}
+	events := []ContainerEvent{
+		{ImageRefType, []byte(launch.ImageRef)},
+		{ImageDigestType, []byte(launch.ImageDigest)},
+	}
+	for _, arg := range launch.Entrypoint {
+		events = append(events, ContainerEvent{EntrypointType, []byte(arg)})
+	}
+	for _, arg := range launch.Args {
+		events = append(events, ContainerEvent{ArgType, []byte(arg)})
+	}
+	if len(launch.EnvPolicyHash) > 0 {
+		events = append(events, ContainerEvent{EnvPolicyHashType, launch.EnvPolicyHash})
+	}
+	events = append(events, ContainerEvent{LaunchSeparatorType, nil})
+
+	for _, event := range events {
+		content, err := event.TLV()
+		if err != nil {
+			return fmt.Errorf("encoding %v event: %w", event.Type, err)
+		}
+		if err = c.AppendEvent(rw, ContainerPCR, banks, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package cel
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestContainerEvent(t *testing.T) {
+	event := ContainerEvent{ImageDigestType, []byte("sha256:digest")}
+	content, err := event.TLV()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseContainerEvent(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Type != event.Type || !bytes.Equal(parsed.Value, event.Value) {
+		t.Errorf("got event %v, want %v", parsed, event)
+	}
+
+	for name, content := range map[string]TLV{
+		"OtherContent": {0x80, content.Value},
+		"Truncated":    {ContainerContentType, content.Value[:len(content.Value)-1]},
+		"Trailing":     {ContainerContentType, append(append([]byte(nil), content.Value...), 0)},
+		"UnknownType":  {ContainerContentType, []byte{0x7f, 0, 0, 0, 0}},
+	} {
+		if _, err := ParseContainerEvent(content); err == nil {
+			t.Errorf("%s: expected parsing the container event to fail", name)
+		}
+	}
+}
+
+func TestAppendContainerLaunch(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	var log CEL
+	launch := ContainerLaunch{ImageRef: "image", ImageDigest: "sha256:digest", Args: []string{"a", "b"}}
+	if err := log.AppendContainerLaunch(rwc, []tpm2.Algorithm{tpm2.AlgSHA256}, launch); err != nil {
+		t.Fatal(err)
+	}
+	// The image reference and digest, the arguments and the separator.
+	if len(log.Records) != 5 {
+		t.Fatalf("got %d records, want 5", len(log.Records))
+	}
+	for _, record := range log.Records {
+		if record.PCR != ContainerPCR {
+			t.Errorf("got record in PCR %d, want %d", record.PCR, ContainerPCR)
+		}
+	}
+	last, err := ParseContainerEvent(log.Records[4].Content)
+	if err != nil || last.Type != LaunchSeparatorType {
+		t.Errorf("got last event %v (%v), want the launch separator", last, err)
+	}
+
+	if err = log.AppendContainerLaunch(rwc, []tpm2.Algorithm{tpm2.AlgSHA256}, ContainerLaunch{ImageRef: "image"}); err == nil {
+		t.Error("appending a container launch without an image digest should fail")
+	}
+}
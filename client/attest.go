@@ -1,25 +1,84 @@
 package client
 
 import (
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 
 	pb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
 )
 
+// The maximum number of intermediate certificates fetched for an AK
+// certificate chain.
+const maxIssuingCertificateURLs = 3
+
 // AttestOpts allows for optional Attest functionality to be enabled.
 type AttestOpts interface{}
 
+// AKCertFetcher fetches the certificate of an AK (such as an
+// AzureIMDSAKCertFetcher).
+type AKCertFetcher interface {
+	FetchAKCert() (*x509.Certificate, error)
+}
+
+// AttestConfig enables the optional functionality of AttestWithConfig.
+type AttestConfig struct {
+	// AKCertFetcher, if non-nil, is used to fetch the certificate of the key
+	// if it has none (see Key.SetCert), as on the Azure VMs whose AK
+	// certificate is not in the vTPM.
+	AKCertFetcher AKCertFetcher
+	// CertChainFetcher, if non-nil, is used to fetch the intermediate
+	// certificates of the key's certificate (see Key.SetCert), by following
+	// the Authority Information Access issuer URLs of each certificate. If
+	// nil, no intermediate certificates are included in the Attestation.
+	CertChainFetcher *http.Client
+	// IMALog, if true, includes the IMA runtime measurement list (as returned
+	// by GetIMALog) in the Attestation.
+	IMALog bool
+	// CanonicalEventLog, if non-empty, is included in the Attestation. This is
+	// the TLV encoded Canonical Event Log of the application's measurements,
+	// such as a cel.CEL recording the launch of a container.
+	CanonicalEventLog []byte
+	// DrtmEventLog, if non-empty, is included in the Attestation. This is the
+	// event log of a dynamic launch (such as the Intel TXT event log recorded
+	// by the SINIT ACM and tboot), which measures into PCRs 17 to 22.
+	DrtmEventLog []byte
+	// SevSnp, if true, includes an SEV-SNP attestation report (as returned by
+	// GetSevSnpAttestation), with the REPORT_DATA binding it to the nonce and
+	// the AK (see TEEReportData). This requires an AMD SEV-SNP guest.
+	SevSnp bool
+	// Tdx, if true, includes a TDX quote (as returned by GetTdxQuote), with the
+	// report data binding it to the nonce and the AK (see TEEReportData). This
+	// requires an Intel TDX guest.
+	Tdx bool
+	// DeriveNonces, if true, binds the evidence to the nonce and to each other
+	// with nonces derived by DeriveAttestationNonces, instead of using the
+	// nonce directly (and TEEReportData). The verifier must then set
+	// server.VerifyOpts.DerivedNonces.
+	DeriveNonces bool
+}
+
 // Attest generates an Attestation containing the TCG Event Log and a Quote over
 // all PCR banks. The provided nonce can be used to guarantee freshness of the
 // attestation. This function will return an error if the key is not a
-// restricted signing key.
+// restricted signing key. If the key has a certificate, it is also included,
+// with the GCE instance it identifies (see GetGCEInstanceInfo), if any.
 //
-// An optional AttestOpts can also be passed. Currently, this parameter must be nil.
+// An optional AttestOpts can also be passed. Currently, this parameter must be
+// nil. Use AttestWithConfig to enable optional functionality.
 func (k *Key) Attest(nonce []byte, opts AttestOpts) (*pb.Attestation, error) {
 	if opts != nil {
 		return nil, errors.New("provided AttestOpts must be nil")
 	}
+	return k.AttestWithConfig(nonce, AttestConfig{})
+}
+
+// AttestWithConfig generates an Attestation like Attest, additionally including
+// the IMA log, a Canonical Event Log, a DRTM event log, a TEE attestation
+// (SEV-SNP or TDX) and the key's certificate chain as enabled by config.
+func (k *Key) AttestWithConfig(nonce []byte, config AttestConfig) (*pb.Attestation, error) {
 	sels, err := implementedPCRs(k.rw)
 	if err != nil {
 		return nil, err
@@ -29,15 +88,107 @@ func (k *Key) Attest(nonce []byte, opts AttestOpts) (*pb.Attestation, error) {
 	if attestation.AkPub, err = k.PublicArea().Encode(); err != nil {
 		return nil, fmt.Errorf("failed to encode public area: %w", err)
 	}
+	// The event logs are read first, as the derived nonces depend on them.
+	if attestation.EventLog, err = GetEventLog(k.rw); err != nil {
+		return nil, fmt.Errorf("failed to retrieve TCG Event Log: %w", err)
+	}
+	if config.IMALog {
+		if attestation.ImaLog, err = GetIMALog(k.rw); err != nil {
+			return nil, fmt.Errorf("failed to retrieve IMA log: %w", err)
+		}
+	}
+	attestation.CanonicalEventLog = config.CanonicalEventLog
+	attestation.DrtmEventLog = config.DrtmEventLog
+
+	quoteNonce, reportData := nonce, TEEReportData(nonce, attestation.AkPub)
+	if config.DeriveNonces {
+		nonces, err := DeriveAttestationNonces(nonce, &attestation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive nonces: %w", err)
+		}
+		quoteNonce, reportData = nonces.TPMQuote, nonces.TEEReport
+	}
 	for _, sel := range sels {
-		quote, err := k.Quote(sel, nonce)
+		quote, err := k.Quote(sel, quoteNonce)
 		if err != nil {
 			return nil, err
 		}
 		attestation.Quotes = append(attestation.Quotes, quote)
 	}
-	if attestation.EventLog, err = GetEventLog(k.rw); err != nil {
-		return nil, fmt.Errorf("failed to retrieve TCG Event Log: %w", err)
+	if config.SevSnp {
+		if attestation.SevSnpAttestation, err = GetSevSnpAttestation(reportData); err != nil {
+			return nil, fmt.Errorf("failed to get SEV-SNP attestation report: %w", err)
+		}
+	}
+	if config.Tdx {
+		if attestation.TdxQuote, err = GetTdxQuote(reportData); err != nil {
+			return nil, fmt.Errorf("failed to get TDX quote: %w", err)
+		}
+	}
+	if k.cert == nil && config.AKCertFetcher != nil {
+		cert, err := config.AKCertFetcher.FetchAKCert()
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch AK certificate: %w", err)
+		}
+		if err = k.SetCert(cert); err != nil {
+			return nil, err
+		}
+	}
+	if k.cert != nil {
+		attestation.AkCert = k.CertDERBytes()
+		if attestation.InstanceInfo, err = GetGCEInstanceInfo(k.cert); err != nil {
+			return nil, err
+		}
+		if config.CertChainFetcher != nil {
+			if attestation.IntermediateCerts, err = fetchIntermediates(config.CertChainFetcher, k.cert); err != nil {
+				return nil, fmt.Errorf("failed to fetch AK certificate chain: %w", err)
+			}
+		}
 	}
 	return &attestation, nil
 }
+
+// fetchIntermediates follows the issuer URLs of cert, returning the DER
+// encoded intermediate certificates. Fetching stops at the first self-signed
+// (root) certificate, which is not included.
+func fetchIntermediates(client *http.Client, cert *x509.Certificate) ([][]byte, error) {
+	var intermediates [][]byte
+	for len(cert.IssuingCertificateURL) > 0 {
+		if len(intermediates) == maxIssuingCertificateURLs {
+			return nil, fmt.Errorf("certificate chain is longer than %d intermediates", maxIssuingCertificateURLs)
+		}
+		issuer, err := fetchCert(client, cert.IssuingCertificateURL[0])
+		if err != nil {
+			return nil, err
+		}
+		if err = cert.CheckSignatureFrom(issuer); err != nil {
+			return nil, fmt.Errorf("certificate %q not signed by issuer %q: %w", cert.Subject, issuer.Subject, err)
+		}
+		if issuer.CheckSignatureFrom(issuer) == nil {
+			break
+		}
+		intermediates = append(intermediates, issuer.Raw)
+		cert = issuer
+	}
+	return intermediates, nil
+}
+
+func fetchCert(client *http.Client, url string) (*x509.Certificate, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %q: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %q: %s", url, resp.Status)
+	}
+	der, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q: %w", url, err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate from %q: %w", url, err)
+	}
+	return cert, nil
+}
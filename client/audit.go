@@ -0,0 +1,93 @@
+package client
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+const cmdGetSessionAuditDigest tpmutil.Command = 0x0000014D
+
+// AuditSession is a TPM audit session. The TPM maintains a digest of all
+// commands executed using the session (and of their responses), which can be
+// signed by an AK to prove to a verifier exactly which commands were executed.
+// Users of AuditSession should call Close() when the session is no longer
+// needed.
+type AuditSession struct {
+	session *hmacSession
+}
+
+// StartAuditSession starts a new audit session. If ek is not nil, the session
+// is salted with the EK, and is also used for parameter encryption (as in
+// Key.UseEncryptedSession). The ek can be closed once the session is started.
+func StartAuditSession(rw io.ReadWriter, ek *Key) (*AuditSession, error) {
+	session, err := newHMACSession(rw, ek, true)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditSession{session}, nil
+}
+
+// Close flushes the audit session. Keys using the session can no longer be
+// used for Seal, Unseal or signing operations.
+func (a *AuditSession) Close() error {
+	return a.session.Close()
+}
+
+// Commands returns the log of the commands audited so far, in the order they
+// were executed.
+func (a *AuditSession) Commands() []*pb.AuditedCommand {
+	return a.session.auditLog
+}
+
+// UseAuditSession makes all subsequent Seal, Unseal and signing operations
+// using k execute in the audit session. Multiple keys can use the same
+// session. Closing k does not close the session. This replaces any session
+// set by UseEncryptedSession.
+func (k *Key) UseAuditSession(a *AuditSession) {
+	k.setExtraSession(a.session)
+}
+
+// Certify has the provided AK sign the current audit digest of the session,
+// together with some extra data (typically a nonce). The returned SessionAudit
+// contains the signed digest and the log of audited commands, and can be
+// verified with server.VerifySessionAudit.
+func (a *AuditSession) Certify(ak *Key, extraData []byte) (*pb.SessionAudit, error) {
+	if err := checkAttestationKey(ak); err != nil {
+		return nil, err
+	}
+	auth, err := ak.session.Auth()
+	if err != nil {
+		return nil, err
+	}
+	// The privacy administrator (the Endorsement hierarchy) must authorize
+	// reporting the audit digest.
+	resp, err := runCommand(ak.rw, cmdGetSessionAuditDigest,
+		[]tpmutil.Handle{tpm2.HandleEndorsement, ak.handle, a.session.handle},
+		[]tpm2.AuthCommand{passwordAuth(hierarchyAuth(ak.rw, tpm2.HandleEndorsement)), auth},
+		tpmutil.U16Bytes(extraData), tpm2.AlgNull)
+	if err != nil {
+		return nil, fmt.Errorf("TPM2_GetSessionAuditDigest failed: %w", err)
+	}
+	var audit tpmutil.U16Bytes
+	read, err := tpmutil.Unpack(resp, &audit)
+	if err != nil {
+		return nil, fmt.Errorf("decoding TPM2_GetSessionAuditDigest response: %w", err)
+	}
+	sessionAudit := &pb.SessionAudit{
+		Audit:    audit,
+		RawSig:   resp[read:],
+		Hash:     pb.HashAlgo(SessionHashAlgTpm),
+		Commands: a.Commands(),
+	}
+	// Verify the audit client-side to make sure the log matches the TPM.
+	// NOTE: the audit still must be verified server-side as well.
+	if err = notinternal.VerifySessionAudit(sessionAudit, ak.PublicKey(), extraData); err != nil {
+		return nil, fmt.Errorf("failed to verify session audit: %w", err)
+	}
+	return sessionAudit, nil
+}
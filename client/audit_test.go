@@ -0,0 +1,82 @@
+package client_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+	"github.com/ThalesIgnite/go-tpm-tools/server"
+)
+
+func TestAuditSession(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ek, err := client.EndorsementKeyECC(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ek.Close()
+	ak, err := client.AttestationKeyRSA(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ak.Close()
+
+	for _, salt := range []*client.Key{nil, ek} {
+		audit, err := client.StartAuditSession(rwc, salt)
+		if err != nil {
+			t.Fatalf("failed to start audit session: %v", err)
+		}
+		defer audit.Close()
+
+		srk, err := client.StorageRootKeyRSA(rwc)
+		if err != nil {
+			t.Fatalf("can't create srk from template: %v", err)
+		}
+		defer srk.Close()
+		srk.UseAuditSession(audit)
+
+		secret := []byte("audited secret")
+		sealed, err := srk.Seal(secret, nil)
+		if err != nil {
+			t.Fatalf("failed to seal: %v", err)
+		}
+		unsealed, err := srk.Unseal(sealed, nil)
+		if err != nil {
+			t.Fatalf("failed to unseal: %v", err)
+		}
+		if !bytes.Equal(unsealed, secret) {
+			t.Errorf("unsealed (%v) not equal to secret (%v)", unsealed, secret)
+		}
+
+		nonce := []byte("super secret nonce")
+		sessionAudit, err := audit.Certify(ak, nonce)
+		if err != nil {
+			t.Fatalf("failed to certify audit digest: %v", err)
+		}
+		commands := sessionAudit.GetCommands()
+		if len(commands) != 2 ||
+			commands[0].GetCommandCode() != uint32(tpm2.CmdCreate) ||
+			commands[1].GetCommandCode() != uint32(tpm2.CmdUnseal) {
+			t.Errorf("got audited commands %v, want Create and Unseal", commands)
+		}
+		if err = server.VerifySessionAudit(sessionAudit, ak.PublicKey(), nonce); err != nil {
+			t.Errorf("failed to verify session audit: %v", err)
+		}
+		if err = server.VerifySessionAudit(sessionAudit, ak.PublicKey(), []byte("wrong nonce")); err == nil {
+			t.Error("verifying session audit with the wrong nonce should fail")
+		}
+
+		truncated := proto.Clone(sessionAudit).(*pb.SessionAudit)
+		truncated.Commands = truncated.Commands[:1]
+		if err = server.VerifySessionAudit(truncated, ak.PublicKey(), nonce); err == nil {
+			t.Error("verifying session audit with missing commands should fail")
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// AzureAttestationKey loads the AK of the vTPM of an Azure VM (such as a
+// Trusted Launch or confidential VM). Note that this function will only work on
+// an Azure VM. Unlike on GCE, the AK is not created from a template, but is
+// persisted by Azure at AzureAKHandle. If the AK certificate is present in
+// AzureAKCertNVIndex, it is also loaded. Otherwise, it can be fetched from the
+// Azure Instance Metadata Service (IMDS) by an AzureIMDSAKCertFetcher, set as
+// AttestConfig.AKCertFetcher.
+//
+// The AK certificate has the TPM manufacturer Subject Alternative Name of EK
+// certificates, which server.VerifyAttestation accepts. Its issuer can be
+// fetched by AttestConfig.CertChainFetcher. Microsoft's vTPM root CAs are not
+// bundled with this module, and the AK certificate is not checked against
+// them by default: the roots must be obtained from Microsoft and passed as
+// server.VerifyOpts.TrustedRootCerts.
+func AzureAttestationKey(rw io.ReadWriter) (*Key, error) {
+	pub, _, _, err := tpm2.ReadPublic(rw, AzureAKHandle)
+	if err != nil {
+		return nil, fmt.Errorf("no Azure AK at handle 0x%x: %w", AzureAKHandle, tpmError(err))
+	}
+	ak := &Key{rw: rw, handle: AzureAKHandle, pubArea: pub}
+	if !ak.hasAttribute(tpm2.FlagSign) || !ak.hasAttribute(tpm2.FlagRestricted) {
+		return nil, fmt.Errorf("key at handle 0x%x is not a restricted signing key", AzureAKHandle)
+	}
+	if err = ak.finish(); err != nil {
+		return nil, err
+	}
+	// Not all Azure VMs have an AK certificate, so a missing index is fine.
+	if _, err = tpm2.NVReadPublic(rw, tpmutil.Handle(AzureAKCertNVIndex)); err != nil {
+		return ak, nil
+	}
+	if err = ak.setCertFromNvIndex(AzureAKCertNVIndex); err != nil {
+		ak.Close()
+		return nil, err
+	}
+	return ak, nil
+}
+
+// AzureIMDSAddress is the address of the Azure Instance Metadata Service
+// (IMDS), which is only reachable from Azure VMs, and not through proxies.
+const AzureIMDSAddress = "169.254.169.254"
+
+// The timeout of the requests to IMDS of the default client.
+const azureIMDSTimeout = 10 * time.Second
+
+// AzureIMDSAKCertFetcher fetches the AK certificate of an Azure VM from IMDS,
+// for the VMs whose vTPM has no certificate in AzureAKCertNVIndex. As with all
+// IMDS requests, it sends a GET request with the "Metadata: true" header. The
+// response must be the DER or PEM encoded certificate.
+type AzureIMDSAKCertFetcher struct {
+	// Client sends the request. If nil, a client that does not use proxies
+	// (as required by IMDS) is used.
+	Client *http.Client
+	// Path is the path of the AK certificate in IMDS, with its query (such
+	// as the api-version). It depends on the type of the VM, so there is no
+	// default.
+	Path string
+}
+
+// FetchAKCert fetches the AK certificate from IMDS.
+func (f *AzureIMDSAKCertFetcher) FetchAKCert() (*x509.Certificate, error) {
+	if f.Path == "" {
+		return nil, errors.New("no IMDS path for the AK certificate")
+	}
+	httpClient := f.Client
+	if httpClient == nil {
+		httpClient = &http.Client{Transport: &http.Transport{}, Timeout: azureIMDSTimeout}
+	}
+	url := "http://" + AzureIMDSAddress + f.Path
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata", "true")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %q: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %q: %s", url, resp.Status)
+	}
+	der, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q: %w", url, err)
+	}
+	if block, _ := pem.Decode(der); block != nil && block.Type == "CERTIFICATE" {
+		der = block.Bytes
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate from %q: %w", url, err)
+	}
+	return cert, nil
+}
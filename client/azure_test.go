@@ -0,0 +1,157 @@
+package client_test
+
+import (
+	"bytes"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestAzureAttestationKey(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	if _, err := client.AzureAttestationKey(rwc); err == nil {
+		t.Fatal("expected failure without a persisted Azure AK")
+	}
+
+	// A storage key is persisted at the handle instead of an AK.
+	srk, err := client.NewKey(rwc, tpm2.HandleOwner, client.SRKTemplateRSA())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = srk.Persist(client.AzureAKHandle); err != nil {
+		srk.Close()
+		t.Fatal(err)
+	}
+	if _, err = client.AzureAttestationKey(rwc); err == nil {
+		t.Error("expected failure with a storage key at the Azure AK handle")
+	}
+	if err = client.EvictPersistent(rwc, client.AzureAKHandle); err != nil {
+		t.Fatal(err)
+	}
+
+	ak, err := client.NewKey(rwc, tpm2.HandleOwner, client.AKTemplateRSA())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ak.Persist(client.AzureAKHandle); err != nil {
+		ak.Close()
+		t.Fatal(err)
+	}
+	defer client.EvictPersistent(rwc, client.AzureAKHandle)
+
+	azureAK, err := client.AzureAttestationKey(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if azureAK.Cert() != nil {
+		t.Error("expected no AK certificate if the NV index is not defined")
+	}
+	if !reflect.DeepEqual(azureAK.PublicKey(), ak.PublicKey()) {
+		t.Error("loaded a different AK than the persisted one")
+	}
+
+	// The certificate may be padded to the size of the NV index.
+	cert := createGCEAKCert(t, ak.PublicKey(), nil)
+	writeEKCert(t, rwc, client.AzureAKCertNVIndex, append(cert.Raw, make([]byte, 64)...))
+	defer client.UndefineNV(rwc, tpmutil.Handle(client.AzureAKCertNVIndex))
+
+	if azureAK, err = client.AzureAttestationKey(rwc); err != nil {
+		t.Fatal(err)
+	}
+	if azureAK.Cert() == nil || !azureAK.Cert().Equal(cert) {
+		t.Error("expected the AK certificate of the NV index to be loaded")
+	}
+	attestation, err := azureAK.Attest([]byte("nonce"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attestation.GetQuotes()) == 0 || len(attestation.GetAkCert()) == 0 {
+		t.Error("expected an attestation with quotes and the AK certificate")
+	}
+}
+
+// redirectTransport sends all requests to a test server.
+type redirectTransport struct {
+	url *url.URL
+}
+
+func (r redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = r.url.Scheme, r.url.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestAzureIMDSAKCertFetcher(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ak, err := client.AttestationKeyRSA(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ak.Close()
+
+	const path = "/metadata/test/akcert?api-version=1"
+	cert := createGCEAKCert(t, ak.PublicKey(), nil)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" {
+			http.Error(w, "missing Metadata header", http.StatusBadRequest)
+			return
+		}
+		if r.URL.RequestURI() != path {
+			http.NotFound(w, r)
+			return
+		}
+		pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}))
+	defer srv.Close()
+	srvURL, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpClient := &http.Client{Transport: redirectTransport{srvURL}}
+
+	fetcher := &client.AzureIMDSAKCertFetcher{Client: httpClient, Path: path}
+	attestation, err := ak.AttestWithConfig([]byte("nonce"), client.AttestConfig{AKCertFetcher: fetcher})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(attestation.GetAkCert(), cert.Raw) {
+		t.Error("expected the attestation to contain the fetched AK certificate")
+	}
+
+	// The certificate must be of the AK.
+	otherAK, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer otherAK.Close()
+	if _, err = otherAK.AttestWithConfig([]byte("nonce"), client.AttestConfig{AKCertFetcher: fetcher}); err == nil {
+		t.Error("expected attesting with the certificate of another AK to fail")
+	}
+
+	failures := []struct {
+		name    string
+		fetcher *client.AzureIMDSAKCertFetcher
+	}{
+		{"NoPath", &client.AzureIMDSAKCertFetcher{Client: httpClient}},
+		{"NotFound", &client.AzureIMDSAKCertFetcher{Client: httpClient, Path: "/metadata/unknown"}},
+	}
+	for _, f := range failures {
+		t.Run(f.name, func(t *testing.T) {
+			if _, err := f.fetcher.FetchAKCert(); err == nil {
+				t.Error("expected fetching the AK certificate to fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// KeyCache keeps the primary keys it creates loaded in the TPM, so that each
+// key is only created once by a process. Creating a primary key can take
+// hundreds of milliseconds on discrete TPMs, while a cached key is returned
+// after reading its public area. Unlike NewCachedKey, the keys are not
+// persisted in the TPM's NVRAM, so they are lost when the process exits (or
+// calls KeyCache.Close).
+//
+// A KeyCache can be used concurrently, but (as with the other functions of
+// this package) the TPM must not be used concurrently, see SharedTPM.
+type KeyCache struct {
+	rw   io.ReadWriter
+	mu   sync.Mutex
+	keys map[keyCacheID]*Key
+}
+
+// Cached keys are identified by their parent and the digest of their template.
+type keyCacheID struct {
+	parent   tpmutil.Handle
+	template [sha256.Size]byte
+}
+
+// NewKeyCache returns an empty KeyCache for the TPM rw.
+func NewKeyCache(rw io.ReadWriter) *KeyCache {
+	return &KeyCache{rw: rw, keys: map[keyCacheID]*Key{}}
+}
+
+// Key returns the key created from template under parent (as with NewKey),
+// creating it if it is not in the cache. Closing the returned key only
+// flushes its sessions, the key stays loaded until the cache is closed. If the
+// key was flushed from the TPM (for example if the hierarchy was cleared), it
+// is created again.
+func (c *KeyCache) Key(parent tpmutil.Handle, template tpm2.Public) (*Key, error) {
+	encoded, err := template.Encode()
+	if err != nil {
+		return nil, fmt.Errorf("encoding template: %w", err)
+	}
+	id := keyCacheID{parent, sha256.Sum256(encoded)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.keys[id]; ok {
+		if c.isLoaded(cached) {
+			return cached.cachedCopy()
+		}
+		delete(c.keys, id)
+	}
+
+	k, err := NewKey(c.rw, parent, template)
+	if err != nil {
+		return nil, err
+	}
+	// The cache only needs the handle and public area of the key.
+	k.session.Close()
+	k.session = nil
+	c.keys[id] = k
+	return k.cachedCopy()
+}
+
+// isLoaded reports whether the cached key is still loaded at its handle.
+func (c *KeyCache) isLoaded(k *Key) bool {
+	pub, _, _, err := tpm2.ReadPublic(c.rw, k.handle)
+	if err != nil {
+		return false
+	}
+	loaded, err := pub.Encode()
+	if err != nil {
+		return false
+	}
+	cached, err := k.pubArea.Encode()
+	return err == nil && bytes.Equal(loaded, cached)
+}
+
+// cachedCopy returns a new Key (with its own session) for the cached key k.
+func (k *Key) cachedCopy() (*Key, error) {
+	copied := &Key{
+		rw:           k.rw,
+		handle:       k.handle,
+		pubArea:      k.pubArea,
+		cached:       true,
+		creationData: k.creationData,
+		creationHash: k.creationHash,
+		ticket:       k.ticket,
+	}
+	return copied, copied.finish()
+}
+
+// Close flushes the cached keys from the TPM. Keys returned by the cache must
+// not be used afterwards.
+func (c *KeyCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var firstErr error
+	for id, k := range c.keys {
+		delete(c.keys, id)
+		if err := tpm2.FlushContext(c.rw, k.handle); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("flushing cached key 0x%x: %w", k.handle, tpmError(err))
+		}
+	}
+	return firstErr
+}
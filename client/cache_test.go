@@ -0,0 +1,101 @@
+package client_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	"github.com/ThalesIgnite/go-tpm-tools/server"
+)
+
+func TestKeyCache(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	cache := client.NewKeyCache(rwc)
+	defer cache.Close()
+
+	ek, err := cache.Key(tpm2.HandleEndorsement, client.DefaultEKTemplateECC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek.Close()
+	cached, err := cache.Key(tpm2.HandleEndorsement, client.DefaultEKTemplateECC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cached.Close()
+	if cached.Handle() != ek.Handle() {
+		t.Errorf("got handle 0x%x, want the cached handle 0x%x", cached.Handle(), ek.Handle())
+	}
+	// The key must still be usable (with its EK session) after closing the
+	// first copy.
+	blob, err := server.CreateImportBlob(cached.PublicKey(), []byte("secret"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret, err := cached.Import(blob); err != nil {
+		t.Errorf("importing with the cached EK failed: %v", err)
+	} else if string(secret) != "secret" {
+		t.Errorf("got imported secret %q, want %q", secret, "secret")
+	}
+	if _, _, _, err = tpm2.ReadPublic(rwc, cached.Handle()); err != nil {
+		t.Errorf("cached key is not loaded: %v", err)
+	}
+
+	srk, err := cache.Key(tpm2.HandleOwner, client.SRKTemplateECC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srk.Close()
+	if srk.Handle() == cached.Handle() {
+		t.Error("keys with different templates should have different handles")
+	}
+	if err = srk.Persist(0x81008F20); err == nil {
+		t.Error("persisting a cached key should fail")
+	}
+}
+
+func TestKeyCacheFlushed(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	cache := client.NewKeyCache(rwc)
+	defer cache.Close()
+
+	ak, err := cache.Key(tpm2.HandleOwner, client.AKTemplateECC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ak.Close()
+	if err = tpm2.FlushContext(rwc, ak.Handle()); err != nil {
+		t.Fatal(err)
+	}
+	recreated, err := cache.Key(tpm2.HandleOwner, client.AKTemplateECC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recreated.Close()
+	if !reflect.DeepEqual(recreated.PublicKey(), ak.PublicKey()) {
+		t.Error("recreated key does not match the flushed key")
+	}
+	if _, err = recreated.SignData([]byte("data")); err != nil {
+		t.Errorf("signing with the recreated key failed: %v", err)
+	}
+}
+
+func BenchmarkKeyCache(b *testing.B) {
+	rwc := test.GetTPM(b)
+	defer client.CheckedClose(b, rwc)
+	cache := client.NewKeyCache(rwc)
+	defer cache.Close()
+
+	for i := 0; i < b.N; i++ {
+		k, err := cache.Key(tpm2.HandleOwner, client.SRKTemplateRSA())
+		if err != nil {
+			b.Fatal(err)
+		}
+		k.Close()
+	}
+}
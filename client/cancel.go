@@ -0,0 +1,257 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+// Commands which return a handle, which must be flushed if the command
+// completes after it was cancelled.
+var handleCommands = map[tpmutil.Command]bool{
+	tpm2.CmdCreatePrimary:       true,
+	tpm2.CmdLoad:                true,
+	tpm2.CmdLoadExternal:        true,
+	tpm2.CmdStartAuthSession:    true,
+	tpm2.CmdContextLoad:         true,
+	tpm2.CmdHashSequenceStart:   true,
+	cmdHMACStart:                true,
+	tpmutil.Command(0x00000191): true, // TPM2_CreateLoaded
+}
+
+// CancellableTPM wraps a TPM so that the operations using it can be cancelled
+// with a context.Context, see WithContext. A TPM command cannot be interrupted
+// once it is sent, so a cancelled command keeps running in the background:
+// the next commands wait for it to complete, and the object or session it
+// creates (if any) is flushed.
+//
+// A CancellableTPM can be used directly as an io.ReadWriter (without
+// cancellation), and is safe for concurrent use, but the TPM's sessions and
+// objects are not isolated (see SharedTPM).
+type CancellableTPM struct {
+	rw io.ReadWriter
+	// Holds a value while a command runs.
+	busy chan struct{}
+	// If set, a cancelled command is waited for, instead of running in the
+	// background, as other users of rw would not wait for it.
+	wait bool
+	conn *cancellableConn
+}
+
+// NewCancellableTPM returns a CancellableTPM for rw, which must not be used
+// directly afterwards. The CancellableTPM forwards Close (and GetEventLog,
+// GetIMALog and HierarchyAuth) to rw.
+func NewCancellableTPM(rw io.ReadWriter) *CancellableTPM {
+	c := &CancellableTPM{rw: rw, busy: make(chan struct{}, 1)}
+	c.conn = &cancellableConn{tpm: c, ctx: context.Background()}
+	return c
+}
+
+// withContext returns rw, for use until ctx is done. Unless rw is a
+// CancellableTPM, a command in progress when ctx is done is waited for.
+func withContext(ctx context.Context, rw io.ReadWriter) io.ReadWriter {
+	if c, ok := rw.(*CancellableTPM); ok {
+		return c.WithContext(ctx)
+	}
+	c := NewCancellableTPM(rw)
+	c.wait = true
+	return c.WithContext(ctx)
+}
+
+// WithContext returns an io.ReadWriter running the commands on the TPM until
+// ctx is done. Afterwards, the commands fail with ctx.Err(), except for
+// TPM2_FlushContext, so that sessions and objects can still be flushed. If ctx
+// is done while a command runs, Write returns ctx.Err() without waiting for
+// the command to complete. Each io.ReadWriter must only be used by one
+// goroutine.
+func (c *CancellableTPM) WithContext(ctx context.Context) io.ReadWriter {
+	return &cancellableConn{tpm: c, ctx: ctx}
+}
+
+// Write runs a command, which must be written in a single call to Write.
+func (c *CancellableTPM) Write(cmd []byte) (int, error) {
+	return c.conn.Write(cmd)
+}
+
+// Read reads the response to the last command.
+func (c *CancellableTPM) Read(p []byte) (int, error) {
+	return c.conn.Read(p)
+}
+
+// Close closes the underlying TPM, if it implements io.Closer.
+func (c *CancellableTPM) Close() error {
+	if closer, ok := c.rw.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// EventLog returns the event log of the underlying TPM (see GetEventLog).
+func (c *CancellableTPM) EventLog() ([]byte, error) {
+	return GetEventLog(c.rw)
+}
+
+// IMALog returns the IMA log of the underlying TPM (see GetIMALog).
+func (c *CancellableTPM) IMALog() ([]byte, error) {
+	return GetIMALog(c.rw)
+}
+
+// HierarchyAuth returns the hierarchy authorization values of the underlying
+// TPM (see HierarchyAuthGetter).
+func (c *CancellableTPM) HierarchyAuth() HierarchyAuth {
+	if getter, ok := c.rw.(HierarchyAuthGetter); ok {
+		return getter.HierarchyAuth()
+	}
+	return HierarchyAuth{}
+}
+
+type cancellableConn struct {
+	tpm  *CancellableTPM
+	ctx  context.Context
+	mu   sync.Mutex // Guards resp for the CancellableTPM's own conn.
+	resp bytes.Reader
+}
+
+// The result of a command run in the background.
+type commandResult struct {
+	resp []byte
+	err  error
+}
+
+// A command run in the background.
+type backgroundCommand struct {
+	mu        sync.Mutex
+	abandoned bool
+	done      chan commandResult
+}
+
+func (c *cancellableConn) Write(cmd []byte) (int, error) {
+	if len(cmd) < headerSize {
+		return 0, io.ErrShortWrite
+	}
+	code := tpmutil.Command(binary.BigEndian.Uint32(cmd[6:headerSize]))
+	// Flushing must still be possible once ctx is done.
+	ctx := c.ctx
+	if code == tpm2.CmdFlushContext {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	select {
+	case c.tpm.busy <- struct{}{}:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+
+	bg := &backgroundCommand{done: make(chan commandResult, 1)}
+	cmd = append([]byte(nil), cmd...)
+	go c.tpm.run(bg, code, cmd)
+
+	var result commandResult
+	select {
+	case result = <-bg.done:
+	case <-ctx.Done():
+		if c.tpm.wait {
+			result = <-bg.done
+			flushOrphan(c.tpm.rw, code, result)
+			return 0, ctx.Err()
+		}
+		bg.mu.Lock()
+		select {
+		case result = <-bg.done:
+			// The command completed anyway.
+			bg.mu.Unlock()
+		default:
+			bg.abandoned = true
+			bg.mu.Unlock()
+			return 0, ctx.Err()
+		}
+	}
+	if result.err != nil {
+		return 0, result.err
+	}
+	c.mu.Lock()
+	c.resp.Reset(result.resp)
+	c.mu.Unlock()
+	return len(cmd), nil
+}
+
+func (c *cancellableConn) Read(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.resp.Read(p)
+}
+
+// run runs cmd, flushing the handle it returns if the command was abandoned,
+// and then allows the next command to run.
+func (c *CancellableTPM) run(bg *backgroundCommand, code tpmutil.Command, cmd []byte) {
+	defer func() { <-c.busy }()
+	var result commandResult
+	if _, result.err = c.rw.Write(cmd); result.err == nil {
+		resp := make([]byte, maxTPMResponse)
+		var n int
+		n, result.err = c.rw.Read(resp)
+		result.resp = resp[:n]
+	}
+
+	bg.mu.Lock()
+	defer bg.mu.Unlock()
+	if bg.abandoned {
+		flushOrphan(c.rw, code, result)
+		return
+	}
+	bg.done <- result
+}
+
+// flushOrphan flushes the handle returned by a command which completed after
+// it was cancelled.
+func flushOrphan(rw io.ReadWriter, code tpmutil.Command, result commandResult) {
+	if result.err != nil || !handleCommands[code] || len(result.resp) < headerSize+4 {
+		return
+	}
+	if binary.BigEndian.Uint32(result.resp[6:headerSize]) != uint32(tpmutil.RCSuccess) {
+		return
+	}
+	tpm2.FlushContext(rw, tpmutil.Handle(binary.BigEndian.Uint32(result.resp[headerSize:])))
+}
+
+// ReadPCRsContext is like ReadPCRs, but stops reading PCRs when ctx is done
+// (see CancellableTPM.WithContext).
+func ReadPCRsContext(ctx context.Context, rw io.ReadWriter, sel tpm2.PCRSelection) (*pb.PCRs, error) {
+	return ReadPCRs(withContext(ctx, rw), sel)
+}
+
+// withContext returns a copy of the key using its TPM until ctx is done. The
+// key's sessions keep using the TPM without ctx.
+func (k *Key) withContext(ctx context.Context) *Key {
+	copied := *k
+	copied.rw = withContext(ctx, k.rw)
+	return &copied
+}
+
+// SealContext is like Seal, but is cancelled when ctx is done. If k was not
+// created from a CancellableTPM, a TPM command in progress when ctx is done is
+// waited for.
+func (k *Key) SealContext(ctx context.Context, sensitive []byte, opts SealOpts) (*pb.SealedBytes, error) {
+	return k.withContext(ctx).Seal(sensitive, opts)
+}
+
+// UnsealContext is like Unseal, but is cancelled when ctx is done (as with
+// SealContext).
+func (k *Key) UnsealContext(ctx context.Context, in *pb.SealedBytes, opts CertifyOpts) ([]byte, error) {
+	return k.withContext(ctx).Unseal(in, opts)
+}
+
+// QuoteContext is like Quote, but is cancelled when ctx is done (as with
+// SealContext).
+func (k *Key) QuoteContext(ctx context.Context, selpcr tpm2.PCRSelection, extraData []byte) (*pb.Quote, error) {
+	return k.withContext(ctx).Quote(selpcr, extraData)
+}
@@ -0,0 +1,126 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+// slowTPM delays each command sent to the TPM.
+type slowTPM struct {
+	io.ReadWriter
+	delay time.Duration
+}
+
+func (s slowTPM) Write(cmd []byte) (int, error) {
+	time.Sleep(s.delay)
+	return s.ReadWriter.Write(cmd)
+}
+
+func TestCancelledContext(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	sel := tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{0, 1, 2}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.ReadPCRsContext(ctx, rwc, sel); !errors.Is(err, context.Canceled) {
+		t.Errorf("ReadPCRsContext() = %v, want %v", err, context.Canceled)
+	}
+	if _, err := client.ReadPCRsContext(context.Background(), rwc, sel); err != nil {
+		t.Errorf("ReadPCRsContext() failed without cancellation: %v", err)
+	}
+
+	ak, err := client.NewKey(rwc, tpm2.HandleOwner, client.AKTemplateECC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ak.Close()
+	if _, err = ak.QuoteContext(ctx, sel, []byte("nonce")); !errors.Is(err, context.Canceled) {
+		t.Errorf("QuoteContext() = %v, want %v", err, context.Canceled)
+	}
+	if _, err = ak.QuoteContext(context.Background(), sel, []byte("nonce")); err != nil {
+		t.Errorf("QuoteContext() failed without cancellation: %v", err)
+	}
+}
+
+func TestSealContext(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	srk, err := client.NewKey(rwc, tpm2.HandleOwner, client.SRKTemplateECC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srk.Close()
+
+	sealed, err := srk.SealContext(context.Background(), []byte("secret"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = srk.UnsealContext(ctx, sealed, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("UnsealContext() = %v, want %v", err, context.Canceled)
+	}
+	unsealed, err := srk.UnsealContext(context.Background(), sealed, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(unsealed, []byte("secret")) {
+		t.Errorf("unsealed %q, want %q", unsealed, "secret")
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	slow := slowTPM{rwc, 200 * time.Millisecond}
+	// Only one CancellableTPM must use the TPM, as the cancelled commands
+	// keep running.
+	tpm := client.NewCancellableTPM(slow)
+
+	for _, cancellable := range []bool{false, true} {
+		var rw io.ReadWriter = slow
+		if cancellable {
+			rw = tpm
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		start := time.Now()
+		_, err := client.ReadPCRsContext(ctx, rw, tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{0}})
+		elapsed := time.Since(start)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("ReadPCRsContext() = %v, want %v", err, context.DeadlineExceeded)
+		}
+		// A CancellableTPM does not wait for the command in progress.
+		if cancellable && elapsed >= slow.delay {
+			t.Errorf("ReadPCRsContext() returned after %v, want less than %v", elapsed, slow.delay)
+		}
+	}
+
+	// Wait for the cancelled command, so that StartAuthSession is sent to the
+	// TPM. The session started after the deadline must be flushed, which
+	// CheckedClose verifies.
+	if _, err := tpm2.GetRandom(tpm, 16); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, _, err := tpm2.StartAuthSession(tpm.WithContext(ctx), tpm2.HandleNull, tpm2.HandleNull,
+		make([]byte, 16), nil, tpm2.SessionHMAC, tpm2.AlgNull, tpm2.AlgSHA256)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("StartAuthSession() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	// The next command runs once the abandoned command completed.
+	if _, err = tpm2.GetRandom(tpm, 16); err != nil {
+		t.Errorf("GetRandom() failed after cancellation: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// CertifyWith has the provided AK certify the key using TPM2_Certify, together
+// with some extra data (typically a nonce). The returned KeyCertification
+// proves that the key is loaded in the same TPM as the AK, and can be verified
+// with server.VerifyKeyCertification. Together with the key's attributes
+// (such as tpm2.FlagFixedTPM), this lets a relying party check that a key
+// (for example, a TLS key) cannot be used outside of the TPM.
+func (k *Key) CertifyWith(ak *Key, extraData []byte) (*pb.KeyCertification, error) {
+	if err := checkAttestationKey(ak); err != nil {
+		return nil, err
+	}
+	objectAuth, err := k.session.Auth()
+	if err != nil {
+		return nil, err
+	}
+	signerAuth, err := ak.session.Auth()
+	if err != nil {
+		return nil, err
+	}
+	// A null scheme uses the scheme of the AK.
+	resp, err := runCommand(k.rw, tpm2.CmdCertify, []tpmutil.Handle{k.handle, ak.handle},
+		[]tpm2.AuthCommand{objectAuth, signerAuth}, tpmutil.U16Bytes(extraData), tpm2.AlgNull)
+	if err != nil {
+		return nil, fmt.Errorf("TPM2_Certify failed: %w", err)
+	}
+	var certifyInfo tpmutil.U16Bytes
+	read, err := tpmutil.Unpack(resp, &certifyInfo)
+	if err != nil {
+		return nil, fmt.Errorf("decoding TPM2_Certify response: %w", err)
+	}
+	pubArea, err := k.pubArea.Encode()
+	if err != nil {
+		return nil, fmt.Errorf("encoding public area: %w", err)
+	}
+	certification := &pb.KeyCertification{
+		CertifyInfo: certifyInfo,
+		RawSig:      resp[read:],
+		PublicArea:  pubArea,
+	}
+	// Verify the certification client-side to make sure it matches the key.
+	// NOTE: the certification still must be verified server-side as well.
+	if _, err = notinternal.VerifyKeyCertification(certification, ak.PublicKey(), extraData); err != nil {
+		return nil, fmt.Errorf("failed to verify key certification: %w", err)
+	}
+	return certification, nil
+}
+
+// CertifyCreation has the provided AK certify the key's creation data using
+// TPM2_CertifyCreation, together with some extra data (typically a nonce).
+// The returned CreationCertification proves that the key was created by the
+// AK's TPM with the recorded creation data, including the values of the PCRs
+// selected with KeyOpts.CreationPCRs, and can be verified (at any time after
+// the key's creation) with server.VerifyCreationCertification.
+//
+// This requires the creation ticket of the key, so it only works with keys
+// created by this package in the Owner, Endorsement or Platform hierarchies
+// (the TPM does not issue tickets for keys in the Null hierarchy). Keys loaded
+// from a cached handle (by NewCachedKey) have no creation ticket.
+func (k *Key) CertifyCreation(ak *Key, extraData []byte) (*pb.CreationCertification, error) {
+	if k.ticket == nil || k.ticket.Hierarchy == tpm2.HandleNull {
+		return nil, fmt.Errorf("key has no creation ticket")
+	}
+	if err := checkAttestationKey(ak); err != nil {
+		return nil, err
+	}
+	signerAuth, err := ak.session.Auth()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := runCommand(k.rw, tpm2.CmdCertifyCreation, []tpmutil.Handle{ak.handle, k.handle},
+		[]tpm2.AuthCommand{signerAuth}, tpmutil.U16Bytes(extraData), tpmutil.U16Bytes(k.creationHash),
+		tpm2.AlgNull, *k.ticket)
+	if err != nil {
+		return nil, fmt.Errorf("TPM2_CertifyCreation failed: %w", err)
+	}
+	var creationInfo tpmutil.U16Bytes
+	read, err := tpmutil.Unpack(resp, &creationInfo)
+	if err != nil {
+		return nil, fmt.Errorf("decoding TPM2_CertifyCreation response: %w", err)
+	}
+	pubArea, err := k.pubArea.Encode()
+	if err != nil {
+		return nil, fmt.Errorf("encoding public area: %w", err)
+	}
+	certification := &pb.CreationCertification{
+		CreationInfo: creationInfo,
+		RawSig:       resp[read:],
+		PublicArea:   pubArea,
+		CreationData: k.creationData,
+	}
+	// NOTE: the certification still must be verified server-side as well.
+	if _, _, err = notinternal.VerifyCreationCertification(certification, ak.PublicKey(), extraData); err != nil {
+		return nil, fmt.Errorf("failed to verify creation certification: %w", err)
+	}
+	return certification, nil
+}
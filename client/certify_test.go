@@ -0,0 +1,183 @@
+package client_test
+
+import (
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+	"github.com/ThalesIgnite/go-tpm-tools/server"
+)
+
+func TestCertifyWith(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	keyOpts := client.KeyOpts{
+		Algorithm: tpm2.AlgECC,
+		Attributes: tpm2.FlagSign | tpm2.FlagFixedTPM | tpm2.FlagFixedParent |
+			tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth,
+		Auth: "password",
+	}
+	key, err := client.NewKeyWithOpts(rwc, tpm2.HandleOwner, keyOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer key.Close()
+
+	for _, getAK := range []func(rw io.ReadWriter) (*client.Key, error){
+		client.AttestationKeyRSA, client.AttestationKeyECC,
+	} {
+		ak, err := getAK(rwc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ak.Close()
+
+		nonce := []byte("super secret nonce")
+		certification, err := key.CertifyWith(ak, nonce)
+		if err != nil {
+			t.Fatalf("failed to certify key: %v", err)
+		}
+		pub, err := server.VerifyKeyCertification(certification, ak.PublicKey(), nonce)
+		if err != nil {
+			t.Fatalf("failed to verify key certification: %v", err)
+		}
+		if !reflect.DeepEqual(pub, key.PublicKey()) {
+			t.Errorf("certified public key %v does not match key %v", pub, key.PublicKey())
+		}
+		if _, err = server.VerifyKeyCertification(certification, ak.PublicKey(), []byte("wrong nonce")); err == nil {
+			t.Error("verifying key certification with the wrong nonce should fail")
+		}
+
+		// Swapping the public area of the key must be detected.
+		other, err := client.NewKeyWithOpts(rwc, tpm2.HandleOwner, client.KeyOpts{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		otherPub, err := other.PublicArea().Encode()
+		other.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		swapped := proto.Clone(certification).(*pb.KeyCertification)
+		swapped.PublicArea = otherPub
+		if _, err = server.VerifyKeyCertification(swapped, ak.PublicKey(), nonce); err == nil {
+			t.Error("verifying key certification with a different public area should fail")
+		}
+	}
+}
+
+func TestVerifyKeyCertificationExportable(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ak, err := client.AttestationKeyRSA(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ak.Close()
+	// A key which is not bound to this TPM.
+	key, err := client.NewKeyWithOpts(rwc, tpm2.HandleNull, client.KeyOpts{
+		Attributes: tpm2.FlagSign | tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer key.Close()
+
+	certification, err := key.CertifyWith(ak, nil)
+	if err != nil {
+		t.Fatalf("failed to certify key: %v", err)
+	}
+	if _, err = server.VerifyKeyCertification(certification, ak.PublicKey(), nil); err == nil {
+		t.Error("verifying the certification of an exportable key should fail")
+	}
+}
+
+func TestCertifyCreation(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ak, err := client.AttestationKeyRSA(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ak.Close()
+
+	sel := tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{test.DebugPCR}}
+	pcrs, err := client.ReadPCRs(rwc, sel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := client.NewKeyWithOpts(rwc, tpm2.HandleOwner, client.KeyOpts{
+		Attributes: tpm2.FlagSign | tpm2.FlagFixedTPM | tpm2.FlagFixedParent |
+			tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth,
+		CreationPCRs: sel,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer key.Close()
+
+	// The creation PCRs can still be proven after they have changed.
+	if err = tpm2.PCRExtend(rwc, tpmutil.Handle(test.DebugPCR), tpm2.AlgSHA256, make([]byte, 32), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	nonce := []byte("super secret nonce")
+	certification, err := key.CertifyCreation(ak, nonce)
+	if err != nil {
+		t.Fatalf("failed to certify creation: %v", err)
+	}
+	pub, err := server.VerifyCreationCertification(certification, ak.PublicKey(), nonce, pcrs)
+	if err != nil {
+		t.Fatalf("failed to verify creation certification: %v", err)
+	}
+	if !reflect.DeepEqual(pub, key.PublicKey()) {
+		t.Errorf("certified public key %v does not match key %v", pub, key.PublicKey())
+	}
+	if _, err = server.VerifyCreationCertification(certification, ak.PublicKey(), []byte("wrong nonce"), pcrs); err == nil {
+		t.Error("verifying creation certification with the wrong nonce should fail")
+	}
+
+	current, err := client.ReadPCRs(rwc, sel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = server.VerifyCreationCertification(certification, ak.PublicKey(), nonce, current); err == nil {
+		t.Error("verifying creation certification with the wrong PCRs should fail")
+	}
+
+	tampered := proto.Clone(certification).(*pb.CreationCertification)
+	tampered.CreationData[len(tampered.CreationData)-1] ^= 1
+	if _, err = server.VerifyCreationCertification(tampered, ak.PublicKey(), nonce, nil); err == nil {
+		t.Error("verifying creation certification with modified creation data should fail")
+	}
+}
+
+func TestCertifyCreationNullHierarchy(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ak, err := client.AttestationKeyRSA(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ak.Close()
+	key, err := client.NewKeyWithOpts(rwc, tpm2.HandleNull, client.KeyOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer key.Close()
+
+	if _, err = key.CertifyCreation(ak, nil); err == nil {
+		t.Error("certifying the creation of a key in the null hierarchy should fail")
+	}
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"fmt"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+	"github.com/google/go-tpm/tpm2"
+)
+
+// CreateChild creates a key from the template described by opts (see
+// KeyOpts.Template) as a child of the storage key k (such as an SRK), without
+// loading it. Unlike primary keys, child keys are generated randomly, so the
+// returned KeyBlob must be stored to use the key later with Key.LoadChild. The
+// KeyBlob is encrypted by k, so it can only be loaded under k on this TPM.
+//
+// Only keys that use opts.Auth (or no authorization) can be loaded with
+// LoadChild, so opts.AuthPolicy must not be set.
+func (k *Key) CreateChild(opts KeyOpts) (*pb.KeyBlob, error) {
+	if opts.AuthPolicy != nil {
+		return nil, fmt.Errorf("child keys cannot have an auth policy")
+	}
+	template, err := opts.Template()
+	if err != nil {
+		return nil, err
+	}
+	child, err := k.createChild(template, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.KeyBlob{PublicArea: child.public, PrivateArea: child.private}, nil
+}
+
+// LoadChild loads a key created by Key.CreateChild under the same parent k.
+// The authValue must match the KeyOpts.Auth used to create the key. The
+// returned key only stays valid as long as k is loaded, and should be closed
+// when no longer needed.
+func (k *Key) LoadChild(blob *pb.KeyBlob, authValue string) (*Key, error) {
+	return k.loadChild(blob.GetPublicArea(), blob.GetPrivateArea(), passwordSession{authValue})
+}
+
+// The encoded public and private areas of a created child key, with the
+// creation data.
+type childKey struct {
+	public       []byte
+	private      []byte
+	creationData []byte
+	creationHash []byte
+	ticket       tpm2.Ticket
+}
+
+func (k *Key) createChild(template tpm2.Public, opts KeyOpts) (*childKey, error) {
+	auth, err := k.session.Auth()
+	if err != nil {
+		return nil, err
+	}
+	var child childKey
+	child.private, child.public, child.creationData, child.creationHash, child.ticket, err =
+		tpm2.CreateKeyUsingAuth(k.rw, k.handle, opts.CreationPCRs, auth, opts.Auth, template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create child key: %w", tpmError(err))
+	}
+	return &child, nil
+}
+
+func (k *Key) loadChild(public, private []byte, keySession session) (key *Key, err error) {
+	auth, err := k.session.Auth()
+	if err != nil {
+		return nil, err
+	}
+	handle, _, err := tpm2.LoadUsingAuth(k.rw, k.handle, auth, public, private)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load child key: %w", tpmError(err))
+	}
+	key = &Key{rw: k.rw, handle: handle, session: keySession}
+	defer func() {
+		if err != nil {
+			key.Close()
+		}
+	}()
+	if key.pubArea, err = tpm2.DecodePublic(public); err != nil {
+		return nil, err
+	}
+	return key, key.finish()
+}
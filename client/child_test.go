@@ -0,0 +1,63 @@
+package client_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestCreateLoadChild(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	srk, err := client.StorageRootKeyECC(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srk.Close()
+
+	opts := client.KeyOpts{
+		Algorithm: tpm2.AlgECC,
+		Attributes: tpm2.FlagSign | tpm2.FlagFixedTPM | tpm2.FlagFixedParent |
+			tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth,
+		Auth: "child password",
+	}
+	blob, err := srk.CreateChild(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Child keys are random, so creating another key gives a different key.
+	other, err := srk.CreateChild(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(blob.GetPublicArea(), other.GetPublicArea()) {
+		t.Error("child keys should be different")
+	}
+
+	key, err := srk.LoadChild(blob, opts.Auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer key.Close()
+	if _, err = key.SignData([]byte("data")); err != nil {
+		t.Errorf("failed to sign with child key: %v", err)
+	}
+
+	wrongAuth, err := srk.LoadChild(blob, "wrong password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wrongAuth.Close()
+	if _, err = wrongAuth.SignData([]byte("data")); err == nil {
+		t.Error("signing with the wrong password should fail")
+	}
+
+	if _, err = srk.CreateChild(client.KeyOpts{AuthPolicy: client.PolicyAuthValue{}}); err == nil {
+		t.Error("creating a child key with an auth policy should fail")
+	}
+}
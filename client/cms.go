@@ -0,0 +1,312 @@
+package client
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// The OIDs of the CMS content types (RFC 5652) and of the algorithms supported
+// by DecryptEnvelopedData.
+var (
+	oidEnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+	oidRSAES         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidRSAESOAEP     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 7}
+	oidMGF1          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 8}
+	oidPSpecified    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 9}
+)
+
+// The AES-CBC content encryption algorithms (RFC 3565), by key size.
+var cmsAESCBC = map[string]int{
+	"2.16.840.1.101.3.4.1.2":  16,
+	"2.16.840.1.101.3.4.1.22": 24,
+	"2.16.840.1.101.3.4.1.42": 32,
+}
+
+// The hash algorithms of RSAES-OAEP (RFC 4055, section 2.1).
+var cmsOAEPHashes = map[string]crypto.Hash{
+	"1.3.14.3.2.26":          crypto.SHA1,
+	"2.16.840.1.101.3.4.2.1": crypto.SHA256,
+	"2.16.840.1.101.3.4.2.2": crypto.SHA384,
+	"2.16.840.1.101.3.4.2.3": crypto.SHA512,
+}
+
+// cmsContentInfo is a ContentInfo. As a RawValue, its Content is the [0]
+// EXPLICIT tag, whose Bytes are the content.
+type cmsContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+type cmsKeyTransRecipientInfo struct {
+	Version                int
+	RID                    asn1.RawValue
+	KeyEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedKey           []byte
+}
+
+type cmsIssuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type cmsEncryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+type cmsOAEPParams struct {
+	Hash    pkix.AlgorithmIdentifier `asn1:"optional,explicit,tag:0"`
+	MGF     pkix.AlgorithmIdentifier `asn1:"optional,explicit,tag:1"`
+	PSource pkix.AlgorithmIdentifier `asn1:"optional,explicit,tag:2"`
+}
+
+// DecryptEnvelopedData decrypts a CMS EnvelopedData (RFC 5652, as created by
+// "openssl cms -encrypt" for S/MIME) with the RSA decryption key k (see
+// GetDecrypter), returning its content. The data is either DER encoded, or a
+// PEM "CMS" or "PKCS7" block; BER encodings with indefinite lengths are not
+// supported.
+//
+// The recipient of k is the key transport recipient matching cert (by issuer
+// and serial number or by subject key identifier), or, if cert is nil, the
+// only key transport recipient. The content encryption key must be encrypted
+// with RSAES-PKCS1-v1_5 or RSAES-OAEP (with MGF1 of the same hash and no
+// label), and the content with AES-CBC.
+func (k *Key) DecryptEnvelopedData(data []byte, cert *x509.Certificate) ([]byte, error) {
+	decrypter, err := k.GetDecrypter()
+	if err != nil {
+		return nil, err
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != "CMS" && block.Type != "PKCS7" {
+			return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+		}
+		data = block.Bytes
+	}
+	var contentInfo cmsContentInfo
+	if err = unmarshalDER(data, &contentInfo); err != nil {
+		return nil, fmt.Errorf("decoding ContentInfo: %w", err)
+	}
+	if !contentInfo.ContentType.Equal(oidEnvelopedData) {
+		return nil, fmt.Errorf("content type %v is not EnvelopedData", contentInfo.ContentType)
+	}
+	recipients, encrypted, err := parseEnvelopedData(contentInfo.Content.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := findRecipient(recipients, cert)
+	if err != nil {
+		return nil, err
+	}
+
+	keySize, ok := cmsAESCBC[encrypted.ContentEncryptionAlgorithm.Algorithm.String()]
+	if !ok {
+		return nil, fmt.Errorf("unsupported content encryption algorithm %v", encrypted.ContentEncryptionAlgorithm.Algorithm)
+	}
+	var iv []byte
+	if err = unmarshalDER(encrypted.ContentEncryptionAlgorithm.Parameters.FullBytes, &iv); err != nil || len(iv) != aes.BlockSize {
+		return nil, errors.New("invalid AES-CBC IV")
+	}
+	opts, err := recipientDecrypterOpts(recipient.KeyEncryptionAlgorithm, keySize)
+	if err != nil {
+		return nil, err
+	}
+	contentKey, err := decrypter.Decrypt(rand.Reader, recipient.EncryptedKey, opts)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting content encryption key: %w", err)
+	}
+	if len(contentKey) != keySize {
+		return nil, errors.New("invalid content encryption key")
+	}
+	ciphertext, err := octetStringContent(encrypted.EncryptedContent)
+	if err != nil {
+		return nil, err
+	}
+	return decryptAESCBC(contentKey, iv, ciphertext)
+}
+
+// parseEnvelopedData returns the key transport recipients and the encrypted
+// content of an EnvelopedData. The fields are parsed one by one, as the
+// optional originatorInfo cannot be told apart from the recipientInfos by
+// encoding/asn1.
+func parseEnvelopedData(der []byte) ([]cmsKeyTransRecipientInfo, *cmsEncryptedContentInfo, error) {
+	var seq asn1.RawValue
+	if err := unmarshalDER(der, &seq); err != nil || seq.Tag != asn1.TagSequence {
+		return nil, nil, errors.New("invalid EnvelopedData")
+	}
+	var fields []asn1.RawValue
+	for rest := seq.Bytes; len(rest) > 0; {
+		var field asn1.RawValue
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &field); err != nil {
+			return nil, nil, fmt.Errorf("decoding EnvelopedData: %w", err)
+		}
+		fields = append(fields, field)
+	}
+	// Skip the version and the originatorInfo ([0] IMPLICIT).
+	if len(fields) > 1 && fields[1].Class == asn1.ClassContextSpecific && fields[1].Tag == 0 {
+		fields = append(fields[:1], fields[2:]...)
+	}
+	if len(fields) < 3 || fields[1].Tag != asn1.TagSet {
+		return nil, nil, errors.New("invalid EnvelopedData")
+	}
+
+	var recipients []cmsKeyTransRecipientInfo
+	for rest := fields[1].Bytes; len(rest) > 0; {
+		var info asn1.RawValue
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &info); err != nil {
+			return nil, nil, fmt.Errorf("decoding RecipientInfo: %w", err)
+		}
+		// Other recipient types (such as key agreement) are tagged.
+		if info.Class != asn1.ClassUniversal || info.Tag != asn1.TagSequence {
+			continue
+		}
+		var ktri cmsKeyTransRecipientInfo
+		if err = unmarshalDER(info.FullBytes, &ktri); err != nil {
+			return nil, nil, fmt.Errorf("decoding KeyTransRecipientInfo: %w", err)
+		}
+		recipients = append(recipients, ktri)
+	}
+	var encrypted cmsEncryptedContentInfo
+	if err := unmarshalDER(fields[2].FullBytes, &encrypted); err != nil {
+		return nil, nil, fmt.Errorf("decoding EncryptedContentInfo: %w", err)
+	}
+	return recipients, &encrypted, nil
+}
+
+func findRecipient(recipients []cmsKeyTransRecipientInfo, cert *x509.Certificate) (*cmsKeyTransRecipientInfo, error) {
+	if cert == nil {
+		if len(recipients) != 1 {
+			return nil, fmt.Errorf("got %d key transport recipients, need a certificate to choose one", len(recipients))
+		}
+		return &recipients[0], nil
+	}
+	for i, recipient := range recipients {
+		rid := recipient.RID
+		switch {
+		case rid.Class == asn1.ClassUniversal && rid.Tag == asn1.TagSequence:
+			var ias cmsIssuerAndSerialNumber
+			if err := unmarshalDER(rid.FullBytes, &ias); err != nil {
+				return nil, fmt.Errorf("decoding IssuerAndSerialNumber: %w", err)
+			}
+			if bytes.Equal(ias.Issuer.FullBytes, cert.RawIssuer) && ias.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				return &recipients[i], nil
+			}
+		case rid.Class == asn1.ClassContextSpecific && rid.Tag == 0:
+			if len(cert.SubjectKeyId) > 0 && bytes.Equal(rid.Bytes, cert.SubjectKeyId) {
+				return &recipients[i], nil
+			}
+		}
+	}
+	return nil, errors.New("no recipient matches the certificate")
+}
+
+// recipientDecrypterOpts returns the DecrypterOpts of the key encryption
+// algorithm. For RSAES-PKCS1-v1_5, invalid paddings give a random key (see
+// rsa.DecryptPKCS1v15SessionKey).
+func recipientDecrypterOpts(alg pkix.AlgorithmIdentifier, keySize int) (crypto.DecrypterOpts, error) {
+	if alg.Algorithm.Equal(oidRSAES) {
+		return &rsa.PKCS1v15DecryptOptions{SessionKeyLen: keySize}, nil
+	}
+	if !alg.Algorithm.Equal(oidRSAESOAEP) {
+		return nil, fmt.Errorf("unsupported key encryption algorithm %v", alg.Algorithm)
+	}
+	var params cmsOAEPParams
+	if len(alg.Parameters.FullBytes) > 0 {
+		if err := unmarshalDER(alg.Parameters.FullBytes, &params); err != nil {
+			return nil, fmt.Errorf("decoding RSAES-OAEP parameters: %w", err)
+		}
+	}
+	hashOID := params.Hash.Algorithm.String()
+	if len(params.Hash.Algorithm) == 0 {
+		hashOID = "1.3.14.3.2.26"
+	}
+	hash, ok := cmsOAEPHashes[hashOID]
+	if !ok {
+		return nil, fmt.Errorf("unsupported RSAES-OAEP hash %s", hashOID)
+	}
+	// The TPM uses MGF1 with the OAEP hash.
+	mgfHash := "1.3.14.3.2.26"
+	if len(params.MGF.Algorithm) > 0 {
+		var mgfHashAlg pkix.AlgorithmIdentifier
+		if !params.MGF.Algorithm.Equal(oidMGF1) || unmarshalDER(params.MGF.Parameters.FullBytes, &mgfHashAlg) != nil {
+			return nil, errors.New("unsupported RSAES-OAEP mask generation function")
+		}
+		mgfHash = mgfHashAlg.Algorithm.String()
+	}
+	if mgfHash != hashOID {
+		return nil, errors.New("RSAES-OAEP mask generation function must use the OAEP hash")
+	}
+	if len(params.PSource.Algorithm) > 0 {
+		var label []byte
+		if !params.PSource.Algorithm.Equal(oidPSpecified) || unmarshalDER(params.PSource.Parameters.FullBytes, &label) != nil || len(label) > 0 {
+			return nil, errors.New("RSAES-OAEP labels are not supported")
+		}
+	}
+	return &rsa.OAEPOptions{Hash: hash}, nil
+}
+
+// octetStringContent returns the content of an [0] IMPLICIT OCTET STRING,
+// which may be constructed from OCTET STRING segments.
+func octetStringContent(value asn1.RawValue) ([]byte, error) {
+	if !value.IsCompound {
+		return value.Bytes, nil
+	}
+	var content []byte
+	for rest := value.Bytes; len(rest) > 0; {
+		var segment []byte
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &segment); err != nil {
+			return nil, fmt.Errorf("decoding encrypted content: %w", err)
+		}
+		content = append(content, segment...)
+	}
+	return content, nil
+}
+
+func decryptAESCBC(key, iv, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid encrypted content size")
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+	// Remove the PKCS #7 padding.
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, errors.New("invalid content padding")
+	}
+	for _, b := range plaintext[len(plaintext)-padding:] {
+		if int(b) != padding {
+			return nil, errors.New("invalid content padding")
+		}
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+// unmarshalDER unmarshals the DER encoded value, which must be all of der.
+func unmarshalDER(der []byte, value interface{}) error {
+	rest, err := asn1.Unmarshal(der, value)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return errors.New("trailing data")
+	}
+	return nil
+}
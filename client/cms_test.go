@@ -0,0 +1,249 @@
+package client_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+type cmsRecipient struct {
+	pub  *rsa.PublicKey
+	rid  asn1.RawValue
+	oaep bool
+}
+
+// envelop creates a DER encoded CMS EnvelopedData of the content, encrypted
+// with AES-256-CBC for the recipients.
+func envelop(t *testing.T, content []byte, recipients ...cmsRecipient) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	iv := make([]byte, aes.BlockSize)
+	rand.Read(key)
+	rand.Read(iv)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	padding := aes.BlockSize - len(content)%aes.BlockSize
+	ciphertext := append(append([]byte{}, content...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, ciphertext)
+
+	type keyTransRecipientInfo struct {
+		Version                int
+		RID                    asn1.RawValue
+		KeyEncryptionAlgorithm pkix.AlgorithmIdentifier
+		EncryptedKey           []byte
+	}
+	var infos []asn1.RawValue
+	for _, recipient := range recipients {
+		info := keyTransRecipientInfo{RID: recipient.rid}
+		if recipient.oaep {
+			params, err := asn1.Marshal(struct {
+				Hash pkix.AlgorithmIdentifier `asn1:"explicit,tag:0"`
+				MGF  pkix.AlgorithmIdentifier `asn1:"explicit,tag:1"`
+			}{
+				pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}},
+				pkix.AlgorithmIdentifier{
+					Algorithm:  asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 8},
+					Parameters: asn1.RawValue{FullBytes: mustMarshal(t, pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}})},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			info.KeyEncryptionAlgorithm = pkix.AlgorithmIdentifier{
+				Algorithm:  asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 7},
+				Parameters: asn1.RawValue{FullBytes: params},
+			}
+			info.EncryptedKey, err = rsa.EncryptOAEP(crypto.SHA256.New(), rand.Reader, recipient.pub, key, nil)
+		} else {
+			info.KeyEncryptionAlgorithm = pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}}
+			info.EncryptedKey, err = rsa.EncryptPKCS1v15(rand.Reader, recipient.pub, key)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		infos = append(infos, asn1.RawValue{FullBytes: mustMarshal(t, info)})
+	}
+
+	envelopedData := struct {
+		Version              int
+		RecipientInfos       []asn1.RawValue `asn1:"set"`
+		EncryptedContentInfo struct {
+			ContentType                asn1.ObjectIdentifier
+			ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+			EncryptedContent           []byte `asn1:"tag:0"`
+		}
+	}{Version: 0, RecipientInfos: infos}
+	envelopedData.EncryptedContentInfo.ContentType = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	envelopedData.EncryptedContentInfo.ContentEncryptionAlgorithm = pkix.AlgorithmIdentifier{
+		Algorithm:  asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42},
+		Parameters: asn1.RawValue{FullBytes: mustMarshal(t, iv)},
+	}
+	envelopedData.EncryptedContentInfo.EncryptedContent = ciphertext
+	return mustMarshal(t, struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}{
+		asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3},
+		asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: mustMarshal(t, envelopedData)},
+	})
+}
+
+func mustMarshal(t *testing.T, value interface{}) []byte {
+	t.Helper()
+	der, err := asn1.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// issueCert returns a certificate for the public key, with a subject key
+// identifier, issued by a test CA.
+func issueCert(t *testing.T, pub crypto.PublicKey, serial int64) *x509.Certificate {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "Device"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		SubjectKeyId: []byte{byte(serial), 1, 2, 3},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caTemplate, pub, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestDecryptEnvelopedData(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	key, err := client.NewKey(rwc, tpm2.HandleOwner, templateDecrypt())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer key.Close()
+	pub := key.PublicKey().(*rsa.PublicKey)
+	cert := issueCert(t, pub, 42)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCert := issueCert(t, &other.PublicKey, 43)
+
+	issuerAndSerial := func(cert *x509.Certificate) asn1.RawValue {
+		return asn1.RawValue{FullBytes: mustMarshal(t, struct {
+			Issuer       asn1.RawValue
+			SerialNumber *big.Int
+		}{asn1.RawValue{FullBytes: cert.RawIssuer}, cert.SerialNumber})}
+	}
+	subjectKeyID := func(cert *x509.Certificate) asn1.RawValue {
+		return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: cert.SubjectKeyId}
+	}
+
+	content := []byte("payload for the device")
+	tests := []struct {
+		name       string
+		cert       *x509.Certificate
+		recipients []cmsRecipient
+	}{
+		{"PKCS1v15", nil, []cmsRecipient{{pub, issuerAndSerial(cert), false}}},
+		{"OAEP", nil, []cmsRecipient{{pub, issuerAndSerial(cert), true}}},
+		{"IssuerAndSerialNumber", cert, []cmsRecipient{
+			{&other.PublicKey, issuerAndSerial(otherCert), false},
+			{pub, issuerAndSerial(cert), false},
+		}},
+		{"SubjectKeyIdentifier", cert, []cmsRecipient{
+			{&other.PublicKey, subjectKeyID(otherCert), true},
+			{pub, subjectKeyID(cert), true},
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data := envelop(t, content, tc.recipients...)
+			got, err := key.DecryptEnvelopedData(data, tc.cert)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("got content %q, want %q", got, content)
+			}
+		})
+	}
+
+	encoded := pem.EncodeToMemory(&pem.Block{Type: "CMS", Bytes: envelop(t, content, cmsRecipient{pub, subjectKeyID(cert), true})})
+	if got, err := key.DecryptEnvelopedData(encoded, nil); err != nil || !bytes.Equal(got, content) {
+		t.Errorf("failed to decrypt PEM encoded EnvelopedData: %q, %v", got, err)
+	}
+}
+
+func TestDecryptEnvelopedDataFails(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	key, err := client.NewKey(rwc, tpm2.HandleOwner, templateDecrypt())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer key.Close()
+	pub := key.PublicKey().(*rsa.PublicKey)
+	cert := issueCert(t, pub, 42)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCert := issueCert(t, &other.PublicKey, 43)
+	rid := asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: otherCert.SubjectKeyId}
+
+	content := []byte("payload for another device")
+	data := envelop(t, content, cmsRecipient{&other.PublicKey, rid, true})
+	if _, err = key.DecryptEnvelopedData(data, cert); err == nil {
+		t.Error("expected decrypting without a matching recipient to fail")
+	}
+	if _, err = key.DecryptEnvelopedData(data, nil); err == nil {
+		t.Error("expected decrypting for another key to fail")
+	}
+	two := envelop(t, content, cmsRecipient{&other.PublicKey, rid, true}, cmsRecipient{pub, rid, true})
+	if _, err = key.DecryptEnvelopedData(two, nil); err == nil {
+		t.Error("expected decrypting with several recipients and no certificate to fail")
+	}
+	if _, err = key.DecryptEnvelopedData([]byte("not CMS"), nil); err == nil {
+		t.Error("expected decrypting invalid data to fail")
+	}
+}
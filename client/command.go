@@ -0,0 +1,118 @@
+package client
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// runCommand executes a TPM command that go-tpm does not (fully) support.
+// The command is built from the handle area, the authorization area (one
+// entry per element of auths) and the parameter area. If auths is empty, the
+// command is sent without sessions. The returned bytes are the response
+// parameters, without the parameter size or the response authorization area.
+// For commands returning handles, use runCommandWithHandle.
+func runCommand(rw io.ReadWriter, cmd tpmutil.Command, handles []tpmutil.Handle, auths []tpm2.AuthCommand, params ...interface{}) ([]byte, error) {
+	paramArea, _, err := runCommandWithAuthArea(rw, cmd, handles, auths, params...)
+	return paramArea, err
+}
+
+// runCommandWithAuthArea is like runCommand, but also returns the response
+// authorization area (one entry per element of auths).
+func runCommandWithAuthArea(rw io.ReadWriter, cmd tpmutil.Command, handles []tpmutil.Handle, auths []tpm2.AuthCommand, params ...interface{}) (paramArea, authArea []byte, err error) {
+	resp, err := execute(rw, cmd, handles, auths, params...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(auths) == 0 {
+		return resp, nil, nil
+	}
+	return splitResponse(resp)
+}
+
+// runCommandWithHandle is like runCommand, for commands returning a handle.
+func runCommandWithHandle(rw io.ReadWriter, cmd tpmutil.Command, handles []tpmutil.Handle, auths []tpm2.AuthCommand, params ...interface{}) (tpmutil.Handle, []byte, error) {
+	resp, err := execute(rw, cmd, handles, auths, params...)
+	if err != nil {
+		return 0, nil, err
+	}
+	var handle tpmutil.Handle
+	read, err := tpmutil.Unpack(resp, &handle)
+	if err != nil {
+		return 0, nil, fmt.Errorf("decoding response handle: %w", err)
+	}
+	if len(auths) == 0 {
+		return handle, resp[read:], nil
+	}
+	paramArea, _, err := splitResponse(resp[read:])
+	return handle, paramArea, err
+}
+
+func execute(rw io.ReadWriter, cmd tpmutil.Command, handles []tpmutil.Handle, auths []tpm2.AuthCommand, params ...interface{}) ([]byte, error) {
+	var in []interface{}
+	for _, h := range handles {
+		in = append(in, h)
+	}
+	tag := tpm2.TagNoSessions
+	if len(auths) > 0 {
+		tag = tpm2.TagSessions
+		var authArea tpmutil.RawBytes
+		for _, auth := range auths {
+			buf, err := tpmutil.Pack(auth)
+			if err != nil {
+				return nil, err
+			}
+			authArea = append(authArea, buf...)
+		}
+		in = append(in, tpmutil.U32Bytes(authArea))
+	}
+	in = append(in, params...)
+
+	resp, code, err := tpmutil.RunCommand(rw, tag, cmd, in...)
+	if err != nil {
+		return nil, err
+	}
+	return resp, tpmError(decodeResponse(code))
+}
+
+// splitResponse splits the parameter area of a response with sessions from
+// the response authorization area.
+func splitResponse(resp []byte) (paramArea, authArea []byte, err error) {
+	var respParams tpmutil.U32Bytes
+	read, err := tpmutil.Unpack(resp, &respParams)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding response parameters: %w", err)
+	}
+	return respParams, resp[read:], nil
+}
+
+// decodeResponse converts a TPM response code into the matching go-tpm error
+// type, as done internally by go-tpm.
+func decodeResponse(code tpmutil.ResponseCode) error {
+	if code == tpmutil.RCSuccess {
+		return nil
+	}
+	if code&0x180 == 0 { // Bits 7:8 == 0 is a TPM1 error
+		return fmt.Errorf("response status 0x%x", code)
+	}
+	if code&0x80 == 0 { // Bit 7 unset
+		if code&0x400 > 0 { // Bit 10 set, vendor specific code
+			return tpm2.VendorError{Code: uint32(code)}
+		}
+		if code&0x800 > 0 { // Bit 11 set, warning with code in bit 0:6
+			return tpm2.Warning{Code: tpm2.RCWarn(code & 0x7f)}
+		}
+		// error with code in bit 0:6
+		return tpm2.Error{Code: tpm2.RCFmt0(code & 0x7f)}
+	}
+	if code&0x40 > 0 { // Bit 6 set, code in 0:5, parameter number in 8:11
+		return tpm2.ParameterError{Code: tpm2.RCFmt1(code & 0x3f), Parameter: tpm2.RCIndex((code & 0xf00) >> 8)}
+	}
+	if code&0x800 == 0 { // Bit 11 unset, code in 0:5, handle in 8:10
+		return tpm2.HandleError{Code: tpm2.RCFmt1(code & 0x3f), Handle: tpm2.RCIndex((code & 0x700) >> 8)}
+	}
+	// Code in 0:5, Session in 8:10
+	return tpm2.SessionError{Code: tpm2.RCFmt1(code & 0x3f), Session: tpm2.RCIndex((code & 0x700) >> 8)}
+}
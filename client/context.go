@@ -0,0 +1,47 @@
+package client
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+)
+
+// ContextSave saves the context of a transient key, returning an encrypted
+// blob which can be passed to LoadKeyContext. This allows long-running
+// processes to free TPM memory by calling Close() on the key, and to restore
+// the key later without recreating it.
+//
+// The context can only be loaded into the same TPM, until the TPM is reset
+// (e.g. by a reboot). The key's certificate (see SetCert) is not saved.
+func (k *Key) ContextSave() ([]byte, error) {
+	if isPersistent(k.handle) {
+		return nil, fmt.Errorf("cannot save the context of persistent key 0x%x", k.handle)
+	}
+	context, err := tpm2.ContextSave(k.rw, k.handle)
+	if err != nil {
+		return nil, fmt.Errorf("saving key context: %w", tpmError(err))
+	}
+	return context, nil
+}
+
+// LoadKeyContext loads a key context saved with Key.ContextSave, returning the
+// restored Key. As with NewKey, the key must either have an empty auth policy
+// or the default EK auth policy.
+func LoadKeyContext(rw io.ReadWriter, context []byte) (k *Key, err error) {
+	handle, err := tpm2.ContextLoad(rw, context)
+	if err != nil {
+		return nil, fmt.Errorf("loading key context: %w", tpmError(err))
+	}
+	defer func() {
+		if err != nil {
+			tpm2.FlushContext(rw, handle)
+		}
+	}()
+
+	k = &Key{rw: rw, handle: handle}
+	if k.pubArea, _, _, err = tpm2.ReadPublic(rw, handle); err != nil {
+		return nil, fmt.Errorf("reading public area: %w", tpmError(err))
+	}
+	return k, k.finish()
+}
@@ -0,0 +1,81 @@
+package client_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestContextSaveLoad(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ak, err := client.NewKey(rwc, tpm2.HandleOwner, client.AKTemplateECC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	context, err := ak.ContextSave()
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := ak.Name()
+	ak.Close()
+
+	handles, err := client.Handles(rwc, tpm2.HandleTypeTransient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(handles) != 0 {
+		t.Fatalf("got %d transient handles after Close, want 0", len(handles))
+	}
+
+	restored, err := client.LoadKeyContext(rwc, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+	if !reflect.DeepEqual(restored.Name(), name) {
+		t.Errorf("restored key has name %v, want %v", restored.Name(), name)
+	}
+	if _, err = restored.Quote(tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{7}}, []byte("nonce")); err != nil {
+		t.Errorf("failed to quote with restored key: %v", err)
+	}
+
+	signingKey, err := client.NewKey(rwc, tpm2.HandleOwner, templateECC(tpm2.AlgSHA256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	context, err = signingKey.ContextSave()
+	signingKey.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored2, err := client.LoadKeyContext(rwc, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored2.Close()
+	signer, err := restored2.GetSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte("message"))
+	sig, err := signer.Sign(nil, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ecdsa.VerifyASN1(restored2.PublicKey().(*ecdsa.PublicKey), digest[:], sig) {
+		t.Error("signature from restored key failed to verify")
+	}
+
+	if _, err = client.LoadKeyContext(rwc, []byte("not a context")); err == nil {
+		t.Error("expected failure loading an invalid context")
+	}
+}
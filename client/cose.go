@@ -0,0 +1,132 @@
+package client
+
+import (
+	"crypto"
+	"fmt"
+	"time"
+
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+	"google.golang.org/protobuf/proto"
+)
+
+// EATProfile is the EAT profile (RFC 9711, section 6) of the tokens created by
+// COSESigner.SignEAT.
+const EATProfile = "tag:github.com,2021:ThalesIgnite/go-tpm-tools/eat"
+
+// The claim keys of the EATs created by COSESigner.SignEAT: the issued at
+// claim (RFC 8392), the nonce and profile claims (RFC 9711), and a private
+// claim containing the binary encoded Attestation.
+const (
+	EATClaimIssuedAt    = 6
+	EATClaimNonce       = 10
+	EATClaimProfile     = 265
+	EATClaimAttestation = -65537
+)
+
+// The size limits of the EAT nonce claim (RFC 9711, section 4.1).
+const (
+	minEATNonceSize = 8
+	maxEATNonceSize = 64
+)
+
+// COSESigner signs COSE_Sign1 messages (RFC 9052) and Entity Attestation
+// Tokens (RFC 9711) with a TPM key. This allows the evidence from Attest to be
+// consumed by RATS verifiers and constrained devices using CBOR.
+type COSESigner struct {
+	key *Key
+	alg int64
+}
+
+// COSESigner returns a COSESigner for the key. The COSE algorithm is given by
+// the key's signing scheme, as for JWSSigner: RSASSA gives RS256, RS384 or
+// RS512, RSAPSS gives PS256, PS384 or PS512, and ECDSA (on the matching curve)
+// gives ES256, ES384 or ES512. Restricted keys (such as AKs) can also be used.
+func (k *Key) COSESigner() (*COSESigner, error) {
+	scheme, err := getSigningScheme(k)
+	if err != nil {
+		return nil, err
+	}
+	for alg, coseAlg := range notinternal.COSEAlgorithms {
+		if coseAlg.Scheme != scheme.Alg || coseAlg.Hash != scheme.Hash {
+			continue
+		}
+		if coseAlg.Curve != 0 && k.pubArea.ECCParameters.CurveID != coseAlg.Curve {
+			return nil, fmt.Errorf("COSE algorithm %d cannot be used with curve %v", alg, k.pubArea.ECCParameters.CurveID)
+		}
+		return &COSESigner{key: k, alg: alg}, nil
+	}
+	return nil, fmt.Errorf("unsupported signing scheme for COSE: %v with %v", scheme.Alg, scheme.Hash)
+}
+
+// Algorithm returns the COSE algorithm identifier of the signer.
+func (s *COSESigner) Algorithm() int64 {
+	return s.alg
+}
+
+// KeyID returns the COSE key ID of the signer: the encoded Name of the key.
+func (s *COSESigner) KeyID() ([]byte, error) {
+	return s.key.name.Digest.Encode()
+}
+
+// Public returns the public key of the signer.
+func (s *COSESigner) Public() crypto.PublicKey {
+	return s.key.PublicKey()
+}
+
+// Sign1 returns the tagged COSE_Sign1 message of payload, signed by the
+// signer, with the algorithm in the protected header and the key ID in the
+// unprotected header.
+func (s *COSESigner) Sign1(payload []byte) ([]byte, error) {
+	kid, err := s.KeyID()
+	if err != nil {
+		return nil, err
+	}
+	protected, err := notinternal.COSEProtectedHeader(s.alg)
+	if err != nil {
+		return nil, err
+	}
+	toBeSigned, err := notinternal.COSESign1ToBeSigned(protected, payload)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := s.key.signDataConcat(toBeSigned)
+	if err != nil {
+		return nil, err
+	}
+	return notinternal.EncodeCOSESign1(&notinternal.COSESign1{
+		Protected: protected,
+		Algorithm: s.alg,
+		KeyID:     kid,
+		Payload:   payload,
+		Signature: sig,
+	})
+}
+
+// SignEAT returns an Entity Attestation Token (a CWT signed with Sign1) for
+// the Attestation, as returned by Key.Attest. The nonce, which must be between
+// 8 and 64 bytes, should be the nonce passed to Attest. The token contains the
+// nonce, the current time, the EATProfile and the binary encoded Attestation
+// (in the EATClaimAttestation claim).
+//
+// The token should be signed by the AK which created the Attestation, as
+// server.VerifyEAT verifies the token with the AK in the Attestation.
+func (s *COSESigner) SignEAT(attestation *pb.Attestation, nonce []byte) ([]byte, error) {
+	if len(nonce) < minEATNonceSize || len(nonce) > maxEATNonceSize {
+		return nil, fmt.Errorf("EAT nonce must be between %d and %d bytes, got %d", minEATNonceSize, maxEATNonceSize, len(nonce))
+	}
+	encoded, err := proto.Marshal(attestation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode attestation: %w", err)
+	}
+	claims, err := notinternal.MarshalCBOR(map[int64]interface{}{
+		EATClaimIssuedAt:    time.Now().Unix(),
+		EATClaimNonce:       nonce,
+		EATClaimProfile:     EATProfile,
+		EATClaimAttestation: encoded,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode claims: %w", err)
+	}
+	return s.Sign1(claims)
+}
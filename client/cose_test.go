@@ -0,0 +1,136 @@
+package client_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"math/big"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+func TestCOSESigner(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	pssTemplate := client.AKTemplateRSA()
+	pssTemplate.Attributes &^= tpm2.FlagRestricted
+	pssTemplate.RSAParameters.Sign.Alg = tpm2.AlgRSAPSS
+	p384Template, err := client.AKTemplateECCWithCurve(tpm2.CurveNISTP384)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		template tpm2.Public
+		alg      int64
+		hash     crypto.Hash
+	}{
+		{"RS256", client.AKTemplateRSA(), -257, crypto.SHA256},
+		{"PS256", pssTemplate, -37, crypto.SHA256},
+		{"ES256", client.AKTemplateECC(), -7, crypto.SHA256},
+		{"ES384", p384Template, -35, crypto.SHA384},
+	}
+	// Larger than the TPM's maximum buffer, so restricted keys hash it with a
+	// hash sequence.
+	payload := bytes.Repeat([]byte("payload"), 500)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key, err := client.NewKey(rwc, tpm2.HandleOwner, test.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer key.Close()
+			signer, err := key.COSESigner()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if signer.Algorithm() != test.alg {
+				t.Errorf("got alg %d, want %d", signer.Algorithm(), test.alg)
+			}
+
+			encoded, err := signer.Sign1(payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg, err := notinternal.DecodeCOSESign1(encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			name, err := key.Name().Digest.Encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Algorithm != test.alg || !bytes.Equal(msg.KeyID, name) || !bytes.Equal(msg.Payload, payload) {
+				t.Errorf("unexpected COSE_Sign1: alg %d, kid %x", msg.Algorithm, msg.KeyID)
+			}
+
+			toBeSigned, err := notinternal.COSESign1ToBeSigned(msg.Protected, msg.Payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			hasher := test.hash.New()
+			hasher.Write(toBeSigned)
+			digest := hasher.Sum(nil)
+			switch pub := key.PublicKey().(type) {
+			case *rsa.PublicKey:
+				if test.alg == -37 {
+					err = rsa.VerifyPSS(pub, test.hash, digest, msg.Signature, nil)
+				} else {
+					err = rsa.VerifyPKCS1v15(pub, test.hash, digest, msg.Signature)
+				}
+			case *ecdsa.PublicKey:
+				r := new(big.Int).SetBytes(msg.Signature[:len(msg.Signature)/2])
+				s := new(big.Int).SetBytes(msg.Signature[len(msg.Signature)/2:])
+				if !ecdsa.Verify(pub, digest, r, s) {
+					err = rsa.ErrVerification
+				}
+			}
+			if err != nil {
+				t.Errorf("COSE_Sign1 signature does not verify: %v", err)
+			}
+		})
+	}
+}
+
+func TestCOSESignerUnsupported(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	// ES256 requires the P-256 curve.
+	template, err := client.AKTemplateECCWithCurve(tpm2.CurveNISTP384)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.ECCParameters.Sign.Hash = tpm2.AlgSHA256
+	key, err := client.NewKey(rwc, tpm2.HandleOwner, template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer key.Close()
+	if _, err = key.COSESigner(); err == nil {
+		t.Error("COSESigner should fail for a P-384 key using SHA-256")
+	}
+
+	ak, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ak.Close()
+	signer, err := ak.COSESigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, nonce := range [][]byte{[]byte("short"), bytes.Repeat([]byte("n"), 65)} {
+		if _, err = signer.SignEAT(&pb.Attestation{}, nonce); err == nil {
+			t.Errorf("SignEAT should fail for a nonce of %d bytes", len(nonce))
+		}
+	}
+}
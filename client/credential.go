@@ -0,0 +1,33 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/go-tpm/tpm2"
+)
+
+// ActivateCredential decrypts a secret that was encrypted to the provided EK
+// using server.MakeCredential. The TPM will only decrypt the secret if the
+// name passed to MakeCredential matches the name of this key (typically an
+// AK). This allows a remote server to check that an AK is resident on the
+// same TPM as a trusted EK.
+func (k *Key) ActivateCredential(ek *Key, credBlob, encSecret []byte) ([]byte, error) {
+	if ek == nil {
+		return nil, errors.New("an EK must be provided to activate a credential")
+	}
+	akAuth, err := k.session.Auth()
+	if err != nil {
+		return nil, err
+	}
+	ekAuth, err := ek.session.Auth()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := tpm2.ActivateCredentialUsingAuth(k.rw, []tpm2.AuthCommand{akAuth, ekAuth},
+		k.Handle(), ek.Handle(), credBlob, encSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to activate credential: %w", tpmError(err))
+	}
+	return secret, nil
+}
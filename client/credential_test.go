@@ -0,0 +1,80 @@
+package client_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	"github.com/ThalesIgnite/go-tpm-tools/server"
+)
+
+func TestActivateCredential(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	eks := []struct {
+		name   string
+		getKey func(io.ReadWriter) (*client.Key, error)
+	}{
+		{"RSA", client.EndorsementKeyRSA},
+		{"ECC", client.EndorsementKeyECC},
+	}
+	for _, e := range eks {
+		t.Run(e.name, func(t *testing.T) {
+			ek, err := e.getKey(rwc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ek.Close()
+			ak, err := client.AttestationKeyRSA(rwc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ak.Close()
+
+			secret := []byte("super secret credential")
+			credBlob, encSecret, err := server.MakeCredential(ek.PublicKey(), ak.Name(), secret)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ak.ActivateCredential(ek, credBlob, encSecret)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, secret) {
+				t.Errorf("got secret %q, want %q", got, secret)
+			}
+		})
+	}
+}
+
+func TestActivateCredentialWrongKey(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ek, err := client.EndorsementKeyRSA(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ek.Close()
+	ak, err := client.AttestationKeyRSA(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ak.Close()
+	otherAK, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer otherAK.Close()
+
+	credBlob, encSecret, err := server.MakeCredential(ek.PublicKey(), otherAK.Name(), []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ak.ActivateCredential(ek, credBlob, encSecret); err == nil {
+		t.Error("expected failure when activating a credential for a different key")
+	}
+}
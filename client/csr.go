@@ -0,0 +1,64 @@
+package client
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/google/go-tpm/tpm2"
+)
+
+// The X.509 signature algorithms matching each supported TPM signing scheme.
+var x509SignatureAlgorithms = map[tpm2.Algorithm]map[tpm2.Algorithm]x509.SignatureAlgorithm{
+	tpm2.AlgRSASSA: {
+		tpm2.AlgSHA256: x509.SHA256WithRSA,
+		tpm2.AlgSHA384: x509.SHA384WithRSA,
+		tpm2.AlgSHA512: x509.SHA512WithRSA,
+	},
+	tpm2.AlgRSAPSS: {
+		tpm2.AlgSHA256: x509.SHA256WithRSAPSS,
+		tpm2.AlgSHA384: x509.SHA384WithRSAPSS,
+		tpm2.AlgSHA512: x509.SHA512WithRSAPSS,
+	},
+	tpm2.AlgECDSA: {
+		tpm2.AlgSHA256: x509.ECDSAWithSHA256,
+		tpm2.AlgSHA384: x509.ECDSAWithSHA384,
+		tpm2.AlgSHA512: x509.ECDSAWithSHA512,
+	},
+}
+
+// CreateCSR returns a PEM-encoded PKCS #10 certificate signing request for the
+// key, with the provided subject and extensions (such as a subjectAltName),
+// signed by the key itself. This can be used to enroll the key with a CA (for
+// example, using EST, SCEP or ACME) as a device identity.
+//
+// The key must be an unrestricted signing key using RSASSA, RSAPSS or ECDSA
+// (such as a key created by NewKeyWithOpts with tpm2.FlagSign and without
+// tpm2.FlagRestricted).
+func (k *Key) CreateCSR(subject pkix.Name, extensions []pkix.Extension) ([]byte, error) {
+	signer, err := k.GetSigner()
+	if err != nil {
+		return nil, err
+	}
+	scheme, err := getSigningScheme(k)
+	if err != nil {
+		return nil, err
+	}
+	sigAlg, ok := x509SignatureAlgorithms[scheme.Alg][scheme.Hash]
+	if !ok {
+		return nil, fmt.Errorf("unsupported signing scheme for CSR: %v with %v", scheme.Alg, scheme.Hash)
+	}
+
+	template := &x509.CertificateRequest{
+		Subject:            subject,
+		ExtraExtensions:    extensions,
+		SignatureAlgorithm: sigAlg,
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CSR: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), nil
+}
@@ -0,0 +1,82 @@
+package client_test
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestCreateCSR(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	subject := pkix.Name{CommonName: "device", Organization: []string{"Example"}}
+	extension := pkix.Extension{Id: asn1.ObjectIdentifier{1, 2, 3, 4}, Value: []byte{0x05, 0x00}}
+	for _, opts := range []client.KeyOpts{
+		{Algorithm: tpm2.AlgRSA},
+		{Algorithm: tpm2.AlgRSA, SignScheme: &tpm2.SigScheme{Alg: tpm2.AlgRSAPSS, Hash: tpm2.AlgSHA384}},
+		{Algorithm: tpm2.AlgECC},
+		{Algorithm: tpm2.AlgECC, Curve: tpm2.CurveNISTP384},
+	} {
+		func() {
+			opts.Attributes = tpm2.FlagSign | tpm2.FlagFixedTPM | tpm2.FlagFixedParent |
+				tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth
+			key, err := client.NewKeyWithOpts(rwc, tpm2.HandleOwner, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer key.Close()
+
+			csrPEM, err := key.CreateCSR(subject, []pkix.Extension{extension})
+			if err != nil {
+				t.Fatalf("failed to create CSR: %v", err)
+			}
+			block, _ := pem.Decode(csrPEM)
+			if block == nil || block.Type != "CERTIFICATE REQUEST" {
+				t.Fatalf("CSR is not a PEM certificate request: %s", csrPEM)
+			}
+			csr, err := x509.ParseCertificateRequest(block.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = csr.CheckSignature(); err != nil {
+				t.Errorf("CSR signature is invalid: %v", err)
+			}
+			if !reflect.DeepEqual(csr.PublicKey, key.PublicKey()) {
+				t.Error("CSR public key does not match the key")
+			}
+			if csr.Subject.CommonName != subject.CommonName {
+				t.Errorf("got CSR common name %q, want %q", csr.Subject.CommonName, subject.CommonName)
+			}
+			found := false
+			for _, ext := range csr.Extensions {
+				found = found || ext.Id.Equal(extension.Id)
+			}
+			if !found {
+				t.Error("CSR does not contain the extension")
+			}
+		}()
+	}
+}
+
+func TestCreateCSRRestrictedKey(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ak, err := client.AttestationKeyRSA(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ak.Close()
+	if _, err = ak.CreateCSR(pkix.Name{CommonName: "ak"}, nil); err == nil {
+		t.Error("creating a CSR with a restricted key should fail")
+	}
+}
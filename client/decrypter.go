@@ -0,0 +1,119 @@
+package client
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+type tpmDecrypter struct {
+	Key *Key
+}
+
+// Public returns the tpmDecrypters public key.
+func (decrypter *tpmDecrypter) Public() crypto.PublicKey {
+	return decrypter.Key.PublicKey()
+}
+
+// Decrypt uses the TPM key to decrypt msg. The opts must either be nil (in
+// which case PKCS #1 v1.5 decryption is used), an *rsa.PKCS1v15DecryptOptions
+// or an *rsa.OAEPOptions. If the key has a non-null decryption scheme, opts
+// must match that scheme.
+// The TPM appends a null byte to any non-empty OAEP label, so a non-empty
+// OAEPOptions.Label must end with a null byte (and the data must have been
+// encrypted with that label).
+// Concurrent use of Decrypt is thread safe, but it is not safe to access the
+// TPM from other sources while Decrypt is executing.
+func (decrypter *tpmDecrypter) Decrypt(rand io.Reader, msg []byte, opts crypto.DecrypterOpts) (plaintext []byte, err error) {
+	scheme := &tpm2.AsymScheme{Alg: tpm2.AlgRSAES}
+	label := ""
+	sessionKeyLen := 0
+	switch o := opts.(type) {
+	case nil:
+	case *rsa.PKCS1v15DecryptOptions:
+		sessionKeyLen = o.SessionKeyLen
+	case *rsa.OAEPOptions:
+		hashAlg, err := tpm2.HashToAlgorithm(o.Hash)
+		if err != nil {
+			return nil, err
+		}
+		scheme = &tpm2.AsymScheme{Alg: tpm2.AlgOAEP, Hash: hashAlg}
+		if len(o.Label) > 0 {
+			if o.Label[len(o.Label)-1] != 0 {
+				return nil, fmt.Errorf("invalid options: OAEP label must end with a null byte")
+			}
+			label = string(o.Label[:len(o.Label)-1])
+		}
+	default:
+		return nil, fmt.Errorf("invalid options: unsupported type %T", opts)
+	}
+
+	signerMutex.Lock()
+	defer signerMutex.Unlock()
+
+	plaintext, err = decrypter.Key.rsaDecrypt(msg, scheme, label)
+	if err != nil && sessionKeyLen > 0 {
+		// Match rsa.DecryptPKCS1v15SessionKey, returning a random key on
+		// failure instead of reporting whether the padding was valid.
+		plaintext = make([]byte, sessionKeyLen)
+		if _, err = io.ReadFull(rand, plaintext); err != nil {
+			return nil, err
+		}
+		return plaintext, nil
+	}
+	return plaintext, err
+}
+
+// GetDecrypter returns a crypto.Decrypter wrapping the loaded TPM Key. Only
+// unrestricted RSA decryption keys are supported. For keys with an
+// authorization policy (such as imported keys bound to PCRs), the policy is
+// satisfied on each call to Decrypt.
+// Concurrent use of one or more Decrypters is thread safe, but it is not safe
+// to access the TPM from other sources while using a Decrypter.
+// The returned Decrypter lasts the lifetime of the Key, and will no longer
+// work once the Key has been closed.
+func (k *Key) GetDecrypter() (crypto.Decrypter, error) {
+	if k.pubArea.Type != tpm2.AlgRSA {
+		return nil, fmt.Errorf("unsupported key type: %v", k.pubArea.Type)
+	}
+	if !k.hasAttribute(tpm2.FlagDecrypt) {
+		return nil, fmt.Errorf("non-decryption key used with decryption operation")
+	}
+	if k.hasAttribute(tpm2.FlagRestricted) {
+		return nil, fmt.Errorf("restricted keys are not supported")
+	}
+	return &tpmDecrypter{k}, nil
+}
+
+// rsaDecrypt runs TPM2_RSA_Decrypt, authorizing use of the key with the key's
+// session (unlike tpm2.RSADecrypt, which only supports password auth).
+func (k *Key) rsaDecrypt(msg []byte, scheme *tpm2.AsymScheme, label string) ([]byte, error) {
+	encScheme, err := tpmutil.Pack(scheme.Alg)
+	if scheme.Alg.UsesHash() {
+		encScheme, err = tpmutil.Pack(scheme.Alg, scheme.Hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if label != "" {
+		label += "\x00"
+	}
+	auth, err := k.session.Auth()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := runCommand(k.rw, tpm2.CmdRSADecrypt, []tpmutil.Handle{k.handle}, []tpm2.AuthCommand{auth},
+		tpmutil.U16Bytes(msg), tpmutil.RawBytes(encScheme), tpmutil.U16Bytes(label))
+	if err != nil {
+		return nil, err
+	}
+	var plaintext tpmutil.U16Bytes
+	if _, err = tpmutil.Unpack(resp, &plaintext); err != nil {
+		return nil, fmt.Errorf("decoding TPM2_RSA_Decrypt response: %w", err)
+	}
+	return plaintext, nil
+}
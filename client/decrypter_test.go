@@ -0,0 +1,145 @@
+package client_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	"github.com/google/go-tpm/tpm2"
+)
+
+func templateDecrypt() tpm2.Public {
+	return tpm2.Public{
+		Type:    tpm2.AlgRSA,
+		NameAlg: tpm2.AlgSHA256,
+		Attributes: tpm2.FlagFixedTPM | tpm2.FlagFixedParent | tpm2.FlagSensitiveDataOrigin |
+			tpm2.FlagUserWithAuth | tpm2.FlagDecrypt,
+		RSAParameters: &tpm2.RSAParams{
+			KeyBits: 2048,
+		},
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	key, err := client.NewKey(rwc, tpm2.HandleOwner, templateDecrypt())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer key.Close()
+
+	decrypter, err := key.GetDecrypter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := decrypter.Public().(*rsa.PublicKey)
+	secret := []byte("wrapped data encryption key")
+
+	encryptOAEP := func(label []byte) func() ([]byte, error) {
+		return func() ([]byte, error) {
+			return rsa.EncryptOAEP(crypto.SHA256.New(), rand.Reader, pub, secret, label)
+		}
+	}
+	encryptPKCS1v15 := func() ([]byte, error) {
+		return rsa.EncryptPKCS1v15(rand.Reader, pub, secret)
+	}
+
+	tests := []struct {
+		name    string
+		encrypt func() ([]byte, error)
+		opts    crypto.DecrypterOpts
+	}{
+		{"OAEP", encryptOAEP(nil), &rsa.OAEPOptions{Hash: crypto.SHA256}},
+		{"OAEP-Label", encryptOAEP([]byte("label\x00")), &rsa.OAEPOptions{Hash: crypto.SHA256, Label: []byte("label\x00")}},
+		{"PKCS1v15", encryptPKCS1v15, &rsa.PKCS1v15DecryptOptions{}},
+		{"PKCS1v15-NilOpts", encryptPKCS1v15, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ciphertext, err := tc.encrypt()
+			if err != nil {
+				t.Fatal(err)
+			}
+			plaintext, err := decrypter.Decrypt(rand.Reader, ciphertext, tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(plaintext, secret) {
+				t.Errorf("got plaintext %q, want %q", plaintext, secret)
+			}
+		})
+	}
+}
+
+func TestDecryptFailures(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	key, err := client.NewKey(rwc, tpm2.HandleOwner, templateDecrypt())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer key.Close()
+
+	decrypter, err := key.GetDecrypter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := decrypter.Public().(*rsa.PublicKey)
+	ciphertext, err := rsa.EncryptOAEP(crypto.SHA256.New(), rand.Reader, pub, []byte("secret"), []byte("label"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = decrypter.Decrypt(rand.Reader, ciphertext, &rsa.OAEPOptions{Hash: crypto.SHA256, Label: []byte("label")}); err == nil {
+		t.Error("expected failure for OAEP label without a null terminator")
+	}
+	if _, err = decrypter.Decrypt(rand.Reader, ciphertext, &rsa.OAEPOptions{Hash: crypto.SHA256}); err == nil {
+		t.Error("expected failure for incorrect OAEP label")
+	}
+
+	// Failing PKCS #1 v1.5 session key decryption returns a random key.
+	sessionKey, err := decrypter.Decrypt(rand.Reader, ciphertext, &rsa.PKCS1v15DecryptOptions{SessionKeyLen: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessionKey) != 16 {
+		t.Errorf("got session key of length %d, want 16", len(sessionKey))
+	}
+}
+
+func TestFailGetDecrypter(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	restricted := templateDecrypt()
+	restricted.Attributes |= tpm2.FlagRestricted
+	restricted.RSAParameters.Symmetric = &tpm2.SymScheme{Alg: tpm2.AlgAES, KeyBits: 128, Mode: tpm2.AlgCFB}
+	templates := []struct {
+		name     string
+		template tpm2.Public
+	}{
+		{"Signing", templateSSA(tpm2.AlgSHA256)},
+		{"ECC", client.SRKTemplateECC()},
+		{"Restricted", restricted},
+		{"Auth", client.DefaultEKTemplateRSA()},
+	}
+	for _, tc := range templates {
+		t.Run(tc.name, func(t *testing.T) {
+			key, err := client.NewKey(rwc, tpm2.HandleEndorsement, tc.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer key.Close()
+
+			if _, err = key.GetDecrypter(); err == nil {
+				t.Error("expected failure when calling GetDecrypter")
+			}
+		})
+	}
+}
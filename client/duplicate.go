@@ -0,0 +1,108 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+const cmdDuplicate tpmutil.Command = 0x0000014B
+
+// DuplicationPolicy returns the auth policy of keys created by
+// NewDuplicableKey. It only authorizes TPM2_Duplicate, and requires the
+// authorization value of the key.
+func DuplicationPolicy() Policy {
+	return PolicySequence{PolicyCommandCode{cmdDuplicate}, PolicyAuthValue{}}
+}
+
+// NewDuplicableKey creates a key from the template described by opts (see
+// KeyOpts.Template) as a child of the storage key parent (such as an SRK),
+// and loads it. Unlike other keys, the key is not bound to this TPM: it can
+// be moved to another TPM with Key.Duplicate (for example, so that a service
+// identity survives failing over to another machine).
+//
+// FlagFixedTPM and FlagFixedParent are removed from the key's attributes, and
+// FlagUserWithAuth is added, so that the key is used with opts.Auth. The key's
+// auth policy is DuplicationPolicy, so opts.AuthPolicy must not be set. As
+// anyone knowing opts.Auth can duplicate the key, it should be a strong secret.
+func NewDuplicableKey(parent *Key, opts KeyOpts) (key *Key, err error) {
+	if opts.AuthPolicy != nil {
+		return nil, fmt.Errorf("duplicable keys cannot have a custom auth policy")
+	}
+	template, err := opts.Template()
+	if err != nil {
+		return nil, err
+	}
+	template.Attributes &^= tpm2.FlagFixedTPM | tpm2.FlagFixedParent
+	template.Attributes |= tpm2.FlagUserWithAuth
+	if template.AuthPolicy, err = PolicyDigest(DuplicationPolicy(), SessionHashAlg); err != nil {
+		return nil, err
+	}
+
+	child, err := parent.createChild(template, opts)
+	if err != nil {
+		return nil, err
+	}
+	if key, err = parent.loadChild(child.public, child.private, passwordSession{opts.Auth}); err != nil {
+		return nil, err
+	}
+	key.creationData = child.creationData
+	key.creationHash = child.creationHash
+	key.ticket = &child.ticket
+	return key, nil
+}
+
+// Duplicate wraps a key created by NewDuplicableKey (or imported with
+// Key.ImportKeyWithAuth) to the storage key newParent, using TPM2_Duplicate.
+// The newParent is usually the public area of another TPM's SRK, which does
+// not need to be loaded in this TPM. The returned ImportBlob can only be
+// imported on the TPM holding newParent, using Key.ImportKeyWithAuth (with
+// the key's authorization value). The caller must check that newParent is a
+// genuine TPM key (for example, with server.VerifyKeyCertification), as the
+// key is otherwise usable by whoever holds newParent's private key.
+func (k *Key) Duplicate(newParent tpm2.Public) (*pb.ImportBlob, error) {
+	password, ok := k.session.(passwordSession)
+	if !ok {
+		return nil, fmt.Errorf("key cannot be duplicated (see NewDuplicableKey)")
+	}
+	parentHandle, _, err := tpm2.LoadExternal(k.rw, newParent, tpm2.Private{}, tpm2.HandleNull)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load new parent: %w", tpmError(err))
+	}
+	defer tpm2.FlushContext(k.rw, parentHandle)
+
+	session, err := newPolicySession(k.rw, DuplicationPolicy(), password.password)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+	auth, err := session.Auth()
+	if err != nil {
+		return nil, err
+	}
+	// No inner wrapper is used (a null symmetricAlg), so the duplicate is only
+	// protected by the seed encrypted to the new parent.
+	resp, err := runCommand(k.rw, cmdDuplicate, []tpmutil.Handle{k.handle, parentHandle},
+		[]tpm2.AuthCommand{auth}, tpmutil.U16Bytes(nil), tpm2.AlgNull)
+	if err != nil {
+		return nil, fmt.Errorf("TPM2_Duplicate failed: %w", err)
+	}
+	var encryptionKey, duplicate, seed tpmutil.U16Bytes
+	if _, err = tpmutil.Unpack(resp, &encryptionKey, &duplicate, &seed); err != nil {
+		return nil, fmt.Errorf("decoding TPM2_Duplicate response: %w", err)
+	}
+	pubArea, err := k.pubArea.Encode()
+	if err != nil {
+		return nil, fmt.Errorf("encoding public area: %w", err)
+	}
+	blob := &pb.ImportBlob{
+		Duplicate:     duplicate,
+		EncryptedSeed: seed,
+		PublicArea:    pubArea,
+		Header:        notinternal.NewBlobHeader(blobCreator, pb.HashAlgo_HASH_INVALID, k.pubArea.AuthPolicy),
+	}
+	return blob, notinternal.SetBlobChecksum(blob)
+}
@@ -0,0 +1,100 @@
+package client_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestDuplicate(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	srk, err := client.StorageRootKeyRSA(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srk.Close()
+	// The SRK of the "target" TPM.
+	newParent, err := client.StorageRootKeyECC(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer newParent.Close()
+
+	opts := client.KeyOpts{
+		Algorithm:  tpm2.AlgECC,
+		Attributes: tpm2.FlagSign | tpm2.FlagSensitiveDataOrigin,
+		Auth:       "duplication password",
+	}
+	key, err := client.NewDuplicableKey(srk, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer key.Close()
+	if key.PublicArea().Attributes&(tpm2.FlagFixedTPM|tpm2.FlagFixedParent) != 0 {
+		t.Error("duplicable key should not have FlagFixedTPM or FlagFixedParent")
+	}
+
+	blob, err := key.Duplicate(newParent.PublicArea())
+	if err != nil {
+		t.Fatalf("failed to duplicate key: %v", err)
+	}
+	imported, err := newParent.ImportKeyWithAuth(blob, opts.Auth)
+	if err != nil {
+		t.Fatalf("failed to import duplicated key: %v", err)
+	}
+	defer imported.Close()
+	if !reflect.DeepEqual(imported.PublicKey(), key.PublicKey()) {
+		t.Fatal("imported key does not match the duplicated key")
+	}
+
+	signer, err := imported.GetSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte("data"))
+	sig, err := signer.Sign(nil, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatalf("failed to sign with imported key: %v", err)
+	}
+	if !ecdsa.VerifyASN1(key.PublicKey().(*ecdsa.PublicKey), digest[:], sig) {
+		t.Error("signature of the imported key does not verify")
+	}
+
+	// The imported key can be duplicated again.
+	if _, err = imported.Duplicate(srk.PublicArea()); err != nil {
+		t.Errorf("failed to duplicate imported key: %v", err)
+	}
+}
+
+func TestDuplicateFails(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	srk, err := client.StorageRootKeyRSA(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srk.Close()
+
+	if _, err = client.NewDuplicableKey(srk, client.KeyOpts{AuthPolicy: client.PolicyAuthValue{}}); err == nil {
+		t.Error("creating a duplicable key with an auth policy should fail")
+	}
+
+	ak, err := client.AttestationKeyRSA(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ak.Close()
+	if _, err = ak.Duplicate(srk.PublicArea()); err == nil {
+		t.Error("duplicating a key bound to the TPM should fail")
+	}
+}
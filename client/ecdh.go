@@ -0,0 +1,63 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// ECDH computes the shared secret between an ECC decryption key (such as one
+// created from ECDHTemplate) and a peer's public key using TPM2_ECDH_ZGen, so
+// the private scalar never leaves the TPM. The peer key must be on the same
+// curve as k.
+//
+// As with crypto/ecdh, the returned secret is the x-coordinate of the shared
+// point, encoded using the full size of the curve. It should be passed through
+// a KDF before being used as a key. If k uses an encrypted session (see
+// UseEncryptedSession), the secret is encrypted on the TPM bus.
+func (k *Key) ECDH(peer *ecdsa.PublicKey) ([]byte, error) {
+	pub, ok := k.pubKey.(*ecdsa.PublicKey)
+	if !ok || !k.hasAttribute(tpm2.FlagDecrypt) || k.hasAttribute(tpm2.FlagRestricted) {
+		return nil, errors.New("ECDH requires an unrestricted ECC decryption key")
+	}
+	if peer.Curve.Params().Name != pub.Curve.Params().Name {
+		return nil, fmt.Errorf("peer key on curve %s, want %s", peer.Curve.Params().Name, pub.Curve.Params().Name)
+	}
+	if !pub.Curve.IsOnCurve(peer.X, peer.Y) {
+		return nil, errors.New("peer key is not a valid point on the curve")
+	}
+	inPoint, err := tpmutil.Pack(tpmutil.U16Bytes(padCoordinate(pub.Curve, peer.X)), tpmutil.U16Bytes(padCoordinate(pub.Curve, peer.Y)))
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := k.session.Auth()
+	if err != nil {
+		return nil, err
+	}
+	var resp []byte
+	if k.extraSession == nil {
+		resp, err = runCommand(k.rw, tpm2.CmdECDHZGen, []tpmutil.Handle{k.handle}, []tpm2.AuthCommand{auth}, tpmutil.U16Bytes(inPoint))
+	} else {
+		var name []byte
+		if name, err = k.name.Digest.Encode(); err != nil {
+			return nil, err
+		}
+		resp, err = k.extraSession.run(tpm2.CmdECDHZGen, []tpmutil.Handle{k.handle}, [][]byte{name}, []tpm2.AuthCommand{auth}, false, true, tpmutil.U16Bytes(inPoint))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("TPM2_ECDH_ZGen failed: %w", err)
+	}
+	var outPoint tpmutil.U16Bytes
+	if _, err = tpmutil.Unpack(resp, &outPoint); err != nil {
+		return nil, fmt.Errorf("decoding TPM2_ECDH_ZGen response: %w", err)
+	}
+	var x, y tpmutil.U16Bytes
+	if _, err = tpmutil.Unpack(outPoint, &x, &y); err != nil {
+		return nil, fmt.Errorf("decoding shared point: %w", err)
+	}
+	return x, nil
+}
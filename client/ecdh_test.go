@@ -0,0 +1,93 @@
+package client_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestECDH(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	tests := []struct {
+		name  string
+		curve tpm2.EllipticCurve
+		peer  elliptic.Curve
+	}{
+		{"P256", tpm2.CurveNISTP256, elliptic.P256()},
+		{"P384", tpm2.CurveNISTP384, elliptic.P384()},
+		{"P521", tpm2.CurveNISTP521, elliptic.P521()},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			template, err := client.ECDHTemplate(tc.curve)
+			if err != nil {
+				t.Fatal(err)
+			}
+			key, err := client.NewKey(rwc, tpm2.HandleOwner, template)
+			if err != nil {
+				t.Fatalf("failed to create ECDH key: %v", err)
+			}
+			defer key.Close()
+
+			// Ephemeral-static key agreement, with the peer in software.
+			peer, err := ecdsa.GenerateKey(tc.peer, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			secret, err := key.ECDH(&peer.PublicKey)
+			if err != nil {
+				t.Fatalf("failed to compute shared secret: %v", err)
+			}
+			pub := key.PublicKey().(*ecdsa.PublicKey)
+			x, _ := tc.peer.ScalarMult(pub.X, pub.Y, peer.D.Bytes())
+			expected := x.FillBytes(make([]byte, (tc.peer.Params().BitSize+7)/8))
+			if !bytes.Equal(secret, expected) {
+				t.Errorf("got shared secret %x, want %x", secret, expected)
+			}
+		})
+	}
+}
+
+func TestECDHWrongKey(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	template, err := client.ECDHTemplate(tpm2.CurveNISTP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := client.NewKey(rwc, tpm2.HandleOwner, template)
+	if err != nil {
+		t.Fatalf("failed to create ECDH key: %v", err)
+	}
+	defer key.Close()
+	peer, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = key.ECDH(&peer.PublicKey); err == nil {
+		t.Error("ECDH with a peer key on a different curve should fail")
+	}
+
+	ak, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ak.Close()
+	peer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ak.ECDH(&peer.PublicKey); err == nil {
+		t.Error("ECDH with a signing key should fail")
+	}
+}
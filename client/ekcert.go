@@ -0,0 +1,64 @@
+package client
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// GetEKCert reads and parses the EK certificate stored by the TPM
+// manufacturer. The RSA EK certificate (at EKCertNVIndexRSA) is returned if
+// present, otherwise the ECC EK certificate (at EKCertNVIndexECC) is returned.
+func GetEKCert(rw io.ReadWriter) (*x509.Certificate, error) {
+	var errs []error
+	for _, idx := range []uint32{EKCertNVIndexRSA, EKCertNVIndexECC} {
+		cert, err := certFromNvIndex(rw, idx)
+		if err == nil {
+			return cert, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, fmt.Errorf("no EK certificate found: %v", errs)
+}
+
+// certFromNvIndex reads and parses the X.509 certificate stored at the
+// provided NV index. The read is authorized with the owner hierarchy.
+// Trailing padding after the DER encoded certificate (as written by some TPM
+// manufacturers) is ignored.
+func certFromNvIndex(rw io.ReadWriter, idx uint32) (*x509.Certificate, error) {
+	data, err := NVRead(rw, tpmutil.Handle(idx), &NVAuth{Handle: tpm2.HandleOwner})
+	if err != nil {
+		return nil, fmt.Errorf("read error at index %d: %w", idx, err)
+	}
+	der, err := trimCertificatePadding(data)
+	if err != nil {
+		return nil, fmt.Errorf("index %d data was not a certificate: %w", idx, err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("index %d data was not a certificate: %w", idx, err)
+	}
+	return cert, nil
+}
+
+// trimCertificatePadding returns the DER encoding of the ASN.1 SEQUENCE at
+// the start of data, ignoring any trailing bytes.
+func trimCertificatePadding(data []byte) ([]byte, error) {
+	var seq asn1.RawValue
+	if _, err := asn1.Unmarshal(data, &seq); err != nil {
+		var syntaxErr asn1.SyntaxError
+		if errors.As(err, &syntaxErr) && len(data) > 0 && data[0] == 0x30 {
+			return nil, fmt.Errorf("certificate is truncated or malformed: %w", err)
+		}
+		return nil, err
+	}
+	if seq.Class != asn1.ClassUniversal || seq.Tag != asn1.TagSequence || !seq.IsCompound {
+		return nil, errors.New("data does not start with an ASN.1 SEQUENCE")
+	}
+	return seq.FullBytes, nil
+}
@@ -0,0 +1,119 @@
+package client_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func writeEKCert(t *testing.T, rw io.ReadWriter, index uint32, data []byte) {
+	t.Helper()
+	idx := tpmutil.Handle(index)
+	attrs := tpm2.AttrOwnerRead | tpm2.AttrOwnerWrite | tpm2.AttrNoDA
+	if err := client.DefineNV(rw, idx, uint16(len(data)), attrs, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.NVWrite(rw, idx, &client.NVAuth{Handle: tpm2.HandleOwner}, data); err != nil {
+		client.UndefineNV(rw, idx)
+		t.Fatal(err)
+	}
+}
+
+func TestGetEKCert(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	if _, err := client.GetEKCert(rwc); err == nil {
+		t.Fatal("expected failure when no EK certificate is present")
+	}
+
+	caPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test TPM Manufacturer CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	ca := createCert(t, caTemplate, caTemplate, caPriv.Public(), caPriv)
+
+	tests := []struct {
+		name   string
+		index  uint32
+		getKey func(io.ReadWriter) (*client.Key, error)
+	}{
+		{"RSA", client.EKCertNVIndexRSA, client.EndorsementKeyRSA},
+		{"ECC", client.EKCertNVIndexECC, client.EndorsementKeyECC},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ek, err := tc.getKey(rwc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ek.Close()
+
+			ekTemplate := &x509.Certificate{
+				SerialNumber: big.NewInt(2),
+				NotBefore:    time.Now().Add(-time.Hour),
+				NotAfter:     time.Now().Add(time.Hour),
+				KeyUsage:     x509.KeyUsageKeyEncipherment,
+			}
+			cert := createCert(t, ekTemplate, ca, ek.PublicKey(), caPriv)
+
+			// Some TPMs pad the certificate to the size of the NV index.
+			padded := append(append([]byte{}, cert.Raw...), bytes.Repeat([]byte{0xff}, 64)...)
+			writeEKCert(t, rwc, tc.index, padded)
+			defer client.UndefineNV(rwc, tpmutil.Handle(tc.index))
+
+			got, err := client.GetEKCert(rwc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Raw, cert.Raw) {
+				t.Error("returned EK certificate does not match the stored certificate")
+			}
+		})
+	}
+}
+
+func TestGetEKCertTruncated(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert := createCert(t, template, template, priv.Public(), priv)
+
+	writeEKCert(t, rwc, client.EKCertNVIndexRSA, cert.Raw[:len(cert.Raw)/2])
+	defer client.UndefineNV(rwc, tpmutil.Handle(client.EKCertNVIndexRSA))
+
+	if _, err := client.GetEKCert(rwc); err == nil {
+		t.Error("expected failure when reading a truncated EK certificate")
+	}
+}
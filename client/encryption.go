@@ -0,0 +1,97 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// Parameter encryption uses AES-128 in CFB mode (which all TPMs support).
+const encryptionKeyBits = 128
+
+// UseEncryptedSession starts an HMAC session salted to the provided EK, which
+// is then used to encrypt the sensitive parameters of all subsequent Seal,
+// Unseal and signing operations using k (the sealed data, the unsealed data
+// and the signed digest respectively). As only the TPM holding the EK can
+// recover the salt, the secrets are not visible in cleartext on the TPM bus,
+// and responses are authenticated by the session.
+//
+// The ek is only used to start the session, and can be closed afterwards. The
+// session is flushed when k is closed. Authorization values (such as the auth
+// value passed to SealWithAuthValue) are not protected by the session. This
+// replaces any session set by UseAuditSession.
+func (k *Key) UseEncryptedSession(ek *Key) error {
+	s, err := newHMACSession(k.rw, ek, false)
+	if err != nil {
+		return err
+	}
+	k.setExtraSession(s)
+	return nil
+}
+
+// createSalt creates a random salt encrypted to the provided public key,
+// returning the salt and the encrypted salt (a TPMU_ENCRYPTED_SECRET), as
+// described in section 11.4.10 of the TPM specification part 1.
+func createSalt(pub tpm2.Public) (salt, encryptedSalt []byte, err error) {
+	hash, err := pub.NameAlg.Hash()
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := pub.Key()
+	if err != nil {
+		return nil, nil, err
+	}
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		salt = make([]byte, hash.Size())
+		if _, err = io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, nil, err
+		}
+		encryptedSalt, err = rsa.EncryptOAEP(hash.New(), rand.Reader, k, salt, []byte("SECRET\x00"))
+		return salt, encryptedSalt, err
+	case *ecdsa.PublicKey:
+		priv, x, y, err := elliptic.GenerateKey(k.Curve, rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		z, _ := k.Curve.ScalarMult(k.X, k.Y, priv)
+		salt, err = tpm2.KDFe(pub.NameAlg, padCoordinate(k.Curve, z), "SECRET",
+			padCoordinate(k.Curve, x), padCoordinate(k.Curve, k.X), hash.Size()*8)
+		if err != nil {
+			return nil, nil, err
+		}
+		encryptedSalt, err = tpmutil.Pack(tpmutil.U16Bytes(padCoordinate(k.Curve, x)), tpmutil.U16Bytes(padCoordinate(k.Curve, y)))
+		return salt, encryptedSalt, err
+	default:
+		return nil, nil, fmt.Errorf("unsupported key type: %v", pub.Type)
+	}
+}
+
+// padCoordinate encodes an ECC coordinate using the full size of the curve.
+func padCoordinate(curve elliptic.Curve, i *big.Int) []byte {
+	size := (curve.Params().BitSize + 7) / 8
+	return i.FillBytes(make([]byte, size))
+}
+
+// encodePCRSelection encodes sel as a TPML_PCR_SELECTION (as done internally by
+// go-tpm), using the minimum (3 byte) selection size.
+func encodePCRSelection(sel tpm2.PCRSelection) ([]byte, error) {
+	if len(sel.PCRs) == 0 {
+		return tpmutil.Pack(uint32(0))
+	}
+	bitmap := make([]byte, 3)
+	for _, pcr := range sel.PCRs {
+		if pcr < 0 || pcr >= len(bitmap)*8 {
+			return nil, fmt.Errorf("invalid PCR index %d", pcr)
+		}
+		bitmap[pcr/8] |= 1 << (pcr % 8)
+	}
+	return tpmutil.Pack(uint32(1), sel.Hash, uint8(len(bitmap)), tpmutil.RawBytes(bitmap))
+}
@@ -0,0 +1,86 @@
+package client_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"io"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestEncryptedSession(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	eks := []struct {
+		name string
+		gen  func(io.ReadWriter) (*client.Key, error)
+	}{
+		{"RSA", client.EndorsementKeyRSA},
+		{"ECC", client.EndorsementKeyECC},
+	}
+	for _, ek := range eks {
+		t.Run(ek.name, func(t *testing.T) {
+			ek, err := ek.gen(rwc)
+			if err != nil {
+				t.Fatalf("failed to create ek: %v", err)
+			}
+			defer ek.Close()
+
+			srk, err := client.StorageRootKeyECC(rwc)
+			if err != nil {
+				t.Fatalf("can't create srk from template: %v", err)
+			}
+			defer srk.Close()
+			if err = srk.UseEncryptedSession(ek); err != nil {
+				t.Fatalf("failed to start encrypted session: %v", err)
+			}
+
+			secret := []byte("a secret not visible on the bus")
+			sel := tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{7}}
+			sealed, err := srk.SealWithAuthValue(secret, client.SealCurrent{PCRSelection: sel}, "passphrase")
+			if err != nil {
+				t.Fatalf("failed to seal: %v", err)
+			}
+			// Multiple commands use (and update) the same session.
+			for i := 0; i < 2; i++ {
+				unsealed, err := srk.UnsealWithAuthValue(sealed, "passphrase", client.CertifyCurrent{PCRSelection: sel})
+				if err != nil {
+					t.Fatalf("failed to unseal: %v", err)
+				}
+				if !bytes.Equal(unsealed, secret) {
+					t.Errorf("unsealed (%v) not equal to secret (%v)", unsealed, secret)
+				}
+			}
+			if _, err = srk.UnsealWithAuthValue(sealed, "wrong", nil); err == nil {
+				t.Error("unseal should fail with the wrong auth value")
+			}
+
+			key, err := client.NewKey(rwc, tpm2.HandleEndorsement, templateECC(tpm2.AlgSHA256))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer key.Close()
+			if err = key.UseEncryptedSession(ek); err != nil {
+				t.Fatalf("failed to start encrypted session: %v", err)
+			}
+			signer, err := key.GetSigner()
+			if err != nil {
+				t.Fatal(err)
+			}
+			digest := sha256.Sum256([]byte("message"))
+			sig, err := signer.Sign(nil, digest[:], crypto.SHA256)
+			if err != nil {
+				t.Fatalf("failed to sign: %v", err)
+			}
+			if !verifyECC(signer.Public(), crypto.SHA256, digest[:], sig) {
+				t.Error("signature verification failed")
+			}
+		})
+	}
+}
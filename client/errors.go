@@ -0,0 +1,123 @@
+package client
+
+import (
+	"errors"
+
+	"github.com/google/go-tpm/tpm2"
+)
+
+// Errors for common TPM failures, which callers may want to recover from.
+// Errors returned by this package match these with errors.Is (for example,
+// errors.Is(err, ErrAuthFail)), regardless of the TPM command that failed. The
+// underlying go-tpm error is kept, so it can also be accessed with errors.As.
+var (
+	// ErrAuthFail is returned if an authorization value (or HMAC) is wrong.
+	// Repeated failures can put the TPM into lockout.
+	ErrAuthFail = errors.New("TPM authorization failed")
+	// ErrLockout is returned if the TPM is in dictionary attack lockout, and
+	// does not accept authorization values until the lockout expires (or is
+	// reset).
+	ErrLockout = errors.New("TPM is in dictionary attack lockout")
+	// ErrPCRChanged is returned if the PCRs do not have the values required by
+	// a policy (such as when unsealing data), or changed while processing a
+	// command.
+	ErrPCRChanged = errors.New("TPM PCR values changed")
+	// ErrNVSpaceFull is returned if the TPM has no NV memory left to define an
+	// NV index or persist a key.
+	ErrNVSpaceFull = errors.New("TPM NV space is full")
+)
+
+// TPMError is an error returned by the TPM. It wraps the go-tpm error for the
+// response code (a tpm2.Error, tpm2.Warning, tpm2.ParameterError,
+// tpm2.HandleError, tpm2.SessionError or tpm2.VendorError), and matches the
+// error variables of this package (such as ErrAuthFail) with errors.Is.
+type TPMError struct {
+	Err error
+	// policyPCR is set for commands authorized by a PCR policy, so policy
+	// failures indicate the PCRs have changed.
+	policyPCR bool
+}
+
+func (e *TPMError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying go-tpm error.
+func (e *TPMError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the TPM error corresponds to target, one of this
+// package's error variables.
+func (e *TPMError) Is(target error) bool {
+	switch target {
+	case ErrAuthFail:
+		return hasFmt1Code(e.Err, tpm2.RCAuthFail) || hasFmt1Code(e.Err, tpm2.RCBadAuth) ||
+			hasFmt0Code(e.Err, tpm2.RCNVAuthorization)
+	case ErrLockout:
+		var warning tpm2.Warning
+		return errors.As(e.Err, &warning) && warning.Code == tpm2.RCLockout
+	case ErrPCRChanged:
+		return hasFmt0Code(e.Err, tpm2.RCPCRChanged) ||
+			(e.policyPCR && hasFmt1Code(e.Err, tpm2.RCPolicyFail))
+	case ErrNVSpaceFull:
+		return hasFmt0Code(e.Err, tpm2.RCNVSpace)
+	}
+	return false
+}
+
+func hasFmt0Code(err error, code tpm2.RCFmt0) bool {
+	var tpmErr tpm2.Error
+	return errors.As(err, &tpmErr) && tpmErr.Code == code
+}
+
+func hasFmt1Code(err error, code tpm2.RCFmt1) bool {
+	var paramErr tpm2.ParameterError
+	if errors.As(err, &paramErr) && paramErr.Code == code {
+		return true
+	}
+	var handleErr tpm2.HandleError
+	if errors.As(err, &handleErr) && handleErr.Code == code {
+		return true
+	}
+	var sessionErr tpm2.SessionError
+	return errors.As(err, &sessionErr) && sessionErr.Code == code
+}
+
+// isResponseError reports whether err contains a TPM response code error.
+func isResponseError(err error) bool {
+	var (
+		tpmErr     tpm2.Error
+		warning    tpm2.Warning
+		paramErr   tpm2.ParameterError
+		handleErr  tpm2.HandleError
+		sessionErr tpm2.SessionError
+		vendorErr  tpm2.VendorError
+	)
+	return errors.As(err, &tpmErr) || errors.As(err, &warning) || errors.As(err, &paramErr) ||
+		errors.As(err, &handleErr) || errors.As(err, &sessionErr) || errors.As(err, &vendorErr)
+}
+
+// tpmError wraps errors returned by go-tpm in a TPMError, if they were caused
+// by a TPM response code (and not, for example, by an I/O error). Other errors
+// (including nil) are returned unchanged.
+func tpmError(err error) error {
+	var wrapped *TPMError
+	if err == nil || errors.As(err, &wrapped) || !isResponseError(err) {
+		return err
+	}
+	return &TPMError{Err: err}
+}
+
+// tpmPolicyError is like tpmError, for commands authorized by a PCR policy.
+func tpmPolicyError(err error) error {
+	var wrapped *TPMError
+	if errors.As(err, &wrapped) {
+		wrapped.policyPCR = true
+		return err
+	}
+	if err == nil || !isResponseError(err) {
+		return err
+	}
+	return &TPMError{Err: err, policyPCR: true}
+}
@@ -0,0 +1,46 @@
+package client_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+)
+
+func TestTPMErrorIs(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"AuthFail", tpm2.SessionError{Code: tpm2.RCAuthFail, Session: 1}, client.ErrAuthFail},
+		{"BadAuth", tpm2.HandleError{Code: tpm2.RCBadAuth, Handle: 1}, client.ErrAuthFail},
+		{"NVAuthorization", tpm2.Error{Code: tpm2.RCNVAuthorization}, client.ErrAuthFail},
+		{"Lockout", tpm2.Warning{Code: tpm2.RCLockout}, client.ErrLockout},
+		{"PCRChanged", tpm2.Error{Code: tpm2.RCPCRChanged}, client.ErrPCRChanged},
+		{"NVSpace", tpm2.Error{Code: tpm2.RCNVSpace}, client.ErrNVSpaceFull},
+		{"Other", tpm2.Error{Code: tpm2.RCNVDefined}, nil},
+	}
+	targets := []error{client.ErrAuthFail, client.ErrLockout, client.ErrPCRChanged, client.ErrNVSpaceFull}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := fmt.Errorf("wrapped: %w", &client.TPMError{Err: test.err})
+			for _, target := range targets {
+				if got := errors.Is(err, target); got != (target == test.want) {
+					t.Errorf("errors.Is(%v, %v) = %v", err, target, got)
+				}
+			}
+			var tpmErr *client.TPMError
+			if !errors.As(err, &tpmErr) {
+				t.Errorf("errors.As(%v) failed for *client.TPMError", err)
+			}
+			// The go-tpm error is still accessible.
+			if !errors.Is(err, test.err) {
+				t.Errorf("errors.Is(%v, %v) = false", err, test.err)
+			}
+		})
+	}
+}
@@ -12,8 +12,23 @@ func GetEventLog(rw io.ReadWriter) ([]byte, error) {
 }
 
 // EventLogGetter allows a TPM (io.ReadWriter) to specify a particular
-// implementation for GetEventLog(). This is useful for testing and necessary
-// for Windows Event Log support (which requires a handle to the TPM).
+// implementation for GetEventLog(). This is useful for testing. On Windows,
+// the event log is retrieved from TBS (see OpenTPM).
 type EventLogGetter interface {
 	EventLog() ([]byte, error)
 }
+
+// GetIMALog grabs the binary IMA runtime measurement list for the system. The
+// TPM can override this implementation by implementing IMALogGetter.
+func GetIMALog(rw io.ReadWriter) ([]byte, error) {
+	if ilg, ok := rw.(IMALogGetter); ok {
+		return ilg.IMALog()
+	}
+	return getRealIMALog()
+}
+
+// IMALogGetter allows a TPM (io.ReadWriter) to specify a particular
+// implementation for GetIMALog(). This is useful for testing.
+type IMALogGetter interface {
+	IMALog() ([]byte, error)
+}
@@ -7,3 +7,7 @@ import (
 func getRealEventLog() ([]byte, error) {
 	return ioutil.ReadFile("/sys/kernel/security/tpm0/binary_bios_measurements")
 }
+
+func getRealIMALog() ([]byte, error) {
+	return ioutil.ReadFile("/sys/kernel/security/ima/binary_runtime_measurements")
+}
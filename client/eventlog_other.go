@@ -1,4 +1,4 @@
-// +build !linux
+// +build !linux,!windows
 
 package client
 
@@ -7,3 +7,7 @@ import "errors"
 func getRealEventLog() ([]byte, error) {
 	return nil, errors.New("failed to get event log: only Linux supported")
 }
+
+func getRealIMALog() ([]byte, error) {
+	return nil, errors.New("failed to get IMA log: only Linux supported")
+}
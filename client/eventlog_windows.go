@@ -0,0 +1,31 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/go-tpm/tpmutil/tbs"
+)
+
+func getRealEventLog() ([]byte, error) {
+	context, err := tbs.CreateContext(tbs.TPMVersion20, tbs.IncludeTPM20)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event log: opening TBS: %w", err)
+	}
+	defer context.Close()
+
+	// Get the size of the log first.
+	size, err := context.GetTCGLog(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event log: %w", err)
+	}
+	log := make([]byte, size)
+	if size, err = context.GetTCGLog(log); err != nil {
+		return nil, fmt.Errorf("failed to get event log: %w", err)
+	}
+	return log[:size], nil
+}
+
+func getRealIMALog() ([]byte, error) {
+	return nil, errors.New("failed to get IMA log: only Linux supported")
+}
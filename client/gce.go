@@ -0,0 +1,94 @@
+package client
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+// The extension of the AK certificates of GCE instances identifying the
+// instance (cloudComputeInstanceIdentifier).
+var oidGCEInstanceInfo = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 1, 21}
+
+// The ASN.1 encoding of the extension.
+type gceSecurityProperties struct {
+	SecurityVersion int64 `asn1:"explicit,tag:0,optional"`
+	IsProduction    bool  `asn1:"explicit,tag:1,optional"`
+}
+
+type gceInstanceInfo struct {
+	Zone               string `asn1:"utf8"`
+	ProjectNumber      int64
+	ProjectID          string `asn1:"utf8"`
+	InstanceID         int64
+	InstanceName       string                `asn1:"utf8"`
+	SecurityProperties gceSecurityProperties `asn1:"explicit,optional"`
+}
+
+// GetGCEInstanceInfo returns the GCE instance identified by the AK
+// certificate of a GCE VM (such as the certificate of GceAttestationKeyRSA),
+// or nil if the certificate has no instance identity extension.
+//
+// This only parses the certificate: the instance can only be trusted once the
+// certificate is verified, as done by server.VerifyAttestation. The instance
+// identity is only read from the AK certificate, not from the event log, and
+// the GCE vTPM CAs are not bundled with this module: Google's root CA must be
+// obtained from Google and passed as a server.VerifyOpts.TrustedRootCerts,
+// otherwise the instance is never verified.
+func GetGCEInstanceInfo(cert *x509.Certificate) (*pb.GCEInstanceInfo, error) {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidGCEInstanceInfo) {
+			continue
+		}
+		var info gceInstanceInfo
+		rest, err := asn1.Unmarshal(ext.Value, &info)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse GCE instance information: %w", err)
+		}
+		if len(rest) > 0 {
+			return nil, errors.New("trailing data after GCE instance information")
+		}
+		if info.ProjectNumber < 0 || info.InstanceID < 0 {
+			return nil, errors.New("negative GCE project number or instance ID")
+		}
+		return &pb.GCEInstanceInfo{
+			Zone:          info.Zone,
+			ProjectId:     info.ProjectID,
+			ProjectNumber: uint64(info.ProjectNumber),
+			InstanceName:  info.InstanceName,
+			InstanceId:    uint64(info.InstanceID),
+		}, nil
+	}
+	return nil, nil
+}
+
+// ReadGCEInstanceInfo returns the GCE instance identified by the AK
+// certificates in the NV indices of the vTPM of a GCE VM (GceAKCertNVIndexRSA,
+// or GceAKCertNVIndexECC). It fails if the vTPM has no AK certificate with an
+// instance identity, as on older GCE VMs and outside of GCE.
+func ReadGCEInstanceInfo(rw io.ReadWriter) (*pb.GCEInstanceInfo, error) {
+	for _, idx := range []uint32{GceAKCertNVIndexRSA, GceAKCertNVIndexECC} {
+		if _, err := tpm2.NVReadPublic(rw, tpmutil.Handle(idx)); err != nil {
+			continue
+		}
+		cert, err := certFromNvIndex(rw, idx)
+		if err != nil {
+			return nil, err
+		}
+		info, err := GetGCEInstanceInfo(cert)
+		if err != nil {
+			return nil, fmt.Errorf("AK certificate at index %d: %w", idx, err)
+		}
+		if info != nil {
+			return info, nil
+		}
+	}
+	return nil, errors.New("no GCE AK certificate with an instance identity")
+}
@@ -0,0 +1,138 @@
+package client_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/go-tpm/tpmutil"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+var testInstanceInfo = &pb.GCEInstanceInfo{
+	Zone:          "us-central1-a",
+	ProjectId:     "test-project",
+	ProjectNumber: 1234567890,
+	InstanceName:  "test-instance",
+	InstanceId:    9876543210,
+}
+
+// Creates an AK certificate for the public key, with the extension of GCE AK
+// certificates identifying the instance.
+func createGCEAKCert(t *testing.T, pub crypto.PublicKey, extension []byte) *x509.Certificate {
+	t.Helper()
+	caPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test vTPM CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "test-instance"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	if extension != nil {
+		template.ExtraExtensions = []pkix.Extension{{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 1, 21}, Value: extension}}
+	}
+	return createCert(t, template, ca, pub, caPriv)
+}
+
+func marshalInstanceInfo(t *testing.T, info *pb.GCEInstanceInfo) []byte {
+	t.Helper()
+	type securityProperties struct {
+		SecurityVersion int64 `asn1:"explicit,tag:0,optional"`
+		IsProduction    bool  `asn1:"explicit,tag:1,optional"`
+	}
+	data, err := asn1.Marshal(struct {
+		Zone               string `asn1:"utf8"`
+		ProjectNumber      int64
+		ProjectID          string `asn1:"utf8"`
+		InstanceID         int64
+		InstanceName       string             `asn1:"utf8"`
+		SecurityProperties securityProperties `asn1:"explicit,optional"`
+	}{info.GetZone(), int64(info.GetProjectNumber()), info.GetProjectId(), int64(info.GetInstanceId()), info.GetInstanceName(),
+		securityProperties{SecurityVersion: 1, IsProduction: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestGetGCEInstanceInfo(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := client.GetGCEInstanceInfo(createGCEAKCert(t, priv.Public(), marshalInstanceInfo(t, testInstanceInfo)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(info, testInstanceInfo) {
+		t.Errorf("got instance info %v, want %v", info, testInstanceInfo)
+	}
+
+	if info, err = client.GetGCEInstanceInfo(createGCEAKCert(t, priv.Public(), nil)); err != nil || info != nil {
+		t.Errorf("got instance info %v (%v) for a certificate without the extension, want none", info, err)
+	}
+	if _, err = client.GetGCEInstanceInfo(createGCEAKCert(t, priv.Public(), []byte{0x30, 0x03, 0x0c, 0x01})); err == nil {
+		t.Error("expected parsing an invalid extension to fail")
+	}
+}
+
+func TestReadGCEInstanceInfo(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	if _, err := client.ReadGCEInstanceInfo(rwc); err == nil {
+		t.Fatal("expected failure without a GCE AK certificate")
+	}
+
+	ak, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ak.Close()
+	cert := createGCEAKCert(t, ak.PublicKey(), marshalInstanceInfo(t, testInstanceInfo))
+	writeEKCert(t, rwc, client.GceAKCertNVIndexECC, cert.Raw)
+	defer client.UndefineNV(rwc, tpmutil.Handle(client.GceAKCertNVIndexECC))
+
+	info, err := client.ReadGCEInstanceInfo(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(info, testInstanceInfo) {
+		t.Errorf("got instance info %v, want %v", info, testInstanceInfo)
+	}
+
+	// The instance is also included in attestations with the AK certificate.
+	if err = ak.SetCert(cert); err != nil {
+		t.Fatal(err)
+	}
+	attestation, err := ak.Attest([]byte("nonce"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(attestation.GetInstanceInfo(), testInstanceInfo) {
+		t.Errorf("got attestation instance info %v, want %v", attestation.GetInstanceInfo(), testInstanceInfo)
+	}
+}
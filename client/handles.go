@@ -1,9 +1,11 @@
 package client
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"math"
+	"sort"
 
 	"github.com/google/go-tpm/tpm2"
 	"github.com/google/go-tpm/tpmutil"
@@ -24,37 +26,151 @@ const (
 	DefaultAKRSAHandle = tpmutil.Handle(0x81008F01)
 )
 
+// NV Indices holding EK Certificates from "TCG TPM v2.0 Provisioning Guidance" - v1r1 - Section 7.8
+const (
+	EKCertNVIndexRSA uint32 = 0x01c00002
+	EKCertNVIndexECC uint32 = 0x01c0000a
+)
+
 // NV Indices holding GCE AK Templates
 const (
 	GceAKTemplateNVIndexRSA uint32 = 0x01c10001
 	GceAKTemplateNVIndexECC uint32 = 0x01c10003
 )
 
+// NV Indices holding GCE AK Certificates
+const (
+	GceAKCertNVIndexRSA uint32 = 0x01c10000
+	GceAKCertNVIndexECC uint32 = 0x01c10002
+)
+
+// Handle of the AK persisted by the Azure vTPM, and the NV Index holding its
+// certificate
+const (
+	AzureAKHandle             = tpmutil.Handle(0x81000003)
+	AzureAKCertNVIndex uint32 = 0x01c101d0
+)
+
 func isHierarchy(h tpmutil.Handle) bool {
 	return h == tpm2.HandleOwner || h == tpm2.HandleEndorsement ||
 		h == tpm2.HandlePlatform || h == tpm2.HandleNull
 }
 
 // Handles returns a slice of tpmutil.Handle objects of all handles within
-// the TPM rw of type handleType.
+// the TPM rw of type handleType, in ascending order.
+//
+// When rw uses a resource manager (such as /dev/tpmrm0 or TBS, see OpenTPM),
+// only the transient objects loaded through rw are returned, using their
+// virtualized handles. Sessions and persistent objects are not virtualized.
 func Handles(rw io.ReadWriter, handleType tpm2.HandleType) ([]tpmutil.Handle, error) {
 	// Handle type is determined by the most-significant octet (MSO) of the property.
 	property := uint32(handleType) << 24
 
-	vals, moreData, err := tpm2.GetCapability(rw, tpm2.CapabilityHandles, math.MaxUint32, property)
-	if err != nil {
-		return nil, err
+	handles := []tpmutil.Handle{}
+	for {
+		vals, moreData, err := tpm2.GetCapability(rw, tpm2.CapabilityHandles, math.MaxUint32, property)
+		if err != nil {
+			return nil, tpmError(err)
+		}
+		for _, v := range vals {
+			handle, ok := v.(tpmutil.Handle)
+			if !ok {
+				return nil, fmt.Errorf("unable to assert type tpmutil.Handle of value %#v", v)
+			}
+			handles = append(handles, handle)
+		}
+		if !moreData {
+			break
+		}
+		// The TPM returns at most MAX_CAP_HANDLES handles at once, so continue
+		// after the last handle. This does not work for virtualized transient
+		// handles, which are not returned in order, but a resource manager only
+		// loads a few transient objects at once.
+		if handleType == tpm2.HandleTypeTransient {
+			return nil, fmt.Errorf("tpm2.GetCapability() returned moreData==true for transient handles")
+		}
+		if len(vals) == 0 {
+			return nil, fmt.Errorf("tpm2.GetCapability() returned moreData==true without any handles")
+		}
+		property = uint32(handles[len(handles)-1]) + 1
+	}
+	// Resource managers return virtualized transient handles in the order of
+	// the TPM's handles.
+	sort.Slice(handles, func(i, j int) bool { return handles[i] < handles[j] })
+	return handles, nil
+}
+
+// FlushHandles flushes all handles within the TPM rw of the provided types,
+// returning the flushed handles. If no types are provided, transient and
+// (loaded and saved) session handles are flushed. Persistent handles cannot
+// be flushed, use EvictPersistent instead.
+//
+// This recovers from a TPM running out of memory because of objects and
+// sessions which were never flushed (for example, by a process which exited
+// without closing them). These may still be used by other users of the TPM,
+// if it is not opened exclusively (see OpenTPMDevice).
+func FlushHandles(rw io.ReadWriter, handleTypes ...tpm2.HandleType) ([]tpmutil.Handle, error) {
+	if len(handleTypes) == 0 {
+		handleTypes = []tpm2.HandleType{tpm2.HandleTypeTransient,
+			tpm2.HandleTypeLoadedSession, tpm2.HandleTypeSavedSession}
+	}
+	var flushed []tpmutil.Handle
+	for _, handleType := range handleTypes {
+		if handleType == tpm2.HandleTypePersistent {
+			return flushed, errors.New("persistent handles cannot be flushed")
+		}
+		handles, err := Handles(rw, handleType)
+		if err != nil {
+			return flushed, fmt.Errorf("getting handles: %w", err)
+		}
+		for _, handle := range handles {
+			if err = tpm2.FlushContext(rw, handle); err != nil {
+				return flushed, fmt.Errorf("flushing handle 0x%x: %w", handle, tpmError(err))
+			}
+			flushed = append(flushed, handle)
+		}
 	}
-	if moreData {
-		return nil, fmt.Errorf("tpm2.GetCapability() should never return moreData==true for tpm2.CapabilityHandles")
+	return flushed, nil
+}
+
+// HandleInfo describes a handle within the TPM, as returned by DescribeHandles.
+type HandleInfo struct {
+	Handle tpmutil.Handle
+	Type   tpm2.HandleType
+	// Public and Name are only set for objects (i.e. transient and persistent
+	// handles), not sessions.
+	Public *tpm2.Public
+	Name   tpm2.Name
+}
+
+// DescribeHandles returns information about all handles within the TPM rw of
+// the provided types. If no types are provided, persistent, transient, and
+// (loaded and saved) session handles are described.
+func DescribeHandles(rw io.ReadWriter, handleTypes ...tpm2.HandleType) ([]HandleInfo, error) {
+	if len(handleTypes) == 0 {
+		handleTypes = []tpm2.HandleType{tpm2.HandleTypePersistent, tpm2.HandleTypeTransient,
+			tpm2.HandleTypeLoadedSession, tpm2.HandleTypeSavedSession}
 	}
-	handles := make([]tpmutil.Handle, len(vals))
-	for i, v := range vals {
-		handle, ok := v.(tpmutil.Handle)
-		if !ok {
-			return nil, fmt.Errorf("unable to assert type tpmutil.Handle of value %#v", v)
+	var infos []HandleInfo
+	for _, handleType := range handleTypes {
+		handles, err := Handles(rw, handleType)
+		if err != nil {
+			return nil, fmt.Errorf("getting handles: %w", err)
+		}
+		for _, handle := range handles {
+			info := HandleInfo{Handle: handle, Type: handleType}
+			if handleType == tpm2.HandleTypePersistent || handleType == tpm2.HandleTypeTransient {
+				pub, _, _, err := tpm2.ReadPublic(rw, handle)
+				if err != nil {
+					return nil, fmt.Errorf("reading public area of handle 0x%x: %w", handle, tpmError(err))
+				}
+				info.Public = &pub
+				if info.Name, err = pub.Name(); err != nil {
+					return nil, fmt.Errorf("computing name of handle 0x%x: %w", handle, err)
+				}
+			}
+			infos = append(infos, info)
 		}
-		handles[i] = handle
 	}
-	return handles, nil
+	return infos, nil
 }
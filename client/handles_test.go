@@ -1,6 +1,8 @@
 package client_test
 
 import (
+	"bytes"
+	"encoding/binary"
 	"reflect"
 	"testing"
 
@@ -39,3 +41,185 @@ func TestHandles(t *testing.T) {
 		}
 	}
 }
+
+func TestDescribeHandles(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	srk, err := client.NewKey(rwc, tpm2.HandleOwner, client.SRKTemplateRSA())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srk.Close()
+	ak, err := client.NewKey(rwc, tpm2.HandleOwner, client.AKTemplateECC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ak.Close()
+
+	infos, err := client.DescribeHandles(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := 0
+	for _, info := range infos {
+		if info.Type != tpm2.HandleTypeTransient {
+			continue
+		}
+		var key *client.Key
+		switch info.Handle {
+		case srk.Handle():
+			key = srk
+		case ak.Handle():
+			key = ak
+		default:
+			t.Errorf("unexpected transient handle 0x%x", info.Handle)
+			continue
+		}
+		found++
+		if info.Public == nil || !info.Public.MatchesTemplate(key.PublicArea()) {
+			t.Errorf("handle 0x%x has public area %+v, want %+v", info.Handle, info.Public, key.PublicArea())
+		}
+		if !reflect.DeepEqual(info.Name, key.Name()) {
+			t.Errorf("handle 0x%x has name %v, want %v", info.Handle, info.Name, key.Name())
+		}
+	}
+	if found != 2 {
+		t.Errorf("found %d of the 2 transient keys", found)
+	}
+
+	infos, err = client.DescribeHandles(rwc, tpm2.HandleTypePersistent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range infos {
+		if info.Type != tpm2.HandleTypePersistent {
+			t.Errorf("got handle 0x%x of type %v, want only persistent handles", info.Handle, info.Type)
+		}
+	}
+}
+
+func TestFlushHandles(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	test.LoadRandomExternalKey(t, rwc)
+	var sessions []tpmutil.Handle
+	for i := 0; i < 2; i++ {
+		session, _, err := tpm2.StartAuthSession(rwc, tpm2.HandleNull, tpm2.HandleNull,
+			make([]byte, 16), nil, tpm2.SessionHMAC, tpm2.AlgNull, tpm2.AlgSHA256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessions = append(sessions, session)
+	}
+	// Saving the context of a session unloads it.
+	if _, err := tpm2.ContextSave(rwc, sessions[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	flushed, err := client.FlushHandles(rwc, tpm2.HandleTypeLoadedSession)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(flushed, sessions[:1]) {
+		t.Errorf("flushed %v, want the loaded session %v", flushed, sessions[:1])
+	}
+	if flushed, err = client.FlushHandles(rwc); err != nil {
+		t.Fatal(err)
+	}
+	if len(flushed) != 2 {
+		t.Errorf("flushed %v, want the transient key and the saved session", flushed)
+	}
+	for _, handleType := range []tpm2.HandleType{tpm2.HandleTypeTransient, tpm2.HandleTypeLoadedSession, tpm2.HandleTypeSavedSession} {
+		handles, err := client.Handles(rwc, handleType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(handles) != 0 {
+			t.Errorf("got handles %v of type %v after flushing", handles, handleType)
+		}
+	}
+
+	if _, err = client.FlushHandles(rwc, tpm2.HandleTypePersistent); err == nil {
+		t.Error("expected flushing persistent handles to fail")
+	}
+}
+
+// capabilityTPM answers TPM2_GetCapability(TPM_CAP_HANDLES) commands with
+// handles, returning at most maxCount handles per response (as a TPM does), in
+// the order of handles (as a resource manager does for transient handles).
+type capabilityTPM struct {
+	handles  []tpmutil.Handle
+	maxCount int
+	resp     bytes.Buffer
+}
+
+func (c *capabilityTPM) Write(cmd []byte) (int, error) {
+	// The property follows the 10 byte header and 4 byte capability.
+	property := tpmutil.Handle(binary.BigEndian.Uint32(cmd[14:18]))
+	var selected []tpmutil.Handle
+	moreData := false
+	for _, h := range c.handles {
+		if h>>24 != property>>24 || h < property {
+			continue
+		}
+		if len(selected) == c.maxCount {
+			moreData = true
+			break
+		}
+		selected = append(selected, h)
+	}
+	body, err := tpmutil.Pack(moreData, uint32(tpm2.CapabilityHandles), uint32(len(selected)))
+	if err != nil {
+		return 0, err
+	}
+	for _, h := range selected {
+		body = append(body, byte(h>>24), byte(h>>16), byte(h>>8), byte(h))
+	}
+	header, err := tpmutil.Pack(uint16(0x8001), uint32(10+len(body)), uint32(0))
+	if err != nil {
+		return 0, err
+	}
+	c.resp.Write(header)
+	c.resp.Write(body)
+	return len(cmd), nil
+}
+
+func (c *capabilityTPM) Read(p []byte) (int, error) {
+	return c.resp.Read(p)
+}
+
+func TestHandlesMoreData(t *testing.T) {
+	persistent := []tpmutil.Handle{0x81000001, 0x81000002, 0x81010001, 0x81010002, 0x81800000}
+	rw := &capabilityTPM{handles: persistent, maxCount: 2}
+	handles, err := client.Handles(rw, tpm2.HandleTypePersistent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(handles, persistent) {
+		t.Errorf("got handles %v, want %v", handles, persistent)
+	}
+}
+
+func TestHandlesVirtualized(t *testing.T) {
+	// The kernel resource manager returns virtualized handles in the order of
+	// the physical handles.
+	rw := &capabilityTPM{handles: []tpmutil.Handle{0x80FFFFFF, 0x80FFFFFD, 0x80FFFFFE}, maxCount: 3}
+	handles, err := client.Handles(rw, tpm2.HandleTypeTransient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tpmutil.Handle{0x80FFFFFD, 0x80FFFFFE, 0x80FFFFFF}
+	if !reflect.DeepEqual(handles, want) {
+		t.Errorf("got handles %v, want %v", handles, want)
+	}
+
+	rw = &capabilityTPM{handles: []tpmutil.Handle{}, maxCount: 3}
+	if handles, err = client.Handles(rw, tpm2.HandleTypeTransient); err != nil {
+		t.Fatal(err)
+	}
+	if len(handles) != 0 {
+		t.Errorf("got handles %v, want none", handles)
+	}
+}
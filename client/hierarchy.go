@@ -0,0 +1,136 @@
+package client
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+const cmdHierarchyControl tpmutil.Command = 0x00000121
+
+// Clear runs TPM2_Clear, authorized by the lockout hierarchy. This resets the
+// owner hierarchy's seed (invalidating all keys in the owner hierarchy, such
+// as the SRK and any sealed data), removes all owner NV indices and persistent
+// keys, and resets the owner, endorsement and lockout authorization values to
+// empty. The endorsement hierarchy's seed (and so the EK) is not changed.
+func Clear(rw io.ReadWriter, lockoutAuth string) error {
+	if err := tpm2.Clear(rw, tpm2.HandleLockout, passwordAuth(lockoutAuth)); err != nil {
+		return fmt.Errorf("TPM2_Clear failed: %w", tpmError(err))
+	}
+	return nil
+}
+
+// ChangeHierarchyAuth sets the authorization value of a hierarchy
+// (tpm2.Handle{Owner|Endorsement|Lockout|Platform}) to newAuth, using
+// TPM2_HierarchyChangeAuth. The change is authorized by the current
+// authorization value of the hierarchy. Setting the owner authorization value
+// is commonly known as taking ownership of the TPM.
+func ChangeHierarchyAuth(rw io.ReadWriter, hierarchy tpmutil.Handle, oldAuth, newAuth string) error {
+	switch hierarchy {
+	case tpm2.HandleOwner, tpm2.HandleEndorsement, tpm2.HandleLockout, tpm2.HandlePlatform:
+	default:
+		return fmt.Errorf("unsupported hierarchy: 0x%x", hierarchy)
+	}
+	if err := tpm2.HierarchyChangeAuth(rw, hierarchy, passwordAuth(oldAuth), newAuth); err != nil {
+		return fmt.Errorf("TPM2_HierarchyChangeAuth failed: %w", tpmError(err))
+	}
+	return nil
+}
+
+// SetHierarchyEnabled enables or disables a hierarchy
+// (tpm2.Handle{Owner|Endorsement|Platform}), using TPM2_HierarchyControl.
+// Keys in a disabled hierarchy cannot be created or used, until it is enabled
+// or the TPM is reset. A hierarchy can be disabled using its own authorization
+// value, while enabling a hierarchy requires the platform authorization value
+// (which is usually only available to firmware). All hierarchies are enabled
+// when the TPM is reset.
+func SetHierarchyEnabled(rw io.ReadWriter, hierarchy tpmutil.Handle, enable bool, auth string) error {
+	if !isHierarchy(hierarchy) || hierarchy == tpm2.HandleNull {
+		return fmt.Errorf("unsupported hierarchy: 0x%x", hierarchy)
+	}
+	authHandle := hierarchy
+	var state byte
+	if enable {
+		authHandle = tpm2.HandlePlatform
+		state = 1
+	}
+	if _, err := runCommand(rw, cmdHierarchyControl, []tpmutil.Handle{authHandle},
+		[]tpm2.AuthCommand{passwordAuth(auth)}, hierarchy, state); err != nil {
+		return fmt.Errorf("TPM2_HierarchyControl failed: %w", err)
+	}
+	return nil
+}
+
+// HierarchyAuth holds the authorization values (passwords) of the TPM's
+// hierarchies, for TPMs where they have been set (see ChangeHierarchyAuth).
+type HierarchyAuth struct {
+	Owner       string
+	Endorsement string
+	Platform    string
+}
+
+// HierarchyAuthGetter allows a TPM (io.ReadWriter) to specify the
+// authorization values of its hierarchies. This package uses them whenever a
+// command is authorized by a hierarchy: when creating primary keys (including
+// the EK, SRK and AK), using the EK, persisting keys, and defining NV indices.
+// If a TPM does not implement HierarchyAuthGetter, empty authorization values
+// are used.
+type HierarchyAuthGetter interface {
+	HierarchyAuth() HierarchyAuth
+}
+
+// WithHierarchyAuth returns a TPM forwarding all commands to rw, which uses
+// the provided hierarchy authorization values with all functions of this
+// package (see HierarchyAuthGetter). The returned TPM forwards Close (and
+// GetEventLog and GetIMALog) to rw. For example, to use the EK of a TPM where
+// the owner and endorsement passwords are set:
+//
+//	tpm := client.WithHierarchyAuth(rwc, client.HierarchyAuth{Owner: "owner", Endorsement: "endorsement"})
+//	ek, err := client.EndorsementKeyRSA(tpm)
+func WithHierarchyAuth(rw io.ReadWriter, auth HierarchyAuth) io.ReadWriteCloser {
+	return hierarchyAuthTPM{rw, auth}
+}
+
+type hierarchyAuthTPM struct {
+	io.ReadWriter
+	auth HierarchyAuth
+}
+
+func (h hierarchyAuthTPM) HierarchyAuth() HierarchyAuth {
+	return h.auth
+}
+
+func (h hierarchyAuthTPM) Close() error {
+	if closer, ok := h.ReadWriter.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (h hierarchyAuthTPM) EventLog() ([]byte, error) {
+	return GetEventLog(h.ReadWriter)
+}
+
+func (h hierarchyAuthTPM) IMALog() ([]byte, error) {
+	return GetIMALog(h.ReadWriter)
+}
+
+// hierarchyAuth returns the authorization value of a hierarchy of the TPM rw.
+func hierarchyAuth(rw io.ReadWriter, hierarchy tpmutil.Handle) string {
+	getter, ok := rw.(HierarchyAuthGetter)
+	if !ok {
+		return ""
+	}
+	auth := getter.HierarchyAuth()
+	switch hierarchy {
+	case tpm2.HandleOwner:
+		return auth.Owner
+	case tpm2.HandleEndorsement:
+		return auth.Endorsement
+	case tpm2.HandlePlatform:
+		return auth.Platform
+	}
+	return ""
+}
@@ -0,0 +1,182 @@
+package client_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	"github.com/ThalesIgnite/go-tpm-tools/server"
+)
+
+func TestChangeHierarchyAuth(t *testing.T) {
+	test.SkipOnRealTPM(t)
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	srk, err := client.NewKey(rwc, tpm2.HandleOwner, client.SRKTemplateECC())
+	if err != nil {
+		t.Fatalf("can't create srk from template: %v", err)
+	}
+	defer srk.Close()
+	if err = client.ChangeHierarchyAuth(rwc, tpm2.HandleOwner, "", "owner"); err != nil {
+		t.Fatalf("failed to set owner auth: %v", err)
+	}
+	// Owner authorization is now needed to persist keys.
+	if err = srk.Persist(client.SRKECCReservedHandle); !errors.Is(err, client.ErrAuthFail) {
+		t.Errorf("persisting without owner auth returned %v, want ErrAuthFail", err)
+	}
+	if err = client.ChangeHierarchyAuth(rwc, tpm2.HandleOwner, "wrong", ""); !errors.Is(err, client.ErrAuthFail) {
+		t.Errorf("changing auth with the wrong password returned %v, want ErrAuthFail", err)
+	}
+	if err = client.ChangeHierarchyAuth(rwc, tpm2.HandleOwner, "owner", ""); err != nil {
+		t.Fatalf("failed to remove owner auth: %v", err)
+	}
+	if err = client.ChangeHierarchyAuth(rwc, tpm2.HandleNull, "", "null"); err == nil {
+		t.Error("changing the auth of the null hierarchy should fail")
+	}
+}
+
+func TestClear(t *testing.T) {
+	test.SkipOnRealTPM(t)
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	srk, err := client.StorageRootKeyECC(rwc)
+	if err != nil {
+		t.Fatalf("can't create srk from template: %v", err)
+	}
+	name := srk.Name()
+	srk.Close()
+	ek, err := client.EndorsementKeyECC(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ekName := ek.Name()
+	ek.Close()
+
+	if err = client.ChangeHierarchyAuth(rwc, tpm2.HandleOwner, "", "owner"); err != nil {
+		t.Fatalf("failed to set owner auth: %v", err)
+	}
+	if err = client.Clear(rwc, ""); err != nil {
+		t.Fatalf("failed to clear TPM: %v", err)
+	}
+
+	// The owner seed (and auth) is reset, but the endorsement seed is not.
+	if srk, err = client.StorageRootKeyECC(rwc); err != nil {
+		t.Fatalf("can't create srk from template: %v", err)
+	}
+	defer srk.Close()
+	if reflect.DeepEqual(srk.Name(), name) {
+		t.Error("SRK did not change after clearing the TPM")
+	}
+	if ek, err = client.EndorsementKeyECC(rwc); err != nil {
+		t.Fatal(err)
+	}
+	defer ek.Close()
+	if !reflect.DeepEqual(ek.Name(), ekName) {
+		t.Error("EK changed after clearing the TPM")
+	}
+	if err = client.ChangeHierarchyAuth(rwc, tpm2.HandleOwner, "", ""); err != nil {
+		t.Errorf("owner auth was not reset: %v", err)
+	}
+}
+
+func TestSetHierarchyEnabled(t *testing.T) {
+	test.SkipOnRealTPM(t)
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	if err := client.SetHierarchyEnabled(rwc, tpm2.HandleEndorsement, false, ""); err != nil {
+		t.Fatalf("failed to disable endorsement hierarchy: %v", err)
+	}
+	if ek, err := client.EndorsementKeyRSA(rwc); err == nil {
+		ek.Close()
+		t.Error("creating an EK in a disabled hierarchy should fail")
+	}
+	// The simulator's platform auth is empty.
+	if err := client.SetHierarchyEnabled(rwc, tpm2.HandleEndorsement, true, ""); err != nil {
+		t.Fatalf("failed to enable endorsement hierarchy: %v", err)
+	}
+	ek, err := client.EndorsementKeyRSA(rwc)
+	if err != nil {
+		t.Fatalf("failed to create EK after enabling hierarchy: %v", err)
+	}
+	ek.Close()
+}
+
+func TestWithHierarchyAuth(t *testing.T) {
+	test.SkipOnRealTPM(t)
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	auth := client.HierarchyAuth{Owner: "owner", Endorsement: "endorsement"}
+	if err := client.ChangeHierarchyAuth(rwc, tpm2.HandleOwner, "", auth.Owner); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.ChangeHierarchyAuth(rwc, tpm2.HandleEndorsement, "", auth.Endorsement); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.EndorsementKeyRSA(rwc); !errors.Is(err, client.ErrAuthFail) {
+		t.Errorf("creating the EK without endorsement auth returned %v, want ErrAuthFail", err)
+	}
+
+	tpm := client.WithHierarchyAuth(rwc, auth)
+	ek, err := client.EndorsementKeyRSA(tpm)
+	if err != nil {
+		t.Fatalf("failed to create EK: %v", err)
+	}
+	defer ek.Close()
+	ak, err := client.AttestationKeyRSA(tpm)
+	if err != nil {
+		t.Fatalf("failed to create AK: %v", err)
+	}
+	defer ak.Close()
+
+	// Using the EK requires endorsement authorization.
+	secret := []byte("super secret credential")
+	credBlob, encSecret, err := server.MakeCredential(ek.PublicKey(), ak.Name(), secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ak.ActivateCredential(ek, credBlob, encSecret)
+	if err != nil {
+		t.Fatalf("failed to activate credential: %v", err)
+	}
+	if !bytes.Equal(got, secret) {
+		t.Errorf("got secret %q, want %q", got, secret)
+	}
+
+	srk, err := client.NewKey(tpm, tpm2.HandleOwner, client.SRKTemplateECC())
+	if err != nil {
+		t.Fatalf("can't create srk from template: %v", err)
+	}
+	defer srk.Close()
+	if err = srk.Persist(client.SRKECCReservedHandle); err != nil {
+		t.Fatalf("failed to persist SRK: %v", err)
+	}
+	if err = client.EvictPersistent(tpm, client.SRKECCReservedHandle); err != nil {
+		t.Fatalf("failed to evict SRK: %v", err)
+	}
+
+	index := tpmutil.Handle(0x1500000)
+	if err = client.DefineNV(tpm, index, 4, tpm2.AttrOwnerRead|tpm2.AttrAuthWrite, "", nil); err != nil {
+		t.Fatalf("failed to define NV index: %v", err)
+	}
+	defer client.UndefineNV(tpm, index)
+	if err = client.NVWrite(tpm, index, nil, []byte("data")); err != nil {
+		t.Fatalf("failed to write NV index: %v", err)
+	}
+	data, err := client.NVRead(tpm, index, &client.NVAuth{Handle: tpm2.HandleOwner})
+	if err != nil {
+		t.Fatalf("failed to read NV index with owner auth: %v", err)
+	}
+	if !bytes.Equal(data, []byte("data")) {
+		t.Errorf("got NV data %q, want %q", data, "data")
+	}
+}
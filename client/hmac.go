@@ -0,0 +1,152 @@
+package client
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+const (
+	cmdHMAC      tpmutil.Command = 0x00000155
+	cmdHMACStart tpmutil.Command = 0x0000015B
+)
+
+// NewHMACKey generates and loads a primary key from HMACTemplate in the
+// provided hierarchy (tpm2.Handle{Owner|Endorsement|Platform|Null}). As the
+// key is derived from the hierarchy's seed, the same key is created on every
+// call (until the hierarchy is cleared), so it can be used to derive stable
+// tokens without the key ever leaving the TPM.
+func NewHMACKey(rw io.ReadWriter, hierarchy tpmutil.Handle, hash tpm2.Algorithm) (*Key, error) {
+	return NewKey(rw, hierarchy, HMACTemplate(hash))
+}
+
+// HMAC computes the HMAC of data using an HMAC key (such as one created by
+// NewHMACKey). Data of any size is supported.
+func (k *Key) HMAC(data []byte) ([]byte, error) {
+	if err := k.checkHMACKey(); err != nil {
+		return nil, err
+	}
+	if len(data) > maxBufferSize {
+		seq, err := k.NewHMACSequence()
+		if err != nil {
+			return nil, err
+		}
+		defer seq.Close()
+		if _, err = seq.Write(data); err != nil {
+			return nil, err
+		}
+		return seq.Sum()
+	}
+
+	auth, err := k.session.Auth()
+	if err != nil {
+		return nil, err
+	}
+	// Hash AlgNull uses the hash of the key's scheme.
+	resp, err := runCommand(k.rw, cmdHMAC, []tpmutil.Handle{k.handle}, []tpm2.AuthCommand{auth},
+		tpmutil.U16Bytes(data), tpm2.AlgNull)
+	if err != nil {
+		return nil, fmt.Errorf("TPM2_HMAC failed: %w", err)
+	}
+	var mac tpmutil.U16Bytes
+	if _, err = tpmutil.Unpack(resp, &mac); err != nil {
+		return nil, fmt.Errorf("decoding TPM2_HMAC response: %w", err)
+	}
+	return mac, nil
+}
+
+// VerifyHMAC computes the HMAC of data (as in HMAC), and checks that it is
+// equal to mac.
+func (k *Key) VerifyHMAC(data, mac []byte) error {
+	expected, err := k.HMAC(data)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(expected, mac) == 0 {
+		return errors.New("HMAC does not match")
+	}
+	return nil
+}
+
+func (k *Key) checkHMACKey() error {
+	if k.pubArea.Type != tpm2.AlgKeyedHash || k.pubArea.KeyedHashParameters == nil ||
+		k.pubArea.KeyedHashParameters.Alg != tpm2.AlgHMAC || !k.hasAttribute(tpm2.FlagSign) {
+		return fmt.Errorf("not an HMAC key")
+	}
+	return nil
+}
+
+// HMACSequence computes an HMAC in the TPM over data written in multiple
+// calls, using TPM2_HMAC_Start, TPM2_SequenceUpdate and
+// TPM2_SequenceComplete. Users of HMACSequence should call Close() if Sum() is
+// not called, so that the sequence handle is freed. HMACSequence is not safe
+// for concurrent use.
+type HMACSequence struct {
+	rw     io.ReadWriter
+	handle tpmutil.Handle
+	buf    []byte
+	done   bool
+}
+
+// NewHMACSequence starts an HMAC sequence using an HMAC key. The sequence can
+// still be used if the key is closed.
+func (k *Key) NewHMACSequence() (*HMACSequence, error) {
+	if err := k.checkHMACKey(); err != nil {
+		return nil, err
+	}
+	auth, err := k.session.Auth()
+	if err != nil {
+		return nil, err
+	}
+	// The sequence has an empty auth value.
+	handle, _, err := runCommandWithHandle(k.rw, cmdHMACStart, []tpmutil.Handle{k.handle}, []tpm2.AuthCommand{auth},
+		tpmutil.U16Bytes(nil), tpm2.AlgNull)
+	if err != nil {
+		return nil, fmt.Errorf("TPM2_HMAC_Start failed: %w", err)
+	}
+	return &HMACSequence{rw: k.rw, handle: handle}, nil
+}
+
+// Write adds data to the HMAC. It implements io.Writer.
+func (s *HMACSequence) Write(p []byte) (int, error) {
+	if s.done {
+		return 0, errors.New("HMAC sequence already completed")
+	}
+	s.buf = append(s.buf, p...)
+	// Keep the last (possibly full) chunk for SequenceComplete.
+	for len(s.buf) > maxBufferSize {
+		if err := tpm2.SequenceUpdate(s.rw, "", s.handle, s.buf[:maxBufferSize]); err != nil {
+			return 0, fmt.Errorf("TPM2_SequenceUpdate failed: %w", tpmError(err))
+		}
+		s.buf = s.buf[maxBufferSize:]
+	}
+	return len(p), nil
+}
+
+// Sum completes the sequence, returning the HMAC of all written data. The
+// sequence cannot be used afterwards.
+func (s *HMACSequence) Sum() ([]byte, error) {
+	if s.done {
+		return nil, errors.New("HMAC sequence already completed")
+	}
+	mac, _, err := tpm2.SequenceComplete(s.rw, "", s.handle, tpm2.HandleNull, s.buf)
+	if err != nil {
+		return nil, fmt.Errorf("TPM2_SequenceComplete failed: %w", tpmError(err))
+	}
+	// The TPM flushes the sequence once it is completed.
+	s.done = true
+	return mac, nil
+}
+
+// Close frees the sequence handle, if the sequence was not completed.
+func (s *HMACSequence) Close() error {
+	if s.done {
+		return nil
+	}
+	s.done = true
+	return tpm2.FlushContext(s.rw, s.handle)
+}
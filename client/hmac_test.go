@@ -0,0 +1,98 @@
+package client_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestHMAC(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	key, err := client.NewHMACKey(rwc, tpm2.HandleOwner, tpm2.AlgSHA256)
+	if err != nil {
+		t.Fatalf("failed to create HMAC key: %v", err)
+	}
+	defer key.Close()
+
+	// Sizes around the 1024 byte TPM2B_MAX_BUFFER boundary
+	for _, size := range []int{0, 1, 1024, 1025, 3000} {
+		data := make([]byte, size)
+		if _, err := rand.Read(data); err != nil {
+			t.Fatal(err)
+		}
+		mac, err := key.HMAC(data)
+		if err != nil {
+			t.Fatalf("failed to compute HMAC of %d bytes: %v", size, err)
+		}
+		if len(mac) != 32 {
+			t.Errorf("got HMAC of length %d, want 32", len(mac))
+		}
+
+		seq, err := key.NewHMACSequence()
+		if err != nil {
+			t.Fatalf("failed to start HMAC sequence: %v", err)
+		}
+		for i := 0; i < size; i += 100 {
+			end := i + 100
+			if end > size {
+				end = size
+			}
+			if _, err = seq.Write(data[i:end]); err != nil {
+				t.Fatalf("failed to update HMAC sequence: %v", err)
+			}
+		}
+		seqMAC, err := seq.Sum()
+		if err != nil {
+			t.Fatalf("failed to complete HMAC sequence: %v", err)
+		}
+		if !bytes.Equal(seqMAC, mac) {
+			t.Errorf("HMAC sequence of %d bytes does not match HMAC", size)
+		}
+
+		if err = key.VerifyHMAC(data, mac); err != nil {
+			t.Errorf("failed to verify HMAC of %d bytes: %v", size, err)
+		}
+		if err = key.VerifyHMAC(append(data, 0), mac); err == nil {
+			t.Errorf("verifying HMAC of modified data should fail")
+		}
+	}
+
+	// The primary key is derived from the hierarchy seed, so is the same.
+	key2, err := client.NewHMACKey(rwc, tpm2.HandleOwner, tpm2.AlgSHA256)
+	if err != nil {
+		t.Fatalf("failed to recreate HMAC key: %v", err)
+	}
+	defer key2.Close()
+	mac1, err := key.HMAC([]byte("token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac2, err := key2.HMAC([]byte("token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(mac1, mac2) {
+		t.Error("recreated HMAC key computes a different HMAC")
+	}
+}
+
+func TestHMACWrongKey(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	srk, err := client.StorageRootKeyRSA(rwc)
+	if err != nil {
+		t.Fatalf("can't create srk from template: %v", err)
+	}
+	defer srk.Close()
+	if _, err = srk.HMAC([]byte("data")); err == nil {
+		t.Error("computing an HMAC with a non-HMAC key should fail")
+	}
+}
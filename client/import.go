@@ -10,13 +10,16 @@ import (
 )
 
 func loadHandle(k *Key, blob *pb.ImportBlob) (tpmutil.Handle, error) {
+	if err := notinternal.CheckBlobHeader(blob); err != nil {
+		return tpm2.HandleNull, err
+	}
 	auth, err := k.session.Auth()
 	if err != nil {
 		return tpm2.HandleNull, err
 	}
 	private, err := tpm2.Import(k.rw, k.Handle(), auth, blob.PublicArea, blob.Duplicate, blob.EncryptedSeed, nil, nil)
 	if err != nil {
-		return tpm2.HandleNull, fmt.Errorf("import failed: %w", err)
+		return tpm2.HandleNull, fmt.Errorf("import failed: %w", tpmError(err))
 	}
 
 	auth, err = k.session.Auth()
@@ -25,7 +28,7 @@ func loadHandle(k *Key, blob *pb.ImportBlob) (tpmutil.Handle, error) {
 	}
 	handle, _, err := tpm2.LoadUsingAuth(k.rw, k.Handle(), auth, blob.PublicArea, private)
 	if err != nil {
-		return tpm2.HandleNull, fmt.Errorf("load failed: %w", err)
+		return tpm2.HandleNull, fmt.Errorf("load failed: %w", tpmError(err))
 	}
 	return handle, nil
 }
@@ -40,7 +43,7 @@ func (k *Key) Import(blob *pb.ImportBlob) ([]byte, error) {
 	}
 	defer tpm2.FlushContext(k.rw, handle)
 
-	unsealSession, err := newPCRSession(k.rw, notinternal.PCRSelection(blob.Pcrs))
+	unsealSession, err := newPCRSession(k.rw, notinternal.PCRSelection(blob.Pcrs), false, "", SessionHashAlgTpm)
 	if err != nil {
 		return nil, err
 	}
@@ -52,7 +55,7 @@ func (k *Key) Import(blob *pb.ImportBlob) ([]byte, error) {
 	}
 	out, err := tpm2.UnsealWithSession(k.rw, auth.Session, handle, "")
 	if err != nil {
-		return nil, fmt.Errorf("unseal failed: %w", err)
+		return nil, fmt.Errorf("unseal failed: %w", tpmPolicyError(err))
 	}
 	return out, nil
 }
@@ -61,6 +64,20 @@ func (k *Key) Import(blob *pb.ImportBlob) ([]byte, error) {
 // The parent key must be an encryption key (signing keys cannot be used).
 // The req parameter should come from server.CreateSigningKeyImportBlob.
 func (k *Key) ImportSigningKey(blob *pb.ImportBlob) (key *Key, err error) {
+	return k.ImportKey(blob)
+}
+
+// ImportKey returns the key contained in an encoded import request, which can
+// only be used on this TPM. The parent key must be an encryption key (signing
+// keys cannot be used). The req parameter should come from one of the
+// server.Create*KeyImportBlob functions.
+func (k *Key) ImportKey(blob *pb.ImportBlob) (key *Key, err error) {
+	return k.ImportKeyWithAuth(blob, "")
+}
+
+// ImportKeyWithAuth is like ImportKey, but for keys with an authorization
+// value, such as keys duplicated from another TPM with Key.Duplicate.
+func (k *Key) ImportKeyWithAuth(blob *pb.ImportBlob, authValue string) (key *Key, err error) {
 	handle, err := loadHandle(k, blob)
 	if err != nil {
 		return nil, err
@@ -76,7 +93,9 @@ func (k *Key) ImportSigningKey(blob *pb.ImportBlob) (key *Key, err error) {
 	if key.pubArea, _, _, err = tpm2.ReadPublic(k.rw, handle); err != nil {
 		return
 	}
-	if key.session, err = newPCRSession(k.rw, notinternal.PCRSelection(blob.Pcrs)); err != nil {
+	if len(blob.GetPcrs().GetPcrs()) == 0 {
+		key.session = passwordSession{authValue}
+	} else if key.session, err = newPCRSession(k.rw, notinternal.PCRSelection(blob.Pcrs), false, authValue, SessionHashAlgTpm); err != nil {
 		return
 	}
 	return key, key.finish()
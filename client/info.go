@@ -0,0 +1,164 @@
+package client
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+const cmdSelfTest tpmutil.Command = 0x00000143
+
+// First property of each group of TPM properties (PT_FIXED and PT_VAR).
+const (
+	ptFixed    = uint32(tpm2.FamilyIndicator)
+	ptVariable = uint32(tpm2.TPMAPermanent)
+)
+
+// SelfTest runs TPM2_SelfTest. If full is false, only the functions which
+// have not yet been tested are tested. If full is true, all functions are
+// tested. Some TPMs run the tests in the background, returning a
+// tpm2.Warning with code tpm2.RCTesting until they have completed.
+func SelfTest(rw io.ReadWriter, full bool) error {
+	var fullTest byte
+	if full {
+		fullTest = 1
+	}
+	if _, err := runCommand(rw, cmdSelfTest, nil, nil, fullTest); err != nil {
+		return fmt.Errorf("TPM2_SelfTest failed: %w", err)
+	}
+	return nil
+}
+
+// TPMInfo describes the TPM's vendor, firmware, and capabilities, as returned
+// by GetInfo.
+type TPMInfo struct {
+	// Manufacturer is the TCG vendor ID of the TPM manufacturer (e.g. "IBM",
+	// "INTC", "GOOG").
+	Manufacturer string
+	// VendorString is the free-form vendor description of the TPM.
+	VendorString string
+	// FirmwareVersion is the vendor-defined firmware version, with
+	// TPM_PT_FIRMWARE_VERSION_1 in the upper 32 bits.
+	FirmwareVersion uint64
+	// Family is the TPM family (e.g. "2.0").
+	Family string
+	// SpecLevel and SpecRevision are the level and revision of the TPM
+	// specification implemented by the TPM (the revision is multiplied by 100,
+	// so 138 corresponds to revision 1.38).
+	SpecLevel    uint32
+	SpecRevision uint32
+	// SpecYear and SpecDayOfYear are the specification's release date.
+	SpecYear      uint32
+	SpecDayOfYear uint32
+	// Algorithms are the algorithms implemented by the TPM.
+	Algorithms []tpm2.AlgorithmDescription
+	// PCRBanks are the TPM's PCR banks, with the PCRs allocated in each.
+	PCRBanks []tpm2.PCRSelection
+	// Properties holds all fixed (PT_FIXED) and variable (PT_VAR) TPM
+	// properties, including the buffer, RAM and NV limits of the TPM.
+	Properties map[tpm2.TPMProp]uint32
+}
+
+// GetInfo queries the TPM for information about its vendor, firmware,
+// supported algorithms, PCR banks and limits, using TPM2_GetCapability.
+func GetInfo(rw io.ReadWriter) (*TPMInfo, error) {
+	props, err := getProperties(rw, ptFixed)
+	if err != nil {
+		return nil, err
+	}
+	varProps, err := getProperties(rw, ptVariable)
+	if err != nil {
+		return nil, err
+	}
+	for tag, value := range varProps {
+		props[tag] = value
+	}
+	algs, err := getAlgorithms(rw)
+	if err != nil {
+		return nil, err
+	}
+	banks, err := implementedPCRs(rw)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TPMInfo{
+		Manufacturer: propertyString(props, tpm2.Manufacturer),
+		VendorString: propertyString(props, tpm2.VendorString1, tpm2.VendorString2,
+			tpm2.VendorString3, tpm2.VendorString4),
+		FirmwareVersion: uint64(props[tpm2.FirmwareVersion1])<<32 | uint64(props[tpm2.FirmwareVersion2]),
+		Family:          propertyString(props, tpm2.FamilyIndicator),
+		SpecLevel:       props[tpm2.SpecLevel],
+		SpecRevision:    props[tpm2.SpecRevision],
+		SpecYear:        props[tpm2.SpecYear],
+		SpecDayOfYear:   props[tpm2.SpecDayOfYear],
+		Algorithms:      algs,
+		PCRBanks:        banks,
+		Properties:      props,
+	}, nil
+}
+
+// getProperties returns all TPM properties in the group starting at first,
+// making multiple calls to the TPM if necessary.
+func getProperties(rw io.ReadWriter, first uint32) (map[tpm2.TPMProp]uint32, error) {
+	props := make(map[tpm2.TPMProp]uint32)
+	for property := first; ; {
+		vals, moreData, err := tpm2.GetCapability(rw, tpm2.CapabilityTPMProperties, math.MaxUint32, property)
+		if err != nil {
+			return nil, fmt.Errorf("listing TPM properties: %w", tpmError(err))
+		}
+		for _, val := range vals {
+			prop, ok := val.(tpm2.TaggedProperty)
+			if !ok {
+				return nil, fmt.Errorf("unexpected data from GetCapability")
+			}
+			// The TPM continues with the following groups.
+			if uint32(prop.Tag)&^0xff != first {
+				return props, nil
+			}
+			props[prop.Tag] = prop.Value
+			property = uint32(prop.Tag) + 1
+		}
+		if !moreData || len(vals) == 0 {
+			return props, nil
+		}
+	}
+}
+
+func getAlgorithms(rw io.ReadWriter) ([]tpm2.AlgorithmDescription, error) {
+	var algs []tpm2.AlgorithmDescription
+	for alg := uint32(0); ; {
+		vals, moreData, err := tpm2.GetCapability(rw, tpm2.CapabilityAlgs, math.MaxUint32, alg)
+		if err != nil {
+			return nil, fmt.Errorf("listing algorithms: %w", tpmError(err))
+		}
+		for _, val := range vals {
+			desc, ok := val.(tpm2.AlgorithmDescription)
+			if !ok {
+				return nil, fmt.Errorf("unexpected data from GetCapability")
+			}
+			algs = append(algs, desc)
+			alg = uint32(desc.ID) + 1
+		}
+		if !moreData || len(vals) == 0 {
+			return algs, nil
+		}
+	}
+}
+
+// propertyString decodes properties holding (up to 4) ASCII characters, as
+// used for the manufacturer and vendor strings.
+func propertyString(props map[tpm2.TPMProp]uint32, tags ...tpm2.TPMProp) string {
+	var buf []byte
+	for _, tag := range tags {
+		var chars [4]byte
+		binary.BigEndian.PutUint32(chars[:], props[tag])
+		buf = append(buf, chars[:]...)
+	}
+	return strings.TrimSpace(strings.ReplaceAll(string(buf), "\x00", ""))
+}
@@ -0,0 +1,58 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestSelfTest(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	for _, full := range []bool{false, true} {
+		if err := client.SelfTest(rwc, full); err != nil {
+			t.Errorf("self test (full=%v) failed: %v", full, err)
+		}
+	}
+}
+
+func TestGetInfo(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	info, err := client.GetInfo(rwc)
+	if err != nil {
+		t.Fatalf("failed to get TPM info: %v", err)
+	}
+	if info.Family != "2.0" {
+		t.Errorf("got TPM family %q, want 2.0", info.Family)
+	}
+	if info.Manufacturer == "" {
+		t.Error("got empty manufacturer")
+	}
+
+	var hasSHA256 bool
+	for _, alg := range info.Algorithms {
+		if alg.ID == tpm2.AlgSHA256 {
+			hasSHA256 = true
+		}
+	}
+	if !hasSHA256 {
+		t.Errorf("algorithms %v do not contain SHA256", info.Algorithms)
+	}
+	if len(info.PCRBanks) == 0 {
+		t.Error("got no PCR banks")
+	}
+
+	// Both fixed and variable properties are returned.
+	if info.Properties[tpm2.PCRCount] != client.NumPCRs {
+		t.Errorf("got %d PCRs, want %d", info.Properties[tpm2.PCRCount], client.NumPCRs)
+	}
+	if _, ok := info.Properties[tpm2.HRTransientAvail]; !ok {
+		t.Error("missing TPM_PT_HR_TRANSIENT_AVAIL property")
+	}
+}
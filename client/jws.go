@@ -0,0 +1,186 @@
+package client
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/google/go-tpm/tpm2"
+)
+
+// The JWS algorithms (RFC 7518) matching each supported TPM signing scheme.
+var jwsAlgorithms = map[tpm2.Algorithm]map[tpm2.Algorithm]string{
+	tpm2.AlgRSASSA: {
+		tpm2.AlgSHA256: "RS256",
+		tpm2.AlgSHA384: "RS384",
+		tpm2.AlgSHA512: "RS512",
+	},
+	tpm2.AlgRSAPSS: {
+		tpm2.AlgSHA256: "PS256",
+		tpm2.AlgSHA384: "PS384",
+		tpm2.AlgSHA512: "PS512",
+	},
+	tpm2.AlgECDSA: {
+		tpm2.AlgSHA256: "ES256",
+		tpm2.AlgSHA384: "ES384",
+		tpm2.AlgSHA512: "ES512",
+	},
+}
+
+// The curves required by each ECDSA JWS algorithm.
+var jwsCurves = map[string]tpm2.EllipticCurve{
+	"ES256": tpm2.CurveNISTP256,
+	"ES384": tpm2.CurveNISTP384,
+	"ES512": tpm2.CurveNISTP521,
+}
+
+// JWSKeyID returns the JWS key ID ("kid") of the key with the given Name: the
+// unpadded base64url encoding of the Name. A verifier holding the key's
+// public area can compute the same key ID (using tpm2.Public.Name).
+func JWSKeyID(name tpm2.Name) (string, error) {
+	if name.Digest == nil {
+		return "", fmt.Errorf("name has no digest")
+	}
+	encoded, err := name.Digest.Encode()
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(encoded), nil
+}
+
+// JWSSigner signs JSON Web Signatures (RFC 7515) and JSON Web Tokens
+// (RFC 7519) with a TPM key. This can be used for tokens rooted in the TPM,
+// such as service account tokens.
+type JWSSigner struct {
+	key *Key
+	alg string
+	kid string
+}
+
+// JWSSigner returns a JWSSigner for the key. The JWS algorithm is given by
+// the key's signing scheme: RSASSA gives RS256, RS384 or RS512, RSAPSS gives
+// PS256, PS384 or PS512, and ECDSA (on the matching curve) gives ES256, ES384
+// or ES512. Restricted keys can also be used (see SignData).
+//
+// For PS256, PS384 and PS512, the TPM may use a salt length longer than the
+// digest (see GetSigner), so verifiers must accept any salt length.
+func (k *Key) JWSSigner() (*JWSSigner, error) {
+	scheme, err := getSigningScheme(k)
+	if err != nil {
+		return nil, err
+	}
+	alg, ok := jwsAlgorithms[scheme.Alg][scheme.Hash]
+	if !ok {
+		return nil, fmt.Errorf("unsupported signing scheme for JWS: %v with %v", scheme.Alg, scheme.Hash)
+	}
+	if curve, ok := jwsCurves[alg]; ok && k.pubArea.ECCParameters.CurveID != curve {
+		return nil, fmt.Errorf("%s cannot be used with curve %v", alg, k.pubArea.ECCParameters.CurveID)
+	}
+	kid, err := JWSKeyID(k.name)
+	if err != nil {
+		return nil, err
+	}
+	return &JWSSigner{key: k, alg: alg, kid: kid}, nil
+}
+
+// Algorithm returns the JWS algorithm ("alg") of the signer.
+func (s *JWSSigner) Algorithm() string {
+	return s.alg
+}
+
+// KeyID returns the JWS key ID ("kid") of the signer (see JWSKeyID).
+func (s *JWSSigner) KeyID() string {
+	return s.kid
+}
+
+// Public returns the public key of the signer.
+func (s *JWSSigner) Public() crypto.PublicKey {
+	return s.key.PublicKey()
+}
+
+// SignPayload returns the JWS signature of signingInput (the encoded header
+// and payload, separated by a "."), in the encoding used by JWS: ECDSA
+// signatures are the concatenation of R and S.
+func (s *JWSSigner) SignPayload(signingInput []byte) ([]byte, error) {
+	return s.key.signDataConcat(signingInput)
+}
+
+// signDataConcat signs data like SignData, but with ECDSA signatures encoded
+// as the concatenation of R and S (as used by JWS and COSE).
+func (k *Key) signDataConcat(data []byte) ([]byte, error) {
+	sig, err := k.signData(data)
+	if err != nil {
+		return nil, err
+	}
+	if sig.Alg != tpm2.AlgECDSA {
+		return getSignature(sig)
+	}
+	size := (k.pubKey.(*ecdsa.PublicKey).Curve.Params().BitSize + 7) / 8
+	concatSig := make([]byte, 2*size)
+	sig.ECC.R.FillBytes(concatSig[:size])
+	sig.ECC.S.FillBytes(concatSig[size:])
+	return concatSig, nil
+}
+
+// SignCompact returns the JWS compact serialization of payload, signed by the
+// signer. The "alg" and "kid" header parameters are set by the signer, and
+// override the provided header (which can be nil).
+func (s *JWSSigner) SignCompact(header map[string]interface{}, payload []byte) (string, error) {
+	fullHeader := map[string]interface{}{"alg": s.alg, "kid": s.kid}
+	for name, value := range header {
+		if name != "alg" && name != "kid" {
+			fullHeader[name] = value
+		}
+	}
+	encodedHeader, err := json.Marshal(fullHeader)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode header: %w", err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(encodedHeader) + "." +
+		base64.RawURLEncoding.EncodeToString(payload)
+	sig, err := s.SignPayload([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// SignJWT returns a JWT with the provided claims, which are encoded as JSON.
+func (s *JWSSigner) SignJWT(claims interface{}) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode claims: %w", err)
+	}
+	return s.SignCompact(map[string]interface{}{"typ": "JWT"}, payload)
+}
+
+// JWK returns the public key of the signer as a JSON Web Key (RFC 7517), with
+// the signer's algorithm and key ID. This can be published (for example, in a
+// JWK Set) for verifiers.
+func (s *JWSSigner) JWK() ([]byte, error) {
+	jwk := map[string]string{"use": "sig", "alg": s.alg, "kid": s.kid}
+	switch pub := s.key.pubKey.(type) {
+	case *rsa.PublicKey:
+		jwk["kty"] = "RSA"
+		jwk["n"] = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk["kty"] = "EC"
+		jwk["crv"] = map[elliptic.Curve]string{
+			elliptic.P256(): "P-256",
+			elliptic.P384(): "P-384",
+			elliptic.P521(): "P-521",
+		}[pub.Curve]
+		jwk["x"] = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+		jwk["y"] = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+	default:
+		return nil, fmt.Errorf("unsupported public key type: %T", pub)
+	}
+	return json.Marshal(jwk)
+}
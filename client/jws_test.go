@@ -0,0 +1,185 @@
+package client_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+// Verify a JWS in compact serialization, returning its header and payload.
+func verifyJWS(t *testing.T, jws string, pub crypto.PublicKey) (map[string]interface{}, []byte) {
+	t.Helper()
+	parts := strings.Split(jws, ".")
+	if len(parts) != 3 {
+		t.Fatalf("JWS has %d parts, want 3", len(parts))
+	}
+	var decoded [3][]byte
+	for i, part := range parts {
+		var err error
+		if decoded[i], err = base64.RawURLEncoding.DecodeString(part); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var header map[string]interface{}
+	if err := json.Unmarshal(decoded[0], &header); err != nil {
+		t.Fatal(err)
+	}
+
+	hashes := map[string]crypto.Hash{"256": crypto.SHA256, "384": crypto.SHA384, "512": crypto.SHA512}
+	alg := header["alg"].(string)
+	hash := hashes[alg[2:]]
+	hasher := hash.New()
+	hasher.Write([]byte(parts[0] + "." + parts[1]))
+	digest := hasher.Sum(nil)
+	sig := decoded[2]
+
+	var err error
+	switch alg[:2] {
+	case "RS":
+		err = rsa.VerifyPKCS1v15(pub.(*rsa.PublicKey), hash, digest, sig)
+	case "PS":
+		err = rsa.VerifyPSS(pub.(*rsa.PublicKey), hash, digest, sig, nil)
+	case "ES":
+		r := new(big.Int).SetBytes(sig[:len(sig)/2])
+		s := new(big.Int).SetBytes(sig[len(sig)/2:])
+		if !ecdsa.Verify(pub.(*ecdsa.PublicKey), digest, r, s) {
+			err = rsa.ErrVerification
+		}
+	default:
+		t.Fatalf("unexpected alg %q", alg)
+	}
+	if err != nil {
+		t.Fatalf("JWS signature does not verify: %v", err)
+	}
+	return header, decoded[1]
+}
+
+func TestJWSSigner(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	signingAttrs := tpm2.FlagSign | tpm2.FlagFixedTPM | tpm2.FlagFixedParent |
+		tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth
+	rsaTemplate, err := client.KeyOpts{Algorithm: tpm2.AlgRSA, Attributes: signingAttrs}.Template()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pssTemplate := rsaTemplate
+	pssParams := *rsaTemplate.RSAParameters
+	pssParams.Sign = &tpm2.SigScheme{Alg: tpm2.AlgRSAPSS, Hash: tpm2.AlgSHA256}
+	pssTemplate.RSAParameters = &pssParams
+	eccTemplate, err := client.KeyOpts{Algorithm: tpm2.AlgECC, Attributes: signingAttrs}.Template()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384Template, err := client.KeyOpts{Algorithm: tpm2.AlgECC, Curve: tpm2.CurveNISTP384, Attributes: signingAttrs}.Template()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		template tpm2.Public
+		alg      string
+	}{
+		{"RS256", rsaTemplate, "RS256"},
+		{"PS256", pssTemplate, "PS256"},
+		{"ES256", eccTemplate, "ES256"},
+		{"ES384", p384Template, "ES384"},
+		{"RestrictedAK", client.AKTemplateECC(), "ES256"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key, err := client.NewKey(rwc, tpm2.HandleOwner, test.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer key.Close()
+			signer, err := key.JWSSigner()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if signer.Algorithm() != test.alg {
+				t.Errorf("got alg %q, want %q", signer.Algorithm(), test.alg)
+			}
+			name, err := key.PublicArea().Name()
+			if err != nil {
+				t.Fatal(err)
+			}
+			kid, err := client.JWSKeyID(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if signer.KeyID() != kid {
+				t.Errorf("got kid %q, want %q", signer.KeyID(), kid)
+			}
+
+			token, err := signer.SignJWT(map[string]interface{}{"sub": "service", "iat": 1600000000})
+			if err != nil {
+				t.Fatal(err)
+			}
+			header, payload := verifyJWS(t, token, key.PublicKey())
+			if header["alg"] != test.alg || header["kid"] != kid || header["typ"] != "JWT" {
+				t.Errorf("unexpected header: %v", header)
+			}
+			var claims map[string]interface{}
+			if err = json.Unmarshal(payload, &claims); err != nil {
+				t.Fatal(err)
+			}
+			if claims["sub"] != "service" {
+				t.Errorf("unexpected claims: %v", claims)
+			}
+
+			jwk, err := signer.JWK()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fields map[string]string
+			if err = json.Unmarshal(jwk, &fields); err != nil {
+				t.Fatal(err)
+			}
+			if fields["kid"] != kid || fields["alg"] != test.alg {
+				t.Errorf("unexpected JWK: %s", jwk)
+			}
+		})
+	}
+}
+
+func TestJWSSignerUnsupported(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	// ES256 requires the P-256 curve.
+	template, err := client.AKTemplateECCWithCurve(tpm2.CurveNISTP384)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.ECCParameters.Sign.Hash = tpm2.AlgSHA256
+	key, err := client.NewKey(rwc, tpm2.HandleOwner, template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer key.Close()
+	if _, err = key.JWSSigner(); err == nil {
+		t.Error("JWSSigner should fail for a P-384 key using SHA-256")
+	}
+
+	srk, err := client.StorageRootKeyRSA(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srk.Close()
+	if _, err = srk.JWSSigner(); err == nil {
+		t.Error("JWSSigner should fail for a non-signing key")
+	}
+}
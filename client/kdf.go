@@ -0,0 +1,45 @@
+package client
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// The largest amount of key material DeriveKey can return (the 32-bit output
+// length field of the KDF is in bits).
+const maxDerivedKeySize = (1<<32 - 1) / 8
+
+// DeriveKey derives size bytes of key material from an HMAC key (such as one
+// created by NewHMACKey), with every HMAC computed by the TPM. This uses the
+// counter mode KDF of NIST SP 800-108 (the same construction as the TPM's
+// KDFa), where each block is the HMAC of:
+//
+//	counter (32-bit) || label || 0x00 || context || size in bits (32-bit)
+//
+// Different labels (the purpose) and contexts (e.g. a user ID) give
+// independent subkeys, so an application can have a hierarchy of keys rooted
+// in a single TPM-resident key.
+func (k *Key) DeriveKey(label string, context []byte, size int) ([]byte, error) {
+	if err := k.checkHMACKey(); err != nil {
+		return nil, err
+	}
+	if size <= 0 || size > maxDerivedKeySize {
+		return nil, fmt.Errorf("invalid derived key size %d", size)
+	}
+	bits := make([]byte, 4)
+	binary.BigEndian.PutUint32(bits, uint32(size*8))
+	suffix := append([]byte(label), 0)
+	suffix = append(append(suffix, context...), bits...)
+
+	var out []byte
+	for counter := uint32(1); len(out) < size; counter++ {
+		block := make([]byte, 4)
+		binary.BigEndian.PutUint32(block, counter)
+		mac, err := k.HMAC(append(block, suffix...))
+		if err != nil {
+			return nil, fmt.Errorf("deriving key block %d: %w", counter, err)
+		}
+		out = append(out, mac...)
+	}
+	return out[:size], nil
+}
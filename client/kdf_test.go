@@ -0,0 +1,75 @@
+package client_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestDeriveKey(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	srk, err := client.StorageRootKeyRSA(rwc)
+	if err != nil {
+		t.Fatalf("can't create srk from template: %v", err)
+	}
+	defer srk.Close()
+
+	// Create an HMAC key with a known secret, so the derived keys can be
+	// checked against a software KDFa.
+	secret := []byte("known HMAC key for testing")
+	template := client.HMACTemplate(tpm2.AlgSHA256)
+	template.Attributes &= ^tpm2.FlagSensitiveDataOrigin
+	priv, pub, _, _, _, err := tpm2.CreateKeyWithSensitive(rwc, srk.Handle(), tpm2.PCRSelection{}, "", "", template, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handle, _, err := tpm2.Load(rwc, srk.Handle(), "", pub, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	context, err := tpm2.ContextSave(rwc, handle)
+	tpm2.FlushContext(rwc, handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := client.LoadKeyContext(rwc, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer key.Close()
+
+	for _, size := range []int{16, 32, 33, 100} {
+		derived, err := key.DeriveKey("purpose", []byte("context"), size)
+		if err != nil {
+			t.Fatalf("failed to derive %d bytes: %v", size, err)
+		}
+		expected, err := tpm2.KDFa(tpm2.AlgSHA256, secret, "purpose", []byte("context"), nil, size*8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(derived, expected) {
+			t.Errorf("derived %d bytes %x, want %x", size, derived, expected)
+		}
+	}
+
+	other, err := key.DeriveKey("other purpose", []byte("context"), 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	derived, err := key.DeriveKey("purpose", []byte("context"), 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(other, derived) {
+		t.Error("keys derived with different labels should differ")
+	}
+	if _, err = key.DeriveKey("purpose", nil, 0); err == nil {
+		t.Error("deriving an empty key should fail")
+	}
+}
@@ -0,0 +1,212 @@
+package client
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// Attributes used by KeyOpts for symmetric and HMAC keys, when none are
+// specified.
+const defaultSymmetricAttributes = tpm2.FlagFixedTPM | tpm2.FlagFixedParent |
+	tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth
+
+// KeyOpts describes a key to be created with NewKeyWithOpts, as an
+// alternative to writing a tpm2.Public template by hand. The zero value
+// describes a restricted RSA-2048 signing key, identical to AKTemplateRSA.
+//
+// The remaining parameters of the template are derived from the attributes:
+//   - Signing keys sign with RSASSA using SHA256, or with ECDSA using the hash
+//     matching the strength of the curve (SM2 using SM3 on the SM2 curve),
+//     unless SignScheme is set.
+//   - Restricted decryption keys (such as SRKs) protect their children with
+//     AES-128 in CFB mode (SM4-128 on the SM2 curve).
+//   - Unrestricted decryption keys (including keys which can both sign and
+//     decrypt) have no scheme, so any scheme can be used with them.
+type KeyOpts struct {
+	// Algorithm is the type of the key: tpm2.AlgRSA (the default),
+	// tpm2.AlgECC, tpm2.AlgSymCipher (an AES key, in CFB mode) or
+	// tpm2.AlgKeyedHash (an HMAC key, using SHA256).
+	Algorithm tpm2.Algorithm
+	// Curve is the curve of ECC keys: NIST P-256 (the default), P-384, P-521
+	// or tpm2.CurveSM2P256.
+	Curve tpm2.EllipticCurve
+	// Bits is the size of RSA keys (default 2048) or AES keys (default 128).
+	Bits uint16
+	// Attributes of the key. If zero, these default to FlagSignerDefault for
+	// RSA and ECC keys, FlagSign for HMAC keys and FlagSign|FlagDecrypt for
+	// AES keys (with FlagFixedTPM, FlagFixedParent, FlagSensitiveDataOrigin
+	// and FlagUserWithAuth). If AuthPolicy is set, FlagUserWithAuth is then
+	// removed, so that the policy is required to use the key.
+	Attributes tpm2.KeyProp
+	// AuthPolicy is an optional policy that must be satisfied to use the key.
+	// Keys created by NewKeyWithOpts satisfy the policy automatically, using
+	// Auth for any PolicyAuthValue.
+	AuthPolicy Policy
+	// Auth is the authorization value (password) of the key.
+	Auth string
+	// ParentAuth is the authorization value of the parent, when the key is
+	// created under a key (such as a per-tenant storage key) rather than a
+	// hierarchy. The authorization of hierarchies comes from the TPM (see
+	// HierarchyAuthGetter).
+	ParentAuth string
+	// SignScheme is the signing scheme of RSA and ECC signing keys:
+	// tpm2.AlgRSASSA or tpm2.AlgRSAPSS for RSA keys, and tpm2.AlgECDSA,
+	// AlgECSchnorr or AlgSM2 for ECC keys. If its hash is unset, the default hash is
+	// used. tpm2.AlgNull creates an unrestricted key without a scheme, so
+	// the scheme is chosen when signing (see Key.SignDataWithScheme).
+	SignScheme *tpm2.SigScheme
+	// CreationPCRs are the PCRs whose values at creation time are recorded
+	// in the key's creation data, so they can be proven with
+	// Key.CertifyCreation.
+	CreationPCRs tpm2.PCRSelection
+}
+
+// Template returns the key template described by the options.
+func (o KeyOpts) Template() (tpm2.Public, error) {
+	public := tpm2.Public{
+		Type:       o.Algorithm,
+		NameAlg:    SessionHashAlgTpm,
+		Attributes: o.Attributes,
+	}
+	if public.Type == 0 {
+		public.Type = tpm2.AlgRSA
+	}
+	if public.Attributes == 0 {
+		switch public.Type {
+		case tpm2.AlgKeyedHash:
+			public.Attributes = tpm2.FlagSign | defaultSymmetricAttributes
+		case tpm2.AlgSymCipher:
+			public.Attributes = tpm2.FlagSign | tpm2.FlagDecrypt | defaultSymmetricAttributes
+		default:
+			public.Attributes = tpm2.FlagSignerDefault
+		}
+		if o.AuthPolicy != nil {
+			public.Attributes &^= tpm2.FlagUserWithAuth
+		}
+	}
+	if o.AuthPolicy != nil {
+		digest, err := PolicyDigest(o.AuthPolicy, SessionHashAlg)
+		if err != nil {
+			return tpm2.Public{}, fmt.Errorf("computing auth policy: %w", err)
+		}
+		public.AuthPolicy = digest
+	}
+
+	attrs := public.Attributes
+	if attrs&(tpm2.FlagSign|tpm2.FlagDecrypt) == 0 {
+		return tpm2.Public{}, fmt.Errorf("key must have FlagSign or FlagDecrypt")
+	}
+	signOnly := attrs&tpm2.FlagSign != 0 && attrs&tpm2.FlagDecrypt == 0
+	restrictedDecrypt := attrs&tpm2.FlagRestricted != 0 && attrs&tpm2.FlagDecrypt != 0
+
+	switch public.Type {
+	case tpm2.AlgRSA:
+		bits := o.Bits
+		if bits == 0 {
+			bits = 2048
+		}
+		public.RSAParameters = &tpm2.RSAParams{KeyBits: bits}
+		if signOnly {
+			scheme, err := o.signScheme(public.Type, tpm2.AlgRSASSA, tpm2.AlgSHA256)
+			if err != nil {
+				return tpm2.Public{}, err
+			}
+			public.RSAParameters.Sign = scheme
+		}
+		if restrictedDecrypt {
+			public.RSAParameters.Symmetric = defaultSymScheme()
+			public.RSAParameters.ModulusRaw = make([]byte, bits/8)
+		}
+	case tpm2.AlgECC:
+		curve := o.Curve
+		if curve == 0 {
+			curve = tpm2.CurveNISTP256
+		}
+		params, err := eccParams(curve)
+		if err != nil {
+			return tpm2.Public{}, err
+		}
+		public.ECCParameters = params
+		if !restrictedDecrypt {
+			public.ECCParameters.Symmetric = nil
+		}
+		if signOnly {
+			scheme, err := o.signScheme(public.Type, curveSigScheme(curve), curveHashes[curve])
+			if err != nil {
+				return tpm2.Public{}, err
+			}
+			public.ECCParameters.Sign = scheme
+		}
+	case tpm2.AlgSymCipher:
+		bits := o.Bits
+		if bits == 0 {
+			bits = 128
+		}
+		if bits != 128 && bits != 192 && bits != 256 {
+			return tpm2.Public{}, fmt.Errorf("unsupported AES key size: %d", bits)
+		}
+		public.SymCipherParameters = &tpm2.SymCipherParams{
+			Symmetric: &tpm2.SymScheme{Alg: tpm2.AlgAES, KeyBits: bits, Mode: tpm2.AlgCFB},
+		}
+	case tpm2.AlgKeyedHash:
+		if attrs&tpm2.FlagDecrypt != 0 {
+			return tpm2.Public{}, fmt.Errorf("HMAC keys cannot have FlagDecrypt")
+		}
+		public.KeyedHashParameters = &tpm2.KeyedHashParams{Alg: tpm2.AlgHMAC, Hash: tpm2.AlgSHA256}
+	default:
+		return tpm2.Public{}, fmt.Errorf("unsupported key algorithm: %v", public.Type)
+	}
+	if o.SignScheme != nil && (!signOnly || (public.Type != tpm2.AlgRSA && public.Type != tpm2.AlgECC)) {
+		return tpm2.Public{}, fmt.Errorf("SignScheme can only be used with RSA or ECC signing keys")
+	}
+	if o.SignScheme != nil && o.SignScheme.Alg.IsNull() && attrs&tpm2.FlagRestricted != 0 {
+		return tpm2.Public{}, fmt.Errorf("restricted signing keys must have a signing scheme")
+	}
+	return public, nil
+}
+
+// signScheme returns the signing scheme of a key of the type (nil for
+// tpm2.AlgNull), using the default algorithm and hash when unset.
+func (o KeyOpts) signScheme(keyType, defaultAlg, defaultHash tpm2.Algorithm) (*tpm2.SigScheme, error) {
+	if o.SignScheme == nil {
+		return &tpm2.SigScheme{Alg: defaultAlg, Hash: defaultHash}, nil
+	}
+	if o.SignScheme.Alg.IsNull() {
+		return nil, nil
+	}
+	if err := checkSigningScheme(keyType, o.SignScheme.Alg); err != nil {
+		return nil, err
+	}
+	scheme := &tpm2.SigScheme{Alg: o.SignScheme.Alg, Hash: o.SignScheme.Hash}
+	if scheme.Hash == 0 {
+		scheme.Hash = defaultHash
+	}
+	return scheme, nil
+}
+
+// NewKeyWithOpts generates a key from the template described by opts (see
+// KeyOpts.Template) and loads that key into the TPM under the specified
+// parent, as in NewKey. Unlike keys created by NewKey, the key can have an
+// authorization value or an auth policy, which is then used whenever the key
+// is used.
+func NewKeyWithOpts(rw io.ReadWriter, parent tpmutil.Handle, opts KeyOpts) (*Key, error) {
+	template, err := opts.Template()
+	if err != nil {
+		return nil, err
+	}
+	var keySession session = passwordSession{opts.Auth}
+	if opts.AuthPolicy != nil {
+		if keySession, err = newPolicySession(rw, opts.AuthPolicy, opts.Auth); err != nil {
+			return nil, err
+		}
+	}
+	k, err := newKey(rw, parent, template, opts, keySession)
+	if err != nil {
+		keySession.Close()
+		return nil, err
+	}
+	return k, nil
+}
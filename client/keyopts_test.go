@@ -0,0 +1,265 @@
+package client_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestKeyOptsTemplate(t *testing.T) {
+	p384Template, err := client.AKTemplateECCWithCurve(tpm2.CurveNISTP384)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdhTemplate, err := client.ECDHTemplate(tpm2.CurveNISTP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		opts     client.KeyOpts
+		template tpm2.Public
+	}{
+		{"AKRSA", client.KeyOpts{}, client.AKTemplateRSA()},
+		{"AKECC", client.KeyOpts{Algorithm: tpm2.AlgECC}, client.AKTemplateECC()},
+		{"AKECCP384", client.KeyOpts{Algorithm: tpm2.AlgECC, Curve: tpm2.CurveNISTP384},
+			p384Template},
+		{"AKSM2", client.KeyOpts{Algorithm: tpm2.AlgECC, Curve: tpm2.CurveSM2P256}, client.AKTemplateSM2()},
+		{"SRKRSA", client.KeyOpts{Attributes: tpm2.FlagStorageDefault | tpm2.FlagNoDA}, client.SRKTemplateRSA()},
+		{"SRKECC", client.KeyOpts{Algorithm: tpm2.AlgECC, Attributes: tpm2.FlagStorageDefault | tpm2.FlagNoDA},
+			client.SRKTemplateECC()},
+		{"SRKSM2", client.KeyOpts{Algorithm: tpm2.AlgECC, Curve: tpm2.CurveSM2P256,
+			Attributes: tpm2.FlagStorageDefault | tpm2.FlagNoDA}, client.SRKTemplateSM2()},
+		{"HMAC", client.KeyOpts{Algorithm: tpm2.AlgKeyedHash}, client.HMACTemplate(tpm2.AlgSHA256)},
+		{"ECDH", client.KeyOpts{Algorithm: tpm2.AlgECC, Attributes: tpm2.FlagDecrypt | tpm2.FlagFixedTPM |
+			tpm2.FlagFixedParent | tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth},
+			ecdhTemplate},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			template, err := test.opts.Template()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(template, test.template) {
+				t.Errorf("got template %+v, want %+v", template, test.template)
+			}
+		})
+	}
+}
+
+func TestKeyOptsTemplateFails(t *testing.T) {
+	tests := []struct {
+		name string
+		opts client.KeyOpts
+	}{
+		{"NoUsage", client.KeyOpts{Attributes: tpm2.FlagFixedTPM}},
+		{"BadCurve", client.KeyOpts{Algorithm: tpm2.AlgECC, Curve: tpm2.CurveBNP256}},
+		{"BadAESSize", client.KeyOpts{Algorithm: tpm2.AlgSymCipher, Bits: 512}},
+		{"HMACDecrypt", client.KeyOpts{Algorithm: tpm2.AlgKeyedHash, Attributes: tpm2.FlagDecrypt}},
+		{"BadAlgorithm", client.KeyOpts{Algorithm: tpm2.AlgSHA256}},
+		{"ECCWithRSAScheme", client.KeyOpts{Algorithm: tpm2.AlgECC, SignScheme: &tpm2.SigScheme{Alg: tpm2.AlgRSASSA}}},
+		{"RSAWithECCScheme", client.KeyOpts{SignScheme: &tpm2.SigScheme{Alg: client.AlgECSchnorr}}},
+		{"ECDAA", client.KeyOpts{Algorithm: tpm2.AlgECC, SignScheme: &tpm2.SigScheme{Alg: tpm2.AlgECDAA}}},
+		{"RestrictedNullScheme", client.KeyOpts{SignScheme: &tpm2.SigScheme{Alg: tpm2.AlgNull}}},
+		{"HMACScheme", client.KeyOpts{Algorithm: tpm2.AlgKeyedHash, SignScheme: &tpm2.SigScheme{Alg: tpm2.AlgECDSA}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := test.opts.Template(); err == nil {
+				t.Error("expected Template to fail")
+			}
+		})
+	}
+}
+
+func TestKeyOptsAuthPolicy(t *testing.T) {
+	opts := client.KeyOpts{AuthPolicy: client.PolicyAuthValue{}}
+	template, err := opts.Template()
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, err := client.PolicyDigest(client.PolicyAuthValue{}, client.SessionHashAlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(template.AuthPolicy, digest) {
+		t.Errorf("got auth policy %x, want %x", template.AuthPolicy, digest)
+	}
+	if template.Attributes&tpm2.FlagUserWithAuth != 0 {
+		t.Error("key with auth policy should not have FlagUserWithAuth")
+	}
+}
+
+func TestNewKeyWithOptsSigning(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	tests := []struct {
+		name string
+		opts client.KeyOpts
+	}{
+		{"Auth", client.KeyOpts{Attributes: tpm2.FlagSign | tpm2.FlagFixedTPM | tpm2.FlagFixedParent |
+			tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth, Auth: "password"}},
+		{"Policy", client.KeyOpts{Algorithm: tpm2.AlgECC, Attributes: tpm2.FlagSign | tpm2.FlagFixedTPM |
+			tpm2.FlagFixedParent | tpm2.FlagSensitiveDataOrigin, AuthPolicy: client.PolicyAuthValue{}, Auth: "password"}},
+		{"Restricted", client.KeyOpts{Auth: "password"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key, err := client.NewKeyWithOpts(rwc, tpm2.HandleOwner, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer key.Close()
+			// Sign twice, as policy sessions must be satisfied for each use.
+			for i := 0; i < 2; i++ {
+				if _, err := key.SignData([]byte("data")); err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestNewKeyWithOptsParent(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	// A per-tenant storage key, with its own password.
+	tenant, err := client.NewKeyWithOpts(rwc, tpm2.HandleOwner, client.KeyOpts{
+		Attributes: tpm2.FlagStorageDefault | tpm2.FlagNoDA,
+		Auth:       "tenant password",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tenant.Close()
+
+	key, err := client.NewKeyWithOpts(rwc, tenant.Handle(), client.KeyOpts{
+		Algorithm:  tpm2.AlgECC,
+		Attributes: tpm2.FlagSign | tpm2.FlagFixedTPM | tpm2.FlagFixedParent | tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth,
+		Auth:       "key password",
+		ParentAuth: "tenant password",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = key.SignData([]byte("data")); err != nil {
+		t.Error(err)
+	}
+	key.Close()
+
+	// Keys without a password can also be created with NewKey, under parents
+	// without a password.
+	srk, err := client.StorageRootKeyRSA(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srk.Close()
+	ak, err := client.NewKey(rwc, srk.Handle(), client.AKTemplateRSA())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ak.SignData([]byte("data")); err != nil {
+		t.Error(err)
+	}
+	ak.Close()
+
+	if _, err = client.NewKeyWithOpts(rwc, tenant.Handle(), client.KeyOpts{ParentAuth: "wrong"}); err == nil {
+		t.Error("creating a key with the wrong parent password succeeded")
+	}
+	if _, err = client.NewKey(rwc, tenant.Handle(), client.AKTemplateRSA()); err == nil {
+		t.Error("creating a key without the parent password succeeded")
+	}
+}
+
+func TestNewKeyWithOptsWrongAuth(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	key, err := client.NewKeyWithOpts(rwc, tpm2.HandleOwner, client.KeyOpts{
+		Attributes: tpm2.FlagSign | tpm2.FlagFixedTPM | tpm2.FlagFixedParent |
+			tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth | tpm2.FlagNoDA,
+		Auth: "password",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer key.Close()
+
+	digest := sha256.Sum256([]byte("data"))
+	scheme := &tpm2.SigScheme{Alg: tpm2.AlgRSASSA, Hash: tpm2.AlgSHA256}
+	if _, err = tpm2.Sign(rwc, key.Handle(), "wrong", digest[:], nil, scheme); err == nil {
+		t.Error("signing with the wrong password succeeded")
+	}
+	if _, err = tpm2.Sign(rwc, key.Handle(), "password", digest[:], nil, scheme); err != nil {
+		t.Errorf("signing with the correct password failed: %v", err)
+	}
+}
+
+func TestNewKeyWithOptsDecryption(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	key, err := client.NewKeyWithOpts(rwc, tpm2.HandleOwner, client.KeyOpts{
+		Attributes: tpm2.FlagDecrypt | tpm2.FlagFixedTPM | tpm2.FlagFixedParent |
+			tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth,
+		Auth: "password",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer key.Close()
+
+	decrypter, err := key.GetDecrypter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("secret message")
+	ciphertext, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, key.PublicKey().(*rsa.PublicKey), msg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := decrypter.Decrypt(rand.Reader, ciphertext, &rsa.OAEPOptions{Hash: crypto.SHA256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plaintext, msg) {
+		t.Errorf("got plaintext %q, want %q", plaintext, msg)
+	}
+}
+
+func TestNewKeyWithOptsSymmetric(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	key, err := client.NewKeyWithOpts(rwc, tpm2.HandleOwner, client.KeyOpts{Algorithm: tpm2.AlgSymCipher, Bits: 256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer key.Close()
+
+	iv := make([]byte, 16)
+	msg := []byte("secret message")
+	ciphertext, err := key.EncryptSymmetric(iv, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := key.DecryptSymmetric(iv, ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plaintext, msg) {
+		t.Errorf("got plaintext %q, want %q", plaintext, msg)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"crypto"
 	"crypto/subtle"
+	"crypto/x509"
 	"fmt"
 	"io"
 
@@ -14,6 +15,9 @@ import (
 	"github.com/google/go-tpm/tpmutil"
 )
 
+// The creator recorded in the headers of the blobs created by this package.
+const blobCreator = "github.com/ThalesIgnite/go-tpm-tools/client"
+
 // Key wraps an active asymmetric TPM2 key. This can either be a signing key or
 // an encryption key. Users of Key should be sure to call Close() when the Key
 // is no longer needed, so that the underlying TPM handle can be freed.
@@ -24,6 +28,17 @@ type Key struct {
 	pubKey  crypto.PublicKey
 	name    tpm2.Name
 	session session
+	cert    *x509.Certificate
+	// Optional session used for parameter encryption or auditing, see
+	// UseEncryptedSession and UseAuditSession.
+	extraSession *hmacSession
+	// Creation data (TPMS_CREATION_DATA), its digest and the creation
+	// ticket, for keys created by this package (see CertifyCreation).
+	creationData []byte
+	creationHash []byte
+	ticket       *tpm2.Ticket
+	// The handle is owned by a KeyCache, so it is not flushed by Close.
+	cached bool
 }
 
 // EndorsementKeyRSA generates and loads a key from DefaultEKTemplateRSA.
@@ -56,6 +71,49 @@ func AttestationKeyECC(rw io.ReadWriter) (*Key, error) {
 	return NewCachedKey(rw, tpm2.HandleOwner, AKTemplateECC(), DefaultAKECCHandle)
 }
 
+// EndorsementKeyECCWithCurve generates and loads a key from
+// EKTemplateECCWithCurve. Only NIST P-256 keys are cached (as in
+// EndorsementKeyECC), keys on other curves are recreated on every call.
+func EndorsementKeyECCWithCurve(rw io.ReadWriter, curve tpm2.EllipticCurve) (*Key, error) {
+	if curve == tpm2.CurveNISTP256 {
+		return EndorsementKeyECC(rw)
+	}
+	template, err := EKTemplateECCWithCurve(curve)
+	if err != nil {
+		return nil, err
+	}
+	return NewKey(rw, tpm2.HandleEndorsement, template)
+}
+
+// StorageRootKeyECCWithCurve generates and loads a key from
+// SRKTemplateECCWithCurve. Only NIST P-256 keys are cached (as in
+// StorageRootKeyECC), keys on other curves are recreated on every call.
+func StorageRootKeyECCWithCurve(rw io.ReadWriter, curve tpm2.EllipticCurve) (*Key, error) {
+	if curve == tpm2.CurveNISTP256 {
+		return StorageRootKeyECC(rw)
+	}
+	template, err := SRKTemplateECCWithCurve(curve)
+	if err != nil {
+		return nil, err
+	}
+	return NewKey(rw, tpm2.HandleOwner, template)
+}
+
+// AttestationKeyECCWithCurve generates and loads a key from
+// AKTemplateECCWithCurve in the Owner hierarchy. Only NIST P-256 keys are
+// cached (as in AttestationKeyECC), keys on other curves are recreated on
+// every call.
+func AttestationKeyECCWithCurve(rw io.ReadWriter, curve tpm2.EllipticCurve) (*Key, error) {
+	if curve == tpm2.CurveNISTP256 {
+		return AttestationKeyECC(rw)
+	}
+	template, err := AKTemplateECCWithCurve(curve)
+	if err != nil {
+		return nil, err
+	}
+	return NewKey(rw, tpm2.HandleOwner, template)
+}
+
 // EndorsementKeyFromNvIndex generates and loads an endorsement key using the
 // template stored at the provided nvdata index. This is useful for TPMs which
 // have a preinstalled AK template.
@@ -66,24 +124,42 @@ func EndorsementKeyFromNvIndex(rw io.ReadWriter, idx uint32) (*Key, error) {
 // GceAttestationKeyRSA generates and loads the GCE RSA AK. Note that this
 // function will only work on a GCE VM. Unlike AttestationKeyRSA, this key uses
 // the Endorsement Hierarchy and its template loaded from GceAKTemplateNVIndexRSA.
+// If the AK certificate is present in GceAKCertNVIndexRSA, it is also loaded.
 func GceAttestationKeyRSA(rw io.ReadWriter) (*Key, error) {
-	return EndorsementKeyFromNvIndex(rw, GceAKTemplateNVIndexRSA)
+	return gceAttestationKey(rw, GceAKTemplateNVIndexRSA, GceAKCertNVIndexRSA)
 }
 
 // GceAttestationKeyECC generates and loads the GCE ECC AK. Note that this
 // function will only work on a GCE VM. Unlike AttestationKeyECC, this key uses
 // the Endorsement Hierarchy and its template loaded from GceAKTemplateNVIndexECC.
+// If the AK certificate is present in GceAKCertNVIndexECC, it is also loaded.
 func GceAttestationKeyECC(rw io.ReadWriter) (*Key, error) {
-	return EndorsementKeyFromNvIndex(rw, GceAKTemplateNVIndexECC)
+	return gceAttestationKey(rw, GceAKTemplateNVIndexECC, GceAKCertNVIndexECC)
+}
+
+func gceAttestationKey(rw io.ReadWriter, templateIdx, certIdx uint32) (*Key, error) {
+	ak, err := EndorsementKeyFromNvIndex(rw, templateIdx)
+	if err != nil {
+		return nil, err
+	}
+	// Older GCE VMs do not have an AK certificate, so a missing index is fine.
+	if _, err = tpm2.NVReadPublic(rw, tpmutil.Handle(certIdx)); err != nil {
+		return ak, nil
+	}
+	if err = ak.setCertFromNvIndex(certIdx); err != nil {
+		ak.Close()
+		return nil, err
+	}
+	return ak, nil
 }
 
 // KeyFromNvIndex generates and loads a key under the provided parent
 // (possibly a hierarchy root tpm2.Handle{Owner|Endorsement|Platform|Null})
 // using the template stored at the provided nvdata index.
 func KeyFromNvIndex(rw io.ReadWriter, parent tpmutil.Handle, idx uint32) (*Key, error) {
-	data, err := tpm2.NVReadEx(rw, tpmutil.Handle(idx), tpm2.HandleOwner, "", 0)
+	data, err := tpm2.NVReadEx(rw, tpmutil.Handle(idx), tpm2.HandleOwner, hierarchyAuth(rw, tpm2.HandleOwner), 0)
 	if err != nil {
-		return nil, fmt.Errorf("read error at index %d: %w", idx, err)
+		return nil, fmt.Errorf("read error at index %d: %w", idx, tpmError(err))
 	}
 	template, err := tpm2.DecodePublic(data)
 	if err != nil {
@@ -111,8 +187,8 @@ func NewCachedKey(rw io.ReadWriter, parent tpmutil.Handle, template tpm2.Public,
 			return k, k.finish()
 		}
 		// Kick out old cached key if it does not match
-		if err = tpm2.EvictControl(rw, "", owner, cachedHandle, cachedHandle); err != nil {
-			return nil, err
+		if err = tpm2.EvictControl(rw, hierarchyAuth(rw, owner), owner, cachedHandle, cachedHandle); err != nil {
+			return nil, tpmError(err)
 		}
 	}
 
@@ -122,8 +198,8 @@ func NewCachedKey(rw io.ReadWriter, parent tpmutil.Handle, template tpm2.Public,
 	}
 	defer tpm2.FlushContext(rw, k.handle)
 
-	if err = tpm2.EvictControl(rw, "", owner, k.handle, cachedHandle); err != nil {
-		return nil, err
+	if err = tpm2.EvictControl(rw, hierarchyAuth(rw, owner), owner, k.handle, cachedHandle); err != nil {
+		return nil, tpmError(err)
 	}
 	k.handle = cachedHandle
 	return k, nil
@@ -133,21 +209,42 @@ func NewCachedKey(rw io.ReadWriter, parent tpmutil.Handle, template tpm2.Public,
 // under the specified parent. NewKey can call many different TPM commands:
 //   - If parent is tpm2.Handle{Owner|Endorsement|Platform|Null} a primary key
 //     is created in the specified hierarchy (using CreatePrimary).
-//   - If parent is a valid key handle, a normal key object is created under
-//     that parent (using Create and Load). NOTE: Not yet supported.
+//   - If parent is a valid key handle (a loaded storage key, such as an SRK),
+//     a normal key object is created under that parent (using Create and
+//     Load). Unlike primary keys, such keys are generated randomly, so every
+//     call creates a different key.
 // This function also assumes that the desired key:
 //   - Does not have its usage locked to specific PCR values
 //   - Usable with empty authorization sessions (i.e. doesn't need a password)
+//
+// Use NewKeyWithOpts for keys which need a password, or whose parent needs one
+// (see KeyOpts.ParentAuth).
 func NewKey(rw io.ReadWriter, parent tpmutil.Handle, template tpm2.Public) (k *Key, err error) {
+	return newKey(rw, parent, template, KeyOpts{}, nil)
+}
+
+// newKey creates a key as in NewKey, with the authorization values of opts,
+// recording the values of opts.CreationPCRs in the key's creation data. The
+// key is used with keySession if it is not nil, otherwise a session is chosen
+// by finish.
+func newKey(rw io.ReadWriter, parent tpmutil.Handle, template tpm2.Public, opts KeyOpts, keySession session) (k *Key, err error) {
 	if !isHierarchy(parent) {
-		// TODO add support for normal objects with Create() and Load()
-		return nil, fmt.Errorf("unsupported parent handle: %x", parent)
+		parentKey := &Key{rw: rw, handle: parent, session: passwordSession{opts.ParentAuth}}
+		child, err := parentKey.createChild(template, opts)
+		if err != nil {
+			return nil, err
+		}
+		if k, err = parentKey.loadChild(child.public, child.private, keySession); err != nil {
+			return nil, err
+		}
+		k.creationData, k.creationHash, k.ticket = child.creationData, child.creationHash, &child.ticket
+		return k, nil
 	}
 
-	handle, pubArea, _, _, _, _, err :=
-		tpm2.CreatePrimaryEx(rw, parent, tpm2.PCRSelection{}, "", "", template)
+	handle, pubArea, creationData, creationHash, ticket, _, err :=
+		tpm2.CreatePrimaryEx(rw, parent, opts.CreationPCRs, hierarchyAuth(rw, parent), opts.Auth, template)
 	if err != nil {
-		return nil, err
+		return nil, tpmError(err)
 	}
 	defer func() {
 		if err != nil {
@@ -155,7 +252,8 @@ func NewKey(rw io.ReadWriter, parent tpmutil.Handle, template tpm2.Public) (k *K
 		}
 	}()
 
-	k = &Key{rw: rw, handle: handle}
+	k = &Key{rw: rw, handle: handle, session: keySession,
+		creationData: creationData, creationHash: creationHash, ticket: &ticket}
 	if k.pubArea, err = tpm2.DecodePublic(pubArea); err != nil {
 		return
 	}
@@ -164,8 +262,11 @@ func NewKey(rw io.ReadWriter, parent tpmutil.Handle, template tpm2.Public) (k *K
 
 func (k *Key) finish() error {
 	var err error
-	if k.pubKey, err = k.pubArea.Key(); err != nil {
-		return err
+	// Symmetric and HMAC keys do not have a public key.
+	if k.pubArea.Type != tpm2.AlgSymCipher && k.pubArea.Type != tpm2.AlgKeyedHash {
+		if k.pubKey, err = notinternal.PublicKey(k.pubArea); err != nil {
+			return err
+		}
 	}
 	if k.name, err = k.pubArea.Name(); err != nil {
 		return err
@@ -203,18 +304,73 @@ func (k *Key) PublicArea() tpm2.Public {
 	return k.pubArea
 }
 
-// PublicKey provides a go interface to the loaded key's public area.
+// PublicKey provides a go interface to the loaded key's public area. This is
+// nil for symmetric keys.
 func (k *Key) PublicKey() crypto.PublicKey {
 	return k.pubKey
 }
 
+// Cert returns the certificate for this key, or nil if no certificate has
+// been set.
+func (k *Key) Cert() *x509.Certificate {
+	return k.cert
+}
+
+// CertDERBytes returns the ASN.1 DER encoding of this key's certificate, or
+// nil if no certificate has been set.
+func (k *Key) CertDERBytes() []byte {
+	if k.cert == nil {
+		return nil
+	}
+	return k.cert.Raw
+}
+
+// SetCert sets the certificate for this key, which will be included in the
+// Attestations produced by Attest. It fails if the public key in the
+// certificate does not match this key's public key.
+func (k *Key) SetCert(cert *x509.Certificate) error {
+	certPub, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
+	if err != nil {
+		return fmt.Errorf("failed to marshal certificate public key: %w", err)
+	}
+	keyPub, err := x509.MarshalPKIXPublicKey(k.pubKey)
+	if err != nil {
+		return fmt.Errorf("failed to marshal key public key: %w", err)
+	}
+	if !bytes.Equal(certPub, keyPub) {
+		return fmt.Errorf("certificate public key does not match key")
+	}
+	k.cert = cert
+	return nil
+}
+
+func (k *Key) setCertFromNvIndex(idx uint32) error {
+	cert, err := certFromNvIndex(k.rw, idx)
+	if err != nil {
+		return err
+	}
+	return k.SetCert(cert)
+}
+
 // Close should be called when the key is no longer needed. This is important to
 // do as most TPMs can only have a small number of key simultaneously loaded.
 func (k *Key) Close() {
 	if k.session != nil {
 		k.session.Close()
 	}
-	tpm2.FlushContext(k.rw, k.handle)
+	k.setExtraSession(nil)
+	if !k.cached {
+		tpm2.FlushContext(k.rw, k.handle)
+	}
+}
+
+// setExtraSession replaces the key's extra session, flushing the previous one
+// unless it is an audit session (which is owned by its AuditSession).
+func (k *Key) setExtraSession(s *hmacSession) {
+	if k.extraSession != nil && !k.extraSession.audit {
+		k.extraSession.Close()
+	}
+	k.extraSession = s
 }
 
 // Seal seals the sensitive byte buffer to a key. This key must be an SRK (we
@@ -222,10 +378,43 @@ func (k *Key) Close() {
 // be provided. In this case, the sensitive data can only be unsealed if the
 // PCRs are in the specified state. During the sealing process, certification
 // data will be created allowing Unseal() to validate the state of the TPM
-// during the sealing process.
+// during the sealing process. If opts is a SealAlternatives, the data can be
+// unsealed in any of the alternative PCR states. If opts is a SealHashAlgs,
+// its hash algorithms are used for the sealed object and the certified PCRs.
 func (k *Key) Seal(sensitive []byte, opts SealOpts) (*pb.SealedBytes, error) {
+	return k.SealWithAuthValue(sensitive, opts, "")
+}
+
+// SealWithAuthValue is like Seal(), but additionally protects the sealed data
+// with an auth value (i.e. a passphrase). If authValue is non-empty, the data
+// can only be unsealed with UnsealWithAuthValue() using the same auth value
+// (and with the PCRs in the specified state).
+func (k *Key) SealWithAuthValue(sensitive []byte, opts SealOpts, authValue string) (*pb.SealedBytes, error) {
+	sessionAlg, certifyAlg := SessionHashAlgTpm, CertifyHashAlgTpm
+	if algs, ok := opts.(SealHashAlgs); ok {
+		if algs.SessionHashAlg != 0 {
+			sessionAlg = algs.SessionHashAlg
+		}
+		if algs.CertifyHashAlg != 0 {
+			certifyAlg = algs.CertifyHashAlg
+		}
+		opts = algs.Opts
+	}
+	if alts, ok := opts.(SealAlternatives); ok {
+		if sessionAlg != SessionHashAlgTpm {
+			return nil, fmt.Errorf("SealAlternatives can only be used with session hash %v", SessionHashAlgTpm)
+		}
+		sb, err := k.sealAlternatives(sensitive, alts, authValue, certifyAlg)
+		if err != nil {
+			return nil, err
+		}
+		return sb, setSealedHeader(sb)
+	}
+	sessionHash, err := sessionAlg.Hash()
+	if err != nil {
+		return nil, err
+	}
 	var pcrs *pb.PCRs
-	var err error
 	var auth []byte
 	if opts != nil {
 		pcrs, err = opts.PCRsForSealing(k.rw)
@@ -233,11 +422,16 @@ func (k *Key) Seal(sensitive []byte, opts SealOpts) (*pb.SealedBytes, error) {
 			return nil, err
 		}
 	}
+	// Without a policy, the sealed object can be used with its auth value
+	// directly, so PolicyAuthValue is only needed when sealing to PCRs.
 	if len(pcrs.GetPcrs()) > 0 {
-		auth = notinternal.PCRSessionAuth(pcrs, SessionHashAlg)
+		auth = notinternal.PCRSessionAuth(pcrs, sessionHash)
+		if authValue != "" {
+			auth, _ = PolicyAuthValue{}.Extend(auth, sessionHash)
+		}
 	}
-	certifySel := FullPcrSel(CertifyHashAlgTpm)
-	sb, err := sealHelper(k.rw, k.Handle(), auth, sensitive, certifySel)
+	certifySel := FullPcrSel(certifyAlg)
+	sb, err := k.sealHelper(auth, authValue, sensitive, certifySel, sessionAlg)
 	if err != nil {
 		return nil, err
 	}
@@ -247,13 +441,59 @@ func (k *Key) Seal(sensitive []byte, opts SealOpts) (*pb.SealedBytes, error) {
 	}
 	sb.Hash = pcrs.GetHash()
 	sb.Srk = pb.ObjectType(k.pubArea.Type)
+	sb.AuthValue = authValue != ""
+	return sb, setSealedHeader(sb)
+}
+
+func (k *Key) sealAlternatives(sensitive []byte, opts SealAlternatives, authValue string, certifyAlg tpm2.Algorithm) (*pb.SealedBytes, error) {
+	alternatives, err := opts.alternativePCRs(k.rw)
+	if err != nil {
+		return nil, err
+	}
+	sb, err := k.sealWithPolicy(sensitive, alternativesPolicy(alternatives, authValue != ""), authValue, certifyAlg)
+	if err != nil {
+		return nil, err
+	}
+	sb.AlternativePcrs = alternatives
+	sb.AuthValue = authValue != ""
+	return sb, nil
+}
+
+// SealWithPolicy seals the sensitive byte buffer to a key (as in Seal), such
+// that it can only be unsealed by satisfying the provided Policy. The sealed
+// object's auth value is set to authValue, which must be provided when
+// unsealing if the policy contains PolicyAuthValue. The policy is not stored
+// in the returned SealedBytes, so the same policy must be passed to
+// UnsealWithPolicy.
+func (k *Key) SealWithPolicy(sensitive []byte, policy Policy, authValue string) (*pb.SealedBytes, error) {
+	sb, err := k.sealWithPolicy(sensitive, policy, authValue, CertifyHashAlgTpm)
+	if err != nil {
+		return nil, err
+	}
+	return sb, setSealedHeader(sb)
+}
+
+func (k *Key) sealWithPolicy(sensitive []byte, policy Policy, authValue string, certifyAlg tpm2.Algorithm) (*pb.SealedBytes, error) {
+	auth, err := PolicyDigest(policy, SessionHashAlg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute policy digest: %w", err)
+	}
+	sb, err := k.sealHelper(auth, authValue, sensitive, FullPcrSel(certifyAlg), SessionHashAlgTpm)
+	if err != nil {
+		return nil, err
+	}
+	sb.Srk = pb.ObjectType(k.pubArea.Type)
 	return sb, nil
 }
 
-func sealHelper(rw io.ReadWriter, parentHandle tpmutil.Handle, auth []byte, sensitive []byte, certifyPCRsSel tpm2.PCRSelection) (*pb.SealedBytes, error) {
+func (k *Key) sealHelper(auth []byte, authValue string, sensitive []byte, certifyPCRsSel tpm2.PCRSelection, nameAlg tpm2.Algorithm) (*pb.SealedBytes, error) {
+	nameHash, err := nameAlg.Hash()
+	if err != nil {
+		return nil, err
+	}
 	inPublic := tpm2.Public{
 		Type:       tpm2.AlgKeyedHash,
-		NameAlg:    SessionHashAlgTpm,
+		NameAlg:    nameAlg,
 		Attributes: tpm2.FlagFixedTPM | tpm2.FlagFixedParent,
 		AuthPolicy: auth,
 	}
@@ -263,15 +503,16 @@ func sealHelper(rw io.ReadWriter, parentHandle tpmutil.Handle, auth []byte, sens
 		inPublic.Attributes |= tpm2.FlagAdminWithPolicy
 	}
 
-	priv, pub, creationData, _, ticket, err := tpm2.CreateKeyWithSensitive(rw, parentHandle, certifyPCRsSel, "", "", inPublic, sensitive)
+	priv, pub, creationData, ticket, err := k.create(certifyPCRsSel, authValue, inPublic, sensitive)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create key: %w", err)
 	}
-	certifiedPcr, err := ReadPCRs(rw, certifyPCRsSel)
+	certifiedPcr, err := ReadPCRs(k.rw, certifyPCRsSel)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read PCRs: %w", err)
 	}
-	computedDigest := notinternal.PCRDigest(certifiedPcr, SessionHashAlg)
+	// The creation PCR digest uses the name algorithm of the sealed object.
+	computedDigest := notinternal.PCRDigest(certifiedPcr, nameHash)
 
 	decodedCreationData, err := tpm2.DecodeCreationData(creationData)
 	if err != nil {
@@ -294,22 +535,122 @@ func sealHelper(rw io.ReadWriter, parentHandle tpmutil.Handle, auth []byte, sens
 	return sb, nil
 }
 
+// setSealedHeader sets the header of sealed data, recording the PCR bank and
+// the auth policy of the sealed object.
+func setSealedHeader(sb *pb.SealedBytes) error {
+	pub, err := tpm2.DecodePublic(sb.GetPub())
+	if err != nil {
+		return fmt.Errorf("failed to decode sealed object: %w", err)
+	}
+	bank := sb.GetHash()
+	if alternatives := sb.GetAlternativePcrs(); len(alternatives) > 0 {
+		bank = alternatives[0].GetHash()
+	}
+	sb.Header = notinternal.NewBlobHeader(blobCreator, bank, pub.AuthPolicy)
+	return notinternal.SetBlobChecksum(sb)
+}
+
+// create runs TPM2_Create under k, using the encryption session (if present)
+// to encrypt the sensitive data. The returned values are the same as those of
+// tpm2.CreateKeyWithSensitive.
+func (k *Key) create(sel tpm2.PCRSelection, authValue string, public tpm2.Public, sensitive []byte) (private, pub, creationData []byte, ticket tpm2.Ticket, err error) {
+	if k.extraSession == nil {
+		private, pub, creationData, _, ticket, err = tpm2.CreateKeyWithSensitive(k.rw, k.handle, sel, "", authValue, public, sensitive)
+		err = tpmError(err)
+		return
+	}
+	inSensitive, err := tpmutil.Pack(tpmutil.U16Bytes(authValue), tpmutil.U16Bytes(sensitive))
+	if err != nil {
+		return nil, nil, nil, ticket, err
+	}
+	inPublic, err := public.Encode()
+	if err != nil {
+		return nil, nil, nil, ticket, err
+	}
+	creationPCR, err := encodePCRSelection(sel)
+	if err != nil {
+		return nil, nil, nil, ticket, err
+	}
+	name, err := k.name.Digest.Encode()
+	if err != nil {
+		return nil, nil, nil, ticket, err
+	}
+	auth, err := k.session.Auth()
+	if err != nil {
+		return nil, nil, nil, ticket, err
+	}
+	resp, err := k.extraSession.run(tpm2.CmdCreate, []tpmutil.Handle{k.handle}, [][]byte{name}, []tpm2.AuthCommand{auth}, true, false,
+		tpmutil.U16Bytes(inSensitive), tpmutil.U16Bytes(inPublic), tpmutil.U16Bytes(nil), tpmutil.RawBytes(creationPCR))
+	if err != nil {
+		return nil, nil, nil, ticket, fmt.Errorf("TPM2_Create failed: %w", err)
+	}
+	var outPrivate, outPublic, outCreationData, creationHash tpmutil.U16Bytes
+	if _, err = tpmutil.Unpack(resp, &outPrivate, &outPublic, &outCreationData, &creationHash, &ticket); err != nil {
+		return nil, nil, nil, ticket, fmt.Errorf("decoding TPM2_Create response: %w", err)
+	}
+	return outPrivate, outPublic, outCreationData, ticket, nil
+}
+
 // Unseal attempts to reverse the process of Seal(), using the PCRs, public, and
 // private data in proto.SealedBytes. Optionally, a CertifyOpt can be
 // passed, to verify the state of the TPM when the data was sealed. A nil value
 // can be passed to skip certification.
 func (k *Key) Unseal(in *pb.SealedBytes, opts CertifyOpts) ([]byte, error) {
+	return k.UnsealWithAuthValue(in, "", opts)
+}
+
+// UnsealWithAuthValue reverses the process of SealWithAuthValue(), where
+// authValue must be the auth value used when sealing. Optionally, a
+// CertifyOpt can be passed (as in Unseal).
+func (k *Key) UnsealWithAuthValue(in *pb.SealedBytes, authValue string, opts CertifyOpts) ([]byte, error) {
+	if len(in.GetAlternativePcrs()) > 0 {
+		return k.UnsealWithPolicy(in, alternativesPolicy(in.GetAlternativePcrs(), in.GetAuthValue()), authValue, opts)
+	}
+	sel := tpm2.PCRSelection{Hash: tpm2.Algorithm(in.GetHash())}
+	for _, pcr := range in.GetPcrs() {
+		sel.PCRs = append(sel.PCRs, int(pcr))
+	}
+	newSession := func(nameAlg tpm2.Algorithm) (session, error) {
+		return newPCRSession(k.rw, sel, in.GetAuthValue(), authValue, nameAlg)
+	}
+	return k.unsealHelper(in, opts, newSession, authValue)
+}
+
+// UnsealWithPolicy reverses the process of SealWithPolicy(), satisfying the
+// policy in a policy session. The policy must be the one passed to
+// SealWithPolicy. If the policy contains PolicyAuthValue, authValue must be the
+// auth value passed to SealWithPolicy. Optionally, a CertifyOpt can be passed
+// (as in Unseal).
+func (k *Key) UnsealWithPolicy(in *pb.SealedBytes, policy Policy, authValue string, opts CertifyOpts) ([]byte, error) {
+	newSession := func(tpm2.Algorithm) (session, error) { return newPolicySession(k.rw, policy, authValue) }
+	return k.unsealHelper(in, opts, newSession, authValue)
+}
+
+// unsealHelper unseals in, using newSession to create the session for the
+// sealed object's name algorithm.
+func (k *Key) unsealHelper(in *pb.SealedBytes, opts CertifyOpts, newSession func(nameAlg tpm2.Algorithm) (session, error), authValue string) ([]byte, error) {
+	if err := notinternal.CheckBlobHeader(in); err != nil {
+		return nil, err
+	}
 	if in.Srk != pb.ObjectType(k.pubArea.Type) {
 		return nil, fmt.Errorf("expected key of type %v, got %v", in.Srk, k.pubArea.Type)
 	}
-	sealed, _, err := tpm2.Load(
+	pub, err := tpm2.DecodePublic(in.GetPub())
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode sealed object: %w", err)
+	}
+	nameHash, err := pub.NameAlg.Hash()
+	if err != nil {
+		return nil, err
+	}
+	sealed, sealedName, err := tpm2.Load(
 		k.rw,
 		k.Handle(),
 		/*parentPassword=*/ "",
 		in.GetPub(),
 		in.GetPriv())
 	if err != nil {
-		return nil, fmt.Errorf("failed to load sealed object: %w", err)
+		return nil, fmt.Errorf("failed to load sealed object: %w", tpmError(err))
 	}
 	defer tpm2.FlushContext(k.rw, sealed)
 
@@ -318,7 +659,7 @@ func (k *Key) Unseal(in *pb.SealedBytes, opts CertifyOpts) ([]byte, error) {
 		if _, err = tpmutil.Unpack(in.GetTicket(), &ticket); err != nil {
 			return nil, fmt.Errorf("ticket unpack failed: %w", err)
 		}
-		creationHash := SessionHashAlg.New()
+		creationHash := nameHash.New()
 		creationHash.Write(in.GetCreationData())
 
 		_, _, certErr := tpm2.CertifyCreation(k.rw, "", sealed, tpm2.HandleNull, nil, creationHash.Sum(nil), tpm2.SigScheme{}, ticket)
@@ -339,7 +680,7 @@ func (k *Key) Unseal(in *pb.SealedBytes, opts CertifyOpts) ([]byte, error) {
 			_, _, certErr = tpm2.CertifyCreation(k.rw, "", sealed, signer.Handle(), nil, creationHash.Sum(nil), tpm2.SigScheme{}, ticket)
 		}
 		if certErr != nil {
-			return nil, fmt.Errorf("failed to certify creation: %w", certErr)
+			return nil, fmt.Errorf("failed to certify creation: %w", tpmError(certErr))
 		}
 
 		// verify certify PCRs haven't been modified
@@ -350,7 +691,7 @@ func (k *Key) Unseal(in *pb.SealedBytes, opts CertifyOpts) ([]byte, error) {
 		if !notinternal.SamePCRSelection(in.GetCertifiedPcrs(), decodedCreationData.PCRSelection) {
 			return nil, fmt.Errorf("certify PCRs does not match the PCR selection in the creation data")
 		}
-		expectedDigest := notinternal.PCRDigest(in.GetCertifiedPcrs(), SessionHashAlg)
+		expectedDigest := notinternal.PCRDigest(in.GetCertifiedPcrs(), nameHash)
 		if subtle.ConstantTimeCompare(decodedCreationData.PCRDigest, expectedDigest) == 0 {
 			return nil, fmt.Errorf("certify PCRs digest does not match the digest in the creation data")
 		}
@@ -360,12 +701,7 @@ func (k *Key) Unseal(in *pb.SealedBytes, opts CertifyOpts) ([]byte, error) {
 		}
 	}
 
-	sel := tpm2.PCRSelection{Hash: tpm2.Algorithm(in.GetHash())}
-	for _, pcr := range in.GetPcrs() {
-		sel.PCRs = append(sel.PCRs, int(pcr))
-	}
-
-	session, err := newPCRSession(k.rw, sel)
+	session, err := newSession(pub.NameAlg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
@@ -375,7 +711,20 @@ func (k *Key) Unseal(in *pb.SealedBytes, opts CertifyOpts) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	return tpm2.UnsealWithSession(k.rw, auth.Session, sealed, "")
+	if k.extraSession == nil {
+		out, err := tpm2.UnsealWithSession(k.rw, auth.Session, sealed, authValue)
+		return out, tpmPolicyError(err)
+	}
+	auth.Auth = []byte(authValue)
+	resp, err := k.extraSession.run(tpm2.CmdUnseal, []tpmutil.Handle{sealed}, [][]byte{sealedName}, []tpm2.AuthCommand{auth}, false, true)
+	if err != nil {
+		return nil, fmt.Errorf("TPM2_Unseal failed: %w", tpmPolicyError(err))
+	}
+	var outData tpmutil.U16Bytes
+	if _, err = tpmutil.Unpack(resp, &outData); err != nil {
+		return nil, fmt.Errorf("decoding TPM2_Unseal response: %w", err)
+	}
+	return outData, nil
 }
 
 // Quote will tell TPM to compute a hash of a set of given PCR selection, together with
@@ -385,7 +734,7 @@ func (k *Key) Unseal(in *pb.SealedBytes, opts CertifyOpts) ([]byte, error) {
 func (k *Key) Quote(selpcr tpm2.PCRSelection, extraData []byte) (*pb.Quote, error) {
 	// Make sure that we have a valid signing key before trying quote
 	var err error
-	if _, err = getSigningHashAlg(k); err != nil {
+	if err = checkAttestationKey(k); err != nil {
 		return nil, err
 	}
 	if !k.hasAttribute(tpm2.FlagRestricted) {
@@ -395,7 +744,7 @@ func (k *Key) Quote(selpcr tpm2.PCRSelection, extraData []byte) (*pb.Quote, erro
 	quote := &pb.Quote{}
 	quote.Quote, quote.RawSig, err = tpm2.QuoteRaw(k.rw, k.Handle(), "", "", extraData, selpcr, tpm2.AlgNull)
 	if err != nil {
-		return nil, fmt.Errorf("failed to quote: %w", err)
+		return nil, fmt.Errorf("failed to quote: %w", tpmError(err))
 	}
 	quote.Pcrs, err = ReadPCRs(k.rw, selpcr)
 	if err != nil {
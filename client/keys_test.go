@@ -1,6 +1,7 @@
 package client_test
 
 import (
+	"crypto/ecdsa"
 	"io"
 	"reflect"
 	"testing"
@@ -126,6 +127,73 @@ func TestKeyCreation(t *testing.T) {
 	}
 }
 
+func TestKeyCreationWithCurve(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	keys := []struct {
+		name   string
+		getKey func(io.ReadWriter, tpm2.EllipticCurve) (*client.Key, error)
+	}{
+		{"SRK-ECC", client.StorageRootKeyECCWithCurve},
+		{"EK-ECC", client.EndorsementKeyECCWithCurve},
+		{"AK-ECC", client.AttestationKeyECCWithCurve},
+	}
+	curves := []struct {
+		name  string
+		curve tpm2.EllipticCurve
+		size  int
+	}{
+		{"P256", tpm2.CurveNISTP256, 256},
+		{"P384", tpm2.CurveNISTP384, 384},
+		{"P521", tpm2.CurveNISTP521, 521},
+	}
+
+	for _, k := range keys {
+		for _, c := range curves {
+			t.Run(k.name+"-"+c.name, func(t *testing.T) {
+				key, err := k.getKey(rwc, c.curve)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer key.Close()
+
+				pub, ok := key.PublicKey().(*ecdsa.PublicKey)
+				if !ok {
+					t.Fatalf("got key of type %T, expected *ecdsa.PublicKey", key.PublicKey())
+				}
+				if got := pub.Curve.Params().BitSize; got != c.size {
+					t.Errorf("got curve size %d, expected %d", got, c.size)
+				}
+			})
+		}
+	}
+}
+
+func TestKeyCreationWithUnsupportedCurve(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	curve := tpm2.CurveBNP256
+	if _, err := client.AttestationKeyECCWithCurve(rwc, curve); err == nil {
+		t.Error("expected creating an AK on an unsupported curve to fail")
+	}
+	templates := []struct {
+		name        string
+		getTemplate func(tpm2.EllipticCurve) (tpm2.Public, error)
+	}{
+		{"EK", client.EKTemplateECCWithCurve},
+		{"AK", client.AKTemplateECCWithCurve},
+		{"SRK", client.SRKTemplateECCWithCurve},
+		{"ECDH", client.ECDHTemplate},
+	}
+	for _, tmpl := range templates {
+		if _, err := tmpl.getTemplate(curve); err == nil {
+			t.Errorf("expected the %s template on an unsupported curve to fail", tmpl.name)
+		}
+	}
+}
+
 func BenchmarkKeyCreation(b *testing.B) {
 	rwc := test.GetTPM(b)
 	defer client.CheckedClose(b, rwc)
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/go-tpm/tpm2"
+)
+
+// The inLockout bit of TPMA_PERMANENT.
+const permanentInLockout = 1 << 9
+
+// LockoutInfo describes the state of the TPM's dictionary attack protection,
+// as returned by GetLockoutInfo. The TPM counts failed authorizations of
+// objects without tpm2.FlagNoDA, and refuses all such authorizations once
+// FailedTries reaches MaxTries.
+type LockoutInfo struct {
+	// FailedTries is the current number of failed authorizations.
+	FailedTries uint32
+	// MaxTries is the number of failed authorizations causing lockout.
+	MaxTries uint32
+	// RecoveryTime is the time after which FailedTries is decremented. If
+	// zero, the TPM only recovers by resetting the lockout.
+	RecoveryTime time.Duration
+	// LockoutRecovery is the time the lockout authorization is unavailable
+	// for, after a failed authorization using it.
+	LockoutRecovery time.Duration
+	// InLockout is true if the TPM is currently in lockout.
+	InLockout bool
+}
+
+// GetLockoutInfo returns the state of the TPM's dictionary attack protection.
+func GetLockoutInfo(rw io.ReadWriter) (*LockoutInfo, error) {
+	props, err := getProperties(rw, ptVariable)
+	if err != nil {
+		return nil, err
+	}
+	for _, prop := range []tpm2.TPMProp{tpm2.TPMAPermanent, tpm2.LockoutCounter,
+		tpm2.MaxAuthFail, tpm2.LockoutInterval, tpm2.LockoutRecovery} {
+		if _, ok := props[prop]; !ok {
+			return nil, fmt.Errorf("TPM did not report property 0x%x", uint32(prop))
+		}
+	}
+	return &LockoutInfo{
+		FailedTries:     props[tpm2.LockoutCounter],
+		MaxTries:        props[tpm2.MaxAuthFail],
+		RecoveryTime:    time.Duration(props[tpm2.LockoutInterval]) * time.Second,
+		LockoutRecovery: time.Duration(props[tpm2.LockoutRecovery]) * time.Second,
+		InLockout:       props[tpm2.TPMAPermanent]&permanentInLockout != 0,
+	}, nil
+}
+
+// ResetLockout resets the TPM's count of failed authorizations (using
+// TPM2_DictionaryAttackLockReset), ending any lockout. It requires the
+// lockout hierarchy's authorization value. If lockoutAuth is wrong, the
+// lockout authorization becomes unavailable for LockoutRecovery.
+func ResetLockout(rw io.ReadWriter, lockoutAuth string) error {
+	if err := tpm2.DictionaryAttackLockReset(rw, passwordAuth(lockoutAuth)); err != nil {
+		return fmt.Errorf("TPM2_DictionaryAttackLockReset failed: %w", tpmError(err))
+	}
+	return nil
+}
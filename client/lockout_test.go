@@ -0,0 +1,56 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestLockout(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	if err := client.ResetLockout(rwc, ""); err != nil {
+		t.Fatalf("failed to reset lockout: %v", err)
+	}
+	info, err := client.GetLockoutInfo(rwc)
+	if err != nil {
+		t.Fatalf("failed to get lockout info: %v", err)
+	}
+	if info.FailedTries != 0 || info.InLockout {
+		t.Errorf("got %+v after reset, want no failed tries", info)
+	}
+	if info.MaxTries == 0 {
+		t.Error("got MaxTries of 0")
+	}
+
+	srk, err := client.StorageRootKeyECC(rwc)
+	if err != nil {
+		t.Fatalf("can't create srk from template: %v", err)
+	}
+	defer srk.Close()
+	sealed, err := srk.SealWithAuthValue([]byte("secret"), nil, "passphrase")
+	if err != nil {
+		t.Fatalf("failed to seal: %v", err)
+	}
+	if _, err = srk.UnsealWithAuthValue(sealed, "wrong", nil); err == nil {
+		t.Fatal("unseal should fail with the wrong auth value")
+	}
+
+	if info, err = client.GetLockoutInfo(rwc); err != nil {
+		t.Fatalf("failed to get lockout info: %v", err)
+	}
+	if info.FailedTries != 1 {
+		t.Errorf("got %d failed tries, want 1", info.FailedTries)
+	}
+	if err = client.ResetLockout(rwc, ""); err != nil {
+		t.Fatalf("failed to reset lockout: %v", err)
+	}
+	if info, err = client.GetLockoutInfo(rwc); err != nil {
+		t.Fatalf("failed to get lockout info: %v", err)
+	}
+	if info.FailedTries != 0 {
+		t.Errorf("got %d failed tries after reset, want 0", info.FailedTries)
+	}
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/google/go-tpm/tpm2"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+// EvidenceType is a type of evidence in an Attestation, used as the label when
+// deriving its nonce (see DeriveNonce).
+type EvidenceType string
+
+// The types of evidence with derived nonces.
+const (
+	// The event logs (TCG, IMA, Canonical and DRTM event logs). They are not
+	// signed, so their nonce is bound by the TPM quote and TEE report nonces.
+	EvidenceEventLogs EvidenceType = "event logs"
+	// The qualifying data of the TPM quotes.
+	EvidenceTPMQuote EvidenceType = "TPM quote"
+	// The report data of a TEE attestation (SEV-SNP report or TDX quote).
+	EvidenceTEEReport EvidenceType = "TEE report"
+)
+
+// DeriveNonce derives the nonce of a type of evidence from the verifier's
+// nonce, using the TPM's KDFa (NIST SP 800-108 counter mode HMAC) with SHA-256:
+//
+//	KDFa(SHA256, nonce, evidence, context, "", 8*size)
+//
+// where size is TEEReportDataSize for EvidenceTEEReport, and 32 otherwise.
+// Evidence generated for one type (or context) is therefore never valid as
+// evidence of another type, even when the verifier's nonce is the same.
+func DeriveNonce(nonce []byte, evidence EvidenceType, context []byte) ([]byte, error) {
+	size := sha256.Size
+	switch evidence {
+	case EvidenceEventLogs, EvidenceTPMQuote:
+	case EvidenceTEEReport:
+		size = TEEReportDataSize
+	default:
+		return nil, fmt.Errorf("unknown evidence type %q", evidence)
+	}
+	return tpm2.KDFa(tpm2.AlgSHA256, nonce, string(evidence), context, nil, 8*size)
+}
+
+// AttestationNonces are the nonces binding the evidence of an Attestation to
+// the verifier's nonce and to each other, as derived by
+// DeriveAttestationNonces.
+type AttestationNonces struct {
+	// Derived from the SHA-256 digest of the event logs (see EventLogsDigest).
+	EventLogs []byte
+	// Derived with the EventLogs nonce as context.
+	TPMQuote []byte
+	// Derived with the AK public area and the EventLogs nonce as context.
+	TEEReport []byte
+}
+
+// DeriveAttestationNonces derives the nonces of the evidence of an Attestation
+// (see DeriveNonce) from the verifier's nonce, the attestation's event logs and
+// its AK. Replacing any of the event logs, the quotes or the TEE attestation
+// with evidence from another Attestation (even for the same verifier nonce)
+// breaks the binding, so such evidence cannot be mixed and matched.
+func DeriveAttestationNonces(nonce []byte, attestation *pb.Attestation) (*AttestationNonces, error) {
+	eventLogs, err := DeriveNonce(nonce, EvidenceEventLogs, EventLogsDigest(attestation))
+	if err != nil {
+		return nil, err
+	}
+	nonces := &AttestationNonces{EventLogs: eventLogs}
+	if nonces.TPMQuote, err = DeriveNonce(nonce, EvidenceTPMQuote, eventLogs); err != nil {
+		return nil, err
+	}
+	teeContext := append(append([]byte{}, attestation.GetAkPub()...), eventLogs...)
+	if nonces.TEEReport, err = DeriveNonce(nonce, EvidenceTEEReport, teeContext); err != nil {
+		return nil, err
+	}
+	return nonces, nil
+}
+
+// EventLogsDigest returns the SHA-256 digest of the event logs of the
+// Attestation: the TCG event log, IMA log, Canonical Event Log and DRTM event
+// log, in that order, each prefixed by its length (64-bit big-endian).
+func EventLogsDigest(attestation *pb.Attestation) []byte {
+	hash := sha256.New()
+	for _, log := range [][]byte{
+		attestation.GetEventLog(),
+		attestation.GetImaLog(),
+		attestation.GetCanonicalEventLog(),
+		attestation.GetDrtmEventLog(),
+	} {
+		size := make([]byte, 8)
+		binary.BigEndian.PutUint64(size, uint64(len(log)))
+		hash.Write(size)
+		hash.Write(log)
+	}
+	return hash.Sum(nil)
+}
@@ -0,0 +1,74 @@
+package client_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+func TestDeriveNonce(t *testing.T) {
+	nonce := []byte("verifier nonce")
+	derived := map[client.EvidenceType][]byte{}
+	for evidence, size := range map[client.EvidenceType]int{
+		client.EvidenceEventLogs: 32,
+		client.EvidenceTPMQuote:  32,
+		client.EvidenceTEEReport: client.TEEReportDataSize,
+	} {
+		out, err := client.DeriveNonce(nonce, evidence, []byte("context"))
+		if err != nil {
+			t.Fatalf("failed to derive the %s nonce: %v", evidence, err)
+		}
+		expected, err := tpm2.KDFa(tpm2.AlgSHA256, nonce, string(evidence), []byte("context"), nil, 8*size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, expected) {
+			t.Errorf("got %s nonce %x, want %x", evidence, out, expected)
+		}
+		derived[evidence] = out
+	}
+	if bytes.Equal(derived[client.EvidenceEventLogs], derived[client.EvidenceTPMQuote]) {
+		t.Error("expected different nonces for each type of evidence")
+	}
+	if _, err := client.DeriveNonce(nonce, "unknown", nil); err == nil {
+		t.Error("expected deriving a nonce for an unknown evidence type to fail")
+	}
+}
+
+func TestDeriveAttestationNonces(t *testing.T) {
+	nonce := []byte("verifier nonce")
+	attestation := &pb.Attestation{AkPub: []byte("AK"), EventLog: []byte("event log")}
+	nonces, err := client.DeriveAttestationNonces(nonce, attestation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nonces.TPMQuote) != 32 || len(nonces.TEEReport) != client.TEEReportDataSize {
+		t.Errorf("got nonces of %d and %d bytes", len(nonces.TPMQuote), len(nonces.TEEReport))
+	}
+
+	// Any difference in the evidence gives different nonces, even when the
+	// concatenation of the event logs is the same.
+	others := []*pb.Attestation{
+		{AkPub: []byte("other AK"), EventLog: []byte("event log")},
+		{AkPub: []byte("AK"), EventLog: []byte("other event log")},
+		{AkPub: []byte("AK"), EventLog: []byte("event"), ImaLog: []byte(" log")},
+		{AkPub: []byte("AK"), EventLog: []byte("event log"), DrtmEventLog: []byte("DRTM")},
+	}
+	for _, other := range others {
+		otherNonces, err := client.DeriveAttestationNonces(nonce, other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(otherNonces.TEEReport, nonces.TEEReport) {
+			t.Errorf("got the same TEE report nonce for %v", other)
+		}
+		sameLogs := bytes.Equal(client.EventLogsDigest(other), client.EventLogsDigest(attestation))
+		if sameLogs != bytes.Equal(otherNonces.TPMQuote, nonces.TPMQuote) {
+			t.Errorf("the TPM quote nonce does not bind the event logs of %v", other)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package client
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// NV index types (TPM_NT), encoded in bits 4:7 of an index's attributes. Pass
+// one of these (or'ed with the other attributes) to DefineNV.
+const (
+	NVTypeOrdinary tpm2.NVAttr = 0x00
+	NVTypeCounter  tpm2.NVAttr = 0x10
+	NVTypeBits     tpm2.NVAttr = 0x20
+	NVTypeExtend   tpm2.NVAttr = 0x40
+)
+
+// NVAuth specifies how an NV index operation is authorized. A nil *NVAuth is
+// equivalent to the zero value, i.e. using the index's (empty) auth value.
+type NVAuth struct {
+	// Handle used to authorize the operation. Either the NV index itself (the
+	// default if Handle is zero), tpm2.HandleOwner or tpm2.HandlePlatform. The
+	// index attributes determine which of these are allowed.
+	Handle tpmutil.Handle
+	// Password is the auth value of Handle. Ignored if Session is set. If
+	// empty and Handle is a hierarchy, the hierarchy's auth value is used (see
+	// HierarchyAuthGetter).
+	Password string
+	// Session is an optional policy session, which must already satisfy the
+	// index's auth policy. The session is not flushed after use.
+	Session tpmutil.Handle
+}
+
+func (a *NVAuth) authHandle(index tpmutil.Handle) tpmutil.Handle {
+	if a == nil || a.Handle == 0 {
+		return index
+	}
+	return a.Handle
+}
+
+func (a *NVAuth) authCommand(rw io.ReadWriter) tpm2.AuthCommand {
+	if a == nil {
+		return passwordAuth("")
+	}
+	if a.Session != 0 {
+		return tpm2.AuthCommand{Session: a.Session, Attributes: tpm2.AttrContinueSession}
+	}
+	if a.Password == "" && isHierarchy(a.Handle) {
+		return passwordAuth(hierarchyAuth(rw, a.Handle))
+	}
+	return passwordAuth(a.Password)
+}
+
+// DefineNV defines an NV index of the specified size in the Owner hierarchy.
+// The index's auth value is set to auth, and its auth policy to policy (which
+// may be nil). The policy must use SessionHashAlg, as that is used as the
+// index's name algorithm. Use NVTypeCounter in attributes to define a
+// counter (the size must then be 8).
+func DefineNV(rw io.ReadWriter, index tpmutil.Handle, size uint16, attributes tpm2.NVAttr, auth string, policy []byte) error {
+	pub := tpm2.NVPublic{
+		NVIndex:    index,
+		NameAlg:    SessionHashAlgTpm,
+		Attributes: attributes,
+		AuthPolicy: policy,
+		DataSize:   size,
+	}
+	if err := tpm2.NVDefineSpaceEx(rw, tpm2.HandleOwner, auth, pub, passwordAuth(hierarchyAuth(rw, tpm2.HandleOwner))); err != nil {
+		return fmt.Errorf("failed to define NV index 0x%x: %w", uint32(index), tpmError(err))
+	}
+	return nil
+}
+
+// UndefineNV removes an NV index previously defined in the Owner hierarchy.
+func UndefineNV(rw io.ReadWriter, index tpmutil.Handle) error {
+	if err := tpm2.NVUndefineSpaceEx(rw, tpm2.HandleOwner, index, passwordAuth(hierarchyAuth(rw, tpm2.HandleOwner))); err != nil {
+		return fmt.Errorf("failed to undefine NV index 0x%x: %w", uint32(index), tpmError(err))
+	}
+	return nil
+}
+
+// NVName returns the name of an NV index (as used in policies such as
+// PolicyNV and PolicySecret). The name depends on the index's attributes, so
+// it changes when the index is first written.
+func NVName(rw io.ReadWriter, index tpmutil.Handle) ([]byte, error) {
+	resp, err := runCommand(rw, tpm2.CmdReadPublicNV, []tpmutil.Handle{index}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public area of NV index 0x%x: %w", uint32(index), err)
+	}
+	var pub, name tpmutil.U16Bytes
+	if _, err = tpmutil.Unpack(resp, &pub, &name); err != nil {
+		return nil, fmt.Errorf("failed to decode public area of NV index 0x%x: %w", uint32(index), err)
+	}
+	return name, nil
+}
+
+// NVRead reads the entire contents of an NV index. The read is split into
+// chunks no larger than the TPM's maximum NV buffer size.
+func NVRead(rw io.ReadWriter, index tpmutil.Handle, auth *NVAuth) ([]byte, error) {
+	pub, err := tpm2.NVReadPublic(rw, index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public area of NV index 0x%x: %w", uint32(index), tpmError(err))
+	}
+	return nvReadRange(rw, index, auth, 0, pub.DataSize)
+}
+
+func nvReadRange(rw io.ReadWriter, index tpmutil.Handle, auth *NVAuth, offset, size uint16) ([]byte, error) {
+	chunk, err := nvBufferSize(rw)
+	if err != nil {
+		return nil, err
+	}
+	handles := []tpmutil.Handle{auth.authHandle(index), index}
+	data := make([]byte, 0, size)
+	for len(data) < int(size) {
+		readSize := int(size) - len(data)
+		if readSize > chunk {
+			readSize = chunk
+		}
+		resp, err := runCommand(rw, tpm2.CmdReadNV, handles, []tpm2.AuthCommand{auth.authCommand(rw)},
+			uint16(readSize), offset+uint16(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read NV index 0x%x at offset %d: %w", uint32(index), len(data), err)
+		}
+		var out tpmutil.U16Bytes
+		if _, err = tpmutil.Unpack(resp, &out); err != nil {
+			return nil, err
+		}
+		data = append(data, out...)
+	}
+	return data, nil
+}
+
+// NVWrite writes data into an NV index, starting at the beginning of the
+// index. The write is split into chunks no larger than the TPM's maximum NV
+// buffer size, so indices with tpm2.AttrWriteAll set must be small enough to
+// be written in a single command.
+func NVWrite(rw io.ReadWriter, index tpmutil.Handle, auth *NVAuth, data []byte) error {
+	chunk, err := nvBufferSize(rw)
+	if err != nil {
+		return err
+	}
+	handles := []tpmutil.Handle{auth.authHandle(index), index}
+	for offset := 0; offset < len(data); offset += chunk {
+		end := offset + chunk
+		if end > len(data) {
+			end = len(data)
+		}
+		if _, err = runCommand(rw, tpm2.CmdWriteNV, handles, []tpm2.AuthCommand{auth.authCommand(rw)},
+			tpmutil.U16Bytes(data[offset:end]), uint16(offset)); err != nil {
+			return fmt.Errorf("failed to write NV index 0x%x at offset %d: %w", uint32(index), offset, err)
+		}
+	}
+	return nil
+}
+
+// NVIncrement increments an NV counter defined with NVTypeCounter.
+func NVIncrement(rw io.ReadWriter, index tpmutil.Handle, auth *NVAuth) error {
+	handles := []tpmutil.Handle{auth.authHandle(index), index}
+	if _, err := runCommand(rw, tpm2.CmdIncrementNVCounter, handles, []tpm2.AuthCommand{auth.authCommand(rw)}); err != nil {
+		return fmt.Errorf("failed to increment NV index 0x%x: %w", uint32(index), err)
+	}
+	return nil
+}
+
+// NVReadCounter reads the current value of an NV counter. The counter must
+// have been incremented at least once before it can be read.
+func NVReadCounter(rw io.ReadWriter, index tpmutil.Handle, auth *NVAuth) (uint64, error) {
+	data, err := nvReadRange(rw, index, auth, 0, 8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(data), nil
+}
+
+func nvBufferSize(rw io.ReadWriter) (int, error) {
+	props, _, err := tpm2.GetCapability(rw, tpm2.CapabilityTPMProperties, 1, uint32(tpm2.NVMaxBufferSize))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get TPM_PT_NV_BUFFER_MAX: %w", tpmError(err))
+	}
+	if len(props) != 1 {
+		return 0, fmt.Errorf("could not determine NV buffer size")
+	}
+	prop, ok := props[0].(tpm2.TaggedProperty)
+	if !ok || prop.Tag != tpm2.NVMaxBufferSize {
+		return 0, fmt.Errorf("could not determine NV buffer size")
+	}
+	return int(prop.Value), nil
+}
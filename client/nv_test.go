@@ -0,0 +1,157 @@
+package client_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+const testNVIndex = tpmutil.Handle(0x01500000)
+
+func TestNVReadWrite(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	// Larger than TPM_PT_NV_BUFFER_MAX, so reads and writes need chunking.
+	data := bytes.Repeat([]byte("NV data "), 192)
+	attrs := tpm2.AttrAuthRead | tpm2.AttrAuthWrite | tpm2.AttrNoDA
+	if err := client.DefineNV(rwc, testNVIndex, uint16(len(data)), attrs, "password", nil); err != nil {
+		t.Fatal(err)
+	}
+	defer client.UndefineNV(rwc, testNVIndex)
+
+	auth := &client.NVAuth{Password: "password"}
+	if err := client.NVWrite(rwc, testNVIndex, auth, data); err != nil {
+		t.Fatal(err)
+	}
+	got, err := client.NVRead(rwc, testNVIndex, auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("got NV data %q, want %q", got, data)
+	}
+
+	if _, err = client.NVRead(rwc, testNVIndex, &client.NVAuth{Password: "wrong"}); err == nil {
+		t.Error("expected failure when reading with the wrong password")
+	}
+	if err = client.NVWrite(rwc, testNVIndex, nil, data); err == nil {
+		t.Error("expected failure when writing without a password")
+	}
+}
+
+func TestNVOwnerAuth(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	data := []byte("provisioning data")
+	attrs := tpm2.AttrOwnerRead | tpm2.AttrOwnerWrite
+	if err := client.DefineNV(rwc, testNVIndex, uint16(len(data)), attrs, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	defer client.UndefineNV(rwc, testNVIndex)
+
+	auth := &client.NVAuth{Handle: tpm2.HandleOwner}
+	if err := client.NVWrite(rwc, testNVIndex, auth, data); err != nil {
+		t.Fatal(err)
+	}
+	got, err := client.NVRead(rwc, testNVIndex, auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("got NV data %q, want %q", got, data)
+	}
+	if _, err = client.NVRead(rwc, testNVIndex, nil); err == nil {
+		t.Error("expected failure when reading with index authorization")
+	}
+}
+
+func startSession(t *testing.T, rw io.ReadWriter, sessionType tpm2.SessionType) tpmutil.Handle {
+	t.Helper()
+	session, _, err := tpm2.StartAuthSession(rw, tpm2.HandleNull, tpm2.HandleNull,
+		make([]byte, client.SessionHashAlg.Size()), nil, sessionType, tpm2.AlgNull, client.SessionHashAlgTpm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return session
+}
+
+func TestNVPolicyRead(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	trial := startSession(t, rwc, tpm2.SessionTrial)
+	if err := tpm2.PolicyCommandCode(rwc, trial, tpm2.CmdReadNV); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := tpm2.PolicyGetDigest(rwc, trial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpm2.FlushContext(rwc, trial)
+
+	data := []byte("policy protected data")
+	attrs := tpm2.AttrPolicyRead | tpm2.AttrAuthWrite
+	if err := client.DefineNV(rwc, testNVIndex, uint16(len(data)), attrs, "", policy); err != nil {
+		t.Fatal(err)
+	}
+	defer client.UndefineNV(rwc, testNVIndex)
+	if err := client.NVWrite(rwc, testNVIndex, nil, data); err != nil {
+		t.Fatal(err)
+	}
+
+	session := startSession(t, rwc, tpm2.SessionPolicy)
+	defer tpm2.FlushContext(rwc, session)
+	auth := &client.NVAuth{Session: session}
+	if _, err = client.NVRead(rwc, testNVIndex, auth); err == nil {
+		t.Error("expected failure when reading with an unsatisfied policy")
+	}
+
+	if err := tpm2.PolicyCommandCode(rwc, session, tpm2.CmdReadNV); err != nil {
+		t.Fatal(err)
+	}
+	got, err := client.NVRead(rwc, testNVIndex, auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("got NV data %q, want %q", got, data)
+	}
+}
+
+func TestNVCounter(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	attrs := client.NVTypeCounter | tpm2.AttrAuthRead | tpm2.AttrAuthWrite | tpm2.AttrNoDA
+	if err := client.DefineNV(rwc, testNVIndex, 8, attrs, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	defer client.UndefineNV(rwc, testNVIndex)
+
+	if err := client.NVIncrement(rwc, testNVIndex, nil); err != nil {
+		t.Fatal(err)
+	}
+	first, err := client.NVReadCounter(rwc, testNVIndex, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := client.NVIncrement(rwc, testNVIndex, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := client.NVReadCounter(rwc, testNVIndex, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != first+3 {
+		t.Errorf("got counter value %d, want %d", got, first+3)
+	}
+}
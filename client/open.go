@@ -0,0 +1,32 @@
+package client
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+)
+
+// OpenOpts configures OpenTPMWithOpts.
+type OpenOpts struct {
+	// The TPM character devices tried, in order. If empty, DefaultTPMPaths
+	// are tried. Paths are not supported on Windows, where the TPM is opened
+	// with TBS.
+	Paths []string
+	// FlushSessions, if true, flushes the loaded and saved sessions in the TPM
+	// after opening it (see FlushHandles). Sessions are not flushed when a
+	// process exits without closing them, so the TPM can run out of session
+	// slots. This must only be used if no other process uses the TPM, such as
+	// with /dev/tpm0, which can only be opened by one process.
+	FlushSessions bool
+}
+
+// flushOpenedSessions flushes the sessions of the TPM opened at path, closing
+// the TPM on failure.
+func flushOpenedSessions(rwc io.ReadWriteCloser, path string) error {
+	if _, err := FlushHandles(rwc, tpm2.HandleTypeLoadedSession, tpm2.HandleTypeSavedSession); err != nil {
+		rwc.Close()
+		return fmt.Errorf("flushing sessions of %s: %w", path, err)
+	}
+	return nil
+}
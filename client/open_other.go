@@ -0,0 +1,66 @@
+// +build !windows
+
+package client
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/google/go-tpm/tpm2"
+)
+
+// DefaultTPMPaths are the TPM character devices tried by OpenTPM, in order:
+// the kernel's resource manager, then the TPM itself.
+var DefaultTPMPaths = []string{"/dev/tpmrm0", "/dev/tpm0"}
+
+// OpenTPM opens the first existing TPM character device of DefaultTPMPaths.
+// Use OpenTPMDevice to know which device was opened.
+func OpenTPM() (io.ReadWriteCloser, error) {
+	rwc, _, err := OpenTPMDevice()
+	return rwc, err
+}
+
+// OpenTPMDevice opens the first existing TPM character device of paths (or of
+// DefaultTPMPaths if no paths are provided), returning the path of the device.
+//
+// The kernel's resource manager (/dev/tpmrm0) can be opened by multiple
+// processes, and virtualizes transient objects and sessions: they are only
+// visible to the process which loaded them (see Handles), and are flushed
+// when the TPM is closed. With /dev/tpm0, only one process can open the TPM,
+// and objects must be flushed explicitly (for example, with Key.Close).
+func OpenTPMDevice(paths ...string) (io.ReadWriteCloser, string, error) {
+	return OpenTPMWithOpts(OpenOpts{Paths: paths})
+}
+
+// OpenTPMWithOpts opens the first existing TPM character device of
+// opts.Paths, as done by OpenTPMDevice, returning the path of the device.
+func OpenTPMWithOpts(opts OpenOpts) (io.ReadWriteCloser, string, error) {
+	rwc, path, err := openTPMPaths(opts.Paths)
+	if err != nil || !opts.FlushSessions {
+		return rwc, path, err
+	}
+	if err = flushOpenedSessions(rwc, path); err != nil {
+		return nil, "", err
+	}
+	return rwc, path, nil
+}
+
+func openTPMPaths(paths []string) (io.ReadWriteCloser, string, error) {
+	if len(paths) == 0 {
+		paths = DefaultTPMPaths
+	}
+	var err error
+	for _, path := range paths {
+		var rwc io.ReadWriteCloser
+		rwc, err = tpm2.OpenTPM(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		return rwc, path, nil
+	}
+	return nil, "", fmt.Errorf("no TPM device found: %w", err)
+}
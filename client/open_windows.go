@@ -0,0 +1,46 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+)
+
+// OpenTPM opens the TPM using the Windows TPM Base Services (TBS). As with
+// /dev/tpmrm0 on Linux, TBS virtualizes transient objects and sessions, so they
+// are flushed when the TPM is closed.
+func OpenTPM() (io.ReadWriteCloser, error) {
+	rwc, err := tpm2.OpenTPM()
+	if err != nil {
+		return nil, fmt.Errorf("opening TBS: %w", err)
+	}
+	return rwc, nil
+}
+
+// The path returned by OpenTPMDevice and OpenTPMWithOpts on Windows.
+const tbsPath = "TBS"
+
+// OpenTPMDevice opens the TPM using TBS, as done by OpenTPM, returning "TBS"
+// as the path of the device. There are no TPM devices on Windows, so it fails
+// if any paths are provided.
+func OpenTPMDevice(paths ...string) (io.ReadWriteCloser, string, error) {
+	return OpenTPMWithOpts(OpenOpts{Paths: paths})
+}
+
+// OpenTPMWithOpts opens the TPM using TBS, as done by OpenTPMDevice, returning
+// "TBS" as the path of the device. It fails if opts.Paths is not empty.
+func OpenTPMWithOpts(opts OpenOpts) (io.ReadWriteCloser, string, error) {
+	if len(opts.Paths) != 0 {
+		return nil, "", errors.New("TPM device paths are not supported on Windows, the TPM is opened with TBS")
+	}
+	rwc, err := OpenTPM()
+	if err != nil || !opts.FlushSessions {
+		return rwc, tbsPath, err
+	}
+	if err = flushOpenedSessions(rwc, tbsPath); err != nil {
+		return nil, "", err
+	}
+	return rwc, tbsPath, nil
+}
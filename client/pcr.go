@@ -1,6 +1,7 @@
 package client
 
 import (
+	"bytes"
 	"crypto"
 	"fmt"
 	"io"
@@ -9,12 +10,14 @@ import (
 	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
 	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
 	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
 )
 
 // NumPCRs is set to the spec minimum of 24, as that's all go-tpm supports.
 const NumPCRs = 24
 
-// We hard-code SHA256 as the policy session hash algorithms. Note that this
+// We use SHA256 as the default policy session hash algorithms (see
+// SealHashAlgs for sealed objects). Note that this
 // differs from the PCR hash algorithm (which selects the bank of PCRs to use)
 // and the Public area Name algorithm. We also chose this for compatibility with
 // github.com/google/go-tpm/tpm2, as it hardcodes the nameAlg as SHA256 in
@@ -24,21 +27,15 @@ const (
 	SessionHashAlgTpm = tpm2.AlgSHA256
 )
 
-// CertifyHashAlgTpm is the hard-coded algorithm used in certify PCRs.
+// CertifyHashAlgTpm is the default algorithm used in certify PCRs (see
+// SealHashAlgs).
 const CertifyHashAlgTpm = tpm2.AlgSHA256
 
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
-
 // Get a list of selections corresponding to the TPM's implemented PCRs
 func implementedPCRs(rw io.ReadWriter) ([]tpm2.PCRSelection, error) {
 	caps, moreData, err := tpm2.GetCapability(rw, tpm2.CapabilityPCRs, math.MaxUint32, 0)
 	if err != nil {
-		return nil, fmt.Errorf("listing implemented PCR banks: %w", err)
+		return nil, fmt.Errorf("listing implemented PCR banks: %w", tpmError(err))
 	}
 	if moreData {
 		return nil, fmt.Errorf("extra data from GetCapability")
@@ -57,29 +54,192 @@ func implementedPCRs(rw io.ReadWriter) ([]tpm2.PCRSelection, error) {
 // ReadPCRs fetches all the PCR values specified in sel, making multiple calls
 // to the TPM if necessary.
 func ReadPCRs(rw io.ReadWriter, sel tpm2.PCRSelection) (*pb.PCRs, error) {
-	pl := pb.PCRs{
-		Hash: pb.HashAlgo(sel.Hash),
-		Pcrs: map[uint32][]byte{},
+	banks, err := ReadPCRBanks(rw, []tpm2.PCRSelection{sel})
+	if err != nil {
+		return nil, err
 	}
+	return banks[0], nil
+}
 
-	for i := 0; i < len(sel.PCRs); i += 8 {
-		end := min(i+8, len(sel.PCRs))
-		pcrSel := tpm2.PCRSelection{
-			Hash: sel.Hash,
-			PCRs: sel.PCRs[i:end],
+// The number of times ReadPCRBanks starts again if a PCR is extended while it
+// reads the PCRs.
+const maxPCRReadAttempts = 4
+
+// ReadPCRBanks fetches the PCR values specified in sels, returning one
+// pb.PCRs for each selection. The selections (which can be of different
+// banks) are combined in each TPM2_PCR_Read command, so the TPM returns as
+// many PCRs (usually 8) as possible each time. This uses fewer commands than
+// calling ReadPCRs for each bank, which matters on slow TPMs. All the values
+// are read while no PCR is extended, as indicated by the TPM's PCR update
+// counter.
+func ReadPCRBanks(rw io.ReadWriter, sels []tpm2.PCRSelection) ([]*pb.PCRs, error) {
+	for attempt := 0; ; attempt++ {
+		banks, consistent, err := readPCRBanks(rw, sels)
+		if err != nil || consistent {
+			return banks, err
+		}
+		if attempt+1 == maxPCRReadAttempts {
+			return nil, fmt.Errorf("PCRs were extended during each of %d attempts to read them", maxPCRReadAttempts)
 		}
+	}
+}
 
-		pcrMap, err := tpm2.ReadPCRs(rw, pcrSel)
+// readPCRBanks reads the PCRs in sels, also returning whether the PCR update
+// counter was the same for all the TPM2_PCR_Read commands.
+func readPCRBanks(rw io.ReadWriter, sels []tpm2.PCRSelection) (banks []*pb.PCRs, consistent bool, err error) {
+	banks = make([]*pb.PCRs, len(sels))
+	// The PCRs left to read in each bank.
+	remaining := map[tpm2.Algorithm]map[int]bool{}
+	var order []tpm2.Algorithm
+	for i, sel := range sels {
+		banks[i] = &pb.PCRs{Hash: pb.HashAlgo(sel.Hash), Pcrs: map[uint32][]byte{}}
+		if remaining[sel.Hash] == nil {
+			remaining[sel.Hash] = map[int]bool{}
+			order = append(order, sel.Hash)
+		}
+		for _, pcr := range sel.PCRs {
+			if pcr < 0 || pcr >= NumPCRs {
+				return nil, false, fmt.Errorf("PCR %d out of range", pcr)
+			}
+			remaining[sel.Hash][pcr] = true
+		}
+	}
+	values := map[tpm2.Algorithm]map[int][]byte{}
+
+	var updateCounter uint32
+	for first := true; ; first = false {
+		var request []tpm2.PCRSelection
+		for _, hash := range order {
+			if len(remaining[hash]) == 0 {
+				continue
+			}
+			sel := tpm2.PCRSelection{Hash: hash}
+			for pcr := range remaining[hash] {
+				sel.PCRs = append(sel.PCRs, pcr)
+			}
+			request = append(request, sel)
+		}
+		if len(request) == 0 {
+			break
+		}
+
+		counter, read, digests, err := pcrRead(rw, request)
 		if err != nil {
-			return nil, err
+			return nil, false, err
+		}
+		if first {
+			updateCounter = counter
+		} else if counter != updateCounter {
+			return nil, false, nil
+		}
+		returned := 0
+		for _, sel := range read {
+			for _, pcr := range sel.PCRs {
+				if returned == len(digests) {
+					return nil, false, fmt.Errorf("TPM returned fewer PCR values than its selection")
+				}
+				if !remaining[sel.Hash][pcr] {
+					return nil, false, fmt.Errorf("TPM returned unrequested PCR %d of bank %v", pcr, sel.Hash)
+				}
+				delete(remaining[sel.Hash], pcr)
+				if values[sel.Hash] == nil {
+					values[sel.Hash] = map[int][]byte{}
+				}
+				values[sel.Hash][pcr] = digests[returned]
+				returned++
+			}
 		}
+		if returned == 0 {
+			return nil, false, fmt.Errorf("TPM did not return any of the PCRs %v", request)
+		}
+	}
+
+	for i, sel := range sels {
+		for _, pcr := range sel.PCRs {
+			banks[i].Pcrs[uint32(pcr)] = values[sel.Hash][pcr]
+		}
+	}
+	return banks, true, nil
+}
+
+// pcrRead runs TPM2_PCR_Read, which (unlike tpm2.ReadPCRs) supports
+// selections of multiple banks.
+func pcrRead(rw io.ReadWriter, sels []tpm2.PCRSelection) (updateCounter uint32, read []tpm2.PCRSelection, digests [][]byte, err error) {
+	selBytes, err := encodePCRSelections(sels)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	resp, err := runCommand(rw, tpm2.CmdPCRRead, nil, nil, tpmutil.RawBytes(selBytes))
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("reading PCRs: %w", err)
+	}
+	buf := bytes.NewBuffer(resp)
+	if err = tpmutil.UnpackBuf(buf, &updateCounter); err != nil {
+		return 0, nil, nil, fmt.Errorf("decoding PCR update counter: %w", err)
+	}
+	if read, err = decodePCRSelections(buf); err != nil {
+		return 0, nil, nil, fmt.Errorf("decoding PCR selection: %w", err)
+	}
+	var count uint32
+	if err = tpmutil.UnpackBuf(buf, &count); err != nil {
+		return 0, nil, nil, fmt.Errorf("decoding PCR values: %w", err)
+	}
+	for i := uint32(0); i < count; i++ {
+		var digest tpmutil.U16Bytes
+		if err = tpmutil.UnpackBuf(buf, &digest); err != nil {
+			return 0, nil, nil, fmt.Errorf("decoding PCR values: %w", err)
+		}
+		digests = append(digests, digest)
+	}
+	return updateCounter, read, digests, nil
+}
 
-		for pcr, val := range pcrMap {
-			pl.Pcrs[uint32(pcr)] = val
+// encodePCRSelections encodes a TPML_PCR_SELECTION.
+func encodePCRSelections(sels []tpm2.PCRSelection) ([]byte, error) {
+	out, err := tpmutil.Pack(uint32(len(sels)))
+	if err != nil {
+		return nil, err
+	}
+	for _, sel := range sels {
+		bitmap := make([]byte, NumPCRs/8)
+		for _, pcr := range sel.PCRs {
+			bitmap[pcr/8] |= 1 << (pcr % 8)
+		}
+		encoded, err := tpmutil.Pack(sel.Hash, uint8(len(bitmap)))
+		if err != nil {
+			return nil, err
 		}
+		out = append(append(out, encoded...), bitmap...)
 	}
+	return out, nil
+}
 
-	return &pl, nil
+// decodePCRSelections decodes a TPML_PCR_SELECTION.
+func decodePCRSelections(buf *bytes.Buffer) ([]tpm2.PCRSelection, error) {
+	var count uint32
+	if err := tpmutil.UnpackBuf(buf, &count); err != nil {
+		return nil, err
+	}
+	if count > uint32(buf.Len()) {
+		return nil, fmt.Errorf("invalid selection count %d", count)
+	}
+	sels := make([]tpm2.PCRSelection, count)
+	for i := range sels {
+		var size uint8
+		if err := tpmutil.UnpackBuf(buf, &sels[i].Hash, &size); err != nil {
+			return nil, err
+		}
+		bitmap := buf.Next(int(size))
+		if len(bitmap) != int(size) {
+			return nil, fmt.Errorf("selection bitmap too short")
+		}
+		for pcr := 0; pcr < 8*len(bitmap); pcr++ {
+			if bitmap[pcr/8]&(1<<(pcr%8)) != 0 {
+				sels[i].PCRs = append(sels[i].PCRs, pcr)
+			}
+		}
+	}
+	return sels, nil
 }
 
 // ReadAllPCRs fetches all the PCR values from all implemented PCR banks.
@@ -88,15 +248,27 @@ func ReadAllPCRs(rw io.ReadWriter) ([]*pb.PCRs, error) {
 	if err != nil {
 		return nil, err
 	}
+	return ReadPCRBanks(rw, sels)
+}
 
-	allPcrs := make([]*pb.PCRs, len(sels))
-	for i, sel := range sels {
-		allPcrs[i], err = ReadPCRs(rw, sel)
-		if err != nil {
-			return nil, fmt.Errorf("reading bank %x PCRs: %w", sel.Hash, err)
-		}
+// ExtendPCR records a measurement of data in the specified PCR of the hash
+// bank. The data is first hashed with the bank's algorithm, so the new PCR
+// value is H(oldValue || H(data)). Applications should generally only extend
+// PCRs 8-15 and 23, as the others are used to measure the platform's boot.
+func ExtendPCR(rw io.ReadWriter, pcr int, hash tpm2.Algorithm, data []byte) error {
+	if pcr < 0 || pcr >= NumPCRs {
+		return fmt.Errorf("PCR %d out of range", pcr)
+	}
+	newHash, err := notinternal.NewHash(hash)
+	if err != nil {
+		return fmt.Errorf("not a valid hash type: %v", hash)
+	}
+	hasher := newHash()
+	hasher.Write(data)
+	if err = tpm2.PCRExtend(rw, tpmutil.Handle(pcr), hash, hasher.Sum(nil), ""); err != nil {
+		return fmt.Errorf("extending PCR %d: %w", pcr, tpmError(err))
 	}
-	return allPcrs, nil
+	return nil
 }
 
 // SealCurrent seals data to the current specified PCR selection.
@@ -105,11 +277,42 @@ type SealCurrent struct{ tpm2.PCRSelection }
 // SealTarget predicatively seals data to the given specified PCR values.
 type SealTarget struct{ Pcrs *pb.PCRs }
 
+// SealAlternatives seals data such that it can be unsealed if the PCRs are in
+// any one of the alternative states. This allows sealed data to survive a
+// planned update, for example by sealing to both the current PCRs and the
+// values predicted for the next firmware. Between 1 and 8 alternatives are
+// supported, which are combined with TPM2_PolicyOR.
+type SealAlternatives []SealOpts
+
 // SealOpts specifies the PCR values that should be used for Seal().
 type SealOpts interface {
 	PCRsForSealing(rw io.ReadWriter) (*pb.PCRs, error)
 }
 
+// SealHashAlgs seals to the PCRs of Opts (or to no PCRs if Opts is nil) with
+// other hash algorithms than SessionHashAlgTpm and CertifyHashAlgTpm, for
+// example on platforms whose primary PCR bank is SHA-384. Unseal finds the
+// algorithms in the SealedBytes, so no options are needed when unsealing.
+type SealHashAlgs struct {
+	Opts SealOpts
+	// SessionHashAlg is the name algorithm of the sealed object, which is
+	// also the hash algorithm of its policy and of the policy session used to
+	// unseal it. Defaults to SessionHashAlgTpm. Other algorithms can only be
+	// used when sealing to a single PCR state (not with SealAlternatives).
+	SessionHashAlg tpm2.Algorithm
+	// CertifyHashAlg is the PCR bank recorded in the creation data, and
+	// certified by Unseal's CertifyOpts. Defaults to CertifyHashAlgTpm.
+	CertifyHashAlg tpm2.Algorithm
+}
+
+// PCRsForSealing returns the PCRs of Opts, or nil if Opts is nil.
+func (p SealHashAlgs) PCRsForSealing(rw io.ReadWriter) (*pb.PCRs, error) {
+	if p.Opts == nil {
+		return nil, nil
+	}
+	return p.Opts.PCRsForSealing(rw)
+}
+
 // PCRsForSealing read from TPM and return the selected PCRs.
 func (p SealCurrent) PCRsForSealing(rw io.ReadWriter) (*pb.PCRs, error) {
 	if len(p.PCRSelection.PCRs) == 0 {
@@ -126,12 +329,55 @@ func (p SealTarget) PCRsForSealing(_ io.ReadWriter) (*pb.PCRs, error) {
 	return p.Pcrs, nil
 }
 
+// PCRsForSealing returns the PCRs of the first alternative. Seal() uses all
+// of the alternatives.
+func (p SealAlternatives) PCRsForSealing(rw io.ReadWriter) (*pb.PCRs, error) {
+	if len(p) == 0 {
+		panic("SealAlternatives contains 0 alternatives")
+	}
+	return p[0].PCRsForSealing(rw)
+}
+
+func (p SealAlternatives) alternativePCRs(rw io.ReadWriter) ([]*pb.PCRs, error) {
+	if len(p) == 0 || len(p) > maxPolicyOrBranches {
+		return nil, fmt.Errorf("between 1 and %d alternatives are supported, got %d", maxPolicyOrBranches, len(p))
+	}
+	alternatives := make([]*pb.PCRs, 0, len(p))
+	for i, opts := range p {
+		pcrs, err := opts.PCRsForSealing(rw)
+		if err != nil {
+			return nil, fmt.Errorf("alternative %d: %w", i, err)
+		}
+		alternatives = append(alternatives, pcrs)
+	}
+	return alternatives, nil
+}
+
+// alternativesPolicy returns the policy satisfied by any of the PCR states,
+// optionally also requiring the object's auth value.
+func alternativesPolicy(alternatives []*pb.PCRs, authValue bool) Policy {
+	var policy Policy = PolicyPCR{Pcrs: alternatives[0]}
+	if len(alternatives) > 1 {
+		branches := make(PolicyOr, 0, len(alternatives))
+		for _, pcrs := range alternatives {
+			branches = append(branches, PolicyPCR{Pcrs: pcrs})
+		}
+		policy = branches
+	}
+	if authValue {
+		policy = PolicySequence{policy, PolicyAuthValue{}}
+	}
+	return policy
+}
+
 // CertifyCurrent certifies that a selection of current PCRs have the same value when sealing.
-// Hash Algorithm in the selection should be CertifyHashAlgTpm.
+// Hash Algorithm in the selection should be CertifyHashAlgTpm (or the
+// SealHashAlgs.CertifyHashAlg used when sealing).
 type CertifyCurrent struct{ tpm2.PCRSelection }
 
 // CertifyExpected certifies that the TPM had a specific set of PCR values when sealing.
-// Hash Algorithm in the PCR proto should be CertifyHashAlgTpm.
+// Hash Algorithm in the PCR proto should be CertifyHashAlgTpm (or the
+// SealHashAlgs.CertifyHashAlg used when sealing).
 type CertifyExpected struct{ Pcrs *pb.PCRs }
 
 // CertifyOpts determines if the given PCR value can pass certification in Unseal().
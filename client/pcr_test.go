@@ -6,11 +6,13 @@ import (
 	"crypto/sha256"
 	"crypto/sha512"
 	"fmt"
+	"io"
 	"testing"
 
 	"github.com/ThalesIgnite/go-tpm-tools/client"
 	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
 	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
 	"github.com/google/go-tpm/tpm2"
 	"github.com/google/go-tpm/tpmutil"
 )
@@ -87,6 +89,115 @@ func TestReadPCRs(t *testing.T) {
 	}
 }
 
+// Counts the commands sent to the TPM.
+type commandCounter struct {
+	io.ReadWriter
+	commands int
+}
+
+func (c *commandCounter) Write(p []byte) (int, error) {
+	c.commands++
+	return c.ReadWriter.Write(p)
+}
+
+func TestReadPCRBanks(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	pcrs := []int{0, 1, 2, test.DebugPCR}
+	sels := []tpm2.PCRSelection{
+		{Hash: tpm2.AlgSHA1, PCRs: pcrs},
+		{Hash: tpm2.AlgSHA256, PCRs: pcrs},
+	}
+	counter := &commandCounter{ReadWriter: rwc}
+	banks, err := client.ReadPCRBanks(counter, sels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The 8 PCRs fit in a single response.
+	if counter.commands != 1 {
+		t.Errorf("read the PCRs with %d commands, want 1", counter.commands)
+	}
+	if len(banks) != len(sels) {
+		t.Fatalf("got %d banks, want %d", len(banks), len(sels))
+	}
+	for i, sel := range sels {
+		if banks[i].GetHash() != pb.HashAlgo(sel.Hash) {
+			t.Errorf("got bank %v, want %v", banks[i].GetHash(), sel.Hash)
+		}
+		for _, pcr := range sel.PCRs {
+			want, err := tpm2.ReadPCR(rwc, pcr, sel.Hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := banks[i].GetPcrs()[uint32(pcr)]; !bytes.Equal(got, want) {
+				t.Errorf("%v PCR%d: got %X, want %X", sel.Hash, pcr, got, want)
+			}
+		}
+	}
+
+	all, err := client.ReadAllPCRs(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bank := range all {
+		if len(bank.GetPcrs()) != client.NumPCRs {
+			t.Errorf("got %d PCRs in bank %v, want %d", len(bank.GetPcrs()), bank.GetHash(), client.NumPCRs)
+		}
+	}
+
+	if _, err = client.ReadPCRBanks(rwc, []tpm2.PCRSelection{{Hash: tpm2.AlgSHA256, PCRs: []int{client.NumPCRs}}}); err == nil {
+		t.Error("expected failure when reading an out of range PCR")
+	}
+}
+
+func TestExtendPCR(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	data := []byte("application measurement")
+	cases := []struct {
+		name string
+		alg  tpm2.Algorithm
+	}{
+		{"SHA1", tpm2.AlgSHA1},
+		{"SHA256", tpm2.AlgSHA256},
+		{"SHA384", tpm2.AlgSHA384},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			test.SkipOnUnsupportedAlg(t, rwc, c.alg)
+
+			old, err := tpm2.ReadPCR(rwc, test.DebugPCR, c.alg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = client.ExtendPCR(rwc, test.DebugPCR, c.alg, data); err != nil {
+				t.Fatal(err)
+			}
+			got, err := tpm2.ReadPCR(rwc, test.DebugPCR, c.alg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			hash, _ := c.alg.Hash()
+			hasher := hash.New()
+			hasher.Write(data)
+			want, err := pcrExtend(c.alg, old, hasher.Sum(nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("got PCR value %X, want %X", got, want)
+			}
+		})
+	}
+
+	if err := client.ExtendPCR(rwc, client.NumPCRs, tpm2.AlgSHA256, data); err == nil {
+		t.Error("expected failure when extending an out of range PCR")
+	}
+}
+
 func TestCheckContainedPCRs(t *testing.T) {
 	rwc := test.GetTPM(t)
 	defer client.CheckedClose(t, rwc)
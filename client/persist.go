@@ -0,0 +1,81 @@
+package client
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// Persistent handles from TPM 2.0 Handles and Localities 2.3.1 - Table 11.
+// Handles below platformPersistentHandle are owned by the owner hierarchy.
+const (
+	minPersistentHandle      = tpmutil.Handle(0x81000000)
+	platformPersistentHandle = tpmutil.Handle(0x81800000)
+	maxPersistentHandle      = tpmutil.Handle(0x81FFFFFF)
+)
+
+func isPersistent(h tpmutil.Handle) bool {
+	return h >= minPersistentHandle && h <= maxPersistentHandle
+}
+
+// persistentOwner returns the hierarchy authorizing TPM2_EvictControl for a
+// persistent handle.
+func persistentOwner(h tpmutil.Handle) tpmutil.Handle {
+	if h >= platformPersistentHandle {
+		return tpm2.HandlePlatform
+	}
+	return tpm2.HandleOwner
+}
+
+// Persist makes a transient key persistent at the provided handle, so that it
+// survives reboots. The transient key is flushed, and the Key then refers to
+// the persistent handle. This is authorized by the hierarchy owning the handle
+// (owner or platform), see HierarchyAuthGetter.
+func (k *Key) Persist(handle tpmutil.Handle) error {
+	if !isPersistent(handle) {
+		return fmt.Errorf("handle 0x%x is not a persistent handle", handle)
+	}
+	if isPersistent(k.handle) {
+		return fmt.Errorf("key is already persisted at 0x%x", k.handle)
+	}
+	if k.cached {
+		return fmt.Errorf("cannot persist key 0x%x owned by a KeyCache", k.handle)
+	}
+	if err := tpm2.EvictControl(k.rw, hierarchyAuth(k.rw, persistentOwner(handle)), persistentOwner(handle), k.handle, handle); err != nil {
+		return fmt.Errorf("persisting key at 0x%x: %w", handle, tpmError(err))
+	}
+	tpm2.FlushContext(k.rw, k.handle)
+	k.handle = handle
+	return nil
+}
+
+// EvictPersistent removes the persistent object at the provided handle from
+// the TPM. This is authorized by the hierarchy owning the handle (owner or
+// platform), see HierarchyAuthGetter.
+func EvictPersistent(rw io.ReadWriter, handle tpmutil.Handle) error {
+	if !isPersistent(handle) {
+		return fmt.Errorf("handle 0x%x is not a persistent handle", handle)
+	}
+	if err := tpm2.EvictControl(rw, hierarchyAuth(rw, persistentOwner(handle)), persistentOwner(handle), handle, handle); err != nil {
+		return fmt.Errorf("evicting handle 0x%x: %w", handle, tpmError(err))
+	}
+	return nil
+}
+
+// KeyFromPersistentHandle returns the key persisted at the provided handle
+// (for example, with Key.Persist or "gotpm persist"). As with NewKey, the key
+// must either have an empty auth policy or the default EK auth policy. Closing
+// the key does not remove it from the TPM (see EvictPersistent).
+func KeyFromPersistentHandle(rw io.ReadWriter, handle tpmutil.Handle) (*Key, error) {
+	if !isPersistent(handle) {
+		return nil, fmt.Errorf("handle 0x%x is not a persistent handle", handle)
+	}
+	pubArea, _, _, err := tpm2.ReadPublic(rw, handle)
+	if err != nil {
+		return nil, fmt.Errorf("reading public area of 0x%x: %w", handle, tpmError(err))
+	}
+	k := &Key{rw: rw, handle: handle, pubArea: pubArea}
+	return k, k.finish()
+}
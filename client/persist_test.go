@@ -0,0 +1,95 @@
+package client_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+const testPersistentHandle = tpmutil.Handle(0x81008F10)
+
+func hasHandle(t *testing.T, handle tpmutil.Handle, handles []tpmutil.Handle) bool {
+	t.Helper()
+	for _, h := range handles {
+		if h == handle {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPersist(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	srk, err := client.NewKey(rwc, tpm2.HandleOwner, client.SRKTemplateECC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srk.Close()
+	if err = srk.Persist(tpmutil.Handle(0x01000000)); err == nil {
+		t.Error("expected failure persisting to a non-persistent handle")
+	}
+	if err = srk.Persist(testPersistentHandle); err != nil {
+		t.Fatal(err)
+	}
+	if srk.Handle() != testPersistentHandle {
+		t.Errorf("got handle 0x%x, want 0x%x", srk.Handle(), testPersistentHandle)
+	}
+	if err = srk.Persist(testPersistentHandle + 1); err == nil {
+		t.Error("expected failure persisting an already persistent key")
+	}
+
+	// The persisted key remains usable.
+	sealed, err := srk.Seal([]byte("secret"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = srk.Unseal(sealed, nil); err != nil {
+		t.Fatal(err)
+	}
+	persistent, err := client.Handles(rwc, tpm2.HandleTypePersistent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasHandle(t, testPersistentHandle, persistent) {
+		t.Errorf("handle 0x%x not in persistent handles %v", testPersistentHandle, persistent)
+	}
+	transient, err := client.Handles(rwc, tpm2.HandleTypeTransient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transient) != 0 {
+		t.Errorf("got %d transient handles, want 0", len(transient))
+	}
+
+	loaded, err := client.KeyFromPersistentHandle(rwc, testPersistentHandle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.PublicKey(), srk.PublicKey()) {
+		t.Error("key loaded from the persistent handle does not match")
+	}
+	loaded.Close()
+	if _, err = client.KeyFromPersistentHandle(rwc, testPersistentHandle+1); err == nil {
+		t.Error("expected failure loading an empty persistent handle")
+	}
+
+	if err = client.EvictPersistent(rwc, testPersistentHandle); err != nil {
+		t.Fatal(err)
+	}
+	if persistent, err = client.Handles(rwc, tpm2.HandleTypePersistent); err != nil {
+		t.Fatal(err)
+	}
+	if hasHandle(t, testPersistentHandle, persistent) {
+		t.Errorf("handle 0x%x still persistent after eviction", testPersistentHandle)
+	}
+	if err = client.EvictPersistent(rwc, testPersistentHandle); err == nil {
+		t.Error("expected failure evicting an empty handle")
+	}
+}
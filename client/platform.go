@@ -0,0 +1,192 @@
+package client
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+// Platform describes where the keys and certificates of a TPM are, as this
+// differs between physical TPMs and the vTPMs of each cloud platform. Code
+// attesting to a TPM can use a Platform instead of assuming where they are.
+type Platform interface {
+	// Name identifies the platform, such as "gce".
+	Name() string
+	// AttestationKey returns the AK of the given algorithm (tpm2.AlgRSA or
+	// tpm2.AlgECC), with its certificate if the platform has one. Platforms
+	// without provisioned AKs create one, as AttestationKeyRSA and
+	// AttestationKeyECC do.
+	AttestationKey(rw io.ReadWriter, algo tpm2.Algorithm) (*Key, error)
+	// GetEKCert returns the EK certificate, failing if there is none.
+	GetEKCert(rw io.ReadWriter) (*x509.Certificate, error)
+	// GetAKCert returns the certificate of the AK of the given algorithm (as
+	// returned by AttestationKey), failing if there is none.
+	GetAKCert(rw io.ReadWriter, algo tpm2.Algorithm) (*x509.Certificate, error)
+	// InstanceInfo returns the instance of the platform identified by the
+	// TPM, or nil if the platform does not identify its instances.
+	InstanceInfo(rw io.ReadWriter) (*pb.GCEInstanceInfo, error)
+	// KnownQuirks describes the known differences of the TPM from the TCG
+	// specifications (and from physical TPMs) which affect attestation.
+	KnownQuirks() []string
+}
+
+// The supported platforms.
+var (
+	// BareMetal is a physical TPM, with the EK certificate of its
+	// manufacturer (see GetEKCert) and no provisioned AK.
+	BareMetal Platform = bareMetal{}
+	// GCE is the vTPM of a Shielded VM on Google Compute Engine, with the
+	// AKs of GceAttestationKeyRSA and GceAttestationKeyECC.
+	GCE Platform = gce{}
+	// Azure is the vTPM of a Trusted Launch or confidential VM on Microsoft
+	// Azure, with the AK of AzureAttestationKey.
+	Azure Platform = azure{}
+	// AWS is the NitroTPM of an EC2 instance on Amazon Web Services, which has
+	// no EK certificate and no provisioned AK.
+	AWS Platform = aws{}
+)
+
+// Platforms returns the supported platforms.
+func Platforms() []Platform {
+	return []Platform{BareMetal, GCE, Azure, AWS}
+}
+
+type bareMetal struct{}
+
+func (bareMetal) Name() string { return "bare-metal" }
+
+func (bareMetal) AttestationKey(rw io.ReadWriter, algo tpm2.Algorithm) (*Key, error) {
+	switch algo {
+	case tpm2.AlgRSA:
+		return AttestationKeyRSA(rw)
+	case tpm2.AlgECC:
+		return AttestationKeyECC(rw)
+	default:
+		return nil, fmt.Errorf("unsupported AK algorithm %v", algo)
+	}
+}
+
+func (bareMetal) GetEKCert(rw io.ReadWriter) (*x509.Certificate, error) {
+	return GetEKCert(rw)
+}
+
+func (bareMetal) GetAKCert(io.ReadWriter, tpm2.Algorithm) (*x509.Certificate, error) {
+	return nil, errors.New("AKs of bare-metal TPMs have no certificate")
+}
+
+func (bareMetal) InstanceInfo(io.ReadWriter) (*pb.GCEInstanceInfo, error) {
+	return nil, nil
+}
+
+func (bareMetal) KnownQuirks() []string {
+	return []string{
+		"EK certificates mark TCG extensions as critical, and some are padded to the size of their NV index",
+	}
+}
+
+type gce struct{}
+
+func (gce) Name() string { return "gce" }
+
+func (gce) AttestationKey(rw io.ReadWriter, algo tpm2.Algorithm) (*Key, error) {
+	switch algo {
+	case tpm2.AlgRSA:
+		return GceAttestationKeyRSA(rw)
+	case tpm2.AlgECC:
+		return GceAttestationKeyECC(rw)
+	default:
+		return nil, fmt.Errorf("unsupported AK algorithm %v", algo)
+	}
+}
+
+func (gce) GetEKCert(rw io.ReadWriter) (*x509.Certificate, error) {
+	return GetEKCert(rw)
+}
+
+func (gce) GetAKCert(rw io.ReadWriter, algo tpm2.Algorithm) (*x509.Certificate, error) {
+	switch algo {
+	case tpm2.AlgRSA:
+		return certFromNvIndex(rw, GceAKCertNVIndexRSA)
+	case tpm2.AlgECC:
+		return certFromNvIndex(rw, GceAKCertNVIndexECC)
+	default:
+		return nil, fmt.Errorf("unsupported AK algorithm %v", algo)
+	}
+}
+
+func (gce) InstanceInfo(rw io.ReadWriter) (*pb.GCEInstanceInfo, error) {
+	return ReadGCEInstanceInfo(rw)
+}
+
+func (gce) KnownQuirks() []string {
+	return []string{
+		"AKs are created in the Endorsement Hierarchy from templates in NV indices",
+		"AK certificates are missing on older VMs",
+		"the S-CRTM version event of PCR0 is the GCE firmware version",
+	}
+}
+
+type azure struct{}
+
+func (azure) Name() string { return "azure" }
+
+func (azure) AttestationKey(rw io.ReadWriter, algo tpm2.Algorithm) (*Key, error) {
+	if algo != tpm2.AlgRSA {
+		return nil, fmt.Errorf("the Azure AK is an RSA key, not %v", algo)
+	}
+	return AzureAttestationKey(rw)
+}
+
+func (azure) GetEKCert(rw io.ReadWriter) (*x509.Certificate, error) {
+	return GetEKCert(rw)
+}
+
+func (azure) GetAKCert(rw io.ReadWriter, algo tpm2.Algorithm) (*x509.Certificate, error) {
+	if algo != tpm2.AlgRSA {
+		return nil, fmt.Errorf("the Azure AK is an RSA key, not %v", algo)
+	}
+	return certFromNvIndex(rw, AzureAKCertNVIndex)
+}
+
+func (azure) InstanceInfo(io.ReadWriter) (*pb.GCEInstanceInfo, error) {
+	return nil, nil
+}
+
+func (azure) KnownQuirks() []string {
+	return []string{
+		"the AK is persisted at AzureAKHandle, not created from a template",
+		"the AK certificate is not in the vTPM of some VMs, but in IMDS (see AzureIMDSAKCertFetcher)",
+		"the AK certificate has the critical TPM manufacturer Subject Alternative Name of EK certificates",
+	}
+}
+
+type aws struct{}
+
+func (aws) Name() string { return "aws" }
+
+func (aws) AttestationKey(rw io.ReadWriter, algo tpm2.Algorithm) (*Key, error) {
+	return bareMetal{}.AttestationKey(rw, algo)
+}
+
+func (aws) GetEKCert(io.ReadWriter) (*x509.Certificate, error) {
+	return nil, errors.New("NitroTPM has no EK certificate, its EK public key is given by the EC2 GetInstanceTpmEkPub API")
+}
+
+func (aws) GetAKCert(io.ReadWriter, tpm2.Algorithm) (*x509.Certificate, error) {
+	return nil, errors.New("NitroTPM has no AK certificates")
+}
+
+func (aws) InstanceInfo(io.ReadWriter) (*pb.GCEInstanceInfo, error) {
+	return nil, nil
+}
+
+func (aws) KnownQuirks() []string {
+	return []string{
+		"there are no EK or AK certificates, AKs must be trusted by other means",
+	}
+}
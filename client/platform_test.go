@@ -0,0 +1,109 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestPlatforms(t *testing.T) {
+	names := make(map[string]bool)
+	for _, p := range client.Platforms() {
+		if names[p.Name()] {
+			t.Errorf("duplicate platform name %q", p.Name())
+		}
+		names[p.Name()] = true
+		if len(p.KnownQuirks()) == 0 {
+			t.Errorf("platform %q has no known quirks", p.Name())
+		}
+	}
+}
+
+func TestBareMetalPlatform(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	for _, algo := range []tpm2.Algorithm{tpm2.AlgRSA, tpm2.AlgECC} {
+		ak, err := client.BareMetal.AttestationKey(rwc, algo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ak.PublicArea().Type != algo {
+			t.Errorf("got AK of type %v, want %v", ak.PublicArea().Type, algo)
+		}
+		ak.Close()
+	}
+	if _, err := client.BareMetal.AttestationKey(rwc, tpm2.AlgSymCipher); err == nil {
+		t.Error("expected creating an AK of an unsupported algorithm to fail")
+	}
+	if _, err := client.BareMetal.GetAKCert(rwc, tpm2.AlgRSA); err == nil {
+		t.Error("expected bare-metal TPMs to have no AK certificate")
+	}
+	if info, err := client.BareMetal.InstanceInfo(rwc); err != nil || info != nil {
+		t.Errorf("got instance info %v (%v) for a bare-metal TPM, want none", info, err)
+	}
+}
+
+func TestGCEPlatform(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	if _, err := client.GCE.GetAKCert(rwc, tpm2.AlgECC); err == nil {
+		t.Fatal("expected failure without a GCE AK certificate")
+	}
+	ak, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ak.Close()
+	cert := createGCEAKCert(t, ak.PublicKey(), marshalInstanceInfo(t, testInstanceInfo))
+	writeEKCert(t, rwc, client.GceAKCertNVIndexECC, cert.Raw)
+	defer client.UndefineNV(rwc, tpmutil.Handle(client.GceAKCertNVIndexECC))
+
+	akCert, err := client.GCE.GetAKCert(rwc, tpm2.AlgECC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !akCert.Equal(cert) {
+		t.Error("got a different AK certificate than the one in the NV index")
+	}
+	if _, err = client.GCE.GetAKCert(rwc, tpm2.AlgRSA); err == nil {
+		t.Error("expected failure without a GCE RSA AK certificate")
+	}
+	info, err := client.GCE.InstanceInfo(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(info, testInstanceInfo) {
+		t.Errorf("got instance info %v, want %v", info, testInstanceInfo)
+	}
+}
+
+func TestAzureAndAWSPlatforms(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	if _, err := client.Azure.AttestationKey(rwc, tpm2.AlgECC); err == nil {
+		t.Error("expected loading an ECC Azure AK to fail")
+	}
+	if _, err := client.Azure.GetAKCert(rwc, tpm2.AlgRSA); err == nil {
+		t.Error("expected failure without an Azure AK certificate")
+	}
+
+	ak, err := client.AWS.AttestationKey(rwc, tpm2.AlgRSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ak.Close()
+	if _, err = client.AWS.GetEKCert(rwc); err == nil {
+		t.Error("expected NitroTPM to have no EK certificate")
+	}
+	if _, err = client.AWS.GetAKCert(rwc, tpm2.AlgRSA); err == nil {
+		t.Error("expected NitroTPM to have no AK certificate")
+	}
+}
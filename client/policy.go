@@ -0,0 +1,356 @@
+package client
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+// Policy commands not defined by go-tpm.
+const (
+	cmdPolicyNV           tpmutil.Command = 0x00000149
+	cmdPolicyAuthValue    tpmutil.Command = 0x0000016B
+	cmdPolicyCounterTimer tpmutil.Command = 0x0000016D
+)
+
+// The maximum number of branches in a TPM2_PolicyOR.
+const maxPolicyOrBranches = 8
+
+// Operations (TPM_EO) used by PolicyCounterTimer and PolicyNV to compare
+// operandA (the TPM value) with operandB (the policy value).
+const (
+	OpEq         uint16 = 0x0000
+	OpNeq        uint16 = 0x0001
+	OpSignedGT   uint16 = 0x0002
+	OpUnsignedGT uint16 = 0x0003
+	OpSignedLT   uint16 = 0x0004
+	OpUnsignedLT uint16 = 0x0005
+	OpSignedGE   uint16 = 0x0006
+	OpUnsignedGE uint16 = 0x0007
+	OpSignedLE   uint16 = 0x0008
+	OpUnsignedLE uint16 = 0x0009
+	OpBitSet     uint16 = 0x000A
+	OpBitClear   uint16 = 0x000B
+)
+
+// Policy is a TPM authorization policy. A Policy can compute its policy
+// digest without a TPM (for use as an object's or NV index's auth policy),
+// and can be executed in a policy session to satisfy that auth policy.
+//
+// Policies are composed using PolicySequence (all policies must be satisfied)
+// and PolicyOr (one of the branches must be satisfied).
+type Policy interface {
+	// Extend returns the policy digest after this policy is applied to a
+	// session with the provided policy digest.
+	Extend(digest []byte, hashAlg crypto.Hash) ([]byte, error)
+	// Execute runs the policy commands on the provided policy session. The
+	// session's hash algorithm must be SessionHashAlg.
+	Execute(rw io.ReadWriter, session tpmutil.Handle) error
+}
+
+// PolicyDigest computes the policy digest for the provided Policy, starting
+// from an empty (all zero) digest.
+func PolicyDigest(policy Policy, hashAlg crypto.Hash) ([]byte, error) {
+	return policy.Extend(make([]byte, hashAlg.Size()), hashAlg)
+}
+
+func extendPolicy(digest []byte, hashAlg crypto.Hash, cmd tpmutil.Command, args ...[]byte) []byte {
+	cc, _ := tpmutil.Pack(cmd)
+	h := hashAlg.New()
+	h.Write(digest)
+	h.Write(cc)
+	for _, arg := range args {
+		h.Write(arg)
+	}
+	return h.Sum(nil)
+}
+
+// PolicySequence is satisfied only if all of its policies are satisfied. The
+// policies are executed in order.
+type PolicySequence []Policy
+
+// Extend applies each of the policies in order.
+func (p PolicySequence) Extend(digest []byte, hashAlg crypto.Hash) ([]byte, error) {
+	var err error
+	for _, policy := range p {
+		if digest, err = policy.Extend(digest, hashAlg); err != nil {
+			return nil, err
+		}
+	}
+	return digest, nil
+}
+
+// Execute executes each of the policies in order.
+func (p PolicySequence) Execute(rw io.ReadWriter, session tpmutil.Handle) error {
+	for _, policy := range p {
+		if err := policy.Execute(rw, session); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PolicyPCR is satisfied if the TPM's PCRs have the specified values.
+type PolicyPCR struct{ Pcrs *pb.PCRs }
+
+// Extend applies TPM2_PolicyPCR.
+func (p PolicyPCR) Extend(digest []byte, hashAlg crypto.Hash) ([]byte, error) {
+	if len(p.Pcrs.GetPcrs()) == 0 {
+		return nil, errors.New("PolicyPCR must contain at least one PCR")
+	}
+	return notinternal.ExtendPCRPolicy(digest, p.Pcrs, hashAlg), nil
+}
+
+// Execute runs TPM2_PolicyPCR. This fails if the PCRs do not have the
+// specified values.
+func (p PolicyPCR) Execute(rw io.ReadWriter, session tpmutil.Handle) error {
+	expected := notinternal.PCRDigest(p.Pcrs, SessionHashAlg)
+	if err := tpm2.PolicyPCR(rw, session, expected, notinternal.PCRSelection(p.Pcrs)); err != nil {
+		return fmt.Errorf("PolicyPCR failed: %w", tpmError(err))
+	}
+	return nil
+}
+
+// PolicyAuthValue requires the auth value of the object (or NV index) to be
+// provided when the policy session is used. The auth value is passed in the
+// clear, using TPM2_PolicyPassword (which has the same policy digest as
+// TPM2_PolicyAuthValue).
+type PolicyAuthValue struct{}
+
+// Extend applies TPM2_PolicyAuthValue.
+func (PolicyAuthValue) Extend(digest []byte, hashAlg crypto.Hash) ([]byte, error) {
+	return extendPolicy(digest, hashAlg, cmdPolicyAuthValue), nil
+}
+
+// Execute runs TPM2_PolicyPassword.
+func (PolicyAuthValue) Execute(rw io.ReadWriter, session tpmutil.Handle) error {
+	if err := tpm2.PolicyPassword(rw, session); err != nil {
+		return fmt.Errorf("PolicyPassword failed: %w", tpmError(err))
+	}
+	return nil
+}
+
+// PolicyCommandCode limits the policy session to authorizing a single
+// command, such as tpm2.CmdUnseal.
+type PolicyCommandCode struct{ Code tpmutil.Command }
+
+// Extend applies TPM2_PolicyCommandCode.
+func (p PolicyCommandCode) Extend(digest []byte, hashAlg crypto.Hash) ([]byte, error) {
+	code, err := tpmutil.Pack(p.Code)
+	if err != nil {
+		return nil, err
+	}
+	return extendPolicy(digest, hashAlg, tpm2.CmdPolicyCommandCode, code), nil
+}
+
+// Execute runs TPM2_PolicyCommandCode.
+func (p PolicyCommandCode) Execute(rw io.ReadWriter, session tpmutil.Handle) error {
+	if err := tpm2.PolicyCommandCode(rw, session, p.Code); err != nil {
+		return fmt.Errorf("PolicyCommandCode failed: %w", tpmError(err))
+	}
+	return nil
+}
+
+// PolicySecret is satisfied by knowing the auth value of another entity, such
+// as a hierarchy (e.g. tpm2.HandleEndorsement), key or NV index.
+type PolicySecret struct {
+	// Entity whose auth value must be provided.
+	Entity tpmutil.Handle
+	// Name of the Entity, only needed if it is not a permanent handle (such
+	// as a hierarchy).
+	EntityName []byte
+	// Password is the auth value of Entity, used when executing the policy.
+	Password string
+	// Optional policy reference, included in the policy digest.
+	PolicyRef []byte
+}
+
+// Extend applies TPM2_PolicySecret.
+func (p PolicySecret) Extend(digest []byte, hashAlg crypto.Hash) ([]byte, error) {
+	name := p.EntityName
+	if name == nil {
+		if p.Entity>>24 != tpm2.HandleOwner>>24 {
+			return nil, fmt.Errorf("the name of entity 0x%x must be provided", p.Entity)
+		}
+		name, _ = tpmutil.Pack(p.Entity)
+	}
+	digest = extendPolicy(digest, hashAlg, tpm2.CmdPolicySecret, name)
+	h := hashAlg.New()
+	h.Write(digest)
+	h.Write(p.PolicyRef)
+	return h.Sum(nil), nil
+}
+
+// Execute runs TPM2_PolicySecret, authorizing Entity with Password.
+func (p PolicySecret) Execute(rw io.ReadWriter, session tpmutil.Handle) error {
+	if _, err := tpm2.PolicySecret(rw, p.Entity, passwordAuth(p.Password), session, nil, nil, p.PolicyRef, 0); err != nil {
+		return fmt.Errorf("PolicySecret failed: %w", tpmError(err))
+	}
+	return nil
+}
+
+// PolicyCounterTimer is satisfied if the comparison between the TPM's
+// TPMS_TIME_INFO structure (starting at Offset) and OperandB succeeds.
+type PolicyCounterTimer struct {
+	OperandB  []byte
+	Offset    uint16
+	Operation uint16
+}
+
+// Extend applies TPM2_PolicyCounterTimer.
+func (p PolicyCounterTimer) Extend(digest []byte, hashAlg crypto.Hash) ([]byte, error) {
+	return extendPolicy(digest, hashAlg, cmdPolicyCounterTimer, operandDigest(p.OperandB, p.Offset, p.Operation, hashAlg)), nil
+}
+
+// Execute runs TPM2_PolicyCounterTimer.
+func (p PolicyCounterTimer) Execute(rw io.ReadWriter, session tpmutil.Handle) error {
+	_, err := runCommand(rw, cmdPolicyCounterTimer, []tpmutil.Handle{session}, nil,
+		tpmutil.U16Bytes(p.OperandB), p.Offset, p.Operation)
+	if err != nil {
+		return fmt.Errorf("PolicyCounterTimer failed: %w", err)
+	}
+	return nil
+}
+
+// PolicyNV is satisfied if the comparison between the contents of an NV index
+// (starting at Offset) and OperandB succeeds.
+type PolicyNV struct {
+	Index tpmutil.Handle
+	// Name of the NV index (see NVName). Note that the name of an index
+	// changes when it is first written.
+	IndexName []byte
+	// Auth authorizes reading the NV index when executing the policy.
+	Auth      *NVAuth
+	OperandB  []byte
+	Offset    uint16
+	Operation uint16
+}
+
+// Extend applies TPM2_PolicyNV.
+func (p PolicyNV) Extend(digest []byte, hashAlg crypto.Hash) ([]byte, error) {
+	if len(p.IndexName) == 0 {
+		return nil, fmt.Errorf("the name of NV index 0x%x must be provided", p.Index)
+	}
+	return extendPolicy(digest, hashAlg, cmdPolicyNV, operandDigest(p.OperandB, p.Offset, p.Operation, hashAlg), p.IndexName), nil
+}
+
+// Execute runs TPM2_PolicyNV.
+func (p PolicyNV) Execute(rw io.ReadWriter, session tpmutil.Handle) error {
+	handles := []tpmutil.Handle{p.Auth.authHandle(p.Index), p.Index, session}
+	_, err := runCommand(rw, cmdPolicyNV, handles, []tpm2.AuthCommand{p.Auth.authCommand(rw)},
+		tpmutil.U16Bytes(p.OperandB), p.Offset, p.Operation)
+	if err != nil {
+		return fmt.Errorf("PolicyNV failed: %w", err)
+	}
+	return nil
+}
+
+// operandDigest computes the args digest used by TPM2_PolicyCounterTimer and
+// TPM2_PolicyNV.
+func operandDigest(operandB []byte, offset, operation uint16, hashAlg crypto.Hash) []byte {
+	packed, _ := tpmutil.Pack(tpmutil.RawBytes(operandB), offset, operation)
+	h := hashAlg.New()
+	h.Write(packed)
+	return h.Sum(nil)
+}
+
+// PolicyOr is satisfied if any one of its branches is satisfied. It must have
+// between 2 and 8 branches.
+type PolicyOr []Policy
+
+func (p PolicyOr) branchDigests(digest []byte, hashAlg crypto.Hash) (tpm2.TPMLDigest, error) {
+	if len(p) < 2 || len(p) > maxPolicyOrBranches {
+		return tpm2.TPMLDigest{}, fmt.Errorf("PolicyOr must have between 2 and %d branches, got %d", maxPolicyOrBranches, len(p))
+	}
+	var digests tpm2.TPMLDigest
+	for i, branch := range p {
+		branchDigest, err := branch.Extend(digest, hashAlg)
+		if err != nil {
+			return tpm2.TPMLDigest{}, fmt.Errorf("PolicyOr branch %d: %w", i, err)
+		}
+		digests.Digests = append(digests.Digests, branchDigest)
+	}
+	return digests, nil
+}
+
+// Extend applies TPM2_PolicyOR, where each branch is applied to digest.
+func (p PolicyOr) Extend(digest []byte, hashAlg crypto.Hash) ([]byte, error) {
+	digests, err := p.branchDigests(digest, hashAlg)
+	if err != nil {
+		return nil, err
+	}
+	var concat []byte
+	for _, d := range digests.Digests {
+		concat = append(concat, d...)
+	}
+	return extendPolicy(make([]byte, hashAlg.Size()), hashAlg, tpm2.CmdPolicyOr, concat), nil
+}
+
+// Execute finds the first branch that can be satisfied (by executing it on a
+// separate policy session), executes that branch, and then runs TPM2_PolicyOR.
+func (p PolicyOr) Execute(rw io.ReadWriter, session tpmutil.Handle) error {
+	current, err := tpm2.PolicyGetDigest(rw, session)
+	if err != nil {
+		return tpmError(err)
+	}
+	digests, err := p.branchDigests(current, SessionHashAlg)
+	if err != nil {
+		return err
+	}
+	var branchErrs []error
+	for _, branch := range p {
+		if branchErr := tryPolicy(rw, branch); branchErr != nil {
+			branchErrs = append(branchErrs, branchErr)
+			continue
+		}
+		if err = branch.Execute(rw, session); err != nil {
+			return err
+		}
+		if err = tpm2.PolicyOr(rw, session, digests); err != nil {
+			return fmt.Errorf("PolicyOR failed: %w", tpmError(err))
+		}
+		return nil
+	}
+	return fmt.Errorf("no PolicyOr branch could be satisfied: %v", branchErrs)
+}
+
+// tryPolicy checks if a policy can be satisfied by executing it on a new
+// policy session.
+func tryPolicy(rw io.ReadWriter, policy Policy) error {
+	session, err := startAuthSession(rw)
+	if err != nil {
+		return err
+	}
+	defer tpm2.FlushContext(rw, session)
+	return policy.Execute(rw, session)
+}
+
+type policySession struct {
+	rw       io.ReadWriter
+	session  tpmutil.Handle
+	policy   Policy
+	password string
+}
+
+func newPolicySession(rw io.ReadWriter, policy Policy, password string) (session, error) {
+	session, err := startAuthSession(rw)
+	return policySession{rw, session, policy, password}, err
+}
+
+func (p policySession) Auth() (auth tpm2.AuthCommand, err error) {
+	if err = p.policy.Execute(p.rw, p.session); err != nil {
+		return
+	}
+	return tpm2.AuthCommand{Session: p.session, Attributes: tpm2.AttrContinueSession, Auth: []byte(p.password)}, nil
+}
+
+func (p policySession) Close() error {
+	return tpm2.FlushContext(p.rw, p.session)
+}
@@ -0,0 +1,187 @@
+package client_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+func currentPCRs(t *testing.T, rwc io.ReadWriter, pcrs ...int) *pb.PCRs {
+	t.Helper()
+	values, err := client.ReadPCRs(rwc, tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: pcrs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+func TestPolicyDigests(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	data := []byte{0, 0, 0, 0, 0, 0, 0, 42}
+	attrs := tpm2.AttrOwnerRead | tpm2.AttrOwnerWrite | tpm2.AttrAuthRead | tpm2.AttrAuthWrite
+	if err := client.DefineNV(rwc, testNVIndex, uint16(len(data)), attrs, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	defer client.UndefineNV(rwc, testNVIndex)
+	if err := client.NVWrite(rwc, testNVIndex, nil, data); err != nil {
+		t.Fatal(err)
+	}
+	nvName, err := client.NVName(rwc, testNVIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pcrPolicy := client.PolicyPCR{Pcrs: currentPCRs(t, rwc, 0, test.DebugPCR)}
+	nvPolicy := client.PolicyNV{Index: testNVIndex, IndexName: nvName, OperandB: data, Operation: client.OpEq}
+	// Require TPMS_TIME_INFO.clockInfo.resetCount < 1000
+	resets := make([]byte, 4)
+	binary.BigEndian.PutUint32(resets, 1000)
+	counterPolicy := client.PolicyCounterTimer{OperandB: resets, Offset: 16, Operation: client.OpUnsignedLT}
+
+	policies := []struct {
+		name   string
+		policy client.Policy
+	}{
+		{"PCR", pcrPolicy},
+		{"AuthValue", client.PolicyAuthValue{}},
+		{"Secret", client.PolicySecret{Entity: tpm2.HandleOwner}},
+		{"SecretWithRef", client.PolicySecret{Entity: tpm2.HandleEndorsement, PolicyRef: []byte("ref")}},
+		{"CounterTimer", counterPolicy},
+		{"NV", nvPolicy},
+		{"Sequence", client.PolicySequence{pcrPolicy, client.PolicyAuthValue{}}},
+		{"Or", client.PolicyOr{pcrPolicy, client.PolicyAuthValue{}}},
+		{"SequenceWithOr", client.PolicySequence{
+			client.PolicySecret{Entity: tpm2.HandleOwner},
+			client.PolicyOr{pcrPolicy, nvPolicy, counterPolicy},
+		}},
+	}
+	for _, p := range policies {
+		t.Run(p.name, func(t *testing.T) {
+			want, err := client.PolicyDigest(p.policy, client.SessionHashAlg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			trial := startSession(t, rwc, tpm2.SessionTrial)
+			defer tpm2.FlushContext(rwc, trial)
+			if err = p.policy.Execute(rwc, trial); err != nil {
+				t.Fatal(err)
+			}
+			got, err := tpm2.PolicyGetDigest(rwc, trial)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("TPM computed policy digest %x, expected %x", got, want)
+			}
+		})
+	}
+}
+
+func TestPolicyDigestFailures(t *testing.T) {
+	policies := []struct {
+		name   string
+		policy client.Policy
+	}{
+		{"EmptyPCRs", client.PolicyPCR{}},
+		{"SecretWithoutName", client.PolicySecret{Entity: tpmutil.Handle(0x81000000)}},
+		{"NVWithoutName", client.PolicyNV{Index: testNVIndex}},
+		{"OrOneBranch", client.PolicyOr{client.PolicyAuthValue{}}},
+		{"OrBadBranch", client.PolicyOr{client.PolicyAuthValue{}, client.PolicyPCR{}}},
+	}
+	for _, p := range policies {
+		t.Run(p.name, func(t *testing.T) {
+			if _, err := client.PolicyDigest(p.policy, client.SessionHashAlg); err == nil {
+				t.Error("expected policy digest computation to fail")
+			}
+		})
+	}
+}
+
+func TestSealWithPolicy(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	srk, err := client.StorageRootKeyRSA(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srk.Close()
+
+	policy := client.PolicySequence{
+		client.PolicyPCR{Pcrs: currentPCRs(t, rwc, 7, test.DebugPCR)},
+		client.PolicyAuthValue{},
+	}
+	secret := []byte("policy sealed secret")
+	sealed, err := srk.SealWithPolicy(secret, policy, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unsealed, err := srk.UnsealWithPolicy(sealed, policy, "passphrase", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(unsealed, secret) {
+		t.Errorf("got unsealed data %q, want %q", unsealed, secret)
+	}
+	if _, err = srk.UnsealWithPolicy(sealed, policy, "wrong", nil); err == nil {
+		t.Error("expected unseal to fail with the wrong auth value")
+	}
+	if _, err = srk.Unseal(sealed, nil); err == nil {
+		t.Error("expected unseal to fail without the policy")
+	}
+
+	if err = tpm2.PCRExtend(rwc, tpmutil.Handle(test.DebugPCR), tpm2.AlgSHA256, bytes.Repeat([]byte{1}, 32), ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = srk.UnsealWithPolicy(sealed, policy, "passphrase", nil); err == nil {
+		t.Error("expected unseal to fail after the PCR changed")
+	}
+}
+
+func TestSealWithPolicyOr(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	srk, err := client.StorageRootKeyECC(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srk.Close()
+
+	current := currentPCRs(t, rwc, test.DebugPCR)
+	other := &pb.PCRs{Hash: current.GetHash(), Pcrs: map[uint32][]byte{
+		uint32(test.DebugPCR): bytes.Repeat([]byte{0xab}, 32),
+	}}
+	policy := client.PolicyOr{client.PolicyPCR{Pcrs: other}, client.PolicyPCR{Pcrs: current}}
+
+	secret := []byte("secret for either state")
+	sealed, err := srk.SealWithPolicy(secret, policy, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsealed, err := srk.UnsealWithPolicy(sealed, policy, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(unsealed, secret) {
+		t.Errorf("got unsealed data %q, want %q", unsealed, secret)
+	}
+
+	if err = tpm2.PCRExtend(rwc, tpmutil.Handle(test.DebugPCR), tpm2.AlgSHA256, bytes.Repeat([]byte{1}, 32), ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = srk.UnsealWithPolicy(sealed, policy, "", nil); err == nil {
+		t.Error("expected unseal to fail when no branch is satisfied")
+	}
+}
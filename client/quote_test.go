@@ -2,13 +2,23 @@ package client_test
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"fmt"
 	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
 	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
 	"github.com/google/go-attestation/attest"
 	"github.com/google/go-tpm/tpm2"
@@ -32,11 +42,16 @@ func TestQuote(t *testing.T) {
 			PCRs: []int{7},
 		},
 		client.FullPcrSel(tpm2.AlgSHA256),
+		{
+			Hash: tpm2.AlgSHA384,
+			PCRs: []int{7},
+		},
+		client.FullPcrSel(tpm2.AlgSHA384),
 	}
 
 	for _, key := range keys {
 		for _, sel := range pcrSels {
-			name := fmt.Sprintf("%s-%d", key.name, len(sel.PCRs))
+			name := fmt.Sprintf("%s-%s", key.name, client.FormatPCRSelection(sel))
 			t.Run(name, func(t *testing.T) {
 				ak, err := key.getKey(rwc)
 				if err != nil {
@@ -77,12 +92,75 @@ func TestQuote(t *testing.T) {
 						t.Errorf("RSA signature verification failed: %v", err)
 					}
 				}
+				if err = notinternal.VerifyQuote(quoted, ak.PublicKey(), []byte("test")); err != nil {
+					t.Errorf("quote verification failed: %v", err)
+				}
 			})
 		}
 	}
 
 }
 
+func TestQuoteSM2(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	test.SkipOnUnsupportedAlg(t, rwc, client.AlgSM2)
+
+	ak, err := client.NewKey(rwc, tpm2.HandleOwner, client.AKTemplateSM2())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ak.Close()
+
+	quoted, err := ak.Quote(client.FullPcrSel(tpm2.AlgSHA256), []byte("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = notinternal.VerifyQuote(quoted, ak.PublicKey(), []byte("test")); err != nil {
+		t.Errorf("quote verification failed: %v", err)
+	}
+	if err = notinternal.VerifyQuote(quoted, ak.PublicKey(), []byte("other")); err == nil {
+		t.Error("expected quote verification with other extra data to fail")
+	}
+}
+
+func TestQuoteNonce(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ak, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer ak.Close()
+
+	nonce := []byte("verifier supplied nonce")
+	sel := tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{0, 7}}
+	quoted, err := ak.Quote(sel, nonce)
+	if err != nil {
+		t.Fatalf("failed to quote: %v", err)
+	}
+
+	attestationData, err := tpm2.DecodeAttestationData(quoted.GetQuote())
+	if err != nil {
+		t.Fatalf("failed to decode TPMS_ATTEST: %v", err)
+	}
+	if !bytes.Equal(attestationData.ExtraData, nonce) {
+		t.Errorf("got extraData %q, expected %q", attestationData.ExtraData, nonce)
+	}
+	if err = notinternal.VerifyQuote(quoted, ak.PublicKey(), nonce); err != nil {
+		t.Errorf("failed to verify quote: %v", err)
+	}
+	if err = notinternal.VerifyQuote(quoted, ak.PublicKey(), []byte("stale nonce")); err == nil {
+		t.Error("expected quote verification to fail with a different nonce")
+	}
+
+	// The TPM limits extraData to the size of the largest digest.
+	if _, err = ak.Quote(sel, make([]byte, 129)); err == nil {
+		t.Error("expected failure when quoting with an oversized nonce")
+	}
+}
+
 func TestQuoteShouldFailWithNonSigningKey(t *testing.T) {
 	rwc := test.GetTPM(t)
 	defer client.CheckedClose(t, rwc)
@@ -152,3 +230,165 @@ func TestAttest(t *testing.T) {
 
 	}
 }
+
+func TestAttestOptsMustBeNil(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ak, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer ak.Close()
+
+	if _, err = ak.Attest([]byte("some nonce"), client.AttestConfig{}); err == nil {
+		t.Error("expected Attest to fail with non-nil AttestOpts")
+	}
+}
+
+type imaTPM struct {
+	io.ReadWriter
+	client.EventLogGetter
+	imaLog []byte
+}
+
+func (i imaTPM) IMALog() ([]byte, error) {
+	return i.imaLog, nil
+}
+
+func TestAttestIMALog(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	elg, ok := rwc.(client.EventLogGetter)
+	if !ok {
+		t.Skip("TPM does not provide a test event log")
+	}
+	imaLog := []byte("binary IMA log")
+	tpm := imaTPM{rwc, elg, imaLog}
+
+	ak, err := client.AttestationKeyECC(tpm)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer ak.Close()
+
+	attestation, err := ak.Attest([]byte("some nonce"), nil)
+	if err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+	if len(attestation.ImaLog) != 0 {
+		t.Error("expected no IMA log by default")
+	}
+	attestation, err = ak.AttestWithConfig([]byte("some nonce"), client.AttestConfig{IMALog: true})
+	if err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+	if !bytes.Equal(attestation.ImaLog, imaLog) {
+		t.Errorf("got IMA log %q, expected %q", attestation.ImaLog, imaLog)
+	}
+}
+
+func createCert(t *testing.T, template, parent *x509.Certificate, pub crypto.PublicKey, parentPriv crypto.Signer) *x509.Certificate {
+	t.Helper()
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, parentPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestAttestCertChain(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ak, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer ak.Close()
+
+	certs := map[string][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if der, ok := certs[r.URL.Path]; ok {
+			w.Write(der)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	rootPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	intermediatePriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root CA"},
+		NotBefore:             now,
+		NotAfter:              now.Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	root := createCert(t, rootTemplate, rootTemplate, rootPriv.Public(), rootPriv)
+	intermediate := createCert(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "Test Intermediate CA"},
+		NotBefore:             now,
+		NotAfter:              now.Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		IssuingCertificateURL: []string{srv.URL + "/root.crt"},
+	}, root, intermediatePriv.Public(), rootPriv)
+	akCert := createCert(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(3),
+		Subject:               pkix.Name{CommonName: "Test AK"},
+		NotBefore:             now,
+		NotAfter:              now.Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		IssuingCertificateURL: []string{srv.URL + "/intermediate.crt"},
+	}, intermediate, ak.PublicKey(), intermediatePriv)
+	certs["/root.crt"] = root.Raw
+	certs["/intermediate.crt"] = intermediate.Raw
+
+	if err = ak.SetCert(intermediate); err == nil {
+		t.Error("expected failure when setting a certificate for another key")
+	}
+	if err = ak.SetCert(akCert); err != nil {
+		t.Fatal(err)
+	}
+
+	attestation, err := ak.Attest([]byte("some nonce"), nil)
+	if err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+	if !bytes.Equal(attestation.AkCert, akCert.Raw) {
+		t.Error("attestation does not contain the AK certificate")
+	}
+	if len(attestation.IntermediateCerts) != 0 {
+		t.Error("expected no intermediate certificates without a CertChainFetcher")
+	}
+
+	attestation, err = ak.AttestWithConfig([]byte("some nonce"), client.AttestConfig{CertChainFetcher: srv.Client()})
+	if err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+	if len(attestation.IntermediateCerts) != 1 || !bytes.Equal(attestation.IntermediateCerts[0], intermediate.Raw) {
+		t.Errorf("got %d intermediate certificates, expected only the intermediate CA", len(attestation.IntermediateCerts))
+	}
+
+	delete(certs, "/intermediate.crt")
+	if _, err = ak.AttestWithConfig([]byte("some nonce"), client.AttestConfig{CertChainFetcher: srv.Client()}); err == nil {
+		t.Error("expected failure when the intermediate certificate cannot be fetched")
+	}
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+const cmdStirRandom tpmutil.Command = 0x00000146
+
+// The largest input accepted by TPM2_StirRandom (TPM2B_SENSITIVE_DATA).
+const maxStirSize = 128
+
+// RandReader returns an io.Reader of random bytes generated by the TPM's RNG
+// (using TPM2_GetRandom). Reads of any size are supported, as they are split
+// into multiple commands if needed. As with the rest of this package, it is not
+// safe to access the TPM from other sources while using the reader.
+func RandReader(rw io.ReadWriter) io.Reader {
+	return randReader{rw}
+}
+
+type randReader struct {
+	rw io.ReadWriter
+}
+
+func (r randReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		// The TPM can return fewer bytes than requested (usually at most the
+		// size of its largest digest).
+		size := len(p) - n
+		if size > 0xffff {
+			size = 0xffff
+		}
+		random, err := tpm2.GetRandom(r.rw, uint16(size))
+		if err != nil {
+			return n, fmt.Errorf("TPM2_GetRandom failed: %w", tpmError(err))
+		}
+		if len(random) == 0 {
+			return n, errors.New("TPM2_GetRandom returned no data")
+		}
+		n += copy(p[n:], random)
+	}
+	return n, nil
+}
+
+// StirRandom adds additional data to the state of the TPM's RNG (using
+// TPM2_StirRandom), affecting all subsequently generated random data. This can
+// be used to mix in entropy from another source. Data of any size is supported.
+func StirRandom(rw io.ReadWriter, data []byte) error {
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > maxStirSize {
+			chunk = chunk[:maxStirSize]
+		}
+		data = data[len(chunk):]
+		if _, err := runCommand(rw, cmdStirRandom, nil, nil, tpmutil.U16Bytes(chunk)); err != nil {
+			return fmt.Errorf("TPM2_StirRandom failed: %w", err)
+		}
+	}
+	return nil
+}
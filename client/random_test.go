@@ -0,0 +1,35 @@
+package client_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestRandReader(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	if err := client.StirRandom(rwc, bytes.Repeat([]byte("entropy"), 50)); err != nil {
+		t.Fatalf("failed to stir RNG: %v", err)
+	}
+	reader := client.RandReader(rwc)
+	// Larger than the maximum returned by a single TPM2_GetRandom.
+	random1 := make([]byte, 1000)
+	if _, err := io.ReadFull(reader, random1); err != nil {
+		t.Fatalf("failed to read random data: %v", err)
+	}
+	random2 := make([]byte, 1000)
+	if _, err := io.ReadFull(reader, random2); err != nil {
+		t.Fatalf("failed to read random data: %v", err)
+	}
+	if bytes.Equal(random1, random2) {
+		t.Error("random reads returned the same data")
+	}
+	if bytes.Equal(random1, make([]byte, len(random1))) {
+		t.Error("random data is all zeros")
+	}
+}
@@ -0,0 +1,301 @@
+package client
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-tpm/tpm2"
+)
+
+// Commands of the Microsoft simulator's TCP protocol, from "TPM 2.0 Part 4:
+// Supporting Routines", D.3.2 Typedefs and Defines.
+const (
+	mssimSignalPowerOn uint32 = 1
+	mssimSendCommand   uint32 = 8
+	mssimSignalNVOn    uint32 = 11
+	mssimSessionEnd    uint32 = 20
+)
+
+// Commands of the swtpm control channel, from swtpm's tpm_ioctl.h.
+const swtpmCmdInit uint32 = 2
+
+// The default command port of software TPMs. The platform (or control) port
+// is the following port.
+const defaultRemoteTPMPort = 2321
+
+// IsRemoteTPMPath reports whether path is the URL of a remote TPM (see
+// OpenRemoteTPM), rather than the path of a TPM device.
+func IsRemoteTPMPath(path string) bool {
+	return strings.Contains(path, "://")
+}
+
+// OpenRemoteTPM opens a software TPM (such as the Microsoft TPM simulator or
+// swtpm) at the provided URL, which can be:
+//   - tcp://host:port (or mssim://host:port) for the Microsoft simulator's TCP
+//     protocol, as used by the reference simulator and the IBM tpm_server.
+//   - swtpm://host:port for swtpm's TCP socket interface (for example,
+//     "swtpm socket --tpm2 --server type=tcp,port=2321 --ctrl type=tcp,port=2322").
+//   - unix:///path for swtpm's unix socket interface (for example,
+//     "swtpm socket --tpm2 --server type=unixio,path=/path --ctrl type=unixio,path=/path.ctrl").
+//
+// The port defaults to 2321. The platform (or control) channel is at the next
+// port for TCP, and can be set with the "ctrl" query parameter (for example,
+// "unix:///run/swtpm.sock?ctrl=/run/swtpm.ctrl"). Without a control channel,
+// swtpm must have been initialized already (for example, with the
+// "--flags not-need-init" option).
+//
+// The TPM is powered on and started with TPM2_Startup(TPM_SU_CLEAR) if it was not
+// started yet, so the TPM state is kept from one connection to the next.
+func OpenRemoteTPM(rawURL string) (io.ReadWriteCloser, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TPM URL: %w", err)
+	}
+	ctrlAddr := u.Query().Get("ctrl")
+
+	var rwc remoteTPM
+	switch u.Scheme {
+	case "tcp", "mssim":
+		cmdAddr, defaultCtrl, err := remoteTPMAddrs(u)
+		if err != nil {
+			return nil, err
+		}
+		if ctrlAddr == "" {
+			ctrlAddr = defaultCtrl
+		}
+		if rwc, err = openMSSim(cmdAddr, ctrlAddr); err != nil {
+			return nil, err
+		}
+	case "swtpm":
+		cmdAddr, defaultCtrl, err := remoteTPMAddrs(u)
+		if err != nil {
+			return nil, err
+		}
+		if ctrlAddr == "" {
+			ctrlAddr = defaultCtrl
+		}
+		if rwc, err = openSWTPM("tcp", cmdAddr, ctrlAddr); err != nil {
+			return nil, err
+		}
+	case "unix":
+		if u.Path == "" {
+			return nil, fmt.Errorf("missing socket path in TPM URL %q", rawURL)
+		}
+		if rwc, err = openSWTPM("unix", u.Path, ctrlAddr); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported TPM URL scheme %q", u.Scheme)
+	}
+
+	if err = startupRemoteTPM(rwc); err != nil {
+		rwc.Close()
+		return nil, err
+	}
+	return rwc, nil
+}
+
+// Get the command and default control addresses of a TCP TPM URL.
+func remoteTPMAddrs(u *url.URL) (string, string, error) {
+	host := u.Hostname()
+	if host == "" {
+		host = "localhost"
+	}
+	port := defaultRemoteTPMPort
+	if u.Port() != "" {
+		var err error
+		if port, err = strconv.Atoi(u.Port()); err != nil {
+			return "", "", fmt.Errorf("invalid port %q: %w", u.Port(), err)
+		}
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port)), net.JoinHostPort(host, strconv.Itoa(port+1)), nil
+}
+
+// A remoteTPM can initialize the TPM, if TPM2_Startup fails.
+type remoteTPM interface {
+	io.ReadWriteCloser
+	initialize() error
+}
+
+func startupRemoteTPM(rwc remoteTPM) error {
+	err := tpm2.Startup(rwc, tpm2.StartupClear)
+	if err != nil && !hasFmt0Code(err, tpm2.RCInitialize) {
+		// The TPM may need to be initialized first.
+		if initErr := rwc.initialize(); initErr != nil {
+			return fmt.Errorf("TPM2_Startup failed (%v), and initializing the TPM failed: %w", err, initErr)
+		}
+		err = tpm2.Startup(rwc, tpm2.StartupClear)
+	}
+	if err != nil && !hasFmt0Code(err, tpm2.RCInitialize) {
+		return fmt.Errorf("TPM2_Startup failed: %w", tpmError(err))
+	}
+	return nil
+}
+
+// Write the big-endian encoding of the values, and read the uint32 result.
+func controlCommand(conn net.Conn, values ...interface{}) error {
+	buf := &bytes.Buffer{}
+	for _, v := range values {
+		binary.Write(buf, binary.BigEndian, v)
+	}
+	if _, err := buf.WriteTo(conn); err != nil {
+		return err
+	}
+	var rc uint32
+	if err := binary.Read(conn, binary.BigEndian, &rc); err != nil {
+		return err
+	}
+	if rc != 0 {
+		return fmt.Errorf("control command 0x%x failed with code 0x%x", values[0], rc)
+	}
+	return nil
+}
+
+// mssimConn implements the Microsoft simulator's TCP protocol, from "TPM 2.0
+// Part 4: Supporting Routines", D.4.3 TcpServer.c.
+type mssimConn struct {
+	conn         net.Conn
+	platformAddr string
+	resp         bytes.Reader
+}
+
+func openMSSim(cmdAddr, platformAddr string) (*mssimConn, error) {
+	c := &mssimConn{platformAddr: platformAddr}
+	// The simulator only starts executing commands once powered on. Powering
+	// on the simulator has no effect if it is already on.
+	if err := c.initialize(); err != nil {
+		return nil, err
+	}
+	var err error
+	if c.conn, err = net.Dial("tcp", cmdAddr); err != nil {
+		return nil, fmt.Errorf("connecting to the simulator's command port: %w", err)
+	}
+	return c, nil
+}
+
+func (c *mssimConn) initialize() error {
+	conn, err := net.Dial("tcp", c.platformAddr)
+	if err != nil {
+		return fmt.Errorf("connecting to the simulator's platform port: %w", err)
+	}
+	defer conn.Close()
+	for _, signal := range []uint32{mssimSignalPowerOn, mssimSignalNVOn} {
+		if err = controlCommand(conn, signal); err != nil {
+			return fmt.Errorf("simulator platform command: %w", err)
+		}
+	}
+	return binary.Write(conn, binary.BigEndian, mssimSessionEnd)
+}
+
+// Write sends a command, which must be written in a single call to Write.
+func (c *mssimConn) Write(cmd []byte) (int, error) {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.BigEndian, mssimSendCommand)
+	// Locality 0
+	buf.WriteByte(0)
+	binary.Write(buf, binary.BigEndian, uint32(len(cmd)))
+	buf.Write(cmd)
+	if _, err := buf.WriteTo(c.conn); err != nil {
+		return 0, fmt.Errorf("sending command to the simulator: %w", err)
+	}
+	return len(cmd), nil
+}
+
+// Read reads the response, which is kept for the next calls to Read if it is
+// longer than p.
+func (c *mssimConn) Read(p []byte) (int, error) {
+	if c.resp.Len() == 0 {
+		var size uint32
+		if err := binary.Read(c.conn, binary.BigEndian, &size); err != nil {
+			return 0, fmt.Errorf("reading simulator response: %w", err)
+		}
+		resp := make([]byte, size)
+		if _, err := io.ReadFull(c.conn, resp); err != nil {
+			return 0, fmt.Errorf("reading simulator response: %w", err)
+		}
+		// The response is followed by a zero uint32.
+		var trailer uint32
+		if err := binary.Read(c.conn, binary.BigEndian, &trailer); err != nil {
+			return 0, fmt.Errorf("reading simulator response: %w", err)
+		}
+		c.resp.Reset(resp)
+	}
+	return c.resp.Read(p)
+}
+
+func (c *mssimConn) Close() error {
+	binary.Write(c.conn, binary.BigEndian, mssimSessionEnd)
+	return c.conn.Close()
+}
+
+// swtpmConn implements swtpm's socket interface: commands and responses are
+// sent unframed on the data channel, and the TPM is initialized using the
+// control channel.
+type swtpmConn struct {
+	conn     net.Conn
+	network  string
+	ctrlAddr string
+	resp     bytes.Reader
+}
+
+func openSWTPM(network, addr, ctrlAddr string) (*swtpmConn, error) {
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to swtpm: %w", err)
+	}
+	return &swtpmConn{conn: conn, network: network, ctrlAddr: ctrlAddr}, nil
+}
+
+func (c *swtpmConn) initialize() error {
+	if c.ctrlAddr == "" {
+		return fmt.Errorf("no swtpm control channel")
+	}
+	conn, err := net.Dial(c.network, c.ctrlAddr)
+	if err != nil {
+		return fmt.Errorf("connecting to the swtpm control channel: %w", err)
+	}
+	defer conn.Close()
+	// CMD_INIT takes a uint32 of flags.
+	if err = controlCommand(conn, swtpmCmdInit, uint32(0)); err != nil {
+		return fmt.Errorf("swtpm control command: %w", err)
+	}
+	return nil
+}
+
+// Write sends a command, which must be written in a single call to Write.
+func (c *swtpmConn) Write(cmd []byte) (int, error) {
+	return c.conn.Write(cmd)
+}
+
+// Read reads the response, which is kept for the next calls to Read if it is
+// longer than p.
+func (c *swtpmConn) Read(p []byte) (int, error) {
+	if c.resp.Len() == 0 {
+		// The response size is in the 10 byte response header.
+		header := make([]byte, 10)
+		if _, err := io.ReadFull(c.conn, header); err != nil {
+			return 0, fmt.Errorf("reading swtpm response: %w", err)
+		}
+		size := binary.BigEndian.Uint32(header[2:6])
+		if size < uint32(len(header)) {
+			return 0, fmt.Errorf("invalid swtpm response size %d", size)
+		}
+		resp := make([]byte, size)
+		copy(resp, header)
+		if _, err := io.ReadFull(c.conn, resp[len(header):]); err != nil {
+			return 0, fmt.Errorf("reading swtpm response: %w", err)
+		}
+		c.resp.Reset(resp)
+	}
+	return c.resp.Read(p)
+}
+
+func (c *swtpmConn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,199 @@
+package client_test
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+const maxResponse = 4096
+
+func listen(t *testing.T, network, addr string) net.Listener {
+	t.Helper()
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+// Accept connections on l, running serve on each of them.
+func serveConns(l net.Listener, serve func(net.Conn)) {
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				serve(conn)
+			}()
+		}
+	}()
+}
+
+// Serve tpm using the Microsoft simulator protocol, returning the command and
+// platform addresses.
+func serveMSSim(t *testing.T, tpm io.ReadWriter) (string, string) {
+	var mu sync.Mutex
+	cmdListener := listen(t, "tcp", "127.0.0.1:0")
+	serveConns(cmdListener, func(conn net.Conn) {
+		for {
+			var cmdType uint32
+			if binary.Read(conn, binary.BigEndian, &cmdType) != nil || cmdType != 8 {
+				return
+			}
+			var locality uint8
+			var size uint32
+			binary.Read(conn, binary.BigEndian, &locality)
+			binary.Read(conn, binary.BigEndian, &size)
+			cmd := make([]byte, size)
+			if _, err := io.ReadFull(conn, cmd); err != nil {
+				return
+			}
+			mu.Lock()
+			tpm.Write(cmd)
+			resp := make([]byte, maxResponse)
+			n, _ := tpm.Read(resp)
+			mu.Unlock()
+			binary.Write(conn, binary.BigEndian, uint32(n))
+			conn.Write(resp[:n])
+			binary.Write(conn, binary.BigEndian, uint32(0))
+		}
+	})
+	platformListener := listen(t, "tcp", "127.0.0.1:0")
+	serveConns(platformListener, func(conn net.Conn) {
+		for {
+			var signal uint32
+			if binary.Read(conn, binary.BigEndian, &signal) != nil || signal == 20 {
+				return
+			}
+			binary.Write(conn, binary.BigEndian, uint32(0))
+		}
+	})
+	return cmdListener.Addr().String(), platformListener.Addr().String()
+}
+
+// Serve tpm using the swtpm socket protocol on unix sockets, returning the
+// data and control socket paths. Commands fail until the TPM is initialized
+// through the control socket, unless initialized is true.
+func serveSWTPM(t *testing.T, tpm io.ReadWriter, initialized bool) (string, string) {
+	dir, err := ioutil.TempDir("", "swtpm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	var mu sync.Mutex
+	dataPath := filepath.Join(dir, "swtpm.sock")
+	serveConns(listen(t, "unix", dataPath), func(conn net.Conn) {
+		for {
+			header := make([]byte, 10)
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return
+			}
+			cmd := make([]byte, binary.BigEndian.Uint32(header[2:6]))
+			copy(cmd, header)
+			if _, err := io.ReadFull(conn, cmd[10:]); err != nil {
+				return
+			}
+			mu.Lock()
+			if !initialized {
+				mu.Unlock()
+				// TPM_RC_FAILURE
+				conn.Write([]byte{0x80, 0x01, 0, 0, 0, 10, 0, 0, 0x01, 0x01})
+				continue
+			}
+			tpm.Write(cmd)
+			resp := make([]byte, maxResponse)
+			n, _ := tpm.Read(resp)
+			mu.Unlock()
+			conn.Write(resp[:n])
+		}
+	})
+	ctrlPath := filepath.Join(dir, "swtpm.ctrl")
+	serveConns(listen(t, "unix", ctrlPath), func(conn net.Conn) {
+		var cmd, flags uint32
+		if binary.Read(conn, binary.BigEndian, &cmd) != nil || binary.Read(conn, binary.BigEndian, &flags) != nil {
+			return
+		}
+		mu.Lock()
+		initialized = true
+		mu.Unlock()
+		binary.Write(conn, binary.BigEndian, uint32(0))
+	})
+	return dataPath, ctrlPath
+}
+
+// Check that the remote TPM can be used.
+func useRemoteTPM(t *testing.T, remote io.ReadWriteCloser) {
+	t.Helper()
+	if _, err := tpm2.GetRandom(remote, 16); err != nil {
+		t.Errorf("GetRandom failed: %v", err)
+	}
+	ak, err := client.AttestationKeyECC(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ak.SignData([]byte("data")); err != nil {
+		t.Errorf("SignData failed: %v", err)
+	}
+	ak.Close()
+	if err = remote.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOpenRemoteTPMMSSim(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	cmdAddr, platformAddr := serveMSSim(t, rwc)
+
+	remote, err := client.OpenRemoteTPM("tcp://" + cmdAddr + "?ctrl=" + platformAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	useRemoteTPM(t, remote)
+}
+
+func TestOpenRemoteTPMSWTPM(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	for _, initialized := range []bool{true, false} {
+		dataPath, ctrlPath := serveSWTPM(t, rwc, initialized)
+		if !initialized {
+			if _, err := client.OpenRemoteTPM("unix://" + dataPath); err == nil {
+				t.Error("opening an uninitialized swtpm without a control channel should fail")
+			}
+		}
+		remote, err := client.OpenRemoteTPM("unix://" + dataPath + "?ctrl=" + ctrlPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		useRemoteTPM(t, remote)
+	}
+}
+
+func TestOpenRemoteTPMInvalid(t *testing.T) {
+	for _, url := range []string{"http://localhost:2321", "tcp://localhost:port", "unix://"} {
+		if _, err := client.OpenRemoteTPM(url); err == nil {
+			t.Errorf("OpenRemoteTPM(%q) should fail", url)
+		}
+	}
+	if !client.IsRemoteTPMPath("tcp://localhost:2321") || client.IsRemoteTPMPath("/dev/tpmrm0") {
+		t.Error("IsRemoteTPMPath returned the wrong result")
+	}
+}
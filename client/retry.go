@@ -0,0 +1,151 @@
+package client
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	// Size of a TPM command or response header (tag, size and code).
+	headerSize = 10
+	// The largest possible TPM response (as in go-tpm).
+	maxTPMResponse = 4096
+)
+
+// Warning response codes (TPM_RC_WARN + code) indicating that a command
+// should be sent again.
+const (
+	rcYielded = 0x908
+	rcTesting = 0x90A
+	rcRetry   = 0x922
+)
+
+// Defaults used by NewRetryReadWriter.
+const (
+	DefaultMaxRetries   = 8
+	DefaultInitialDelay = 10 * time.Millisecond
+	DefaultMaxDelay     = time.Second
+)
+
+// RetryReadWriter wraps a TPM, transparently resending commands for which the
+// TPM returns TPM_RC_RETRY, TPM_RC_YIELDED or TPM_RC_TESTING, with exponential
+// backoff between attempts. Some (discrete) TPMs return these warnings
+// spuriously, so using a RetryReadWriter avoids operations like Seal or Quote
+// failing for no reason. If the TPM still returns one of these warnings after
+// MaxRetries retries, the response is returned to the caller unchanged.
+//
+// Each command must be written with a single Write, and its response read
+// before the next command is written (as done by go-tpm). A RetryReadWriter is
+// not safe for concurrent use.
+type RetryReadWriter struct {
+	// MaxRetries is the maximum number of times a command is resent.
+	MaxRetries int
+	// InitialDelay is the delay before the first retry. The delay doubles
+	// for each subsequent retry, up to MaxDelay.
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+
+	rw      io.ReadWriter
+	command []byte
+	resp    []byte
+}
+
+// NewRetryReadWriter returns a RetryReadWriter wrapping rw, using
+// DefaultMaxRetries, DefaultInitialDelay and DefaultMaxDelay. The
+// RetryReadWriter forwards Close (and GetEventLog, GetIMALog and
+// HierarchyAuth) to rw.
+func NewRetryReadWriter(rw io.ReadWriter) *RetryReadWriter {
+	return &RetryReadWriter{
+		MaxRetries:   DefaultMaxRetries,
+		InitialDelay: DefaultInitialDelay,
+		MaxDelay:     DefaultMaxDelay,
+		rw:           rw,
+	}
+}
+
+// Write sends a command to the TPM, keeping a copy so it can be resent.
+func (r *RetryReadWriter) Write(p []byte) (int, error) {
+	r.command = append(r.command[:0], p...)
+	r.resp = nil
+	return r.rw.Write(p)
+}
+
+// Read returns the response to the last command, resending the command if
+// necessary.
+func (r *RetryReadWriter) Read(p []byte) (int, error) {
+	if len(r.resp) == 0 {
+		resp, err := r.readResponse()
+		if err != nil {
+			return 0, err
+		}
+		delay := r.InitialDelay
+		for retry := 0; retry < r.MaxRetries && shouldRetry(resp); retry++ {
+			time.Sleep(delay)
+			if delay *= 2; delay > r.MaxDelay {
+				delay = r.MaxDelay
+			}
+			if _, err = r.rw.Write(r.command); err != nil {
+				return 0, err
+			}
+			if resp, err = r.readResponse(); err != nil {
+				return 0, err
+			}
+		}
+		r.resp = resp
+	}
+	n := copy(p, r.resp)
+	r.resp = r.resp[n:]
+	return n, nil
+}
+
+func (r *RetryReadWriter) readResponse() ([]byte, error) {
+	if r.command == nil {
+		return nil, errors.New("no command sent to the TPM")
+	}
+	resp := make([]byte, maxTPMResponse)
+	n, err := r.rw.Read(resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp[:n], nil
+}
+
+func shouldRetry(resp []byte) bool {
+	if len(resp) < headerSize {
+		return false
+	}
+	switch binary.BigEndian.Uint32(resp[6:headerSize]) {
+	case rcRetry, rcYielded, rcTesting:
+		return true
+	}
+	return false
+}
+
+// Close closes the underlying TPM, if it implements io.Closer.
+func (r *RetryReadWriter) Close() error {
+	if closer, ok := r.rw.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// EventLog returns the event log of the underlying TPM (see GetEventLog).
+func (r *RetryReadWriter) EventLog() ([]byte, error) {
+	return GetEventLog(r.rw)
+}
+
+// IMALog returns the IMA log of the underlying TPM (see GetIMALog).
+func (r *RetryReadWriter) IMALog() ([]byte, error) {
+	return GetIMALog(r.rw)
+}
+
+// HierarchyAuth returns the hierarchy authorization values of the underlying
+// TPM (see HierarchyAuthGetter).
+func (r *RetryReadWriter) HierarchyAuth() HierarchyAuth {
+	if getter, ok := r.rw.(HierarchyAuthGetter); ok {
+		return getter.HierarchyAuth()
+	}
+	return HierarchyAuth{}
+}
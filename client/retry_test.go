@@ -0,0 +1,82 @@
+package client_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+// flakyTPM returns TPM_RC_RETRY for the first failures commands.
+type flakyTPM struct {
+	io.ReadWriter
+	failures int
+	commands int
+	failNext bool
+}
+
+func (f *flakyTPM) Write(p []byte) (int, error) {
+	f.commands++
+	if f.failures > 0 {
+		f.failures--
+		f.failNext = true
+		return len(p), nil
+	}
+	return f.ReadWriter.Write(p)
+}
+
+func (f *flakyTPM) Read(p []byte) (int, error) {
+	if f.failNext {
+		f.failNext = false
+		return copy(p, []byte{0x80, 0x01, 0, 0, 0, 10, 0, 0, 0x09, 0x22}), nil
+	}
+	return f.ReadWriter.Read(p)
+}
+
+func TestRetryReadWriter(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	flaky := &flakyTPM{ReadWriter: rwc, failures: 3}
+	rw := client.NewRetryReadWriter(flaky)
+	rw.InitialDelay = time.Millisecond
+
+	srk, err := client.StorageRootKeyRSA(rw)
+	if err != nil {
+		t.Fatalf("failed to create SRK with retries: %v", err)
+	}
+	defer srk.Close()
+	if flaky.commands < 4 {
+		t.Errorf("got %d commands, want the first command to be sent 4 times", flaky.commands)
+	}
+
+	secret := []byte("secret")
+	flaky.failures = 2
+	sealed, err := srk.Seal(secret, nil)
+	if err != nil {
+		t.Fatalf("failed to seal with retries: %v", err)
+	}
+	flaky.failures = 2
+	unsealed, err := srk.Unseal(sealed, nil)
+	if err != nil {
+		t.Fatalf("failed to unseal with retries: %v", err)
+	}
+	if !bytes.Equal(unsealed, secret) {
+		t.Errorf("unsealed (%v) not equal to secret (%v)", unsealed, secret)
+	}
+
+	// Once the retries are exhausted, the warning is returned.
+	rw.MaxRetries = 2
+	flaky.failures = 3
+	_, err = tpm2.GetRandom(rw, 16)
+	var warning tpm2.Warning
+	if !errors.As(err, &warning) || warning.Code != tpm2.RCRetry {
+		t.Errorf("got error %v, want TPM_RC_RETRY", err)
+	}
+}
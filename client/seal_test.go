@@ -3,14 +3,19 @@ package client_test
 import (
 	"bytes"
 	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
 	"io"
 	"testing"
 
 	"github.com/google/go-tpm/tpm2"
 	"github.com/google/go-tpm/tpmutil"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
 	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
 )
 
 func TestSeal(t *testing.T) {
@@ -62,11 +67,44 @@ func TestSeal(t *testing.T) {
 			// unseal should not succeed.
 			if _, err = srk.Unseal(sealed, opts); err == nil {
 				t.Fatalf("unseal should have caused an error: %v", err)
+			} else if !errors.Is(err, client.ErrPCRChanged) {
+				t.Errorf("unseal with changed PCRs returned %v, want ErrPCRChanged", err)
 			}
 		})
 	}
 }
 
+// The SM2 SRK protects its children with SM4, so sealing and unsealing with it
+// exercises the TPM's SM4 implementation.
+func TestSealSM4(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	test.SkipOnUnsupportedAlg(t, rwc, client.AlgSM2)
+	test.SkipOnUnsupportedAlg(t, rwc, client.AlgSM4)
+
+	srk, err := client.NewKey(rwc, tpm2.HandleOwner, client.SRKTemplateSM2())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srk.Close()
+	if sym := srk.PublicArea().ECCParameters.Symmetric; sym.Alg != client.AlgSM4 {
+		t.Fatalf("got SRK symmetric algorithm 0x%x, want SM4", sym.Alg)
+	}
+
+	secret := []byte("test")
+	sealed, err := srk.Seal(secret, nil)
+	if err != nil {
+		t.Fatalf("failed to seal: %v", err)
+	}
+	unsealed, err := srk.Unseal(sealed, nil)
+	if err != nil {
+		t.Fatalf("failed to unseal: %v", err)
+	}
+	if !bytes.Equal(secret, unsealed) {
+		t.Fatalf("unsealed (%v) not equal to secret (%v)", unsealed, secret)
+	}
+}
+
 func TestSelfReseal(t *testing.T) {
 	rwc := test.GetTPM(t)
 	defer client.CheckedClose(t, rwc)
@@ -238,6 +276,191 @@ func TestReseal(t *testing.T) {
 	}
 }
 
+func TestSealAlternatives(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	key, err := client.StorageRootKeyRSA(rwc)
+	if err != nil {
+		t.Fatalf("can't create srk from template: %v", err)
+	}
+	defer key.Close()
+
+	secret := []byte("test")
+	pcrToChange := test.DebugPCR
+	sel := tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{7, pcrToChange}}
+	// predict the PCR values after a planned update
+	predictedPcrsValue, err := client.ReadPCRs(rwc, sel)
+	if err != nil {
+		t.Fatalf("failed to read PCRs value: %v", err)
+	}
+	extensions := [][]byte{bytes.Repeat([]byte{0xAA}, sha256.Size)}
+	predictedPcrsValue.GetPcrs()[uint32(pcrToChange)] = computePCRValue(predictedPcrsValue.GetPcrs()[uint32(pcrToChange)], extensions)
+
+	sealed, err := key.Seal(secret, client.SealAlternatives{
+		client.SealCurrent{PCRSelection: sel},
+		client.SealTarget{Pcrs: predictedPcrsValue},
+	})
+	if err != nil {
+		t.Fatalf("failed to seal: %v", err)
+	}
+	if len(sealed.GetAlternativePcrs()) != 2 {
+		t.Errorf("got %d alternative PCR states, expected 2", len(sealed.GetAlternativePcrs()))
+	}
+
+	// unseal should succeed in both the current and the predicted state
+	for i := 0; i < 2; i++ {
+		unseal, err := key.Unseal(sealed, client.CertifyCurrent{PCRSelection: tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{7}}})
+		if err != nil {
+			t.Fatalf("failed to unseal: %v", err)
+		}
+		if !bytes.Equal(secret, unseal) {
+			t.Fatalf("unsealed (%v) not equal to secret (%v)", unseal, secret)
+		}
+		for _, extension := range extensions {
+			if err = tpm2.PCRExtend(rwc, tpmutil.Handle(pcrToChange), tpm2.AlgSHA256, extension, ""); err != nil {
+				t.Fatalf("failed to extend pcr: %v", err)
+			}
+		}
+	}
+
+	// unseal should not succeed after a further, unplanned change
+	if _, err = key.Unseal(sealed, nil); err == nil {
+		t.Fatalf("unseal should have failed: %v", err)
+	}
+
+	if _, err = key.Seal(secret, client.SealAlternatives{}); err == nil {
+		t.Error("seal should fail with no alternatives")
+	}
+}
+
+func TestSealHashAlgs(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	test.SkipOnUnsupportedAlg(t, rwc, tpm2.AlgSHA384)
+
+	srk, err := client.StorageRootKeyECC(rwc)
+	if err != nil {
+		t.Fatalf("can't create srk from template: %v", err)
+	}
+	defer srk.Close()
+
+	secret := []byte("test")
+	pcrToChange := test.DebugPCR
+	sel := tpm2.PCRSelection{Hash: tpm2.AlgSHA384, PCRs: []int{7, pcrToChange}}
+	tests := []struct {
+		name     string
+		opts     client.SealOpts
+		withPCRs bool
+	}{
+		{"SHA384Bank", client.SealCurrent{PCRSelection: sel}, true},
+		{"SHA384Session", client.SealHashAlgs{
+			Opts:           client.SealCurrent{PCRSelection: sel},
+			SessionHashAlg: tpm2.AlgSHA384,
+			CertifyHashAlg: tpm2.AlgSHA384,
+		}, true},
+		{"SHA384NoPCRs", client.SealHashAlgs{SessionHashAlg: tpm2.AlgSHA384, CertifyHashAlg: tpm2.AlgSHA384}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sealed, err := srk.SealWithAuthValue(secret, test.opts, "password")
+			if err != nil {
+				t.Fatalf("failed to seal: %v", err)
+			}
+			certifySel := tpm2.PCRSelection{Hash: tpm2.Algorithm(sealed.GetCertifiedPcrs().GetHash()), PCRs: []int{7}}
+			if algs, ok := test.opts.(client.SealHashAlgs); ok {
+				pub, err := tpm2.DecodePublic(sealed.GetPub())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if pub.NameAlg != algs.SessionHashAlg {
+					t.Errorf("got sealed object name algorithm %v, want %v", pub.NameAlg, algs.SessionHashAlg)
+				}
+				if certifySel.Hash != algs.CertifyHashAlg {
+					t.Errorf("got certified PCRs in the %v bank, want %v", certifySel.Hash, algs.CertifyHashAlg)
+				}
+			}
+
+			unseal, err := srk.UnsealWithAuthValue(sealed, "password", client.CertifyCurrent{PCRSelection: certifySel})
+			if err != nil {
+				t.Fatalf("failed to unseal: %v", err)
+			}
+			if !bytes.Equal(secret, unseal) {
+				t.Fatalf("unsealed (%v) not equal to secret (%v)", unseal, secret)
+			}
+
+			extension := bytes.Repeat([]byte{0xAA}, sha512.Size384)
+			if err = tpm2.PCRExtend(rwc, tpmutil.Handle(pcrToChange), tpm2.AlgSHA384, extension, ""); err != nil {
+				t.Fatalf("failed to extend pcr: %v", err)
+			}
+			_, err = srk.UnsealWithAuthValue(sealed, "password", nil)
+			if test.withPCRs && !errors.Is(err, client.ErrPCRChanged) {
+				t.Errorf("unseal with changed PCRs returned %v, want ErrPCRChanged", err)
+			} else if !test.withPCRs && err != nil {
+				t.Errorf("unseal without PCRs failed: %v", err)
+			}
+		})
+	}
+
+	if _, err = srk.Seal(secret, client.SealHashAlgs{
+		Opts:           client.SealAlternatives{client.SealCurrent{PCRSelection: sel}},
+		SessionHashAlg: tpm2.AlgSHA384,
+	}); err == nil {
+		t.Error("sealing alternatives with a SHA384 session should fail")
+	}
+}
+
+func TestSealAuthValue(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	key, err := client.StorageRootKeyECC(rwc)
+	if err != nil {
+		t.Fatalf("can't create srk from template: %v", err)
+	}
+	defer key.Close()
+
+	secret := []byte("test")
+	sel := tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{7}}
+	current, err := client.ReadPCRs(rwc, sel)
+	if err != nil {
+		t.Fatalf("failed to read PCRs value: %v", err)
+	}
+	sealOpts := []struct {
+		name string
+		opts client.SealOpts
+	}{
+		{"NoPCRs", nil},
+		{"PCRs", client.SealCurrent{PCRSelection: sel}},
+		{"Alternatives", client.SealAlternatives{client.SealTarget{Pcrs: current}}},
+	}
+	for _, opts := range sealOpts {
+		t.Run(opts.name, func(t *testing.T) {
+			sealed, err := key.SealWithAuthValue(secret, opts.opts, "passphrase")
+			if err != nil {
+				t.Fatalf("failed to seal: %v", err)
+			}
+			unseal, err := key.UnsealWithAuthValue(sealed, "passphrase", nil)
+			if err != nil {
+				t.Fatalf("failed to unseal: %v", err)
+			}
+			if !bytes.Equal(secret, unseal) {
+				t.Fatalf("unsealed (%v) not equal to secret (%v)", unseal, secret)
+			}
+			if _, err = key.UnsealWithAuthValue(sealed, "wrong", nil); !errors.Is(err, client.ErrAuthFail) {
+				t.Errorf("unseal with the wrong auth value returned %v, want ErrAuthFail", err)
+			}
+			if _, err = key.Unseal(sealed, nil); err == nil {
+				t.Error("unseal should fail without the auth value")
+			}
+			// The failed authorizations count towards the DA lockout.
+			if err = tpm2.DictionaryAttackLockReset(rwc, tpm2.AuthCommand{Session: tpm2.HandlePasswordSession, Attributes: tpm2.AttrContinueSession}); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
 func TestSealResealWithEmptyPCRs(t *testing.T) {
 	rwc := test.GetTPM(t)
 	defer client.CheckedClose(t, rwc)
@@ -336,3 +559,59 @@ func BenchmarkSeal(b *testing.B) {
 		})
 	}
 }
+
+func TestSealHeader(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	srk, err := client.StorageRootKeyECC(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srk.Close()
+
+	secret := []byte("test")
+	sel := tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{7}}
+	sealed, err := srk.Seal(secret, client.SealCurrent{PCRSelection: sel})
+	if err != nil {
+		t.Fatalf("failed to seal: %v", err)
+	}
+	header := sealed.GetHeader()
+	if header.GetVersion() != notinternal.BlobVersion {
+		t.Errorf("got blob version %d, want %d", header.GetVersion(), notinternal.BlobVersion)
+	}
+	if header.GetPcrBank() != pb.HashAlgo_SHA256 {
+		t.Errorf("got PCR bank %v, want SHA256", header.GetPcrBank())
+	}
+	pub, err := tpm2.DecodePublic(sealed.GetPub())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(header.GetPolicyDigest(), pub.AuthPolicy) || len(pub.AuthPolicy) == 0 {
+		t.Errorf("got policy digest %x, want %x", header.GetPolicyDigest(), pub.AuthPolicy)
+	}
+	if header.GetCreator() == "" || header.GetCreated() == 0 {
+		t.Error("expected the header to record the creator and creation time")
+	}
+
+	// Blobs without a header, as sealed by older versions, can be unsealed.
+	legacy := proto.Clone(sealed).(*pb.SealedBytes)
+	legacy.Header = nil
+	if unsealed, err := srk.Unseal(legacy, nil); err != nil || !bytes.Equal(unsealed, secret) {
+		t.Errorf("failed to unseal a blob without a header: %v", err)
+	}
+
+	modified := proto.Clone(sealed).(*pb.SealedBytes)
+	modified.Pcrs = append(modified.Pcrs, uint32(test.DebugPCR))
+	if _, err := srk.Unseal(modified, nil); err == nil {
+		t.Error("expected unsealing a modified blob to fail")
+	}
+	future := proto.Clone(sealed).(*pb.SealedBytes)
+	future.Header.Version = notinternal.BlobVersion + 1
+	if err := notinternal.SetBlobChecksum(future); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := srk.Unseal(future, nil); err == nil {
+		t.Error("expected unsealing a blob of a future version to fail")
+	}
+}
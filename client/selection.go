@@ -0,0 +1,160 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-tpm/tpm2"
+)
+
+// Names of the PCR banks in PCR selection strings, as used by tpm2-tools.
+var pcrBankNames = map[tpm2.Algorithm]string{
+	tpm2.AlgSHA1:     "sha1",
+	tpm2.AlgSHA256:   "sha256",
+	tpm2.AlgSHA384:   "sha384",
+	tpm2.AlgSHA512:   "sha512",
+	tpm2.AlgSHA3_256: "sha3_256",
+	tpm2.AlgSHA3_384: "sha3_384",
+	tpm2.AlgSHA3_512: "sha3_512",
+	AlgSM3:           "sm3_256",
+}
+
+// ParsePCRSelection parses a PCR selection of the form "<bank>:<pcrs>", such
+// as "sha256:0,1,2,3,7", where the bank is the name of a hash algorithm (sha1,
+// sha256, sha384, sha512, sha3_256, sha3_384, sha3_512 or sm3_256) and the PCRs are
+// parsed with ParsePCRs. This is the format used by tpm2-tools.
+func ParsePCRSelection(s string) (tpm2.PCRSelection, error) {
+	i := strings.Index(s, ":")
+	if i < 0 {
+		return tpm2.PCRSelection{}, fmt.Errorf("PCR selection %q has no bank, expected <bank>:<pcrs>", s)
+	}
+	hash, err := parsePCRBank(s[:i])
+	if err != nil {
+		return tpm2.PCRSelection{}, err
+	}
+	pcrs, err := ParsePCRs(s[i+1:])
+	if err != nil {
+		return tpm2.PCRSelection{}, err
+	}
+	return tpm2.PCRSelection{Hash: hash, PCRs: pcrs}, nil
+}
+
+// ParsePCRSelections parses PCR selections of multiple banks separated by
+// "+", such as "sha1:0,1+sha256:7" (see ParsePCRSelection). Each bank can only
+// be selected once.
+func ParsePCRSelections(s string) ([]tpm2.PCRSelection, error) {
+	var sels []tpm2.PCRSelection
+	seen := map[tpm2.Algorithm]bool{}
+	for _, part := range strings.Split(s, "+") {
+		sel, err := ParsePCRSelection(part)
+		if err != nil {
+			return nil, err
+		}
+		if seen[sel.Hash] {
+			return nil, fmt.Errorf("PCR bank %s selected more than once", pcrBankName(sel.Hash))
+		}
+		seen[sel.Hash] = true
+		sels = append(sels, sel)
+	}
+	return sels, nil
+}
+
+// ParsePCRs parses a comma separated list of PCR numbers or ranges, such as
+// "0,1,2,3,7" or "0-3,7", or "all" for all the PCRs. The PCRs are returned
+// sorted, without duplicates.
+func ParsePCRs(s string) ([]int, error) {
+	if s == "all" {
+		return FullPcrSel(tpm2.AlgUnknown).PCRs, nil
+	}
+	selected := map[int]bool{}
+	for _, d := range strings.Split(s, ",") {
+		first, last := d, d
+		if i := strings.Index(d, "-"); i > 0 {
+			first, last = d[:i], d[i+1:]
+		}
+		start, err := parsePCR(first)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parsePCR(last)
+		if err != nil {
+			return nil, err
+		}
+		if start > end {
+			return nil, fmt.Errorf("invalid PCR range %q", d)
+		}
+		for pcr := start; pcr <= end; pcr++ {
+			selected[pcr] = true
+		}
+	}
+	pcrs := make([]int, 0, len(selected))
+	for pcr := range selected {
+		pcrs = append(pcrs, pcr)
+	}
+	sort.Ints(pcrs)
+	return pcrs, nil
+}
+
+func parsePCR(s string) (int, error) {
+	pcr, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid PCR %q: %w", s, err)
+	}
+	if pcr < 0 || pcr >= NumPCRs {
+		return 0, fmt.Errorf("PCR %d out of range, must be less than %d", pcr, NumPCRs)
+	}
+	return pcr, nil
+}
+
+// FormatPCRSelection formats sel as parsed by ParsePCRSelection, such as
+// "sha256:0,1,2,3,7". The PCRs are formatted in increasing order. Unknown
+// banks are formatted as the hexadecimal value of the hash algorithm.
+func FormatPCRSelection(sel tpm2.PCRSelection) string {
+	return pcrBankName(sel.Hash) + ":" + FormatPCRs(sel.PCRs)
+}
+
+// FormatPCRSelections formats the selections of multiple banks as parsed by
+// ParsePCRSelections, such as "sha1:0,1+sha256:7".
+func FormatPCRSelections(sels []tpm2.PCRSelection) string {
+	parts := make([]string, len(sels))
+	for i, sel := range sels {
+		parts[i] = FormatPCRSelection(sel)
+	}
+	return strings.Join(parts, "+")
+}
+
+// FormatPCRs formats a list of PCR numbers as parsed by ParsePCRs, in
+// increasing order.
+func FormatPCRs(pcrs []int) string {
+	sorted := append([]int(nil), pcrs...)
+	sort.Ints(sorted)
+	parts := make([]string, len(sorted))
+	for i, pcr := range sorted {
+		parts[i] = strconv.Itoa(pcr)
+	}
+	return strings.Join(parts, ",")
+}
+
+func parsePCRBank(name string) (tpm2.Algorithm, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for hash, bankName := range pcrBankNames {
+		if bankName == name {
+			return hash, nil
+		}
+	}
+	if strings.HasPrefix(name, "0x") {
+		if hash, err := strconv.ParseUint(name[2:], 16, 16); err == nil && hash != 0 {
+			return tpm2.Algorithm(hash), nil
+		}
+	}
+	return tpm2.AlgUnknown, fmt.Errorf("unknown PCR bank %q", name)
+}
+
+func pcrBankName(hash tpm2.Algorithm) string {
+	if name, ok := pcrBankNames[hash]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", uint16(hash))
+}
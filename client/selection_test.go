@@ -0,0 +1,88 @@
+package client_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+)
+
+func TestParsePCRSelection(t *testing.T) {
+	tests := []struct {
+		in        string
+		want      tpm2.PCRSelection
+		formatted string
+	}{
+		{"sha256:0,1,2,3,7", tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{0, 1, 2, 3, 7}}, "sha256:0,1,2,3,7"},
+		{"sha1:7", tpm2.PCRSelection{Hash: tpm2.AlgSHA1, PCRs: []int{7}}, "sha1:7"},
+		{"SHA384:23, 4,4", tpm2.PCRSelection{Hash: tpm2.AlgSHA384, PCRs: []int{4, 23}}, "sha384:4,23"},
+		{"0x000b:1", tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{1}}, "sha256:1"},
+		{"0x0012:1", tpm2.PCRSelection{Hash: client.AlgSM3, PCRs: []int{1}}, "sm3_256:1"},
+		{"sm3_256:0,7", tpm2.PCRSelection{Hash: client.AlgSM3, PCRs: []int{0, 7}}, "sm3_256:0,7"},
+		{"0x0020:1", tpm2.PCRSelection{Hash: tpm2.Algorithm(0x0020), PCRs: []int{1}}, "0x0020:1"},
+		{"sha256:0-3,7", tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{0, 1, 2, 3, 7}}, "sha256:0,1,2,3,7"},
+		{"sha256:16-16,2-4,3", tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{2, 3, 4, 16}}, "sha256:2,3,4,16"},
+		{"sha512:all", client.FullPcrSel(tpm2.AlgSHA512), "sha512:0,1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21,22,23"},
+	}
+	for _, test := range tests {
+		sel, err := client.ParsePCRSelection(test.in)
+		if err != nil {
+			t.Errorf("ParsePCRSelection(%q) failed: %v", test.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(sel, test.want) {
+			t.Errorf("ParsePCRSelection(%q) = %v, want %v", test.in, sel, test.want)
+		}
+		if got := client.FormatPCRSelection(sel); got != test.formatted {
+			t.Errorf("FormatPCRSelection(%v) = %q, want %q", sel, got, test.formatted)
+		}
+	}
+}
+
+func TestParsePCRSelectionFail(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"0,1,2",
+		"sha256:",
+		"sha256:1,",
+		"sha256:-1",
+		"sha256:24",
+		"sha256:one",
+		"sha256:3-1",
+		"sha256:0-24",
+		"sha256:0-",
+		"sha256:-",
+		"md5:1",
+		"0x0000:1",
+		"sha1:0+sha256:1",
+	} {
+		if sel, err := client.ParsePCRSelection(in); err == nil {
+			t.Errorf("ParsePCRSelection(%q) = %v, want an error", in, sel)
+		}
+	}
+}
+
+func TestParsePCRSelections(t *testing.T) {
+	sels, err := client.ParsePCRSelections("sha1:0,1+sha256:7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tpm2.PCRSelection{
+		{Hash: tpm2.AlgSHA1, PCRs: []int{0, 1}},
+		{Hash: tpm2.AlgSHA256, PCRs: []int{7}},
+	}
+	if !reflect.DeepEqual(sels, want) {
+		t.Errorf("ParsePCRSelections() = %v, want %v", sels, want)
+	}
+	if got := client.FormatPCRSelections(sels); got != "sha1:0,1+sha256:7" {
+		t.Errorf("FormatPCRSelections() = %q, want %q", got, "sha1:0,1+sha256:7")
+	}
+
+	for _, in := range []string{"sha1:0+sha1:1", "sha1:0+", "sha1:0,sha256:1"} {
+		if _, err = client.ParsePCRSelections(in); err == nil {
+			t.Errorf("ParsePCRSelections(%q) should fail", in)
+		}
+	}
+}
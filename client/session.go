@@ -1,8 +1,17 @@
 package client
 
 import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"io"
 
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
 	"github.com/google/go-tpm/tpm2"
 	"github.com/google/go-tpm/tpmutil"
 )
@@ -13,6 +22,16 @@ type session interface {
 }
 
 func startAuthSession(rw io.ReadWriter) (session tpmutil.Handle, err error) {
+	return startAuthSessionWithHash(rw, SessionHashAlgTpm)
+}
+
+// startAuthSessionWithHash is like startAuthSession, for a policy session
+// using hashAlg (which must be the name algorithm of the authorized object).
+func startAuthSessionWithHash(rw io.ReadWriter, hashAlg tpm2.Algorithm) (session tpmutil.Handle, err error) {
+	hash, err := hashAlg.Hash()
+	if err != nil {
+		return 0, err
+	}
 	// This session assumes the bus is trusted, so we:
 	// - use nil for tpmKey, encrypted salt, and symmetric
 	// - use and all-zeros caller nonce, and ignore the returned nonce
@@ -23,33 +42,44 @@ func startAuthSession(rw io.ReadWriter) (session tpmutil.Handle, err error) {
 		rw,
 		/*tpmKey=*/ tpm2.HandleNull,
 		/*bindKey=*/ tpm2.HandleNull,
-		/*nonceCaller=*/ make([]byte, SessionHashAlg.Size()),
+		/*nonceCaller=*/ make([]byte, hash.Size()),
 		/*encryptedSalt=*/ nil,
 		/*sessionType=*/ tpm2.SessionPolicy,
 		/*symmetric=*/ tpm2.AlgNull,
-		/*authHash=*/ SessionHashAlgTpm)
-	return
+		/*authHash=*/ hashAlg)
+	return session, tpmError(err)
 }
 
 type pcrSession struct {
-	rw      io.ReadWriter
-	session tpmutil.Handle
-	sel     tpm2.PCRSelection
+	rw        io.ReadWriter
+	session   tpmutil.Handle
+	sel       tpm2.PCRSelection
+	authValue bool
+	password  string
 }
 
-func newPCRSession(rw io.ReadWriter, sel tpm2.PCRSelection) (session, error) {
+// newPCRSession creates a session satisfying a PolicyPCR over the current
+// values of sel. If authValue is set, the policy also contains a
+// PolicyAuthValue, satisfied by the provided password. The session uses
+// hashAlg, which must be the name algorithm of the authorized object.
+func newPCRSession(rw io.ReadWriter, sel tpm2.PCRSelection, authValue bool, password string, hashAlg tpm2.Algorithm) (session, error) {
 	if len(sel.PCRs) == 0 {
 		return nullSession{}, nil
 	}
-	session, err := startAuthSession(rw)
-	return pcrSession{rw, session, sel}, err
+	session, err := startAuthSessionWithHash(rw, hashAlg)
+	return pcrSession{rw, session, sel, authValue, password}, err
 }
 
 func (p pcrSession) Auth() (auth tpm2.AuthCommand, err error) {
 	if err = tpm2.PolicyPCR(p.rw, p.session, nil, p.sel); err != nil {
-		return
+		return auth, tpmError(err)
+	}
+	if p.authValue {
+		if err = tpm2.PolicyPassword(p.rw, p.session); err != nil {
+			return auth, tpmError(err)
+		}
 	}
-	return tpm2.AuthCommand{Session: p.session, Attributes: tpm2.AttrContinueSession}, nil
+	return tpm2.AuthCommand{Session: p.session, Attributes: tpm2.AttrContinueSession, Auth: []byte(p.password)}, nil
 }
 
 func (p pcrSession) Close() error {
@@ -67,9 +97,9 @@ func newEKSession(rw io.ReadWriter) (session, error) {
 }
 
 func (e ekSession) Auth() (auth tpm2.AuthCommand, err error) {
-	nullAuth := tpm2.AuthCommand{Session: tpm2.HandlePasswordSession, Attributes: tpm2.AttrContinueSession}
-	if _, err = tpm2.PolicySecret(e.rw, tpm2.HandleEndorsement, nullAuth, e.session, nil, nil, nil, 0); err != nil {
-		return
+	endorsementAuth := passwordAuth(hierarchyAuth(e.rw, tpm2.HandleEndorsement))
+	if _, err = tpm2.PolicySecret(e.rw, tpm2.HandleEndorsement, endorsementAuth, e.session, nil, nil, nil, 0); err != nil {
+		return auth, tpmError(err)
 	}
 	return tpm2.AuthCommand{Session: e.session, Attributes: tpm2.AttrContinueSession}, nil
 }
@@ -78,6 +108,11 @@ func (e ekSession) Close() error {
 	return tpm2.FlushContext(e.rw, e.session)
 }
 
+// passwordAuth returns the authorization for a password session.
+func passwordAuth(password string) tpm2.AuthCommand {
+	return tpm2.AuthCommand{Session: tpm2.HandlePasswordSession, Attributes: tpm2.AttrContinueSession, Auth: []byte(password)}
+}
+
 type nullSession struct{}
 
 func (n nullSession) Auth() (auth tpm2.AuthCommand, err error) {
@@ -87,3 +122,196 @@ func (n nullSession) Auth() (auth tpm2.AuthCommand, err error) {
 func (n nullSession) Close() error {
 	return nil
 }
+
+// passwordSession authorizes a key with its authorization value, using a
+// password session.
+type passwordSession struct {
+	password string
+}
+
+func (p passwordSession) Auth() (auth tpm2.AuthCommand, err error) {
+	return passwordAuth(p.password), nil
+}
+
+func (p passwordSession) Close() error {
+	return nil
+}
+
+// hmacSession is an unbound HMAC session, which is not used to authorize
+// commands (so its HMAC key is just the session key). If it is salted, it is
+// used for parameter encryption. If audit is set, the commands using the
+// session are audited, and logged in auditLog.
+type hmacSession struct {
+	rw         io.ReadWriter
+	handle     tpmutil.Handle
+	sessionKey []byte
+	nonceTPM   []byte
+	salted     bool
+	audit      bool
+	auditLog   []*pb.AuditedCommand
+}
+
+// newHMACSession starts an hmacSession, salted to tpmKey if it is not nil.
+func newHMACSession(rw io.ReadWriter, tpmKey *Key, audit bool) (*hmacSession, error) {
+	keyHandle := tpm2.HandleNull
+	var salt, encryptedSalt []byte
+	symmetric := []interface{}{tpm2.AlgNull}
+	if tpmKey != nil {
+		var err error
+		if salt, encryptedSalt, err = createSalt(tpmKey.pubArea); err != nil {
+			return nil, fmt.Errorf("failed to create session salt: %w", err)
+		}
+		keyHandle = tpmKey.handle
+		symmetric = []interface{}{tpm2.AlgAES, uint16(encryptionKeyBits), tpm2.AlgCFB}
+	}
+	nonceCaller, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+	params := []interface{}{tpmutil.U16Bytes(nonceCaller), tpmutil.U16Bytes(encryptedSalt), tpm2.SessionHMAC}
+	params = append(append(params, symmetric...), SessionHashAlgTpm)
+	handle, resp, err := runCommandWithHandle(rw, tpm2.CmdStartAuthSession, []tpmutil.Handle{keyHandle, tpm2.HandleNull}, nil, params...)
+	if err != nil {
+		return nil, fmt.Errorf("TPM2_StartAuthSession failed: %w", err)
+	}
+	var nonceTPM tpmutil.U16Bytes
+	if _, err = tpmutil.Unpack(resp, &nonceTPM); err != nil {
+		return nil, fmt.Errorf("decoding TPM2_StartAuthSession response: %w", err)
+	}
+	s := &hmacSession{rw: rw, handle: handle, nonceTPM: nonceTPM, salted: tpmKey != nil, audit: audit}
+	// Without a salt (or bind key), the session key is empty.
+	if s.salted {
+		if s.sessionKey, err = tpm2.KDFa(SessionHashAlgTpm, salt, "ATH", nonceTPM, nonceCaller, SessionHashAlg.Size()*8); err != nil {
+			tpm2.FlushContext(rw, handle)
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *hmacSession) Close() error {
+	return tpm2.FlushContext(s.rw, s.handle)
+}
+
+// run executes a command (as in runCommand) with the session appended to
+// auths. names must contain the (encoded) Name of each handle, as returned by
+// tpm2.Load or HashValue.Encode(). If the session is salted, the first command
+// parameter is encrypted if decrypt is set, and the first response parameter
+// is decrypted if encrypt is set. Both parameters must be TPM2B structures.
+func (s *hmacSession) run(cmd tpmutil.Command, handles []tpmutil.Handle, names [][]byte, auths []tpm2.AuthCommand, decrypt, encrypt bool, params ...interface{}) ([]byte, error) {
+	cmdParams, err := tpmutil.Pack(params...)
+	if err != nil {
+		return nil, err
+	}
+	nonceCaller, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+	decrypt = decrypt && s.salted
+	encrypt = encrypt && s.salted
+	attrs := tpm2.AttrContinueSession
+	if s.audit {
+		attrs |= tpm2.AttrAudit
+	}
+	if decrypt {
+		attrs |= tpm2.AttrDecrypt
+		if err = s.cryptFirstParam(cmdParams, nonceCaller, s.nonceTPM, false); err != nil {
+			return nil, fmt.Errorf("encrypting command parameter: %w", err)
+		}
+	}
+	if encrypt {
+		attrs |= tpm2.AttrEcrypt
+	}
+
+	cpHash := SessionHashAlg.New()
+	binary.Write(cpHash, binary.BigEndian, cmd)
+	for _, name := range names {
+		cpHash.Write(name)
+	}
+	cpHash.Write(cmdParams)
+	cpDigest := cpHash.Sum(nil)
+	auths = append(auths, tpm2.AuthCommand{
+		Session:    s.handle,
+		Nonce:      nonceCaller,
+		Attributes: attrs,
+		Auth:       s.hmac(cpDigest, nonceCaller, s.nonceTPM, attrs),
+	})
+
+	respParams, authArea, err := runCommandWithAuthArea(s.rw, cmd, handles, auths, tpmutil.RawBytes(cmdParams))
+	if err != nil {
+		return nil, err
+	}
+	// The response entry for this session follows those of the other sessions.
+	buf := bytes.NewBuffer(authArea)
+	var nonceTPM, respHMAC tpmutil.U16Bytes
+	var respAttrs tpm2.SessionAttributes
+	for range auths {
+		if err = tpmutil.UnpackBuf(buf, &nonceTPM, &respAttrs, &respHMAC); err != nil {
+			return nil, fmt.Errorf("decoding response authorization area: %w", err)
+		}
+	}
+	rpHash := SessionHashAlg.New()
+	binary.Write(rpHash, binary.BigEndian, uint32(tpmutil.RCSuccess))
+	binary.Write(rpHash, binary.BigEndian, cmd)
+	rpHash.Write(respParams)
+	rpDigest := rpHash.Sum(nil)
+	if !hmac.Equal(respHMAC, s.hmac(rpDigest, nonceTPM, nonceCaller, respAttrs)) {
+		return nil, errors.New("response HMAC of session does not match")
+	}
+	s.nonceTPM = nonceTPM
+	if s.audit {
+		s.auditLog = append(s.auditLog, &pb.AuditedCommand{CommandCode: uint32(cmd), CpHash: cpDigest, RpHash: rpDigest})
+	}
+
+	if encrypt {
+		if err = s.cryptFirstParam(respParams, nonceTPM, nonceCaller, true); err != nil {
+			return nil, fmt.Errorf("decrypting response parameter: %w", err)
+		}
+	}
+	return respParams, nil
+}
+
+func (s *hmacSession) hmac(pHash, nonceNewer, nonceOlder []byte, attrs tpm2.SessionAttributes) []byte {
+	mac := hmac.New(SessionHashAlg.New, s.sessionKey)
+	mac.Write(pHash)
+	mac.Write(nonceNewer)
+	mac.Write(nonceOlder)
+	mac.Write([]byte{byte(attrs)})
+	return mac.Sum(nil)
+}
+
+// cryptFirstParam encrypts or decrypts (in place) the data of the TPM2B at the
+// start of params, using AES-CFB keyed from the session key and nonces.
+func (s *hmacSession) cryptFirstParam(params, nonceNewer, nonceOlder []byte, decrypt bool) error {
+	if len(params) < 2 {
+		return errors.New("missing parameter")
+	}
+	size := int(binary.BigEndian.Uint16(params))
+	if len(params) < 2+size {
+		return errors.New("parameter too short")
+	}
+	keyIV, err := tpm2.KDFa(SessionHashAlgTpm, s.sessionKey, "CFB", nonceNewer, nonceOlder, encryptionKeyBits+aes.BlockSize*8)
+	if err != nil {
+		return err
+	}
+	block, err := aes.NewCipher(keyIV[:encryptionKeyBits/8])
+	if err != nil {
+		return err
+	}
+	iv := keyIV[encryptionKeyBits/8:]
+	data := params[2 : 2+size]
+	if decrypt {
+		cipher.NewCFBDecrypter(block, iv).XORKeyStream(data, data)
+	} else {
+		cipher.NewCFBEncrypter(block, iv).XORKeyStream(data, data)
+	}
+	return nil
+}
+
+func newNonce() ([]byte, error) {
+	nonce := make([]byte, SessionHashAlg.Size())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return nonce, nil
+}
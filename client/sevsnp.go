@@ -0,0 +1,58 @@
+package client
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+// GUIDs of the certificates in the certificate table returned with an
+// extended SEV-SNP attestation report, from the GHCB specification.
+var (
+	vcekGUID = []byte{0x63, 0xda, 0x75, 0x8d, 0xe6, 0x64, 0x45, 0x64,
+		0xad, 0xc5, 0xf4, 0xb9, 0x3b, 0xe8, 0xac, 0xcd}
+	askGUID = []byte{0x4a, 0xb7, 0xb3, 0x79, 0xbb, 0xac, 0x4f, 0xe4,
+		0xa0, 0x2f, 0x05, 0xae, 0xf3, 0x27, 0xc7, 0x82}
+)
+
+// GetSevSnpAttestation requests an SEV-SNP attestation report with the given
+// REPORT_DATA (see TEEReportData) from the AMD Secure Processor. The VCEK
+// and ASK certificates are included if the host provides them. This is only
+// supported in Linux SEV-SNP guests, with the /dev/sev-guest device.
+func GetSevSnpAttestation(reportData []byte) (*pb.SevSnpAttestation, error) {
+	if len(reportData) != TEEReportDataSize {
+		return nil, fmt.Errorf("SEV-SNP report data must be %d bytes, got %d", TEEReportDataSize, len(reportData))
+	}
+	return getSevSnpAttestation(reportData)
+}
+
+// parseSevSnpCertTable sets the VCEK and ASK certificates of the attestation
+// from the certificate table of an extended report. The table is an array of
+// (GUID, offset, length) entries, terminated by an all-zero entry.
+func parseSevSnpCertTable(table []byte, attestation *pb.SevSnpAttestation) error {
+	const entrySize = 24
+	for i := 0; ; i += entrySize {
+		if i+entrySize > len(table) {
+			return errors.New("unterminated SEV-SNP certificate table")
+		}
+		guid := table[i : i+16]
+		offset := binary.LittleEndian.Uint32(table[i+16:])
+		length := binary.LittleEndian.Uint32(table[i+20:])
+		if bytes.Count(table[i:i+entrySize], []byte{0}) == entrySize {
+			return nil
+		}
+		if uint64(offset)+uint64(length) > uint64(len(table)) {
+			return fmt.Errorf("SEV-SNP certificate table entry %d is out of bounds", i/entrySize)
+		}
+		cert := table[offset : offset+length]
+		switch {
+		case bytes.Equal(guid, vcekGUID):
+			attestation.VcekCert = cert
+		case bytes.Equal(guid, askGUID):
+			attestation.AskCert = cert
+		}
+	}
+}
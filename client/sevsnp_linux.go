@@ -0,0 +1,103 @@
+package client
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+// The SEV guest driver interface, from include/uapi/linux/sev-guest.h.
+const (
+	sevGuestDevice = "/dev/sev-guest"
+	// _IOWR('S', 0x2, struct snp_guest_request_ioctl)
+	snpGetExtReport = 0xc0205302
+	// Returned in the VMM error if the certificate buffer is too small.
+	snpGuestVMMErrInvalidLen = 1
+	// The header of MSG_REPORT_RSP, followed by the report.
+	snpReportResponseHeaderSize = 0x20
+	snpReportSize               = 0x4a0
+	// The initial size of the certificate table buffer (a multiple of the
+	// page size, as required by the host).
+	snpCertTableSize = 4 * 4096
+)
+
+type snpReportReq struct {
+	UserData [TEEReportDataSize]byte
+	VMPL     uint32
+	_        [28]byte
+}
+
+type snpExtReportReq struct {
+	Data         snpReportReq
+	CertsAddress uint64
+	CertsLen     uint32
+	_            uint32
+}
+
+type snpGuestRequestIoctl struct {
+	MsgVersion uint8
+	_          [7]uint8
+	ReqData    uint64
+	RespData   uint64
+	FWError    uint32
+	VMMError   uint32
+}
+
+func getSevSnpAttestation(reportData []byte) (*pb.SevSnpAttestation, error) {
+	device, err := os.OpenFile(sevGuestDevice, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("not in an SEV-SNP guest: %w", err)
+	}
+	defer device.Close()
+
+	req := &snpExtReportReq{}
+	copy(req.Data.UserData[:], reportData)
+	resp := make([]byte, 4000)
+	certs := make([]byte, snpCertTableSize)
+	for {
+		req.CertsAddress = uint64(uintptr(unsafe.Pointer(&certs[0])))
+		req.CertsLen = uint32(len(certs))
+		ioctl := &snpGuestRequestIoctl{
+			MsgVersion: 1,
+			ReqData:    uint64(uintptr(unsafe.Pointer(req))),
+			RespData:   uint64(uintptr(unsafe.Pointer(&resp[0]))),
+		}
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, device.Fd(), snpGetExtReport, uintptr(unsafe.Pointer(ioctl)))
+		runtime.KeepAlive(req)
+		runtime.KeepAlive(certs)
+		runtime.KeepAlive(resp)
+		if errno == 0 {
+			break
+		}
+		if errno == syscall.EIO && ioctl.VMMError == snpGuestVMMErrInvalidLen && int(req.CertsLen) > len(certs) {
+			// The host returned the size of its certificate table.
+			certs = make([]byte, req.CertsLen)
+			continue
+		}
+		return nil, fmt.Errorf("SNP_GET_EXT_REPORT failed (firmware error %#x, VMM error %#x): %w", ioctl.FWError, ioctl.VMMError, errno)
+	}
+
+	// The response is the MSG_REPORT_RSP structure.
+	if status := binary.LittleEndian.Uint32(resp[0:]); status != 0 {
+		return nil, fmt.Errorf("the AMD Secure Processor failed to generate the report (status %#x)", status)
+	}
+	if size := binary.LittleEndian.Uint32(resp[4:]); size != snpReportSize {
+		return nil, fmt.Errorf("got an SEV-SNP report of %d bytes, expected %d", size, snpReportSize)
+	}
+	attestation := &pb.SevSnpAttestation{
+		Report: resp[snpReportResponseHeaderSize : snpReportResponseHeaderSize+snpReportSize],
+	}
+	// The table is empty (all zeros) if the host does not provide certificates.
+	if req.CertsLen > 0 && int(req.CertsLen) < len(certs) {
+		certs = certs[:req.CertsLen]
+	}
+	if err = parseSevSnpCertTable(certs, attestation); err != nil {
+		return nil, err
+	}
+	return attestation, nil
+}
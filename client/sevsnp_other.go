@@ -0,0 +1,13 @@
+// +build !linux
+
+package client
+
+import (
+	"errors"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+func getSevSnpAttestation(reportData []byte) (*pb.SevSnpAttestation, error) {
+	return nil, errors.New("SEV-SNP attestation reports are only supported on Linux")
+}
@@ -0,0 +1,489 @@
+package client
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// Bits of a command's TPMA_CC attributes, from "TPM 2.0 Part 2: Structures",
+// 8.9 TPMA_CC.
+const (
+	ccIndexMask    = 0x0000FFFF
+	ccHandlesShift = 25
+	ccHandlesMask  = 0x7
+	ccRHandle      = 1 << 28
+	ccVendor       = 1 << 29
+)
+
+// The handle types (most significant octet of a handle) which a SharedTPM
+// tracks, from "TPM 2.0 Part 2: Structures", 7.2 TPM_HT.
+const (
+	htHMACSession   = 0x02
+	htPolicySession = 0x03
+	htTransient     = 0x80
+)
+
+// Virtual transient handles used by a multiplexed SharedTPM.
+const (
+	firstVirtualHandle tpmutil.Handle = 0x80FF0000
+	lastVirtualHandle  tpmutil.Handle = 0x80FFFFFF
+)
+
+// The response to a successful command without sessions or parameters.
+var successResponse = []byte{0x80, 0x01, 0, 0, 0, headerSize, 0, 0, 0, 0}
+
+// SharedTPMOpts configures a SharedTPM.
+type SharedTPMOpts struct {
+	// Multiplex saves the transient objects and sessions of a connection
+	// (using TPM2_ContextSave) after each of its commands, and loads them
+	// again when they are used, as done by a resource manager. This allows
+	// the connections to have more objects and sessions than fit in the
+	// TPM's memory, at the cost of extra TPM commands. The transient handles
+	// used by the connections are then virtual handles, which are not the
+	// TPM's handles of the objects.
+	Multiplex bool
+}
+
+// SharedTPM serializes the commands of multiple users of a TPM, such as
+// goroutines, each using its own SharedConn. Without it, concurrent users of a
+// TPM interleave their commands and responses, and can use or flush each
+// other's sessions and objects, resulting in corrupted sessions or
+// authorizations of the wrong command.
+//
+// A SharedConn can only use the transient objects and sessions it created
+// (or loaded), which are flushed when the SharedConn is closed. Persistent
+// objects, NV indices and PCRs are shared by all connections. Note that the
+// handles returned by TPM2_GetCapability are those of all connections.
+type SharedTPM struct {
+	rw        io.ReadWriter
+	multiplex bool
+	// The TPMA_CC attributes of the TPM's commands.
+	commands map[tpmutil.Command]uint32
+
+	mu sync.Mutex
+	// The connection owning each transient object and session, by the
+	// handle used by the connections.
+	owners      map[tpmutil.Handle]*SharedConn
+	nextVirtual tpmutil.Handle
+}
+
+// NewSharedTPM returns a SharedTPM for rw, which must not be used directly
+// while the SharedTPM is in use. The SharedTPM forwards Close (and
+// GetEventLog, GetIMALog and HierarchyAuth of its connections) to rw.
+func NewSharedTPM(rw io.ReadWriter, opts SharedTPMOpts) (*SharedTPM, error) {
+	commands, err := getCommandAttributes(rw)
+	if err != nil {
+		return nil, err
+	}
+	return &SharedTPM{
+		rw:          rw,
+		multiplex:   opts.Multiplex,
+		commands:    commands,
+		owners:      map[tpmutil.Handle]*SharedConn{},
+		nextVirtual: firstVirtualHandle,
+	}, nil
+}
+
+// getCommandAttributes returns the TPMA_CC attributes of the commands
+// implemented by the TPM.
+func getCommandAttributes(rw io.ReadWriter) (map[tpmutil.Command]uint32, error) {
+	commands := map[tpmutil.Command]uint32{}
+	property := uint32(0)
+	for {
+		resp, err := runCommand(rw, tpm2.CmdGetCapability, nil, nil, tpm2.CapabilityCommands, property, uint32(256))
+		if err != nil {
+			return nil, fmt.Errorf("getting TPM commands: %w", err)
+		}
+		var moreData bool
+		var capability tpm2.Capability
+		var count uint32
+		read, err := tpmutil.Unpack(resp, &moreData, &capability, &count)
+		if err != nil {
+			return nil, fmt.Errorf("decoding TPM commands: %w", err)
+		}
+		if uint64(len(resp)-read) < 4*uint64(count) {
+			return nil, fmt.Errorf("decoding TPM commands: response too short for %d commands", count)
+		}
+		attrs := make([]uint32, count)
+		if _, err = tpmutil.Unpack(resp[read:], &attrs); err != nil {
+			return nil, fmt.Errorf("decoding TPM commands: %w", err)
+		}
+		for _, attr := range attrs {
+			cmd := tpmutil.Command(attr & ccIndexMask)
+			if attr&ccVendor != 0 {
+				cmd |= ccVendor
+			}
+			commands[cmd] = attr
+		}
+		if !moreData || len(attrs) == 0 {
+			return commands, nil
+		}
+		property = uint32(attrs[len(attrs)-1]&ccIndexMask) + 1
+	}
+}
+
+// NewConn returns a new connection to the TPM, for use by a single goroutine.
+func (s *SharedTPM) NewConn() *SharedConn {
+	return &SharedConn{tpm: s, handles: map[tpmutil.Handle]*sharedHandle{}}
+}
+
+// Close closes the underlying TPM, if it implements io.Closer. The
+// connections must be closed first.
+func (s *SharedTPM) Close() error {
+	if closer, ok := s.rw.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// A transient object or session owned by a SharedConn.
+type sharedHandle struct {
+	// The TPM's handle, or 0 if the context is saved.
+	handle  tpmutil.Handle
+	context []byte
+}
+
+// SharedConn is a connection to a SharedTPM. Each command (written with a
+// single Write, as done by go-tpm) is run while no other connection uses the
+// TPM, and its response is kept for the next calls to Read. A SharedConn is
+// not safe for concurrent use.
+type SharedConn struct {
+	tpm     *SharedTPM
+	resp    bytes.Reader
+	closed  bool
+	handles map[tpmutil.Handle]*sharedHandle
+}
+
+// Write runs a command. Commands using a transient object or session of
+// another connection fail without being sent to the TPM.
+func (c *SharedConn) Write(cmd []byte) (int, error) {
+	if c.closed {
+		return 0, errors.New("connection to the shared TPM is closed")
+	}
+	c.tpm.mu.Lock()
+	defer c.tpm.mu.Unlock()
+
+	resp, err := c.run(append([]byte(nil), cmd...))
+	if c.tpm.multiplex {
+		if saveErr := c.saveAll(); err == nil {
+			err = saveErr
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	c.resp.Reset(resp)
+	return len(cmd), nil
+}
+
+// Read reads the response to the last command.
+func (c *SharedConn) Read(p []byte) (int, error) {
+	return c.resp.Read(p)
+}
+
+func (c *SharedConn) run(cmd []byte) ([]byte, error) {
+	if len(cmd) < headerSize {
+		return nil, fmt.Errorf("TPM command too short (%d bytes)", len(cmd))
+	}
+	tag := tpmutil.Tag(binary.BigEndian.Uint16(cmd))
+	code := tpmutil.Command(binary.BigEndian.Uint32(cmd[6:headerSize]))
+	attrs, ok := c.tpm.commands[code]
+	if !ok {
+		// The TPM rejects unknown commands, without looking at handles.
+		return c.tpm.execute(cmd)
+	}
+
+	if code == tpm2.CmdFlushContext {
+		return c.flushContext(cmd)
+	}
+	numHandles := int(attrs >> ccHandlesShift & ccHandlesMask)
+	offset := headerSize
+	for i := 0; i < numHandles; i++ {
+		if err := c.useHandle(cmd, offset); err != nil {
+			return nil, err
+		}
+		offset += 4
+	}
+
+	// Sessions which are flushed by the TPM after a successful command.
+	var flushed []tpmutil.Handle
+	if tag == tpm2.TagSessions {
+		if len(cmd) < offset+4 {
+			return nil, errors.New("TPM command too short for its authorization area")
+		}
+		end := offset + 4 + int(binary.BigEndian.Uint32(cmd[offset:]))
+		if end > len(cmd) {
+			return nil, errors.New("TPM command too short for its authorization area")
+		}
+		for offset += 4; offset < end; {
+			handle, continueSession, size, err := parseAuthCommand(cmd[offset:end])
+			if err != nil {
+				return nil, err
+			}
+			if handle != tpm2.HandlePasswordSession {
+				if err = c.useHandle(cmd, offset); err != nil {
+					return nil, err
+				}
+				if !continueSession {
+					flushed = append(flushed, handle)
+				}
+			}
+			offset += size
+		}
+	}
+
+	resp, err := c.tpm.execute(cmd)
+	if err != nil || responseCode(resp) != tpmutil.RCSuccess {
+		return resp, err
+	}
+	for _, handle := range flushed {
+		c.release(handle)
+	}
+	if attrs&ccRHandle != 0 && len(resp) >= headerSize+4 {
+		handle := tpmutil.Handle(binary.BigEndian.Uint32(resp[headerSize:]))
+		if err = c.track(resp, handle); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// parseAuthCommand parses the TPMS_AUTH_COMMAND at the start of auth,
+// returning its session handle, whether the session continues after the
+// command, and its size.
+func parseAuthCommand(auth []byte) (handle tpmutil.Handle, continueSession bool, size int, err error) {
+	var nonce, hmac tpmutil.U16Bytes
+	var attrs tpm2.SessionAttributes
+	buf := bytes.NewBuffer(auth)
+	if err = tpmutil.UnpackBuf(buf, &handle, &nonce, &attrs, &hmac); err != nil {
+		return 0, false, 0, fmt.Errorf("decoding command authorization: %w", err)
+	}
+	return handle, attrs&tpm2.AttrContinueSession != 0, len(auth) - buf.Len(), nil
+}
+
+// useHandle checks that the handle at offset in cmd can be used by the
+// connection, loading its context if needed, and replaces it with the TPM's
+// handle.
+func (c *SharedConn) useHandle(cmd []byte, offset int) error {
+	if len(cmd) < offset+4 {
+		return errors.New("TPM command too short for its handles")
+	}
+	handle := tpmutil.Handle(binary.BigEndian.Uint32(cmd[offset:]))
+	if !isTracked(handle) {
+		return nil
+	}
+	owned, err := c.owned(handle)
+	if err != nil {
+		return err
+	}
+	if owned.handle == 0 {
+		if owned.handle, err = tpm2.ContextLoad(c.tpm.rw, owned.context); err != nil {
+			return fmt.Errorf("loading context of handle 0x%x: %w", handle, tpmError(err))
+		}
+		owned.context = nil
+	}
+	binary.BigEndian.PutUint32(cmd[offset:], uint32(owned.handle))
+	return nil
+}
+
+func (c *SharedConn) owned(handle tpmutil.Handle) (*sharedHandle, error) {
+	if owned, ok := c.handles[handle]; ok {
+		return owned, nil
+	}
+	if _, ok := c.tpm.owners[handle]; ok {
+		return nil, fmt.Errorf("handle 0x%x belongs to another connection to the shared TPM", handle)
+	}
+	return nil, fmt.Errorf("handle 0x%x was not created by this connection to the shared TPM", handle)
+}
+
+// flushContext runs TPM2_FlushContext, which has its handle as a parameter.
+func (c *SharedConn) flushContext(cmd []byte) ([]byte, error) {
+	if len(cmd) < headerSize+4 {
+		return nil, errors.New("TPM command too short for its parameters")
+	}
+	handle := tpmutil.Handle(binary.BigEndian.Uint32(cmd[headerSize:]))
+	if !isTracked(handle) {
+		return c.tpm.execute(cmd)
+	}
+	owned, err := c.owned(handle)
+	if err != nil {
+		return nil, err
+	}
+	if owned.handle == 0 && handleType(handle) == htTransient {
+		// A saved object context only exists outside the TPM.
+		c.release(handle)
+		return successResponse, nil
+	}
+	if owned.handle != 0 {
+		binary.BigEndian.PutUint32(cmd[headerSize:], uint32(owned.handle))
+	}
+	resp, err := c.tpm.execute(cmd)
+	if err == nil && responseCode(resp) == tpmutil.RCSuccess {
+		c.release(handle)
+	}
+	return resp, err
+}
+
+// track records the transient object or session created by a command,
+// replacing the handle in resp with a virtual handle if the TPM is
+// multiplexed.
+func (c *SharedConn) track(resp []byte, handle tpmutil.Handle) error {
+	if !isTracked(handle) {
+		return nil
+	}
+	owned := &sharedHandle{handle: handle}
+	if previous, ok := c.tpm.owners[handle]; ok {
+		// The TPM reused the handle of a session which it flushed.
+		delete(previous.handles, handle)
+	}
+	if c.tpm.multiplex && handleType(handle) == htTransient {
+		virtual, err := c.tpm.allocateVirtual()
+		if err != nil {
+			tpm2.FlushContext(c.tpm.rw, handle)
+			return err
+		}
+		binary.BigEndian.PutUint32(resp[headerSize:], uint32(virtual))
+		handle = virtual
+	}
+	c.handles[handle] = owned
+	c.tpm.owners[handle] = c
+	return nil
+}
+
+func (s *SharedTPM) allocateVirtual() (tpmutil.Handle, error) {
+	for i := 0; i <= int(lastVirtualHandle-firstVirtualHandle); i++ {
+		handle := s.nextVirtual
+		if s.nextVirtual++; s.nextVirtual > lastVirtualHandle {
+			s.nextVirtual = firstVirtualHandle
+		}
+		if _, ok := s.owners[handle]; !ok {
+			return handle, nil
+		}
+	}
+	return 0, errors.New("no virtual handles left in the shared TPM")
+}
+
+func (c *SharedConn) release(handle tpmutil.Handle) {
+	delete(c.handles, handle)
+	delete(c.tpm.owners, handle)
+}
+
+// saveAll saves the contexts of the connection's loaded objects and sessions,
+// so that they do not use the TPM's memory while other connections run
+// commands.
+func (c *SharedConn) saveAll() error {
+	var errs []error
+	for handle, owned := range c.handles {
+		if owned.handle == 0 {
+			continue
+		}
+		context, err := tpm2.ContextSave(c.tpm.rw, owned.handle)
+		if err != nil {
+			if handleType(handle) != htTransient {
+				// The session was flushed by the TPM, for example because
+				// it was bound to a command which failed.
+				c.release(handle)
+				continue
+			}
+			errs = append(errs, fmt.Errorf("saving context of handle 0x%x: %w", handle, tpmError(err)))
+			continue
+		}
+		// Saving a session removes it from the TPM's memory, but objects
+		// must also be flushed.
+		if handleType(handle) == htTransient {
+			if err = tpm2.FlushContext(c.tpm.rw, owned.handle); err != nil {
+				errs = append(errs, fmt.Errorf("flushing handle 0x%x: %w", handle, tpmError(err)))
+			}
+		}
+		owned.handle, owned.context = 0, context
+	}
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// Close flushes the transient objects and sessions owned by the connection.
+// The connection cannot be used afterwards.
+func (c *SharedConn) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	c.tpm.mu.Lock()
+	defer c.tpm.mu.Unlock()
+	var firstErr error
+	for handle, owned := range c.handles {
+		c.release(handle)
+		if owned.handle == 0 && handleType(handle) == htTransient {
+			continue
+		}
+		tpmHandle := owned.handle
+		if tpmHandle == 0 {
+			tpmHandle = handle
+		}
+		if err := tpm2.FlushContext(c.tpm.rw, tpmHandle); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("flushing handle 0x%x: %w", handle, tpmError(err))
+		}
+	}
+	return firstErr
+}
+
+// EventLog returns the event log of the underlying TPM (see GetEventLog).
+func (c *SharedConn) EventLog() ([]byte, error) {
+	return GetEventLog(c.tpm.rw)
+}
+
+// IMALog returns the IMA log of the underlying TPM (see GetIMALog).
+func (c *SharedConn) IMALog() ([]byte, error) {
+	return GetIMALog(c.tpm.rw)
+}
+
+// HierarchyAuth returns the hierarchy authorization values of the underlying
+// TPM (see HierarchyAuthGetter).
+func (c *SharedConn) HierarchyAuth() HierarchyAuth {
+	if getter, ok := c.tpm.rw.(HierarchyAuthGetter); ok {
+		return getter.HierarchyAuth()
+	}
+	return HierarchyAuth{}
+}
+
+// execute sends a command to the TPM and returns its response.
+func (s *SharedTPM) execute(cmd []byte) ([]byte, error) {
+	if _, err := s.rw.Write(cmd); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, maxTPMResponse)
+	n, err := s.rw.Read(resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp[:n], nil
+}
+
+func responseCode(resp []byte) tpmutil.ResponseCode {
+	if len(resp) < headerSize {
+		return tpmutil.ResponseCode(0xFFFFFFFF)
+	}
+	return tpmutil.ResponseCode(binary.BigEndian.Uint32(resp[6:headerSize]))
+}
+
+func handleType(handle tpmutil.Handle) byte {
+	return byte(handle >> 24)
+}
+
+// isTracked reports whether handle is a transient object or session.
+func isTracked(handle tpmutil.Handle) bool {
+	switch handleType(handle) {
+	case htHMACSession, htPolicySession, htTransient:
+		return true
+	}
+	return false
+}
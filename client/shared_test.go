@@ -0,0 +1,163 @@
+package client_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestSharedTPMConcurrent(t *testing.T) {
+	for _, multiplex := range []bool{false, true} {
+		t.Run(fmt.Sprintf("Multiplex=%v", multiplex), func(t *testing.T) {
+			rwc := test.GetTPM(t)
+			defer client.CheckedClose(t, rwc)
+			shared, err := client.NewSharedTPM(rwc, client.SharedTPMOpts{Multiplex: multiplex})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Only a few keys fit in the simulator's memory at once, unless
+			// the TPM is multiplexed.
+			goroutines, seal := 3, false
+			if multiplex {
+				goroutines, seal = 8, true
+			}
+			errs := make(chan error, goroutines)
+			var wg sync.WaitGroup
+			for i := 0; i < goroutines; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errs <- useSharedTPM(shared.NewConn(), seal)
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				if err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+// Create a key on conn and use it for some signatures, also sealing and
+// unsealing data if seal is set.
+func useSharedTPM(conn *client.SharedConn, seal bool) error {
+	defer conn.Close()
+	key, err := client.NewKey(conn, tpm2.HandleOwner, client.AKTemplateECC())
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+	for i := 0; i < 4; i++ {
+		if _, err = key.SignData([]byte("data")); err != nil {
+			return fmt.Errorf("signing: %w", err)
+		}
+	}
+	if !seal {
+		return nil
+	}
+
+	srk, err := client.NewKey(conn, tpm2.HandleOwner, client.SRKTemplateECC())
+	if err != nil {
+		return err
+	}
+	defer srk.Close()
+	for i := 0; i < 4; i++ {
+		sealed, err := srk.Seal([]byte("secret"), nil)
+		if err != nil {
+			return fmt.Errorf("sealing: %w", err)
+		}
+		if _, err = srk.Unseal(sealed, nil); err != nil {
+			return fmt.Errorf("unsealing: %w", err)
+		}
+	}
+	return nil
+}
+
+func TestSharedTPMOwnership(t *testing.T) {
+	for _, multiplex := range []bool{false, true} {
+		t.Run(fmt.Sprintf("Multiplex=%v", multiplex), func(t *testing.T) {
+			rwc := test.GetTPM(t)
+			defer client.CheckedClose(t, rwc)
+			shared, err := client.NewSharedTPM(rwc, client.SharedTPMOpts{Multiplex: multiplex})
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn := shared.NewConn()
+			defer conn.Close()
+			other := shared.NewConn()
+			defer other.Close()
+
+			key, err := client.NewKey(conn, tpm2.HandleOwner, client.AKTemplateECC())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer key.Close()
+			if _, _, _, err = tpm2.ReadPublic(conn, key.Handle()); err != nil {
+				t.Errorf("ReadPublic failed on the key's connection: %v", err)
+			}
+			if _, _, _, err = tpm2.ReadPublic(other, key.Handle()); err == nil {
+				t.Error("ReadPublic should fail on another connection")
+			}
+			if err = tpm2.FlushContext(other, key.Handle()); err == nil {
+				t.Error("FlushContext should fail on another connection")
+			}
+
+			session, _, err := tpm2.StartAuthSession(conn, tpm2.HandleNull, tpm2.HandleNull,
+				make([]byte, 16), nil, tpm2.SessionPolicy, tpm2.AlgNull, tpm2.AlgSHA256)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tpm2.FlushContext(conn, session)
+			if _, err = tpm2.PolicyGetDigest(other, session); err == nil {
+				t.Error("PolicyGetDigest should fail on another connection")
+			}
+			digest := sha256.Sum256([]byte("data"))
+			if _, err = tpm2.SignWithSession(other, session, key.Handle(), "", digest[:], nil, nil); err == nil {
+				t.Error("signing with the session of another connection should fail")
+			}
+			otherKey, err := client.NewKey(other, tpm2.HandleOwner, client.AKTemplateECC())
+			if err != nil {
+				t.Fatalf("creating a key on another connection failed: %v", err)
+			}
+			otherKey.Close()
+		})
+	}
+}
+
+func TestSharedConnClose(t *testing.T) {
+	for _, multiplex := range []bool{false, true} {
+		t.Run(fmt.Sprintf("Multiplex=%v", multiplex), func(t *testing.T) {
+			rwc := test.GetTPM(t)
+			defer client.CheckedClose(t, rwc)
+			shared, err := client.NewSharedTPM(rwc, client.SharedTPMOpts{Multiplex: multiplex})
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn := shared.NewConn()
+			// Leak a key and a session, which are flushed by Close.
+			if _, err = client.NewKey(conn, tpm2.HandleOwner, client.AKTemplateECC()); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err = tpm2.StartAuthSession(conn, tpm2.HandleNull, tpm2.HandleNull,
+				make([]byte, 16), nil, tpm2.SessionHMAC, tpm2.AlgNull, tpm2.AlgSHA256); err != nil {
+				t.Fatal(err)
+			}
+			if err = conn.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err = tpm2.GetRandom(conn, 16); err == nil {
+				t.Error("using a closed connection should fail")
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"crypto"
+	"crypto/ecdsa"
 	"crypto/rsa"
 	"encoding/asn1"
 	"fmt"
@@ -9,9 +10,15 @@ import (
 	"math/big"
 	"sync"
 
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
 	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
 )
 
+// AlgECSchnorr is the TPM_ALG_ECSCHNORR signing scheme, which is not defined
+// by go-tpm.
+const AlgECSchnorr tpm2.Algorithm = 0x001C
+
 // Global mutex to protect against concurrent TPM access.
 var signerMutex sync.Mutex
 
@@ -30,10 +37,13 @@ func (signer *tpmSigner) Public() crypto.PublicKey {
 // The opts hash function must also match the keys scheme (or be nil).
 // Concurrent use of Sign is thread safe, but it is not safe to access the TPM
 // from other sources while Sign is executing.
-// For RSAPSS signatures, you cannot specify custom salt lengths. The salt
-// length will be (keyBits/8) - digestSize - 2, unless that is less than the
-// digestSize in which case, saltLen will be digestSize. The only normal case
-// where saltLen is not digestSize is when using 1024 keyBits with SHA512.
+// For RSAPSS signatures, the salt length is chosen by the TPM. The salt
+// length will be digestSize, unless that is more than (keyBits/8) - digestSize
+// - 2, in which case saltLen will be (keyBits/8) - digestSize - 2. The only
+// normal case where saltLen is not digestSize is when using 1024 keyBits with
+// SHA512. The PSSOptions salt length can be rsa.PSSSaltLengthAuto or saltLen
+// itself, and rsa.PSSSaltLengthEqualsHash (as used by crypto/x509 and
+// crypto/tls) is accepted when saltLen is digestSize.
 func (signer *tpmSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) (signature []byte, err error) {
 	if pssOpts, ok := opts.(*rsa.PSSOptions); ok {
 		if signer.Key.pubArea.RSAParameters == nil {
@@ -42,15 +52,23 @@ func (signer *tpmSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts
 		if signer.Key.pubArea.RSAParameters.Sign.Alg != tpm2.AlgRSAPSS {
 			return nil, fmt.Errorf("invalid options: PSSOptions cannot be used with signing alg: %v", signer.Key.pubArea.RSAParameters.Sign.Alg)
 		}
-		if pssOpts.SaltLength != rsa.PSSSaltLengthAuto {
-			return nil, fmt.Errorf("salt length must be rsa.PSSSaltLengthAuto")
+		keyBits := signer.Key.pubArea.RSAParameters.KeyBits
+		saltLen := pssSaltLength(keyBits, signer.Hash)
+		switch pssOpts.SaltLength {
+		case rsa.PSSSaltLengthAuto, saltLen:
+		case rsa.PSSSaltLengthEqualsHash:
+			if saltLen != signer.Hash.Size() {
+				return nil, fmt.Errorf("salt length must be rsa.PSSSaltLengthAuto or %d for %d bit keys with %v", saltLen, keyBits, signer.Hash)
+			}
+		default:
+			return nil, fmt.Errorf("salt length must be rsa.PSSSaltLengthAuto, rsa.PSSSaltLengthEqualsHash or %d, the TPM's salt length", saltLen)
 		}
 	}
 	if opts != nil && opts.HashFunc() != signer.Hash {
 		return nil, fmt.Errorf("hash algorithm: got %v, want %v", opts.HashFunc(), signer.Hash)
 	}
 	if len(digest) != signer.Hash.Size() {
-		return nil, fmt.Errorf("digest length: got %d, want %d", digest, signer.Hash.Size())
+		return nil, fmt.Errorf("digest length: got %d, want %d", len(digest), signer.Hash.Size())
 	}
 
 	signerMutex.Lock()
@@ -61,13 +79,22 @@ func (signer *tpmSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts
 		return nil, err
 	}
 
-	sig, err := tpm2.SignWithSession(signer.Key.rw, auth.Session, signer.Key.handle, "", digest, nil, nil)
+	sig, err := signer.Key.sign(auth, digest, nil, nil)
 	if err != nil {
 		return nil, err
 	}
 	return getSignature(sig)
 }
 
+// pssSaltLength returns the salt length of the TPM's RSAPSS signatures.
+func pssSaltLength(keyBits uint16, hash crypto.Hash) int {
+	saltLen := hash.Size()
+	if maxSaltLen := int(keyBits)/8 - hash.Size() - 2; maxSaltLen < saltLen {
+		saltLen = maxSaltLen
+	}
+	return saltLen
+}
+
 // GetSigner returns a crypto.Signer wrapping the loaded TPM Key.
 // Concurrent use of one or more Signers is thread safe, but it is not safe to
 // access the TPM from other sources while using a Signer.
@@ -91,30 +118,95 @@ func (k *Key) GetSigner() (crypto.Signer, error) {
 
 // SignData signs a data buffer with a TPM loaded key. Unlike GetSigner, this
 // method works with restricted and unrestricted keys. If this method is called
-// on a restriced key, the TPM itself will hash the provided data, failing the
-// signing operation if the data begins with TPM_GENERATED_VALUE.
+// on a restriced key, the TPM itself will hash the provided data (with a hash
+// sequence for data larger than 1024 bytes), failing the signing operation if
+// the data begins with TPM_GENERATED_VALUE.
+//
+// Besides RSASSA, RSAPSS and ECDSA, keys signing with ECSchnorr or SM2 can be
+// used. SM2 signatures are over the data prefixed by Z, using the default user
+// ID (see notinternal.SM2Digest). ECDSA, ECSchnorr and SM2 signatures are
+// ASN.1 encoded (as R and S), as in crypto/ecdsa.
 func (k *Key) SignData(data []byte) ([]byte, error) {
-	hashAlg, err := getSigningHashAlg(k)
+	sig, err := k.signData(data)
+	if err != nil {
+		return nil, err
+	}
+	return getSignature(sig)
+}
+
+// SignDataWithScheme is like SignData, but signs with the given scheme rather
+// than the key's scheme. Keys created without a scheme (for example, with
+// KeyOpts.SignScheme set to tpm2.AlgNull) can sign with any scheme matching
+// their type: RSASSA or RSAPSS for RSA keys, and ECDSA, ECSchnorr or SM2 for
+// ECC keys. Keys created with a scheme can only sign with that scheme.
+//
+// If the hash of the scheme is unset, it defaults to the hash of the key's
+// scheme, or (for keys without a scheme) to the hash matching the curve, or
+// SHA256 for RSA keys. ECDAA is not supported: it needs keys created with the
+// ECDAA scheme, whose count go-tpm does not encode correctly.
+func (k *Key) SignDataWithScheme(data []byte, scheme tpm2.SigScheme) ([]byte, error) {
+	sig, err := k.signDataWithScheme(data, &scheme)
 	if err != nil {
 		return nil, err
 	}
+	return getSignature(sig)
+}
+
+func (k *Key) signData(data []byte) (*tpm2.Signature, error) {
+	return k.signDataWithScheme(data, nil)
+}
+
+// signDataWithScheme signs data with the scheme, or with the key's scheme if
+// the scheme is nil.
+func (k *Key) signDataWithScheme(data []byte, scheme *tpm2.SigScheme) (*tpm2.Signature, error) {
+	keyScheme, err := getKeySigningScheme(k)
+	if err != nil {
+		return nil, err
+	}
+	if scheme == nil {
+		if keyScheme == nil {
+			return nil, fmt.Errorf("key has no signing scheme, one must be specified")
+		}
+		scheme = keyScheme
+	}
+	scheme = &tpm2.SigScheme{Alg: scheme.Alg, Hash: scheme.Hash}
+	if scheme.Hash == 0 {
+		if keyScheme != nil {
+			scheme.Hash = keyScheme.Hash
+		} else if k.pubArea.Type == tpm2.AlgECC {
+			scheme.Hash = curveHashes[k.pubArea.ECCParameters.CurveID]
+		} else {
+			scheme.Hash = tpm2.AlgSHA256
+		}
+	}
+	if err := checkSigningScheme(k.pubArea.Type, scheme.Alg); err != nil {
+		return nil, err
+	}
+	hashAlg := scheme.Hash
+	if scheme.Alg == AlgSM2 {
+		// SM2 signs the digest of Z (derived from the public key and the
+		// default user ID) followed by the data.
+		ecdsaPub, ok := k.pubKey.(*ecdsa.PublicKey)
+		if !ok || ecdsaPub.Curve != notinternal.SM2P256() {
+			return nil, fmt.Errorf("SM2 signatures require a key on the SM2 curve")
+		}
+		data = append(notinternal.SM2Z(ecdsaPub, ""), data...)
+	}
 
 	var digest []byte
 	var ticket *tpm2.Ticket
 	if k.hasAttribute(tpm2.FlagRestricted) {
-		// Restricted keys can only sign data hashed by the TPM. We use the
-		// owner hierarchy for the Ticket, but any non-Null hierarchy would do.
-		digest, ticket, err = tpm2.Hash(k.rw, hashAlg, data, tpm2.HandleOwner)
-		if err != nil {
+		// Restricted keys can only sign data hashed by the TPM.
+		if digest, ticket, err = hashWithTicket(k.rw, hashAlg, data); err != nil {
 			return nil, err
 		}
 	} else {
 		// Unrestricted keys can sign any digest, no need for TPM hashing.
-		hash, err := hashAlg.Hash()
+		newHash, err := notinternal.NewHash(hashAlg)
 		if err != nil {
 			return nil, err
 		}
-		hasher := hash.New()
+		hasher := newHash()
 		hasher.Write(data)
 		digest = hasher.Sum(nil)
 	}
@@ -123,37 +215,141 @@ func (k *Key) SignData(data []byte) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	sig, err := tpm2.SignWithSession(k.rw, auth.Session, k.handle, "", digest, ticket, nil)
+	return k.sign(auth, digest, ticket, scheme)
+}
+
+// hashWithTicket hashes data in the TPM, returning the digest and a Ticket
+// allowing a restricted key to sign it. Data too large for TPM2_Hash is hashed
+// with a hash sequence. We use the owner hierarchy for the Ticket, but any
+// non-Null hierarchy would do.
+func hashWithTicket(rw io.ReadWriter, hashAlg tpm2.Algorithm, data []byte) ([]byte, *tpm2.Ticket, error) {
+	if len(data) <= maxBufferSize {
+		digest, ticket, err := tpm2.Hash(rw, hashAlg, data, tpm2.HandleOwner)
+		if err != nil {
+			return nil, nil, tpmError(err)
+		}
+		return digest, ticket, nil
+	}
+	handle, err := tpm2.HashSequenceStart(rw, "", hashAlg)
 	if err != nil {
-		return nil, err
+		return nil, nil, fmt.Errorf("TPM2_HashSequenceStart failed: %w", tpmError(err))
 	}
-	return getSignature(sig)
+	for len(data) > maxBufferSize {
+		if err = tpm2.SequenceUpdate(rw, "", handle, data[:maxBufferSize]); err != nil {
+			tpm2.FlushContext(rw, handle)
+			return nil, nil, fmt.Errorf("TPM2_SequenceUpdate failed: %w", tpmError(err))
+		}
+		data = data[maxBufferSize:]
+	}
+	// The TPM flushes the sequence once it is successfully completed.
+	digest, ticket, err := tpm2.SequenceComplete(rw, "", handle, tpm2.HandleOwner, data)
+	if err != nil {
+		tpm2.FlushContext(rw, handle)
+		return nil, nil, fmt.Errorf("TPM2_SequenceComplete failed: %w", tpmError(err))
+	}
+	return digest, ticket, nil
+}
+
+// sign runs TPM2_Sign with the scheme (or the key's scheme if nil), using the
+// encryption session (if present) to encrypt the digest.
+func (k *Key) sign(auth tpm2.AuthCommand, digest []byte, ticket *tpm2.Ticket, scheme *tpm2.SigScheme) (*tpm2.Signature, error) {
+	if ticket == nil {
+		ticket = &tpm2.Ticket{Type: tpm2.TagHashCheck, Hierarchy: tpm2.HandleNull}
+	}
+	params := []interface{}{tpmutil.U16Bytes(digest), encodeSigScheme(scheme), *ticket}
+	var resp []byte
+	var err error
+	if k.extraSession == nil {
+		resp, err = runCommand(k.rw, tpm2.CmdSign, []tpmutil.Handle{k.handle}, []tpm2.AuthCommand{auth}, params...)
+	} else {
+		var name []byte
+		if name, err = k.name.Digest.Encode(); err != nil {
+			return nil, err
+		}
+		resp, err = k.extraSession.run(tpm2.CmdSign, []tpmutil.Handle{k.handle}, [][]byte{name}, []tpm2.AuthCommand{auth}, true, false, params...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("TPM2_Sign failed: %w", err)
+	}
+	return notinternal.DecodeSignature(resp)
+}
+
+// encodeSigScheme encodes a TPMT_SIG_SCHEME (without a count), as the
+// encoding of tpm2.SigScheme is not exported.
+func encodeSigScheme(scheme *tpm2.SigScheme) tpmutil.RawBytes {
+	if scheme == nil || scheme.Alg.IsNull() {
+		buf, _ := tpmutil.Pack(tpm2.AlgNull)
+		return buf
+	}
+	buf, _ := tpmutil.Pack(scheme.Alg, scheme.Hash)
+	return buf
 }
 
 func getSigningHashAlg(k *Key) (tpm2.Algorithm, error) {
-	if !k.hasAttribute(tpm2.FlagSign) {
-		return tpm2.AlgNull, fmt.Errorf("non-signing key used with signing operation")
+	sigScheme, err := getSigningScheme(k)
+	if err != nil {
+		return tpm2.AlgNull, err
 	}
+	return sigScheme.Hash, nil
+}
 
-	var sigScheme *tpm2.SigScheme
-	switch k.pubArea.Type {
-	case tpm2.AlgRSA:
-		sigScheme = k.pubArea.RSAParameters.Sign
-	case tpm2.AlgECC:
-		sigScheme = k.pubArea.ECCParameters.Sign
-	default:
-		return tpm2.AlgNull, fmt.Errorf("unsupported key type: %v", k.pubArea.Type)
+// checkAttestationKey checks that the key can sign attestations (such as
+// quotes) which notinternal can verify, with any of the schemes allowed by
+// checkSigningScheme.
+func checkAttestationKey(k *Key) error {
+	sigScheme, err := getKeySigningScheme(k)
+	if err != nil {
+		return err
 	}
+	if sigScheme == nil {
+		return fmt.Errorf("unsupported null signing scheme")
+	}
+	return checkSigningScheme(k.pubArea.Type, sigScheme.Alg)
+}
 
+// getSigningScheme returns the signing scheme of the key, which must be
+// RSASSA, RSAPSS or ECDSA.
+func getSigningScheme(k *Key) (*tpm2.SigScheme, error) {
+	sigScheme, err := getKeySigningScheme(k)
+	if err != nil {
+		return nil, err
+	}
 	if sigScheme == nil {
-		return tpm2.AlgNull, fmt.Errorf("unsupported null signing scheme")
+		return nil, fmt.Errorf("unsupported null signing scheme")
 	}
 	switch sigScheme.Alg {
 	case tpm2.AlgRSAPSS, tpm2.AlgRSASSA, tpm2.AlgECDSA:
-		return sigScheme.Hash, nil
+		return sigScheme, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %v", sigScheme.Alg)
+	}
+}
+
+// getKeySigningScheme returns the signing scheme of an RSA or ECC signing key,
+// or nil if the key has no scheme.
+func getKeySigningScheme(k *Key) (*tpm2.SigScheme, error) {
+	if !k.hasAttribute(tpm2.FlagSign) {
+		return nil, fmt.Errorf("non-signing key used with signing operation")
+	}
+	switch k.pubArea.Type {
+	case tpm2.AlgRSA:
+		return k.pubArea.RSAParameters.Sign, nil
+	case tpm2.AlgECC:
+		return k.pubArea.ECCParameters.Sign, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %v", k.pubArea.Type)
+	}
+}
+
+// checkSigningScheme checks that keys of the type can sign with the scheme.
+func checkSigningScheme(keyType, alg tpm2.Algorithm) error {
+	switch {
+	case keyType == tpm2.AlgRSA && (alg == tpm2.AlgRSASSA || alg == tpm2.AlgRSAPSS):
+	case keyType == tpm2.AlgECC && (alg == tpm2.AlgECDSA || alg == AlgECSchnorr || alg == AlgSM2):
 	default:
-		return tpm2.AlgNull, fmt.Errorf("unsupported signing algorithm: %v", sigScheme.Alg)
+		return fmt.Errorf("unsupported signing algorithm for %v keys: %v", keyType, alg)
 	}
+	return nil
 }
 
 func getSignature(sig *tpm2.Signature) ([]byte, error) {
@@ -162,7 +358,7 @@ func getSignature(sig *tpm2.Signature) ([]byte, error) {
 		return sig.RSA.Signature, nil
 	case tpm2.AlgRSAPSS:
 		return sig.RSA.Signature, nil
-	case tpm2.AlgECDSA:
+	case tpm2.AlgECDSA, AlgECSchnorr, AlgSM2:
 		sigStruct := struct{ R, S *big.Int }{sig.ECC.R, sig.ECC.S}
 		return asn1.Marshal(sigStruct)
 	default:
@@ -3,14 +3,18 @@ package client_test
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha1"
 	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/asn1"
 	"math/big"
 	"testing"
 
 	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
 	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
 	"github.com/google/go-tpm/tpm2"
 )
@@ -222,6 +226,9 @@ func TestSignPSS(t *testing.T) {
 		{"RSA-SHA384", &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto, Hash: crypto.SHA384}, templatePSS(tpm2.AlgSHA384), 1024, 48},
 		{"RSA-SHA512", &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto, Hash: crypto.SHA512}, templatePSS(tpm2.AlgSHA512), 1024, 62},
 		{"RSA-SHA512", &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto, Hash: crypto.SHA512}, templatePSS(tpm2.AlgSHA512), 2048, 64},
+		{"RSA-SHA256", &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}, templatePSS(tpm2.AlgSHA256), 2048, 32},
+		{"RSA-SHA384", &rsa.PSSOptions{SaltLength: 48, Hash: crypto.SHA384}, templatePSS(tpm2.AlgSHA384), 2048, 48},
+		{"RSA-SHA512", &rsa.PSSOptions{SaltLength: 62, Hash: crypto.SHA512}, templatePSS(tpm2.AlgSHA512), 1024, 62},
 	}
 
 	for _, k := range keys {
@@ -258,6 +265,94 @@ func TestSignPSS(t *testing.T) {
 			if err != nil {
 				t.Error(err)
 			}
+			if pssOpts, ok := k.opts.(*rsa.PSSOptions); ok && pssOpts.SaltLength > 0 {
+				err = rsa.VerifyPSS(signer.Public().(*rsa.PublicKey), k.opts.HashFunc(), digest[:], sig, pssOpts)
+				if err != nil {
+					t.Errorf("signature does not have a salt length of %d: %v", pssOpts.SaltLength, err)
+				}
+			}
+		})
+	}
+}
+
+// Signers must be usable with the standard library's x509 package.
+func TestSignerCertificateRequest(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	keys := []struct {
+		name     string
+		template tpm2.Public
+	}{
+		{"RSA-SSA", templateSSA(tpm2.AlgSHA256)},
+		{"RSA-PSS", templatePSS(tpm2.AlgSHA256)},
+		{"ECC", templateECC(tpm2.AlgSHA256)},
+	}
+
+	for _, k := range keys {
+		t.Run(k.name, func(t *testing.T) {
+			key, err := client.NewKey(rwc, tpm2.HandleEndorsement, k.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer key.Close()
+
+			signer, err := key.GetSigner()
+			if err != nil {
+				t.Fatal(err)
+			}
+			template := &x509.CertificateRequest{Subject: pkix.Name{CommonName: "TPM Key"}}
+			if k.template.Type == tpm2.AlgRSA && k.template.RSAParameters.Sign.Alg == tpm2.AlgRSAPSS {
+				template.SignatureAlgorithm = x509.SHA256WithRSAPSS
+			}
+			der, err := x509.CreateCertificateRequest(rand.Reader, template, signer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			csr, err := x509.ParseCertificateRequest(der)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = csr.CheckSignature(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// Signing fails with a PSS salt length the TPM does not use.
+func TestFailSignPSSSaltLength(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	tests := []struct {
+		name    string
+		keyBits uint16
+		opts    *rsa.PSSOptions
+	}{
+		{"OtherLength", 2048, &rsa.PSSOptions{SaltLength: 20, Hash: crypto.SHA256}},
+		{"EqualsHashTooLong", 1024, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA512}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hashAlg, err := tpm2.HashToAlgorithm(test.opts.Hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			template := templatePSS(hashAlg)
+			template.RSAParameters.KeyBits = test.keyBits
+			key, err := client.NewKey(rwc, tpm2.HandleEndorsement, template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer key.Close()
+
+			signer, err := key.GetSigner()
+			if err != nil {
+				t.Fatal(err)
+			}
+			digest := make([]byte, test.opts.Hash.Size())
+			if _, err = signer.Sign(nil, digest, test.opts); err == nil {
+				t.Error("expected signing to fail")
+			}
 		})
 	}
 }
@@ -315,3 +410,150 @@ func TestFailGetSignerNullScheme(t *testing.T) {
 		t.Error("expected failure when calling GetSigner")
 	}
 }
+
+// verifySchnorr verifies an ASN.1 encoded ECSchnorr signature, as returned by
+// Key.SignData.
+func verifySchnorr(pubKey crypto.PublicKey, hash crypto.Hash, digest, sig []byte) bool {
+	var sigStruct struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(sig, &sigStruct); err != nil {
+		return false
+	}
+	return notinternal.VerifyECSchnorr(pubKey.(*ecdsa.PublicKey), hash, digest, sigStruct.R, sigStruct.S)
+}
+
+func TestSignDataWithScheme(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	attrs := tpm2.FlagSign | tpm2.FlagFixedTPM | tpm2.FlagFixedParent |
+		tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth
+	data := []byte("data")
+	tests := []struct {
+		name      string
+		keyScheme *tpm2.SigScheme
+		scheme    *tpm2.SigScheme
+		hash      crypto.Hash
+		verify    func(crypto.PublicKey, crypto.Hash, []byte, []byte) bool
+	}{
+		{"ECDSA", nil, nil, crypto.SHA256, verifyECC},
+		{"ECDSA-SHA384", &tpm2.SigScheme{Alg: tpm2.AlgECDSA, Hash: tpm2.AlgSHA384}, nil, crypto.SHA384, verifyECC},
+		{"ECSchnorr", &tpm2.SigScheme{Alg: client.AlgECSchnorr}, nil, crypto.SHA256, verifySchnorr},
+		{"ECSchnorrSameScheme", &tpm2.SigScheme{Alg: client.AlgECSchnorr}, &tpm2.SigScheme{Alg: client.AlgECSchnorr},
+			crypto.SHA256, verifySchnorr},
+		{"NullSchemeECDSA", &tpm2.SigScheme{Alg: tpm2.AlgNull}, &tpm2.SigScheme{Alg: tpm2.AlgECDSA},
+			crypto.SHA256, verifyECC},
+		{"NullSchemeECSchnorr", &tpm2.SigScheme{Alg: tpm2.AlgNull},
+			&tpm2.SigScheme{Alg: client.AlgECSchnorr, Hash: tpm2.AlgSHA384}, crypto.SHA384, verifySchnorr},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key, err := client.NewKeyWithOpts(rwc, tpm2.HandleOwner, client.KeyOpts{
+				Algorithm:  tpm2.AlgECC,
+				Attributes: attrs,
+				SignScheme: test.keyScheme,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer key.Close()
+
+			var sig []byte
+			if test.scheme == nil {
+				sig, err = key.SignData(data)
+			} else {
+				sig, err = key.SignDataWithScheme(data, *test.scheme)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			hasher := test.hash.New()
+			hasher.Write(data)
+			if !test.verify(key.PublicKey(), test.hash, hasher.Sum(nil), sig) {
+				t.Error("signature does not verify")
+			}
+		})
+	}
+}
+
+func TestFailSignDataWithScheme(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	attrs := tpm2.FlagSign | tpm2.FlagFixedTPM | tpm2.FlagFixedParent |
+		tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth
+	tests := []struct {
+		name      string
+		keyScheme *tpm2.SigScheme
+		scheme    *tpm2.SigScheme
+	}{
+		{"OtherScheme", nil, &tpm2.SigScheme{Alg: client.AlgECSchnorr}},
+		{"OtherHash", nil, &tpm2.SigScheme{Alg: tpm2.AlgECDSA, Hash: tpm2.AlgSHA384}},
+		{"RSAScheme", &tpm2.SigScheme{Alg: tpm2.AlgNull}, &tpm2.SigScheme{Alg: tpm2.AlgRSASSA}},
+		{"ECDAA", &tpm2.SigScheme{Alg: tpm2.AlgNull}, &tpm2.SigScheme{Alg: tpm2.AlgECDAA}},
+		{"NullScheme", &tpm2.SigScheme{Alg: tpm2.AlgNull}, nil},
+		{"SM2OnNISTCurve", &tpm2.SigScheme{Alg: tpm2.AlgNull}, &tpm2.SigScheme{Alg: client.AlgSM2, Hash: client.AlgSM3}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key, err := client.NewKeyWithOpts(rwc, tpm2.HandleOwner, client.KeyOpts{
+				Algorithm:  tpm2.AlgECC,
+				Attributes: attrs,
+				SignScheme: test.keyScheme,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer key.Close()
+
+			if test.scheme == nil {
+				_, err = key.SignData([]byte("data"))
+			} else {
+				_, err = key.SignDataWithScheme([]byte("data"), *test.scheme)
+			}
+			if err == nil {
+				t.Error("expected signing to fail")
+			}
+		})
+	}
+}
+
+func TestSignSM2(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	test.SkipOnUnsupportedAlg(t, rwc, client.AlgSM2)
+
+	templates := []struct {
+		name     string
+		template tpm2.Public
+	}{
+		{"Restricted", client.AKTemplateSM2()},
+		{"Unrestricted", func() tpm2.Public {
+			template := client.AKTemplateSM2()
+			template.Attributes &^= tpm2.FlagRestricted
+			return template
+		}()},
+	}
+	data := []byte("data")
+	for _, tmpl := range templates {
+		t.Run(tmpl.name, func(t *testing.T) {
+			key, err := client.NewKey(rwc, tpm2.HandleOwner, tmpl.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer key.Close()
+
+			sig, err := key.SignData(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var rs struct{ R, S *big.Int }
+			if _, err := asn1.Unmarshal(sig, &rs); err != nil {
+				t.Fatal(err)
+			}
+			pub := key.PublicKey().(*ecdsa.PublicKey)
+			if !notinternal.VerifySM2(pub, notinternal.SM2Digest(pub, "", data), rs.R, rs.S) {
+				t.Error("SM2 signature does not verify")
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package client
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+const (
+	streamKeySize   = 32
+	streamChunkSize = 64 * 1024
+	// Maximum chunk size accepted when unsealing, to bound memory usage.
+	maxStreamChunkSize = 16 * 1024 * 1024
+)
+
+// SealStream encrypts all data read from src with a newly generated
+// AES-256-GCM data key, writing the ciphertext to dst. Only the data key is
+// sealed to the TPM (as in Seal), so the size of the data is not limited by the
+// TPM. The returned SealedBytes contains the sealed data key and the envelope
+// needed to decrypt dst with UnsealStream().
+func (k *Key) SealStream(dst io.Writer, src io.Reader, opts SealOpts) (*pb.SealedBytes, error) {
+	dataKey := make([]byte, streamKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	aead, err := newStreamAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	envelope := &pb.StreamEnvelope{
+		Nonce:     make([]byte, aead.NonceSize()),
+		ChunkSize: streamChunkSize,
+	}
+	if _, err = io.ReadFull(rand.Reader, envelope.Nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sb, err := k.Seal(dataKey, opts)
+	if err != nil {
+		return nil, err
+	}
+	sb.Stream = envelope
+	if err = notinternal.SetBlobChecksum(sb); err != nil {
+		return nil, err
+	}
+
+	in := bufio.NewReaderSize(src, streamChunkSize)
+	chunk := make([]byte, streamChunkSize)
+	for i := uint64(0); ; i++ {
+		n, err := io.ReadFull(in, chunk)
+		final := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !final {
+			return nil, fmt.Errorf("failed to read data: %w", err)
+		}
+		if !final {
+			if _, err = in.Peek(1); err == io.EOF {
+				final = true
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to read data: %w", err)
+			}
+		}
+		ciphertext := aead.Seal(nil, chunkNonce(envelope.Nonce, i), chunk[:n], chunkAdditionalData(final))
+		if _, err = dst.Write(ciphertext); err != nil {
+			return nil, fmt.Errorf("failed to write encrypted data: %w", err)
+		}
+		if final {
+			return sb, nil
+		}
+	}
+}
+
+// UnsealStream reverses the process of SealStream(), unsealing the data key
+// and decrypting the ciphertext read from src to dst. Optionally, a CertifyOpt
+// can be passed (as in Unseal).
+//
+// Each chunk is authenticated before being written to dst, but as data is
+// written as it is decrypted, dst may have been partially written when an
+// error is returned (for example, if src was truncated).
+func (k *Key) UnsealStream(dst io.Writer, src io.Reader, in *pb.SealedBytes, opts CertifyOpts) error {
+	envelope := in.GetStream()
+	if envelope == nil {
+		return errors.New("sealed data does not contain a stream envelope")
+	}
+	if envelope.GetChunkSize() == 0 || envelope.GetChunkSize() > maxStreamChunkSize {
+		return fmt.Errorf("invalid stream chunk size %d", envelope.GetChunkSize())
+	}
+	dataKey, err := k.Unseal(in, opts)
+	if err != nil {
+		return err
+	}
+	aead, err := newStreamAEAD(dataKey)
+	if err != nil {
+		return err
+	}
+	if len(envelope.GetNonce()) != aead.NonceSize() {
+		return fmt.Errorf("invalid stream nonce size %d", len(envelope.GetNonce()))
+	}
+
+	chunkSize := int(envelope.GetChunkSize()) + aead.Overhead()
+	r := bufio.NewReaderSize(src, chunkSize)
+	chunk := make([]byte, chunkSize)
+	for i := uint64(0); ; i++ {
+		n, err := io.ReadFull(r, chunk)
+		final := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !final {
+			return fmt.Errorf("failed to read encrypted data: %w", err)
+		}
+		if !final {
+			if _, err = r.Peek(1); err == io.EOF {
+				final = true
+			} else if err != nil {
+				return fmt.Errorf("failed to read encrypted data: %w", err)
+			}
+		}
+		plaintext, err := aead.Open(chunk[:0], chunkNonce(envelope.GetNonce(), i), chunk[:n], chunkAdditionalData(final))
+		if err != nil {
+			return fmt.Errorf("failed to decrypt chunk %d: %w", i, err)
+		}
+		if _, err = dst.Write(plaintext); err != nil {
+			return fmt.Errorf("failed to write decrypted data: %w", err)
+		}
+		if final {
+			return nil
+		}
+	}
+}
+
+func newStreamAEAD(dataKey []byte) (cipher.AEAD, error) {
+	if len(dataKey) != streamKeySize {
+		return nil, fmt.Errorf("invalid data key size %d", len(dataKey))
+	}
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce XORs the chunk index into the last 8 bytes of the base nonce.
+func chunkNonce(base []byte, index uint64) []byte {
+	nonce := append([]byte(nil), base...)
+	counter := nonce[len(nonce)-8:]
+	binary.BigEndian.PutUint64(counter, binary.BigEndian.Uint64(counter)^index)
+	return nonce
+}
+
+// chunkAdditionalData marks the final chunk, so truncation of the stream at a
+// chunk boundary is detected.
+func chunkAdditionalData(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
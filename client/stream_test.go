@@ -0,0 +1,69 @@
+package client_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestSealStream(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	srk, err := client.StorageRootKeyECC(rwc)
+	if err != nil {
+		t.Fatalf("can't create srk from template: %v", err)
+	}
+	defer srk.Close()
+
+	// Sizes around the 64KiB chunk boundary
+	for _, size := range []int{0, 1, 64 * 1024, 64*1024 + 1, 300 * 1024} {
+		secret := make([]byte, size)
+		if _, err := rand.Read(secret); err != nil {
+			t.Fatal(err)
+		}
+		sel := tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{7}}
+		var ciphertext bytes.Buffer
+		sealed, err := srk.SealStream(&ciphertext, bytes.NewReader(secret), client.SealCurrent{PCRSelection: sel})
+		if err != nil {
+			t.Fatalf("failed to seal %d bytes: %v", size, err)
+		}
+
+		var plaintext bytes.Buffer
+		if err = srk.UnsealStream(&plaintext, bytes.NewReader(ciphertext.Bytes()), sealed, nil); err != nil {
+			t.Fatalf("failed to unseal %d bytes: %v", size, err)
+		}
+		if !bytes.Equal(plaintext.Bytes(), secret) {
+			t.Errorf("unsealed %d bytes not equal to secret", size)
+		}
+
+		truncated := ciphertext.Bytes()[:ciphertext.Len()-1]
+		if err = srk.UnsealStream(&bytes.Buffer{}, bytes.NewReader(truncated), sealed, nil); err == nil {
+			t.Errorf("unsealing truncated stream of %d bytes should fail", size)
+		}
+	}
+}
+
+func TestUnsealStreamWithoutEnvelope(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	srk, err := client.StorageRootKeyRSA(rwc)
+	if err != nil {
+		t.Fatalf("can't create srk from template: %v", err)
+	}
+	defer srk.Close()
+
+	sealed, err := srk.Seal([]byte("not a stream"), nil)
+	if err != nil {
+		t.Fatalf("failed to seal: %v", err)
+	}
+	if err = srk.UnsealStream(&bytes.Buffer{}, &bytes.Buffer{}, sealed, nil); err == nil {
+		t.Error("expected failure unsealing a non-stream sealed blob")
+	}
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// The largest input accepted by a single TPM2_EncryptDecrypt2 (or TPM2_HMAC or
+// TPM2_SequenceUpdate) command on all TPMs (the minimum size of
+// TPM2B_MAX_BUFFER).
+const maxBufferSize = 1024
+
+// EncryptSymmetric encrypts data with a symmetric key (such as one imported
+// with ImportKey), using the key's mode and the provided IV. The IV must be
+// the size of the cipher's block.
+func (k *Key) EncryptSymmetric(iv, data []byte) ([]byte, error) {
+	return k.encryptDecrypt(false, iv, data)
+}
+
+// DecryptSymmetric decrypts data encrypted with EncryptSymmetric, using the
+// same symmetric key and IV.
+func (k *Key) DecryptSymmetric(iv, data []byte) ([]byte, error) {
+	return k.encryptDecrypt(true, iv, data)
+}
+
+func (k *Key) encryptDecrypt(decrypt bool, iv, data []byte) ([]byte, error) {
+	if k.pubArea.Type != tpm2.AlgSymCipher {
+		return nil, fmt.Errorf("unsupported key type: %v", k.pubArea.Type)
+	}
+	out := []byte{}
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > maxBufferSize {
+			chunk = chunk[:maxBufferSize]
+		}
+		data = data[len(chunk):]
+
+		auth, err := k.session.Auth()
+		if err != nil {
+			return nil, err
+		}
+		// Mode AlgNull uses the mode of the key.
+		resp, err := runCommand(k.rw, tpm2.CmdEncryptDecrypt2, []tpmutil.Handle{k.handle}, []tpm2.AuthCommand{auth},
+			tpmutil.U16Bytes(chunk), decrypt, tpm2.AlgNull, tpmutil.U16Bytes(iv))
+		if err != nil {
+			return nil, fmt.Errorf("TPM2_EncryptDecrypt2 failed: %w", err)
+		}
+		var outData, ivOut tpmutil.U16Bytes
+		if _, err = tpmutil.Unpack(resp, &outData, &ivOut); err != nil {
+			return nil, fmt.Errorf("decoding TPM2_EncryptDecrypt2 response: %w", err)
+		}
+		out = append(out, outData...)
+		iv = ivOut
+	}
+	return out, nil
+}
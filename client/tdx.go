@@ -0,0 +1,69 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// The configfs-tsm interface for TEE attestation reports, from
+// Documentation/ABI/testing/configfs-tsm in the Linux kernel.
+const (
+	tsmReportDir   = "/sys/kernel/config/tsm/report"
+	tsmTdxProvider = "tdx_guest"
+)
+
+// GetTdxQuote requests a TDX quote with the given report data (see
+// TEEReportData) from the TDX quoting enclave. This is only supported in Linux
+// TDX guests with the configfs-tsm interface (/sys/kernel/config/tsm/report).
+func GetTdxQuote(reportData []byte) ([]byte, error) {
+	if len(reportData) != TEEReportDataSize {
+		return nil, fmt.Errorf("TDX report data must be %d bytes, got %d", TEEReportDataSize, len(reportData))
+	}
+	entry, err := ioutil.TempDir(tsmReportDir, "gotpm")
+	if err != nil {
+		return nil, fmt.Errorf("configfs-tsm is not available: %w", err)
+	}
+	defer os.Remove(entry)
+
+	provider, err := ioutil.ReadFile(filepath.Join(entry, "provider"))
+	if err != nil {
+		return nil, err
+	}
+	if p := string(bytes.TrimSpace(provider)); p != tsmTdxProvider {
+		return nil, fmt.Errorf("not in a TDX guest (TSM provider %q)", p)
+	}
+	inblob, err := os.OpenFile(filepath.Join(entry, "inblob"), os.O_WRONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	_, err = inblob.Write(reportData)
+	if closeErr := inblob.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write report data: %w", err)
+	}
+
+	// Each write to the entry increments its generation, so the quote is only
+	// for our report data if the generation did not change while reading it.
+	generation, err := ioutil.ReadFile(filepath.Join(entry, "generation"))
+	if err != nil {
+		return nil, err
+	}
+	quote, err := ioutil.ReadFile(filepath.Join(entry, "outblob"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get TDX quote: %w", err)
+	}
+	after, err := ioutil.ReadFile(filepath.Join(entry, "generation"))
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(generation, after) {
+		return nil, errors.New("the report data was concurrently modified")
+	}
+	return quote, nil
+}
@@ -0,0 +1,21 @@
+package client
+
+import "crypto/sha512"
+
+// TEEReportDataSize is the size of the data included in a TEE attestation (the
+// REPORT_DATA of an SEV-SNP attestation report or of a TDX quote).
+const TEEReportDataSize = 64
+
+// TEEReportData returns the report data binding a TEE attestation (such as an
+// SEV-SNP attestation report or a TDX quote) to a TPM attestation: the SHA-512
+// digest of the nonce followed by the AK public area (encoded as a
+// TPMT_PUBLIC, as in Attestation.AkPub). As the quotes contain the same nonce,
+// the TEE attestation and the quotes must have been generated for the same
+// verifier challenge, and the AK must belong to the guest which requested the
+// TEE attestation.
+func TEEReportData(nonce []byte, akPub []byte) []byte {
+	hash := sha512.New()
+	hash.Write(nonce)
+	hash.Write(akPub)
+	return hash.Sum(nil)
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"crypto/sha256"
+	"fmt"
 
 	"github.com/google/go-tpm/tpm2"
 	"github.com/google/go-tpm/tpmutil"
@@ -48,14 +49,78 @@ func defaultRSAParams() *tpm2.RSAParams {
 }
 
 func defaultECCParams() *tpm2.ECCParams {
+	params, err := eccParams(tpm2.CurveNISTP256)
+	if err != nil {
+		panic(err)
+	}
+	return params
+}
+
+// The ShangMi algorithms, required by TPMs sold in China, which are not
+// defined by go-tpm: the SM2 signing scheme (on tpm2.CurveSM2P256), the SM3
+// hash and the SM4 block cipher.
+const (
+	AlgSM3 tpm2.Algorithm = 0x0012
+	AlgSM4 tpm2.Algorithm = 0x0013
+	AlgSM2 tpm2.Algorithm = 0x001B
+)
+
+// The size (in bytes) of a coordinate for each supported curve.
+var curveSizes = map[tpm2.EllipticCurve]int{
+	tpm2.CurveNISTP256: 32,
+	tpm2.CurveNISTP384: 48,
+	tpm2.CurveNISTP521: 66,
+	tpm2.CurveSM2P256:  32,
+}
+
+// The signing hash algorithm matching the strength of each curve.
+var curveHashes = map[tpm2.EllipticCurve]tpm2.Algorithm{
+	tpm2.CurveNISTP256: tpm2.AlgSHA256,
+	tpm2.CurveNISTP384: tpm2.AlgSHA384,
+	tpm2.CurveNISTP521: tpm2.AlgSHA512,
+	tpm2.CurveSM2P256:  AlgSM3,
+}
+
+// curveSigScheme returns the default signing scheme of keys on the curve:
+// SM2 on the SM2 curve, and ECDSA on the NIST curves.
+func curveSigScheme(curve tpm2.EllipticCurve) tpm2.Algorithm {
+	if curve == tpm2.CurveSM2P256 {
+		return AlgSM2
+	}
+	return tpm2.AlgECDSA
+}
+
+// curveSymScheme returns the scheme protecting the children of storage keys on
+// the curve: SM4-128 in CFB mode on the SM2 curve, and AES-128 otherwise.
+func curveSymScheme(curve tpm2.EllipticCurve) *tpm2.SymScheme {
+	if curve == tpm2.CurveSM2P256 {
+		return &tpm2.SymScheme{Alg: AlgSM4, KeyBits: 128, Mode: tpm2.AlgCFB}
+	}
+	return defaultSymScheme()
+}
+
+func eccParams(curve tpm2.EllipticCurve) (*tpm2.ECCParams, error) {
+	size, ok := curveSizes[curve]
+	if !ok {
+		return nil, fmt.Errorf("unsupported ECC curve: %v", curve)
+	}
 	return &tpm2.ECCParams{
-		Symmetric: defaultSymScheme(),
-		CurveID:   tpm2.CurveNISTP256,
+		Symmetric: curveSymScheme(curve),
+		CurveID:   curve,
 		Point: tpm2.ECPoint{
-			XRaw: make([]byte, 32),
-			YRaw: make([]byte, 32),
+			XRaw: make([]byte, size),
+			YRaw: make([]byte, size),
 		},
+	}, nil
+}
+
+// mustTemplate returns the template created for one of the supported curves,
+// which cannot fail.
+func mustTemplate(template tpm2.Public, err error) tpm2.Public {
+	if err != nil {
+		panic(err)
 	}
+	return template
 }
 
 // DefaultEKTemplateRSA returns the default Endorsement Key (EK) template as
@@ -75,13 +140,26 @@ func DefaultEKTemplateRSA() tpm2.Public {
 // specified in Credential_Profile_EK_V2.0, section 2.1.5.2 - authPolicy.
 // https://trustedcomputinggroup.org/wp-content/uploads/Credential_Profile_EK_V2.0_R14_published.pdf
 func DefaultEKTemplateECC() tpm2.Public {
+	return mustTemplate(EKTemplateECCWithCurve(tpm2.CurveNISTP256))
+}
+
+// EKTemplateECCWithCurve returns an Endorsement Key (EK) template identical
+// to DefaultEKTemplateECC, except that the key is created on the provided
+// curve. Supported curves are NIST P-256, P-384, P-521 and SM2 (whose keys
+// protect their children with SM4 rather than AES). An error is returned for
+// any other curve.
+func EKTemplateECCWithCurve(curve tpm2.EllipticCurve) (tpm2.Public, error) {
+	params, err := eccParams(curve)
+	if err != nil {
+		return tpm2.Public{}, err
+	}
 	return tpm2.Public{
 		Type:          tpm2.AlgECC,
 		NameAlg:       tpm2.AlgSHA256,
 		Attributes:    defaultEKAttributes(),
 		AuthPolicy:    defaultEKAuthPolicy(),
-		ECCParameters: defaultECCParams(),
-	}
+		ECCParameters: params,
+	}, nil
 }
 
 // AKTemplateRSA returns a potential Attestation Key (AK) template.
@@ -106,18 +184,38 @@ func AKTemplateRSA() tpm2.Public {
 // This is very similar to DefaultEKTemplateECC, except that this will be a
 // signing key instead of an encrypting key.
 func AKTemplateECC() tpm2.Public {
-	params := defaultECCParams()
+	return mustTemplate(AKTemplateECCWithCurve(tpm2.CurveNISTP256))
+}
+
+// AKTemplateECCWithCurve returns an Attestation Key (AK) template identical to
+// AKTemplateECC, except that the key is created on the provided curve. The
+// ECDSA signing hash is chosen to match the strength of the curve (SHA256 for
+// P-256, SHA384 for P-384, and SHA512 for P-521). Keys on the SM2 curve sign
+// with SM2 using SM3 instead. An error is returned for unsupported curves (see
+// EKTemplateECCWithCurve).
+func AKTemplateECCWithCurve(curve tpm2.EllipticCurve) (tpm2.Public, error) {
+	params, err := eccParams(curve)
+	if err != nil {
+		return tpm2.Public{}, err
+	}
 	params.Symmetric = nil
 	params.Sign = &tpm2.SigScheme{
-		Alg:  tpm2.AlgECDSA,
-		Hash: tpm2.AlgSHA256,
+		Alg:  curveSigScheme(curve),
+		Hash: curveHashes[curve],
 	}
 	return tpm2.Public{
 		Type:          tpm2.AlgECC,
 		NameAlg:       tpm2.AlgSHA256,
 		Attributes:    tpm2.FlagSignerDefault,
 		ECCParameters: params,
-	}
+	}, nil
+}
+
+// AKTemplateSM2 returns an Attestation Key (AK) template for TPMs using the
+// ShangMi algorithms: an SM2 key signing with SM2 using SM3. Its quotes can be
+// verified with the key returned by notinternal.PublicKey.
+func AKTemplateSM2() tpm2.Public {
+	return mustTemplate(AKTemplateECCWithCurve(tpm2.CurveSM2P256))
 }
 
 // SRKTemplateRSA returns a standard Storage Root Key (SRK) template.
@@ -134,10 +232,61 @@ func SRKTemplateRSA() tpm2.Public {
 // SRKTemplateECC returns a standard Storage Root Key (SRK) template.
 // This is based upon the advice in the TCG's TPM v2.0 Provisioning Guidance.
 func SRKTemplateECC() tpm2.Public {
+	return mustTemplate(SRKTemplateECCWithCurve(tpm2.CurveNISTP256))
+}
+
+// SRKTemplateECCWithCurve returns a Storage Root Key (SRK) template identical
+// to SRKTemplateECC, except that the key is created on the provided curve. An
+// error is returned for unsupported curves (see EKTemplateECCWithCurve).
+func SRKTemplateECCWithCurve(curve tpm2.EllipticCurve) (tpm2.Public, error) {
+	params, err := eccParams(curve)
+	if err != nil {
+		return tpm2.Public{}, err
+	}
 	return tpm2.Public{
 		Type:          tpm2.AlgECC,
 		NameAlg:       tpm2.AlgSHA256,
 		Attributes:    defaultSRKAttributes(),
-		ECCParameters: defaultECCParams(),
+		ECCParameters: params,
+	}, nil
+}
+
+// SRKTemplateSM2 returns a Storage Root Key (SRK) template for TPMs using the
+// ShangMi algorithms: an SM2 key protecting its children with SM4-128 in CFB
+// mode.
+func SRKTemplateSM2() tpm2.Public {
+	return mustTemplate(SRKTemplateECCWithCurve(tpm2.CurveSM2P256))
+}
+
+// HMACTemplate returns a template for an HMAC key, using the provided hash
+// algorithm. The key can be used with Key.HMAC and Key.NewHMACSequence.
+func HMACTemplate(hash tpm2.Algorithm) tpm2.Public {
+	return tpm2.Public{
+		Type:    tpm2.AlgKeyedHash,
+		NameAlg: tpm2.AlgSHA256,
+		Attributes: tpm2.FlagSign | tpm2.FlagFixedTPM | tpm2.FlagFixedParent |
+			tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth,
+		KeyedHashParameters: &tpm2.KeyedHashParams{
+			Alg:  tpm2.AlgHMAC,
+			Hash: hash,
+		},
 	}
 }
+
+// ECDHTemplate returns a template for an unrestricted ECC decryption key on the
+// provided curve, which can be used for key agreement with Key.ECDH. An error
+// is returned for unsupported curves (see EKTemplateECCWithCurve).
+func ECDHTemplate(curve tpm2.EllipticCurve) (tpm2.Public, error) {
+	params, err := eccParams(curve)
+	if err != nil {
+		return tpm2.Public{}, err
+	}
+	params.Symmetric = nil
+	return tpm2.Public{
+		Type:    tpm2.AlgECC,
+		NameAlg: tpm2.AlgSHA256,
+		Attributes: tpm2.FlagDecrypt | tpm2.FlagFixedTPM | tpm2.FlagFixedParent |
+			tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth,
+		ECCParameters: params,
+	}, nil
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/google/go-tpm/tpm2"
+)
+
+// The TLS signature schemes matching each supported TPM signing scheme.
+var tlsSignatureSchemes = map[tpm2.Algorithm]map[tpm2.Algorithm]tls.SignatureScheme{
+	tpm2.AlgRSASSA: {
+		tpm2.AlgSHA256: tls.PKCS1WithSHA256,
+		tpm2.AlgSHA384: tls.PKCS1WithSHA384,
+		tpm2.AlgSHA512: tls.PKCS1WithSHA512,
+	},
+	tpm2.AlgRSAPSS: {
+		tpm2.AlgSHA256: tls.PSSWithSHA256,
+		tpm2.AlgSHA384: tls.PSSWithSHA384,
+		tpm2.AlgSHA512: tls.PSSWithSHA512,
+	},
+	tpm2.AlgECDSA: {
+		tpm2.AlgSHA256: tls.ECDSAWithP256AndSHA256,
+		tpm2.AlgSHA384: tls.ECDSAWithP384AndSHA384,
+		tpm2.AlgSHA512: tls.ECDSAWithP521AndSHA512,
+	},
+}
+
+// TLSCertificate returns a tls.Certificate whose private key is the key (see
+// GetSigner), for use in a tls.Config. The certificate chain starts with the
+// key's certificate, which is chain[0] if chain is not empty, or else the
+// certificate set with SetCert. The certificate must match the key.
+//
+// The tls.Certificate can be used for any number of (concurrent) handshakes,
+// until the key is closed. Each handshake signs with the key, so the key must
+// stay loaded in the TPM. As the TPM signs with a single hash, only the
+// matching TLS signature scheme is advertised. RSA keys using RSASSA can only
+// be used with TLS 1.2, as TLS 1.3 requires RSA-PSS (or ECDSA). RSA keys using
+// RSAPSS can be used with TLS 1.3 if the TPM's salt length is the digest size,
+// which is the case for keys of 2048 bits or more (see GetSigner).
+func (k *Key) TLSCertificate(chain []*x509.Certificate) (tls.Certificate, error) {
+	if len(chain) == 0 {
+		if k.cert == nil {
+			return tls.Certificate{}, fmt.Errorf("key has no certificate")
+		}
+		chain = []*x509.Certificate{k.cert}
+	}
+	leafPub, err := x509.MarshalPKIXPublicKey(chain[0].PublicKey)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to marshal certificate public key: %w", err)
+	}
+	keyPub, err := x509.MarshalPKIXPublicKey(k.pubKey)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to marshal key public key: %w", err)
+	}
+	if !bytes.Equal(leafPub, keyPub) {
+		return tls.Certificate{}, fmt.Errorf("certificate public key does not match key")
+	}
+
+	signer, err := k.GetSigner()
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	scheme, err := getSigningScheme(k)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	tlsScheme, ok := tlsSignatureSchemes[scheme.Alg][scheme.Hash]
+	if !ok {
+		return tls.Certificate{}, fmt.Errorf("unsupported signing scheme for TLS: %v with %v", scheme.Alg, scheme.Hash)
+	}
+
+	certificate := tls.Certificate{
+		PrivateKey:                   signer,
+		SupportedSignatureAlgorithms: []tls.SignatureScheme{tlsScheme},
+		Leaf:                         chain[0],
+	}
+	for _, cert := range chain {
+		certificate.Certificate = append(certificate.Certificate, cert.Raw)
+	}
+	return certificate, nil
+}
@@ -0,0 +1,131 @@
+package client_test
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func selfSignedCert(t *testing.T, key *client.Key) *x509.Certificate {
+	t.Helper()
+	signer, err := key.GetSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "tpm.example.com"},
+		DNSNames:     []string{"tpm.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if params := key.PublicArea().RSAParameters; params != nil && params.Sign.Alg == tpm2.AlgRSAPSS {
+		template.SignatureAlgorithm = x509.SHA256WithRSAPSS
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.PublicKey(), signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestTLSCertificate(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	tests := []struct {
+		name       string
+		opts       client.KeyOpts
+		maxVersion uint16
+	}{
+		{"ECC", client.KeyOpts{Algorithm: tpm2.AlgECC}, tls.VersionTLS13},
+		{"ECCP384", client.KeyOpts{Algorithm: tpm2.AlgECC, Curve: tpm2.CurveNISTP384}, tls.VersionTLS13},
+		{"RSA", client.KeyOpts{Algorithm: tpm2.AlgRSA}, tls.VersionTLS12},
+		{"RSAPSS", client.KeyOpts{Algorithm: tpm2.AlgRSA, SignScheme: &tpm2.SigScheme{Alg: tpm2.AlgRSAPSS}}, tls.VersionTLS13},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.opts.Attributes = tpm2.FlagSign | tpm2.FlagFixedTPM | tpm2.FlagFixedParent |
+				tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth
+			key, err := client.NewKeyWithOpts(rwc, tpm2.HandleOwner, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer key.Close()
+			cert := selfSignedCert(t, key)
+			if err = key.SetCert(cert); err != nil {
+				t.Fatal(err)
+			}
+			tlsCert, err := key.TLSCertificate(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			roots := x509.NewCertPool()
+			roots.AddCert(cert)
+			// Run multiple handshakes with the same certificate.
+			for i := 0; i < 2; i++ {
+				serverConn, clientConn := net.Pipe()
+				server := tls.Server(serverConn, &tls.Config{
+					Certificates: []tls.Certificate{tlsCert},
+					MaxVersion:   test.maxVersion,
+				})
+				errs := make(chan error, 1)
+				go func() {
+					errs <- server.Handshake()
+					server.Close()
+				}()
+				client := tls.Client(clientConn, &tls.Config{RootCAs: roots, ServerName: "tpm.example.com"})
+				if err := client.Handshake(); err != nil {
+					t.Fatalf("client handshake failed: %v", err)
+				}
+				client.Close()
+				if err := <-errs; err != nil {
+					t.Fatalf("server handshake failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestTLSCertificateMismatch(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	opts := client.KeyOpts{Algorithm: tpm2.AlgECC, Attributes: tpm2.FlagSign | tpm2.FlagFixedTPM |
+		tpm2.FlagFixedParent | tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth}
+	key, err := client.NewKeyWithOpts(rwc, tpm2.HandleOwner, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer key.Close()
+	if _, err = key.TLSCertificate(nil); err == nil {
+		t.Error("TLSCertificate should fail for a key without a certificate")
+	}
+
+	opts.Algorithm = tpm2.AlgRSA
+	other, err := client.NewKeyWithOpts(rwc, tpm2.HandleOwner, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if _, err = key.TLSCertificate([]*x509.Certificate{selfSignedCert(t, other)}); err == nil {
+		t.Error("TLSCertificate should fail for a certificate of another key")
+	}
+}
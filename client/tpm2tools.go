@@ -0,0 +1,142 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+// The header of the context files of tpm2-tools.
+const (
+	tpm2ToolsContextMagic   uint32 = 0xBADCC0DE
+	tpm2ToolsContextVersion uint32 = 1
+)
+
+// The resource type of keys in the ESYS metadata of saved contexts.
+const esysKeyResource uint32 = 1
+
+// tpmsContext is a TPMS_CONTEXT, as returned by TPM2_ContextSave.
+type tpmsContext struct {
+	Sequence    uint64
+	SavedHandle tpmutil.Handle
+	Hierarchy   tpmutil.Handle
+	Blob        tpmutil.U16Bytes
+}
+
+// SaveTPM2ToolsContext saves the context of a transient key (see ContextSave)
+// as a tpm2-tools context file, which can be used as the context ("-c") of the
+// commands of tpm2-tools 4.0 or later, such as tpm2_sign. As with ContextSave,
+// it can only be loaded into the same TPM, until the TPM is reset.
+func (k *Key) SaveTPM2ToolsContext() ([]byte, error) {
+	saved, err := k.ContextSave()
+	if err != nil {
+		return nil, err
+	}
+	var context tpmsContext
+	if _, err = tpmutil.Unpack(saved, &context.Sequence, &context.SavedHandle, &context.Hierarchy, &context.Blob); err != nil {
+		return nil, fmt.Errorf("decoding key context: %w", err)
+	}
+	name, err := k.name.Encode()
+	if err != nil {
+		return nil, err
+	}
+	pub, err := k.pubArea.Encode()
+	if err != nil {
+		return nil, err
+	}
+	// tpm2-tools saves contexts with ESYS, which wraps the TPM's context blob
+	// with the metadata of the ESYS object (the IESYS_CONTEXT_DATA). As with
+	// ESYS, the size of the metadata is left as zero.
+	esysBlob, err := tpmutil.Pack(uint32(0), context.Blob,
+		uint16(0), k.handle, tpmutil.U16Bytes(name), esysKeyResource, tpmutil.U16Bytes(pub))
+	if err != nil {
+		return nil, err
+	}
+	return tpmutil.Pack(tpm2ToolsContextMagic, tpm2ToolsContextVersion,
+		context.Hierarchy, context.SavedHandle, context.Sequence, tpmutil.U16Bytes(esysBlob))
+}
+
+// LoadTPM2ToolsContext loads a key from a tpm2-tools context file, such as one
+// written by "tpm2_createprimary -c" or "tpm2_load -c", returning the restored
+// Key. Contexts saved by both ESYS (tpm2-tools 4.0 or later) and earlier
+// versions are supported, but not the serialized ESYS objects which tpm2-tools
+// writes for persistent keys (which can be loaded with
+// KeyFromPersistentHandle). As with LoadKeyContext, the key must either have
+// an empty auth policy or the default EK auth policy.
+func LoadTPM2ToolsContext(rw io.ReadWriter, data []byte) (*Key, error) {
+	var magic, version uint32
+	var context tpmsContext
+	if _, err := tpmutil.Unpack(data, &magic, &version, &context.Hierarchy, &context.SavedHandle, &context.Sequence, &context.Blob); err != nil {
+		return nil, fmt.Errorf("decoding tpm2-tools context: %w", err)
+	}
+	if magic != tpm2ToolsContextMagic {
+		return nil, errors.New("not a tpm2-tools context file")
+	}
+	if version != tpm2ToolsContextVersion {
+		return nil, fmt.Errorf("unsupported tpm2-tools context version %d", version)
+	}
+	// The context blobs of the TPM start with their integrity HMAC (a non-empty
+	// TPM2B), while the IESYS_CONTEXT_DATA of ESYS starts with a zero UINT32.
+	if bytes.HasPrefix(context.Blob, []byte{0, 0, 0, 0}) {
+		var reserved uint32
+		var tpmContext tpmutil.U16Bytes
+		if err := tpmutil.UnpackBuf(bytes.NewReader(context.Blob), &reserved, &tpmContext); err != nil {
+			return nil, fmt.Errorf("decoding ESYS context: %w", err)
+		}
+		context.Blob = tpmContext
+	}
+	saved, err := tpmutil.Pack(context.Sequence, context.SavedHandle, context.Hierarchy, context.Blob)
+	if err != nil {
+		return nil, err
+	}
+	return LoadKeyContext(rw, saved)
+}
+
+// ParseTPM2ToolsKeyBlob returns the KeyBlob of the public and private files of
+// a key created by tpm2_create (its "-u" and "-r" outputs, which contain a
+// TPM2B_PUBLIC and a TPM2B_PRIVATE), so that it can be loaded with
+// Key.LoadChild under the parent it was created with.
+func ParseTPM2ToolsKeyBlob(public, private []byte) (*pb.KeyBlob, error) {
+	var pub, priv tpmutil.U16Bytes
+	if err := unpackAll(public, &pub); err != nil {
+		return nil, fmt.Errorf("decoding TPM2B_PUBLIC: %w", err)
+	}
+	if _, err := tpm2.DecodePublic(pub); err != nil {
+		return nil, fmt.Errorf("decoding public area: %w", err)
+	}
+	if err := unpackAll(private, &priv); err != nil {
+		return nil, fmt.Errorf("decoding TPM2B_PRIVATE: %w", err)
+	}
+	return &pb.KeyBlob{PublicArea: pub, PrivateArea: priv}, nil
+}
+
+// TPM2ToolsKeyBlob returns the public and private files of a KeyBlob created by
+// Key.CreateChild, in the format of tpm2_create, so that the key can be loaded
+// with "tpm2_load -u public -r private" under the same parent.
+func TPM2ToolsKeyBlob(blob *pb.KeyBlob) (public, private []byte, err error) {
+	if public, err = tpmutil.Pack(tpmutil.U16Bytes(blob.GetPublicArea())); err != nil {
+		return nil, nil, err
+	}
+	if private, err = tpmutil.Pack(tpmutil.U16Bytes(blob.GetPrivateArea())); err != nil {
+		return nil, nil, err
+	}
+	return public, private, nil
+}
+
+// unpackAll unpacks data into the elements, which must consume all of it.
+func unpackAll(data []byte, elts ...interface{}) error {
+	read, err := tpmutil.Unpack(data, elts...)
+	if err != nil {
+		return err
+	}
+	if read != len(data) {
+		return fmt.Errorf("%d trailing bytes", len(data)-read)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package client_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestTPM2ToolsContext(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ak, err := client.NewKey(rwc, tpm2.HandleOwner, client.AKTemplateECC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ak.SaveTPM2ToolsContext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := ak.Name()
+	ak.Close()
+	if got := binary.BigEndian.Uint32(data); got != 0xBADCC0DE {
+		t.Errorf("got magic 0x%x, want 0xBADCC0DE", got)
+	}
+
+	restored, err := client.LoadTPM2ToolsContext(rwc, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+	if !reflect.DeepEqual(restored.Name(), name) {
+		t.Errorf("restored key has name %v, want %v", restored.Name(), name)
+	}
+	if _, err = restored.Quote(tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{7}}, []byte("nonce")); err != nil {
+		t.Errorf("failed to quote with restored key: %v", err)
+	}
+}
+
+func TestTPM2ToolsContextWithoutESYS(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ak, err := client.NewKey(rwc, tpm2.HandleOwner, client.AKTemplateECC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := ak.ContextSave()
+	ak.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// tpm2-tools 3.x wrote the TPM's context blob as is, after its header.
+	var sequence uint64
+	var savedHandle, hierarchy tpmutil.Handle
+	var blob tpmutil.U16Bytes
+	if _, err = tpmutil.Unpack(saved, &sequence, &savedHandle, &hierarchy, &blob); err != nil {
+		t.Fatal(err)
+	}
+	data, err := tpmutil.Pack(uint32(0xBADCC0DE), uint32(1), hierarchy, savedHandle, sequence, blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := client.LoadTPM2ToolsContext(rwc, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored.Close()
+
+	if _, err = client.LoadTPM2ToolsContext(rwc, saved); err == nil {
+		t.Error("expected loading a context without a tpm2-tools header to fail")
+	}
+}
+
+func TestTPM2ToolsKeyBlob(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	srk, err := client.StorageRootKeyECC(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srk.Close()
+	blob, err := srk.CreateChild(client.KeyOpts{
+		Algorithm: tpm2.AlgECC,
+		Attributes: tpm2.FlagSign | tpm2.FlagFixedTPM | tpm2.FlagFixedParent |
+			tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	public, private, err := client.TPM2ToolsKeyBlob(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := int(binary.BigEndian.Uint16(public)); size != len(blob.GetPublicArea()) {
+		t.Errorf("got TPM2B_PUBLIC size %d, want %d", size, len(blob.GetPublicArea()))
+	}
+	parsed, err := client.ParseTPM2ToolsKeyBlob(public, private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := srk.LoadChild(parsed, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer key.Close()
+	if _, err = key.SignData([]byte("data")); err != nil {
+		t.Errorf("failed to sign with loaded key: %v", err)
+	}
+
+	if _, err = client.ParseTPM2ToolsKeyBlob(blob.GetPublicArea(), private); err == nil {
+		t.Error("expected parsing a public area without a size to fail")
+	}
+	if _, err = client.ParseTPM2ToolsKeyBlob(public, append(private, 0)); err == nil {
+		t.Error("expected parsing a private area with trailing data to fail")
+	}
+	if !bytes.Equal(parsed.GetPrivateArea(), blob.GetPrivateArea()) {
+		t.Error("parsed private area differs from the created one")
+	}
+}
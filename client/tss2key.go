@@ -0,0 +1,155 @@
+package client
+
+import (
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+// TSS2PEMType is the PEM block type of TSS2PrivateKey.
+const TSS2PEMType = "TSS2 PRIVATE KEY"
+
+// The OIDs of the types of TSS2 private keys. Only loadable keys (which are
+// created by the TPM of their parent) are supported, not importable keys or
+// sealed data.
+var (
+	oidLoadableKey   = asn1.ObjectIdentifier{2, 23, 133, 10, 1, 3}
+	oidImportableKey = asn1.ObjectIdentifier{2, 23, 133, 10, 1, 4}
+	oidSealedData    = asn1.ObjectIdentifier{2, 23, 133, 10, 1, 5}
+)
+
+// tss2Key is the ASN.1 TPMKey structure of the "ASN.1 Specification for TPM
+// 2.0 Key Files" draft.
+type tss2Key struct {
+	Type        asn1.ObjectIdentifier
+	EmptyAuth   bool            `asn1:"optional,explicit,tag:0"`
+	Policy      []asn1.RawValue `asn1:"optional,explicit,tag:1"`
+	Secret      []byte          `asn1:"optional,explicit,tag:2"`
+	AuthPolicy  []asn1.RawValue `asn1:"optional,explicit,tag:3"`
+	Description string          `asn1:"optional,explicit,tag:4,utf8"`
+	RSAParent   bool            `asn1:"optional,explicit,tag:5"`
+	Parent      int64
+	PubKey      []byte
+	PrivKey     []byte
+}
+
+// TSS2PrivateKey is a key in the "TSS2 PRIVATE KEY" PEM format of the OpenSSL
+// tpm2 provider, the tpm2-tss-engine and OpenConnect: a child key (see
+// Key.CreateChild) with a reference to its parent. Keys without policies are
+// supported.
+type TSS2PrivateKey struct {
+	// The public and private areas of the key.
+	Blob *pb.KeyBlob
+	// The parent of the key: either a persistent handle, or a hierarchy (such
+	// as tpm2.HandleOwner), meaning the primary storage key of the hierarchy
+	// created from SRKTemplateECC (or SRKTemplateRSA, if RSAParent is set).
+	Parent    tpmutil.Handle
+	RSAParent bool
+	// Whether the key has an empty auth value.
+	EmptyAuth bool
+	// An optional description of the key.
+	Description string
+}
+
+// ParseTSS2PrivateKey parses a PEM encoded "TSS2 PRIVATE KEY", as written by
+// the OpenSSL tpm2 provider, tpm2tss-genkey, or TSS2PrivateKey.Marshal.
+func ParseTSS2PrivateKey(data []byte) (*TSS2PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != TSS2PEMType {
+		return nil, fmt.Errorf("no PEM encoded %q", TSS2PEMType)
+	}
+	var key tss2Key
+	rest, err := asn1.Unmarshal(block.Bytes, &key)
+	if err != nil {
+		return nil, fmt.Errorf("decoding TPMKey: %w", err)
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("trailing data after TPMKey")
+	}
+	switch {
+	case key.Type.Equal(oidImportableKey):
+		return nil, errors.New("importable keys are not supported")
+	case key.Type.Equal(oidSealedData):
+		return nil, errors.New("sealed data is not supported")
+	case !key.Type.Equal(oidLoadableKey):
+		return nil, fmt.Errorf("unknown key type %v", key.Type)
+	}
+	if len(key.Policy) > 0 || len(key.AuthPolicy) > 0 {
+		return nil, errors.New("keys with policies are not supported")
+	}
+	if key.Parent < 0 || key.Parent > 0xffffffff {
+		return nil, fmt.Errorf("invalid parent handle %d", key.Parent)
+	}
+	blob, err := ParseTPM2ToolsKeyBlob(key.PubKey, key.PrivKey)
+	if err != nil {
+		return nil, err
+	}
+	return &TSS2PrivateKey{
+		Blob:        blob,
+		Parent:      tpmutil.Handle(key.Parent),
+		RSAParent:   key.RSAParent,
+		EmptyAuth:   key.EmptyAuth,
+		Description: key.Description,
+	}, nil
+}
+
+// Marshal returns the PEM encoding of the key.
+func (k *TSS2PrivateKey) Marshal() ([]byte, error) {
+	pub, priv, err := TPM2ToolsKeyBlob(k.Blob)
+	if err != nil {
+		return nil, err
+	}
+	der, err := asn1.Marshal(tss2Key{
+		Type:        oidLoadableKey,
+		EmptyAuth:   k.EmptyAuth,
+		Description: k.Description,
+		RSAParent:   k.RSAParent,
+		Parent:      int64(k.Parent),
+		PubKey:      pub,
+		PrivKey:     priv,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: TSS2PEMType, Bytes: der}), nil
+}
+
+// LoadParent returns the parent of the key: the persistent key at Parent, or
+// the SRK of the hierarchy (which must be the owner hierarchy).
+func (k *TSS2PrivateKey) LoadParent(rw io.ReadWriter) (*Key, error) {
+	if isPersistent(k.Parent) {
+		return KeyFromPersistentHandle(rw, k.Parent)
+	}
+	if k.Parent != tpm2.HandleOwner {
+		return nil, fmt.Errorf("unsupported parent 0x%x", k.Parent)
+	}
+	if k.RSAParent {
+		return StorageRootKeyRSA(rw)
+	}
+	return StorageRootKeyECC(rw)
+}
+
+// Load loads the key under its parent (see LoadParent), with the authValue if
+// the key does not have an EmptyAuth. As with Key.LoadChild, the returned key
+// only stays valid as long as the parent is loaded, so the parent is returned
+// too, and both should be closed when no longer needed.
+func (k *TSS2PrivateKey) Load(rw io.ReadWriter, authValue string) (key, parent *Key, err error) {
+	if k.EmptyAuth && authValue != "" {
+		return nil, nil, errors.New("key has an empty auth value")
+	}
+	if parent, err = k.LoadParent(rw); err != nil {
+		return nil, nil, fmt.Errorf("loading parent: %w", err)
+	}
+	if key, err = parent.LoadChild(k.Blob, authValue); err != nil {
+		parent.Close()
+		return nil, nil, err
+	}
+	return key, parent, nil
+}
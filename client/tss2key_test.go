@@ -0,0 +1,153 @@
+package client_test
+
+import (
+	"encoding/asn1"
+	"encoding/pem"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+var tss2ChildOpts = client.KeyOpts{
+	Algorithm: tpm2.AlgECC,
+	Attributes: tpm2.FlagSign | tpm2.FlagFixedTPM | tpm2.FlagFixedParent |
+		tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth,
+}
+
+func TestTSS2PrivateKey(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	tests := []struct {
+		name      string
+		rsaParent bool
+		auth      string
+	}{
+		{"ECCParent", false, ""},
+		{"RSAParent", true, ""},
+		{"Auth", false, "password"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srk, err := client.StorageRootKeyECC(rwc)
+			if tc.rsaParent {
+				srk, err = client.StorageRootKeyRSA(rwc)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer srk.Close()
+			opts := tss2ChildOpts
+			opts.Auth = tc.auth
+			blob, err := srk.CreateChild(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := &client.TSS2PrivateKey{
+				Blob:        blob,
+				Parent:      tpm2.HandleOwner,
+				RSAParent:   tc.rsaParent,
+				EmptyAuth:   tc.auth == "",
+				Description: "test key",
+			}
+			data, err := want.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := client.ParseTSS2PrivateKey(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Blob.GetPublicArea(), blob.GetPublicArea()) {
+				t.Error("parsed key has a different public area")
+			}
+			got.Blob = blob
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got key %+v, want %+v", got, want)
+			}
+
+			key, parent, err := got.Load(rwc, tc.auth)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer parent.Close()
+			defer key.Close()
+			if _, err = key.SignData([]byte("data")); err != nil {
+				t.Errorf("failed to sign with loaded key: %v", err)
+			}
+		})
+	}
+}
+
+func TestTSS2PrivateKeyPersistentParent(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	srk, err := client.StorageRootKeyECC(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srk.Close()
+	blob, err := srk.CreateChild(tss2ChildOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := (&client.TSS2PrivateKey{Blob: blob, Parent: srk.Handle(), EmptyAuth: true}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := client.ParseTSS2PrivateKey(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, parent, err := parsed.Load(rwc, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer parent.Close()
+	key.Close()
+	if parent.Handle() != srk.Handle() {
+		t.Errorf("got parent 0x%x, want 0x%x", parent.Handle(), srk.Handle())
+	}
+}
+
+func TestParseTSS2PrivateKeyUnsupported(t *testing.T) {
+	type tpmPolicy struct {
+		CommandCode   int    `asn1:"explicit,tag:0"`
+		CommandPolicy []byte `asn1:"explicit,tag:1"`
+	}
+	type tpmKey struct {
+		Type    asn1.ObjectIdentifier
+		Policy  []tpmPolicy `asn1:"optional,explicit,tag:1"`
+		Parent  int64
+		PubKey  []byte
+		PrivKey []byte
+	}
+	loadable := asn1.ObjectIdentifier{2, 23, 133, 10, 1, 3}
+	tests := []struct {
+		name string
+		key  tpmKey
+	}{
+		{"Importable", tpmKey{Type: asn1.ObjectIdentifier{2, 23, 133, 10, 1, 4}, Parent: 0x40000001}},
+		{"Sealed", tpmKey{Type: asn1.ObjectIdentifier{2, 23, 133, 10, 1, 5}, Parent: 0x40000001}},
+		{"Policy", tpmKey{Type: loadable, Policy: []tpmPolicy{{0x17f, []byte{0}}}, Parent: 0x40000001}},
+		{"NoBlob", tpmKey{Type: loadable, Parent: 0x40000001}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			der, err := asn1.Marshal(tc.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data := pem.EncodeToMemory(&pem.Block{Type: client.TSS2PEMType, Bytes: der})
+			if _, err = client.ParseTSS2PrivateKey(data); err == nil {
+				t.Error("expected parsing an unsupported key to fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ThalesIgnite/go-tpm-tools/agent"
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	verifierpb "github.com/ThalesIgnite/go-tpm-tools/proto/verifier"
+	"github.com/ThalesIgnite/go-tpm-tools/server"
+	"github.com/ThalesIgnite/go-tpm-tools/verifier"
+)
+
+var (
+	attestIMALog bool
+	attestSevSnp bool
+	attestTdx    bool
+	// The URL of the verifier service to attest with, if any.
+	attestVerifier string
+	attestJWT      bool
+	// The identifier of the secret to get from the verifier, and its file.
+	attestReleaseSecret string
+	attestSecretOutput  string
+)
+
+var attestCmd = &cobra.Command{
+	Use:   "attest",
+	Short: "Create a remote attestation report",
+	Long: `Create a remote attestation report for offline verification
+
+An Attestation Key (AK) is created (using --algo, rsa by default) and used to
+quote all PCR banks, with the --nonce included in each quote. The report also
+contains the AK's public area, the TCG event log, and the EK certificate (if
+present in the TPM's NVDATA, see --platform). The --ima-log flag also includes the IMA
+runtime measurement list. In an AMD SEV-SNP guest, the --sev-snp flag also
+includes an SEV-SNP attestation report, and in an Intel TDX guest, the --tdx
+flag includes a TDX quote, both bound to the nonce and the AK. With
+--derive-nonces, each piece of evidence is bound with its own nonce derived
+from the --nonce, which also binds the event logs to the quotes.
+
+The report is written as a single protobuf, encoded using --format. With
+--eat, the report is instead written as an Entity Attestation Token (EAT): a
+CBOR Web Token containing the nonce and the protobuf, signed by the AK in a
+COSE_Sign1 message, for RATS verifiers. The nonce must then be between 8 and
+64 bytes. Use "gotpm verify" to verify the report.
+
+With --verifier, the evidence is instead sent to the verifier service at its
+URL (see "gotpm verifier"): the nonce is given by a challenge of the verifier,
+and the signed attestation result of the verifier is written (a CWT, or a JWT
+with --jwt). The exchange is retried if the verifier cannot be reached. The
+command fails if the result is not "affirming". With --release-secret, the
+secret of the verifier with the ID is then released to the TPM: the AK
+activates a credential of the verifier with the EK, and the secret is
+decrypted with the EK and written to the --secret-output file.
+
+With --platform, the TPM is the vTPM of a cloud platform: "gce" and "azure"
+use the AK provisioned by the platform (and include its certificate, with its
+intermediate CAs), while "aws" does not include an EK certificate, as the
+NitroTPM of EC2 instances has none. Use the same --platform with "gotpm
+verify".`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if attestVerifier != "" && len(nonce) != 0 {
+			return errors.New("--nonce cannot be used with --verifier")
+		}
+		if attestVerifier == "" && len(nonce) == 0 {
+			return errors.New("a --nonce must be provided")
+		}
+		if attestReleaseSecret != "" && (attestVerifier == "" || attestSecretOutput == "") {
+			return errors.New("--release-secret requires --verifier and --secret-output")
+		}
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+		p, err := getPlatform()
+		if err != nil {
+			return err
+		}
+		config := client.AttestConfig{IMALog: attestIMALog, SevSnp: attestSevSnp, Tdx: attestTdx, DeriveNonces: deriveNonces}
+		if platformAKCerts[p.Name()] {
+			config.CertChainFetcher = http.DefaultClient
+		}
+		if attestVerifier != "" {
+			return attestWithVerifier(rwc, p, config)
+		}
+
+		fmt.Fprintln(debugOutput(), "Loading AK")
+		ak, err := getAK(rwc)
+		if err != nil {
+			return err
+		}
+		defer ak.Close()
+
+		fmt.Fprintln(debugOutput(), "Creating attestation")
+		attestation, err := ak.AttestWithConfig(nonce, config)
+		if err != nil {
+			return fmt.Errorf("creating attestation: %w", err)
+		}
+		if ekCert, err := p.GetEKCert(rwc); err != nil {
+			fmt.Fprintf(debugOutput(), "Not including EK certificate: %v\n", err)
+		} else {
+			attestation.EkCert = ekCert.Raw
+		}
+
+		fmt.Fprintln(debugOutput(), "Writing attestation report")
+		var out []byte
+		if eatReport {
+			signer, err := ak.COSESigner()
+			if err != nil {
+				return err
+			}
+			out, err = signer.SignEAT(attestation, nonce)
+		} else {
+			out, err = marshalProto(attestation)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = dataOutput().Write(out)
+		return err
+	},
+}
+
+func attestWithVerifier(rw io.ReadWriter, p client.Platform, attestConfig client.AttestConfig) error {
+	transport, err := verifier.NewClient(attestVerifier, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(debugOutput(), "Attesting with the verifier")
+	config := agent.Config{NewAK: getAK, Platform: p, AttestConfig: attestConfig, EAT: eatReport}
+	if attestJWT {
+		config.ResultFormat = verifierpb.ResultFormat_JWT
+	}
+	a := agent.New(rw, transport, config)
+	result, err := a.Attest()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(debugOutput(), "Writing attestation result")
+	if _, err = dataOutput().Write(result.Token); err != nil {
+		return err
+	}
+	if result.Status != server.TierAffirming.String() {
+		return fmt.Errorf("attestation result is %q: %s", result.Status, result.Error)
+	}
+	if attestReleaseSecret == "" {
+		return nil
+	}
+	fmt.Fprintf(debugOutput(), "Releasing secret %q\n", attestReleaseSecret)
+	secret, err := a.ReleaseSecret(result, attestReleaseSecret)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(attestSecretOutput, secret, 0600)
+}
+
+func init() {
+	RootCmd.AddCommand(attestCmd)
+	addNonceFlag(attestCmd)
+	addDeriveNoncesFlag(attestCmd)
+	addPublicKeyAlgoFlag(attestCmd)
+	addPlatformFlag(attestCmd)
+	addFormatFlag(attestCmd)
+	addEATFlag(attestCmd)
+	addOutputFlag(attestCmd)
+	attestCmd.PersistentFlags().BoolVar(&attestIMALog, "ima-log", false,
+		"include the IMA runtime measurement list")
+	attestCmd.PersistentFlags().BoolVar(&attestSevSnp, "sev-snp", false,
+		"include an AMD SEV-SNP attestation report")
+	attestCmd.PersistentFlags().BoolVar(&attestTdx, "tdx", false,
+		"include an Intel TDX quote")
+	attestCmd.PersistentFlags().StringVar(&attestVerifier, "verifier", "",
+		"URL of a verifier service to attest with, instead of writing a report")
+	attestCmd.PersistentFlags().BoolVar(&attestJWT, "jwt", false,
+		"with --verifier, get the attestation result as a JWT")
+	attestCmd.PersistentFlags().StringVar(&attestReleaseSecret, "release-secret", "",
+		"with --verifier, the ID of a secret to get from the verifier")
+	attestCmd.PersistentFlags().StringVar(&attestSecretOutput, "secret-output", "",
+		"file to write the --release-secret to")
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+	"github.com/ThalesIgnite/go-tpm-tools/server"
+	"github.com/ThalesIgnite/go-tpm-tools/verifier"
+)
+
+func TestAttest(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+
+	for _, f := range []string{formatBinary, formatText, formatJSON} {
+		for _, algo := range []string{"rsa", "ecc"} {
+			t.Run(f+"-"+algo, func(t *testing.T) {
+				reportFile := makeTempFile(t, nil)
+				defer os.Remove(reportFile)
+
+				RootCmd.SetArgs([]string{"attest", "--nonce", "0123456789abcdef", "--algo", algo, "--format", f, "--output", reportFile})
+				if err := RootCmd.Execute(); err != nil {
+					t.Fatal(err)
+				}
+
+				data, err := ioutil.ReadFile(reportFile)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var attestation pb.Attestation
+				if err = unmarshalProto(data, &attestation); err != nil {
+					t.Fatal(err)
+				}
+				pub, err := tpm2.DecodePublic(attestation.GetAkPub())
+				if err != nil {
+					t.Fatal(err)
+				}
+				akPub, err := pub.Key()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(attestation.GetQuotes()) == 0 {
+					t.Fatal("expected at least one quote")
+				}
+				want := []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+				for _, quote := range attestation.GetQuotes() {
+					if err = server.VerifyQuote(quote, akPub, want); err != nil {
+						t.Error(err)
+					}
+				}
+				if len(attestation.GetEventLog()) == 0 {
+					t.Error("expected an event log")
+				}
+			})
+		}
+	}
+}
+
+func TestAttestNoNonce(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+
+	nonce = nil
+	RootCmd.SetArgs([]string{"attest", "--nonce", ""})
+	if err := RootCmd.Execute(); err == nil {
+		t.Error("expected failure without a nonce")
+	}
+}
+
+func TestAttestVerifier(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+	defer func() { attestVerifier, attestJWT, keyAlgo, nonce = "", false, tpm2.AlgRSA, nil }()
+	defer func() { attestReleaseSecret, attestSecretOutput = "", "" }()
+
+	ak, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trustedAK := ak.PublicKey()
+	ak.Close()
+	resultKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := verifier.NewServer(verifier.Config{
+		Opts:   server.VerifyOpts{TrustedAKs: []crypto.PublicKey{trustedAK}},
+		Signer: resultKey,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(s)
+	defer httpServer.Close()
+
+	nonce = nil
+	resultFile := makeTempFile(t, nil)
+	defer os.Remove(resultFile)
+	RootCmd.SetArgs([]string{"attest", "--verifier", httpServer.URL, "--algo", "ecc", "--output", resultFile})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	token, err := ioutil.ReadFile(resultFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = notinternal.DecodeCOSESign1(token); err != nil {
+		t.Errorf("failed to decode attestation result: %v", err)
+	}
+
+	RootCmd.SetArgs([]string{"attest", "--verifier", httpServer.URL, "--algo", "ecc", "--jwt", "--output", resultFile})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if token, err = ioutil.ReadFile(resultFile); err != nil {
+		t.Fatal(err)
+	}
+	if parts := strings.Split(string(token), "."); len(parts) != 3 {
+		t.Errorf("attestation result is not a JWT: %s", token)
+	}
+
+	RootCmd.SetArgs([]string{"attest", "--verifier", httpServer.URL, "--algo", "ecc", "--release-secret", "disk", "--output", resultFile})
+	if err := RootCmd.Execute(); err == nil {
+		t.Error("expected releasing a secret without --secret-output to fail")
+	}
+	attestReleaseSecret = ""
+
+	// The RSA AK is not trusted by the verifier.
+	RootCmd.SetArgs([]string{"attest", "--verifier", httpServer.URL, "--algo", "rsa", "--output", resultFile})
+	if err := RootCmd.Execute(); err == nil {
+		t.Error("expected attesting with an untrusted AK to fail")
+	}
+}
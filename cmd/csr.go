@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+	"github.com/spf13/cobra"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+)
+
+var (
+	csrCommonName   string
+	csrOrganization []string
+	csrDNSNames     []string
+)
+
+// OID of the X.509 subjectAltName extension.
+var oidSubjectAltName = asn1.ObjectIdentifier{2, 5, 29, 17}
+
+var csrCmd = &cobra.Command{
+	Use:   "csr <endorsement | owner | null>",
+	Short: "Create a certificate signing request for a TPM key",
+	Long: `Create a PEM-formatted PKCS #10 certificate signing request for a TPM key
+
+The CSR is signed by an unrestricted signing key (using --algo, rsa by
+default), created as a primary key in the given hierarchy. As primary keys are
+derived from the hierarchy's seed, the same key is created every time (until
+the hierarchy's seed changes, e.g. on TPM2_Clear for the owner hierarchy). If
+--index is provided, the key's template is read from NVDATA instead.
+
+The subject is set with --common-name and --organization, and DNS names are
+added to the subjectAltName extension with --dns.`,
+	ValidArgs: []string{"endorsement", "owner", "null"},
+	Args:      cobra.ExactValidArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+
+		key, err := getSigningKey(rwc, hierarchyNames[args[0]])
+		if err != nil {
+			return err
+		}
+		defer key.Close()
+
+		subject := pkix.Name{CommonName: csrCommonName, Organization: csrOrganization}
+		var extensions []pkix.Extension
+		if len(csrDNSNames) > 0 {
+			san, err := marshalDNSNames(csrDNSNames)
+			if err != nil {
+				return err
+			}
+			extensions = append(extensions, pkix.Extension{Id: oidSubjectAltName, Value: san})
+		}
+		csr, err := key.CreateCSR(subject, extensions)
+		if err != nil {
+			return err
+		}
+		_, err = dataOutput().Write(csr)
+		return err
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(csrCmd)
+	addIndexFlag(csrCmd)
+	addOutputFlag(csrCmd)
+	addPublicKeyAlgoFlag(csrCmd)
+	csrCmd.PersistentFlags().StringVar(&csrCommonName, "common-name", "",
+		"common name (CN) of the subject")
+	csrCmd.PersistentFlags().StringSliceVar(&csrOrganization, "organization", nil,
+		"organization (O) of the subject, can be repeated")
+	csrCmd.PersistentFlags().StringSliceVar(&csrDNSNames, "dns", nil,
+		"DNS name to include in the subjectAltName, can be repeated")
+}
+
+// Create an unrestricted signing key based on the global flag vars.
+func getSigningKey(rw io.ReadWriter, hierarchy tpmutil.Handle) (*client.Key, error) {
+	fmt.Fprintf(debugOutput(), "Using hierarchy 0x%x\n", hierarchy)
+	if nvIndex != 0 {
+		fmt.Fprintf(debugOutput(), "Reading from NVDATA index %d\n", nvIndex)
+		return client.KeyFromNvIndex(rw, hierarchy, nvIndex)
+	}
+	return client.NewKeyWithOpts(rw, hierarchy, client.KeyOpts{
+		Algorithm: keyAlgo,
+		Attributes: tpm2.FlagSign | tpm2.FlagFixedTPM | tpm2.FlagFixedParent |
+			tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth,
+	})
+}
+
+// Encode DNS names as the GeneralNames of a subjectAltName extension.
+func marshalDNSNames(names []string) ([]byte, error) {
+	var generalNames []asn1.RawValue
+	for _, name := range names {
+		generalNames = append(generalNames, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, Bytes: []byte(name)})
+	}
+	return asn1.Marshal(generalNames)
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestCSR(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+	defer func() { keyAlgo, csrCommonName, csrDNSNames = tpm2.AlgRSA, "", nil }()
+
+	// Slice flags accumulate values across executions, so only the last run
+	// sets --dns.
+	for i, algo := range []string{"rsa", "ecc"} {
+		outFile := makeTempFile(t, nil)
+		defer os.Remove(outFile)
+		args := []string{"csr", "owner", "--algo", algo, "--common-name", "device", "--output", outFile}
+		var want []string
+		if i == 1 {
+			args = append(args, "--dns", "device.example.com,other.example.com")
+			want = []string{"device.example.com", "other.example.com"}
+		}
+		RootCmd.SetArgs(args)
+		if err := RootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadFile(outFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			t.Fatalf("output is not PEM: %s", data)
+		}
+		csr, err := x509.ParseCertificateRequest(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = csr.CheckSignature(); err != nil {
+			t.Errorf("CSR signature is invalid: %v", err)
+		}
+		if csr.Subject.CommonName != "device" {
+			t.Errorf("got common name %q, want %q", csr.Subject.CommonName, "device")
+		}
+		if !reflect.DeepEqual(csr.DNSNames, want) {
+			t.Errorf("got DNS names %v, want %v", csr.DNSNames, want)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/spf13/cobra"
+
+	"github.com/ThalesIgnite/go-tpm-tools/disk"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+var (
+	diskHashAlgo = tpm2.AlgSHA256
+	diskPIN      string
+	// The files of the PCR values predicted after planned updates.
+	diskUpgrades []string
+	// Whether the sealed key is a systemd-cryptenroll token, and its keyslot.
+	diskSystemd bool
+	diskKeyslot int
+)
+
+var diskCmd = &cobra.Command{
+	Use:   "disk",
+	Short: "Seal disk encryption keys to the boot state",
+	Long: `Seal the keys of encrypted volumes to the boot state of the machine
+
+The volume key (such as a LUKS keyfile, of at most 128 bytes) is sealed to
+PCRs 0, 2, 4 and 7 by default (the firmware, option ROMs, boot loader and
+Secure Boot policy), so that it can only be unsealed by this TPM after the
+same boot. For example:
+
+  gotpm disk seal --input volume.key --output volume.sealed
+  gotpm disk unseal --input volume.sealed | cryptsetup open --key-file=- /dev/sda2 root
+
+With --systemd, the sealed key is instead a "systemd-tpm2" LUKS2 token, as
+enrolled by systemd-cryptenroll, so that volumes can be unlocked at boot by
+systemd-cryptsetup (and other initrd tooling supporting these tokens). As
+systemd unlocks the keyslot of the token with the base64 encoding of the key,
+that passphrase must be added to the keyslot:
+
+  base64 -w0 volume.key > passphrase
+  cryptsetup luksAddKey --key-slot 1 /dev/sda2 passphrase
+  gotpm disk seal --systemd --keyslot 1 --input volume.key --output token.json
+  cryptsetup token import --json-file token.json /dev/sda2`,
+	Args: cobra.NoArgs,
+}
+
+var diskSealCmd = &cobra.Command{
+	Use:   "seal",
+	Short: "Seal a volume key to the boot state",
+	Long: `Seal a volume key to the current boot state
+
+The volume key is read from --input and sealed to the current values of the
+--pcrs (0,2,4,7 by default). The sealed key is written to --output, encoded
+using --format.
+
+Before a planned update of the firmware or of the boot loader, the values of
+the PCRs after the update can be given with --upgrade: each file contains a
+pb.PCRs as textproto (as written by "gotpm pcrs read --format textproto"),
+with the PCRs which the update changes. The key can then be unsealed both in
+the current state and after the update (up to 7 upgrades are supported).
+After the update, "gotpm disk reseal" seals it to the new state only.
+
+With --pin, the PIN is also required to unseal the key.
+
+With --systemd, a systemd-cryptenroll token for the --keyslot is written
+instead (which cannot have upgrades).`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := readDiskSealOpts()
+		if err != nil {
+			return err
+		}
+		volumeKey, err := ioutil.ReadAll(dataInput())
+		if err != nil {
+			return err
+		}
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+
+		fmt.Fprintln(debugOutput(), "Sealing volume key")
+		if diskSystemd {
+			token, err := disk.SealSystemd(rwc, volumeKey, diskKeyslot, opts)
+			if err != nil {
+				return err
+			}
+			out, err := json.MarshalIndent(token, "", "  ")
+			if err != nil {
+				return err
+			}
+			_, err = dataOutput().Write(append(out, '\n'))
+			return err
+		}
+		sealed, err := disk.Seal(rwc, volumeKey, opts)
+		if err != nil {
+			return err
+		}
+		return writeSealedVolumeKey(sealed)
+	},
+}
+
+var diskUnsealCmd = &cobra.Command{
+	Use:   "unseal",
+	Short: "Unseal a volume key",
+	Long: `Unseal a volume key sealed with "gotpm disk seal"
+
+The sealed key is read from --input, and the volume key is written to
+--output. Unsealing fails if the PCRs are not in the state the key was sealed
+to (or in the state of one of its upgrades), or without its --pin.
+
+With --systemd, the input is a systemd-cryptenroll token (as exported by
+"cryptsetup token export"), and its secret is written. Tokens with signed PCR
+policies or pcrlock policies are not supported.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if diskSystemd {
+			return unsealSystemdToken()
+		}
+		sealed, err := readSealedVolumeKey()
+		if err != nil {
+			return err
+		}
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+
+		fmt.Fprintln(debugOutput(), "Unsealing volume key")
+		volumeKey, err := disk.Unseal(rwc, sealed, diskPIN)
+		if err != nil {
+			return err
+		}
+		_, err = dataOutput().Write(volumeKey)
+		return err
+	},
+}
+
+func unsealSystemdToken() error {
+	data, err := ioutil.ReadAll(dataInput())
+	if err != nil {
+		return err
+	}
+	token := &disk.SystemdToken{}
+	if err = json.Unmarshal(data, token); err != nil {
+		return fmt.Errorf("parsing systemd token: %w", err)
+	}
+	rwc, err := openTpm()
+	if err != nil {
+		return err
+	}
+	defer rwc.Close()
+
+	fmt.Fprintln(debugOutput(), "Unsealing systemd token")
+	secret, err := disk.UnsealSystemd(rwc, token, diskPIN)
+	if err != nil {
+		return err
+	}
+	_, err = dataOutput().Write(secret)
+	return err
+}
+
+var diskResealCmd = &cobra.Command{
+	Use:   "reseal",
+	Short: "Reseal a volume key to the current boot state",
+	Long: `Reseal a volume key sealed with "gotpm disk seal"
+
+The sealed key is read from --input, unsealed (as by "gotpm disk unseal"),
+and sealed again (as by "gotpm disk seal") to the --pcrs and --upgrade flags,
+with the same --pin. The resealed key is written to --output.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if diskSystemd {
+			return errors.New("systemd tokens cannot be resealed, enroll a new token instead")
+		}
+		opts, err := readDiskSealOpts()
+		if err != nil {
+			return err
+		}
+		sealed, err := readSealedVolumeKey()
+		if err != nil {
+			return err
+		}
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+
+		fmt.Fprintln(debugOutput(), "Resealing volume key")
+		if sealed, err = disk.Reseal(rwc, sealed, diskPIN, opts); err != nil {
+			return err
+		}
+		return writeSealedVolumeKey(sealed)
+	},
+}
+
+// readDiskSealOpts returns the SealOpts of the flags of the seal commands.
+func readDiskSealOpts() (disk.SealOpts, error) {
+	opts := disk.SealOpts{PCRs: pcrs, Bank: diskHashAlgo, PIN: diskPIN}
+	for _, file := range diskUpgrades {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return disk.SealOpts{}, err
+		}
+		upgrade := &pb.PCRs{}
+		if err = unmarshalProtoFormat(data, upgrade, formatText); err != nil {
+			return disk.SealOpts{}, fmt.Errorf("parsing %s: %w", file, err)
+		}
+		opts.Upgrades = append(opts.Upgrades, upgrade)
+	}
+	return opts, nil
+}
+
+func readSealedVolumeKey() (*pb.SealedBytes, error) {
+	data, err := ioutil.ReadAll(dataInput())
+	if err != nil {
+		return nil, err
+	}
+	sealed := &pb.SealedBytes{}
+	if err = unmarshalProtoFormat(data, sealed, sealFormat); err != nil {
+		return nil, err
+	}
+	return sealed, nil
+}
+
+func writeSealedVolumeKey(sealed *pb.SealedBytes) error {
+	out, err := marshalProtoFormat(sealed, sealFormat)
+	if err != nil {
+		return err
+	}
+	_, err = dataOutput().Write(out)
+	return err
+}
+
+func init() {
+	RootCmd.AddCommand(diskCmd)
+	hideHelp(diskCmd)
+	for _, cmd := range []*cobra.Command{diskSealCmd, diskUnsealCmd, diskResealCmd} {
+		diskCmd.AddCommand(cmd)
+		addInputFlag(cmd)
+		addOutputFlag(cmd)
+		addSealFormatFlag(cmd)
+		cmd.PersistentFlags().StringVar(&diskPIN, "pin", "",
+			"PIN required to unseal the volume key (defaults to none)")
+		cmd.PersistentFlags().BoolVar(&diskSystemd, "systemd", false,
+			"use systemd-cryptenroll tokens (JSON) as the sealed key")
+	}
+	diskSealCmd.PersistentFlags().IntVar(&diskKeyslot, "keyslot", 0,
+		"with --systemd, the LUKS2 keyslot of the token")
+	for _, cmd := range []*cobra.Command{diskSealCmd, diskResealCmd} {
+		addPCRsFlag(cmd, &diskHashAlgo)
+		addHashAlgoFlag(cmd, &diskHashAlgo)
+		cmd.PersistentFlags().StringSliceVar(&diskUpgrades, "upgrade", nil,
+			"textproto files of the PCR values predicted after planned updates")
+	}
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/disk"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+func TestDiskSealUnseal(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+	defer func() { pcrs, diskPIN, diskUpgrades = nil, "", nil }()
+
+	// Predict the value of the debug PCR after an update.
+	current, err := client.ReadPCRs(rwc, tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{test.DebugPCR}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	measurement := sha256.Sum256([]byte("new boot loader"))
+	predicted := sha256.Sum256(append(append([]byte{}, current.GetPcrs()[uint32(test.DebugPCR)]...), measurement[:]...))
+	upgrade, err := marshalOptions.Marshal(&pb.PCRs{Hash: pb.HashAlgo_SHA256, Pcrs: map[uint32][]byte{uint32(test.DebugPCR): predicted[:]}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	upgradeFile := makeTempFile(t, upgrade)
+	defer os.Remove(upgradeFile)
+
+	volumeKey := []byte("volume key")
+	keyFile := makeTempFile(t, volumeKey)
+	defer os.Remove(keyFile)
+	sealedFile := makeTempFile(t, nil)
+	defer os.Remove(sealedFile)
+	unsealedFile := makeTempFile(t, nil)
+	defer os.Remove(unsealedFile)
+
+	RootCmd.SetArgs([]string{"disk", "seal", "--quiet", "--input", keyFile, "--output", sealedFile,
+		"--pcrs", strconv.Itoa(test.DebugPCR), "--upgrade", upgradeFile, "--pin", "1234"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	pcrs = nil
+
+	unseal := func(pin string) error {
+		RootCmd.SetArgs([]string{"disk", "unseal", "--quiet", "--input", sealedFile, "--output", unsealedFile, "--pin", pin})
+		if err := RootCmd.Execute(); err != nil {
+			return err
+		}
+		got, err := ioutil.ReadFile(unsealedFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, volumeKey) {
+			t.Errorf("got volume key %q, want %q", got, volumeKey)
+		}
+		return nil
+	}
+	if err := unseal("4321"); err == nil {
+		t.Error("expected unsealing with the wrong PIN to fail")
+	}
+	if err := unseal("1234"); err != nil {
+		t.Errorf("failed to unseal in the current state: %v", err)
+	}
+	if err := tpm2.PCRExtend(rwc, tpmutil.Handle(test.DebugPCR), tpm2.AlgSHA256, measurement[:], ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := unseal("1234"); err != nil {
+		t.Errorf("failed to unseal in the upgraded state: %v", err)
+	}
+
+	// After the update, reseal to the new state only.
+	RootCmd.SetArgs([]string{"disk", "reseal", "--quiet", "--input", sealedFile, "--output", sealedFile,
+		"--pcrs", strconv.Itoa(test.DebugPCR), "--pin", "1234"})
+	diskUpgrades = nil
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	pcrs = nil
+	if err := unseal("1234"); err != nil {
+		t.Errorf("failed to unseal the resealed key: %v", err)
+	}
+}
+
+func TestDiskSystemdToken(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+	defer func() { pcrs, diskPIN, diskSystemd, diskKeyslot = nil, "", false, 0 }()
+
+	volumeKey := []byte("volume key")
+	keyFile := makeTempFile(t, volumeKey)
+	defer os.Remove(keyFile)
+	tokenFile := makeTempFile(t, nil)
+	defer os.Remove(tokenFile)
+	unsealedFile := makeTempFile(t, nil)
+	defer os.Remove(unsealedFile)
+
+	RootCmd.SetArgs([]string{"disk", "seal", "--quiet", "--systemd", "--keyslot", "2", "--input", keyFile,
+		"--output", tokenFile, "--pcrs", strconv.Itoa(test.DebugPCR), "--pin", "1234"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	pcrs = nil
+	data, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := &disk.SystemdToken{}
+	if err = json.Unmarshal(data, token); err != nil {
+		t.Fatal(err)
+	}
+	if len(token.Keyslots) != 1 || token.Keyslots[0] != "2" {
+		t.Errorf("got keyslots %v, want [2]", token.Keyslots)
+	}
+
+	RootCmd.SetArgs([]string{"disk", "unseal", "--quiet", "--systemd", "--input", tokenFile, "--output", unsealedFile, "--pin", "1234"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(unsealedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, volumeKey) {
+		t.Errorf("got volume key %q, want %q", got, volumeKey)
+	}
+}
@@ -0,0 +1,310 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/go-attestation/attest"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/spf13/cobra"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+	"github.com/ThalesIgnite/go-tpm-tools/server"
+)
+
+// Supported output formats for the eventlog parse command.
+const (
+	eventLogText = "text"
+	eventLogJSON = "json"
+)
+
+var (
+	eventLogHashAlgo = tpm2.AlgSHA256
+	eventLogFormat   = eventLogText
+	eventLogReplay   = true
+	eventLogExplain  bool
+	eventLogRefs     string
+)
+
+var eventLogCmd = &cobra.Command{
+	Use:   "eventlog",
+	Short: "Work with the TCG event log",
+	Long:  `Work with the TCG event log of the measured boot`,
+	Args:  cobra.NoArgs,
+}
+
+var eventLogParseCmd = &cobra.Command{
+	Use:   "parse",
+	Short: "Decode and print the event log",
+	Long: `Decode and print the events of a TCG event log
+
+The event log is read from --input (the event log of this system by default).
+For each event of the PCR bank given with --hash (sha256 by default), its PCR,
+type and digest are printed, with a description of its data: the name of UEFI
+variables, the text of actions and GRUB commands, and the firmware version.
+The output is text, or JSON with --format.
+
+The events are replayed, and compared to the PCRs of the TPM: the events of
+PCRs which do not replay to the live PCR values are flagged. This is disabled
+with --replay=false, for example to parse the event log of another machine.
+
+With --explain, known quirks of firmware and event logs (such as the locality
+of TPM2_Startup, extended EV_NO_ACTION events, separator variations, SHA-1
+digests extended into other banks, and events which were not extended) are
+tried for each PCR which does not replay, and the quirk which makes the events
+consistent with the live PCR value is reported. This is for debugging only: an
+event log explained by a quirk must still not be trusted.
+
+With --reference-values, the digests of the events are labeled with the known
+components they measure (such as "shim 15.7"), from a database of reference
+values: a JSON file, a directory of JSON files, or an http(s) URL.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if eventLogFormat != eventLogText && eventLogFormat != eventLogJSON {
+			return fmt.Errorf("unknown format: %q", eventLogFormat)
+		}
+		if eventLogExplain && !eventLogReplay {
+			return fmt.Errorf("--explain requires replaying the event log")
+		}
+		var rwc io.ReadWriteCloser
+		if eventLogReplay || input == "" {
+			var err error
+			if rwc, err = openTpm(); err != nil {
+				return err
+			}
+			defer rwc.Close()
+		}
+		var rawEventLog []byte
+		var err error
+		if input == "" {
+			rawEventLog, err = client.GetEventLog(rwc)
+		} else {
+			rawEventLog, err = ioutil.ReadAll(dataInput())
+		}
+		if err != nil {
+			return fmt.Errorf("reading event log: %w", err)
+		}
+
+		eventLog, err := attest.ParseEventLog(rawEventLog)
+		if err != nil {
+			return fmt.Errorf("parsing event log: %w", err)
+		}
+		var events []*attestpb.Event
+		for _, event := range eventLog.Events(attest.HashAlg(eventLogHashAlgo)) {
+			events = append(events, &attestpb.Event{
+				PcrIndex:      uint32(event.Index),
+				UntrustedType: uint32(event.Type),
+				Data:          event.Data,
+				Digest:        event.Digest,
+			})
+		}
+		if len(events) == 0 {
+			return fmt.Errorf("event log has no events for the %s bank", algos[eventLogHashAlgo])
+		}
+
+		var labels []string
+		if eventLogRefs != "" {
+			if labels, err = labelEvents(events); err != nil {
+				return err
+			}
+		}
+
+		out := eventLogOutput{Hash: algos[eventLogHashAlgo]}
+		var mismatched map[uint32]bool
+		if eventLogReplay {
+			fmt.Fprintln(debugOutput(), "Replaying event log")
+			var live *pb.PCRs
+			if out.PCRs, live, err = replayEventLog(rwc, events); err != nil {
+				return err
+			}
+			if eventLogExplain {
+				if err = explainEventLog(rawEventLog, live, out.PCRs); err != nil {
+					return err
+				}
+			}
+			mismatched = map[uint32]bool{}
+			for _, pcr := range out.PCRs {
+				if !pcr.Replays {
+					mismatched[pcr.PCR] = true
+				}
+			}
+		}
+		for i, event := range events {
+			e := eventOutput{
+				Sequence:    i,
+				PCR:         event.GetPcrIndex(),
+				Type:        attest.EventType(event.GetUntrustedType()).String(),
+				Digest:      hex.EncodeToString(event.GetDigest()),
+				Description: server.DescribeEvent(event),
+			}
+			if labels != nil {
+				e.Label = labels[i]
+			}
+			if mismatched != nil {
+				replays := !mismatched[e.PCR]
+				e.Replays = &replays
+			}
+			out.Events = append(out.Events, e)
+		}
+		return writeEventLog(dataOutput(), out)
+	},
+}
+
+// The JSON encoding of a parsed event log.
+type eventLogOutput struct {
+	Hash   string            `json:"hash"`
+	Events []eventOutput     `json:"events"`
+	PCRs   []pcrReplayOutput `json:"pcrs,omitempty"`
+}
+
+type eventOutput struct {
+	Sequence    int    `json:"sequence"`
+	PCR         uint32 `json:"pcr"`
+	Type        string `json:"type"`
+	Digest      string `json:"digest"`
+	Description string `json:"description,omitempty"`
+	// The known component measured by the event, with --reference-values.
+	Label string `json:"label,omitempty"`
+	// Whether the events of the PCR replay to its live value, if replayed.
+	Replays *bool `json:"replays,omitempty"`
+}
+
+type pcrReplayOutput struct {
+	PCR      uint32 `json:"pcr"`
+	Replayed string `json:"replayed"`
+	Live     string `json:"live"`
+	Replays  bool   `json:"replays"`
+	// The quirk explaining why the PCR does not replay, with --explain.
+	Quirk string `json:"quirk,omitempty"`
+}
+
+// replayEventLog compares the replay of the events to the PCRs of the TPM,
+// for each PCR with events. It also returns the PCRs of the TPM.
+func replayEventLog(rw io.ReadWriter, events []*attestpb.Event) ([]pcrReplayOutput, *pb.PCRs, error) {
+	predicted, err := server.PredictPCRs(pb.HashAlgo(eventLogHashAlgo), events)
+	if err != nil {
+		return nil, nil, fmt.Errorf("replaying event log: %w", err)
+	}
+	sel := tpm2.PCRSelection{Hash: eventLogHashAlgo}
+	for index := range predicted.GetPcrs() {
+		sel.PCRs = append(sel.PCRs, int(index))
+	}
+	sort.Ints(sel.PCRs)
+	live, err := client.ReadPCRs(rw, sel)
+	if err != nil {
+		return nil, nil, err
+	}
+	var replays []pcrReplayOutput
+	for _, index := range sel.PCRs {
+		replayed := hex.EncodeToString(predicted.GetPcrs()[uint32(index)])
+		value := hex.EncodeToString(live.GetPcrs()[uint32(index)])
+		replays = append(replays, pcrReplayOutput{PCR: uint32(index), Replayed: replayed, Live: value, Replays: replayed == value})
+	}
+	return replays, live, nil
+}
+
+// labelEvents labels the events with the reference values of --reference-values.
+func labelEvents(events []*attestpb.Event) ([]string, error) {
+	var provider server.ReferenceValueProvider
+	if strings.HasPrefix(eventLogRefs, "http://") || strings.HasPrefix(eventLogRefs, "https://") {
+		provider = server.NewHTTPReferenceValues(http.DefaultClient, eventLogRefs)
+	} else {
+		values, err := server.LoadReferenceValues(eventLogRefs)
+		if err != nil {
+			return nil, fmt.Errorf("reading reference values: %w", err)
+		}
+		provider = values
+	}
+	labels, err := server.LabelEvents(provider, events)
+	if err != nil {
+		return nil, fmt.Errorf("labeling events: %w", err)
+	}
+	return labels, nil
+}
+
+// explainEventLog sets the quirk explaining each PCR which does not replay.
+func explainEventLog(rawEventLog []byte, live *pb.PCRs, replays []pcrReplayOutput) error {
+	fmt.Fprintln(debugOutput(), "Explaining replay failures")
+	explanations, err := server.ExplainReplay(rawEventLog, live)
+	if err != nil {
+		return fmt.Errorf("explaining replay failures: %w", err)
+	}
+	quirks := map[uint32]string{}
+	for _, explanation := range explanations {
+		quirks[explanation.Index] = explanation.Quirk
+	}
+	for i := range replays {
+		replays[i].Quirk = quirks[replays[i].PCR]
+	}
+	return nil
+}
+
+func writeEventLog(w io.Writer, out eventLogOutput) error {
+	if eventLogFormat == eventLogJSON {
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	}
+
+	// Events of PCRs which do not replay are flagged with a "!".
+	var b strings.Builder
+	for _, event := range out.Events {
+		flag := " "
+		if event.Replays != nil && !*event.Replays {
+			flag = "!"
+		}
+		fmt.Fprintf(&b, "%s%4d PCR%-2d %-36s %s", flag, event.Sequence, event.PCR, event.Type, event.Digest)
+		if event.Description != "" {
+			fmt.Fprintf(&b, " %q", event.Description)
+		}
+		if event.Label != "" {
+			fmt.Fprintf(&b, " [%s]", event.Label)
+		}
+		b.WriteString("\n")
+	}
+	for _, pcr := range out.PCRs {
+		if !pcr.Replays {
+			fmt.Fprintf(&b, "PCR%d does not replay: the events give %s, but the PCR is %s\n", pcr.PCR, pcr.Replayed, pcr.Live)
+			if !eventLogExplain {
+				continue
+			}
+			if pcr.Quirk != "" {
+				fmt.Fprintf(&b, "  The events replay to the PCR if %s\n", pcr.Quirk)
+			} else {
+				fmt.Fprintf(&b, "  No known quirk explains the value of PCR%d\n", pcr.PCR)
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func init() {
+	RootCmd.AddCommand(eventLogCmd)
+	hideHelp(eventLogCmd)
+	eventLogCmd.AddCommand(eventLogParseCmd)
+	eventLogParseCmd.PersistentFlags().StringVar(&input, "input", "",
+		"event log file (defaults to the event log of this system)")
+	hash := algoFlag{&eventLogHashAlgo, []tpm2.Algorithm{tpm2.AlgSHA1, tpm2.AlgSHA256, tpm2.AlgSHA384, tpm2.AlgSHA512}}
+	eventLogParseCmd.PersistentFlags().Var(&hash, "hash", "PCR bank: "+hash.Allowed())
+	eventLogParseCmd.PersistentFlags().StringVar(&eventLogFormat, "format", eventLogText,
+		"output format: "+eventLogText+" or "+eventLogJSON)
+	eventLogParseCmd.PersistentFlags().BoolVar(&eventLogReplay, "replay", true,
+		"compare the replayed events to the PCRs of the TPM")
+	eventLogParseCmd.PersistentFlags().BoolVar(&eventLogExplain, "explain", false,
+		"try known quirks for the PCRs which do not replay")
+	eventLogParseCmd.PersistentFlags().StringVar(&eventLogRefs, "reference-values", "",
+		"reference values for labeling the events: a JSON file or directory, or an http(s) URL")
+	addOutputFlag(eventLogParseCmd)
+}
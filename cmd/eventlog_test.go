@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestEventLogParse(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+	// Other tests may leave --input set.
+	input = ""
+	defer func() {
+		input = ""
+		eventLogHashAlgo = tpm2.AlgSHA256
+		eventLogFormat = eventLogText
+		eventLogReplay = true
+		eventLogExplain = false
+		eventLogRefs = ""
+	}()
+	outFile := makeTempFile(t, nil)
+	defer os.Remove(outFile)
+	parse := func(args ...string) []byte {
+		t.Helper()
+		RootCmd.SetArgs(append([]string{"eventlog", "parse", "--output", outFile}, args...))
+		if err := RootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		out, err := ioutil.ReadFile(outFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	// The PCRs of the test TPM are extended with the events of its log.
+	out := parse()
+	for _, want := range []string{"EV_EFI_VARIABLE_DRIVER_CONFIG", "\"SecureBoot (EFI_GLOBAL_VARIABLE)\"", "\"grub_cmd insmod part_gpt\""} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+	if bytes.Contains(out, []byte("does not replay")) {
+		t.Errorf("event log does not replay:\n%s", out)
+	}
+
+	measurement := sha256.Sum256([]byte("unlogged measurement"))
+	if err := tpm2.PCRExtend(rwc, tpmutil.Handle(4), tpm2.AlgSHA256, measurement[:], ""); err != nil {
+		t.Fatal(err)
+	}
+	var parsed eventLogOutput
+	if err := json.Unmarshal(parse("--format", eventLogJSON), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	for _, pcr := range parsed.PCRs {
+		if pcr.Replays != (pcr.PCR != 4) {
+			t.Errorf("PCR%d: got replays %v, want %v", pcr.PCR, pcr.Replays, pcr.PCR != 4)
+		}
+	}
+	for _, event := range parsed.Events {
+		if event.Replays == nil || *event.Replays != (event.PCR != 4) {
+			t.Errorf("event %d of PCR%d is not flagged correctly", event.Sequence, event.PCR)
+		}
+	}
+
+	// No known quirk explains an unlogged measurement.
+	if out := parse("--format", eventLogText, "--explain"); !bytes.Contains(out, []byte("No known quirk explains the value of PCR4")) {
+		t.Errorf("unlogged measurement is not reported:\n%s", out)
+	}
+	RootCmd.SetArgs([]string{"eventlog", "parse", "--explain", "--replay=false"})
+	if err := RootCmd.Execute(); err == nil {
+		t.Error("expected --explain without replaying the event log to fail")
+	}
+	eventLogExplain = false
+
+	// The event log of another machine, without replaying it.
+	logFile := makeTempFile(t, test.Debian10EventLog)
+	defer os.Remove(logFile)
+	parsed = eventLogOutput{}
+	if err := json.Unmarshal(parse("--input", logFile, "--hash", "sha1", "--format", eventLogJSON, "--replay=false"), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Hash != "sha1" || len(parsed.Events) == 0 || len(parsed.PCRs) != 0 || parsed.Events[0].Replays != nil {
+		t.Errorf("unexpected output for an event log which is not replayed: %+v", parsed)
+	}
+
+	// Labels from reference values.
+	refsFile := makeTempFile(t, []byte(fmt.Sprintf(`[{"digest": %q, "label": "firmware 1.0"}]`, parsed.Events[0].Digest)))
+	defer os.Remove(refsFile)
+	out = parse("--input", logFile, "--hash", "sha1", "--format", eventLogText, "--replay=false", "--reference-values", refsFile)
+	if !bytes.Contains(out, []byte("[firmware 1.0]")) {
+		t.Errorf("output does not contain the reference value label:\n%s", out)
+	}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/spf13/cobra"
+)
+
+var extendHashAlgo = tpm2.AlgSHA256
+
+var extendCmd = &cobra.Command{
+	Use:   "extend <pcr>",
+	Short: "Extend a measurement into a PCR",
+	Long: `Extend a measurement of the input data into a PCR
+
+The input data is hashed using --hash-algo (sha256 by default), and the digest
+is extended into the given PCR of that bank. Applications should only extend
+PCRs 8-15 and 23, as PCRs 0-7 are used to measure the platform's boot.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pcr, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid PCR %q: %w", args[0], err)
+		}
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+
+		data, err := ioutil.ReadAll(dataInput())
+		if err != nil {
+			return fmt.Errorf("cannot read input: %w", err)
+		}
+		fmt.Fprintf(debugOutput(), "Extending %d bytes of data into PCR %d\n", len(data), pcr)
+		if err = client.ExtendPCR(rwc, pcr, extendHashAlgo, data); err != nil {
+			return err
+		}
+
+		sel := tpm2.PCRSelection{Hash: extendHashAlgo, PCRs: []int{pcr}}
+		pcrs, err := client.ReadPCRs(rwc, sel)
+		if err != nil {
+			return err
+		}
+		return notinternal.FormatPCRs(messageOutput(), pcrs)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(extendCmd)
+	addInputFlag(extendCmd)
+	addHashAlgoFlag(extendCmd, &extendHashAlgo)
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	"github.com/google/go-tpm/tpm2"
+)
+
+func TestExtend(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+
+	data := []byte("application measurement")
+	inFile := makeTempFile(t, data)
+	defer os.Remove(inFile)
+
+	old, err := tpm2.ReadPCR(rwc, test.DebugPCR, tpm2.AlgSHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	RootCmd.SetArgs([]string{"extend", strconv.Itoa(test.DebugPCR), "--quiet", "--hash-algo=sha256", "--input", inFile})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := tpm2.ReadPCR(rwc, test.DebugPCR, tpm2.AlgSHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(data)
+	want := sha256.Sum256(append(old, digest[:]...))
+	if !bytes.Equal(got, want[:]) {
+		t.Errorf("got PCR value %X, want %X", got, want)
+	}
+}
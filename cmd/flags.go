@@ -5,37 +5,64 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"strconv"
 	"strings"
 
 	"github.com/ThalesIgnite/go-tpm-tools/client"
 	"github.com/google/go-tpm/tpm2"
 	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 )
 
 var (
 	output  string
 	input   string
 	nvIndex uint32
+	nvSize  uint16
+	nvAuth  string
 	keyAlgo = tpm2.AlgRSA
 	pcrs    []int
+	nonce   []byte
+	format  = formatBinary
+	// Whether the attestation evidence is bound with derived nonces.
+	deriveNonces bool
+	// Whether the attestation report is an EAT, rather than a protobuf.
+	eatReport bool
+)
+
+// Supported encodings for protobuf messages, for use with the format flag.
+const (
+	formatBinary = "binarypb"
+	formatText   = "textproto"
+	formatJSON   = "json"
 )
 
 type pcrsFlag struct {
 	value *[]int
+	// The PCR bank, which can be set with a "<bank>:" prefix. If fixed, the
+	// prefix must match the bank used by the command.
+	hash  *tpm2.Algorithm
+	fixed bool
 }
 
 func (f *pcrsFlag) Set(val string) error {
-	for _, d := range strings.Split(val, ",") {
-		pcr, err := strconv.Atoi(d)
+	if !strings.Contains(val, ":") {
+		pcrs, err := client.ParsePCRs(val)
 		if err != nil {
 			return err
 		}
-		if pcr < 0 || pcr >= client.NumPCRs {
-			return errors.New("pcr out of range")
-		}
-		*f.value = append(*f.value, pcr)
+		*f.value = append(*f.value, pcrs...)
+		return nil
+	}
+	sel, err := client.ParsePCRSelection(val)
+	if err != nil {
+		return err
 	}
+	if f.fixed && sel.Hash != *f.hash {
+		return fmt.Errorf("PCRs must be in the %s bank", algos[*f.hash])
+	}
+	*f.hash = sel.Hash
+	*f.value = append(*f.value, sel.PCRs...)
 	return nil
 }
 
@@ -47,12 +74,10 @@ func (f *pcrsFlag) String() string {
 	if len(*f.value) == 0 {
 		return ""
 	}
-	var b strings.Builder
-	fmt.Fprintf(&b, "%d", (*f.value)[0])
-	for _, pcr := range (*f.value)[1:] {
-		fmt.Fprintf(&b, ",%d", pcr)
+	if *f.hash == tpm2.AlgUnknown {
+		return client.FormatPCRs(*f.value)
 	}
-	return b.String()
+	return client.FormatPCRSelection(tpm2.PCRSelection{Hash: *f.hash, PCRs: *f.value})
 }
 
 var algos = map[tpm2.Algorithm]string{
@@ -63,6 +88,7 @@ var algos = map[tpm2.Algorithm]string{
 	tpm2.AlgSHA256:  "sha256",
 	tpm2.AlgSHA384:  "sha384",
 	tpm2.AlgSHA512:  "sha512",
+	client.AlgSM3:   "sm3_256",
 }
 
 type algoFlag struct {
@@ -126,9 +152,58 @@ func addIndexFlag(cmd *cobra.Command) {
 		"NVDATA index, cannot be 0")
 }
 
+// Lets this command specify the size of an NVDATA index, for use with nvSize.
+func addSizeFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().Uint16Var(&nvSize, "size", 0,
+		"NVDATA index size in bytes, cannot be 0")
+}
+
+// Lets this command specify an NVDATA index password, for use with nvAuth.
+func addAuthFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&nvAuth, "auth", "",
+		"NVDATA index password (defaults to empty)")
+}
+
+// Lets this command specify a hex encoded nonce, for use with nonce.
+func addNonceFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().BytesHexVar(&nonce, "nonce", nil,
+		"hex encoded nonce, used to guarantee freshness")
+}
+
+// Lets this command bind the attestation evidence with nonces derived from the
+// --nonce, for use with deriveNonces.
+func addDeriveNoncesFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&deriveNonces, "derive-nonces", false,
+		"bind the quotes, event logs and TEE attestation with nonces derived from --nonce")
+}
+
+// Lets this command use attestation reports encoded as an Entity Attestation
+// Token (a COSE_Sign1 signed by the AK), for use with eatReport.
+func addEATFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&eatReport, "eat", false,
+		"encode the report as a CBOR Entity Attestation Token, instead of using --format")
+}
+
+// Lets this command specify the encoding of protobuf messages, for use with
+// marshalProto() and unmarshalProto().
+func addFormatFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&format, "format", formatBinary,
+		"protobuf encoding: "+strings.Join([]string{formatBinary, formatText, formatJSON}, ", "))
+}
+
 // Lets this command specify some number of PCR arguments, check if in range.
-func addPCRsFlag(cmd *cobra.Command) {
-	cmd.PersistentFlags().Var(&pcrsFlag{&pcrs}, "pcrs", "comma separated list of PCR numbers")
+// A "<bank>:" prefix (as in "sha256:0,1,7") sets hashAlgo to the PCR bank.
+func addPCRsFlag(cmd *cobra.Command, hashAlgo *tpm2.Algorithm) {
+	cmd.PersistentFlags().Var(&pcrsFlag{value: &pcrs, hash: hashAlgo}, "pcrs",
+		"comma separated list of PCR numbers, optionally prefixed by the bank (e.g. sha256:0,1,7)")
+}
+
+// Lets this command specify some number of PCRs in the bank used for
+// certification (as in "0,1,7" or "sha256:0,1,7").
+func addCertifyPCRsFlag(cmd *cobra.Command) {
+	hashAlgo := client.CertifyHashAlgTpm
+	cmd.PersistentFlags().Var(&pcrsFlag{value: &pcrs, hash: &hashAlgo, fixed: true}, "pcrs",
+		"comma separated list of "+algos[hashAlgo]+" PCR numbers")
 }
 
 // Lets this command specify the public key algorithm.
@@ -183,6 +258,45 @@ func dataInput() io.Reader {
 	return file
 }
 
+// Encode a protobuf message using the format set in the global flag vars.
+func marshalProto(m proto.Message) ([]byte, error) {
+	return marshalProtoFormat(m, format)
+}
+
+// Decode a protobuf message using the format set in the global flag vars.
+func unmarshalProto(data []byte, m proto.Message) error {
+	return unmarshalProtoFormat(data, m, format)
+}
+
+// Encode a protobuf message using the format (binarypb, textproto or json).
+func marshalProtoFormat(m proto.Message, format string) ([]byte, error) {
+	switch format {
+	case formatBinary:
+		return proto.Marshal(m)
+	case formatText:
+		return marshalOptions.Marshal(m)
+	case formatJSON:
+		return protojson.MarshalOptions{Multiline: true}.Marshal(m)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// Decode a protobuf message using the format (binarypb, textproto or json).
+// Unknown JSON fields are ignored, as in the JSON encoding of the messages.
+func unmarshalProtoFormat(data []byte, m proto.Message, format string) error {
+	switch format {
+	case formatBinary:
+		return proto.Unmarshal(data, m)
+	case formatText:
+		return unmarshalOptions.Unmarshal(data, m)
+	case formatJSON:
+		return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, m)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
 // Load SRK based on tpm2.Algorithm set in the global flag vars.
 func getSRK(rwc io.ReadWriter) (*client.Key, error) {
 	switch keyAlgo {
@@ -206,3 +320,12 @@ func getEK(rwc io.ReadWriter) (*client.Key, error) {
 		panic("unexpected keyAlgo")
 	}
 }
+
+// Load AK based on the platform and tpm2.Algorithm set in the global flag vars.
+func getAK(rwc io.ReadWriter) (*client.Key, error) {
+	p, err := getPlatform()
+	if err != nil {
+		return nil, err
+	}
+	return p.AttestationKey(rwc, keyAlgo)
+}
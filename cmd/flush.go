@@ -9,15 +9,19 @@ import (
 )
 
 var handleNames = map[string][]tpm2.HandleType{
-	"all":        {tpm2.HandleTypeLoadedSession, tpm2.HandleTypeSavedSession, tpm2.HandleTypeTransient},
-	"loaded":     {tpm2.HandleTypeLoadedSession},
-	"saved":      {tpm2.HandleTypeSavedSession},
-	"transient":  {tpm2.HandleTypeTransient},
-	"persistent": {tpm2.HandleTypePersistent},
+	"all":            {tpm2.HandleTypeLoadedSession, tpm2.HandleTypeSavedSession, tpm2.HandleTypeTransient},
+	"loaded":         {tpm2.HandleTypeLoadedSession},
+	"loaded-session": {tpm2.HandleTypeLoadedSession},
+	"saved":          {tpm2.HandleTypeSavedSession},
+	"saved-session":  {tpm2.HandleTypeSavedSession},
+	"transient":      {tpm2.HandleTypeTransient},
+	"persistent":     {tpm2.HandleTypePersistent},
 }
 
+var flushType string
+
 var flushCmd = &cobra.Command{
-	Use:   "flush <all | loaded | saved | transient | persistent>",
+	Use:   "flush [--type all | loaded-session | saved-session | transient | persistent]",
 	Short: "Close active handles on the TPM",
 	Long: `Close some or all currently active handles on the TPM
 
@@ -30,25 +34,50 @@ to be used with future TPM operations.
 The TPM can also take an active handle and "persist" it to NVRAM. This frees up
 memory for more transient handles. It can also allow for caching the creation of
 slow keys (such as the RSA-based EK or SRK). These handles can be evicted from
-NVRAM using the "persistent" argument, but are not flushed with "all", as this
+NVRAM using the "persistent" type, but are not flushed with "all", as this
 can result in data loss (if the persisted key cannot be regenerated).
 
-Which handles are flushed depends on the argument passed:
-	loaded     - only flush the loaded session handles
-	saved      - only flush the saved session handles
-	transient  - only flush the transient handles
-	all        - flush all loaded, saved, and transient handles
-	persistent - only evict the persistent handles`,
+When using the kernel's resource manager (/dev/tpmrm0, used by default if
+present), transient objects are flushed automatically when a process closes the
+TPM, and the transient objects of other processes are not visible. To flush
+transient handles leaked through /dev/tpm0, use --tpm-path=/dev/tpm0.
+
+Which handles are flushed depends on the --type (or the argument) passed:
+	loaded-session - only flush the loaded session handles
+	saved-session  - only flush the saved session handles
+	transient      - only flush the transient handles
+	all            - flush all loaded, saved, and transient handles (default)
+	persistent     - only evict the persistent handles
+
+The "loaded" and "saved" types are aliases of "loaded-session" and
+"saved-session".`,
 	ValidArgs: func() []string {
 		// The keys from the handleNames map are our valid arguments
-		keys := make([]string, len(handleNames))
+		keys := make([]string, 0, len(handleNames))
 		for k := range handleNames {
 			keys = append(keys, k)
 		}
 		return keys
 	}(),
-	Args: cobra.ExactValidArgs(1),
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.MaximumNArgs(1)(cmd, args); err != nil {
+			return err
+		}
+		return cobra.OnlyValidArgs(cmd, args)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
+		name := flushType
+		if len(args) == 1 {
+			if cmd.Flags().Changed("type") && args[0] != flushType {
+				return fmt.Errorf("handle type %q does not match --type %q", args[0], flushType)
+			}
+			name = args[0]
+		}
+		handleTypes, ok := handleNames[name]
+		if !ok {
+			return fmt.Errorf("invalid handle type %q", name)
+		}
+
 		rwc, err := openTpm()
 		if err != nil {
 			return err
@@ -56,23 +85,27 @@ Which handles are flushed depends on the argument passed:
 		defer rwc.Close()
 
 		totalHandles := 0
-		for _, handleType := range handleNames[args[0]] {
+		for _, handleType := range handleTypes {
+			if handleType != tpm2.HandleTypePersistent {
+				flushed, err := client.FlushHandles(rwc, handleType)
+				for _, handle := range flushed {
+					fmt.Fprintf(debugOutput(), "Handle 0x%x flushed\n", handle)
+				}
+				totalHandles += len(flushed)
+				if err != nil {
+					return err
+				}
+				continue
+			}
 			handles, err := client.Handles(rwc, handleType)
 			if err != nil {
 				return fmt.Errorf("getting handles: %w", err)
 			}
 			for _, handle := range handles {
-				if handleType == tpm2.HandleTypePersistent {
-					if err = tpm2.EvictControl(rwc, "", tpm2.HandleOwner, handle, handle); err != nil {
-						return fmt.Errorf("evicting handle 0x%x: %w", handle, err)
-					}
-					fmt.Fprintf(debugOutput(), "Handle 0x%x evicted\n", handle)
-				} else {
-					if err = tpm2.FlushContext(rwc, handle); err != nil {
-						return fmt.Errorf("flushing handle 0x%x: %w", handle, err)
-					}
-					fmt.Fprintf(debugOutput(), "Handle 0x%x flushed\n", handle)
+				if err = client.EvictPersistent(rwc, handle); err != nil {
+					return err
 				}
+				fmt.Fprintf(debugOutput(), "Handle 0x%x evicted\n", handle)
 				totalHandles++
 			}
 		}
@@ -84,4 +117,6 @@ Which handles are flushed depends on the argument passed:
 
 func init() {
 	RootCmd.AddCommand(flushCmd)
+	flushCmd.PersistentFlags().StringVar(&flushType, "type", "all",
+		"type of handles to flush: all, loaded-session, saved-session, transient or persistent")
 }
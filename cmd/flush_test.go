@@ -46,3 +46,49 @@ func TestFlush(t *testing.T) {
 		}
 	}
 }
+
+func TestFlushType(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+	defer func() {
+		flag := flushCmd.PersistentFlags().Lookup("type")
+		flag.Value.Set("all")
+		flag.Changed = false
+	}()
+
+	session, _, err := tpm2.StartAuthSession(rwc, tpm2.HandleNull, tpm2.HandleNull,
+		make([]byte, 16), nil, tpm2.SessionHMAC, tpm2.AlgNull, tpm2.AlgSHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.LoadRandomExternalKey(t, rwc)
+
+	RootCmd.SetArgs([]string{"flush", "--type", "loaded-session", "--quiet"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Error(err)
+	}
+	if h, err := client.Handles(rwc, tpm2.HandleTypeLoadedSession); err != nil || len(h) != 0 {
+		t.Errorf("session 0x%x should be flushed; got handles %v (%v)", session, h, err)
+	}
+	if h, err := client.Handles(rwc, tpm2.HandleTypeTransient); err != nil || len(h) != 1 {
+		t.Errorf("the transient handle should not be flushed; got handles %v (%v)", h, err)
+	}
+
+	// The argument must match the --type.
+	RootCmd.SetArgs([]string{"flush", "transient", "--type", "saved-session", "--quiet"})
+	if err := RootCmd.Execute(); err == nil {
+		t.Error("expected failure with a different argument and --type")
+	}
+	RootCmd.SetArgs([]string{"flush", "--type", "invalid", "--quiet"})
+	if err := RootCmd.Execute(); err == nil {
+		t.Error("expected failure with an invalid --type")
+	}
+	RootCmd.SetArgs([]string{"flush", "--type", "all", "--quiet"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Error(err)
+	}
+	if h, err := client.Handles(rwc, tpm2.HandleTypeTransient); err != nil || len(h) != 0 {
+		t.Errorf("TPM should be empty of transient handles; got handles %v (%v)", h, err)
+	}
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+	"github.com/spf13/cobra"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+)
+
+var (
+	hierarchyAuth    string
+	hierarchyNewAuth string
+)
+
+// Hierarchies which have an authorization value (unlike the null hierarchy).
+var authHierarchyNames = map[string]tpmutil.Handle{
+	"endorsement": tpm2.HandleEndorsement,
+	"owner":       tpm2.HandleOwner,
+	"platform":    tpm2.HandlePlatform,
+	"lockout":     tpm2.HandleLockout,
+}
+
+var hierarchyCmd = &cobra.Command{
+	Use:   "hierarchy",
+	Short: "Manage the TPM's hierarchies",
+	Long: `Clear the TPM, or change the authorization value or state of a hierarchy
+
+These commands are intended for provisioning a TPM, and can make existing keys
+and sealed data unusable.`,
+	Args: cobra.NoArgs,
+}
+
+var clearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Clear the TPM's owner hierarchy",
+	Long: `Run TPM2_Clear, authorized with the lockout hierarchy's --auth password
+
+This invalidates all keys and sealed data in the owner hierarchy, removes all
+owner NV indices and persistent keys, and resets the owner, endorsement and
+lockout passwords. The EK is not changed.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+
+		if err = client.Clear(rwc, hierarchyAuth); err != nil {
+			return err
+		}
+		fmt.Fprintln(messageOutput(), "TPM cleared")
+		return nil
+	},
+}
+
+var changeAuthCmd = &cobra.Command{
+	Use:   "changeauth <endorsement | owner | platform | lockout>",
+	Short: "Change the authorization value of a hierarchy",
+	Long: `Change the password of a hierarchy from --auth to --new-auth
+
+Setting the owner password is commonly known as taking ownership of the TPM.
+An empty --new-auth removes the password.`,
+	ValidArgs: validArgs(authHierarchyNames),
+	Args:      cobra.ExactValidArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+
+		if err = client.ChangeHierarchyAuth(rwc, authHierarchyNames[args[0]], hierarchyAuth, hierarchyNewAuth); err != nil {
+			return err
+		}
+		fmt.Fprintf(messageOutput(), "Changed %s authorization\n", args[0])
+		return nil
+	},
+}
+
+var disableCmd = &cobra.Command{
+	Use:   "disable <endorsement | owner | platform>",
+	Short: "Disable a hierarchy",
+	Long: `Disable a hierarchy until the next TPM reset, using its --auth password
+
+Keys in a disabled hierarchy cannot be created or used. Enabling a hierarchy
+again requires the platform password.`,
+	ValidArgs: []string{"endorsement", "owner", "platform"},
+	Args:      cobra.ExactValidArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setHierarchyEnabled(args[0], false)
+	},
+}
+
+var enableCmd = &cobra.Command{
+	Use:   "enable <endorsement | owner>",
+	Short: "Enable a hierarchy",
+	Long: `Enable a disabled hierarchy, using the platform --auth password
+
+All hierarchies are enabled when the TPM is reset, so this is rarely needed.`,
+	ValidArgs: []string{"endorsement", "owner"},
+	Args:      cobra.ExactValidArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setHierarchyEnabled(args[0], true)
+	},
+}
+
+func setHierarchyEnabled(name string, enable bool) error {
+	rwc, err := openTpm()
+	if err != nil {
+		return err
+	}
+	defer rwc.Close()
+
+	if err = client.SetHierarchyEnabled(rwc, authHierarchyNames[name], enable, hierarchyAuth); err != nil {
+		return err
+	}
+	state := "disabled"
+	if enable {
+		state = "enabled"
+	}
+	fmt.Fprintf(messageOutput(), "Hierarchy %s %s\n", name, state)
+	return nil
+}
+
+func validArgs(names map[string]tpmutil.Handle) []string {
+	keys := make([]string, 0, len(names))
+	for k := range names {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func init() {
+	RootCmd.AddCommand(hierarchyCmd)
+	for _, cmd := range []*cobra.Command{clearCmd, changeAuthCmd, disableCmd, enableCmd} {
+		hierarchyCmd.AddCommand(cmd)
+		cmd.PersistentFlags().StringVar(&hierarchyAuth, "auth", "",
+			"current authorization value of the hierarchy (see the command's help)")
+	}
+	changeAuthCmd.PersistentFlags().StringVar(&hierarchyNewAuth, "new-auth", "",
+		"new authorization value of the hierarchy")
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestHierarchy(t *testing.T) {
+	test.SkipOnRealTPM(t)
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+	defer func() { hierarchyAuth, hierarchyNewAuth = "", "" }()
+
+	for _, args := range [][]string{
+		{"hierarchy", "changeauth", "owner", "--new-auth", "owner"},
+		{"hierarchy", "changeauth", "owner", "--auth", "owner", "--new-auth", ""},
+		{"hierarchy", "disable", "endorsement", "--auth", ""},
+		{"hierarchy", "enable", "endorsement"},
+		{"hierarchy", "clear"},
+	} {
+		RootCmd.SetArgs(args)
+		if err := RootCmd.Execute(); err != nil {
+			t.Fatalf("%v failed: %v", args, err)
+		}
+	}
+
+	RootCmd.SetArgs([]string{"hierarchy", "changeauth", "null"})
+	if err := RootCmd.Execute(); err == nil {
+		t.Error("changing the auth of the null hierarchy should fail")
+	}
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/spf13/cobra"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+	"github.com/ThalesIgnite/go-tpm-tools/server"
+)
+
+// Types of data which can be wrapped by "gotpm wrap".
+const (
+	wrapData       = "data"
+	wrapAES        = "aes"
+	wrapSigning    = "signing"
+	wrapDecryption = "decryption"
+)
+
+var (
+	importBlob string
+	wrapEK     string
+	wrapKey    string
+	wrapType   = wrapData
+)
+
+var wrapCmd = &cobra.Command{
+	Use:   "wrap",
+	Short: "Wrap a secret or key to a remote TPM's EK",
+	Long: `Create an import blob which can only be imported by a specific TPM
+
+This command does not use a TPM. The --key file is encrypted to the PEM encoded
+EK public key in --ek (as written by "gotpm pubkey endorsement"), so that only
+the TPM with that EK can import it using "gotpm import".
+
+The --type flag determines how the --key file is interpreted:
+  data:       arbitrary secret data (up to 128 bytes), returned by "gotpm import"
+  aes:        a raw 128, 192 or 256 bit AES key
+  signing:    a PEM encoded RSA or ECDSA private key, usable for signing
+  decryption: a PEM encoded RSA private key, usable for decryption, or ECDSA
+              private key, usable for ECDH
+
+The import blob is written as a protobuf, encoded using --format.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ekPub, err := readPublicKey(wrapEK)
+		if err != nil {
+			return err
+		}
+		key, err := ioutil.ReadFile(wrapKey)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(debugOutput(), "Wrapping %s to EK\n", wrapType)
+		var blob *pb.ImportBlob
+		switch wrapType {
+		case wrapData:
+			blob, err = server.CreateImportBlob(ekPub, key, nil)
+		case wrapAES:
+			blob, err = server.CreateSymmetricKeyImportBlob(ekPub, key, nil)
+		case wrapSigning, wrapDecryption:
+			var priv crypto.PrivateKey
+			if priv, err = parsePrivateKey(key); err != nil {
+				return fmt.Errorf("parsing %s: %w", wrapKey, err)
+			}
+			if wrapType == wrapSigning {
+				blob, err = server.CreateSigningKeyImportBlob(ekPub, priv, nil)
+			} else {
+				blob, err = server.CreateDecryptionKeyImportBlob(ekPub, priv, nil)
+			}
+		default:
+			return fmt.Errorf("unknown type %q", wrapType)
+		}
+		if err != nil {
+			return fmt.Errorf("wrapping key: %w", err)
+		}
+
+		out, err := marshalProto(blob)
+		if err != nil {
+			return err
+		}
+		_, err = dataOutput().Write(out)
+		return err
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import a blob created by \"gotpm wrap\"",
+	Long: `Import a secret or key wrapped to this TPM's EK
+
+The --blob file (created by "gotpm wrap", and encoded using --format) is
+imported under the EK selected by --algo, which must match the EK public key
+used to create the blob.
+
+For wrapped data, the secret is written to --output. For wrapped keys, the key
+is loaded and its saved context is written to --output. As with all transient
+objects, the context can only be loaded until the TPM is reset.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := ioutil.ReadFile(importBlob)
+		if err != nil {
+			return err
+		}
+		var blob pb.ImportBlob
+		if err = unmarshalProto(data, &blob); err != nil {
+			return fmt.Errorf("parsing %s: %w", importBlob, err)
+		}
+		pub, err := tpm2.DecodePublic(blob.GetPublicArea())
+		if err != nil {
+			return fmt.Errorf("decoding public area: %w", err)
+		}
+
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+
+		fmt.Fprintln(debugOutput(), "Loading EK")
+		ek, err := getEK(rwc)
+		if err != nil {
+			return err
+		}
+		defer ek.Close()
+
+		var out []byte
+		if pub.Type == tpm2.AlgKeyedHash {
+			fmt.Fprintln(debugOutput(), "Importing data")
+			if out, err = ek.Import(&blob); err != nil {
+				return fmt.Errorf("importing data: %w", err)
+			}
+		} else {
+			fmt.Fprintln(debugOutput(), "Importing key")
+			key, err := ek.ImportKey(&blob)
+			if err != nil {
+				return fmt.Errorf("importing key: %w", err)
+			}
+			defer key.Close()
+			if out, err = key.ContextSave(); err != nil {
+				return err
+			}
+		}
+		_, err = dataOutput().Write(out)
+		return err
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(wrapCmd)
+	wrapCmd.PersistentFlags().StringVar(&wrapEK, "ek", "",
+		"PEM encoded EK public key file")
+	wrapCmd.MarkPersistentFlagRequired("ek")
+	wrapCmd.PersistentFlags().StringVar(&wrapKey, "key", "",
+		"secret or key file to wrap")
+	wrapCmd.MarkPersistentFlagRequired("key")
+	wrapCmd.PersistentFlags().StringVar(&wrapType, "type", wrapData,
+		"type of --key: data, aes, signing or decryption")
+	addFormatFlag(wrapCmd)
+	addOutputFlag(wrapCmd)
+
+	RootCmd.AddCommand(importCmd)
+	importCmd.PersistentFlags().StringVar(&importBlob, "blob", "",
+		"import blob file")
+	importCmd.MarkPersistentFlagRequired("blob")
+	addPublicKeyAlgoFlag(importCmd)
+	addFormatFlag(importCmd)
+	addOutputFlag(importCmd)
+}
+
+// parsePrivateKey parses a PEM encoded PKCS #8, PKCS #1 or SEC 1 private key.
+func parsePrivateKey(data []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded private key")
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+}
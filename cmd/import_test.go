@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestWrapImport(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+	defer func() { keyAlgo = tpm2.AlgRSA }()
+
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(signingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingKeyFile := makeTempFile(t, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	defer os.Remove(signingKeyFile)
+	secret := []byte("wrapped secret")
+	secretFile := makeTempFile(t, secret)
+	defer os.Remove(secretFile)
+
+	for _, algo := range []string{"rsa", "ecc"} {
+		t.Run(algo, func(t *testing.T) {
+			getEK := client.EndorsementKeyRSA
+			if algo == "ecc" {
+				getEK = client.EndorsementKeyECC
+			}
+			ek, err := getEK(rwc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			der, err := x509.MarshalPKIXPublicKey(ek.PublicKey())
+			ek.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			ekFile := makeTempFile(t, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+			defer os.Remove(ekFile)
+			blobFile := makeTempFile(t, nil)
+			defer os.Remove(blobFile)
+			outFile := makeTempFile(t, nil)
+			defer os.Remove(outFile)
+
+			RootCmd.SetArgs([]string{"wrap", "--quiet", "--ek", ekFile, "--key", secretFile, "--type", "data", "--output", blobFile})
+			if err := RootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+			RootCmd.SetArgs([]string{"import", "--quiet", "--algo", algo, "--blob", blobFile, "--output", outFile})
+			if err := RootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+			imported, err := ioutil.ReadFile(outFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(imported, secret) {
+				t.Errorf("got imported data %q, want %q", imported, secret)
+			}
+
+			RootCmd.SetArgs([]string{"wrap", "--quiet", "--ek", ekFile, "--key", signingKeyFile, "--type", "signing", "--format", formatText, "--output", blobFile})
+			if err := RootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+			RootCmd.SetArgs([]string{"import", "--quiet", "--algo", algo, "--blob", blobFile, "--format", formatText, "--output", outFile})
+			if err := RootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+			format = formatBinary
+			keyContext, err := ioutil.ReadFile(outFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			handle, err := tpm2.ContextLoad(rwc, keyContext)
+			if err != nil {
+				t.Fatalf("failed to load saved key context: %v", err)
+			}
+			defer tpm2.FlushContext(rwc, handle)
+			pub, _, _, err := tpm2.ReadPublic(rwc, handle)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pub.ECCParameters.Point.X().Cmp(signingKey.X) != 0 {
+				t.Error("imported key does not match the wrapped signing key")
+			}
+		})
+	}
+}
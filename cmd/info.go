@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/spf13/cobra"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+)
+
+// Supported output formats for the info command.
+const (
+	infoTable = "table"
+	infoJSON  = "json"
+)
+
+var (
+	infoFormat   = infoTable
+	infoFullTest bool
+)
+
+var algorithmNames = map[tpm2.Algorithm]string{
+	tpm2.AlgRSA:       "rsa",
+	tpm2.AlgSHA1:      "sha1",
+	tpm2.AlgHMAC:      "hmac",
+	tpm2.AlgAES:       "aes",
+	tpm2.AlgKeyedHash: "keyedhash",
+	tpm2.AlgXOR:       "xor",
+	tpm2.AlgSHA256:    "sha256",
+	tpm2.AlgSHA384:    "sha384",
+	tpm2.AlgSHA512:    "sha512",
+	tpm2.AlgNull:      "null",
+	tpm2.AlgRSASSA:    "rsassa",
+	tpm2.AlgRSAES:     "rsaes",
+	tpm2.AlgRSAPSS:    "rsapss",
+	tpm2.AlgOAEP:      "oaep",
+	tpm2.AlgECDSA:     "ecdsa",
+	tpm2.AlgECDH:      "ecdh",
+	tpm2.AlgECDAA:     "ecdaa",
+	tpm2.AlgKDF2:      "kdf2",
+	tpm2.AlgECC:       "ecc",
+	tpm2.AlgSymCipher: "symcipher",
+	tpm2.AlgSHA3_256:  "sha3_256",
+	tpm2.AlgSHA3_384:  "sha3_384",
+	tpm2.AlgSHA3_512:  "sha3_512",
+	client.AlgSM2:     "sm2",
+	client.AlgSM3:     "sm3_256",
+	client.AlgSM4:     "sm4",
+	tpm2.AlgCTR:       "ctr",
+	tpm2.AlgOFB:       "ofb",
+	tpm2.AlgCBC:       "cbc",
+	tpm2.AlgCFB:       "cfb",
+	tpm2.AlgECB:       "ecb",
+}
+
+// TPMA_ALGORITHM bits, in order.
+var algorithmAttributeNames = []struct {
+	bit  tpm2.AlgorithmAttributes
+	name string
+}{
+	{1 << 0, "asymmetric"},
+	{1 << 1, "symmetric"},
+	{1 << 2, "hash"},
+	{1 << 3, "object"},
+	{1 << 8, "signing"},
+	{1 << 9, "encrypting"},
+	{1 << 10, "method"},
+}
+
+// TPM properties describing the TPM's buffer, RAM and NV limits.
+var limitNames = []struct {
+	prop tpm2.TPMProp
+	name string
+}{
+	{tpm2.InputMaxBufferSize, "input-buffer-max"},
+	{tpm2.CommandMaxSize, "command-max"},
+	{tpm2.ResponseMaxSize, "response-max"},
+	{tpm2.NVMaxBufferSize, "nv-buffer-max"},
+	{tpm2.NVIndexMax, "nv-index-size-max"},
+	{tpm2.HRNVIndex, "nv-indices-defined"},
+	{tpm2.NVCountersMax, "nv-counters-max"},
+	{tpm2.NVCountersAvail, "nv-counters-avail"},
+	{tpm2.TransientObjectsMin, "transient-objects-min"},
+	{tpm2.HRTransientAvail, "transient-objects-avail"},
+	{tpm2.LoadedObjectsMin, "loaded-objects-min"},
+	{tpm2.HRLoadedAvail, "loaded-sessions-avail"},
+	{tpm2.ActiveSessionsMax, "active-sessions-max"},
+	{tpm2.PersistentObjectsMin, "persistent-objects-min"},
+	{tpm2.CurrentPersistent, "persistent-objects-defined"},
+	{tpm2.AvailPersistent, "persistent-objects-avail"},
+	{tpm2.PCRCount, "pcr-count"},
+	{tpm2.LockoutCounter, "lockout-counter"},
+	{tpm2.MaxAuthFail, "max-auth-fail"},
+}
+
+var infoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Run a TPM self test and describe the TPM",
+	Long: `Run TPM2_SelfTest, then print information about the TPM
+
+The TPM's manufacturer, firmware version, implemented specification, supported
+algorithms, PCR banks, and buffer, RAM and NV limits are printed as a table, or
+as JSON with --format=json.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if infoFormat != infoTable && infoFormat != infoJSON {
+			return fmt.Errorf("unknown format: %q", infoFormat)
+		}
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+
+		fmt.Fprintln(debugOutput(), "Running TPM self test")
+		if err = client.SelfTest(rwc, infoFullTest); err != nil {
+			return err
+		}
+		info, err := client.GetInfo(rwc)
+		if err != nil {
+			return err
+		}
+		if infoFormat == infoJSON {
+			return writeInfoJSON(info)
+		}
+		return writeInfoTable(info)
+	},
+}
+
+func algorithmAttributesString(attrs tpm2.AlgorithmAttributes) string {
+	var names []string
+	for _, attr := range algorithmAttributeNames {
+		if attrs&attr.bit != 0 {
+			names = append(names, attr.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+func specString(info *client.TPMInfo) string {
+	return fmt.Sprintf("%s level %d revision %d.%02d (day %d of %d)", info.Family, info.SpecLevel,
+		info.SpecRevision/100, info.SpecRevision%100, info.SpecDayOfYear, info.SpecYear)
+}
+
+func writeInfoTable(info *client.TPMInfo) error {
+	w := tabwriter.NewWriter(dataOutput(), 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Manufacturer:\t%s\n", info.Manufacturer)
+	fmt.Fprintf(w, "Vendor:\t%s\n", info.VendorString)
+	fmt.Fprintf(w, "Firmware version:\t0x%016x\n", info.FirmwareVersion)
+	fmt.Fprintf(w, "Specification:\t%s\n", specString(info))
+
+	fmt.Fprintln(w, "\nALGORITHM\tATTRIBUTES")
+	for _, alg := range info.Algorithms {
+		fmt.Fprintf(w, "%s\t%s\n", algoName(algorithmNames, alg.ID), algorithmAttributesString(alg.Attributes))
+	}
+	fmt.Fprintln(w, "\nPCR BANK\tPCRS")
+	for _, bank := range info.PCRBanks {
+		fmt.Fprintf(w, "%s\t%s\n", algoName(algorithmNames, bank.Hash), client.FormatPCRs(bank.PCRs))
+	}
+	fmt.Fprintln(w, "\nLIMIT\tVALUE")
+	for _, limit := range limitNames {
+		if value, ok := info.Properties[limit.prop]; ok {
+			fmt.Fprintf(w, "%s\t%d\n", limit.name, value)
+		}
+	}
+	return w.Flush()
+}
+
+type infoAlgorithm struct {
+	Name       string   `json:"name"`
+	Attributes []string `json:"attributes"`
+}
+
+type infoPCRBank struct {
+	Hash string `json:"hash"`
+	PCRs []int  `json:"pcrs"`
+}
+
+type infoOutput struct {
+	Manufacturer    string            `json:"manufacturer"`
+	Vendor          string            `json:"vendor"`
+	FirmwareVersion string            `json:"firmware_version"`
+	Specification   string            `json:"specification"`
+	Algorithms      []infoAlgorithm   `json:"algorithms"`
+	PCRBanks        []infoPCRBank     `json:"pcr_banks"`
+	Limits          map[string]uint32 `json:"limits"`
+}
+
+func writeInfoJSON(info *client.TPMInfo) error {
+	out := infoOutput{
+		Manufacturer:    info.Manufacturer,
+		Vendor:          info.VendorString,
+		FirmwareVersion: fmt.Sprintf("0x%016x", info.FirmwareVersion),
+		Specification:   specString(info),
+		Algorithms:      []infoAlgorithm{},
+		PCRBanks:        []infoPCRBank{},
+		Limits:          map[string]uint32{},
+	}
+	for _, alg := range info.Algorithms {
+		attrs := []string{}
+		if s := algorithmAttributesString(alg.Attributes); s != "" {
+			attrs = strings.Split(s, "|")
+		}
+		out.Algorithms = append(out.Algorithms, infoAlgorithm{algoName(algorithmNames, alg.ID), attrs})
+	}
+	for _, bank := range info.PCRBanks {
+		out.PCRBanks = append(out.PCRBanks, infoPCRBank{algoName(algorithmNames, bank.Hash), bank.PCRs})
+	}
+	for _, limit := range limitNames {
+		if value, ok := info.Properties[limit.prop]; ok {
+			out.Limits[limit.name] = value
+		}
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = dataOutput().Write(append(data, '\n'))
+	return err
+}
+
+func init() {
+	RootCmd.AddCommand(infoCmd)
+	infoCmd.PersistentFlags().StringVar(&infoFormat, "format", infoTable,
+		"output format: "+strings.Join([]string{infoTable, infoJSON}, ", "))
+	infoCmd.PersistentFlags().BoolVar(&infoFullTest, "full-test", false,
+		"test all TPM functions, not only those not yet tested")
+	addOutputFlag(infoCmd)
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestInfo(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+	defer func() { infoFormat = infoTable }()
+
+	outFile := makeTempFile(t, nil)
+	defer os.Remove(outFile)
+	RootCmd.SetArgs([]string{"info", "--output", outFile})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadFile(outFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Manufacturer:", "sha256", "pcr-count"} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("info output %q does not contain %q", out, want)
+		}
+	}
+
+	RootCmd.SetArgs([]string{"info", "--format", "json", "--full-test", "--output", outFile})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if out, err = ioutil.ReadFile(outFile); err != nil {
+		t.Fatal(err)
+	}
+	var info infoOutput
+	if err = json.Unmarshal(out, &info); err != nil {
+		t.Fatalf("failed to parse JSON output: %v", err)
+	}
+	if len(info.PCRBanks) == 0 || info.Limits["pcr-count"] != client.NumPCRs {
+		t.Errorf("unexpected JSON output %s", out)
+	}
+}
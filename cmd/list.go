@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/spf13/cobra"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+)
+
+var handleTypeNames = map[tpm2.HandleType]string{
+	tpm2.HandleTypePersistent:    "persistent",
+	tpm2.HandleTypeTransient:     "transient",
+	tpm2.HandleTypeLoadedSession: "loaded session",
+	tpm2.HandleTypeSavedSession:  "saved session",
+}
+
+var objectTypeNames = map[tpm2.Algorithm]string{
+	tpm2.AlgRSA:       "rsa",
+	tpm2.AlgECC:       "ecc",
+	tpm2.AlgKeyedHash: "keyedhash",
+	tpm2.AlgSymCipher: "symcipher",
+}
+
+// Object attributes in the order of TPMA_OBJECT bits.
+var attributeNames = []struct {
+	flag tpm2.KeyProp
+	name string
+}{
+	{tpm2.FlagFixedTPM, "fixedTPM"},
+	{tpm2.FlagStClear, "stClear"},
+	{tpm2.FlagFixedParent, "fixedParent"},
+	{tpm2.FlagSensitiveDataOrigin, "sensitiveDataOrigin"},
+	{tpm2.FlagUserWithAuth, "userWithAuth"},
+	{tpm2.FlagAdminWithPolicy, "adminWithPolicy"},
+	{tpm2.FlagNoDA, "noDA"},
+	{tpm2.FlagRestricted, "restricted"},
+	{tpm2.FlagDecrypt, "decrypt"},
+	{tpm2.FlagSign, "sign"},
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the active handles on the TPM",
+	Long: `List all persistent, transient, and session handles on the TPM
+
+For each object (persistent or transient handle), the type, name algorithm and
+attributes of its public area are also printed.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+
+		infos, err := client.DescribeHandles(rwc)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(dataOutput(), 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "HANDLE\tKIND\tTYPE\tNAME ALG\tATTRIBUTES")
+		for _, info := range infos {
+			if info.Public == nil {
+				fmt.Fprintf(w, "0x%08x\t%s\t\t\t\n", info.Handle, handleTypeNames[info.Type])
+				continue
+			}
+			fmt.Fprintf(w, "0x%08x\t%s\t%s\t%s\t%s\n", info.Handle, handleTypeNames[info.Type],
+				algoName(objectTypeNames, info.Public.Type), algoName(algos, info.Public.NameAlg),
+				attributesString(info.Public.Attributes))
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(listCmd)
+	addOutputFlag(listCmd)
+}
+
+func algoName(names map[tpm2.Algorithm]string, alg tpm2.Algorithm) string {
+	if name, ok := names[alg]; ok && name != "" {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", uint16(alg))
+}
+
+func attributesString(attrs tpm2.KeyProp) string {
+	var names []string
+	for _, attr := range attributeNames {
+		if attrs&attr.flag != 0 {
+			names = append(names, attr.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestAttributesString(t *testing.T) {
+	if got, want := attributesString(tpm2.FlagStorageDefault), "fixedTPM|fixedParent|sensitiveDataOrigin|userWithAuth|restricted|decrypt"; got != want {
+		t.Errorf("attributesString() = %q, want %q", got, want)
+	}
+	if got := attributesString(0); got != "" {
+		t.Errorf("attributesString(0) = %q, want empty", got)
+	}
+}
+
+func TestList(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+
+	srk, err := client.NewKey(rwc, tpm2.HandleOwner, client.SRKTemplateECC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srk.Close()
+
+	outFile := makeTempFile(t, nil)
+	defer os.Remove(outFile)
+	RootCmd.SetArgs([]string{"list", "--output", outFile})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadFile(outFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("0x%08x  transient", srk.Handle())
+	if !bytes.Contains(out, []byte(want)) {
+		t.Errorf("list output %q does not contain %q", out, want)
+	}
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+)
+
+var lockoutAuth string
+
+var lockoutCmd = &cobra.Command{
+	Use:   "lockout",
+	Short: "Inspect or reset the TPM's dictionary attack lockout",
+	Long: `Inspect or reset the TPM's dictionary attack lockout
+
+The TPM counts failed authorizations, and refuses to authorize most objects
+once too many have failed (until the failures are forgotten over time, or the
+lockout is reset).`,
+	Args: cobra.NoArgs,
+}
+
+var lockoutStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Print the state of the TPM's dictionary attack protection",
+	Long: `Print the state of the TPM's dictionary attack protection
+
+The number of failed authorizations, the number causing lockout, the time after
+which failures are forgotten, and whether the TPM is in lockout are printed.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+
+		info, err := client.GetLockoutInfo(rwc)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(dataOutput(), 0, 8, 2, ' ', 0)
+		fmt.Fprintf(w, "In lockout:\t%v\n", info.InLockout)
+		fmt.Fprintf(w, "Failed tries:\t%d\n", info.FailedTries)
+		fmt.Fprintf(w, "Max tries:\t%d\n", info.MaxTries)
+		fmt.Fprintf(w, "Recovery time:\t%v\n", info.RecoveryTime)
+		fmt.Fprintf(w, "Lockout auth recovery time:\t%v\n", info.LockoutRecovery)
+		return w.Flush()
+	},
+}
+
+var lockoutResetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Reset the TPM's dictionary attack lockout",
+	Long: `Reset the TPM's count of failed authorizations, ending any lockout
+
+This is authorized with the lockout hierarchy's --auth password (empty by
+default). If the password is wrong, the lockout hierarchy itself is locked out
+for some time (see "gotpm lockout status").`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+
+		if err = client.ResetLockout(rwc, lockoutAuth); err != nil {
+			return err
+		}
+		fmt.Fprintln(messageOutput(), "Dictionary attack lockout reset")
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(lockoutCmd)
+	lockoutCmd.AddCommand(lockoutStatusCmd)
+	lockoutCmd.AddCommand(lockoutResetCmd)
+	addOutputFlag(lockoutStatusCmd)
+	lockoutResetCmd.PersistentFlags().StringVar(&lockoutAuth, "auth", "",
+		"authorization value of the lockout hierarchy")
+}
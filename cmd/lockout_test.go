@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestLockout(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+
+	RootCmd.SetArgs([]string{"lockout", "reset"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	outFile := makeTempFile(t, nil)
+	defer os.Remove(outFile)
+	RootCmd.SetArgs([]string{"lockout", "status", "--output", outFile})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadFile(outFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"In lockout:", "false", "Failed tries:", "0"} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("lockout status output %q does not contain %q", out, want)
+		}
+	}
+}
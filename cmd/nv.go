@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+	"github.com/spf13/cobra"
+)
+
+// Attributes of indices created by nvdefine. The index can be read and written
+// using its auth value, and can also be read using owner authorization (as
+// done by "gotpm read nvdata").
+const nvDefineAttrs = tpm2.AttrOwnerRead | tpm2.AttrAuthRead | tpm2.AttrAuthWrite
+
+var nvDefineCmd = &cobra.Command{
+	Use:   "nvdefine",
+	Short: "Define a TPM NV index",
+	Long: `Define an NV index of the given size in the owner hierarchy
+
+Based on the --index and --size flags, this defines a new NV index. The index is
+protected with the --auth password (empty by default), which is needed to read
+or write the index with nvread and nvwrite. The index can also be read with
+"gotpm read nvdata".`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if nvSize == 0 {
+			return errors.New("--size must be greater than 0")
+		}
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+
+		if err = client.DefineNV(rwc, tpmutil.Handle(nvIndex), nvSize, nvDefineAttrs, nvAuth, nil); err != nil {
+			return err
+		}
+		fmt.Fprintf(messageOutput(), "NV index 0x%x defined\n", nvIndex)
+		return nil
+	},
+}
+
+var nvUndefineCmd = &cobra.Command{
+	Use:   "nvundefine",
+	Short: "Remove a TPM NV index",
+	Long: `Remove an NV index from the owner hierarchy
+
+Based on the --index flag, this removes the NV index and any data it contains.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+
+		if err = client.UndefineNV(rwc, tpmutil.Handle(nvIndex)); err != nil {
+			return err
+		}
+		fmt.Fprintf(messageOutput(), "NV index 0x%x removed\n", nvIndex)
+		return nil
+	},
+}
+
+var nvWriteCmd = &cobra.Command{
+	Use:   "nvwrite",
+	Short: "Write data to a TPM NV index",
+	Long: `Write data to an NV index
+
+Based on the --index flag, this writes the input data to the start of the NV
+index. The write is authorized with the --auth password.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+
+		data, err := ioutil.ReadAll(dataInput())
+		if err != nil {
+			return fmt.Errorf("cannot read input: %w", err)
+		}
+		fmt.Fprintf(debugOutput(), "Writing %d bytes to NV index 0x%x\n", len(data), nvIndex)
+		if err = client.NVWrite(rwc, tpmutil.Handle(nvIndex), &client.NVAuth{Password: nvAuth}, data); err != nil {
+			return err
+		}
+		fmt.Fprintf(messageOutput(), "%d bytes written\n", len(data))
+		return nil
+	},
+}
+
+var nvReadIndexCmd = &cobra.Command{
+	Use:   "nvread",
+	Short: "Read data from a TPM NV index",
+	Long: `Read data from an NV index
+
+Based on the --index flag, this reads all of the data present at the NV index.
+The read is authorized with the --auth password. Use "gotpm read nvdata" to read
+an index with owner authorization instead.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+
+		data, err := client.NVRead(rwc, tpmutil.Handle(nvIndex), &client.NVAuth{Password: nvAuth})
+		if err != nil {
+			return err
+		}
+		if _, err := dataOutput().Write(data); err != nil {
+			return fmt.Errorf("cannot output NVData: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{nvDefineCmd, nvUndefineCmd, nvWriteCmd, nvReadIndexCmd} {
+		RootCmd.AddCommand(cmd)
+		addIndexFlag(cmd)
+		cmd.MarkPersistentFlagRequired("index")
+	}
+	addSizeFlag(nvDefineCmd)
+	nvDefineCmd.MarkPersistentFlagRequired("size")
+	addAuthFlag(nvDefineCmd)
+	addAuthFlag(nvWriteCmd)
+	addInputFlag(nvWriteCmd)
+	addAuthFlag(nvReadIndexCmd)
+	addOutputFlag(nvReadIndexCmd)
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestNVDefineWriteRead(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+
+	data := []byte("provisioning data")
+	inFile := makeTempFile(t, data)
+	defer os.Remove(inFile)
+	outFile := makeTempFile(t, nil)
+	defer os.Remove(outFile)
+
+	RootCmd.SetArgs([]string{"nvdefine", "--quiet", "--index=0x1500000", "--size=17", "--auth=password"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		RootCmd.SetArgs([]string{"nvundefine", "--quiet", "--index=0x1500000"})
+		if err := RootCmd.Execute(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	commands := [][]string{
+		{"nvwrite", "--quiet", "--index=0x1500000", "--auth=password", "--input", inFile},
+		{"nvread", "--quiet", "--index=0x1500000", "--auth=password", "--output", outFile},
+	}
+	for _, args := range commands {
+		RootCmd.SetArgs(args)
+		if err := RootCmd.Execute(); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+	}
+
+	got, err := ioutil.ReadFile(outFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("got NV data %q, want %q", got, data)
+	}
+
+	RootCmd.SetArgs([]string{"nvread", "--quiet", "--index=0x1500000", "--auth=wrong", "--output", outFile})
+	if err := RootCmd.Execute(); err == nil {
+		t.Error("expected failure when reading with the wrong password")
+	}
+}
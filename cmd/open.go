@@ -3,6 +3,8 @@ package cmd
 import (
 	"fmt"
 	"io"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
 )
 
 // ExternalTPM can be set to run tests against an TPM initialized by an
@@ -20,6 +22,15 @@ func (ic ignoreClose) Close() error {
 	return nil
 }
 
+// EventLog and IMALog forward to the ExternalTPM, so it can override them.
+func (ic ignoreClose) EventLog() ([]byte, error) {
+	return client.GetEventLog(ic.ReadWriter)
+}
+
+func (ic ignoreClose) IMALog() ([]byte, error) {
+	return client.GetIMALog(ic.ReadWriter)
+}
+
 func openTpm() (io.ReadWriteCloser, error) {
 	if ExternalTPM != nil {
 		return ignoreClose{ExternalTPM}, nil
@@ -28,5 +39,6 @@ func openTpm() (io.ReadWriteCloser, error) {
 	if err != nil {
 		return nil, fmt.Errorf("connecting to TPM: %w", err)
 	}
-	return rwc, nil
+	// Discrete TPMs can ask for commands to be retried at any time.
+	return client.NewRetryReadWriter(rwc), nil
 }
@@ -3,27 +3,41 @@
 package cmd
 
 import (
+	"fmt"
 	"io"
-	"os"
 
-	"github.com/google/go-tpm/tpm2"
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/proxy"
 )
 
-var tpmPath string
+var (
+	tpmPath       string
+	flushSessions bool
+)
 
 func init() {
 	RootCmd.PersistentFlags().StringVar(&tpmPath, "tpm-path", "",
-		"path to TPM device (defaults to /dev/tpmrm0 then /dev/tpm0)")
+		"path to TPM device (defaults to /dev/tpmrm0 then /dev/tpm0), URL of a software TPM (tcp://, swtpm:// or unix://), or URL of a TPM proxy (http:// or https://)")
+	RootCmd.PersistentFlags().BoolVar(&flushSessions, "flush-sessions", false,
+		"flush the sessions left in the TPM device after opening it (only if no other process uses the TPM, such as with /dev/tpm0)")
 }
 
 // On Linux, we have to pass in the TPM path though a flag
 func openImpl() (io.ReadWriteCloser, error) {
-	if tpmPath == "" {
-		tpm, err := tpm2.OpenTPM("/dev/tpmrm0")
-		if os.IsNotExist(err) {
-			tpm, err = tpm2.OpenTPM("/dev/tpm0")
-		}
-		return tpm, err
+	if proxy.IsProxyURL(tpmPath) {
+		return openProxy(tpmPath)
+	}
+	if client.IsRemoteTPMPath(tpmPath) {
+		return client.OpenRemoteTPM(tpmPath)
+	}
+	var paths []string
+	if tpmPath != "" {
+		paths = []string{tpmPath}
+	}
+	rwc, path, err := client.OpenTPMWithOpts(client.OpenOpts{Paths: paths, FlushSessions: flushSessions})
+	if err != nil {
+		return nil, err
 	}
-	return tpm2.OpenTPM(tpmPath)
+	fmt.Fprintf(debugOutput(), "Using TPM device %s\n", path)
+	return rwc, nil
 }
@@ -3,10 +3,25 @@ package cmd
 import (
 	"io"
 
-	"github.com/google/go-tpm/tpm2"
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/proxy"
 )
 
-// There is no need for flags on Windows, as there is no concept of a TPM path.
+var tpmPath string
+
+func init() {
+	RootCmd.PersistentFlags().StringVar(&tpmPath, "tpm-path", "",
+		"URL of a software TPM (tcp://, swtpm:// or unix://) or of a TPM proxy (http:// or https://), defaults to using TBS")
+}
+
+// There is no concept of a TPM path on Windows, so only software TPMs and TPM
+// proxies can be selected.
 func openImpl() (io.ReadWriteCloser, error) {
-	return tpm2.OpenTPM()
+	if proxy.IsProxyURL(tpmPath) {
+		return openProxy(tpmPath)
+	}
+	if tpmPath != "" {
+		return client.OpenRemoteTPM(tpmPath)
+	}
+	return client.OpenTPM()
 }
@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-attestation/attest"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+	"github.com/ThalesIgnite/go-tpm-tools/server"
+)
+
+// Supported output formats for the pcrs read command.
+const (
+	pcrsHex  = "hex"
+	pcrsJSON = "json"
+	pcrsYAML = "yaml"
+)
+
+var (
+	pcrsHashAlgo = tpm2.AlgSHA256
+	pcrsFormat   = pcrsHex
+	// The pb.PCRs files and event log compared by "gotpm pcrs diff".
+	pcrsBefore   string
+	pcrsAfter    string
+	pcrsEventLog string
+)
+
+var pcrsCmd = &cobra.Command{
+	Use:   "pcrs",
+	Short: "Work with the TPM's PCRs",
+	Long:  `Work with the TPM's Platform Configuration Registers (PCRs)`,
+	Args:  cobra.NoArgs,
+}
+
+var pcrsReadCmd = &cobra.Command{
+	Use:   "read",
+	Short: "Read PCR values from the TPM",
+	Long: `Read PCR values from the TPM, for example to capture golden values
+
+The PCRs given with --pcrs (all PCRs by default, ranges such as 0-7 are
+allowed) are read from the bank given with --hash (sha256 by default). The
+values are printed in hex, or written with --format as JSON, YAML, or a pb.PCRs
+protobuf (binarypb or textproto).`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !isPCRsFormat(pcrsFormat) {
+			return fmt.Errorf("unknown format: %q", pcrsFormat)
+		}
+		sel := tpm2.PCRSelection{Hash: pcrsHashAlgo, PCRs: pcrs}
+		if len(sel.PCRs) == 0 {
+			sel = client.FullPcrSel(sel.Hash)
+		}
+
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+
+		fmt.Fprintf(debugOutput(), "Reading PCRs %s\n", client.FormatPCRSelection(sel))
+		values, err := client.ReadPCRs(rwc, sel)
+		if err != nil {
+			return err
+		}
+		return writePCRs(dataOutput(), values)
+	},
+}
+
+var pcrsDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Explain which PCRs changed between two boots",
+	Long: `Explain which PCRs changed between two boots, using the event log
+
+The --before and --after files contain the PCR values of two boots, as written
+by "gotpm pcrs read --format binarypb" (or another protobuf --format). This is
+useful to find out why data sealed in the earlier boot stopped unsealing, for
+example after a firmware update.
+
+For each changed PCR, the events of the --eventlog (which must be the event
+log of the --after boot) extended into the PCR are shown. If the PCR had its
+--before value partway through the events, the events after that point account
+for the change. Otherwise, one of the events was measured differently in the
+earlier boot (such as an updated firmware or boot loader).`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		before, err := readPCRsFile(pcrsBefore)
+		if err != nil {
+			return err
+		}
+		after, err := readPCRsFile(pcrsAfter)
+		if err != nil {
+			return err
+		}
+		eventLog, err := ioutil.ReadFile(pcrsEventLog)
+		if err != nil {
+			return err
+		}
+		diffs, err := server.DiffPCRs(before, after, eventLog)
+		if err != nil {
+			return err
+		}
+		return writePCRDiffs(dataOutput(), before, after, diffs)
+	},
+}
+
+func readPCRsFile(file string) (*pb.PCRs, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	values := &pb.PCRs{}
+	if err = unmarshalProto(data, values); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", file, err)
+	}
+	return values, nil
+}
+
+func writePCRDiffs(w io.Writer, before, after *pb.PCRs, diffs []*server.PCRDiff) error {
+	changed := map[uint32]bool{}
+	var b strings.Builder
+	for _, diff := range diffs {
+		changed[diff.Index] = true
+		fmt.Fprintf(&b, "PCR%d changed\n  before: %x\n  after:  %x\n", diff.Index, diff.Before, diff.After)
+		if !diff.Replayed {
+			b.WriteString("  warning: the events do not replay to the after value, the event log is not of that boot\n")
+		}
+		events := diff.Events
+		if diff.Common >= 0 {
+			fmt.Fprintf(&b, "  the PCR had the before value after %d of %d events, the change is from:\n", diff.Common, len(diff.Events))
+			events = events[diff.Common:]
+		} else {
+			b.WriteString("  the PCR never had the before value, one of these events was measured differently:\n")
+		}
+		for _, event := range events {
+			fmt.Fprintf(&b, "    %s %x", attest.EventType(event.GetUntrustedType()), event.GetDigest())
+			if description := server.DescribeEvent(event); description != "" {
+				fmt.Fprintf(&b, " %q", description)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	var unchanged []int
+	for index := range after.GetPcrs() {
+		if _, ok := before.GetPcrs()[index]; ok && !changed[index] {
+			unchanged = append(unchanged, int(index))
+		}
+	}
+	sort.Ints(unchanged)
+	if len(diffs) == 0 {
+		b.WriteString("No PCRs changed\n")
+	}
+	if len(unchanged) > 0 {
+		indexes := make([]string, len(unchanged))
+		for i, index := range unchanged {
+			indexes[i] = strconv.Itoa(index)
+		}
+		fmt.Fprintf(&b, "Unchanged: PCRs %s\n", strings.Join(indexes, ", "))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func isPCRsFormat(f string) bool {
+	switch f {
+	case pcrsHex, pcrsJSON, pcrsYAML, formatBinary, formatText:
+		return true
+	}
+	return false
+}
+
+// The JSON and YAML encoding of PCR values.
+type pcrsOutput struct {
+	Hash string            `json:"hash"`
+	PCRs map[uint32]string `json:"pcrs"`
+}
+
+func writePCRs(w io.Writer, values *pb.PCRs) error {
+	var data []byte
+	var err error
+	switch pcrsFormat {
+	case pcrsHex:
+		return notinternal.FormatPCRs(w, values)
+	case formatBinary:
+		data, err = proto.Marshal(values)
+	case formatText:
+		data, err = marshalOptions.Marshal(values)
+	case pcrsJSON:
+		if data, err = json.MarshalIndent(newPCRsOutput(values), "", "  "); err == nil {
+			data = append(data, '\n')
+		}
+	case pcrsYAML:
+		data = marshalPCRsYAML(newPCRsOutput(values))
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func newPCRsOutput(values *pb.PCRs) pcrsOutput {
+	out := pcrsOutput{
+		Hash: algos[tpm2.Algorithm(values.GetHash())],
+		PCRs: map[uint32]string{},
+	}
+	for pcr, value := range values.GetPcrs() {
+		out.PCRs[pcr] = hex.EncodeToString(value)
+	}
+	return out
+}
+
+// The output only contains strings and numbers, so a YAML library is not
+// needed. The digests are quoted, as a hex digest could be parsed as a number.
+func marshalPCRsYAML(out pcrsOutput) []byte {
+	pcrNums := make([]int, 0, len(out.PCRs))
+	for pcr := range out.PCRs {
+		pcrNums = append(pcrNums, int(pcr))
+	}
+	sort.Ints(pcrNums)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "hash: %s\npcrs:\n", out.Hash)
+	for _, pcr := range pcrNums {
+		fmt.Fprintf(&b, "  %d: \"%s\"\n", pcr, out.PCRs[uint32(pcr)])
+	}
+	return []byte(b.String())
+}
+
+func init() {
+	RootCmd.AddCommand(pcrsCmd)
+	hideHelp(pcrsCmd)
+	pcrsCmd.AddCommand(pcrsReadCmd)
+	hash := algoFlag{&pcrsHashAlgo, []tpm2.Algorithm{tpm2.AlgSHA1, tpm2.AlgSHA256, tpm2.AlgSHA384, tpm2.AlgSHA512, client.AlgSM3}}
+	pcrsReadCmd.PersistentFlags().Var(&hash, "hash", "PCR bank: "+hash.Allowed())
+	addPCRsFlag(pcrsReadCmd, &pcrsHashAlgo)
+	pcrsReadCmd.PersistentFlags().StringVar(&pcrsFormat, "format", pcrsHex,
+		"output format: "+strings.Join([]string{pcrsHex, pcrsJSON, pcrsYAML, formatBinary, formatText}, ", "))
+	addOutputFlag(pcrsReadCmd)
+
+	pcrsCmd.AddCommand(pcrsDiffCmd)
+	pcrsDiffCmd.PersistentFlags().StringVar(&pcrsBefore, "before", "",
+		"file of the PCR values of the earlier boot")
+	pcrsDiffCmd.MarkPersistentFlagRequired("before")
+	pcrsDiffCmd.PersistentFlags().StringVar(&pcrsAfter, "after", "",
+		"file of the PCR values of the later boot")
+	pcrsDiffCmd.MarkPersistentFlagRequired("after")
+	pcrsDiffCmd.PersistentFlags().StringVar(&pcrsEventLog, "eventlog", "",
+		"event log of the later boot")
+	pcrsDiffCmd.MarkPersistentFlagRequired("eventlog")
+	addFormatFlag(pcrsDiffCmd)
+	addOutputFlag(pcrsDiffCmd)
+}
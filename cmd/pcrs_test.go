@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/google/go-attestation/attest"
+	"github.com/google/go-tpm/tpm2"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+	"github.com/ThalesIgnite/go-tpm-tools/server"
+)
+
+func TestPCRsRead(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+	defer func() {
+		pcrs = []int{}
+		pcrsHashAlgo = tpm2.AlgSHA256
+		pcrsFormat = pcrsHex
+	}()
+
+	sel := tpm2.PCRSelection{Hash: tpm2.AlgSHA1, PCRs: []int{0, 1, 2, 3, 7}}
+	want, err := client.ReadPCRs(rwc, sel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outFile := makeTempFile(t, nil)
+	defer os.Remove(outFile)
+	readPCRs := func(format string, pcrArgs ...string) []byte {
+		t.Helper()
+		pcrs = []int{}
+		args := append([]string{"pcrs", "read", "--format", format, "--output", outFile}, pcrArgs...)
+		RootCmd.SetArgs(args)
+		if err := RootCmd.Execute(); err != nil {
+			t.Fatalf("%v failed: %v", args, err)
+		}
+		out, err := ioutil.ReadFile(outFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	var got pb.PCRs
+	if err = proto.Unmarshal(readPCRs("binarypb", "--hash", "sha1", "--pcrs", "0-3,7"), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(&got, want) {
+		t.Errorf("got PCRs %v, want %v", &got, want)
+	}
+	got.Reset()
+	if err = unmarshalOptions.Unmarshal(readPCRs("textproto", "--pcrs", "sha1:0,1,2,3,7"), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(&got, want) {
+		t.Errorf("got PCRs %v, want %v", &got, want)
+	}
+
+	var out pcrsOutput
+	if err = json.Unmarshal(readPCRs("json", "--pcrs", "sha1:0-3,7"), &out); err != nil {
+		t.Fatalf("failed to parse JSON output: %v", err)
+	}
+	if out.Hash != "sha1" || len(out.PCRs) != len(sel.PCRs) {
+		t.Errorf("unexpected JSON output %v", out)
+	}
+	for pcr, value := range want.GetPcrs() {
+		if out.PCRs[pcr] != hex.EncodeToString(value) {
+			t.Errorf("got PCR %d value %s in JSON output, want %x", pcr, out.PCRs[pcr], value)
+		}
+	}
+
+	yaml := readPCRs("yaml", "--pcrs", "sha1:0-3,7")
+	if !bytes.HasPrefix(yaml, []byte("hash: sha1\npcrs:\n  0: \"")) {
+		t.Errorf("unexpected YAML output %q", yaml)
+	}
+	for pcr, value := range want.GetPcrs() {
+		line := fmt.Sprintf("\n  %d: \"%x\"\n", pcr, value)
+		if !bytes.Contains(yaml, []byte(line)) {
+			t.Errorf("YAML output %q does not contain %q", yaml, line)
+		}
+	}
+
+	// All the PCRs of the SHA256 bank are read by default.
+	pcrsHashAlgo = tpm2.AlgSHA256
+	hexOut := readPCRs("hex")
+	if !bytes.HasPrefix(hexOut, []byte("SHA256:\n")) || !bytes.Contains(hexOut, []byte("\n  23: 0x")) {
+		t.Errorf("unexpected hex output %q", hexOut)
+	}
+}
+
+func TestPCRsReadFail(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+	defer func() {
+		pcrs = []int{}
+		pcrsFormat = pcrsHex
+	}()
+
+	for _, args := range [][]string{
+		{"pcrs", "read", "--format", "xml"},
+		{"pcrs", "read", "--hash", "md5"},
+		{"pcrs", "read", "--pcrs", "7-24"},
+	} {
+		pcrs = []int{}
+		pcrsFormat = pcrsHex
+		RootCmd.SetArgs(args)
+		if err := RootCmd.Execute(); err == nil {
+			t.Errorf("%v should fail", args)
+		}
+	}
+}
+
+func TestPCRsDiff(t *testing.T) {
+	defer func() { format = formatBinary }()
+
+	// The PCR values of the boot of the event log.
+	eventLog, err := attest.ParseEventLog(test.Debian10EventLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []*attestpb.Event
+	for _, event := range eventLog.Events(attest.HashSHA1) {
+		events = append(events, &attestpb.Event{PcrIndex: uint32(event.Index), UntrustedType: uint32(event.Type), Data: event.Data, Digest: event.Digest})
+	}
+	after, err := server.PredictPCRs(pb.HashAlgo_SHA1, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := proto.Clone(after).(*pb.PCRs)
+	before.Pcrs[4] = bytes.Repeat([]byte{1}, 20)
+
+	writePCRsFile := func(values *pb.PCRs) string {
+		data, err := marshalOptions.Marshal(values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return makeTempFile(t, data)
+	}
+	beforeFile := writePCRsFile(before)
+	defer os.Remove(beforeFile)
+	afterFile := writePCRsFile(after)
+	defer os.Remove(afterFile)
+	logFile := makeTempFile(t, test.Debian10EventLog)
+	defer os.Remove(logFile)
+	outFile := makeTempFile(t, nil)
+	defer os.Remove(outFile)
+
+	RootCmd.SetArgs([]string{"pcrs", "diff", "--before", beforeFile, "--after", afterFile,
+		"--eventlog", logFile, "--format", formatText, "--output", outFile})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadFile(outFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"PCR4 changed", "the PCR never had the before value", "EV_EFI_BOOT_SERVICES_APPLICATION", "Unchanged: PCRs 0, 1, 2, 3, 5"} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+	if bytes.Contains(out, []byte("warning")) {
+		t.Errorf("event log does not replay:\n%s", out)
+	}
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+	"github.com/spf13/cobra"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+)
+
+// The keys which can be persisted, by hierarchy and template for each --algo.
+// They are created as transient keys, as the cached keys returned by
+// getEK, getSRK and getAK may already be persistent.
+var persistKeys = map[string]struct {
+	hierarchy tpmutil.Handle
+	templates map[tpm2.Algorithm]func() tpm2.Public
+}{
+	"ek": {tpm2.HandleEndorsement, map[tpm2.Algorithm]func() tpm2.Public{
+		tpm2.AlgRSA: client.DefaultEKTemplateRSA,
+		tpm2.AlgECC: client.DefaultEKTemplateECC,
+	}},
+	"srk": {tpm2.HandleOwner, map[tpm2.Algorithm]func() tpm2.Public{
+		tpm2.AlgRSA: client.SRKTemplateRSA,
+		tpm2.AlgECC: client.SRKTemplateECC,
+	}},
+	"ak": {tpm2.HandleOwner, map[tpm2.Algorithm]func() tpm2.Public{
+		tpm2.AlgRSA: client.AKTemplateRSA,
+		tpm2.AlgECC: client.AKTemplateECC,
+	}},
+}
+
+var persistCmd = &cobra.Command{
+	Use:   "persist <ek | srk | ak> <handle>",
+	Short: "Persist a key to a handle in the TPM's NVRAM",
+	Long: `Create a key and make it persistent at the specified handle
+
+The key (using --algo, rsa by default) is created from its standard template
+and persisted at the handle (in the range 0x81000000 to 0x81FFFFFF), so that it
+survives reboots and does not need to be regenerated. Use "gotpm evict" to
+remove it.`,
+	ValidArgs: []string{"ek", "srk", "ak"},
+	Args:      cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		persistKey, ok := persistKeys[args[0]]
+		if !ok {
+			return fmt.Errorf("unknown key %q", args[0])
+		}
+		handle, err := parseHandle(args[1])
+		if err != nil {
+			return err
+		}
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+
+		fmt.Fprintf(debugOutput(), "Creating %s\n", args[0])
+		key, err := client.NewKey(rwc, persistKey.hierarchy, persistKey.templates[keyAlgo]())
+		if err != nil {
+			return err
+		}
+		defer key.Close()
+		if err = key.Persist(handle); err != nil {
+			return err
+		}
+		fmt.Fprintf(messageOutput(), "Key persisted at 0x%x\n", handle)
+		return nil
+	},
+}
+
+var evictCmd = &cobra.Command{
+	Use:   "evict <handle>",
+	Short: "Evict a persistent object from the TPM's NVRAM",
+	Long: `Remove the persistent object at the specified handle
+
+This is the opposite of "gotpm persist". Note that evicting a key which cannot
+be regenerated (i.e. one not created from a template) results in data loss.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		handle, err := parseHandle(args[0])
+		if err != nil {
+			return err
+		}
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+
+		if err = client.EvictPersistent(rwc, handle); err != nil {
+			return err
+		}
+		fmt.Fprintf(messageOutput(), "Handle 0x%x evicted\n", handle)
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(persistCmd)
+	RootCmd.AddCommand(evictCmd)
+	addPublicKeyAlgoFlag(persistCmd)
+}
+
+// parseHandle parses a handle in decimal or (0x prefixed) hexadecimal.
+func parseHandle(s string) (tpmutil.Handle, error) {
+	h, err := strconv.ParseUint(s, 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid handle %q: %w", s, err)
+	}
+	return tpmutil.Handle(h), nil
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestPersistEvict(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+
+	handle := tpmutil.Handle(0x81008F20)
+	RootCmd.SetArgs([]string{"persist", "srk", "0x81008F20", "--quiet"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	pub, _, _, err := tpm2.ReadPublic(rwc, handle)
+	if err != nil {
+		t.Fatalf("reading persisted key: %v", err)
+	}
+	if !pub.MatchesTemplate(client.SRKTemplateRSA()) {
+		t.Error("persisted key does not match the SRK template")
+	}
+
+	RootCmd.SetArgs([]string{"evict", "0x81008F20", "--quiet"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err = tpm2.ReadPublic(rwc, handle); err == nil {
+		t.Error("expected key to be evicted")
+	}
+
+	RootCmd.SetArgs([]string{"evict", "not-a-handle", "--quiet"})
+	if err := RootCmd.Execute(); err == nil {
+		t.Error("expected failure evicting an invalid handle")
+	}
+}
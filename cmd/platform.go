@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+)
+
+// The name of the client.Platform of the TPM. If empty, the TPM is a
+// bare-metal TPM.
+var platform string
+
+// The platforms which certify their AKs. The root CAs of these certificates
+// are not bundled, so "gotpm verify" must be given them with --trusted-root.
+// On other platforms, the AK must be trusted with --trusted-ak.
+var platformAKCerts = map[string]bool{
+	client.GCE.Name():   true,
+	client.Azure.Name(): true,
+}
+
+// Lets this command specify the platform of the TPM.
+func addPlatformFlag(cmd *cobra.Command) {
+	var names []string
+	for _, p := range client.Platforms() {
+		names = append(names, p.Name())
+	}
+	cmd.PersistentFlags().StringVar(&platform, "platform", "",
+		"platform of the TPM: "+strings.Join(names, ", ")+" (default "+client.BareMetal.Name()+")")
+}
+
+// getPlatform returns the client.Platform named by the --platform.
+func getPlatform() (client.Platform, error) {
+	if platform == "" {
+		return client.BareMetal, nil
+	}
+	for _, p := range client.Platforms() {
+		if p.Name() == platform {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown platform %q", platform)
+}
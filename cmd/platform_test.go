@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+func TestPlatform(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+	defer func() { platform, verifyTrustedAKs, verifyPolicy = "", nil, "" }()
+
+	// The AK persisted by Azure.
+	ak, err := client.NewKey(rwc, tpm2.HandleOwner, client.AKTemplateRSA())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ak.Persist(client.AzureAKHandle); err != nil {
+		ak.Close()
+		t.Fatal(err)
+	}
+	defer client.EvictPersistent(rwc, client.AzureAKHandle)
+	akPub, err := ak.PublicArea().Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(ak.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	akFile := makeTempFile(t, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	defer os.Remove(akFile)
+
+	reportFile := makeTempFile(t, nil)
+	defer os.Remove(reportFile)
+	RootCmd.SetArgs([]string{"attest", "--nonce", "abcd", "--platform", "azure", "--format", formatBinary, "--output", reportFile})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(reportFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var attestation pb.Attestation
+	if err = unmarshalProto(data, &attestation); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(attestation.GetAkPub(), akPub) {
+		t.Error("attestation does not use the Azure AK")
+	}
+
+	// The AK has no certificate, so it must be trusted directly.
+	for _, tc := range []struct {
+		name     string
+		args     []string
+		verified bool
+	}{
+		{"NoTrustedAK", nil, false},
+		{"TrustedAK", []string{"--trusted-ak", akFile}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			verifyTrustedAKs, verifyPolicy = nil, ""
+			RootCmd.SetArgs(append([]string{"verify", "--report", reportFile, "--nonce", "abcd", "--platform", "azure",
+				"--format", formatBinary, "--output", os.DevNull}, tc.args...))
+			err := RootCmd.Execute()
+			if tc.verified && err != nil {
+				t.Errorf("verification failed: %v", err)
+			}
+			if !tc.verified && err == nil {
+				t.Error("expected verification to fail")
+			}
+		})
+	}
+
+	RootCmd.SetArgs([]string{"attest", "--nonce", "abcd", "--platform", "aws", "--format", formatBinary, "--output", reportFile})
+	if err := RootCmd.Execute(); err != nil {
+		t.Errorf("attesting on AWS failed: %v", err)
+	}
+	RootCmd.SetArgs([]string{"attest", "--nonce", "abcd", "--platform", "unknown", "--output", os.DevNull})
+	if err := RootCmd.Execute(); err == nil {
+		t.Error("expected attesting on an unknown platform to fail")
+	}
+}
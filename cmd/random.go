@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+)
+
+var (
+	randomBytes int
+	randomStir  bool
+)
+
+var randomCmd = &cobra.Command{
+	Use:   "random",
+	Short: "Generate random bytes using the TPM",
+	Long: `Write random bytes generated by the TPM's RNG to --output
+
+With --stir, random data from the operating system is mixed into the TPM's RNG
+state before generating the output, so the output is unpredictable if either
+source is good.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if randomBytes < 0 {
+			return fmt.Errorf("invalid number of bytes: %d", randomBytes)
+		}
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+
+		if randomStir {
+			fmt.Fprintln(debugOutput(), "Stirring TPM RNG")
+			seed := make([]byte, 32)
+			if _, err = io.ReadFull(rand.Reader, seed); err != nil {
+				return err
+			}
+			if err = client.StirRandom(rwc, seed); err != nil {
+				return err
+			}
+		}
+
+		fmt.Fprintf(debugOutput(), "Generating %d random bytes\n", randomBytes)
+		random := make([]byte, randomBytes)
+		if _, err = io.ReadFull(client.RandReader(rwc), random); err != nil {
+			return err
+		}
+		_, err = dataOutput().Write(random)
+		return err
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(randomCmd)
+	randomCmd.PersistentFlags().IntVar(&randomBytes, "bytes", 32,
+		"number of random bytes to generate")
+	randomCmd.PersistentFlags().BoolVar(&randomStir, "stir", false,
+		"mix random data from the operating system into the TPM's RNG first")
+	addOutputFlag(randomCmd)
+}
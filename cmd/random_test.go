@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestRandom(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+	defer func() { randomStir = false }()
+
+	outFile := makeTempFile(t, nil)
+	defer os.Remove(outFile)
+	for _, args := range [][]string{
+		{"random", "--bytes", "100", "--output", outFile},
+		{"random", "--bytes", "100", "--stir", "--output", outFile},
+	} {
+		RootCmd.SetArgs(args)
+		if err := RootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		random, err := ioutil.ReadFile(outFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(random) != 100 {
+			t.Errorf("got %d random bytes, want 100", len(random))
+		}
+	}
+}
@@ -28,7 +28,8 @@ var pcrCmd = &cobra.Command{
 Based on --hash-algo and --pcrs flags, read the contents of the TPM's PCRs.
 
 If --hash-algo is not provided, all banks of PCRs will be read.
-If --pcrs is not provided, all PCRs are read for that hash algorithm.`,
+If --pcrs is not provided, all PCRs are read for that hash algorithm. The hash
+algorithm can also be given as a prefix of --pcrs, as in --pcrs=sha256:0,1,7.`,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		rwc, err := openTpm()
@@ -43,7 +44,7 @@ If --pcrs is not provided, all PCRs are read for that hash algorithm.`,
 				sel = client.FullPcrSel(sel.Hash)
 			}
 
-			fmt.Fprintf(debugOutput(), "Reading %v PCRs (%v)\n", sel.Hash, sel.PCRs)
+			fmt.Fprintf(debugOutput(), "Reading PCRs %s\n", client.FormatPCRSelection(sel))
 			pcrs, err := client.ReadPCRs(rwc, sel)
 			if err != nil {
 				return err
@@ -51,7 +52,7 @@ If --pcrs is not provided, all PCRs are read for that hash algorithm.`,
 			return notinternal.FormatPCRs(dataOutput(), pcrs)
 		}
 		if len(pcrs) != 0 {
-			return errors.New("--hash-algo must be used with --pcrs")
+			return errors.New("--hash-algo (or a PCR bank prefix) must be used with --pcrs")
 		}
 
 		fmt.Fprintln(debugOutput(), "Reading all PCRs")
@@ -100,7 +101,7 @@ func init() {
 	readCmd.AddCommand(pcrCmd)
 	readCmd.AddCommand(nvReadCmd)
 	addOutputFlag(pcrCmd)
-	addPCRsFlag(pcrCmd)
+	addPCRsFlag(pcrCmd, &pcrHashAlgo)
 	addHashAlgoFlag(pcrCmd, &pcrHashAlgo)
 	addIndexFlag(nvReadCmd)
 	nvReadCmd.MarkPersistentFlagRequired("index")
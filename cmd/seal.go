@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -11,7 +13,12 @@ import (
 	"github.com/google/go-tpm/tpm2"
 )
 
-var sealHashAlgo = tpm2.AlgSHA256
+var (
+	sealHashAlgo = tpm2.AlgSHA256
+	sealAuth     string
+	sealEncrypt  bool
+	sealFormat   = formatText
+)
 
 var sealCmd = &cobra.Command{
 	Use:   "seal",
@@ -25,7 +32,14 @@ the encryption.
 Optionally (using the --pcrs flag), this decryption can be furthur restricted to
 only work if certain Platform Control Registers (PCRs) are in the correct state.
 This allows a key (i.e. a disk encryption key) to be bound to specific machine
-state (like Secure Boot).`,
+state (like Secure Boot).
+
+Optionally (using the --auth flag), the data can also be protected with an auth
+value (passphrase). Unsealing then requires both the correct PCR state and the
+same auth value.
+
+Optionally (using the --encrypt flag), the secret data is encrypted on the way
+to the TPM, using a session salted with the EK.`,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		rwc, err := openTpm()
@@ -40,6 +54,9 @@ state (like Secure Boot).`,
 			return err
 		}
 		defer srk.Close()
+		if err = useEncryptedSession(rwc, srk); err != nil {
+			return err
+		}
 
 		fmt.Fprintln(debugOutput(), "Reading sealed data")
 		secret, err := ioutil.ReadAll(dataInput())
@@ -48,25 +65,25 @@ state (like Secure Boot).`,
 		}
 
 		sel := tpm2.PCRSelection{Hash: sealHashAlgo, PCRs: pcrs}
-		fmt.Fprintf(debugOutput(), "Sealing to PCRs: %v\n", sel.PCRs)
+		fmt.Fprintf(debugOutput(), "Sealing to PCRs: %s\n", client.FormatPCRs(sel.PCRs))
 		var opts client.SealOpts
 		if len(sel.PCRs) > 0 {
 			opts = client.SealCurrent{PCRSelection: sel}
 		}
-		sealed, err := srk.Seal(secret, opts)
+		sealed, err := srk.SealWithAuthValue(secret, opts, sealAuth)
 		if err != nil {
 			return fmt.Errorf("sealing data: %w", err)
 		}
 
 		fmt.Fprintln(debugOutput(), "Writing sealed data")
 		var output []byte
-		if output, err = marshalOptions.Marshal(sealed); err != nil {
+		if output, err = marshalProtoFormat(sealed, sealFormat); err != nil {
 			return err
 		}
 		if _, err = dataOutput().Write(output); err != nil {
 			return err
 		}
-		fmt.Fprintf(debugOutput(), "Sealed data to PCRs: %v\n", sel.PCRs)
+		fmt.Fprintf(debugOutput(), "Sealed data to PCRs: %s\n", client.FormatPCRs(sel.PCRs))
 		return nil
 	},
 }
@@ -87,6 +104,10 @@ We do support an optional "certification" process. A list of PCRs may be
 provided with --pcrs, and the unwrapping will fail if the PCR values when
 sealing differ from the current PCR values. This allows for verification of the
 machine state when sealing took place.
+
+If the data was sealed with an auth value, the same value must be provided with
+--auth. With --encrypt, the unsealed data is encrypted on the way back from the
+TPM, using a session salted with the EK.
 `,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -102,7 +123,7 @@ machine state when sealing took place.
 			return err
 		}
 		var sealed pb.SealedBytes
-		if err := unmarshalOptions.Unmarshal(data, &sealed); err != nil {
+		if err := unmarshalProtoFormat(data, &sealed, sealFormat); err != nil {
 			return err
 		}
 
@@ -113,6 +134,9 @@ machine state when sealing took place.
 			return err
 		}
 		defer srk.Close()
+		if err = useEncryptedSession(rwc, srk); err != nil {
+			return err
+		}
 
 		fmt.Fprintln(debugOutput(), "Unsealing data")
 
@@ -121,7 +145,7 @@ machine state when sealing took place.
 		if len(certifySel.PCRs) > 0 {
 			opts = client.CertifyCurrent{PCRSelection: certifySel}
 		}
-		secret, err := srk.Unseal(&sealed, opts)
+		secret, err := srk.UnsealWithAuthValue(&sealed, sealAuth, opts)
 		if err != nil {
 			return fmt.Errorf("unsealing data: %w", err)
 		}
@@ -143,8 +167,49 @@ func init() {
 	addOutputFlag(sealCmd)
 	addOutputFlag(unsealCmd)
 	// PCRs and hash algorithm only used for sealing
-	addPCRsFlag(sealCmd)
+	addPCRsFlag(sealCmd, &sealHashAlgo)
 	addHashAlgoFlag(sealCmd, &sealHashAlgo)
-	addPCRsFlag(unsealCmd)
+	addCertifyPCRsFlag(unsealCmd)
 	addPublicKeyAlgoFlag(sealCmd)
+	addSealAuthFlag(sealCmd)
+	addSealAuthFlag(unsealCmd)
+	addSealEncryptFlag(sealCmd)
+	addSealEncryptFlag(unsealCmd)
+	addSealFormatFlag(sealCmd)
+	addSealFormatFlag(unsealCmd)
+}
+
+// Lets this command specify the encoding of the sealed data, for use with
+// sealFormat. Unlike addFormatFlag, this defaults to textproto.
+func addSealFormatFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&sealFormat, "format", formatText,
+		"encoding of the sealed data: "+strings.Join([]string{formatBinary, formatText, formatJSON}, ", "))
+}
+
+// Lets this command specify the auth value of sealed data, for use with sealAuth.
+func addSealAuthFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&sealAuth, "auth", "",
+		"auth value (passphrase) for the sealed data (defaults to empty)")
+}
+
+// Lets this command encrypt the data sent to/from the TPM, for use with
+// useEncryptedSession.
+func addSealEncryptFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&sealEncrypt, "encrypt", false,
+		"encrypt the secret data on the TPM bus, using a session salted with the EK")
+}
+
+// useEncryptedSession makes key use parameter encryption if --encrypt is set.
+// The EK matches the algorithm of key.
+func useEncryptedSession(rwc io.ReadWriter, key *client.Key) error {
+	if !sealEncrypt {
+		return nil
+	}
+	fmt.Fprintln(debugOutput(), "Starting encrypted session with EK")
+	ek, err := getEK(rwc)
+	if err != nil {
+		return err
+	}
+	defer ek.Close()
+	return key.UseEncryptedSession(ek)
 }
@@ -3,6 +3,7 @@ package cmd
 import (
 	"bytes"
 	"crypto/sha256"
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"strconv"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/ThalesIgnite/go-tpm-tools/client"
 	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
 	"github.com/google/go-tpm/tpm2"
 	"github.com/google/go-tpm/tpmutil"
 )
@@ -48,7 +50,10 @@ func TestSealPlain(t *testing.T) {
 		{"ECCCertifyWithPCR", "ecc", "", "7"},
 		{"RSASealAndCertifyWithPCR", "rsa", "7,8", "1"},
 		{"ECCSealAndCertifyWithPCR", "ecc", "7", "7,23"},
+		{"RSASealWithPCRBank", "rsa", "sha1:7,0", "sha256:7"},
+		{"ECCSealWithAllPCRs", "ecc", "sha256:all", "all"},
 	}
+	defer func() { sealHashAlgo = tpm2.AlgSHA256 }()
 	for _, op := range operations {
 		t.Run(op.name, func(t *testing.T) {
 			secretIn := []byte("Hello")
@@ -91,6 +96,72 @@ func TestSealPlain(t *testing.T) {
 	}
 }
 
+func TestSealFormats(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+	defer func() { sealFormat = formatText }()
+
+	for _, format := range []string{formatBinary, formatText, formatJSON} {
+		t.Run(format, func(t *testing.T) {
+			secretIn := []byte("Hello")
+			secretFile1 := makeTempFile(t, secretIn)
+			defer os.Remove(secretFile1)
+			sealedFile := makeTempFile(t, nil)
+			defer os.Remove(sealedFile)
+			secretFile2 := makeTempFile(t, nil)
+			defer os.Remove(secretFile2)
+
+			RootCmd.SetArgs([]string{"seal", "--quiet", "--input", secretFile1, "--output", sealedFile, "--format", format})
+			if err := RootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+			if format == formatJSON {
+				data, err := ioutil.ReadFile(sealedFile)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var sealed pb.SealedBytes
+				if err := json.Unmarshal(data, &sealed); err != nil {
+					t.Fatalf("sealed data is not valid JSON: %v", err)
+				}
+				if len(sealed.GetPriv()) == 0 || sealed.GetHeader().GetVersion() == 0 {
+					t.Errorf("decoded incomplete sealed data: %v", &sealed)
+				}
+			}
+
+			RootCmd.SetArgs([]string{"unseal", "--quiet", "--input", sealedFile, "--output", secretFile2, "--format", format})
+			if err := RootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+			secretOut, err := ioutil.ReadFile(secretFile2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(secretIn, secretOut) {
+				t.Errorf("Expected %s, got %s", secretIn, secretOut)
+			}
+		})
+	}
+}
+
+func TestUnsealPCRBank(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+	defer func() { pcrs = []int{} }()
+
+	// Certified PCRs must be in the CertifyHashAlgTpm bank.
+	RootCmd.SetArgs([]string{"unseal", "--quiet", "--pcrs", "sha1:7"})
+	if err := RootCmd.Execute(); err == nil {
+		t.Error("unsealing with certified PCRs in the SHA1 bank should fail")
+	}
+	RootCmd.SetArgs([]string{"unseal", "--quiet", "--pcrs", "sha256:24"})
+	if err := RootCmd.Execute(); err == nil {
+		t.Error("unsealing with an out of range PCR should fail")
+	}
+}
+
 func TestUnsealFail(t *testing.T) {
 	rwc := test.GetTPM(t)
 	defer client.CheckedClose(t, rwc)
@@ -146,3 +217,89 @@ func TestUnsealFail(t *testing.T) {
 		})
 	}
 }
+
+func TestSealAuthValue(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+
+	for _, sealPCRs := range []string{"", "7"} {
+		t.Run("PCRs="+sealPCRs, func(t *testing.T) {
+			secretIn := []byte("Hello")
+			secretFile := makeTempFile(t, secretIn)
+			defer os.Remove(secretFile)
+			sealedFile := makeTempFile(t, nil)
+			defer os.Remove(sealedFile)
+			secretFile2 := makeTempFile(t, nil)
+			defer os.Remove(secretFile2)
+
+			sealArgs := []string{"seal", "--quiet", "--input", secretFile, "--output", sealedFile, "--auth", "passphrase"}
+			if sealPCRs != "" {
+				sealArgs = append(sealArgs, "--pcrs", sealPCRs)
+			}
+			RootCmd.SetArgs(sealArgs)
+			if err := RootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+			pcrs = []int{} // "flush" pcrs value in last Execute() cmd
+
+			for _, auth := range []string{"", "wrong"} {
+				sealAuth = "" // "flush" auth value in last Execute() cmd
+				RootCmd.SetArgs([]string{"unseal", "--quiet", "--input", sealedFile, "--output", secretFile2, "--auth", auth})
+				if RootCmd.Execute() == nil {
+					t.Errorf("Unsealing with auth value %q should have failed", auth)
+				}
+			}
+
+			// The failed authorizations count towards the DA lockout.
+			if err := tpm2.DictionaryAttackLockReset(rwc, tpm2.AuthCommand{Session: tpm2.HandlePasswordSession, Attributes: tpm2.AttrContinueSession}); err != nil {
+				t.Fatal(err)
+			}
+			RootCmd.SetArgs([]string{"unseal", "--quiet", "--input", sealedFile, "--output", secretFile2, "--auth", "passphrase"})
+			if err := RootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+			sealAuth = ""
+			secretOut, err := ioutil.ReadFile(secretFile2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(secretIn, secretOut) {
+				t.Errorf("Expected %s, got %s", secretIn, secretOut)
+			}
+		})
+	}
+}
+
+func TestSealEncryptedSession(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+	defer func() { sealEncrypt = false }()
+
+	secretIn := []byte("Hello")
+	secretFile := makeTempFile(t, secretIn)
+	defer os.Remove(secretFile)
+	sealedFile := makeTempFile(t, nil)
+	defer os.Remove(sealedFile)
+	secretFile2 := makeTempFile(t, nil)
+	defer os.Remove(secretFile2)
+
+	RootCmd.SetArgs([]string{"seal", "--quiet", "--encrypt", "--pcrs", "7", "--input", secretFile, "--output", sealedFile})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	pcrs = []int{} // "flush" pcrs value in last Execute() cmd
+
+	RootCmd.SetArgs([]string{"unseal", "--quiet", "--encrypt", "--input", sealedFile, "--output", secretFile2})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	secretOut, err := ioutil.ReadFile(secretFile2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(secretIn, secretOut) {
+		t.Errorf("Expected %s, got %s", secretIn, secretOut)
+	}
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/google/go-tpm/tpmutil"
+	"github.com/spf13/cobra"
+
+	"github.com/ThalesIgnite/go-tpm-tools/proxy"
+)
+
+var (
+	serveAddr     string
+	serveClients  string
+	serveCertFile string
+	serveKeyFile  string
+)
+
+// proxyTokenEnv is the environment variable holding the token used to connect
+// to a TPM proxy. The token is not a flag, so that it is not visible to other
+// users in the process list.
+const proxyTokenEnv = "GOTPM_PROXY_TOKEN"
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Proxy TPM commands from the network to the TPM",
+	Long: `Proxy TPM commands from the network to the TPM
+
+The TPM is served over HTTP (or HTTPS using --cert and --key) on --listen, so
+that containers or other processes without access to the TPM device can use
+it. Use it with gotpm by setting --tpm-path to the URL of the proxy (for
+example, "http://localhost:8321"), and the token in the GOTPM_PROXY_TOKEN
+environment variable.
+
+Clients are configured in the JSON file given by --clients, for example:
+  {"clients": [
+    {"name": "app", "token": "<secret>", "commands": ["GetRandom", "PCR_Read"]},
+    {"name": "attester", "token": "<other secret>"}
+  ]}
+Each client can only run the TPM commands in its "commands" list. Clients
+without a list can create and use keys, read PCRs and NV indices, and manage
+sessions, but cannot change the TPM's persistent state. Every command is logged
+to stderr.
+
+The proxy should use the kernel's resource manager (/dev/tpmrm0) so that clients
+cannot exhaust the TPM's object slots, and do not see each other's handles.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if serveClients == "" {
+			return errors.New("--clients must be provided")
+		}
+		if (serveCertFile == "") != (serveKeyFile == "") {
+			return errors.New("--cert and --key must be provided together")
+		}
+		clients, err := readProxyClients(serveClients)
+		if err != nil {
+			return err
+		}
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+		server, err := proxy.NewServer(rwc, clients, log.New(os.Stderr, "gotpm serve: ", log.LstdFlags))
+		if err != nil {
+			return err
+		}
+		return serveHTTP(server, serveAddr, serveCertFile, serveKeyFile, "the TPM")
+	},
+}
+
+// serveHTTP serves the handler on addr (over HTTPS if certFile and keyFile are
+// not empty), until the process is interrupted.
+func serveHTTP(handler http.Handler, addr, certFile, keyFile, what string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	httpServer := &http.Server{Handler: handler}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		<-signals
+		httpServer.Close()
+	}()
+
+	fmt.Fprintf(messageOutput(), "Serving %s on %s\n", what, listener.Addr())
+	if certFile != "" {
+		err = httpServer.ServeTLS(listener, certFile, keyFile)
+	} else {
+		err = httpServer.Serve(listener)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// The format of the --clients file.
+type proxyClientsConfig struct {
+	Clients []struct {
+		Name     string    `json:"name"`
+		Token    string    `json:"token"`
+		Commands *[]string `json:"commands"`
+	} `json:"clients"`
+}
+
+func readProxyClients(path string) ([]proxy.Client, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config proxyClientsConfig
+	if err = json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid clients file: %w", err)
+	}
+	if len(config.Clients) == 0 {
+		return nil, errors.New("no clients in the clients file")
+	}
+
+	clients := make([]proxy.Client, len(config.Clients))
+	for i, c := range config.Clients {
+		clients[i] = proxy.Client{Name: c.Name, Token: c.Token}
+		if c.Commands == nil {
+			continue
+		}
+		// An empty list allows no commands, rather than the default ones.
+		clients[i].AllowedCommands = []tpmutil.Command{}
+		for _, name := range *c.Commands {
+			command, err := proxy.ParseCommand(name)
+			if err != nil {
+				return nil, fmt.Errorf("client %q: %w", c.Name, err)
+			}
+			clients[i].AllowedCommands = append(clients[i].AllowedCommands, command)
+		}
+	}
+	return clients, nil
+}
+
+// Open the TPM proxy at url, using the token from the environment.
+func openProxy(url string) (*proxy.Conn, error) {
+	token := os.Getenv(proxyTokenEnv)
+	if token == "" {
+		return nil, fmt.Errorf("%s must be set to use a TPM proxy", proxyTokenEnv)
+	}
+	return proxy.Dial(url, token, nil)
+}
+
+func init() {
+	RootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&serveAddr, "listen", "localhost:8321",
+		"address to listen on")
+	serveCmd.Flags().StringVar(&serveClients, "clients", "",
+		"JSON file of the clients, their tokens and allowed commands")
+	serveCmd.Flags().StringVar(&serveCertFile, "cert", "",
+		"PEM certificate file, to serve over HTTPS")
+	serveCmd.Flags().StringVar(&serveKeyFile, "key", "",
+		"PEM private key file, to serve over HTTPS")
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+)
+
+func TestReadProxyClients(t *testing.T) {
+	file := makeTempFile(t, []byte(`{"clients": [
+		{"name": "default", "token": "a"},
+		{"name": "none", "token": "b", "commands": []},
+		{"name": "some", "token": "c", "commands": ["TPM2_GetRandom", "PCR_Read", "0x158"]}
+	]}`))
+	defer os.Remove(file)
+
+	clients, err := readProxyClients(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clients) != 3 {
+		t.Fatalf("got %d clients, want 3", len(clients))
+	}
+	if clients[0].AllowedCommands != nil {
+		t.Errorf("client without commands should use the default commands, got %v", clients[0].AllowedCommands)
+	}
+	if clients[1].AllowedCommands == nil || len(clients[1].AllowedCommands) != 0 {
+		t.Errorf("client with an empty list should have no commands, got %v", clients[1].AllowedCommands)
+	}
+	commands := clients[2].AllowedCommands
+	if len(commands) != 3 || commands[0] != tpm2.CmdGetRandom || commands[1] != tpm2.CmdPCRRead || commands[2] != tpm2.CmdQuote {
+		t.Errorf("unexpected commands %v", commands)
+	}
+}
+
+func TestReadProxyClientsInvalid(t *testing.T) {
+	for _, config := range []string{
+		`{"clients": []}`,
+		`{"clients": [{"name": "c", "token": "t", "commands": ["NotACommand"]}]}`,
+		`not json`,
+	} {
+		file := makeTempFile(t, []byte(config))
+		defer os.Remove(file)
+		if _, err := readProxyClients(file); err == nil {
+			t.Errorf("readProxyClients(%s) should fail", config)
+		}
+	}
+}
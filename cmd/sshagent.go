@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/ThalesIgnite/go-tpm-tools/sshagent"
+)
+
+var (
+	sshSocket string
+	sshKeyDir string
+)
+
+var sshAgentCmd = &cobra.Command{
+	Use:   "ssh-agent",
+	Short: "Run an SSH agent using keys stored in the TPM",
+	Long: `Run an SSH agent using keys stored in the TPM
+
+The agent listens on the unix socket given by --socket, and can be used by
+OpenSSH clients by setting SSH_AUTH_SOCK to the socket path. The agent serves
+the keys stored in --dir, which are created with "gotpm ssh-agent keygen". The
+private keys never leave the TPM, and can only be used on this TPM.
+
+Keys cannot be added to or removed from the agent with ssh-add. To remove a key,
+delete its file from --dir.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if sshSocket == "" {
+			return errors.New("--socket must be provided")
+		}
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+		dir, err := getSSHKeyDir()
+		if err != nil {
+			return err
+		}
+		sshAgent, err := sshagent.New(rwc, dir)
+		if err != nil {
+			return err
+		}
+		defer sshAgent.Close()
+
+		listener, err := net.Listen("unix", sshSocket)
+		if err != nil {
+			return err
+		}
+		// Closing the listener removes the socket file.
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(signals)
+		go func() {
+			<-signals
+			listener.Close()
+		}()
+
+		fmt.Fprintf(messageOutput(), "SSH_AUTH_SOCK=%s; export SSH_AUTH_SOCK;\n", sshSocket)
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return nil
+				}
+				return err
+			}
+			go func() {
+				defer conn.Close()
+				if err := agent.ServeAgent(sshAgent, conn); err != nil && !errors.Is(err, io.EOF) {
+					fmt.Fprintf(debugOutput(), "SSH agent connection failed: %v\n", err)
+				}
+			}()
+		}
+	},
+}
+
+var sshKeygenCmd = &cobra.Command{
+	Use:   "keygen <name>",
+	Short: "Create an SSH key in the TPM",
+	Long: `Create an SSH key in the TPM, for use with "gotpm ssh-agent"
+
+The key (using --algo, rsa by default) is created under the SRK, and its blob is
+stored in --dir as <name>.tpmkey. The public key is written in authorized_keys
+format, with the name as comment.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rwc, err := openTpm()
+		if err != nil {
+			return err
+		}
+		defer rwc.Close()
+		dir, err := getSSHKeyDir()
+		if err != nil {
+			return err
+		}
+		sshAgent, err := sshagent.New(rwc, dir)
+		if err != nil {
+			return err
+		}
+		defer sshAgent.Close()
+
+		pub, err := sshAgent.GenerateKey(args[0], keyAlgo)
+		if err != nil {
+			return err
+		}
+		authorizedKey := ssh.MarshalAuthorizedKey(pub)
+		// Add the name as comment before the trailing newline.
+		authorizedKey = append(authorizedKey[:len(authorizedKey)-1], " "+args[0]+"\n"...)
+		_, err = dataOutput().Write(authorizedKey)
+		return err
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(sshAgentCmd)
+	sshAgentCmd.AddCommand(sshKeygenCmd)
+	hideHelp(sshAgentCmd)
+	sshAgentCmd.Flags().StringVar(&sshSocket, "socket", "",
+		"path of the unix socket to listen on")
+	sshAgentCmd.PersistentFlags().StringVar(&sshKeyDir, "dir", "",
+		"directory of the key blobs (defaults to gotpm/ssh in the user config directory)")
+	addPublicKeyAlgoFlag(sshKeygenCmd)
+	addOutputFlag(sshKeygenCmd)
+}
+
+// Get the key directory based on the global flag vars.
+func getSSHKeyDir() (string, error) {
+	if sshKeyDir != "" {
+		return sshKeyDir, nil
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "gotpm", "ssh"), nil
+}
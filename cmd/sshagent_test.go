@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestSSHKeygen(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+	defer func() { keyAlgo, sshKeyDir = tpm2.AlgRSA, "" }()
+
+	dir, err := ioutil.TempDir("", "gotpm-ssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, algo := range []string{"rsa", "ecc"} {
+		outFile := makeTempFile(t, nil)
+		defer os.Remove(outFile)
+		RootCmd.SetArgs([]string{"ssh-agent", "keygen", algo + "-key", "--algo", algo, "--dir", dir, "--output", outFile})
+		if err := RootCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadFile(outFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, comment, _, _, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			t.Fatalf("output is not an authorized key: %v", err)
+		}
+		if comment != algo+"-key" {
+			t.Errorf("got comment %q, want %q", comment, algo+"-key")
+		}
+		if _, err = os.Stat(filepath.Join(dir, algo+"-key.tpmkey")); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ThalesIgnite/go-tpm-tools/server"
+	"github.com/ThalesIgnite/go-tpm-tools/verifier"
+)
+
+var (
+	verifierAddr       string
+	verifierSigningKey string
+	verifierPolicyID   string
+	verifierTTL        time.Duration
+	verifierLifetime   time.Duration
+	verifierCertFile   string
+	verifierKeyFile    string
+	// The files of the secrets to release, by their identifier.
+	verifierSecrets map[string]string
+	verifierEKRoots []string
+)
+
+var verifierCmd = &cobra.Command{
+	Use:   "verifier",
+	Short: "Run a remote attestation verifier service",
+	Long: `Run a remote attestation verifier service, without a TPM
+
+The verifier protocol (see proto/verifier.proto) is served over HTTP (or
+HTTPS using --cert and --key) on --listen. Attesters get a challenge containing a
+nonce, answer it with an attestation (from "gotpm attest") or an Entity
+Attestation Token (from "gotpm attest --eat") using the nonce, and then get
+the signed attestation result.
+
+The evidence is verified as by "gotpm verify", using the same --trusted-ak,
+--trusted-root, --amd-cert-chain, --intel-root, --corim and --policy flags.
+The result is an EAT Attestation Result (EAR), signed by the --signing-key (a
+PEM encoded ECDSA or RSA private key) as a CWT or a JWT (as requested by the
+attester), with the --policy-id as its appraisal policy. It contains claims
+about the verified machine (whether Secure Boot is enabled, the digest of the
+Linux kernel, and the TEE type), on which relying parties can gate access, and
+expires after --result-lifetime. Every appraisal is logged to stderr.
+
+Challenges are kept in memory for --challenge-ttl, and can only be answered
+once.
+
+Each --secret ID=FILE (of at most 128 bytes, such as a LUKS passphrase) is
+released to attesters whose result is affirming, and whose EK certificate
+chains to a bundled TPM manufacturer root (see server.DefaultEKRoots) or to
+an --ek-root, by "gotpm attest --release-secret ID". The attester must first
+activate a credential for its AK encrypted to the certified EK, proving that
+the AK is in the TPM of the EK. The secret is then encrypted to the EK, so
+that it can only be decrypted by the attested TPM.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if verifierSigningKey == "" {
+			return errors.New("--signing-key must be provided")
+		}
+		if (verifierCertFile == "") != (verifierKeyFile == "") {
+			return errors.New("--cert and --key must be provided together")
+		}
+		opts, err := readVerifyOpts()
+		if err != nil {
+			return err
+		}
+		if verifyPolicy != "" {
+			policy, err := readPolicy(verifyPolicy)
+			if err != nil {
+				return err
+			}
+			opts.Verifiers = append(opts.Verifiers, server.PolicyVerifier(policy))
+		}
+		signer, err := readSigningKey(verifierSigningKey)
+		if err != nil {
+			return err
+		}
+		secrets, err := readSecrets(verifierSecrets)
+		if err != nil {
+			return err
+		}
+		ekOpts := &server.VerifyEKOpts{}
+		for _, file := range verifierEKRoots {
+			roots, err := readCertificates(file)
+			if err != nil {
+				return err
+			}
+			ekOpts.Roots = append(ekOpts.Roots, roots...)
+		}
+		s, err := verifier.NewServer(verifier.Config{
+			Opts:           opts,
+			PolicyID:       verifierPolicyID,
+			Signer:         signer,
+			ChallengeTTL:   verifierTTL,
+			ResultLifetime: verifierLifetime,
+			Secrets:        secrets,
+			EKOpts:         ekOpts,
+			Logger:         log.New(os.Stderr, "gotpm verifier: ", log.LstdFlags),
+		})
+		if err != nil {
+			return err
+		}
+		return serveHTTP(s, verifierAddr, verifierCertFile, verifierKeyFile, "the verifier")
+	},
+}
+
+func readSigningKey(file string) (crypto.Signer, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", file, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s does not contain a signing key", file)
+	}
+	return signer, nil
+}
+
+// readSecrets reads the secrets of the --secret flags, from their files.
+func readSecrets(files map[string]string) (map[string]verifier.Secret, error) {
+	secrets := map[string]verifier.Secret{}
+	for id, file := range files {
+		value, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		secrets[id] = verifier.Secret{Value: value}
+	}
+	return secrets, nil
+}
+
+func init() {
+	RootCmd.AddCommand(verifierCmd)
+	verifierCmd.Flags().StringVar(&verifierAddr, "listen", "localhost:8322",
+		"address to listen on")
+	verifierCmd.Flags().StringVar(&verifierSigningKey, "signing-key", "",
+		"PEM encoded private key file, to sign the attestation results")
+	verifierCmd.Flags().StringVar(&verifierPolicyID, "policy-id", "",
+		"identifier of the appraisal policy in the attestation results")
+	verifierCmd.Flags().DurationVar(&verifierTTL, "challenge-ttl", verifier.DefaultChallengeTTL,
+		"time for which challenges can be answered, and their results retrieved")
+	verifierCmd.Flags().DurationVar(&verifierLifetime, "result-lifetime", time.Hour,
+		"time for which relying parties can accept attestation results (0 for no expiry)")
+	verifierCmd.Flags().StringVar(&verifierCertFile, "cert", "",
+		"PEM certificate file, to serve over HTTPS")
+	verifierCmd.Flags().StringVar(&verifierKeyFile, "key", "",
+		"PEM private key file, to serve over HTTPS")
+	verifierCmd.Flags().StringToStringVar(&verifierSecrets, "secret", nil,
+		"ID=FILE of a secret to release to affirmed attesters")
+	verifierCmd.Flags().StringSliceVar(&verifierEKRoots, "ek-root", nil,
+		"PEM or DER encoded TPM manufacturer root certificate files trusted to issue EK certificates, in addition to the bundled roots")
+	verifierCmd.PersistentFlags().StringVar(&verifyPolicy, "policy", "",
+		"policy file (defaults to allowing any machine state)")
+	addTrustFlags(verifierCmd)
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"testing"
+)
+
+func TestReadSigningKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := makeTempFile(t, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+	defer os.Remove(keyFile)
+	signer, err := readSigningKey(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.PublicKey.Equal(signer.Public()) {
+		t.Error("signing key does not match the written key")
+	}
+
+	der, err = x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubFile := makeTempFile(t, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	defer os.Remove(pubFile)
+	if _, err = readSigningKey(pubFile); err == nil {
+		t.Error("expected reading a public key as a signing key to fail")
+	}
+}
+
+func TestReadSecrets(t *testing.T) {
+	secretFile := makeTempFile(t, []byte("passphrase"))
+	defer os.Remove(secretFile)
+	secrets, err := readSecrets(map[string]string{"disk": secretFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(secrets["disk"].Value) != "passphrase" {
+		t.Errorf("got secrets %v, want the disk passphrase", secrets)
+	}
+	if _, err = readSecrets(map[string]string{"disk": secretFile + ".missing"}); err == nil {
+		t.Error("expected reading a missing secret file to fail")
+	}
+}
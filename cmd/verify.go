@@ -0,0 +1,348 @@
+package cmd
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+	"github.com/ThalesIgnite/go-tpm-tools/server"
+)
+
+var (
+	verifyReport       string
+	verifyPolicy       string
+	verifyTrustedAKs   []string
+	verifyTrustedRoots []string
+	verifyAMDCerts     []string
+	verifyIntelRoots   []string
+	verifyPCRPolicy    string
+	verifyCoRIM        string
+	verifyCoRIMSigner  string
+	verifyCheckDbx     bool
+	verifyDbxUpdates   []string
+)
+
+// verdict is the machine-readable result of "gotpm verify".
+type verdict struct {
+	Verified     bool            `json:"verified"`
+	Error        string          `json:"error,omitempty"`
+	MachineState json.RawMessage `json:"machineState,omitempty"`
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify a remote attestation report",
+	Long: `Verify an attestation report (from "gotpm attest") without a TPM
+
+The report (encoded using --format, or an Entity Attestation Token with
+--eat) is checked to ensure:
+  - the Attestation Key (AK) is trusted, either because it matches a
+    --trusted-ak public key (PEM encoded, as output by "gotpm pubkey"), or
+    because the AK certificate chains up to a --trusted-root certificate
+    (with --platform "gce" or "azure", the root CA of the platform's vTPMs,
+    as published by Google or Microsoft, which is not bundled)
+  - the quotes are signed by the AK and contain the --nonce (or, with
+    --derive-nonces, the nonce derived from it and the event logs)
+  - with --eat, the token is signed by the AK and contains the --nonce
+  - the event log (and IMA log, if present) replays to the quoted PCRs
+  - the SEV-SNP attestation report (if present) is signed by a VCEK, chaining
+    up to an ARK from the --amd-cert-chain files (such as the cert_chain of
+    the AMD Key Distribution Service for the processor family)
+  - the TDX quote (if present) chains up to an --intel-root certificate (the
+    Intel SGX Root CA), and the platform is up to date according to the
+    collateral fetched from the Intel Provisioning Certification Service
+  - the quoted PCRs satisfy the --pcr-policy (if provided), a JSON file of
+    golden PCR values (see server.PCRPolicy)
+  - the quoted PCRs match the reference values of the --corim (if provided),
+    a CoRIM endorsing PCR values, signed by the --corim-signer (PEM encoded
+    public key) if provided
+  - with --check-dbx (or --dbx), no booted component (UEFI image, or the
+    certificate which verified it) is revoked by the dbx measured in the event
+    log, or by the --dbx updates (such as the DBXUpdate.bin files of the UEFI
+    Revocation List)
+  - the resulting machine state satisfies the --policy (if provided)
+
+The policy file contains an attest.Policy protobuf, in JSON if the filename
+ends in ".json", otherwise in the protobuf text format. YAML is not supported
+for either policy: convert it to JSON first (for example with "yq -o json").
+
+A JSON verdict is written to --output (stdout by default). The command fails
+if the report could not be verified.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(nonce) == 0 {
+			return errors.New("a --nonce must be provided")
+		}
+		opts, err := readVerifyOpts()
+		if err != nil {
+			return err
+		}
+		opts.Nonce, opts.DerivedNonces = nonce, deriveNonces
+		var policy *pb.Policy
+		if verifyPolicy != "" {
+			if policy, err = readPolicy(verifyPolicy); err != nil {
+				return err
+			}
+		}
+		data, err := ioutil.ReadFile(verifyReport)
+		if err != nil {
+			return fmt.Errorf("reading report: %w", err)
+		}
+		attestation := &pb.Attestation{}
+		if eatReport {
+			eat, err := server.ParseEAT(data)
+			if err != nil {
+				return fmt.Errorf("decoding report: %w", err)
+			}
+			attestation = eat.Attestation
+		} else if err = unmarshalProto(data, attestation); err != nil {
+			return fmt.Errorf("decoding report: %w", err)
+		}
+		if quote := attestation.GetTdxQuote(); len(quote) > 0 {
+			fmt.Fprintln(debugOutput(), "Fetching TDX collateral")
+			if opts.Tdx.Collateral, err = server.FetchTdxCollateral(http.DefaultClient, quote); err != nil {
+				return fmt.Errorf("fetching TDX collateral: %w", err)
+			}
+		}
+
+		fmt.Fprintln(debugOutput(), "Verifying attestation")
+		result := verdict{}
+		var state *pb.MachineState
+		if eatReport {
+			state, err = server.VerifyEAT(data, opts)
+		} else {
+			state, err = server.VerifyAttestation(attestation, opts)
+		}
+		if err == nil {
+			if result.MachineState, err = protojson.Marshal(state); err != nil {
+				return err
+			}
+			fmt.Fprintln(debugOutput(), "Evaluating policy")
+			err = server.EvaluatePolicy(state, policy)
+		}
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Verified = true
+		}
+
+		out, jsonErr := json.MarshalIndent(result, "", "  ")
+		if jsonErr != nil {
+			return jsonErr
+		}
+		if _, jsonErr = dataOutput().Write(append(out, '\n')); jsonErr != nil {
+			return jsonErr
+		}
+		return err
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(verifyCmd)
+	verifyCmd.PersistentFlags().StringVar(&verifyReport, "report", "",
+		"attestation report file")
+	verifyCmd.MarkPersistentFlagRequired("report")
+	verifyCmd.PersistentFlags().StringVar(&verifyPolicy, "policy", "",
+		"policy file (defaults to allowing any machine state)")
+	addTrustFlags(verifyCmd)
+	addNonceFlag(verifyCmd)
+	addDeriveNoncesFlag(verifyCmd)
+	addFormatFlag(verifyCmd)
+	addEATFlag(verifyCmd)
+	addOutputFlag(verifyCmd)
+}
+
+// addTrustFlags adds the flags of the keys, certificates and reference values
+// trusted to verify attestations (see readVerifyOpts).
+func addTrustFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringSliceVar(&verifyTrustedAKs, "trusted-ak", nil,
+		"PEM encoded AK public key files to trust")
+	cmd.PersistentFlags().StringSliceVar(&verifyTrustedRoots, "trusted-root", nil,
+		"PEM or DER encoded root certificate files trusted to issue AK certificates")
+	addPlatformFlag(cmd)
+	cmd.PersistentFlags().StringSliceVar(&verifyAMDCerts, "amd-cert-chain", nil,
+		"PEM encoded AMD ARK and ASK certificate files trusted to issue SEV-SNP VCEKs")
+	cmd.PersistentFlags().StringSliceVar(&verifyIntelRoots, "intel-root", nil,
+		"PEM or DER encoded Intel SGX Root CA certificate files trusted for TDX quotes")
+	cmd.PersistentFlags().StringVar(&verifyPCRPolicy, "pcr-policy", "",
+		"JSON file with the golden values of the PCRs")
+	cmd.PersistentFlags().StringVar(&verifyCoRIM, "corim", "",
+		"CoRIM file with the reference values of the PCRs")
+	cmd.PersistentFlags().StringVar(&verifyCoRIMSigner, "corim-signer", "",
+		"PEM encoded public key file of the --corim signer (if the CoRIM is signed)")
+	cmd.PersistentFlags().BoolVar(&verifyCheckDbx, "check-dbx", false,
+		"check the booted components against the dbx of the machine")
+	cmd.PersistentFlags().StringSliceVar(&verifyDbxUpdates, "dbx", nil,
+		"dbx update files with additional revoked hashes and certificates (implies --check-dbx)")
+}
+
+// readVerifyOpts returns the VerifyOpts given by the flags of addTrustFlags,
+// without a nonce.
+func readVerifyOpts() (server.VerifyOpts, error) {
+	opts := server.VerifyOpts{}
+	for _, file := range verifyTrustedAKs {
+		ak, err := readPublicKey(file)
+		if err != nil {
+			return server.VerifyOpts{}, err
+		}
+		opts.TrustedAKs = append(opts.TrustedAKs, ak)
+	}
+	for _, file := range verifyTrustedRoots {
+		roots, err := readCertificates(file)
+		if err != nil {
+			return server.VerifyOpts{}, err
+		}
+		opts.TrustedRootCerts = append(opts.TrustedRootCerts, roots...)
+	}
+	if _, err := getPlatform(); err != nil {
+		return server.VerifyOpts{}, err
+	}
+	for _, file := range verifyAMDCerts {
+		certs, err := readCertificates(file)
+		if err != nil {
+			return server.VerifyOpts{}, err
+		}
+		// The chain contains the (self-signed) ARK and the ASK.
+		for _, cert := range certs {
+			if cert.CheckSignatureFrom(cert) == nil {
+				opts.SevSnp.TrustedRoots = append(opts.SevSnp.TrustedRoots, cert)
+			} else {
+				opts.SevSnp.Intermediates = append(opts.SevSnp.Intermediates, cert)
+			}
+		}
+	}
+	for _, file := range verifyIntelRoots {
+		roots, err := readCertificates(file)
+		if err != nil {
+			return server.VerifyOpts{}, err
+		}
+		opts.Tdx.TrustedRoots = append(opts.Tdx.TrustedRoots, roots...)
+	}
+	if verifyPCRPolicy != "" {
+		pcrPolicy, err := readPCRPolicy(verifyPCRPolicy)
+		if err != nil {
+			return server.VerifyOpts{}, err
+		}
+		opts.Verifiers = append(opts.Verifiers, server.PCRPolicyVerifier(pcrPolicy))
+	}
+	if verifyCoRIM != "" {
+		pcrPolicy, err := readCoRIM(verifyCoRIM, verifyCoRIMSigner)
+		if err != nil {
+			return server.VerifyOpts{}, err
+		}
+		opts.Verifiers = append(opts.Verifiers, server.PCRPolicyVerifier(pcrPolicy))
+	}
+	if verifyCheckDbx || len(verifyDbxUpdates) > 0 {
+		dbx := &pb.Database{}
+		for _, file := range verifyDbxUpdates {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				return server.VerifyOpts{}, err
+			}
+			update, err := server.ParseDbxUpdate(data)
+			if err != nil {
+				return server.VerifyOpts{}, fmt.Errorf("reading %s: %w", file, err)
+			}
+			dbx.Certs = append(dbx.Certs, update.GetCerts()...)
+			dbx.Hashes = append(dbx.Hashes, update.GetHashes()...)
+		}
+		opts.Verifiers = append(opts.Verifiers, server.DbxVerifier(dbx))
+	}
+	return opts, nil
+}
+
+func readPublicKey(file string) (crypto.PublicKey, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("%s does not contain a PEM encoded public key", file)
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+func readPCRPolicy(file string) (*server.PCRPolicy, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := server.ParsePCRPolicy(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", file, err)
+	}
+	return policy, nil
+}
+
+func readCoRIM(file, signerFile string) (*server.PCRPolicy, error) {
+	var signer crypto.PublicKey
+	if signerFile != "" {
+		var err error
+		if signer, err = readPublicKey(signerFile); err != nil {
+			return nil, err
+		}
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := server.ParseCoRIM(data, signer)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", file, err)
+	}
+	return policy, nil
+}
+
+func readCertificates(file string) ([]*x509.Certificate, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var certs []*x509.Certificate
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", file, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) > 0 {
+		return certs, nil
+	}
+	cert, err := x509.ParseCertificate(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", file, err)
+	}
+	return []*x509.Certificate{cert}, nil
+}
+
+func readPolicy(file string) (*pb.Policy, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	policy := &pb.Policy{}
+	if filepath.Ext(file) == ".json" {
+		err = protojson.Unmarshal(data, policy)
+	} else {
+		err = unmarshalOptions.Unmarshal(data, policy)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing policy %s: %w", file, err)
+	}
+	return policy, nil
+}
@@ -0,0 +1,377 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/google/go-attestation/attest"
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestVerify(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+
+	ak, err := client.AttestationKeyRSA(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(ak.PublicKey())
+	ak.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	akFile := makeTempFile(t, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	defer os.Remove(akFile)
+
+	reportFile := makeTempFile(t, nil)
+	defer os.Remove(reportFile)
+	RootCmd.SetArgs([]string{"attest", "--nonce", "abcd", "--algo", "rsa", "--format", formatBinary, "--output", reportFile})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	allowPolicy := makeTempFile(t, []byte(`platform { minimum_technology: NONE }`))
+	defer os.Remove(allowPolicy)
+	denyPolicy := makeTempFile(t, []byte(`platform { minimum_technology: AMD_SEV_ES }`))
+	defer os.Remove(denyPolicy)
+
+	tests := []struct {
+		name     string
+		nonce    string
+		policy   string
+		verified bool
+	}{
+		{"NoPolicy", "abcd", "", true},
+		{"AllowPolicy", "abcd", allowPolicy, true},
+		{"DenyPolicy", "abcd", denyPolicy, false},
+		{"WrongNonce", "1234", "", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			verdictFile := makeTempFile(t, nil)
+			defer os.Remove(verdictFile)
+			verifyTrustedAKs, verifyPolicy = nil, ""
+
+			args := []string{"verify", "--report", reportFile, "--nonce", tc.nonce, "--format", formatBinary,
+				"--trusted-ak", akFile, "--output", verdictFile}
+			if tc.policy != "" {
+				args = append(args, "--policy", tc.policy)
+			}
+			RootCmd.SetArgs(args)
+			err := RootCmd.Execute()
+			if tc.verified && err != nil {
+				t.Errorf("verification failed: %v", err)
+			}
+			if !tc.verified && err == nil {
+				t.Error("expected verification to fail")
+			}
+
+			data, err := ioutil.ReadFile(verdictFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var result verdict
+			if err = json.Unmarshal(data, &result); err != nil {
+				t.Fatalf("failed to parse verdict %q: %v", data, err)
+			}
+			if result.Verified != tc.verified {
+				t.Errorf("got verdict %+v, expected verified=%v", result, tc.verified)
+			}
+		})
+	}
+}
+
+func TestVerifyEAT(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+	defer func() { eatReport = false }()
+
+	ak, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(ak.PublicKey())
+	ak.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	akFile := makeTempFile(t, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	defer os.Remove(akFile)
+
+	reportFile := makeTempFile(t, nil)
+	defer os.Remove(reportFile)
+	RootCmd.SetArgs([]string{"attest", "--nonce", "0123456789abcdef", "--algo", "ecc", "--eat", "--output", reportFile})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		nonce    string
+		verified bool
+	}{
+		{"RightNonce", "0123456789abcdef", true},
+		{"WrongNonce", "fedcba9876543210", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			verdictFile := makeTempFile(t, nil)
+			defer os.Remove(verdictFile)
+			verifyTrustedAKs, verifyPolicy = nil, ""
+
+			RootCmd.SetArgs([]string{"verify", "--report", reportFile, "--nonce", tc.nonce, "--eat",
+				"--trusted-ak", akFile, "--output", verdictFile})
+			err := RootCmd.Execute()
+			if tc.verified && err != nil {
+				t.Errorf("verification failed: %v", err)
+			}
+			if !tc.verified && err == nil {
+				t.Error("expected verification to fail")
+			}
+		})
+	}
+
+	// The EAT is not a protobuf.
+	eatReport = false
+	verifyTrustedAKs = nil
+	RootCmd.SetArgs([]string{"verify", "--report", reportFile, "--nonce", "0123456789abcdef", "--format", formatBinary,
+		"--trusted-ak", akFile, "--output", os.DevNull})
+	if err := RootCmd.Execute(); err == nil {
+		t.Error("expected verifying an EAT as a protobuf to fail")
+	}
+}
+
+// Returns an unsigned CoRIM with the reference value of the SHA-256 PCR 0.
+func makePCR0CoRIM(t *testing.T, digest []byte) []byte {
+	t.Helper()
+	measurement := map[int64]interface{}{
+		0: int64(0),
+		1: map[int64]interface{}{2: []interface{}{[]interface{}{int64(1), digest}}},
+	}
+	comid, err := notinternal.MarshalCBOR(map[int64]interface{}{
+		1: map[int64]interface{}{0: "tag-id"},
+		4: map[int64]interface{}{0: []interface{}{[]interface{}{map[int64]interface{}{}, []interface{}{measurement}}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	corim, err := notinternal.MarshalCBOR(notinternal.CBORTag{Number: 501, Content: map[int64]interface{}{
+		0: "corim-id",
+		1: []interface{}{notinternal.CBORTag{Number: 506, Content: comid}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return corim
+}
+
+func TestVerifyCoRIM(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+	defer func() { verifyCoRIM = "" }()
+
+	ak, err := client.AttestationKeyRSA(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(ak.PublicKey())
+	ak.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	akFile := makeTempFile(t, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	defer os.Remove(akFile)
+	pcrs, err := client.ReadPCRs(rwc, tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reportFile := makeTempFile(t, nil)
+	defer os.Remove(reportFile)
+	RootCmd.SetArgs([]string{"attest", "--nonce", "abcd", "--algo", "rsa", "--format", formatBinary, "--output", reportFile})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	goodCoRIM := makeTempFile(t, makePCR0CoRIM(t, pcrs.GetPcrs()[0]))
+	defer os.Remove(goodCoRIM)
+	badCoRIM := makeTempFile(t, makePCR0CoRIM(t, make([]byte, 32)))
+	defer os.Remove(badCoRIM)
+	for _, tc := range []struct {
+		name     string
+		corim    string
+		verified bool
+	}{
+		{"MatchingReferenceValues", goodCoRIM, true},
+		{"OtherReferenceValues", badCoRIM, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			verifyTrustedAKs, verifyPolicy = nil, ""
+			RootCmd.SetArgs([]string{"verify", "--report", reportFile, "--nonce", "abcd", "--format", formatBinary,
+				"--trusted-ak", akFile, "--corim", tc.corim, "--output", os.DevNull})
+			err := RootCmd.Execute()
+			if tc.verified && err != nil {
+				t.Errorf("verification failed: %v", err)
+			}
+			if !tc.verified && err == nil {
+				t.Error("expected verification to fail")
+			}
+		})
+	}
+}
+
+func TestVerifyPCRPolicy(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+	defer func() { verifyPCRPolicy = "" }()
+
+	ak, err := client.AttestationKeyRSA(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(ak.PublicKey())
+	ak.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	akFile := makeTempFile(t, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	defer os.Remove(akFile)
+	pcrs, err := client.ReadPCRs(rwc, tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reportFile := makeTempFile(t, nil)
+	defer os.Remove(reportFile)
+	RootCmd.SetArgs([]string{"attest", "--nonce", "abcd", "--algo", "rsa", "--format", formatBinary, "--output", reportFile})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	goodPolicy := makeTempFile(t, []byte(`{"pcrs": [{"pcr": 0, "bank": "sha256", "values": ["`+hex.EncodeToString(pcrs.GetPcrs()[0])+`"]}]}`))
+	defer os.Remove(goodPolicy)
+	badPolicy := makeTempFile(t, []byte(`{"pcrs": [{"pcr": 0, "bank": "sha256", "values": ["`+hex.EncodeToString(make([]byte, 32))+`"]}]}`))
+	defer os.Remove(badPolicy)
+	yamlPolicy := makeTempFile(t, []byte("pcrs:\n  - pcr: 0\n"))
+	defer os.Remove(yamlPolicy)
+	for _, tc := range []struct {
+		name     string
+		policy   string
+		verified bool
+	}{
+		{"MatchingGoldenValues", goodPolicy, true},
+		{"OtherGoldenValues", badPolicy, false},
+		{"YAMLPolicy", yamlPolicy, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			verifyTrustedAKs, verifyPolicy = nil, ""
+			RootCmd.SetArgs([]string{"verify", "--report", reportFile, "--nonce", "abcd", "--format", formatBinary,
+				"--trusted-ak", akFile, "--pcr-policy", tc.policy, "--output", os.DevNull})
+			err := RootCmd.Execute()
+			if tc.verified && err != nil {
+				t.Errorf("verification failed: %v", err)
+			}
+			if !tc.verified && err == nil {
+				t.Error("expected verification to fail")
+			}
+		})
+	}
+}
+
+// makeDbxUpdate returns the EFI_SIGNATURE_LIST (of type EFI_CERT_SHA256_GUID)
+// of the SHA-256 hashes.
+func makeDbxUpdate(hashes ...[]byte) []byte {
+	var b bytes.Buffer
+	b.Write([]byte{0x26, 0x16, 0xc4, 0xc1, 0x4c, 0x50, 0x92, 0x40, 0xac, 0xa9, 0x41, 0xf9, 0x36, 0x93, 0x43, 0x28})
+	binary.Write(&b, binary.LittleEndian, uint32(28+len(hashes)*(16+sha256.Size)))
+	binary.Write(&b, binary.LittleEndian, uint32(0))
+	binary.Write(&b, binary.LittleEndian, uint32(16+sha256.Size))
+	for _, hash := range hashes {
+		b.Write(make([]byte, 16))
+		b.Write(hash)
+	}
+	return b.Bytes()
+}
+
+func TestVerifyDbx(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ExternalTPM = rwc
+	defer func() { verifyCheckDbx, verifyDbxUpdates = false, nil }()
+
+	ak, err := client.AttestationKeyRSA(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(ak.PublicKey())
+	ak.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	akFile := makeTempFile(t, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	defer os.Remove(akFile)
+
+	reportFile := makeTempFile(t, nil)
+	defer os.Remove(reportFile)
+	RootCmd.SetArgs([]string{"attest", "--nonce", "abcd", "--algo", "rsa", "--format", formatBinary, "--output", reportFile})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Revoke the first UEFI application of the event log of the test TPM.
+	eventLog, err := attest.ParseEventLog(test.Rhel8EventLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var app []byte
+	for _, event := range eventLog.Events(attest.HashSHA256) {
+		if event.Type == attest.EventType(0x80000003) {
+			app = event.Digest
+			break
+		}
+	}
+	unrelated := sha256.Sum256([]byte("unrelated"))
+	unrelatedDbx := makeTempFile(t, makeDbxUpdate(unrelated[:]))
+	defer os.Remove(unrelatedDbx)
+	revokingDbx := makeTempFile(t, makeDbxUpdate(unrelated[:], app))
+	defer os.Remove(revokingDbx)
+	for _, tc := range []struct {
+		name     string
+		args     []string
+		verified bool
+	}{
+		{"MachineDbx", []string{"--check-dbx"}, true},
+		{"UnrelatedUpdate", []string{"--dbx", unrelatedDbx}, true},
+		{"RevokingUpdate", []string{"--dbx", unrelatedDbx, "--dbx", revokingDbx}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			verifyTrustedAKs, verifyPolicy = nil, ""
+			verifyCheckDbx, verifyDbxUpdates = false, nil
+			RootCmd.SetArgs(append([]string{"verify", "--report", reportFile, "--nonce", "abcd", "--format", formatBinary,
+				"--trusted-ak", akFile, "--output", os.DevNull}, tc.args...))
+			err := RootCmd.Execute()
+			if tc.verified && err != nil {
+				t.Errorf("verification failed: %v", err)
+			}
+			if !tc.verified && err == nil {
+				t.Error("expected verification to fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+// Package disk seals the keys of encrypted volumes (such as LUKS keyfiles, or
+// BitLocker-style volume master keys) to the boot state of the machine, so
+// that a volume can only be unlocked by its TPM, after a trusted boot.
+//
+// By default, volume keys are sealed to PCRs 0, 2, 4 and 7 of the SHA-256
+// bank: the platform firmware, the option ROMs, the boot loader and the Secure
+// Boot policy. Planned updates of these components (which change the PCRs)
+// are supported by also sealing to the values predicted after the update (see
+// SealOpts.Upgrades), and the key can additionally be protected by a PIN.
+//
+// Keys can also be sealed as the LUKS2 tokens of systemd-cryptenroll (see
+// SealSystemd), so that volumes can be unlocked at boot by systemd-cryptsetup.
+package disk
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+// DefaultPCRs are the PCRs to which volume keys are sealed by default.
+var DefaultPCRs = []int{0, 2, 4, 7}
+
+// The maximum number of upgrades, as the current state and the upgrades are
+// the (at most 8) branches of a TPM2_PolicyOR.
+const maxUpgrades = 7
+
+// SealOpts configures the sealing of a volume key.
+type SealOpts struct {
+	// PCRs are the PCRs to seal to. If empty, DefaultPCRs are used.
+	PCRs []int
+	// Bank is the PCR bank to seal to. If zero, the SHA-256 bank is used.
+	Bank tpm2.Algorithm
+	// Upgrades are the predicted values of the PCRs after planned updates
+	// (such as of the firmware or of the boot loader). The key can be
+	// unsealed in the current state or in the state of any upgrade, which is
+	// the current state with the PCR values of the upgrade. The upgrades can
+	// only contain PCRs of the bank and of the PCRs sealed to.
+	Upgrades []*pb.PCRs
+	// PIN, if not empty, is also required to unseal the key. Attempts with a
+	// wrong PIN are subject to the TPM's dictionary attack protection.
+	PIN string
+}
+
+// Seal seals the volume key to the current state of the TPM's PCRs (and of the
+// upgrades), with the TPM's ECC Storage Root Key (SRK). The volume key can be
+// at most 128 bytes. The returned SealedBytes can be stored alongside the
+// volume (for example, in a LUKS token or in the initramfs), and are unsealed
+// with Unseal.
+func Seal(rw io.ReadWriter, volumeKey []byte, opts SealOpts) (*pb.SealedBytes, error) {
+	sel := tpm2.PCRSelection{Hash: opts.Bank, PCRs: opts.PCRs}
+	if sel.Hash == tpm2.AlgUnknown {
+		sel.Hash = tpm2.AlgSHA256
+	}
+	if len(sel.PCRs) == 0 {
+		sel.PCRs = DefaultPCRs
+	}
+	if len(opts.Upgrades) > maxUpgrades {
+		return nil, fmt.Errorf("at most %d upgrades are supported, got %d", maxUpgrades, len(opts.Upgrades))
+	}
+	current, err := client.ReadPCRs(rw, sel)
+	if err != nil {
+		return nil, fmt.Errorf("reading PCRs: %w", err)
+	}
+	alternatives := client.SealAlternatives{client.SealTarget{Pcrs: current}}
+	for i, upgrade := range opts.Upgrades {
+		pcrs, err := upgradePCRs(current, upgrade)
+		if err != nil {
+			return nil, fmt.Errorf("upgrade %d: %w", i, err)
+		}
+		alternatives = append(alternatives, client.SealTarget{Pcrs: pcrs})
+	}
+
+	srk, err := client.StorageRootKeyECC(rw)
+	if err != nil {
+		return nil, fmt.Errorf("loading SRK: %w", err)
+	}
+	defer srk.Close()
+	sealed, err := srk.SealWithAuthValue(volumeKey, alternatives, opts.PIN)
+	if err != nil {
+		return nil, fmt.Errorf("sealing volume key: %w", err)
+	}
+	return sealed, nil
+}
+
+// upgradePCRs returns the current PCRs, with the values of the upgrade.
+func upgradePCRs(current, upgrade *pb.PCRs) (*pb.PCRs, error) {
+	if upgrade.GetHash() != current.GetHash() {
+		return nil, fmt.Errorf("PCRs are in the %v bank, expected %v", upgrade.GetHash(), current.GetHash())
+	}
+	pcrs := &pb.PCRs{Hash: current.GetHash(), Pcrs: map[uint32][]byte{}}
+	for pcr, value := range current.GetPcrs() {
+		pcrs.Pcrs[pcr] = value
+	}
+	for pcr, value := range upgrade.GetPcrs() {
+		if _, ok := pcrs.Pcrs[pcr]; !ok {
+			return nil, fmt.Errorf("PCR %d is not sealed to", pcr)
+		}
+		pcrs.Pcrs[pcr] = value
+	}
+	return pcrs, nil
+}
+
+// Unseal unseals a volume key sealed with Seal, if the PCRs are in the state
+// in which it was sealed or in the state of one of its upgrades. The pin must
+// be the PIN it was sealed with, if any.
+func Unseal(rw io.ReadWriter, sealed *pb.SealedBytes, pin string) ([]byte, error) {
+	var srk *client.Key
+	var err error
+	switch sealed.GetSrk() {
+	case pb.ObjectType_ECC:
+		srk, err = client.StorageRootKeyECC(rw)
+	case pb.ObjectType_RSA:
+		srk, err = client.StorageRootKeyRSA(rw)
+	default:
+		return nil, fmt.Errorf("unsupported SRK type %v", sealed.GetSrk())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading SRK: %w", err)
+	}
+	defer srk.Close()
+	volumeKey, err := srk.UnsealWithAuthValue(sealed, pin, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unsealing volume key: %w", err)
+	}
+	return volumeKey, nil
+}
+
+// Reseal unseals a volume key sealed with Seal (see Unseal), and seals it again
+// with the opts. This is used to add upgrades before a planned update, and to
+// remove them (sealing to the new current state) after the update.
+func Reseal(rw io.ReadWriter, sealed *pb.SealedBytes, pin string, opts SealOpts) (*pb.SealedBytes, error) {
+	volumeKey, err := Unseal(rw, sealed, pin)
+	if err != nil {
+		return nil, err
+	}
+	return Seal(rw, volumeKey, opts)
+}
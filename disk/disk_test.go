@@ -0,0 +1,107 @@
+package disk_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/disk"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+var volumeKey = bytes.Repeat([]byte{0x42}, 64)
+
+func TestSealDefaultPCRs(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	sealed, err := disk.Seal(rwc, volumeKey, disk.SealOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sealed.GetAlternativePcrs()[0].GetPcrs(); len(got) != len(disk.DefaultPCRs) {
+		t.Errorf("sealed to %d PCRs, want %d", len(got), len(disk.DefaultPCRs))
+	}
+	key, err := disk.Unseal(rwc, sealed, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key, volumeKey) {
+		t.Errorf("got volume key %x, want %x", key, volumeKey)
+	}
+}
+
+func TestSealUpgrades(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	sel := tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{test.DebugPCR}}
+	current, err := client.ReadPCRs(rwc, sel)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Predict the value of the PCR after an update extends its measurement.
+	measurement := sha256.Sum256([]byte("new boot loader"))
+	predicted := sha256.Sum256(append(append([]byte{}, current.GetPcrs()[uint32(test.DebugPCR)]...), measurement[:]...))
+	upgrade := &pb.PCRs{Hash: pb.HashAlgo_SHA256, Pcrs: map[uint32][]byte{uint32(test.DebugPCR): predicted[:]}}
+	opts := disk.SealOpts{PCRs: sel.PCRs, Upgrades: []*pb.PCRs{upgrade}, PIN: "1234"}
+	sealed, err := disk.Seal(rwc, volumeKey, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = disk.Unseal(rwc, sealed, "4321"); err == nil {
+		t.Error("expected unsealing with the wrong PIN to fail")
+	}
+	if _, err = disk.Unseal(rwc, sealed, "1234"); err != nil {
+		t.Errorf("failed to unseal in the current state: %v", err)
+	}
+	if err = tpm2.PCRExtend(rwc, tpmutil.Handle(test.DebugPCR), tpm2.AlgSHA256, measurement[:], ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = disk.Unseal(rwc, sealed, "1234"); err != nil {
+		t.Errorf("failed to unseal in the upgraded state: %v", err)
+	}
+
+	// After the update, the key is resealed to the new state only.
+	resealed, err := disk.Reseal(rwc, sealed, "1234", disk.SealOpts{PCRs: sel.PCRs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = tpm2.PCRExtend(rwc, tpmutil.Handle(test.DebugPCR), tpm2.AlgSHA256, measurement[:], ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = disk.Unseal(rwc, sealed, "1234"); err == nil {
+		t.Error("expected unsealing in an unknown state to fail")
+	}
+	if _, err = disk.Unseal(rwc, resealed, ""); err == nil {
+		t.Error("expected unsealing in an unknown state to fail")
+	}
+}
+
+func TestSealInvalidUpgrades(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	value := make([]byte, sha256.Size)
+	tests := []struct {
+		name     string
+		upgrades []*pb.PCRs
+	}{
+		{"WrongBank", []*pb.PCRs{{Hash: pb.HashAlgo_SHA1, Pcrs: map[uint32][]byte{0: value}}}},
+		{"PCRNotSealed", []*pb.PCRs{{Hash: pb.HashAlgo_SHA256, Pcrs: map[uint32][]byte{1: value}}}},
+		{"TooMany", make([]*pb.PCRs, 8)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := disk.Seal(rwc, volumeKey, disk.SealOpts{Upgrades: tc.upgrades}); err == nil {
+				t.Error("expected sealing with invalid upgrades to fail")
+			}
+		})
+	}
+}
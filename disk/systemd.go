@@ -0,0 +1,247 @@
+package disk
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+// SystemdTokenType is the type of the LUKS2 tokens of systemd-cryptenroll.
+const SystemdTokenType = "systemd-tpm2"
+
+// The salting of PINs by systemd: the salted PIN is the base64 encoding of
+// PBKDF2-HMAC-SHA256 of the PIN, with a random salt.
+const (
+	systemdSaltSize       = 16
+	systemdPINIterations  = 10000
+	systemdSaltedPINBytes = sha256.Size
+)
+
+// SystemdToken is a LUKS2 token of type "systemd-tpm2", as enrolled by
+// "systemd-cryptenroll --tpm2-device" and used by systemd-cryptsetup (and
+// other initrd tooling) to unlock a volume. Its JSON encoding is the token's
+// JSON in the LUKS2 header (see "cryptsetup token import").
+//
+// systemd-cryptsetup unseals the secret of the token, and unlocks the keyslots
+// of the token with the base64 encoding of the secret as their passphrase (see
+// SystemdPassphrase).
+type SystemdToken struct {
+	Type string `json:"type"`
+	// The LUKS2 keyslots unlocked by the token, as decimal strings.
+	Keyslots []string `json:"keyslots"`
+	// The TPM2B_PRIVATE and TPM2B_PUBLIC of the sealed object.
+	Blob []byte `json:"tpm2-blob"`
+	// The PCRs of the PCR bank which the object is sealed to.
+	PCRs    []int  `json:"tpm2-pcrs"`
+	PCRBank string `json:"tpm2-pcr-bank,omitempty"`
+	// The algorithm of the primary key the object is sealed with ("ecc" or
+	// "rsa"), created from systemd's legacy template (see systemdPrimary).
+	PrimaryAlg string `json:"tpm2-primary-alg,omitempty"`
+	// The hex encoded auth policy digest of the sealed object.
+	PolicyHash string `json:"tpm2-policy-hash"`
+	// Whether a PIN is required, and its salt (if it is salted).
+	PIN  bool   `json:"tpm2-pin"`
+	Salt []byte `json:"tpm2_salt,omitempty"`
+	// The serialized SRK, if the object is sealed with the SRK persisted at
+	// client.SRKReservedHandle rather than with a primary key.
+	SRK []byte `json:"tpm2_srk,omitempty"`
+	// The signed PCR policies and pcrlock policies of systemd, which are not
+	// supported.
+	PublicKey     []byte `json:"tpm2_pubkey,omitempty"`
+	PublicKeyPCRs []int  `json:"tpm2_pubkey_pcrs,omitempty"`
+	PCRLock       bool   `json:"tpm2_pcrlock,omitempty"`
+}
+
+// SystemdPassphrase returns the passphrase with which systemd-cryptsetup
+// unlocks the keyslots of a SystemdToken sealing the secret. The passphrase
+// must be added to the keyslot of the token (for example, with "cryptsetup
+// luksAddKey").
+func SystemdPassphrase(secret []byte) string {
+	return base64.StdEncoding.EncodeToString(secret)
+}
+
+// systemdPrimary creates the primary key of systemd's legacy template: an
+// SRK template with an empty unique field and without noDA.
+func systemdPrimary(rw io.ReadWriter, alg string) (*client.Key, error) {
+	template := tpm2.Public{
+		NameAlg:    tpm2.AlgSHA256,
+		Attributes: tpm2.FlagStorageDefault,
+	}
+	symmetric := &tpm2.SymScheme{Alg: tpm2.AlgAES, KeyBits: 128, Mode: tpm2.AlgCFB}
+	switch alg {
+	case "ecc":
+		template.Type = tpm2.AlgECC
+		template.ECCParameters = &tpm2.ECCParams{Symmetric: symmetric, CurveID: tpm2.CurveNISTP256}
+	case "rsa":
+		template.Type = tpm2.AlgRSA
+		template.RSAParameters = &tpm2.RSAParams{Symmetric: symmetric, KeyBits: 2048}
+	default:
+		return nil, fmt.Errorf("unsupported primary key algorithm %q", alg)
+	}
+	return client.NewKey(rw, tpm2.HandleOwner, template)
+}
+
+// systemdPINAuth returns the auth value of the sealed object for the PIN: the
+// SHA-256 digest of the (salted) PIN.
+func systemdPINAuth(pin string, salt []byte) string {
+	if len(salt) > 0 {
+		salted := pbkdf2.Key([]byte(pin), salt, systemdPINIterations, systemdSaltedPINBytes, sha256.New)
+		pin = base64.StdEncoding.EncodeToString(salted)
+	}
+	digest := sha256.Sum256([]byte(pin))
+	return string(digest[:])
+}
+
+// SealSystemd seals the secret (of at most 128 bytes) to the current values of
+// the PCRs, as systemd-cryptenroll does, returning a SystemdToken for the
+// keyslot. The secret is sealed with systemd's legacy ECC primary key, which
+// all versions of systemd-cryptsetup support. As systemd does not support
+// TPM2_PolicyOR, the opts cannot have Upgrades. A non-empty opts.PIN is salted
+// as by systemd.
+//
+// Once the token is imported in the LUKS2 header, and SystemdPassphrase of the
+// secret added to the keyslot, the volume is unlocked at boot by
+// systemd-cryptsetup (with the "tpm2-device=auto" option of crypttab).
+func SealSystemd(rw io.ReadWriter, secret []byte, keyslot int, opts SealOpts) (*SystemdToken, error) {
+	if len(opts.Upgrades) > 0 {
+		return nil, errors.New("systemd tokens do not support upgrades")
+	}
+	sel := tpm2.PCRSelection{Hash: opts.Bank, PCRs: opts.PCRs}
+	if sel.Hash == tpm2.AlgUnknown {
+		sel.Hash = tpm2.AlgSHA256
+	}
+	if len(sel.PCRs) == 0 {
+		sel.PCRs = DefaultPCRs
+	}
+	bank, ok := systemdBanks[sel.Hash]
+	if !ok {
+		return nil, fmt.Errorf("unsupported PCR bank %v", sel.Hash)
+	}
+	token := &SystemdToken{
+		Type:       SystemdTokenType,
+		Keyslots:   []string{strconv.Itoa(keyslot)},
+		PCRs:       sel.PCRs,
+		PCRBank:    bank,
+		PrimaryAlg: "ecc",
+		PIN:        opts.PIN != "",
+	}
+	var auth string
+	if token.PIN {
+		token.Salt = make([]byte, systemdSaltSize)
+		if _, err := rand.Read(token.Salt); err != nil {
+			return nil, err
+		}
+		auth = systemdPINAuth(opts.PIN, token.Salt)
+	}
+
+	primary, err := systemdPrimary(rw, token.PrimaryAlg)
+	if err != nil {
+		return nil, fmt.Errorf("creating primary key: %w", err)
+	}
+	defer primary.Close()
+	sealed, err := primary.SealWithAuthValue(secret, client.SealCurrent{PCRSelection: sel}, auth)
+	if err != nil {
+		return nil, fmt.Errorf("sealing secret: %w", err)
+	}
+	pub, err := tpm2.DecodePublic(sealed.GetPub())
+	if err != nil {
+		return nil, err
+	}
+	token.PolicyHash = hex.EncodeToString(pub.AuthPolicy)
+	if token.Blob, err = tpmutil.Pack(tpmutil.U16Bytes(sealed.GetPriv()), tpmutil.U16Bytes(sealed.GetPub())); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// The names of the PCR banks in systemd tokens.
+var systemdBanks = map[tpm2.Algorithm]string{
+	tpm2.AlgSHA1:   "sha1",
+	tpm2.AlgSHA256: "sha256",
+	tpm2.AlgSHA384: "sha384",
+	tpm2.AlgSHA512: "sha512",
+}
+
+// UnsealSystemd unseals the secret of a SystemdToken (enrolled by
+// systemd-cryptenroll, or returned by SealSystemd), if the PCRs are in the
+// state it was sealed to. The pin must be the PIN it was enrolled with, if
+// any. Tokens with signed PCR policies or pcrlock policies are not supported.
+func UnsealSystemd(rw io.ReadWriter, token *SystemdToken, pin string) ([]byte, error) {
+	if token.Type != SystemdTokenType {
+		return nil, fmt.Errorf("unsupported token type %q", token.Type)
+	}
+	if len(token.PublicKey) > 0 || len(token.PublicKeyPCRs) > 0 || token.PCRLock {
+		return nil, errors.New("signed PCR policies and pcrlock policies are not supported")
+	}
+	if token.PIN && pin == "" {
+		return nil, errors.New("token requires a PIN")
+	}
+	sel := tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: token.PCRs}
+	if token.PCRBank != "" {
+		sel.Hash = tpm2.AlgUnknown
+		for alg, name := range systemdBanks {
+			if name == token.PCRBank {
+				sel.Hash = alg
+			}
+		}
+		if sel.Hash == tpm2.AlgUnknown {
+			return nil, fmt.Errorf("unsupported PCR bank %q", token.PCRBank)
+		}
+	}
+	var priv, pub tpmutil.U16Bytes
+	blob := bytes.NewReader(token.Blob)
+	if err := tpmutil.UnpackBuf(blob, &priv, &pub); err != nil {
+		return nil, fmt.Errorf("invalid token blob: %w", err)
+	}
+
+	var parent *client.Key
+	var err error
+	if len(token.SRK) > 0 {
+		parent, err = client.KeyFromPersistentHandle(rw, client.SRKReservedHandle)
+	} else {
+		alg := token.PrimaryAlg
+		if alg == "" {
+			alg = "ecc"
+		}
+		parent, err = systemdPrimary(rw, alg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading primary key: %w", err)
+	}
+	defer parent.Close()
+
+	// The policy of systemd: TPM2_PolicyPCR (if sealed to PCRs), followed by
+	// TPM2_PolicyAuthValue (if a PIN is required).
+	var policy client.PolicySequence
+	if len(sel.PCRs) > 0 {
+		current, err := client.ReadPCRs(rw, sel)
+		if err != nil {
+			return nil, fmt.Errorf("reading PCRs: %w", err)
+		}
+		policy = append(policy, client.PolicyPCR{Pcrs: current})
+	}
+	var auth string
+	if token.PIN {
+		policy = append(policy, client.PolicyAuthValue{})
+		auth = systemdPINAuth(pin, token.Salt)
+	}
+	sealed := &pb.SealedBytes{Priv: priv, Pub: pub, Srk: pb.ObjectType(parent.PublicArea().Type)}
+	secret, err := parent.UnsealWithPolicy(sealed, policy, auth, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unsealing secret: %w", err)
+	}
+	return secret, nil
+}
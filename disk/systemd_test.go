@@ -0,0 +1,97 @@
+package disk_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/disk"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+func TestSystemdToken(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	for _, pin := range []string{"", "1234"} {
+		opts := disk.SealOpts{PCRs: []int{7, test.DebugPCR}, PIN: pin}
+		token, err := disk.SealSystemd(rwc, volumeKey[:32], 1, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The fields read by systemd-cryptsetup.
+		var fields map[string]interface{}
+		if err = json.Unmarshal(data, &fields); err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range []string{"type", "keyslots", "tpm2-blob", "tpm2-pcrs", "tpm2-pcr-bank", "tpm2-primary-alg", "tpm2-policy-hash", "tpm2-pin"} {
+			if _, ok := fields[field]; !ok {
+				t.Errorf("token has no %q field: %s", field, data)
+			}
+		}
+		if _, ok := fields["tpm2_salt"]; ok != (pin != "") {
+			t.Errorf("token has a salt: %v, want %v", ok, pin != "")
+		}
+
+		decoded := &disk.SystemdToken{}
+		if err = json.Unmarshal(data, decoded); err != nil {
+			t.Fatal(err)
+		}
+		if pin != "" {
+			if _, err = disk.UnsealSystemd(rwc, decoded, "4321"); err == nil {
+				t.Error("expected unsealing with the wrong PIN to fail")
+			}
+		}
+		secret, err := disk.UnsealSystemd(rwc, decoded, pin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(secret, volumeKey[:32]) {
+			t.Errorf("got secret %x, want %x", secret, volumeKey[:32])
+		}
+	}
+
+	token, err := disk.SealSystemd(rwc, volumeKey[:32], 1, disk.SealOpts{PCRs: []int{test.DebugPCR}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = tpm2.PCRExtend(rwc, tpmutil.Handle(test.DebugPCR), tpm2.AlgSHA256, make([]byte, 32), ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = disk.UnsealSystemd(rwc, token, ""); err == nil {
+		t.Error("expected unsealing with modified PCRs to fail")
+	}
+}
+
+func TestSystemdTokenUnsupported(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	upgrades := disk.SealOpts{Upgrades: []*pb.PCRs{{Hash: pb.HashAlgo_SHA256}}}
+	if _, err := disk.SealSystemd(rwc, volumeKey[:32], 0, upgrades); err == nil {
+		t.Error("expected sealing a systemd token with upgrades to fail")
+	}
+	token, err := disk.SealSystemd(rwc, volumeKey[:32], 0, disk.SealOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token.PCRLock = true
+	if _, err = disk.UnsealSystemd(rwc, token, ""); err == nil {
+		t.Error("expected unsealing a pcrlock token to fail")
+	}
+}
+
+func TestSystemdPassphrase(t *testing.T) {
+	if got := disk.SystemdPassphrase([]byte{0xfb, 0xff}); got != "+/8=" {
+		t.Errorf("got passphrase %q, want %q", got, "+/8=")
+	}
+}
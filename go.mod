@@ -6,5 +6,6 @@ require (
 	github.com/google/go-attestation v0.3.2
 	github.com/google/go-tpm v0.3.2
 	github.com/spf13/cobra v1.1.3
+	golang.org/x/crypto v0.0.0-20210314154223-e6e6c4f2bb5b
 	google.golang.org/protobuf v1.27.1
 )
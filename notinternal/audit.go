@@ -0,0 +1,78 @@
+package notinternal
+
+import (
+	"crypto"
+	"crypto/subtle"
+	"fmt"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// TPM_ST_ATTEST_SESSION_AUDIT, not defined by go-tpm.
+const tagAttestSessionAudit tpmutil.Tag = 0x8016
+
+// AuditDigest computes the audit digest of a session after executing the
+// provided commands. Starting from all zeros, the digest is extended with the
+// cpHash and rpHash of each command.
+func AuditDigest(commands []*pb.AuditedCommand, hash crypto.Hash) []byte {
+	digest := make([]byte, hash.Size())
+	for _, cmd := range commands {
+		h := hash.New()
+		h.Write(digest)
+		h.Write(cmd.GetCpHash())
+		h.Write(cmd.GetRpHash())
+		digest = h.Sum(nil)
+	}
+	return digest
+}
+
+// VerifySessionAudit performs the following checks to validate a SessionAudit:
+//   - the provided signature is generated by the trusted AK public key
+//   - the signature signs the provided audit data
+//   - the audit data is a TPMS_ATTEST of type TPM_ST_ATTEST_SESSION_AUDIT
+//   - the audited session digest matches the provided command log
+//   - the provided extraData matches that in the audit data
+//
+// Note that the caller must have already established trust in the provided
+// public key, and must check the commands themselves.
+func VerifySessionAudit(a *pb.SessionAudit, trustedPub crypto.PublicKey, extraData []byte) error {
+	if _, err := verifyAttestSignature(trustedPub, a.GetAudit(), a.GetRawSig()); err != nil {
+		return err
+	}
+
+	var magic uint32
+	var tag tpmutil.Tag
+	var signer, attestedExtraData, sessionDigest tpmutil.U16Bytes
+	var clockInfo tpm2.ClockInfo
+	var firmwareVersion uint64
+	var exclusiveSession byte
+	if _, err := tpmutil.Unpack(a.GetAudit(), &magic, &tag, &signer, &attestedExtraData, &clockInfo,
+		&firmwareVersion, &exclusiveSession, &sessionDigest); err != nil {
+		return fmt.Errorf("decoding attestation data failed: %v", err)
+	}
+	if magic != 0xff544347 {
+		return fmt.Errorf("incorrect magic value: %x", magic)
+	}
+	if tag != tagAttestSessionAudit {
+		return fmt.Errorf("expected session audit tag, got: %v", tag)
+	}
+	if subtle.ConstantTimeCompare(attestedExtraData, extraData) == 0 {
+		return fmt.Errorf("session audit extraData did not match expected extraData")
+	}
+
+	hash, err := tpm2.Algorithm(a.GetHash()).Hash()
+	if err != nil {
+		return err
+	}
+	for i, cmd := range a.GetCommands() {
+		if len(cmd.GetCpHash()) != hash.Size() || len(cmd.GetRpHash()) != hash.Size() {
+			return fmt.Errorf("audited command %d (0x%x) has invalid hash sizes", i, cmd.GetCommandCode())
+		}
+	}
+	if subtle.ConstantTimeCompare(sessionDigest, AuditDigest(a.GetCommands(), hash)) == 0 {
+		return fmt.Errorf("session audit digest does not match the audited commands")
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package notinternal
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"time"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+	"google.golang.org/protobuf/proto"
+)
+
+// BlobVersion is the format version of the SealedBytes and ImportBlobs
+// created by this library. Version 1 added the BlobHeader.
+const BlobVersion = 1
+
+// HeaderBlob is a blob with a BlobHeader: a SealedBytes or an ImportBlob.
+type HeaderBlob interface {
+	proto.Message
+	GetHeader() *pb.BlobHeader
+}
+
+// NewBlobHeader returns the header of a blob of the current version, created
+// now by the creator. Its checksum must be set with SetBlobChecksum once the
+// blob is complete.
+func NewBlobHeader(creator string, pcrBank pb.HashAlgo, policyDigest []byte) *pb.BlobHeader {
+	return &pb.BlobHeader{
+		Version:      BlobVersion,
+		Created:      time.Now().Unix(),
+		PcrBank:      pcrBank,
+		PolicyDigest: policyDigest,
+		Creator:      creator,
+	}
+}
+
+// SetBlobChecksum sets the checksum in the header of the blob, which must be
+// recomputed whenever the blob is modified.
+func SetBlobChecksum(blob HeaderBlob) error {
+	if blob.GetHeader() == nil {
+		return fmt.Errorf("blob has no header")
+	}
+	checksum, err := blobChecksum(blob)
+	if err != nil {
+		return err
+	}
+	blob.GetHeader().Checksum = checksum
+	return nil
+}
+
+// CheckBlobHeader checks that the blob can be decoded by this library: blobs
+// without a header (version 0) are always accepted, while blobs with a header
+// must have a supported version and a valid checksum.
+func CheckBlobHeader(blob HeaderBlob) error {
+	header := blob.GetHeader()
+	if header == nil {
+		return nil
+	}
+	if header.GetVersion() == 0 || header.GetVersion() > BlobVersion {
+		return fmt.Errorf("unsupported blob version %d, expected at most %d", header.GetVersion(), BlobVersion)
+	}
+	checksum, err := blobChecksum(blob)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(checksum, header.GetChecksum()) == 0 {
+		return fmt.Errorf("blob checksum does not match, the blob has been modified or corrupted")
+	}
+	return nil
+}
+
+func blobChecksum(blob HeaderBlob) ([]byte, error) {
+	unset := proto.Clone(blob).(HeaderBlob)
+	unset.GetHeader().Checksum = nil
+	encoded, err := proto.MarshalOptions{Deterministic: true}.Marshal(unset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode blob: %w", err)
+	}
+	checksum := sha256.Sum256(encoded)
+	return checksum[:], nil
+}
@@ -0,0 +1,52 @@
+package notinternal
+
+import (
+	"testing"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+func TestBlobHeader(t *testing.T) {
+	blob := &pb.ImportBlob{
+		Duplicate:  []byte("duplicate"),
+		PublicArea: []byte("public area"),
+		Pcrs:       &pb.PCRs{Hash: pb.HashAlgo_SHA256, Pcrs: map[uint32][]byte{0: {1}, 7: {2}}},
+		Header:     NewBlobHeader("test", pb.HashAlgo_SHA256, []byte("policy")),
+	}
+	if err := CheckBlobHeader(blob); err == nil {
+		t.Error("expected checking a blob without a checksum to fail")
+	}
+	if err := SetBlobChecksum(blob); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckBlobHeader(blob); err != nil {
+		t.Errorf("failed to check blob header: %v", err)
+	}
+
+	blob.Pcrs.Pcrs[7] = []byte{3}
+	if err := CheckBlobHeader(blob); err == nil {
+		t.Error("expected checking a modified blob to fail")
+	}
+	if err := SetBlobChecksum(blob); err != nil {
+		t.Fatal(err)
+	}
+	blob.Header.Creator = "other"
+	if err := CheckBlobHeader(blob); err == nil {
+		t.Error("expected checking a blob with a modified header to fail")
+	}
+
+	blob.Header.Version = BlobVersion + 1
+	if err := SetBlobChecksum(blob); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckBlobHeader(blob); err == nil {
+		t.Error("expected checking a blob of a future version to fail")
+	}
+
+	if err := CheckBlobHeader(&pb.SealedBytes{Priv: []byte("priv")}); err != nil {
+		t.Errorf("blobs without a header should be accepted: %v", err)
+	}
+	if err := SetBlobChecksum(&pb.SealedBytes{}); err == nil {
+		t.Error("expected setting the checksum of a blob without a header to fail")
+	}
+}
@@ -0,0 +1,294 @@
+package notinternal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// The CBOR major types (RFC 8949, section 3.1).
+const (
+	cborUnsigned byte = iota
+	cborNegative
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+// The CBOR simple values false, true and null.
+const (
+	cborFalse = 20
+	cborTrue  = 21
+	cborNull  = 22
+)
+
+// The maximum nesting of arrays, maps and tags accepted by UnmarshalCBOR.
+const maxCBORDepth = 16
+
+// CBORTag is a tagged CBOR data item (RFC 8949, section 3.4).
+type CBORTag struct {
+	Number  uint64
+	Content interface{}
+}
+
+// MarshalCBOR encodes v using the deterministic CBOR encoding (RFC 8949,
+// section 4.2.1). Only the data items needed for COSE and EAT are supported:
+// v must be nil, a bool, an int, an int64, a uint64, a []byte, a string, a
+// []interface{}, a map[int64]interface{}, a map[string]interface{} or a
+// CBORTag (or nest these).
+func MarshalCBOR(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := appendCBOR(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func appendCBORHead(buf *bytes.Buffer, major byte, arg uint64) {
+	major <<= 5
+	switch {
+	case arg < 24:
+		buf.WriteByte(major | byte(arg))
+	case arg <= math.MaxUint8:
+		buf.Write([]byte{major | 24, byte(arg)})
+	case arg <= math.MaxUint16:
+		buf.WriteByte(major | 25)
+		binary.Write(buf, binary.BigEndian, uint16(arg))
+	case arg <= math.MaxUint32:
+		buf.WriteByte(major | 26)
+		binary.Write(buf, binary.BigEndian, uint32(arg))
+	default:
+		buf.WriteByte(major | 27)
+		binary.Write(buf, binary.BigEndian, arg)
+	}
+}
+
+func appendCBORInt(buf *bytes.Buffer, i int64) {
+	if i < 0 {
+		appendCBORHead(buf, cborNegative, uint64(-(i + 1)))
+	} else {
+		appendCBORHead(buf, cborUnsigned, uint64(i))
+	}
+}
+
+func appendCBOR(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		appendCBORHead(buf, cborSimple, cborNull)
+	case bool:
+		if v {
+			appendCBORHead(buf, cborSimple, cborTrue)
+		} else {
+			appendCBORHead(buf, cborSimple, cborFalse)
+		}
+	case int:
+		appendCBORInt(buf, int64(v))
+	case int64:
+		appendCBORInt(buf, v)
+	case uint64:
+		appendCBORHead(buf, cborUnsigned, v)
+	case []byte:
+		appendCBORHead(buf, cborBytes, uint64(len(v)))
+		buf.Write(v)
+	case string:
+		appendCBORHead(buf, cborText, uint64(len(v)))
+		buf.WriteString(v)
+	case []interface{}:
+		appendCBORHead(buf, cborArray, uint64(len(v)))
+		for _, item := range v {
+			if err := appendCBOR(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[int64]interface{}:
+		entries := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			var encoded bytes.Buffer
+			appendCBORInt(&encoded, key)
+			entries[encoded.String()] = value
+		}
+		return appendCBORMap(buf, entries)
+	case map[string]interface{}:
+		entries := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			var encoded bytes.Buffer
+			appendCBORHead(&encoded, cborText, uint64(len(key)))
+			encoded.WriteString(key)
+			entries[encoded.String()] = value
+		}
+		return appendCBORMap(buf, entries)
+	case CBORTag:
+		appendCBORHead(buf, cborTag, v.Number)
+		return appendCBOR(buf, v.Content)
+	default:
+		return fmt.Errorf("unsupported CBOR type: %T", v)
+	}
+	return nil
+}
+
+// appendCBORMap appends a map, given its values by the encoding of their keys.
+// Deterministic encoding sorts the keys by their encoding.
+func appendCBORMap(buf *bytes.Buffer, entries map[string]interface{}) error {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	appendCBORHead(buf, cborMap, uint64(len(entries)))
+	for _, key := range keys {
+		buf.WriteString(key)
+		if err := appendCBOR(buf, entries[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnmarshalCBOR decodes a single CBOR data item, the inverse of MarshalCBOR.
+// Integers are decoded as int64 (failing for larger unsigned integers), maps
+// are decoded as a map[int64]interface{} or a map[string]interface{} (so their
+// keys must all be integers or all be text), and indefinite lengths and
+// floating-point numbers are not supported.
+func UnmarshalCBOR(data []byte) (interface{}, error) {
+	r := bytes.NewReader(data)
+	v, err := readCBOR(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%d trailing bytes after CBOR data item", r.Len())
+	}
+	return v, nil
+}
+
+func readCBORHead(r *bytes.Reader) (byte, uint64, error) {
+	initial, err := r.ReadByte()
+	if err != nil {
+		return 0, 0, fmt.Errorf("truncated CBOR data item")
+	}
+	major, info := initial>>5, initial&0x1f
+	if info < 24 {
+		return major, uint64(info), nil
+	}
+	// Indefinite lengths, floating-point numbers and the simple values
+	// encoded in an extra byte are not supported.
+	if info > 27 || major == cborSimple {
+		return 0, 0, fmt.Errorf("unsupported CBOR initial byte 0x%02x", initial)
+	}
+	arg := make([]byte, 1<<(info-24))
+	if n, _ := r.Read(arg); n != len(arg) {
+		return 0, 0, fmt.Errorf("truncated CBOR data item")
+	}
+	var padded [8]byte
+	copy(padded[8-len(arg):], arg)
+	return major, binary.BigEndian.Uint64(padded[:]), nil
+}
+
+func readCBORInt(major byte, arg uint64) (int64, error) {
+	if arg > math.MaxInt64 {
+		return 0, fmt.Errorf("CBOR integer out of range")
+	}
+	if major == cborNegative {
+		return -1 - int64(arg), nil
+	}
+	return int64(arg), nil
+}
+
+func readCBOR(r *bytes.Reader, depth int) (interface{}, error) {
+	if depth > maxCBORDepth {
+		return nil, fmt.Errorf("CBOR data item nested too deeply")
+	}
+	major, arg, err := readCBORHead(r)
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case cborUnsigned, cborNegative:
+		return readCBORInt(major, arg)
+	case cborBytes, cborText:
+		if arg > uint64(r.Len()) {
+			return nil, fmt.Errorf("truncated CBOR string")
+		}
+		content := make([]byte, arg)
+		r.Read(content)
+		if major == cborText {
+			return string(content), nil
+		}
+		return content, nil
+	case cborArray:
+		if arg > uint64(r.Len()) {
+			return nil, fmt.Errorf("truncated CBOR array")
+		}
+		items := make([]interface{}, arg)
+		for i := range items {
+			if items[i], err = readCBOR(r, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	case cborMap:
+		if arg > uint64(r.Len()) {
+			return nil, fmt.Errorf("truncated CBOR map")
+		}
+		return readCBORMap(r, arg, depth)
+	case cborTag:
+		content, err := readCBOR(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return CBORTag{Number: arg, Content: content}, nil
+	default:
+		switch arg {
+		case cborFalse:
+			return false, nil
+		case cborTrue:
+			return true, nil
+		case cborNull:
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unsupported CBOR simple value %d", arg)
+	}
+}
+
+// readCBORMap reads the entries of a map of the given size, whose keys must
+// all be integers or all be text.
+func readCBORMap(r *bytes.Reader, size uint64, depth int) (interface{}, error) {
+	intMap := make(map[int64]interface{})
+	textMap := make(map[string]interface{})
+	for i := uint64(0); i < size; i++ {
+		key, err := readCBOR(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := readCBOR(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		switch key := key.(type) {
+		case int64:
+			if _, ok := intMap[key]; ok {
+				return nil, fmt.Errorf("duplicate CBOR map key %d", key)
+			}
+			intMap[key] = value
+		case string:
+			if _, ok := textMap[key]; ok {
+				return nil, fmt.Errorf("duplicate CBOR map key %q", key)
+			}
+			textMap[key] = value
+		default:
+			return nil, fmt.Errorf("unsupported CBOR map key of type %T", key)
+		}
+		if len(intMap) > 0 && len(textMap) > 0 {
+			return nil, fmt.Errorf("unsupported CBOR map with both integer and text keys")
+		}
+	}
+	if len(textMap) > 0 {
+		return textMap, nil
+	}
+	return intMap, nil
+}
@@ -0,0 +1,157 @@
+package notinternal
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestMarshalCBOR(t *testing.T) {
+	// Examples from RFC 8949, Appendix A.
+	tests := []struct {
+		value   interface{}
+		encoded string
+	}{
+		{0, "00"},
+		{23, "17"},
+		{24, "1818"},
+		{int64(1000), "1903e8"},
+		{int64(1000000), "1a000f4240"},
+		{uint64(18446744073709551615), "1bffffffffffffffff"},
+		{-1, "20"},
+		{int64(-1000), "3903e7"},
+		{int64(math.MinInt64), "3b7fffffffffffffff"},
+		{false, "f4"},
+		{true, "f5"},
+		{nil, "f6"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+		{"IETF", "6449455446"},
+		{"ü", "62c3bc"},
+		{[]interface{}{}, "80"},
+		{[]interface{}{1, []interface{}{2, 3}, []interface{}{4, 5}}, "8301820203820405"},
+		{map[int64]interface{}{1: 2, 3: 4}, "a201020304"},
+		// Deterministic encoding sorts the keys by their encoding.
+		{map[int64]interface{}{-1: 0, 10: 0, 100: 0, 1: 0}, "a401000a00186400" + "2000"},
+		{map[string]interface{}{"b": 1, "a": 0, "aa": 2}, "a3616100616201626161" + "02"},
+		{CBORTag{Number: 1, Content: int64(1363896240)}, "c11a514b67b0"},
+	}
+	for _, test := range tests {
+		encoded, err := MarshalCBOR(test.value)
+		if err != nil {
+			t.Fatalf("MarshalCBOR(%v) failed: %v", test.value, err)
+		}
+		if got := hex.EncodeToString(encoded); got != test.encoded {
+			t.Errorf("MarshalCBOR(%v) = %s, want %s", test.value, got, test.encoded)
+		}
+	}
+
+	if _, err := MarshalCBOR(1.5); err == nil {
+		t.Error("expected encoding a float to fail")
+	}
+}
+
+func TestUnmarshalCBOR(t *testing.T) {
+	value := map[int64]interface{}{
+		-65537: []byte("bytes"),
+		1:      "text",
+		2:      []interface{}{int64(-1), int64(1 << 40), true, false, nil},
+		3:      CBORTag{Number: 18, Content: map[int64]interface{}{}},
+		4:      map[string]interface{}{"a": "b", "c": []interface{}{}},
+	}
+	encoded, err := MarshalCBOR(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := UnmarshalCBOR(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, value) {
+		t.Errorf("UnmarshalCBOR(MarshalCBOR(%v)) = %v", value, decoded)
+	}
+
+	for i := 0; i < len(encoded); i++ {
+		if _, err := UnmarshalCBOR(encoded[:i]); err == nil {
+			t.Errorf("expected decoding the encoding truncated to %d bytes to fail", i)
+		}
+	}
+
+	failures := []struct {
+		name    string
+		encoded string
+	}{
+		{"TrailingBytes", "0000"},
+		{"LargeInteger", "1bffffffffffffffff"},
+		{"Float", "f93c00"},
+		{"IndefiniteArray", "9f01ff"},
+		{"BytesMapKey", "a1416101"},
+		{"MixedMapKeys", "a201026161f6"},
+		{"DuplicateTextMapKey", "a2616101616102"},
+		{"DuplicateMapKey", "a201020103"},
+		{"HugeLength", "5bffffffffffffffff"},
+		{"UnassignedSimpleValue", "f0"},
+	}
+	for _, f := range failures {
+		t.Run(f.name, func(t *testing.T) {
+			data, err := hex.DecodeString(f.encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := UnmarshalCBOR(data); err == nil {
+				t.Error("expected decoding to fail")
+			}
+		})
+	}
+
+	nested := append(bytes.Repeat([]byte{0x81}, maxCBORDepth+1), 0)
+	if _, err := UnmarshalCBOR(nested); err == nil {
+		t.Error("expected decoding a deeply nested array to fail")
+	}
+}
+
+func TestCOSESign1(t *testing.T) {
+	protected, err := COSEProtectedHeader(-7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &COSESign1{
+		Protected: protected,
+		Algorithm: -7,
+		KeyID:     []byte("kid"),
+		Payload:   []byte("payload"),
+		Signature: []byte("signature"),
+	}
+	encoded, err := EncodeCOSESign1(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeCOSESign1(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, msg) {
+		t.Errorf("DecodeCOSESign1(EncodeCOSESign1(%v)) = %v", msg, decoded)
+	}
+
+	// The Sig_structure from RFC 9052, section 4.4.
+	toBeSigned, err := COSESign1ToBeSigned(protected, msg.Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hex.EncodeToString(toBeSigned), "846a5369676e61747572653143a1012640477061796c6f6164"; got != want {
+		t.Errorf("COSESign1ToBeSigned() = %s, want %s", got, want)
+	}
+
+	// Wrong tag, no algorithm, and a detached payload.
+	for _, encoded := range []string{"d18443a10126a0477061796c6f616440", "d28440a0477061796c6f616440", "d28443a10126a0f640"} {
+		data, err := hex.DecodeString(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := DecodeCOSESign1(data); err == nil {
+			t.Errorf("expected decoding %s to fail", encoded)
+		}
+	}
+}
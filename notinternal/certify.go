@@ -0,0 +1,109 @@
+package notinternal
+
+import (
+	"crypto"
+	"crypto/subtle"
+	"fmt"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+	"github.com/google/go-tpm/tpm2"
+)
+
+// VerifyKeyCertification performs the following checks to validate a
+// KeyCertification:
+//   - the provided signature is generated by the trusted AK public key
+//   - the signature signs the provided certify info
+//   - the certify info is a TPMS_ATTEST of type TPM_ST_ATTEST_CERTIFY
+//   - the certified Name matches the provided public area
+//   - the provided extraData matches that in the certify info
+//
+// The decoded public area of the certified key is returned. Note that the
+// caller must have already established trust in the provided public key, and
+// must check the attributes of the certified key.
+func VerifyKeyCertification(c *pb.KeyCertification, trustedPub crypto.PublicKey, extraData []byte) (tpm2.Public, error) {
+	if _, err := verifyAttestSignature(trustedPub, c.GetCertifyInfo(), c.GetRawSig()); err != nil {
+		return tpm2.Public{}, err
+	}
+
+	attestationData, err := tpm2.DecodeAttestationData(c.GetCertifyInfo())
+	if err != nil {
+		return tpm2.Public{}, fmt.Errorf("decoding attestation data failed: %v", err)
+	}
+	if attestationData.Type != tpm2.TagAttestCertify || attestationData.AttestedCertifyInfo == nil {
+		return tpm2.Public{}, fmt.Errorf("expected certify tag, got: %v", attestationData.Type)
+	}
+	if subtle.ConstantTimeCompare(attestationData.ExtraData, extraData) == 0 {
+		return tpm2.Public{}, fmt.Errorf("certify extraData did not match expected extraData")
+	}
+
+	pub, err := tpm2.DecodePublic(c.GetPublicArea())
+	if err != nil {
+		return tpm2.Public{}, fmt.Errorf("decoding public area failed: %v", err)
+	}
+	matches, err := attestationData.AttestedCertifyInfo.Name.MatchesPublic(pub)
+	if err != nil {
+		return tpm2.Public{}, err
+	}
+	if !matches {
+		return tpm2.Public{}, fmt.Errorf("certified name does not match the public area")
+	}
+	return pub, nil
+}
+
+// VerifyCreationCertification performs the following checks to validate a
+// CreationCertification:
+//   - the provided signature is generated by the trusted AK public key
+//   - the signature signs the provided creation info
+//   - the creation info is a TPMS_ATTEST of type TPM_ST_ATTEST_CREATION
+//   - the certified Name matches the provided public area
+//   - the certified creation hash matches the provided creation data
+//   - the provided extraData matches that in the creation info
+//
+// The decoded public area and creation data of the certified key are
+// returned. Note that the caller must have already established trust in the
+// provided public key, and must check the creation data itself.
+func VerifyCreationCertification(c *pb.CreationCertification, trustedPub crypto.PublicKey, extraData []byte) (tpm2.Public, *tpm2.CreationData, error) {
+	if _, err := verifyAttestSignature(trustedPub, c.GetCreationInfo(), c.GetRawSig()); err != nil {
+		return tpm2.Public{}, nil, err
+	}
+
+	attestationData, err := tpm2.DecodeAttestationData(c.GetCreationInfo())
+	if err != nil {
+		return tpm2.Public{}, nil, fmt.Errorf("decoding attestation data failed: %v", err)
+	}
+	creationInfo := attestationData.AttestedCreationInfo
+	if attestationData.Type != tpm2.TagAttestCreation || creationInfo == nil {
+		return tpm2.Public{}, nil, fmt.Errorf("expected creation tag, got: %v", attestationData.Type)
+	}
+	if subtle.ConstantTimeCompare(attestationData.ExtraData, extraData) == 0 {
+		return tpm2.Public{}, nil, fmt.Errorf("creation extraData did not match expected extraData")
+	}
+
+	pub, err := tpm2.DecodePublic(c.GetPublicArea())
+	if err != nil {
+		return tpm2.Public{}, nil, fmt.Errorf("decoding public area failed: %v", err)
+	}
+	matches, err := creationInfo.Name.MatchesPublic(pub)
+	if err != nil {
+		return tpm2.Public{}, nil, err
+	}
+	if !matches {
+		return tpm2.Public{}, nil, fmt.Errorf("certified name does not match the public area")
+	}
+
+	// The creation hash uses the name algorithm of the key.
+	hash, err := pub.NameAlg.Hash()
+	if err != nil {
+		return tpm2.Public{}, nil, err
+	}
+	creationHash := hash.New()
+	creationHash.Write(c.GetCreationData())
+	if subtle.ConstantTimeCompare(creationInfo.OpaqueDigest, creationHash.Sum(nil)) == 0 {
+		return tpm2.Public{}, nil, fmt.Errorf("certified creation hash does not match the creation data")
+	}
+	creationData, err := tpm2.DecodeCreationData(c.GetCreationData())
+	if err != nil {
+		return tpm2.Public{}, nil, fmt.Errorf("decoding creation data failed: %v", err)
+	}
+	return pub, creationData, nil
+}
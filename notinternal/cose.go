@@ -0,0 +1,128 @@
+package notinternal
+
+import (
+	"fmt"
+
+	"github.com/google/go-tpm/tpm2"
+)
+
+// COSETagSign1 is the CBOR tag of a COSE_Sign1 message (RFC 9052).
+const COSETagSign1 = 18
+
+// The COSE header parameters (RFC 9052, section 3.1) used in COSE_Sign1.
+const (
+	COSEHeaderAlgorithm = 1
+	COSEHeaderKeyID     = 4
+)
+
+// COSEAlgorithm is the TPM signing scheme of a COSE algorithm (RFC 9053).
+type COSEAlgorithm struct {
+	Scheme tpm2.Algorithm
+	Hash   tpm2.Algorithm
+	// The curve required by ECDSA algorithms.
+	Curve tpm2.EllipticCurve
+}
+
+// COSEAlgorithms contains the COSE algorithms matching each supported TPM
+// signing scheme, by their COSE algorithm identifier.
+var COSEAlgorithms = map[int64]COSEAlgorithm{
+	-7:   {tpm2.AlgECDSA, tpm2.AlgSHA256, tpm2.CurveNISTP256}, // ES256
+	-35:  {tpm2.AlgECDSA, tpm2.AlgSHA384, tpm2.CurveNISTP384}, // ES384
+	-36:  {tpm2.AlgECDSA, tpm2.AlgSHA512, tpm2.CurveNISTP521}, // ES512
+	-37:  {Scheme: tpm2.AlgRSAPSS, Hash: tpm2.AlgSHA256},      // PS256
+	-38:  {Scheme: tpm2.AlgRSAPSS, Hash: tpm2.AlgSHA384},      // PS384
+	-39:  {Scheme: tpm2.AlgRSAPSS, Hash: tpm2.AlgSHA512},      // PS512
+	-257: {Scheme: tpm2.AlgRSASSA, Hash: tpm2.AlgSHA256},      // RS256
+	-258: {Scheme: tpm2.AlgRSASSA, Hash: tpm2.AlgSHA384},      // RS384
+	-259: {Scheme: tpm2.AlgRSASSA, Hash: tpm2.AlgSHA512},      // RS512
+}
+
+// COSESign1 is a decoded COSE_Sign1 message.
+type COSESign1 struct {
+	// The encoded protected header, covered by the signature.
+	Protected []byte
+	// The algorithm and key ID, from the protected and unprotected headers.
+	Algorithm int64
+	KeyID     []byte
+	Payload   []byte
+	// The signature, with ECDSA signatures being the concatenation of R and S.
+	Signature []byte
+}
+
+// COSESign1ToBeSigned returns the data signed in a COSE_Sign1 message with the
+// encoded protected header and payload: the encoded Sig_structure, without
+// any external data.
+func COSESign1ToBeSigned(protected, payload []byte) ([]byte, error) {
+	return MarshalCBOR([]interface{}{"Signature1", protected, []byte{}, payload})
+}
+
+// EncodeCOSESign1 returns the tagged COSE_Sign1 message, with the key ID in
+// the unprotected header. msg.Protected must be the protected header of the
+// algorithm (see COSEProtectedHeader), and the signature must be of
+// COSESign1ToBeSigned(msg.Protected, msg.Payload).
+func EncodeCOSESign1(msg *COSESign1) ([]byte, error) {
+	unprotected := map[int64]interface{}{}
+	if len(msg.KeyID) > 0 {
+		unprotected[COSEHeaderKeyID] = msg.KeyID
+	}
+	return MarshalCBOR(CBORTag{
+		Number:  COSETagSign1,
+		Content: []interface{}{msg.Protected, unprotected, msg.Payload, msg.Signature},
+	})
+}
+
+// COSEProtectedHeader returns the encoded protected header of a COSE_Sign1
+// message signed with the algorithm.
+func COSEProtectedHeader(alg int64) ([]byte, error) {
+	return MarshalCBOR(map[int64]interface{}{COSEHeaderAlgorithm: alg})
+}
+
+// DecodeCOSESign1 decodes a COSE_Sign1 message, tagged or untagged. The
+// signature is not verified.
+func DecodeCOSESign1(data []byte) (*COSESign1, error) {
+	decoded, err := UnmarshalCBOR(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode COSE_Sign1: %w", err)
+	}
+	if tag, ok := decoded.(CBORTag); ok {
+		if tag.Number != COSETagSign1 {
+			return nil, fmt.Errorf("unexpected CBOR tag %d, expected COSE_Sign1 (%d)", tag.Number, COSETagSign1)
+		}
+		decoded = tag.Content
+	}
+	items, ok := decoded.([]interface{})
+	if !ok || len(items) != 4 {
+		return nil, fmt.Errorf("COSE_Sign1 is not an array of 4 items")
+	}
+	protected, ok1 := items[0].([]byte)
+	unprotected, ok2 := items[1].(map[int64]interface{})
+	payload, ok3 := items[2].([]byte)
+	signature, ok4 := items[3].([]byte)
+	if !ok1 || !ok2 || !ok3 || !ok4 {
+		return nil, fmt.Errorf("COSE_Sign1 has invalid item types (or a detached payload)")
+	}
+
+	msg := &COSESign1{Protected: protected, Payload: payload, Signature: signature}
+	header := map[int64]interface{}{}
+	if len(protected) > 0 {
+		decodedHeader, err := UnmarshalCBOR(protected)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode COSE protected header: %w", err)
+		}
+		if header, ok = decodedHeader.(map[int64]interface{}); !ok {
+			return nil, fmt.Errorf("COSE protected header is not a map")
+		}
+	}
+	if msg.Algorithm, ok = header[COSEHeaderAlgorithm].(int64); !ok {
+		return nil, fmt.Errorf("COSE protected header has no integer algorithm")
+	}
+	if kid, ok := header[COSEHeaderKeyID]; ok {
+		unprotected[COSEHeaderKeyID] = kid
+	}
+	if kid, ok := unprotected[COSEHeaderKeyID]; ok {
+		if msg.KeyID, ok = kid.([]byte); !ok {
+			return nil, fmt.Errorf("COSE key ID is not a byte string")
+		}
+	}
+	return msg, nil
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"crypto"
 	"fmt"
+	"hash"
 	"io"
 
 	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
@@ -89,7 +90,12 @@ func SamePCRSelection(p *pb.PCRs, sel tpm2.PCRSelection) bool {
 // PCRSessionAuth calculates the authorization value for the given PCRs.
 func PCRSessionAuth(p *pb.PCRs, hashAlg crypto.Hash) []byte {
 	// Start with all zeros, we only use a single policy command on our session.
-	oldDigest := make([]byte, hashAlg.Size())
+	return ExtendPCRPolicy(make([]byte, hashAlg.Size()), p, hashAlg)
+}
+
+// ExtendPCRPolicy calculates the policy digest resulting from executing
+// TPM2_PolicyPCR (for the given PCRs) on a session with digest oldDigest.
+func ExtendPCRPolicy(oldDigest []byte, p *pb.PCRs, hashAlg crypto.Hash) []byte {
 	ccPolicyPCR, _ := tpmutil.Pack(tpm2.CmdPolicyPCR)
 
 	// Extend the policy digest, see TPM2_PolicyPCR in Part 3 of the spec.
@@ -105,7 +111,12 @@ func PCRSessionAuth(p *pb.PCRs, hashAlg crypto.Hash) []byte {
 // PCRDigest computes the digest of the Pcrs. Note that the digest hash
 // algorithm may differ from the PCRs' hash (which denotes the PCR bank).
 func PCRDigest(p *pb.PCRs, hashAlg crypto.Hash) []byte {
-	hash := hashAlg.New()
+	return pcrDigest(p, hashAlg.New())
+}
+
+// pcrDigest computes the digest of the Pcrs, as in PCRDigest, with the hash
+// (which may be SM3, that has no crypto.Hash).
+func pcrDigest(p *pb.PCRs, hash hash.Hash) []byte {
 	for i := uint32(0); i < 24; i++ {
 		if pcrValue, exists := p.GetPcrs()[i]; exists {
 			hash.Write(pcrValue)
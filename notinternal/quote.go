@@ -1,12 +1,12 @@
 package notinternal
 
 import (
-	"bytes"
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/rsa"
 	"crypto/subtle"
 	"fmt"
+	"hash"
 
 	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
 	"github.com/google/go-tpm/tpm2"
@@ -23,34 +23,16 @@ import (
 // Note that the caller must have already established trust in the provided
 // public key before validating the Quote.
 //
-// VerifyQuote supports ECDSA and RSASSA signature verification.
+// VerifyQuote supports ECDSA, ECSchnorr, RSASSA and SM2 signature verification. SM2
+// quotes (using SM3) can only be verified with a key returned by PublicKey.
 func VerifyQuote(q *pb.Quote, trustedPub crypto.PublicKey, extraData []byte) error {
-	sig, err := tpm2.DecodeSignature(bytes.NewBuffer(q.GetRawSig()))
+	hashAlg, err := verifyAttestSignature(trustedPub, q.GetQuote(), q.GetRawSig())
 	if err != nil {
-		return fmt.Errorf("signature decoding failed: %v", err)
+		return err
 	}
-
-	var hash crypto.Hash
-	switch pub := trustedPub.(type) {
-	case *ecdsa.PublicKey:
-		hash, err = sig.ECC.HashAlg.Hash()
-		if err != nil {
-			return err
-		}
-		if err = verifyECDSAQuoteSignature(pub, hash, q.GetQuote(), sig); err != nil {
-			return err
-		}
-	case *rsa.PublicKey:
-		hash, err = sig.RSA.HashAlg.Hash()
-		if err != nil {
-			return err
-		}
-		if err = verifyRSASSAQuoteSignature(pub, hash, q.GetQuote(), sig); err != nil {
-			return err
-		}
-	default:
-		return fmt.Errorf("only RSA and ECC public keys are currently supported, received type: %T", pub)
-
+	newHash, err := NewHash(hashAlg)
+	if err != nil {
+		return err
 	}
 
 	// Decode and check for magic TPMS_GENERATED_VALUE.
@@ -68,7 +50,50 @@ func VerifyQuote(q *pb.Quote, trustedPub crypto.PublicKey, extraData []byte) err
 	if subtle.ConstantTimeCompare(attestationData.ExtraData, extraData) == 0 {
 		return fmt.Errorf("quote extraData did not match expected extraData")
 	}
-	return validatePCRDigest(attestedQuoteInfo, q.GetPcrs(), hash)
+	return validatePCRDigest(attestedQuoteInfo, q.GetPcrs(), newHash)
+}
+
+// verifyAttestSignature checks that rawSig (a TPMT_SIGNATURE) over attest was
+// generated by trustedPub, returning the hash algorithm of the signature.
+func verifyAttestSignature(trustedPub crypto.PublicKey, attest, rawSig []byte) (tpm2.Algorithm, error) {
+	sig, err := DecodeSignature(rawSig)
+	if err != nil {
+		return 0, fmt.Errorf("signature decoding failed: %v", err)
+	}
+
+	switch pub := trustedPub.(type) {
+	case *ecdsa.PublicKey:
+		if sig.Alg == algSM2 {
+			if err = verifySM2QuoteSignature(pub, attest, sig); err != nil {
+				return 0, err
+			}
+			return sig.ECC.HashAlg, nil
+		}
+		hash, err := sig.ECC.HashAlg.Hash()
+		if err != nil {
+			return 0, err
+		}
+		if sig.Alg == algECSchnorr {
+			err = verifyECSchnorrQuoteSignature(pub, hash, attest, sig)
+		} else {
+			err = verifyECDSAQuoteSignature(pub, hash, attest, sig)
+		}
+		if err != nil {
+			return 0, err
+		}
+		return sig.ECC.HashAlg, nil
+	case *rsa.PublicKey:
+		hash, err := sig.RSA.HashAlg.Hash()
+		if err != nil {
+			return 0, err
+		}
+		if err = verifyRSASSAQuoteSignature(pub, hash, attest, sig); err != nil {
+			return 0, err
+		}
+		return sig.RSA.HashAlg, nil
+	default:
+		return 0, fmt.Errorf("only RSA and ECC public keys are currently supported, received type: %T", pub)
+	}
 }
 
 func verifyECDSAQuoteSignature(ecdsaPub *ecdsa.PublicKey, hash crypto.Hash, quoted []byte, sig *tpm2.Signature) error {
@@ -84,6 +109,32 @@ func verifyECDSAQuoteSignature(ecdsaPub *ecdsa.PublicKey, hash crypto.Hash, quot
 	return nil
 }
 
+func verifyECSchnorrQuoteSignature(ecdsaPub *ecdsa.PublicKey, hash crypto.Hash, quoted []byte, sig *tpm2.Signature) error {
+	hashConstructor := hash.New()
+	hashConstructor.Write(quoted)
+	if !VerifyECSchnorr(ecdsaPub, hash, hashConstructor.Sum(nil), sig.ECC.R, sig.ECC.S) {
+		return fmt.Errorf("ECSchnorr signature verification failed")
+	}
+	return nil
+}
+
+// verifySM2QuoteSignature verifies an SM2 signature made by the TPM, which
+// signs the SM3 digest of the attestation structure, without a Z prefix.
+func verifySM2QuoteSignature(ecdsaPub *ecdsa.PublicKey, quoted []byte, sig *tpm2.Signature) error {
+	if sig.ECC.HashAlg != algSM3 {
+		return fmt.Errorf("SM2 signatures must use SM3, got hash 0x%x", sig.ECC.HashAlg)
+	}
+	if ecdsaPub.Curve != SM2P256() {
+		return fmt.Errorf("SM2 signatures can only be verified with keys on the SM2 curve")
+	}
+	hash := NewSM3()
+	hash.Write(quoted)
+	if !VerifySM2(ecdsaPub, hash.Sum(nil), sig.ECC.R, sig.ECC.S) {
+		return fmt.Errorf("SM2 signature verification failed")
+	}
+	return nil
+}
+
 func verifyRSASSAQuoteSignature(rsaPub *rsa.PublicKey, hash crypto.Hash, quoted []byte, sig *tpm2.Signature) error {
 	if sig.Alg != tpm2.AlgRSASSA {
 		return fmt.Errorf("signature scheme 0x%x is not supported, only RSASSA (PKCS#1 v1.5) is supported", sig.Alg)
@@ -97,11 +148,11 @@ func verifyRSASSAQuoteSignature(rsaPub *rsa.PublicKey, hash crypto.Hash, quoted
 	return nil
 }
 
-func validatePCRDigest(quoteInfo *tpm2.QuoteInfo, pcrs *pb.PCRs, hash crypto.Hash) error {
+func validatePCRDigest(quoteInfo *tpm2.QuoteInfo, pcrs *pb.PCRs, newHash func() hash.Hash) error {
 	if !SamePCRSelection(pcrs, quoteInfo.PCRSelection) {
 		return fmt.Errorf("given PCRs and Quote do not have the same PCR selection")
 	}
-	pcrDigest := PCRDigest(pcrs, hash)
+	pcrDigest := pcrDigest(pcrs, newHash())
 	if subtle.ConstantTimeCompare(quoteInfo.PCRDigest, pcrDigest) == 0 {
 		return fmt.Errorf("given PCRs digest not matching")
 	}
@@ -0,0 +1,43 @@
+package notinternal
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"math/big"
+)
+
+// VerifyECSchnorr verifies the ECSchnorr signature (r, s) of a digest, as
+// generated by a TPM with the hash. Following the TPM reference
+// implementation, r must be Hash(E.x || digest), truncated to the size of the
+// curve order, with E = [s]G - [r]Q.
+func VerifyECSchnorr(pub *ecdsa.PublicKey, hash crypto.Hash, digest []byte, r, s *big.Int) bool {
+	if !hash.Available() || !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		return false
+	}
+	n := pub.Curve.Params().N
+	// r and s must be in [1, n-1].
+	if r.Sign() <= 0 || s.Sign() <= 0 || r.Cmp(n) >= 0 || s.Cmp(n) >= 0 {
+		return false
+	}
+
+	minusR := new(big.Int).Sub(n, r)
+	sx, sy := pub.Curve.ScalarBaseMult(s.Bytes())
+	rx, ry := pub.Curve.ScalarMult(pub.X, pub.Y, minusR.Bytes())
+	ex, ey := pub.Curve.Add(sx, sy, rx, ry)
+	if ex.Sign() == 0 && ey.Sign() == 0 {
+		return false
+	}
+
+	size := (n.BitLen() + 7) / 8
+	if ex.BitLen() > 8*size {
+		return false
+	}
+	h := hash.New()
+	h.Write(ex.FillBytes(make([]byte, size)))
+	h.Write(digest)
+	e := h.Sum(nil)
+	if len(e) > size {
+		e = e[:size]
+	}
+	return new(big.Int).SetBytes(e).Cmp(r) == 0
+}
@@ -0,0 +1,103 @@
+package notinternal
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+// signSchnorr signs the digest as the TPM reference implementation does:
+// r = Hash(R.x || digest), truncated to the size of the order, with R = [k]G,
+// and s = k + r*d (mod n).
+func signSchnorr(t *testing.T, priv *ecdsa.PrivateKey, hash crypto.Hash, digest []byte) (r, s *big.Int) {
+	params := priv.Curve.Params()
+	size := (params.N.BitLen() + 7) / 8
+	for {
+		k, err := rand.Int(rand.Reader, params.N)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if k.Sign() == 0 {
+			continue
+		}
+		x, _ := priv.Curve.ScalarBaseMult(k.Bytes())
+		h := hash.New()
+		h.Write(x.FillBytes(make([]byte, size)))
+		h.Write(digest)
+		e := h.Sum(nil)
+		if len(e) > size {
+			e = e[:size]
+		}
+		r = new(big.Int).SetBytes(e)
+		if r.Sign() == 0 || r.Cmp(params.N) >= 0 {
+			continue
+		}
+		s = new(big.Int).Mul(r, priv.D)
+		s.Add(s, k)
+		s.Mod(s, params.N)
+		if s.Sign() != 0 {
+			return r, s
+		}
+	}
+}
+
+func TestVerifyECSchnorr(t *testing.T) {
+	for _, curve := range []struct {
+		name  string
+		curve elliptic.Curve
+		hash  crypto.Hash
+	}{
+		{"P256", elliptic.P256(), crypto.SHA256},
+		{"P384", elliptic.P384(), crypto.SHA384},
+		{"P521", elliptic.P521(), crypto.SHA512},
+		{"P256-SHA512", elliptic.P256(), crypto.SHA512},
+	} {
+		t.Run(curve.name, func(t *testing.T) {
+			priv, err := ecdsa.GenerateKey(curve.curve, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := curve.hash.New()
+			h.Write([]byte("data"))
+			digest := h.Sum(nil)
+			r, s := signSchnorr(t, priv, curve.hash, digest)
+			if !VerifyECSchnorr(&priv.PublicKey, curve.hash, digest, r, s) {
+				t.Fatal("failed to verify an ECSchnorr signature")
+			}
+
+			otherKey, err := ecdsa.GenerateKey(curve.curve, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n := curve.curve.Params().N
+			one := big.NewInt(1)
+			tests := []struct {
+				name   string
+				pub    *ecdsa.PublicKey
+				hash   crypto.Hash
+				digest []byte
+				r, s   *big.Int
+			}{
+				{"OtherKey", &otherKey.PublicKey, curve.hash, digest, r, s},
+				{"OtherHash", &priv.PublicKey, crypto.SHA1, digest, r, s},
+				{"OtherDigest", &priv.PublicKey, curve.hash, append([]byte{1}, digest[1:]...), r, s},
+				{"ModifiedR", &priv.PublicKey, curve.hash, digest, new(big.Int).Add(r, one), s},
+				{"ModifiedS", &priv.PublicKey, curve.hash, digest, r, new(big.Int).Add(s, one)},
+				{"ZeroR", &priv.PublicKey, curve.hash, digest, new(big.Int), s},
+				{"ZeroS", &priv.PublicKey, curve.hash, digest, r, new(big.Int)},
+				{"LargeR", &priv.PublicKey, curve.hash, digest, new(big.Int).Add(r, n), s},
+				{"LargeS", &priv.PublicKey, curve.hash, digest, r, new(big.Int).Add(s, n)},
+			}
+			for _, test := range tests {
+				t.Run(test.name, func(t *testing.T) {
+					if VerifyECSchnorr(test.pub, test.hash, test.digest, test.r, test.s) {
+						t.Error("expected ECSchnorr verification to fail")
+					}
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package notinternal
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"math/big"
+	"sync"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// The ShangMi algorithms and the ECSchnorr scheme, which go-tpm does not
+// define.
+const (
+	algSM3       tpm2.Algorithm = 0x0012
+	algSM2       tpm2.Algorithm = 0x001B
+	algECSchnorr tpm2.Algorithm = 0x001C
+)
+
+// SM2DefaultID is the default user ID (ID_A) of SM2 signatures, as specified
+// by GM/T 0009-2012.
+const SM2DefaultID = "1234567812345678"
+
+var (
+	sm2Once  sync.Once
+	sm2Curve *elliptic.CurveParams
+)
+
+// SM2P256 returns the SM2 elliptic curve recommended by GB/T 32918.5-2017
+// (tpm2.CurveSM2P256). Its a parameter is p - 3, like the NIST curves, so
+// the generic elliptic.CurveParams arithmetic applies.
+func SM2P256() elliptic.Curve {
+	sm2Once.Do(func() {
+		sm2Curve = &elliptic.CurveParams{Name: "SM2-P-256", BitSize: 256}
+		sm2Curve.P, _ = new(big.Int).SetString("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF00000000FFFFFFFFFFFFFFFF", 16)
+		sm2Curve.N, _ = new(big.Int).SetString("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFF7203DF6B21C6052B53BBF40939D54123", 16)
+		sm2Curve.B, _ = new(big.Int).SetString("28E9FA9E9D9F5E344D5A9E4BCF6509A7F39789F515AB8F92DDBCBD414D940E93", 16)
+		sm2Curve.Gx, _ = new(big.Int).SetString("32C4AE2C1F1981195F9904466A39C9948FE30BBFF2660BE1715A4589334C74C7", 16)
+		sm2Curve.Gy, _ = new(big.Int).SetString("BC3736A2F4F6779C59BDCEE36B692153D0A9877CC62A474002DF32E52139F0A0", 16)
+	})
+	return sm2Curve
+}
+
+// SM2Z returns Z, the SM3 digest of the user ID and the public key, which
+// prefixes the message signed by an SM2 signature (see GB/T 32918.2-2016,
+// section 5.5). An empty id means SM2DefaultID.
+func SM2Z(pub *ecdsa.PublicKey, id string) []byte {
+	if id == "" {
+		id = SM2DefaultID
+	}
+	params := pub.Curve.Params()
+	size := (params.BitSize + 7) / 8
+	a := new(big.Int).Sub(params.P, big.NewInt(3))
+
+	z := NewSM3()
+	entl := make([]byte, 2)
+	binary.BigEndian.PutUint16(entl, uint16(8*len(id)))
+	z.Write(entl)
+	z.Write([]byte(id))
+	for _, v := range []*big.Int{a, params.B, params.Gx, params.Gy, pub.X, pub.Y} {
+		z.Write(v.FillBytes(make([]byte, size)))
+	}
+	return z.Sum(nil)
+}
+
+// SM2Digest returns the digest signed by an SM2 signature over msg:
+// SM3(Z || msg), with Z as returned by SM2Z.
+func SM2Digest(pub *ecdsa.PublicKey, id string, msg []byte) []byte {
+	e := NewSM3()
+	e.Write(SM2Z(pub, id))
+	e.Write(msg)
+	return e.Sum(nil)
+}
+
+// VerifySM2 verifies the SM2 signature (r, s) of a digest (as returned by
+// SM2Digest, or the SM3 digest of a TPM attestation structure), following
+// GB/T 32918.2-2016, section 7.
+func VerifySM2(pub *ecdsa.PublicKey, digest []byte, r, s *big.Int) bool {
+	if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		return false
+	}
+	n := pub.Curve.Params().N
+	one := big.NewInt(1)
+	if r.Cmp(one) < 0 || r.Cmp(n) >= 0 || s.Cmp(one) < 0 || s.Cmp(n) >= 0 {
+		return false
+	}
+	t := new(big.Int).Add(r, s)
+	t.Mod(t, n)
+	if t.Sign() == 0 {
+		return false
+	}
+	x1, y1 := pub.Curve.ScalarBaseMult(s.Bytes())
+	x2, y2 := pub.Curve.ScalarMult(pub.X, pub.Y, t.Bytes())
+	x, _ := pub.Curve.Add(x1, y1, x2, y2)
+
+	expected := new(big.Int).SetBytes(digest)
+	expected.Add(expected, x)
+	expected.Mod(expected, n)
+	return expected.Cmp(r) == 0
+}
+
+// NewHash returns a constructor for the TPM hash algorithm, including SM3
+// (which has no crypto.Hash).
+func NewHash(alg tpm2.Algorithm) (func() hash.Hash, error) {
+	if alg == algSM3 {
+		return NewSM3, nil
+	}
+	h, err := alg.Hash()
+	if err != nil {
+		return nil, err
+	}
+	return h.New, nil
+}
+
+// PublicKey returns the public key of a TPM public area, as in
+// tpm2.Public.Key, but also supporting ECC keys on the SM2 curve. Those are
+// returned as an *ecdsa.PublicKey on the SM2P256 curve.
+func PublicKey(pub tpm2.Public) (crypto.PublicKey, error) {
+	if pub.ECCParameters == nil || pub.ECCParameters.CurveID != tpm2.CurveSM2P256 {
+		return pub.Key()
+	}
+	key := &ecdsa.PublicKey{
+		Curve: SM2P256(),
+		X:     pub.ECCParameters.Point.X(),
+		Y:     pub.ECCParameters.Point.Y(),
+	}
+	if !key.Curve.IsOnCurve(key.X, key.Y) {
+		return nil, fmt.Errorf("public point is not on the SM2 curve")
+	}
+	return key, nil
+}
+
+// DecodeSignature decodes a TPMT_SIGNATURE, as in tpm2.DecodeSignature, but
+// also supporting ECSchnorr and SM2 signatures. Those have the same encoding
+// as ECDSA signatures.
+func DecodeSignature(in []byte) (*tpm2.Signature, error) {
+	var alg tpm2.Algorithm
+	if _, err := tpmutil.Unpack(in, &alg); err != nil {
+		return nil, fmt.Errorf("decoding signature algorithm: %w", err)
+	}
+	if alg != algECSchnorr && alg != algSM2 {
+		return tpm2.DecodeSignature(bytes.NewBuffer(in))
+	}
+	ecdsaSig := append([]byte{}, in...)
+	binary.BigEndian.PutUint16(ecdsaSig, uint16(tpm2.AlgECDSA))
+	sig, err := tpm2.DecodeSignature(bytes.NewBuffer(ecdsaSig))
+	if err != nil {
+		return nil, err
+	}
+	sig.Alg = alg
+	return sig, nil
+}
@@ -0,0 +1,130 @@
+package notinternal
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// SM3Size is the size of an SM3 digest in bytes.
+const SM3Size = 32
+
+const sm3BlockSize = 64
+
+var sm3IV = [8]uint32{
+	0x7380166f, 0x4914b2b9, 0x172442d7, 0xda8a0600,
+	0xa96f30bc, 0x163138aa, 0xe38dee4d, 0xb0fb0e4e,
+}
+
+type sm3Digest struct {
+	h   [8]uint32
+	buf [sm3BlockSize]byte
+	n   int
+	len uint64
+}
+
+// NewSM3 returns a hash.Hash computing the SM3 digest (GB/T 32905-2016), the
+// hash of the ShangMi algorithms used by TPMs in China. The standard library
+// does not implement SM3, so it cannot be used as a crypto.Hash.
+func NewSM3() hash.Hash {
+	d := new(sm3Digest)
+	d.Reset()
+	return d
+}
+
+func (d *sm3Digest) Reset() {
+	d.h = sm3IV
+	d.n = 0
+	d.len = 0
+}
+
+func (d *sm3Digest) Size() int { return SM3Size }
+
+func (d *sm3Digest) BlockSize() int { return sm3BlockSize }
+
+func (d *sm3Digest) Write(p []byte) (int, error) {
+	written := len(p)
+	d.len += uint64(written)
+	if d.n > 0 {
+		copied := copy(d.buf[d.n:], p)
+		d.n += copied
+		p = p[copied:]
+		if d.n < sm3BlockSize {
+			return written, nil
+		}
+		d.block(d.buf[:])
+		d.n = 0
+	}
+	for len(p) >= sm3BlockSize {
+		d.block(p[:sm3BlockSize])
+		p = p[sm3BlockSize:]
+	}
+	d.n = copy(d.buf[:], p)
+	return written, nil
+}
+
+func (d *sm3Digest) Sum(in []byte) []byte {
+	// Pad a copy of the state, so the caller can keep writing.
+	c := *d
+	padding := make([]byte, sm3BlockSize+8)
+	padding[0] = 0x80
+	padLen := sm3BlockSize - (c.n+8)%sm3BlockSize
+	binary.BigEndian.PutUint64(padding[padLen:], c.len*8)
+	c.Write(padding[:padLen+8])
+
+	out := make([]byte, SM3Size)
+	for i, v := range c.h {
+		binary.BigEndian.PutUint32(out[4*i:], v)
+	}
+	return append(in, out...)
+}
+
+func sm3P0(x uint32) uint32 { return x ^ bits.RotateLeft32(x, 9) ^ bits.RotateLeft32(x, 17) }
+
+func sm3P1(x uint32) uint32 { return x ^ bits.RotateLeft32(x, 15) ^ bits.RotateLeft32(x, 23) }
+
+// block runs the compression function on a 64 byte block.
+func (d *sm3Digest) block(p []byte) {
+	var w [68]uint32
+	for i := 0; i < 16; i++ {
+		w[i] = binary.BigEndian.Uint32(p[4*i:])
+	}
+	for i := 16; i < 68; i++ {
+		w[i] = sm3P1(w[i-16]^w[i-9]^bits.RotateLeft32(w[i-3], 15)) ^ bits.RotateLeft32(w[i-13], 7) ^ w[i-6]
+	}
+
+	a, b, c, e, f, g, h := d.h[0], d.h[1], d.h[2], d.h[4], d.h[5], d.h[6], d.h[7]
+	dd := d.h[3]
+	for j := 0; j < 64; j++ {
+		t := uint32(0x79cc4519)
+		var ff, gg uint32
+		if j < 16 {
+			ff = a ^ b ^ c
+			gg = e ^ f ^ g
+		} else {
+			t = 0x7a879d8a
+			ff = (a & b) | (a & c) | (b & c)
+			gg = (e & f) | (^e & g)
+		}
+		ss1 := bits.RotateLeft32(bits.RotateLeft32(a, 12)+e+bits.RotateLeft32(t, j%32), 7)
+		ss2 := ss1 ^ bits.RotateLeft32(a, 12)
+		tt1 := ff + dd + ss2 + (w[j] ^ w[j+4])
+		tt2 := gg + h + ss1 + w[j]
+		dd = c
+		c = bits.RotateLeft32(b, 9)
+		b = a
+		a = tt1
+		h = g
+		g = bits.RotateLeft32(f, 19)
+		f = e
+		e = sm3P0(tt2)
+	}
+	d.h[0] ^= a
+	d.h[1] ^= b
+	d.h[2] ^= c
+	d.h[3] ^= dd
+	d.h[4] ^= e
+	d.h[5] ^= f
+	d.h[6] ^= g
+	d.h[7] ^= h
+}
@@ -0,0 +1,200 @@
+package notinternal
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestSM3(t *testing.T) {
+	// The vectors of GB/T 32905-2016, appendix A, and digests computed with
+	// "openssl dgst -sm3" covering the padding of empty and multi-block inputs.
+	tests := []struct {
+		input  string
+		digest string
+	}{
+		{"", "1ab21d8355cfa17f8e61194831e81a8f22bec8c728fefb747ed035eb5082aa2b"},
+		{strings.Repeat("a", 1000), "f4bedca973227d45c5b822551d2e762d4cfb0e9af70b241452545727b5fb046f"},
+		{"abc", "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"},
+		{"abcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcdabcd", "debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732"},
+	}
+	for _, test := range tests {
+		h := NewSM3()
+		h.Write([]byte(test.input))
+		if got := hex.EncodeToString(h.Sum(nil)); got != test.digest {
+			t.Errorf("SM3(%q) = %s, want %s", test.input, got, test.digest)
+		}
+
+		// Writing byte by byte, and summing in the middle, gives the same digest.
+		h.Reset()
+		for i := range test.input {
+			h.Sum(nil)
+			h.Write([]byte{test.input[i]})
+		}
+		if got := hex.EncodeToString(h.Sum(nil)); got != test.digest {
+			t.Errorf("SM3(%q) written byte by byte = %s, want %s", test.input, got, test.digest)
+		}
+	}
+}
+
+func TestSM2Curve(t *testing.T) {
+	curve := SM2P256()
+	params := curve.Params()
+	if !curve.IsOnCurve(params.Gx, params.Gy) {
+		t.Fatal("the SM2 generator is not on the curve")
+	}
+	if x, y := curve.ScalarBaseMult(params.N.Bytes()); x.Sign() != 0 || y.Sign() != 0 {
+		t.Error("the SM2 generator does not have order N")
+	}
+}
+
+// A known-answer test of SM2 signature verification. The key is the example
+// key of GM/T 0003.5-2012, and the signatures were made with OpenSSL 3.0:
+//
+//	openssl pkeyutl -sign -inkey key.pem -in msg -rawin -digest sm3 -pkeyopt distid:<id>
+//
+// The expected Z and digest were computed with "openssl dgst -sm3".
+func TestSM2KnownAnswer(t *testing.T) {
+	d := mustParseHex(t, "3945208f7b2144b13f36e38ac6d39f95889393692860b51a42fb81ef4df7c5b8")
+	pub := &ecdsa.PublicKey{
+		Curve: SM2P256(),
+		X:     mustParseHex(t, "09f9df311e5421a150dd7d161e4bc5c672179fad1833fc076bb08ff356f35020"),
+		Y:     mustParseHex(t, "ccea490ce26775a52dc6ea718cc1aa600aed05fbf35e084a6632f6072da9ad13"),
+	}
+	if x, y := pub.Curve.ScalarBaseMult(d.Bytes()); x.Cmp(pub.X) != 0 || y.Cmp(pub.Y) != 0 {
+		t.Fatal("the public key does not match the private key")
+	}
+	msg := []byte("message digest")
+
+	tests := []struct {
+		name   string
+		id     string
+		z      string
+		digest string
+		r, s   string
+	}{
+		{
+			"DefaultID", SM2DefaultID,
+			"b2e14c5c79c6df5b85f4fe7ed8db7a262b9da7e07ccb0ea9f4747b8ccda8a4f3",
+			"f0b43e94ba45accaace692ed534382eb17e6ab5a19ce7b31f4486fdfc0d28640",
+			"de7b6308c87a92f40fb8cb95ffaaf266bf982b729805c28e9fc41016ee0701d7",
+			"fb2535981b114bf788f8b921ae78bd0db43bdd86d3cb356e8374284440181918",
+		},
+		{
+			"OtherID", "ALICE123@YAHOO.COM",
+			"26db4bc1839bd22e97e1dab667ec5e0a730d5e16521398b4435c576a93afd7ed",
+			"abf7eb631d94615fd1a941d40e99932ddb1899e1dfae7179b4a79417ea3743e5",
+			"f6ae9e06087a3e025a18bbb8ba90c751afd79b2dd2d2ae347a94f78684a0b823",
+			"d4a0619a245c88f5b4b1dbb58a9bb0dcf1031c460e63770de52a4645c351d4d3",
+		},
+	}
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := hex.EncodeToString(SM2Z(pub, test.id)); got != test.z {
+				t.Errorf("got Z %s, want %s", got, test.z)
+			}
+			digest := SM2Digest(pub, test.id, msg)
+			if got := hex.EncodeToString(digest); got != test.digest {
+				t.Errorf("got digest %s, want %s", got, test.digest)
+			}
+			r, s := mustParseHex(t, test.r), mustParseHex(t, test.s)
+			if !VerifySM2(pub, digest, r, s) {
+				t.Error("failed to verify the OpenSSL signature")
+			}
+			// The signature is bound to the ID.
+			other := tests[(i+1)%len(tests)]
+			if VerifySM2(pub, SM2Digest(pub, other.id, msg), r, s) {
+				t.Errorf("verified the signature with the ID %q", other.id)
+			}
+		})
+	}
+}
+
+func mustParseHex(t *testing.T, s string) *big.Int {
+	t.Helper()
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		t.Fatalf("invalid hex number %q", s)
+	}
+	return n
+}
+
+// signSM2 signs the digest, following GB/T 32918.2-2016, section 6.
+func signSM2(t *testing.T, priv *ecdsa.PrivateKey, digest []byte) (r, s *big.Int) {
+	n := priv.Curve.Params().N
+	for {
+		k, err := rand.Int(rand.Reader, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		x1, _ := priv.Curve.ScalarBaseMult(k.Bytes())
+		r = new(big.Int).SetBytes(digest)
+		r.Add(r, x1)
+		r.Mod(r, n)
+		if r.Sign() == 0 || new(big.Int).Add(r, k).Cmp(n) == 0 {
+			continue
+		}
+		d1 := new(big.Int).Add(priv.D, big.NewInt(1))
+		d1.ModInverse(d1, n)
+		s = new(big.Int).Mul(r, priv.D)
+		s.Sub(k, s)
+		s.Mul(s, d1)
+		s.Mod(s, n)
+		if s.Sign() != 0 {
+			return r, s
+		}
+	}
+}
+
+func TestVerifySM2(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(SM2P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("message digest")
+	digest := SM2Digest(&priv.PublicKey, "", msg)
+	if !bytes.Equal(digest, SM2Digest(&priv.PublicKey, SM2DefaultID, msg)) {
+		t.Error("the empty ID is not the default ID")
+	}
+	r, s := signSM2(t, priv, digest)
+	if !VerifySM2(&priv.PublicKey, digest, r, s) {
+		t.Fatal("failed to verify an SM2 signature")
+	}
+
+	otherKey, err := ecdsa.GenerateKey(SM2P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub := &otherKey.PublicKey
+	one := big.NewInt(1)
+	tests := []struct {
+		name   string
+		pub    *ecdsa.PublicKey
+		digest []byte
+		r, s   *big.Int
+	}{
+		{"OtherKey", otherPub, digest, r, s},
+		{"OtherID", &priv.PublicKey, SM2Digest(&priv.PublicKey, "other", msg), r, s},
+		{"OtherMessage", &priv.PublicKey, SM2Digest(&priv.PublicKey, "", []byte("other")), r, s},
+		{"ModifiedR", &priv.PublicKey, digest, new(big.Int).Add(r, one), s},
+		{"ModifiedS", &priv.PublicKey, digest, r, new(big.Int).Add(s, one)},
+		{"ZeroS", &priv.PublicKey, digest, r, new(big.Int)},
+		{"LargeR", &priv.PublicKey, digest, new(big.Int).Add(r, SM2P256().Params().N), s},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if VerifySM2(test.pub, test.digest, test.r, test.s) {
+				t.Error("expected SM2 verification to fail")
+			}
+		})
+	}
+
+	if VerifySM2(&ecdsa.PublicKey{Curve: elliptic.P256(), X: priv.X, Y: priv.Y}, digest, r, s) {
+		t.Error("expected SM2 verification with the wrong curve to fail")
+	}
+}
@@ -63,6 +63,16 @@ func SkipOnUnsupportedAlg(t testing.TB, rw io.ReadWriter, alg tpm2.Algorithm) {
 	t.Skipf("Algorithm %v is not supported by the TPM", alg)
 }
 
+// SkipOnRealTPM skips tests which modify the state of the TPM in ways that
+// could break the system (such as clearing the TPM or setting passwords), if
+// the tests are run against a real TPM.
+func SkipOnRealTPM(tb testing.TB) {
+	tb.Helper()
+	if useRealTPM() {
+		tb.Skip("Test modifies the TPM state, skipping on a real TPM")
+	}
+}
+
 // GetTPM is a cross-platform testing helper function that retrives the
 // appropriate TPM device from the flags passed into "go test".
 //
@@ -81,7 +91,25 @@ func GetTPM(tb testing.TB) io.ReadWriteCloser {
 		}
 		return noClose{tpm}
 	}
+	return getSimulatedTPM(tb, Rhel8EventLog)
+}
+
+// GetTPMWithEventLog is like GetTPM, but the test TPM's PCRs are initialized by
+// extending the events of the supplied TCG event log (such as the logs in this
+// package) instead of the default test event log, and eventLog is returned by
+// client.GetEventLog. This allows testing verification code against the boot
+// measurements of a real machine. As the PCRs of a real TPM cannot be set, the
+// test is skipped if it is run against a real TPM.
+func GetTPMWithEventLog(tb testing.TB, eventLog []byte) io.ReadWriteCloser {
+	tb.Helper()
+	if useRealTPM() {
+		tb.Skip("Test needs the simulator to replay an event log, skipping on a real TPM")
+	}
+	return getSimulatedTPM(tb, eventLog)
+}
 
+func getSimulatedTPM(tb testing.TB, eventLog []byte) io.ReadWriteCloser {
+	tb.Helper()
 	simulator, err := simulator.Get()
 	if err != nil {
 		tb.Fatalf("Simulator initialization failed: %v", err)
@@ -95,17 +123,41 @@ func GetTPM(tb testing.TB) io.ReadWriteCloser {
 			}
 		}
 	})
-	eventLog := Rhel8EventLog
 
 	// Extend event log events on simulator TPM.
-	simulateEventLogEvents(tb, simulator, eventLog)
+	ExtendEventLog(tb, simulator, eventLog)
 	return simulatedTpm{simulator, eventLog}
 }
 
-// simulateEventLogEvents simulates the events in the test event log
-// "server/test/ubuntu-2104-event-log" by parsing the log
-// and manually extending the TPM.
-func simulateEventLogEvents(tb testing.TB, rw io.ReadWriter, eventLog []byte) {
+// GetSimulator returns a new simulator, powered on and started, which is closed
+// when the test finishes. Unlike GetTPM, it always uses the simulator (even if
+// tests are run against a real TPM) and does not extend any events into it, so
+// it can be used by packages outside this module to test their TPM code. As
+// only one simulator can run at a time, tests using it must not run in
+// parallel.
+func GetSimulator(tb testing.TB) *simulator.Simulator {
+	tb.Helper()
+	simulator, err := simulator.Get()
+	if err != nil {
+		tb.Fatalf("Simulator initialization failed: %v", err)
+	}
+	tb.Cleanup(func() {
+		if !simulator.IsClosed() {
+			if err := simulator.Close(); err != nil {
+				tb.Errorf("when closing simulator: %v", err)
+			}
+		}
+	})
+	return simulator
+}
+
+// ExtendEventLog extends the SHA-1 and SHA-256 digests of the events in the
+// TCG event log into the PCRs of rw (for example, a simulator returned by
+// GetSimulator). If the PCRs were reset, they then have the values which the
+// event log replays to. Event logs with a StartupLocality event cannot be
+// replayed, as the simulator always starts at locality 0.
+func ExtendEventLog(tb testing.TB, rw io.ReadWriter, eventLog []byte) {
+	tb.Helper()
 	attestEventLog, err := attest.ParseEventLog(eventLog)
 	if err != nil {
 		tb.Fatalf("Failed to parse test event log: %v", err)
@@ -119,6 +171,10 @@ func simulateEventLogEvents(tb testing.TB, rw io.ReadWriter, eventLog []byte) {
 	}
 
 	for tpm2Alg, attestAlg := range hashAlgs {
+		if !hasAlg(attestEventLog, attestAlg) {
+			// For example, legacy event logs only have SHA-1 digests.
+			continue
+		}
 		events := attestEventLog.Events(attestAlg)
 		for _, event := range events {
 			extendOnePcr(tb, rw, event.Index, tpm2Alg, event.Digest)
@@ -126,6 +182,15 @@ func simulateEventLogEvents(tb testing.TB, rw io.ReadWriter, eventLog []byte) {
 	}
 }
 
+func hasAlg(eventLog *attest.EventLog, alg attest.HashAlg) bool {
+	for _, logAlg := range eventLog.Algs {
+		if logAlg == alg {
+			return true
+		}
+	}
+	return false
+}
+
 func extendOnePcr(tb testing.TB, rw io.ReadWriter, pcr int, hashAlg tpm2.Algorithm, hash []byte) {
 	err := tpm2.PCRExtend(rw, tpmutil.Handle(pcr), hashAlg, hash, "")
 	if err != nil {
@@ -0,0 +1,105 @@
+package pkcs11uri
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/google/go-tpm/tpmutil"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+	"github.com/ThalesIgnite/go-tpm-tools/sshagent"
+)
+
+// TokenLabel is the label of the token holding the TPM keys (the value of the
+// "token" attribute).
+const TokenLabel = "gotpm"
+
+// PersistentKeyURI returns the URI of the private key persisted at handle.
+func PersistentKeyURI(handle tpmutil.Handle) *URI {
+	id := make([]byte, 4)
+	binary.BigEndian.PutUint32(id, uint32(handle))
+	return &URI{
+		Path:  map[string]string{"token": TokenLabel, "id": string(id), "type": "private"},
+		Query: map[string]string{},
+	}
+}
+
+// StoredKeyURI returns the URI of the private key stored with the given name.
+func StoredKeyURI(name string) *URI {
+	return &URI{
+		Path:  map[string]string{"token": TokenLabel, "object": name, "type": "private"},
+		Query: map[string]string{},
+	}
+}
+
+// Resolver loads the TPM keys referred to by PKCS #11 URIs.
+type Resolver struct {
+	rw     io.ReadWriter
+	keyDir string
+}
+
+// NewResolver returns a Resolver using the TPM rw, and the key blobs stored in
+// keyDir (which can be empty if only persistent keys are used). Key blobs are
+// stored as in sshagent.Agent: as name+sshagent.KeyExtension, created under
+// the ECC SRK.
+func NewResolver(rw io.ReadWriter, keyDir string) *Resolver {
+	return &Resolver{rw: rw, keyDir: keyDir}
+}
+
+// Resolve loads the key referred to by uri, which should be closed when no
+// longer needed. The "token" attribute (if present) must be TokenLabel, and
+// the "type" attribute (if present) must be "private" or "public". The key is
+// selected by the "id" attribute if present, otherwise by the "object"
+// attribute. For stored keys, the "pin-value" query attribute is used as the
+// key's authorization value.
+func (r *Resolver) Resolve(uri *URI) (*client.Key, error) {
+	if token, ok := uri.Path["token"]; ok && token != TokenLabel {
+		return nil, fmt.Errorf("unknown token %q", token)
+	}
+	switch uri.Path["type"] {
+	case "", "private", "public":
+	default:
+		return nil, fmt.Errorf("unsupported object type %q", uri.Path["type"])
+	}
+
+	if id, ok := uri.Path["id"]; ok {
+		if _, ok := uri.Query["pin-value"]; ok {
+			return nil, fmt.Errorf("pin-value is not supported for persistent keys")
+		}
+		if len(id) != 4 {
+			return nil, fmt.Errorf("invalid id: got %d bytes, want 4", len(id))
+		}
+		return client.KeyFromPersistentHandle(r.rw, tpmutil.Handle(binary.BigEndian.Uint32([]byte(id))))
+	}
+	name, ok := uri.Path["object"]
+	if !ok {
+		return nil, fmt.Errorf("URI must have an id or object attribute")
+	}
+	if r.keyDir == "" {
+		return nil, fmt.Errorf("no key directory for object %q", name)
+	}
+	if filepath.Base(name) != name {
+		return nil, fmt.Errorf("invalid object %q", name)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(r.keyDir, name+sshagent.KeyExtension))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %q: %w", name, err)
+	}
+	var blob pb.KeyBlob
+	if err = proto.Unmarshal(data, &blob); err != nil {
+		return nil, fmt.Errorf("failed to decode key %q: %w", name, err)
+	}
+
+	srk, err := client.StorageRootKeyECC(r.rw)
+	if err != nil {
+		return nil, err
+	}
+	// The loaded key does not need its parent to stay loaded.
+	defer srk.Close()
+	return srk.LoadChild(&blob, uri.Query["pin-value"])
+}
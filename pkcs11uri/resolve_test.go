@@ -0,0 +1,89 @@
+package pkcs11uri
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	"github.com/ThalesIgnite/go-tpm-tools/sshagent"
+)
+
+func TestResolvePersistentKey(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	handle := tpmutil.Handle(0x81008F20)
+	ak, err := client.NewKey(rwc, tpm2.HandleOwner, client.AKTemplateECC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ak.Close()
+	if err = ak.Persist(handle); err != nil {
+		t.Fatal(err)
+	}
+	defer client.EvictPersistent(rwc, handle)
+
+	resolver := NewResolver(rwc, "")
+	key, err := resolver.Resolve(PersistentKeyURI(handle))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer key.Close()
+	if !reflect.DeepEqual(key.PublicKey(), ak.PublicKey()) {
+		t.Error("resolved key does not match the persisted key")
+	}
+
+	if _, err = resolver.Resolve(PersistentKeyURI(handle + 1)); err == nil {
+		t.Error("resolving an empty handle should fail")
+	}
+	uri := PersistentKeyURI(handle)
+	uri.Path["token"] = "other"
+	if _, err = resolver.Resolve(uri); err == nil {
+		t.Error("resolving a key of another token should fail")
+	}
+	if _, err = resolver.Resolve(StoredKeyURI("key")); err == nil {
+		t.Error("resolving a stored key without a key directory should fail")
+	}
+}
+
+func TestResolveStoredKey(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	dir, err := ioutil.TempDir("", "pkcs11uri")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	agent, err := sshagent.New(rwc, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer agent.Close()
+	if _, err = agent.GenerateKey("laptop", tpm2.AlgECC); err != nil {
+		t.Fatal(err)
+	}
+
+	uri, err := Parse("pkcs11:token=gotpm;object=laptop;type=private")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := NewResolver(rwc, dir).Resolve(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer key.Close()
+	if _, err = key.SignData([]byte("data")); err != nil {
+		t.Errorf("failed to sign with resolved key: %v", err)
+	}
+
+	if _, err = NewResolver(rwc, dir).Resolve(StoredKeyURI("missing")); err == nil {
+		t.Error("resolving a missing key should fail")
+	}
+}
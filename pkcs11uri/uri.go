@@ -0,0 +1,124 @@
+// Package pkcs11uri resolves PKCS #11 URIs (RFC 7512) to TPM keys, so that
+// software configured with PKCS #11 URIs (as used by p11-kit, OpenSSL engines
+// and providers, and GnuTLS) can refer to keys managed by this module.
+//
+// Keys are exposed in a single token, labeled TokenLabel. Two kinds of keys
+// can be referred to:
+//   - Persistent keys (see client.Key.Persist), by the big-endian encoding of
+//     their handle in the "id" attribute, for example
+//     "pkcs11:token=gotpm;id=%81%00%00%01;type=private".
+//   - Keys stored as key blobs (see client.Key.CreateChild) in a directory, as
+//     used by sshagent.Agent, by their name in the "object" attribute, for
+//     example "pkcs11:token=gotpm;object=laptop;type=private".
+//
+// This package does not implement a PKCS #11 module, so it cannot be loaded by
+// software which only uses the PKCS #11 C API.
+package pkcs11uri
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const scheme = "pkcs11:"
+
+// URI is a parsed PKCS #11 URI. The values of the path attributes (such as
+// "token" and "object") and query attributes (such as "pin-value") are stored
+// decoded.
+type URI struct {
+	Path  map[string]string
+	Query map[string]string
+}
+
+// Parse parses a PKCS #11 URI, such as "pkcs11:token=gotpm;object=key".
+func Parse(uri string) (*URI, error) {
+	if !strings.HasPrefix(uri, scheme) {
+		return nil, fmt.Errorf("not a PKCS #11 URI: %q", uri)
+	}
+	rest := strings.TrimPrefix(uri, scheme)
+	path, query := rest, ""
+	if i := strings.IndexByte(rest, '?'); i >= 0 {
+		path, query = rest[:i], rest[i+1:]
+	}
+
+	u := &URI{Path: map[string]string{}, Query: map[string]string{}}
+	if err := parseAttributes(path, ";", u.Path); err != nil {
+		return nil, err
+	}
+	if err := parseAttributes(query, "&", u.Query); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func parseAttributes(s string, sep string, attrs map[string]string) error {
+	if s == "" {
+		return nil
+	}
+	for _, attr := range strings.Split(s, sep) {
+		i := strings.IndexByte(attr, '=')
+		if i <= 0 {
+			return fmt.Errorf("invalid PKCS #11 URI attribute %q", attr)
+		}
+		name := attr[:i]
+		if _, ok := attrs[name]; ok {
+			return fmt.Errorf("duplicate PKCS #11 URI attribute %q", name)
+		}
+		value, err := url.PathUnescape(attr[i+1:])
+		if err != nil {
+			return fmt.Errorf("invalid value of PKCS #11 URI attribute %q: %w", name, err)
+		}
+		attrs[name] = value
+	}
+	return nil
+}
+
+// String returns the URI, with the attributes in lexical order. Values are
+// percent-encoded, except for unreserved characters. The "id" attribute is
+// always fully percent-encoded, as it usually contains binary data.
+func (u *URI) String() string {
+	var b strings.Builder
+	b.WriteString(scheme)
+	writeAttributes(&b, u.Path, ";")
+	if len(u.Query) > 0 {
+		b.WriteByte('?')
+		writeAttributes(&b, u.Query, "&")
+	}
+	return b.String()
+}
+
+func writeAttributes(b *strings.Builder, attrs map[string]string, sep string) {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(escape(attrs[name], name == "id"))
+	}
+}
+
+func escape(value string, all bool) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if !all && isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
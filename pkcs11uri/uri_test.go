@@ -0,0 +1,72 @@
+package pkcs11uri
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		uri   string
+		path  map[string]string
+		query map[string]string
+	}{
+		{"pkcs11:", map[string]string{}, map[string]string{}},
+		{
+			"pkcs11:token=gotpm;object=my%20key;type=private",
+			map[string]string{"token": "gotpm", "object": "my key", "type": "private"},
+			map[string]string{},
+		},
+		{
+			"pkcs11:id=%81%00%00%01?pin-value=1234&module-name=gotpm",
+			map[string]string{"id": "\x81\x00\x00\x01"},
+			map[string]string{"pin-value": "1234", "module-name": "gotpm"},
+		},
+	}
+	for _, test := range tests {
+		u, err := Parse(test.uri)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", test.uri, err)
+			continue
+		}
+		if !reflect.DeepEqual(u.Path, test.path) || !reflect.DeepEqual(u.Query, test.query) {
+			t.Errorf("Parse(%q) = %v, %v, want %v, %v", test.uri, u.Path, u.Query, test.path, test.query)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, uri := range []string{
+		"file:key",
+		"pkcs11:token",
+		"pkcs11:=value",
+		"pkcs11:object=a;object=b",
+		"pkcs11:object=%zz",
+	} {
+		if _, err := Parse(uri); err == nil {
+			t.Errorf("Parse(%q) should fail", uri)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	u := PersistentKeyURI(0x81000001)
+	want := "pkcs11:id=%81%00%00%01;token=gotpm;type=private"
+	if got := u.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	u = StoredKeyURI("my key@host")
+	u.Query["pin-value"] = "a&b"
+	want = "pkcs11:object=my%20key%40host;token=gotpm;type=private?pin-value=a%26b"
+	if got := u.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	parsed, err := Parse(u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, u) {
+		t.Errorf("parsed URI %v does not match %v", parsed, u)
+	}
+}
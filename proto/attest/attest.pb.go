@@ -166,6 +166,27 @@ type Attestation struct {
 	EventLog []byte `protobuf:"bytes,3,opt,name=event_log,json=eventLog,proto3" json:"event_log,omitempty"`
 	// Optional information about a GCE instance, unused outside of GCE
 	InstanceInfo *GCEInstanceInfo `protobuf:"bytes,4,opt,name=instance_info,json=instanceInfo,proto3" json:"instance_info,omitempty"`
+	// Optional Attestation Key (AK) certificate, encoded as ASN.1 DER
+	AkCert []byte `protobuf:"bytes,5,opt,name=ak_cert,json=akCert,proto3" json:"ak_cert,omitempty"`
+	// Optional intermediate certificates (encoded as ASN.1 DER) chaining the
+	// AK certificate to a root of trust, ordered from the AK certificate's
+	// issuer towards (but not including) the root
+	IntermediateCerts [][]byte `protobuf:"bytes,6,rep,name=intermediate_certs,json=intermediateCerts,proto3" json:"intermediate_certs,omitempty"`
+	// Optional IMA runtime measurement list, encoded in the binary format
+	ImaLog []byte `protobuf:"bytes,7,opt,name=ima_log,json=imaLog,proto3" json:"ima_log,omitempty"`
+	// Optional Endorsement Key (EK) certificate, encoded as ASN.1 DER. Note that
+	// this certificate is not bound to the AK by the Attestation itself.
+	EkCert []byte `protobuf:"bytes,8,opt,name=ek_cert,json=ekCert,proto3" json:"ek_cert,omitempty"`
+	// Optional Canonical Event Log (in the TLV encoding) of application-level
+	// measurements, such as the launch of a container
+	CanonicalEventLog []byte `protobuf:"bytes,9,opt,name=canonical_event_log,json=canonicalEventLog,proto3" json:"canonical_event_log,omitempty"`
+	// Optional DRTM event log (such as the Intel TXT event log), encoded in the
+	// raw binary format, if the machine was started with a dynamic launch
+	DrtmEventLog []byte `protobuf:"bytes,10,opt,name=drtm_event_log,json=drtmEventLog,proto3" json:"drtm_event_log,omitempty"`
+	// Optional AMD SEV-SNP attestation report, bound to the nonce and the AK
+	SevSnpAttestation *SevSnpAttestation `protobuf:"bytes,11,opt,name=sev_snp_attestation,json=sevSnpAttestation,proto3" json:"sev_snp_attestation,omitempty"`
+	// Optional Intel TDX quote (version 4), bound to the nonce and the AK
+	TdxQuote []byte `protobuf:"bytes,12,opt,name=tdx_quote,json=tdxQuote,proto3" json:"tdx_quote,omitempty"`
 }
 
 func (x *Attestation) Reset() {
@@ -228,6 +249,132 @@ func (x *Attestation) GetInstanceInfo() *GCEInstanceInfo {
 	return nil
 }
 
+func (x *Attestation) GetAkCert() []byte {
+	if x != nil {
+		return x.AkCert
+	}
+	return nil
+}
+
+func (x *Attestation) GetIntermediateCerts() [][]byte {
+	if x != nil {
+		return x.IntermediateCerts
+	}
+	return nil
+}
+
+func (x *Attestation) GetImaLog() []byte {
+	if x != nil {
+		return x.ImaLog
+	}
+	return nil
+}
+
+func (x *Attestation) GetEkCert() []byte {
+	if x != nil {
+		return x.EkCert
+	}
+	return nil
+}
+
+func (x *Attestation) GetCanonicalEventLog() []byte {
+	if x != nil {
+		return x.CanonicalEventLog
+	}
+	return nil
+}
+
+func (x *Attestation) GetDrtmEventLog() []byte {
+	if x != nil {
+		return x.DrtmEventLog
+	}
+	return nil
+}
+
+func (x *Attestation) GetSevSnpAttestation() *SevSnpAttestation {
+	if x != nil {
+		return x.SevSnpAttestation
+	}
+	return nil
+}
+
+func (x *Attestation) GetTdxQuote() []byte {
+	if x != nil {
+		return x.TdxQuote
+	}
+	return nil
+}
+
+// An AMD SEV-SNP attestation report, with the certificates of the key which
+// signed it
+type SevSnpAttestation struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The ATTESTATION_REPORT structure, as returned by the AMD Secure Processor
+	Report []byte `protobuf:"bytes,1,opt,name=report,proto3" json:"report,omitempty"`
+	// The VCEK certificate (ASN.1 DER) which signed the report, if provided by
+	// the host
+	VcekCert []byte `protobuf:"bytes,2,opt,name=vcek_cert,json=vcekCert,proto3" json:"vcek_cert,omitempty"`
+	// The ASK certificate (ASN.1 DER) which signed the VCEK certificate, if
+	// provided by the host
+	AskCert []byte `protobuf:"bytes,3,opt,name=ask_cert,json=askCert,proto3" json:"ask_cert,omitempty"`
+}
+
+func (x *SevSnpAttestation) Reset() {
+	*x = SevSnpAttestation{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_attest_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SevSnpAttestation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SevSnpAttestation) ProtoMessage() {}
+
+func (x *SevSnpAttestation) ProtoReflect() protoreflect.Message {
+	mi := &file_attest_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SevSnpAttestation.ProtoReflect.Descriptor instead.
+func (*SevSnpAttestation) Descriptor() ([]byte, []int) {
+	return file_attest_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SevSnpAttestation) GetReport() []byte {
+	if x != nil {
+		return x.Report
+	}
+	return nil
+}
+
+func (x *SevSnpAttestation) GetVcekCert() []byte {
+	if x != nil {
+		return x.VcekCert
+	}
+	return nil
+}
+
+func (x *SevSnpAttestation) GetAskCert() []byte {
+	if x != nil {
+		return x.AskCert
+	}
+	return nil
+}
+
 // The platform/firmware state for this instance
 type PlatformState struct {
 	state         protoimpl.MessageState
@@ -244,23 +391,732 @@ type PlatformState struct {
 	InstanceInfo *GCEInstanceInfo `protobuf:"bytes,4,opt,name=instance_info,json=instanceInfo,proto3" json:"instance_info,omitempty"`
 }
 
-func (x *PlatformState) Reset() {
-	*x = PlatformState{}
+func (x *PlatformState) Reset() {
+	*x = PlatformState{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_attest_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PlatformState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlatformState) ProtoMessage() {}
+
+func (x *PlatformState) ProtoReflect() protoreflect.Message {
+	mi := &file_attest_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlatformState.ProtoReflect.Descriptor instead.
+func (*PlatformState) Descriptor() ([]byte, []int) {
+	return file_attest_proto_rawDescGZIP(), []int{3}
+}
+
+func (m *PlatformState) GetFirmware() isPlatformState_Firmware {
+	if m != nil {
+		return m.Firmware
+	}
+	return nil
+}
+
+func (x *PlatformState) GetScrtmVersionId() []byte {
+	if x, ok := x.GetFirmware().(*PlatformState_ScrtmVersionId); ok {
+		return x.ScrtmVersionId
+	}
+	return nil
+}
+
+func (x *PlatformState) GetGceVersion() uint32 {
+	if x, ok := x.GetFirmware().(*PlatformState_GceVersion); ok {
+		return x.GceVersion
+	}
+	return 0
+}
+
+func (x *PlatformState) GetTechnology() GCEConfidentialTechnology {
+	if x != nil {
+		return x.Technology
+	}
+	return GCEConfidentialTechnology_NONE
+}
+
+func (x *PlatformState) GetInstanceInfo() *GCEInstanceInfo {
+	if x != nil {
+		return x.InstanceInfo
+	}
+	return nil
+}
+
+type isPlatformState_Firmware interface {
+	isPlatformState_Firmware()
+}
+
+type PlatformState_ScrtmVersionId struct {
+	// Raw S-CRTM version identifier (EV_S_CRTM_VERSION)
+	ScrtmVersionId []byte `protobuf:"bytes,1,opt,name=scrtm_version_id,json=scrtmVersionId,proto3,oneof"`
+}
+
+type PlatformState_GceVersion struct {
+	// Virtual GCE firmware version (parsed from S-CRTM version id)
+	GceVersion uint32 `protobuf:"varint,2,opt,name=gce_version,json=gceVersion,proto3,oneof"`
+}
+
+func (*PlatformState_ScrtmVersionId) isPlatformState_Firmware() {}
+
+func (*PlatformState_GceVersion) isPlatformState_Firmware() {}
+
+// A parsed event from the TCG event log
+type Event struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The Platform Control Register (PCR) this event was extended into.
+	PcrIndex uint32 `protobuf:"varint,1,opt,name=pcr_index,json=pcrIndex,proto3" json:"pcr_index,omitempty"`
+	// The type of this event. Note that this value is not verified, so it should
+	// only be used as a hint during event parsing.
+	UntrustedType uint32 `protobuf:"varint,2,opt,name=untrusted_type,json=untrustedType,proto3" json:"untrusted_type,omitempty"`
+	// The raw data associated to this event. The meaning of this data is
+	// specific to the type of the event.
+	Data []byte `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	// The event digest actually extended into the TPM. This is often the hash of
+	// the data field, but in some cases it may have a type-specific calculation.
+	Digest []byte `protobuf:"bytes,4,opt,name=digest,proto3" json:"digest,omitempty"`
+	// This is true if hash(data) == digest.
+	DigestVerified bool `protobuf:"varint,5,opt,name=digest_verified,json=digestVerified,proto3" json:"digest_verified,omitempty"`
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_attest_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_attest_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_attest_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Event) GetPcrIndex() uint32 {
+	if x != nil {
+		return x.PcrIndex
+	}
+	return 0
+}
+
+func (x *Event) GetUntrustedType() uint32 {
+	if x != nil {
+		return x.UntrustedType
+	}
+	return 0
+}
+
+func (x *Event) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Event) GetDigest() []byte {
+	if x != nil {
+		return x.Digest
+	}
+	return nil
+}
+
+func (x *Event) GetDigestVerified() bool {
+	if x != nil {
+		return x.DigestVerified
+	}
+	return false
+}
+
+// A database of certificates and hashes, such as the contents of one or more
+// EFI_SIGNATURE_LISTs.
+type Database struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// DER encoded X.509 certificates
+	Certs [][]byte `protobuf:"bytes,1,rep,name=certs,proto3" json:"certs,omitempty"`
+	// Hashes (i.e. image digests or TBS certificate digests)
+	Hashes [][]byte `protobuf:"bytes,2,rep,name=hashes,proto3" json:"hashes,omitempty"`
+}
+
+func (x *Database) Reset() {
+	*x = Database{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_attest_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Database) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Database) ProtoMessage() {}
+
+func (x *Database) ProtoReflect() protoreflect.Message {
+	mi := &file_attest_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Database.ProtoReflect.Descriptor instead.
+func (*Database) Descriptor() ([]byte, []int) {
+	return file_attest_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Database) GetCerts() [][]byte {
+	if x != nil {
+		return x.Certs
+	}
+	return nil
+}
+
+func (x *Database) GetHashes() [][]byte {
+	if x != nil {
+		return x.Hashes
+	}
+	return nil
+}
+
+// The Secure Boot state of a booted machine, parsed from the PCR7 events
+type SecureBootState struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Whether Secure Boot is enabled (from the SecureBoot variable)
+	Enabled bool `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	// The contents of the db (allowed signatures) variable
+	Db *Database `protobuf:"bytes,2,opt,name=db,proto3" json:"db,omitempty"`
+	// The contents of the dbx (forbidden signatures) variable
+	Dbx *Database `protobuf:"bytes,3,opt,name=dbx,proto3" json:"dbx,omitempty"`
+	// The certificates and hashes used to verify the boot components, from the
+	// EV_EFI_VARIABLE_AUTHORITY events
+	Authority *Database `protobuf:"bytes,4,opt,name=authority,proto3" json:"authority,omitempty"`
+	// The contents of the PK (Platform Key) variable
+	Pk *Database `protobuf:"bytes,5,opt,name=pk,proto3" json:"pk,omitempty"`
+	// The contents of the KEK (Key Exchange Key) variable
+	Kek *Database `protobuf:"bytes,6,opt,name=kek,proto3" json:"kek,omitempty"`
+}
+
+func (x *SecureBootState) Reset() {
+	*x = SecureBootState{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_attest_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SecureBootState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SecureBootState) ProtoMessage() {}
+
+func (x *SecureBootState) ProtoReflect() protoreflect.Message {
+	mi := &file_attest_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SecureBootState.ProtoReflect.Descriptor instead.
+func (*SecureBootState) Descriptor() ([]byte, []int) {
+	return file_attest_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SecureBootState) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *SecureBootState) GetDb() *Database {
+	if x != nil {
+		return x.Db
+	}
+	return nil
+}
+
+func (x *SecureBootState) GetDbx() *Database {
+	if x != nil {
+		return x.Dbx
+	}
+	return nil
+}
+
+func (x *SecureBootState) GetAuthority() *Database {
+	if x != nil {
+		return x.Authority
+	}
+	return nil
+}
+
+func (x *SecureBootState) GetPk() *Database {
+	if x != nil {
+		return x.Pk
+	}
+	return nil
+}
+
+func (x *SecureBootState) GetKek() *Database {
+	if x != nil {
+		return x.Kek
+	}
+	return nil
+}
+
+// A file read and measured (into PCR9) by GRUB
+type GrubFile struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The digest of the file, as extended into PCR9
+	Digest []byte `protobuf:"bytes,1,opt,name=digest,proto3" json:"digest,omitempty"`
+	// The name of the file, from the event data. Only the digest is measured,
+	// so the name is not verified.
+	UntrustedFilename []byte `protobuf:"bytes,2,opt,name=untrusted_filename,json=untrustedFilename,proto3" json:"untrusted_filename,omitempty"`
+}
+
+func (x *GrubFile) Reset() {
+	*x = GrubFile{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_attest_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GrubFile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GrubFile) ProtoMessage() {}
+
+func (x *GrubFile) ProtoReflect() protoreflect.Message {
+	mi := &file_attest_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GrubFile.ProtoReflect.Descriptor instead.
+func (*GrubFile) Descriptor() ([]byte, []int) {
+	return file_attest_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GrubFile) GetDigest() []byte {
+	if x != nil {
+		return x.Digest
+	}
+	return nil
+}
+
+func (x *GrubFile) GetUntrustedFilename() []byte {
+	if x != nil {
+		return x.UntrustedFilename
+	}
+	return nil
+}
+
+// The state of GRUB, parsed from the PCR8 and PCR9 events
+type GrubState struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The files read by GRUB (including its config files, the kernel and the
+	// initrds), in the order they were read
+	Files []*GrubFile `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"`
+	// The GRUB commands executed, and the command lines passed to the kernel and
+	// modules, with their measurement prefix (such as "grub_cmd: ")
+	Commands []string `protobuf:"bytes,2,rep,name=commands,proto3" json:"commands,omitempty"`
+}
+
+func (x *GrubState) Reset() {
+	*x = GrubState{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_attest_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GrubState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GrubState) ProtoMessage() {}
+
+func (x *GrubState) ProtoReflect() protoreflect.Message {
+	mi := &file_attest_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GrubState.ProtoReflect.Descriptor instead.
+func (*GrubState) Descriptor() ([]byte, []int) {
+	return file_attest_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GrubState) GetFiles() []*GrubFile {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+func (x *GrubState) GetCommands() []string {
+	if x != nil {
+		return x.Commands
+	}
+	return nil
+}
+
+// The Linux kernel booted by GRUB
+type LinuxKernelState struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The kernel command line, including the path of the kernel
+	CommandLine string `protobuf:"bytes,1,opt,name=command_line,json=commandLine,proto3" json:"command_line,omitempty"`
+	// The path of the kernel, from the GRUB linux command
+	KernelPath string `protobuf:"bytes,2,opt,name=kernel_path,json=kernelPath,proto3" json:"kernel_path,omitempty"`
+	// The digest of the kernel, as measured by GRUB into PCR9
+	KernelDigest []byte `protobuf:"bytes,3,opt,name=kernel_digest,json=kernelDigest,proto3" json:"kernel_digest,omitempty"`
+	// The digests of the initrds, as measured by GRUB into PCR9
+	InitrdDigests [][]byte `protobuf:"bytes,4,rep,name=initrd_digests,json=initrdDigests,proto3" json:"initrd_digests,omitempty"`
+}
+
+func (x *LinuxKernelState) Reset() {
+	*x = LinuxKernelState{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_attest_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LinuxKernelState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LinuxKernelState) ProtoMessage() {}
+
+func (x *LinuxKernelState) ProtoReflect() protoreflect.Message {
+	mi := &file_attest_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LinuxKernelState.ProtoReflect.Descriptor instead.
+func (*LinuxKernelState) Descriptor() ([]byte, []int) {
+	return file_attest_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *LinuxKernelState) GetCommandLine() string {
+	if x != nil {
+		return x.CommandLine
+	}
+	return ""
+}
+
+func (x *LinuxKernelState) GetKernelPath() string {
+	if x != nil {
+		return x.KernelPath
+	}
+	return ""
+}
+
+func (x *LinuxKernelState) GetKernelDigest() []byte {
+	if x != nil {
+		return x.KernelDigest
+	}
+	return nil
+}
+
+func (x *LinuxKernelState) GetInitrdDigests() [][]byte {
+	if x != nil {
+		return x.InitrdDigests
+	}
+	return nil
+}
+
+// The state of a Dynamic Root of Trust for Measurement (DRTM) launch, such as
+// an Intel TXT launch by tboot, parsed from the DRTM event log
+type DrtmState struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The events of the DRTM event log, replayed against the DRTM PCRs (17-22)
+	RawEvents []*Event `protobuf:"bytes,1,rep,name=raw_events,json=rawEvents,proto3" json:"raw_events,omitempty"`
+}
+
+func (x *DrtmState) Reset() {
+	*x = DrtmState{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_attest_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DrtmState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrtmState) ProtoMessage() {}
+
+func (x *DrtmState) ProtoReflect() protoreflect.Message {
+	mi := &file_attest_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrtmState.ProtoReflect.Descriptor instead.
+func (*DrtmState) Descriptor() ([]byte, []int) {
+	return file_attest_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DrtmState) GetRawEvents() []*Event {
+	if x != nil {
+		return x.RawEvents
+	}
+	return nil
+}
+
+// The state of an AMD SEV-SNP guest, from its verified attestation report
+type SevSnpState struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The launch measurement of the guest
+	Measurement []byte `protobuf:"bytes,1,opt,name=measurement,proto3" json:"measurement,omitempty"`
+	// The guest policy, set by the guest owner at launch
+	Policy uint64 `protobuf:"varint,2,opt,name=policy,proto3" json:"policy,omitempty"`
+	// The VMPL (Virtual Machine Privilege Level) which requested the report
+	Vmpl uint32 `protobuf:"varint,3,opt,name=vmpl,proto3" json:"vmpl,omitempty"`
+	// The data provided by the host at launch
+	HostData []byte `protobuf:"bytes,4,opt,name=host_data,json=hostData,proto3" json:"host_data,omitempty"`
+	// The TCB version used to derive the VCEK which signed the report
+	ReportedTcb uint64 `protobuf:"varint,5,opt,name=reported_tcb,json=reportedTcb,proto3" json:"reported_tcb,omitempty"`
+	// The unique identifier of the chip
+	ChipId []byte `protobuf:"bytes,6,opt,name=chip_id,json=chipId,proto3" json:"chip_id,omitempty"`
+	// The digest of the ID key which signed the ID block, if any
+	IdKeyDigest []byte `protobuf:"bytes,7,opt,name=id_key_digest,json=idKeyDigest,proto3" json:"id_key_digest,omitempty"`
+	// The Security Version Number of the guest
+	GuestSvn uint32 `protobuf:"varint,8,opt,name=guest_svn,json=guestSvn,proto3" json:"guest_svn,omitempty"`
+}
+
+func (x *SevSnpState) Reset() {
+	*x = SevSnpState{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_attest_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SevSnpState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SevSnpState) ProtoMessage() {}
+
+func (x *SevSnpState) ProtoReflect() protoreflect.Message {
+	mi := &file_attest_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SevSnpState.ProtoReflect.Descriptor instead.
+func (*SevSnpState) Descriptor() ([]byte, []int) {
+	return file_attest_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SevSnpState) GetMeasurement() []byte {
+	if x != nil {
+		return x.Measurement
+	}
+	return nil
+}
+
+func (x *SevSnpState) GetPolicy() uint64 {
+	if x != nil {
+		return x.Policy
+	}
+	return 0
+}
+
+func (x *SevSnpState) GetVmpl() uint32 {
+	if x != nil {
+		return x.Vmpl
+	}
+	return 0
+}
+
+func (x *SevSnpState) GetHostData() []byte {
+	if x != nil {
+		return x.HostData
+	}
+	return nil
+}
+
+func (x *SevSnpState) GetReportedTcb() uint64 {
+	if x != nil {
+		return x.ReportedTcb
+	}
+	return 0
+}
+
+func (x *SevSnpState) GetChipId() []byte {
+	if x != nil {
+		return x.ChipId
+	}
+	return nil
+}
+
+func (x *SevSnpState) GetIdKeyDigest() []byte {
+	if x != nil {
+		return x.IdKeyDigest
+	}
+	return nil
+}
+
+func (x *SevSnpState) GetGuestSvn() uint32 {
+	if x != nil {
+		return x.GuestSvn
+	}
+	return 0
+}
+
+// The state of an Intel TDX Trust Domain (TD), from its verified quote
+type TdxState struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The measurement of the initial contents of the TD
+	MrTd []byte `protobuf:"bytes,1,opt,name=mr_td,json=mrTd,proto3" json:"mr_td,omitempty"`
+	// The software-defined IDs of the TD, set by the host at launch
+	MrConfigId    []byte `protobuf:"bytes,2,opt,name=mr_config_id,json=mrConfigId,proto3" json:"mr_config_id,omitempty"`
+	MrOwner       []byte `protobuf:"bytes,3,opt,name=mr_owner,json=mrOwner,proto3" json:"mr_owner,omitempty"`
+	MrOwnerConfig []byte `protobuf:"bytes,4,opt,name=mr_owner_config,json=mrOwnerConfig,proto3" json:"mr_owner_config,omitempty"`
+	// The 4 runtime-extendable measurement registers (RTMRs)
+	Rtmrs [][]byte `protobuf:"bytes,5,rep,name=rtmrs,proto3" json:"rtmrs,omitempty"`
+	// The attributes of the TD (TDATTRIBUTES_T)
+	TdAttributes uint64 `protobuf:"varint,6,opt,name=td_attributes,json=tdAttributes,proto3" json:"td_attributes,omitempty"`
+	// The CPU extended features enabled in the TD (XFAM)
+	Xfam uint64 `protobuf:"varint,7,opt,name=xfam,proto3" json:"xfam,omitempty"`
+	// The measurement and TCB SVN of the TDX module
+	MrSeam    []byte `protobuf:"bytes,8,opt,name=mr_seam,json=mrSeam,proto3" json:"mr_seam,omitempty"`
+	TeeTcbSvn []byte `protobuf:"bytes,9,opt,name=tee_tcb_svn,json=teeTcbSvn,proto3" json:"tee_tcb_svn,omitempty"`
+	// The TCB status of the platform, from the Intel TCB info (e.g. "UpToDate")
+	TcbStatus string `protobuf:"bytes,10,opt,name=tcb_status,json=tcbStatus,proto3" json:"tcb_status,omitempty"`
+}
+
+func (x *TdxState) Reset() {
+	*x = TdxState{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_attest_proto_msgTypes[2]
+		mi := &file_attest_proto_msgTypes[12]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *PlatformState) String() string {
+func (x *TdxState) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PlatformState) ProtoMessage() {}
+func (*TdxState) ProtoMessage() {}
 
-func (x *PlatformState) ProtoReflect() protoreflect.Message {
-	mi := &file_attest_proto_msgTypes[2]
+func (x *TdxState) ProtoReflect() protoreflect.Message {
+	mi := &file_attest_proto_msgTypes[12]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -271,102 +1127,115 @@ func (x *PlatformState) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PlatformState.ProtoReflect.Descriptor instead.
-func (*PlatformState) Descriptor() ([]byte, []int) {
-	return file_attest_proto_rawDescGZIP(), []int{2}
+// Deprecated: Use TdxState.ProtoReflect.Descriptor instead.
+func (*TdxState) Descriptor() ([]byte, []int) {
+	return file_attest_proto_rawDescGZIP(), []int{12}
 }
 
-func (m *PlatformState) GetFirmware() isPlatformState_Firmware {
-	if m != nil {
-		return m.Firmware
+func (x *TdxState) GetMrTd() []byte {
+	if x != nil {
+		return x.MrTd
 	}
 	return nil
 }
 
-func (x *PlatformState) GetScrtmVersionId() []byte {
-	if x, ok := x.GetFirmware().(*PlatformState_ScrtmVersionId); ok {
-		return x.ScrtmVersionId
+func (x *TdxState) GetMrConfigId() []byte {
+	if x != nil {
+		return x.MrConfigId
 	}
 	return nil
 }
 
-func (x *PlatformState) GetGceVersion() uint32 {
-	if x, ok := x.GetFirmware().(*PlatformState_GceVersion); ok {
-		return x.GceVersion
+func (x *TdxState) GetMrOwner() []byte {
+	if x != nil {
+		return x.MrOwner
 	}
-	return 0
+	return nil
 }
 
-func (x *PlatformState) GetTechnology() GCEConfidentialTechnology {
+func (x *TdxState) GetMrOwnerConfig() []byte {
 	if x != nil {
-		return x.Technology
+		return x.MrOwnerConfig
 	}
-	return GCEConfidentialTechnology_NONE
+	return nil
 }
 
-func (x *PlatformState) GetInstanceInfo() *GCEInstanceInfo {
+func (x *TdxState) GetRtmrs() [][]byte {
 	if x != nil {
-		return x.InstanceInfo
+		return x.Rtmrs
 	}
 	return nil
 }
 
-type isPlatformState_Firmware interface {
-	isPlatformState_Firmware()
+func (x *TdxState) GetTdAttributes() uint64 {
+	if x != nil {
+		return x.TdAttributes
+	}
+	return 0
 }
 
-type PlatformState_ScrtmVersionId struct {
-	// Raw S-CRTM version identifier (EV_S_CRTM_VERSION)
-	ScrtmVersionId []byte `protobuf:"bytes,1,opt,name=scrtm_version_id,json=scrtmVersionId,proto3,oneof"`
+func (x *TdxState) GetXfam() uint64 {
+	if x != nil {
+		return x.Xfam
+	}
+	return 0
 }
 
-type PlatformState_GceVersion struct {
-	// Virtual GCE firmware version (parsed from S-CRTM version id)
-	GceVersion uint32 `protobuf:"varint,2,opt,name=gce_version,json=gceVersion,proto3,oneof"`
+func (x *TdxState) GetMrSeam() []byte {
+	if x != nil {
+		return x.MrSeam
+	}
+	return nil
 }
 
-func (*PlatformState_ScrtmVersionId) isPlatformState_Firmware() {}
+func (x *TdxState) GetTeeTcbSvn() []byte {
+	if x != nil {
+		return x.TeeTcbSvn
+	}
+	return nil
+}
 
-func (*PlatformState_GceVersion) isPlatformState_Firmware() {}
+func (x *TdxState) GetTcbStatus() string {
+	if x != nil {
+		return x.TcbStatus
+	}
+	return ""
+}
 
-// A parsed event from the TCG event log
-type Event struct {
+// The container launched on the machine, parsed from the container launch
+// events of the Canonical Event Log
+type ContainerState struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	// The Platform Control Register (PCR) this event was extended into.
-	PcrIndex uint32 `protobuf:"varint,1,opt,name=pcr_index,json=pcrIndex,proto3" json:"pcr_index,omitempty"`
-	// The type of this event. Note that this value is not verified, so it should
-	// only be used as a hint during event parsing.
-	UntrustedType uint32 `protobuf:"varint,2,opt,name=untrusted_type,json=untrustedType,proto3" json:"untrusted_type,omitempty"`
-	// The raw data associated to this event. The meaning of this data is
-	// specific to the type of the event.
-	Data []byte `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
-	// The event digest actually extended into the TPM. This is often the hash of
-	// the data field, but in some cases it may have a type-specific calculation.
-	Digest []byte `protobuf:"bytes,4,opt,name=digest,proto3" json:"digest,omitempty"`
-	// This is true if hash(data) == digest.
-	DigestVerified bool `protobuf:"varint,5,opt,name=digest_verified,json=digestVerified,proto3" json:"digest_verified,omitempty"`
+	ImageReference string `protobuf:"bytes,1,opt,name=image_reference,json=imageReference,proto3" json:"image_reference,omitempty"`
+	// The digest of the image, such as "sha256:<hex digest>"
+	ImageDigest string   `protobuf:"bytes,2,opt,name=image_digest,json=imageDigest,proto3" json:"image_digest,omitempty"`
+	Entrypoint  []string `protobuf:"bytes,3,rep,name=entrypoint,proto3" json:"entrypoint,omitempty"`
+	Args        []string `protobuf:"bytes,4,rep,name=args,proto3" json:"args,omitempty"`
+	// The hash of the policy deciding which environment variables the container
+	// could be launched with
+	EnvPolicyHash []byte `protobuf:"bytes,5,opt,name=env_policy_hash,json=envPolicyHash,proto3" json:"env_policy_hash,omitempty"`
 }
 
-func (x *Event) Reset() {
-	*x = Event{}
+func (x *ContainerState) Reset() {
+	*x = ContainerState{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_attest_proto_msgTypes[3]
+		mi := &file_attest_proto_msgTypes[13]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *Event) String() string {
+func (x *ContainerState) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Event) ProtoMessage() {}
+func (*ContainerState) ProtoMessage() {}
 
-func (x *Event) ProtoReflect() protoreflect.Message {
-	mi := &file_attest_proto_msgTypes[3]
+func (x *ContainerState) ProtoReflect() protoreflect.Message {
+	mi := &file_attest_proto_msgTypes[13]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -377,44 +1246,44 @@ func (x *Event) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Event.ProtoReflect.Descriptor instead.
-func (*Event) Descriptor() ([]byte, []int) {
-	return file_attest_proto_rawDescGZIP(), []int{3}
+// Deprecated: Use ContainerState.ProtoReflect.Descriptor instead.
+func (*ContainerState) Descriptor() ([]byte, []int) {
+	return file_attest_proto_rawDescGZIP(), []int{13}
 }
 
-func (x *Event) GetPcrIndex() uint32 {
+func (x *ContainerState) GetImageReference() string {
 	if x != nil {
-		return x.PcrIndex
+		return x.ImageReference
 	}
-	return 0
+	return ""
 }
 
-func (x *Event) GetUntrustedType() uint32 {
+func (x *ContainerState) GetImageDigest() string {
 	if x != nil {
-		return x.UntrustedType
+		return x.ImageDigest
 	}
-	return 0
+	return ""
 }
 
-func (x *Event) GetData() []byte {
+func (x *ContainerState) GetEntrypoint() []string {
 	if x != nil {
-		return x.Data
+		return x.Entrypoint
 	}
 	return nil
 }
 
-func (x *Event) GetDigest() []byte {
+func (x *ContainerState) GetArgs() []string {
 	if x != nil {
-		return x.Digest
+		return x.Args
 	}
 	return nil
 }
 
-func (x *Event) GetDigestVerified() bool {
+func (x *ContainerState) GetEnvPolicyHash() []byte {
 	if x != nil {
-		return x.DigestVerified
+		return x.EnvPolicyHash
 	}
-	return false
+	return nil
 }
 
 // The verified state of a booted machine, obtained from an Attestation
@@ -423,20 +1292,32 @@ type MachineState struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Platform *PlatformState `protobuf:"bytes,1,opt,name=platform,proto3" json:"platform,omitempty"`
+	Platform   *PlatformState   `protobuf:"bytes,1,opt,name=platform,proto3" json:"platform,omitempty"`
+	SecureBoot *SecureBootState `protobuf:"bytes,2,opt,name=secure_boot,json=secureBoot,proto3" json:"secure_boot,omitempty"`
 	// The complete parsed TCG Event Log, including those events used to
 	// create the PlatformState.
 	RawEvents []*Event `protobuf:"bytes,3,rep,name=raw_events,json=rawEvents,proto3" json:"raw_events,omitempty"`
 	// The hash algorithm used when verifying the Attestation. This indicates:
 	//   - which PCR bank was used for for quote validation and event log replay
 	//   - the hash algorithm used to calculate event digests
-	Hash tpm.HashAlgo `protobuf:"varint,4,opt,name=hash,proto3,enum=tpm.HashAlgo" json:"hash,omitempty"`
+	Hash        tpm.HashAlgo      `protobuf:"varint,4,opt,name=hash,proto3,enum=tpm.HashAlgo" json:"hash,omitempty"`
+	Grub        *GrubState        `protobuf:"bytes,5,opt,name=grub,proto3" json:"grub,omitempty"`
+	LinuxKernel *LinuxKernelState `protobuf:"bytes,6,opt,name=linux_kernel,json=linuxKernel,proto3" json:"linux_kernel,omitempty"`
+	// Only set if the Attestation contains a Canonical Event Log with container
+	// launch events
+	Container *ContainerState `protobuf:"bytes,7,opt,name=container,proto3" json:"container,omitempty"`
+	// Only set if the Attestation contains a DRTM event log
+	Drtm *DrtmState `protobuf:"bytes,8,opt,name=drtm,proto3" json:"drtm,omitempty"`
+	// Only set if the Attestation contains an SEV-SNP attestation report
+	SevSnp *SevSnpState `protobuf:"bytes,9,opt,name=sev_snp,json=sevSnp,proto3" json:"sev_snp,omitempty"`
+	// Only set if the Attestation contains a TDX quote
+	Tdx *TdxState `protobuf:"bytes,10,opt,name=tdx,proto3" json:"tdx,omitempty"`
 }
 
 func (x *MachineState) Reset() {
 	*x = MachineState{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_attest_proto_msgTypes[4]
+		mi := &file_attest_proto_msgTypes[14]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -449,7 +1330,7 @@ func (x *MachineState) String() string {
 func (*MachineState) ProtoMessage() {}
 
 func (x *MachineState) ProtoReflect() protoreflect.Message {
-	mi := &file_attest_proto_msgTypes[4]
+	mi := &file_attest_proto_msgTypes[14]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -462,7 +1343,7 @@ func (x *MachineState) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MachineState.ProtoReflect.Descriptor instead.
 func (*MachineState) Descriptor() ([]byte, []int) {
-	return file_attest_proto_rawDescGZIP(), []int{4}
+	return file_attest_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *MachineState) GetPlatform() *PlatformState {
@@ -472,6 +1353,13 @@ func (x *MachineState) GetPlatform() *PlatformState {
 	return nil
 }
 
+func (x *MachineState) GetSecureBoot() *SecureBootState {
+	if x != nil {
+		return x.SecureBoot
+	}
+	return nil
+}
+
 func (x *MachineState) GetRawEvents() []*Event {
 	if x != nil {
 		return x.RawEvents
@@ -486,6 +1374,48 @@ func (x *MachineState) GetHash() tpm.HashAlgo {
 	return tpm.HashAlgo(0)
 }
 
+func (x *MachineState) GetGrub() *GrubState {
+	if x != nil {
+		return x.Grub
+	}
+	return nil
+}
+
+func (x *MachineState) GetLinuxKernel() *LinuxKernelState {
+	if x != nil {
+		return x.LinuxKernel
+	}
+	return nil
+}
+
+func (x *MachineState) GetContainer() *ContainerState {
+	if x != nil {
+		return x.Container
+	}
+	return nil
+}
+
+func (x *MachineState) GetDrtm() *DrtmState {
+	if x != nil {
+		return x.Drtm
+	}
+	return nil
+}
+
+func (x *MachineState) GetSevSnp() *SevSnpState {
+	if x != nil {
+		return x.SevSnp
+	}
+	return nil
+}
+
+func (x *MachineState) GetTdx() *TdxState {
+	if x != nil {
+		return x.Tdx
+	}
+	return nil
+}
+
 // A policy dictating which values of PlatformState to allow
 type PlatformPolicy struct {
 	state         protoimpl.MessageState
@@ -507,7 +1437,7 @@ type PlatformPolicy struct {
 func (x *PlatformPolicy) Reset() {
 	*x = PlatformPolicy{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_attest_proto_msgTypes[5]
+		mi := &file_attest_proto_msgTypes[15]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -520,7 +1450,7 @@ func (x *PlatformPolicy) String() string {
 func (*PlatformPolicy) ProtoMessage() {}
 
 func (x *PlatformPolicy) ProtoReflect() protoreflect.Message {
-	mi := &file_attest_proto_msgTypes[5]
+	mi := &file_attest_proto_msgTypes[15]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -533,7 +1463,7 @@ func (x *PlatformPolicy) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PlatformPolicy.ProtoReflect.Descriptor instead.
 func (*PlatformPolicy) Descriptor() ([]byte, []int) {
-	return file_attest_proto_rawDescGZIP(), []int{5}
+	return file_attest_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *PlatformPolicy) GetAllowedScrtmVersionIds() [][]byte {
@@ -569,7 +1499,7 @@ type Policy struct {
 func (x *Policy) Reset() {
 	*x = Policy{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_attest_proto_msgTypes[6]
+		mi := &file_attest_proto_msgTypes[16]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -582,7 +1512,7 @@ func (x *Policy) String() string {
 func (*Policy) ProtoMessage() {}
 
 func (x *Policy) ProtoReflect() protoreflect.Message {
-	mi := &file_attest_proto_msgTypes[6]
+	mi := &file_attest_proto_msgTypes[16]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -595,7 +1525,7 @@ func (x *Policy) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Policy.ProtoReflect.Descriptor instead.
 func (*Policy) Descriptor() ([]byte, []int) {
-	return file_attest_proto_rawDescGZIP(), []int{6}
+	return file_attest_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *Policy) GetPlatform() *PlatformPolicy {
@@ -621,7 +1551,7 @@ var file_attest_proto_rawDesc = []byte{
 	0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65,
 	0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65,
 	0x5f, 0x69, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x69, 0x6e, 0x73, 0x74, 0x61,
-	0x6e, 0x63, 0x65, 0x49, 0x64, 0x22, 0xa3, 0x01, 0x0a, 0x0b, 0x41, 0x74, 0x74, 0x65, 0x73, 0x74,
+	0x6e, 0x63, 0x65, 0x49, 0x64, 0x22, 0xdb, 0x03, 0x0a, 0x0b, 0x41, 0x74, 0x74, 0x65, 0x73, 0x74,
 	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x15, 0x0a, 0x06, 0x61, 0x6b, 0x5f, 0x70, 0x75, 0x62, 0x18,
 	0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x61, 0x6b, 0x50, 0x75, 0x62, 0x12, 0x22, 0x0a, 0x06,
 	0x71, 0x75, 0x6f, 0x74, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0a, 0x2e, 0x74,
@@ -631,67 +1561,202 @@ var file_attest_proto_rawDesc = []byte{
 	0x0d, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x5f, 0x69, 0x6e, 0x66, 0x6f, 0x18, 0x04,
 	0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x47, 0x43,
 	0x45, 0x49, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x0c, 0x69,
-	0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x22, 0xeb, 0x01, 0x0a, 0x0d,
-	0x50, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x2a, 0x0a,
-	0x10, 0x73, 0x63, 0x72, 0x74, 0x6d, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x69,
-	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x48, 0x00, 0x52, 0x0e, 0x73, 0x63, 0x72, 0x74, 0x6d,
-	0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x12, 0x21, 0x0a, 0x0b, 0x67, 0x63, 0x65,
-	0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x48, 0x00,
-	0x52, 0x0a, 0x67, 0x63, 0x65, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x41, 0x0a, 0x0a,
-	0x74, 0x65, 0x63, 0x68, 0x6e, 0x6f, 0x6c, 0x6f, 0x67, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e,
-	0x32, 0x21, 0x2e, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x47, 0x43, 0x45, 0x43, 0x6f, 0x6e,
-	0x66, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x54, 0x65, 0x63, 0x68, 0x6e, 0x6f, 0x6c,
-	0x6f, 0x67, 0x79, 0x52, 0x0a, 0x74, 0x65, 0x63, 0x68, 0x6e, 0x6f, 0x6c, 0x6f, 0x67, 0x79, 0x12,
-	0x3c, 0x0a, 0x0d, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x5f, 0x69, 0x6e, 0x66, 0x6f,
-	0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e,
-	0x47, 0x43, 0x45, 0x49, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52,
-	0x0c, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x42, 0x0a, 0x0a,
-	0x08, 0x66, 0x69, 0x72, 0x6d, 0x77, 0x61, 0x72, 0x65, 0x22, 0xa0, 0x01, 0x0a, 0x05, 0x45, 0x76,
-	0x65, 0x6e, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x70, 0x63, 0x72, 0x5f, 0x69, 0x6e, 0x64, 0x65, 0x78,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08, 0x70, 0x63, 0x72, 0x49, 0x6e, 0x64, 0x65, 0x78,
-	0x12, 0x25, 0x0a, 0x0e, 0x75, 0x6e, 0x74, 0x72, 0x75, 0x73, 0x74, 0x65, 0x64, 0x5f, 0x74, 0x79,
-	0x70, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0d, 0x75, 0x6e, 0x74, 0x72, 0x75, 0x73,
-	0x74, 0x65, 0x64, 0x54, 0x79, 0x70, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18,
-	0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x12, 0x16, 0x0a, 0x06, 0x64,
-	0x69, 0x67, 0x65, 0x73, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x64, 0x69, 0x67,
-	0x65, 0x73, 0x74, 0x12, 0x27, 0x0a, 0x0f, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x5f, 0x76, 0x65,
-	0x72, 0x69, 0x66, 0x69, 0x65, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0e, 0x64, 0x69,
-	0x67, 0x65, 0x73, 0x74, 0x56, 0x65, 0x72, 0x69, 0x66, 0x69, 0x65, 0x64, 0x22, 0x92, 0x01, 0x0a,
-	0x0c, 0x4d, 0x61, 0x63, 0x68, 0x69, 0x6e, 0x65, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x31, 0x0a,
-	0x08, 0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32,
-	0x15, 0x2e, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x50, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72,
-	0x6d, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x08, 0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d,
+	0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x17, 0x0a, 0x07, 0x61,
+	0x6b, 0x5f, 0x63, 0x65, 0x72, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x61, 0x6b,
+	0x43, 0x65, 0x72, 0x74, 0x12, 0x2d, 0x0a, 0x12, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6d, 0x65, 0x64,
+	0x69, 0x61, 0x74, 0x65, 0x5f, 0x63, 0x65, 0x72, 0x74, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0c,
+	0x52, 0x11, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6d, 0x65, 0x64, 0x69, 0x61, 0x74, 0x65, 0x43, 0x65,
+	0x72, 0x74, 0x73, 0x12, 0x17, 0x0a, 0x07, 0x69, 0x6d, 0x61, 0x5f, 0x6c, 0x6f, 0x67, 0x18, 0x07,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x69, 0x6d, 0x61, 0x4c, 0x6f, 0x67, 0x12, 0x17, 0x0a, 0x07,
+	0x65, 0x6b, 0x5f, 0x63, 0x65, 0x72, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x65,
+	0x6b, 0x43, 0x65, 0x72, 0x74, 0x12, 0x2e, 0x0a, 0x13, 0x63, 0x61, 0x6e, 0x6f, 0x6e, 0x69, 0x63,
+	0x61, 0x6c, 0x5f, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x5f, 0x6c, 0x6f, 0x67, 0x18, 0x09, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x11, 0x63, 0x61, 0x6e, 0x6f, 0x6e, 0x69, 0x63, 0x61, 0x6c, 0x45, 0x76, 0x65,
+	0x6e, 0x74, 0x4c, 0x6f, 0x67, 0x12, 0x24, 0x0a, 0x0e, 0x64, 0x72, 0x74, 0x6d, 0x5f, 0x65, 0x76,
+	0x65, 0x6e, 0x74, 0x5f, 0x6c, 0x6f, 0x67, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0c, 0x64,
+	0x72, 0x74, 0x6d, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x4c, 0x6f, 0x67, 0x12, 0x49, 0x0a, 0x13, 0x73,
+	0x65, 0x76, 0x5f, 0x73, 0x6e, 0x70, 0x5f, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x61, 0x74, 0x74, 0x65, 0x73,
+	0x74, 0x2e, 0x53, 0x65, 0x76, 0x53, 0x6e, 0x70, 0x41, 0x74, 0x74, 0x65, 0x73, 0x74, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x52, 0x11, 0x73, 0x65, 0x76, 0x53, 0x6e, 0x70, 0x41, 0x74, 0x74, 0x65, 0x73,
+	0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1b, 0x0a, 0x09, 0x74, 0x64, 0x78, 0x5f, 0x71, 0x75,
+	0x6f, 0x74, 0x65, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x74, 0x64, 0x78, 0x51, 0x75,
+	0x6f, 0x74, 0x65, 0x22, 0x63, 0x0a, 0x11, 0x53, 0x65, 0x76, 0x53, 0x6e, 0x70, 0x41, 0x74, 0x74,
+	0x65, 0x73, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x70, 0x6f,
+	0x72, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74,
+	0x12, 0x1b, 0x0a, 0x09, 0x76, 0x63, 0x65, 0x6b, 0x5f, 0x63, 0x65, 0x72, 0x74, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x08, 0x76, 0x63, 0x65, 0x6b, 0x43, 0x65, 0x72, 0x74, 0x12, 0x19, 0x0a,
+	0x08, 0x61, 0x73, 0x6b, 0x5f, 0x63, 0x65, 0x72, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x07, 0x61, 0x73, 0x6b, 0x43, 0x65, 0x72, 0x74, 0x22, 0xeb, 0x01, 0x0a, 0x0d, 0x50, 0x6c, 0x61,
+	0x74, 0x66, 0x6f, 0x72, 0x6d, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x2a, 0x0a, 0x10, 0x73, 0x63,
+	0x72, 0x74, 0x6d, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0c, 0x48, 0x00, 0x52, 0x0e, 0x73, 0x63, 0x72, 0x74, 0x6d, 0x56, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x12, 0x21, 0x0a, 0x0b, 0x67, 0x63, 0x65, 0x5f, 0x76, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x48, 0x00, 0x52, 0x0a, 0x67,
+	0x63, 0x65, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x41, 0x0a, 0x0a, 0x74, 0x65, 0x63,
+	0x68, 0x6e, 0x6f, 0x6c, 0x6f, 0x67, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x21, 0x2e,
+	0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x47, 0x43, 0x45, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x64,
+	0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x54, 0x65, 0x63, 0x68, 0x6e, 0x6f, 0x6c, 0x6f, 0x67, 0x79,
+	0x52, 0x0a, 0x74, 0x65, 0x63, 0x68, 0x6e, 0x6f, 0x6c, 0x6f, 0x67, 0x79, 0x12, 0x3c, 0x0a, 0x0d,
+	0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x5f, 0x69, 0x6e, 0x66, 0x6f, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x47, 0x43, 0x45,
+	0x49, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x0c, 0x69, 0x6e,
+	0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x42, 0x0a, 0x0a, 0x08, 0x66, 0x69,
+	0x72, 0x6d, 0x77, 0x61, 0x72, 0x65, 0x22, 0xa0, 0x01, 0x0a, 0x05, 0x45, 0x76, 0x65, 0x6e, 0x74,
+	0x12, 0x1b, 0x0a, 0x09, 0x70, 0x63, 0x72, 0x5f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x08, 0x70, 0x63, 0x72, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x25, 0x0a,
+	0x0e, 0x75, 0x6e, 0x74, 0x72, 0x75, 0x73, 0x74, 0x65, 0x64, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0d, 0x75, 0x6e, 0x74, 0x72, 0x75, 0x73, 0x74, 0x65, 0x64,
+	0x54, 0x79, 0x70, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x12, 0x16, 0x0a, 0x06, 0x64, 0x69, 0x67, 0x65,
+	0x73, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74,
+	0x12, 0x27, 0x0a, 0x0f, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x5f, 0x76, 0x65, 0x72, 0x69, 0x66,
+	0x69, 0x65, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0e, 0x64, 0x69, 0x67, 0x65, 0x73,
+	0x74, 0x56, 0x65, 0x72, 0x69, 0x66, 0x69, 0x65, 0x64, 0x22, 0x38, 0x0a, 0x08, 0x44, 0x61, 0x74,
+	0x61, 0x62, 0x61, 0x73, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x65, 0x72, 0x74, 0x73, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x0c, 0x52, 0x05, 0x63, 0x65, 0x72, 0x74, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x68,
+	0x61, 0x73, 0x68, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x06, 0x68, 0x61, 0x73,
+	0x68, 0x65, 0x73, 0x22, 0xe7, 0x01, 0x0a, 0x0f, 0x53, 0x65, 0x63, 0x75, 0x72, 0x65, 0x42, 0x6f,
+	0x6f, 0x74, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x65, 0x6e, 0x61, 0x62, 0x6c,
+	0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x65, 0x6e, 0x61, 0x62, 0x6c, 0x65,
+	0x64, 0x12, 0x20, 0x0a, 0x02, 0x64, 0x62, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e,
+	0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x52,
+	0x02, 0x64, 0x62, 0x12, 0x22, 0x0a, 0x03, 0x64, 0x62, 0x78, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x10, 0x2e, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61,
+	0x73, 0x65, 0x52, 0x03, 0x64, 0x62, 0x78, 0x12, 0x2e, 0x0a, 0x09, 0x61, 0x75, 0x74, 0x68, 0x6f,
+	0x72, 0x69, 0x74, 0x79, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x61, 0x74, 0x74,
+	0x65, 0x73, 0x74, 0x2e, 0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x52, 0x09, 0x61, 0x75,
+	0x74, 0x68, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x12, 0x20, 0x0a, 0x02, 0x70, 0x6b, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x44, 0x61, 0x74,
+	0x61, 0x62, 0x61, 0x73, 0x65, 0x52, 0x02, 0x70, 0x6b, 0x12, 0x22, 0x0a, 0x03, 0x6b, 0x65, 0x6b,
+	0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e,
+	0x44, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x52, 0x03, 0x6b, 0x65, 0x6b, 0x22, 0x51, 0x0a,
+	0x08, 0x47, 0x72, 0x75, 0x62, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x64, 0x69, 0x67,
+	0x65, 0x73, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x64, 0x69, 0x67, 0x65, 0x73,
+	0x74, 0x12, 0x2d, 0x0a, 0x12, 0x75, 0x6e, 0x74, 0x72, 0x75, 0x73, 0x74, 0x65, 0x64, 0x5f, 0x66,
+	0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x11, 0x75,
+	0x6e, 0x74, 0x72, 0x75, 0x73, 0x74, 0x65, 0x64, 0x46, 0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65,
+	0x22, 0x4f, 0x0a, 0x09, 0x47, 0x72, 0x75, 0x62, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x26, 0x0a,
+	0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x61,
+	0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x47, 0x72, 0x75, 0x62, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x05,
+	0x66, 0x69, 0x6c, 0x65, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64,
+	0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64,
+	0x73, 0x22, 0xa2, 0x01, 0x0a, 0x10, 0x4c, 0x69, 0x6e, 0x75, 0x78, 0x4b, 0x65, 0x72, 0x6e, 0x65,
+	0x6c, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e,
+	0x64, 0x5f, 0x6c, 0x69, 0x6e, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x63, 0x6f,
+	0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x4c, 0x69, 0x6e, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x6b, 0x65, 0x72,
+	0x6e, 0x65, 0x6c, 0x5f, 0x70, 0x61, 0x74, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a,
+	0x6b, 0x65, 0x72, 0x6e, 0x65, 0x6c, 0x50, 0x61, 0x74, 0x68, 0x12, 0x23, 0x0a, 0x0d, 0x6b, 0x65,
+	0x72, 0x6e, 0x65, 0x6c, 0x5f, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x0c, 0x6b, 0x65, 0x72, 0x6e, 0x65, 0x6c, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x12,
+	0x25, 0x0a, 0x0e, 0x69, 0x6e, 0x69, 0x74, 0x72, 0x64, 0x5f, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74,
+	0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x0d, 0x69, 0x6e, 0x69, 0x74, 0x72, 0x64, 0x44,
+	0x69, 0x67, 0x65, 0x73, 0x74, 0x73, 0x22, 0x39, 0x0a, 0x09, 0x44, 0x72, 0x74, 0x6d, 0x53, 0x74,
+	0x61, 0x74, 0x65, 0x12, 0x2c, 0x0a, 0x0a, 0x72, 0x61, 0x77, 0x5f, 0x65, 0x76, 0x65, 0x6e, 0x74,
+	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74,
+	0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x09, 0x72, 0x61, 0x77, 0x45, 0x76, 0x65, 0x6e, 0x74,
+	0x73, 0x22, 0xf5, 0x01, 0x0a, 0x0b, 0x53, 0x65, 0x76, 0x53, 0x6e, 0x70, 0x53, 0x74, 0x61, 0x74,
+	0x65, 0x12, 0x20, 0x0a, 0x0b, 0x6d, 0x65, 0x61, 0x73, 0x75, 0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b, 0x6d, 0x65, 0x61, 0x73, 0x75, 0x72, 0x65, 0x6d,
+	0x65, 0x6e, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x06, 0x70, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x76,
+	0x6d, 0x70, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x76, 0x6d, 0x70, 0x6c, 0x12,
+	0x1b, 0x0a, 0x09, 0x68, 0x6f, 0x73, 0x74, 0x5f, 0x64, 0x61, 0x74, 0x61, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x08, 0x68, 0x6f, 0x73, 0x74, 0x44, 0x61, 0x74, 0x61, 0x12, 0x21, 0x0a, 0x0c,
+	0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x64, 0x5f, 0x74, 0x63, 0x62, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x0b, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x64, 0x54, 0x63, 0x62, 0x12,
+	0x17, 0x0a, 0x07, 0x63, 0x68, 0x69, 0x70, 0x5f, 0x69, 0x64, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x06, 0x63, 0x68, 0x69, 0x70, 0x49, 0x64, 0x12, 0x22, 0x0a, 0x0d, 0x69, 0x64, 0x5f, 0x6b,
+	0x65, 0x79, 0x5f, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x0b, 0x69, 0x64, 0x4b, 0x65, 0x79, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x12, 0x1b, 0x0a, 0x09,
+	0x67, 0x75, 0x65, 0x73, 0x74, 0x5f, 0x73, 0x76, 0x6e, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x08, 0x67, 0x75, 0x65, 0x73, 0x74, 0x53, 0x76, 0x6e, 0x22, 0xab, 0x02, 0x0a, 0x08, 0x54, 0x64,
+	0x78, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x13, 0x0a, 0x05, 0x6d, 0x72, 0x5f, 0x74, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x6d, 0x72, 0x54, 0x64, 0x12, 0x20, 0x0a, 0x0c, 0x6d,
+	0x72, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x0a, 0x6d, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x49, 0x64, 0x12, 0x19, 0x0a,
+	0x08, 0x6d, 0x72, 0x5f, 0x6f, 0x77, 0x6e, 0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x07, 0x6d, 0x72, 0x4f, 0x77, 0x6e, 0x65, 0x72, 0x12, 0x26, 0x0a, 0x0f, 0x6d, 0x72, 0x5f, 0x6f,
+	0x77, 0x6e, 0x65, 0x72, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x0d, 0x6d, 0x72, 0x4f, 0x77, 0x6e, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x12, 0x14, 0x0a, 0x05, 0x72, 0x74, 0x6d, 0x72, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0c, 0x52,
+	0x05, 0x72, 0x74, 0x6d, 0x72, 0x73, 0x12, 0x23, 0x0a, 0x0d, 0x74, 0x64, 0x5f, 0x61, 0x74, 0x74,
+	0x72, 0x69, 0x62, 0x75, 0x74, 0x65, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0c, 0x74,
+	0x64, 0x41, 0x74, 0x74, 0x72, 0x69, 0x62, 0x75, 0x74, 0x65, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x78,
+	0x66, 0x61, 0x6d, 0x18, 0x07, 0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x78, 0x66, 0x61, 0x6d, 0x12,
+	0x17, 0x0a, 0x07, 0x6d, 0x72, 0x5f, 0x73, 0x65, 0x61, 0x6d, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x06, 0x6d, 0x72, 0x53, 0x65, 0x61, 0x6d, 0x12, 0x1e, 0x0a, 0x0b, 0x74, 0x65, 0x65, 0x5f,
+	0x74, 0x63, 0x62, 0x5f, 0x73, 0x76, 0x6e, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x74,
+	0x65, 0x65, 0x54, 0x63, 0x62, 0x53, 0x76, 0x6e, 0x12, 0x1d, 0x0a, 0x0a, 0x74, 0x63, 0x62, 0x5f,
+	0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x74, 0x63,
+	0x62, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x22, 0xb8, 0x01, 0x0a, 0x0e, 0x43, 0x6f, 0x6e, 0x74,
+	0x61, 0x69, 0x6e, 0x65, 0x72, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x27, 0x0a, 0x0f, 0x69, 0x6d,
+	0x61, 0x67, 0x65, 0x5f, 0x72, 0x65, 0x66, 0x65, 0x72, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0e, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x52, 0x65, 0x66, 0x65, 0x72, 0x65,
+	0x6e, 0x63, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x5f, 0x64, 0x69, 0x67,
+	0x65, 0x73, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x69, 0x6d, 0x61, 0x67, 0x65,
+	0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x65, 0x6e, 0x74, 0x72, 0x79, 0x70,
+	0x6f, 0x69, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0a, 0x65, 0x6e, 0x74, 0x72,
+	0x79, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x61, 0x72, 0x67, 0x73, 0x18, 0x04,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x61, 0x72, 0x67, 0x73, 0x12, 0x26, 0x0a, 0x0f, 0x65, 0x6e,
+	0x76, 0x5f, 0x70, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x0d, 0x65, 0x6e, 0x76, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x48, 0x61,
+	0x73, 0x68, 0x22, 0xdf, 0x03, 0x0a, 0x0c, 0x4d, 0x61, 0x63, 0x68, 0x69, 0x6e, 0x65, 0x53, 0x74,
+	0x61, 0x74, 0x65, 0x12, 0x31, 0x0a, 0x08, 0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x50,
+	0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x08, 0x70, 0x6c,
+	0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x12, 0x38, 0x0a, 0x0b, 0x73, 0x65, 0x63, 0x75, 0x72, 0x65,
+	0x5f, 0x62, 0x6f, 0x6f, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x61, 0x74,
+	0x74, 0x65, 0x73, 0x74, 0x2e, 0x53, 0x65, 0x63, 0x75, 0x72, 0x65, 0x42, 0x6f, 0x6f, 0x74, 0x53,
+	0x74, 0x61, 0x74, 0x65, 0x52, 0x0a, 0x73, 0x65, 0x63, 0x75, 0x72, 0x65, 0x42, 0x6f, 0x6f, 0x74,
 	0x12, 0x2c, 0x0a, 0x0a, 0x72, 0x61, 0x77, 0x5f, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x03,
 	0x20, 0x03, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x45, 0x76,
 	0x65, 0x6e, 0x74, 0x52, 0x09, 0x72, 0x61, 0x77, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x21,
 	0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x74,
 	0x70, 0x6d, 0x2e, 0x48, 0x61, 0x73, 0x68, 0x41, 0x6c, 0x67, 0x6f, 0x52, 0x04, 0x68, 0x61, 0x73,
-	0x68, 0x22, 0xde, 0x01, 0x0a, 0x0e, 0x50, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x50, 0x6f,
-	0x6c, 0x69, 0x63, 0x79, 0x12, 0x39, 0x0a, 0x19, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64, 0x5f,
-	0x73, 0x63, 0x72, 0x74, 0x6d, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x64,
-	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x16, 0x61, 0x6c, 0x6c, 0x6f, 0x77, 0x65, 0x64,
-	0x53, 0x63, 0x72, 0x74, 0x6d, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x73, 0x12,
-	0x3f, 0x0a, 0x1c, 0x6d, 0x69, 0x6e, 0x69, 0x6d, 0x75, 0x6d, 0x5f, 0x67, 0x63, 0x65, 0x5f, 0x66,
-	0x69, 0x72, 0x6d, 0x77, 0x61, 0x72, 0x65, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x19, 0x6d, 0x69, 0x6e, 0x69, 0x6d, 0x75, 0x6d, 0x47, 0x63,
-	0x65, 0x46, 0x69, 0x72, 0x6d, 0x77, 0x61, 0x72, 0x65, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
-	0x12, 0x50, 0x0a, 0x12, 0x6d, 0x69, 0x6e, 0x69, 0x6d, 0x75, 0x6d, 0x5f, 0x74, 0x65, 0x63, 0x68,
-	0x6e, 0x6f, 0x6c, 0x6f, 0x67, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x21, 0x2e, 0x61,
-	0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x47, 0x43, 0x45, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x64, 0x65,
-	0x6e, 0x74, 0x69, 0x61, 0x6c, 0x54, 0x65, 0x63, 0x68, 0x6e, 0x6f, 0x6c, 0x6f, 0x67, 0x79, 0x52,
-	0x11, 0x6d, 0x69, 0x6e, 0x69, 0x6d, 0x75, 0x6d, 0x54, 0x65, 0x63, 0x68, 0x6e, 0x6f, 0x6c, 0x6f,
-	0x67, 0x79, 0x22, 0x3c, 0x0a, 0x06, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x12, 0x32, 0x0a, 0x08,
-	0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16,
-	0x2e, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x50, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d,
-	0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x08, 0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d,
-	0x2a, 0x42, 0x0a, 0x19, 0x47, 0x43, 0x45, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x64, 0x65, 0x6e, 0x74,
-	0x69, 0x61, 0x6c, 0x54, 0x65, 0x63, 0x68, 0x6e, 0x6f, 0x6c, 0x6f, 0x67, 0x79, 0x12, 0x08, 0x0a,
-	0x04, 0x4e, 0x4f, 0x4e, 0x45, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07, 0x41, 0x4d, 0x44, 0x5f, 0x53,
-	0x45, 0x56, 0x10, 0x01, 0x12, 0x0e, 0x0a, 0x0a, 0x41, 0x4d, 0x44, 0x5f, 0x53, 0x45, 0x56, 0x5f,
-	0x45, 0x53, 0x10, 0x02, 0x42, 0x2d, 0x5a, 0x2b, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
-	0x6f, 0x6d, 0x2f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x67, 0x6f, 0x2d, 0x74, 0x70, 0x6d,
-	0x2d, 0x74, 0x6f, 0x6f, 0x6c, 0x73, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x61, 0x74, 0x74,
-	0x65, 0x73, 0x74, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x68, 0x12, 0x25, 0x0a, 0x04, 0x67, 0x72, 0x75, 0x62, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x11, 0x2e, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x47, 0x72, 0x75, 0x62, 0x53, 0x74, 0x61,
+	0x74, 0x65, 0x52, 0x04, 0x67, 0x72, 0x75, 0x62, 0x12, 0x3b, 0x0a, 0x0c, 0x6c, 0x69, 0x6e, 0x75,
+	0x78, 0x5f, 0x6b, 0x65, 0x72, 0x6e, 0x65, 0x6c, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18,
+	0x2e, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x4c, 0x69, 0x6e, 0x75, 0x78, 0x4b, 0x65, 0x72,
+	0x6e, 0x65, 0x6c, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x0b, 0x6c, 0x69, 0x6e, 0x75, 0x78, 0x4b,
+	0x65, 0x72, 0x6e, 0x65, 0x6c, 0x12, 0x34, 0x0a, 0x09, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e,
+	0x65, 0x72, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x61, 0x74, 0x74, 0x65, 0x73,
+	0x74, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x53, 0x74, 0x61, 0x74, 0x65,
+	0x52, 0x09, 0x63, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x12, 0x25, 0x0a, 0x04, 0x64,
+	0x72, 0x74, 0x6d, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x61, 0x74, 0x74, 0x65,
+	0x73, 0x74, 0x2e, 0x44, 0x72, 0x74, 0x6d, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x04, 0x64, 0x72,
+	0x74, 0x6d, 0x12, 0x2c, 0x0a, 0x07, 0x73, 0x65, 0x76, 0x5f, 0x73, 0x6e, 0x70, 0x18, 0x09, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x53, 0x65, 0x76,
+	0x53, 0x6e, 0x70, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x06, 0x73, 0x65, 0x76, 0x53, 0x6e, 0x70,
+	0x12, 0x22, 0x0a, 0x03, 0x74, 0x64, 0x78, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e,
+	0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x54, 0x64, 0x78, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52,
+	0x03, 0x74, 0x64, 0x78, 0x22, 0xde, 0x01, 0x0a, 0x0e, 0x50, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72,
+	0x6d, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x12, 0x39, 0x0a, 0x19, 0x61, 0x6c, 0x6c, 0x6f, 0x77,
+	0x65, 0x64, 0x5f, 0x73, 0x63, 0x72, 0x74, 0x6d, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
+	0x5f, 0x69, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x16, 0x61, 0x6c, 0x6c, 0x6f,
+	0x77, 0x65, 0x64, 0x53, 0x63, 0x72, 0x74, 0x6d, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x49,
+	0x64, 0x73, 0x12, 0x3f, 0x0a, 0x1c, 0x6d, 0x69, 0x6e, 0x69, 0x6d, 0x75, 0x6d, 0x5f, 0x67, 0x63,
+	0x65, 0x5f, 0x66, 0x69, 0x72, 0x6d, 0x77, 0x61, 0x72, 0x65, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x19, 0x6d, 0x69, 0x6e, 0x69, 0x6d, 0x75,
+	0x6d, 0x47, 0x63, 0x65, 0x46, 0x69, 0x72, 0x6d, 0x77, 0x61, 0x72, 0x65, 0x56, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x12, 0x50, 0x0a, 0x12, 0x6d, 0x69, 0x6e, 0x69, 0x6d, 0x75, 0x6d, 0x5f, 0x74,
+	0x65, 0x63, 0x68, 0x6e, 0x6f, 0x6c, 0x6f, 0x67, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32,
+	0x21, 0x2e, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x47, 0x43, 0x45, 0x43, 0x6f, 0x6e, 0x66,
+	0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x54, 0x65, 0x63, 0x68, 0x6e, 0x6f, 0x6c, 0x6f,
+	0x67, 0x79, 0x52, 0x11, 0x6d, 0x69, 0x6e, 0x69, 0x6d, 0x75, 0x6d, 0x54, 0x65, 0x63, 0x68, 0x6e,
+	0x6f, 0x6c, 0x6f, 0x67, 0x79, 0x22, 0x3c, 0x0a, 0x06, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x12,
+	0x32, 0x0a, 0x08, 0x70, 0x6c, 0x61, 0x74, 0x66, 0x6f, 0x72, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x16, 0x2e, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x50, 0x6c, 0x61, 0x74, 0x66,
+	0x6f, 0x72, 0x6d, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x08, 0x70, 0x6c, 0x61, 0x74, 0x66,
+	0x6f, 0x72, 0x6d, 0x2a, 0x42, 0x0a, 0x19, 0x47, 0x43, 0x45, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x64,
+	0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x54, 0x65, 0x63, 0x68, 0x6e, 0x6f, 0x6c, 0x6f, 0x67, 0x79,
+	0x12, 0x08, 0x0a, 0x04, 0x4e, 0x4f, 0x4e, 0x45, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07, 0x41, 0x4d,
+	0x44, 0x5f, 0x53, 0x45, 0x56, 0x10, 0x01, 0x12, 0x0e, 0x0a, 0x0a, 0x41, 0x4d, 0x44, 0x5f, 0x53,
+	0x45, 0x56, 0x5f, 0x45, 0x53, 0x10, 0x02, 0x42, 0x2d, 0x5a, 0x2b, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x67, 0x6f, 0x2d,
+	0x74, 0x70, 0x6d, 0x2d, 0x74, 0x6f, 0x6f, 0x6c, 0x73, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f,
+	0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -707,34 +1772,59 @@ func file_attest_proto_rawDescGZIP() []byte {
 }
 
 var file_attest_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_attest_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_attest_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
 var file_attest_proto_goTypes = []interface{}{
 	(GCEConfidentialTechnology)(0), // 0: attest.GCEConfidentialTechnology
 	(*GCEInstanceInfo)(nil),        // 1: attest.GCEInstanceInfo
 	(*Attestation)(nil),            // 2: attest.Attestation
-	(*PlatformState)(nil),          // 3: attest.PlatformState
-	(*Event)(nil),                  // 4: attest.Event
-	(*MachineState)(nil),           // 5: attest.MachineState
-	(*PlatformPolicy)(nil),         // 6: attest.PlatformPolicy
-	(*Policy)(nil),                 // 7: attest.Policy
-	(*tpm.Quote)(nil),              // 8: tpm.Quote
-	(tpm.HashAlgo)(0),              // 9: tpm.HashAlgo
+	(*SevSnpAttestation)(nil),      // 3: attest.SevSnpAttestation
+	(*PlatformState)(nil),          // 4: attest.PlatformState
+	(*Event)(nil),                  // 5: attest.Event
+	(*Database)(nil),               // 6: attest.Database
+	(*SecureBootState)(nil),        // 7: attest.SecureBootState
+	(*GrubFile)(nil),               // 8: attest.GrubFile
+	(*GrubState)(nil),              // 9: attest.GrubState
+	(*LinuxKernelState)(nil),       // 10: attest.LinuxKernelState
+	(*DrtmState)(nil),              // 11: attest.DrtmState
+	(*SevSnpState)(nil),            // 12: attest.SevSnpState
+	(*TdxState)(nil),               // 13: attest.TdxState
+	(*ContainerState)(nil),         // 14: attest.ContainerState
+	(*MachineState)(nil),           // 15: attest.MachineState
+	(*PlatformPolicy)(nil),         // 16: attest.PlatformPolicy
+	(*Policy)(nil),                 // 17: attest.Policy
+	(*tpm.Quote)(nil),              // 18: tpm.Quote
+	(tpm.HashAlgo)(0),              // 19: tpm.HashAlgo
 }
 var file_attest_proto_depIdxs = []int32{
-	8, // 0: attest.Attestation.quotes:type_name -> tpm.Quote
-	1, // 1: attest.Attestation.instance_info:type_name -> attest.GCEInstanceInfo
-	0, // 2: attest.PlatformState.technology:type_name -> attest.GCEConfidentialTechnology
-	1, // 3: attest.PlatformState.instance_info:type_name -> attest.GCEInstanceInfo
-	3, // 4: attest.MachineState.platform:type_name -> attest.PlatformState
-	4, // 5: attest.MachineState.raw_events:type_name -> attest.Event
-	9, // 6: attest.MachineState.hash:type_name -> tpm.HashAlgo
-	0, // 7: attest.PlatformPolicy.minimum_technology:type_name -> attest.GCEConfidentialTechnology
-	6, // 8: attest.Policy.platform:type_name -> attest.PlatformPolicy
-	9, // [9:9] is the sub-list for method output_type
-	9, // [9:9] is the sub-list for method input_type
-	9, // [9:9] is the sub-list for extension type_name
-	9, // [9:9] is the sub-list for extension extendee
-	0, // [0:9] is the sub-list for field type_name
+	18, // 0: attest.Attestation.quotes:type_name -> tpm.Quote
+	1,  // 1: attest.Attestation.instance_info:type_name -> attest.GCEInstanceInfo
+	3,  // 2: attest.Attestation.sev_snp_attestation:type_name -> attest.SevSnpAttestation
+	0,  // 3: attest.PlatformState.technology:type_name -> attest.GCEConfidentialTechnology
+	1,  // 4: attest.PlatformState.instance_info:type_name -> attest.GCEInstanceInfo
+	6,  // 5: attest.SecureBootState.db:type_name -> attest.Database
+	6,  // 6: attest.SecureBootState.dbx:type_name -> attest.Database
+	6,  // 7: attest.SecureBootState.authority:type_name -> attest.Database
+	6,  // 8: attest.SecureBootState.pk:type_name -> attest.Database
+	6,  // 9: attest.SecureBootState.kek:type_name -> attest.Database
+	8,  // 10: attest.GrubState.files:type_name -> attest.GrubFile
+	5,  // 11: attest.DrtmState.raw_events:type_name -> attest.Event
+	4,  // 12: attest.MachineState.platform:type_name -> attest.PlatformState
+	7,  // 13: attest.MachineState.secure_boot:type_name -> attest.SecureBootState
+	5,  // 14: attest.MachineState.raw_events:type_name -> attest.Event
+	19, // 15: attest.MachineState.hash:type_name -> tpm.HashAlgo
+	9,  // 16: attest.MachineState.grub:type_name -> attest.GrubState
+	10, // 17: attest.MachineState.linux_kernel:type_name -> attest.LinuxKernelState
+	14, // 18: attest.MachineState.container:type_name -> attest.ContainerState
+	11, // 19: attest.MachineState.drtm:type_name -> attest.DrtmState
+	12, // 20: attest.MachineState.sev_snp:type_name -> attest.SevSnpState
+	13, // 21: attest.MachineState.tdx:type_name -> attest.TdxState
+	0,  // 22: attest.PlatformPolicy.minimum_technology:type_name -> attest.GCEConfidentialTechnology
+	16, // 23: attest.Policy.platform:type_name -> attest.PlatformPolicy
+	24, // [24:24] is the sub-list for method output_type
+	24, // [24:24] is the sub-list for method input_type
+	24, // [24:24] is the sub-list for extension type_name
+	24, // [24:24] is the sub-list for extension extendee
+	0,  // [0:24] is the sub-list for field type_name
 }
 
 func init() { file_attest_proto_init() }
@@ -768,7 +1858,7 @@ func file_attest_proto_init() {
 			}
 		}
 		file_attest_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*PlatformState); i {
+			switch v := v.(*SevSnpAttestation); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -780,7 +1870,7 @@ func file_attest_proto_init() {
 			}
 		}
 		file_attest_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Event); i {
+			switch v := v.(*PlatformState); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -792,7 +1882,7 @@ func file_attest_proto_init() {
 			}
 		}
 		file_attest_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*MachineState); i {
+			switch v := v.(*Event); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -804,7 +1894,7 @@ func file_attest_proto_init() {
 			}
 		}
 		file_attest_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*PlatformPolicy); i {
+			switch v := v.(*Database); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -816,6 +1906,126 @@ func file_attest_proto_init() {
 			}
 		}
 		file_attest_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SecureBootState); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_attest_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GrubFile); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_attest_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GrubState); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_attest_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LinuxKernelState); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_attest_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DrtmState); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_attest_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SevSnpState); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_attest_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TdxState); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_attest_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ContainerState); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_attest_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MachineState); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_attest_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PlatformPolicy); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_attest_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*Policy); i {
 			case 0:
 				return &v.state
@@ -828,7 +2038,7 @@ func file_attest_proto_init() {
 			}
 		}
 	}
-	file_attest_proto_msgTypes[2].OneofWrappers = []interface{}{
+	file_attest_proto_msgTypes[3].OneofWrappers = []interface{}{
 		(*PlatformState_ScrtmVersionId)(nil),
 		(*PlatformState_GceVersion)(nil),
 	}
@@ -838,7 +2048,7 @@ func file_attest_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_attest_proto_rawDesc,
 			NumEnums:      1,
-			NumMessages:   7,
+			NumMessages:   17,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
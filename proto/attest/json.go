@@ -0,0 +1,121 @@
+package attest
+
+import (
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// marshalJSON encodes m with protojson, the canonical JSON mapping of protocol
+// buffers. The encoding is not byte-for-byte stable, but always decodes to the
+// same message.
+func marshalJSON(m proto.Message) ([]byte, error) {
+	return protojson.Marshal(m)
+}
+
+// unmarshalJSON decodes m with protojson, ignoring unknown fields so that JSON
+// produced by newer versions can still be decoded.
+func unmarshalJSON(data []byte, m proto.Message) error {
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, m)
+}
+
+// MarshalJSON encodes the GCEInstanceInfo as JSON, using protojson.
+func (x *GCEInstanceInfo) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the GCEInstanceInfo from JSON, using protojson.
+func (x *GCEInstanceInfo) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the Attestation as JSON, using protojson.
+func (x *Attestation) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the Attestation from JSON, using protojson.
+func (x *Attestation) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the SevSnpAttestation as JSON, using protojson.
+func (x *SevSnpAttestation) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the SevSnpAttestation from JSON, using protojson.
+func (x *SevSnpAttestation) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the PlatformState as JSON, using protojson.
+func (x *PlatformState) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the PlatformState from JSON, using protojson.
+func (x *PlatformState) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the Event as JSON, using protojson.
+func (x *Event) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the Event from JSON, using protojson.
+func (x *Event) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the Database as JSON, using protojson.
+func (x *Database) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the Database from JSON, using protojson.
+func (x *Database) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the SecureBootState as JSON, using protojson.
+func (x *SecureBootState) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the SecureBootState from JSON, using protojson.
+func (x *SecureBootState) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the GrubFile as JSON, using protojson.
+func (x *GrubFile) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the GrubFile from JSON, using protojson.
+func (x *GrubFile) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the GrubState as JSON, using protojson.
+func (x *GrubState) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the GrubState from JSON, using protojson.
+func (x *GrubState) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the LinuxKernelState as JSON, using protojson.
+func (x *LinuxKernelState) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the LinuxKernelState from JSON, using protojson.
+func (x *LinuxKernelState) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the DrtmState as JSON, using protojson.
+func (x *DrtmState) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the DrtmState from JSON, using protojson.
+func (x *DrtmState) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the SevSnpState as JSON, using protojson.
+func (x *SevSnpState) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the SevSnpState from JSON, using protojson.
+func (x *SevSnpState) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the TdxState as JSON, using protojson.
+func (x *TdxState) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the TdxState from JSON, using protojson.
+func (x *TdxState) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the ContainerState as JSON, using protojson.
+func (x *ContainerState) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the ContainerState from JSON, using protojson.
+func (x *ContainerState) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the MachineState as JSON, using protojson.
+func (x *MachineState) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the MachineState from JSON, using protojson.
+func (x *MachineState) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the PlatformPolicy as JSON, using protojson.
+func (x *PlatformPolicy) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the PlatformPolicy from JSON, using protojson.
+func (x *PlatformPolicy) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the Policy as JSON, using protojson.
+func (x *Policy) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the Policy from JSON, using protojson.
+func (x *Policy) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
@@ -1,6 +1,12 @@
 // Package proto contains protocol buffers that are exchanged between the client
 // and server.
 //
+// JSON Encoding
+//
+// The messages implement json.Marshaler and json.Unmarshaler with protojson,
+// the canonical JSON mapping of protocol buffers, so they can be exchanged as
+// JSON with consumers in other languages (and encoded by encoding/json).
+//
 // Generating Protocol Buffer Code
 //
 // Anytime the Protocol Buffer definitions change, the generated Go code must be
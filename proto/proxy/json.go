@@ -0,0 +1,31 @@
+package proxy
+
+import (
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// marshalJSON encodes m with protojson, the canonical JSON mapping of protocol
+// buffers. The encoding is not byte-for-byte stable, but always decodes to the
+// same message.
+func marshalJSON(m proto.Message) ([]byte, error) {
+	return protojson.Marshal(m)
+}
+
+// unmarshalJSON decodes m with protojson, ignoring unknown fields so that JSON
+// produced by newer versions can still be decoded.
+func unmarshalJSON(data []byte, m proto.Message) error {
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, m)
+}
+
+// MarshalJSON encodes the CommandRequest as JSON, using protojson.
+func (x *CommandRequest) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the CommandRequest from JSON, using protojson.
+func (x *CommandRequest) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the CommandResponse as JSON, using protojson.
+func (x *CommandResponse) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the CommandResponse from JSON, using protojson.
+func (x *CommandResponse) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
@@ -0,0 +1,85 @@
+package tpm
+
+import (
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// marshalJSON encodes m with protojson, the canonical JSON mapping of protocol
+// buffers. The encoding is not byte-for-byte stable, but always decodes to the
+// same message.
+func marshalJSON(m proto.Message) ([]byte, error) {
+	return protojson.Marshal(m)
+}
+
+// unmarshalJSON decodes m with protojson, ignoring unknown fields so that JSON
+// produced by newer versions can still be decoded.
+func unmarshalJSON(data []byte, m proto.Message) error {
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, m)
+}
+
+// MarshalJSON encodes the SealedBytes as JSON, using protojson.
+func (x *SealedBytes) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the SealedBytes from JSON, using protojson.
+func (x *SealedBytes) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the BlobHeader as JSON, using protojson.
+func (x *BlobHeader) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the BlobHeader from JSON, using protojson.
+func (x *BlobHeader) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the StreamEnvelope as JSON, using protojson.
+func (x *StreamEnvelope) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the StreamEnvelope from JSON, using protojson.
+func (x *StreamEnvelope) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the ImportBlob as JSON, using protojson.
+func (x *ImportBlob) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the ImportBlob from JSON, using protojson.
+func (x *ImportBlob) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the KeyBlob as JSON, using protojson.
+func (x *KeyBlob) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the KeyBlob from JSON, using protojson.
+func (x *KeyBlob) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the Quote as JSON, using protojson.
+func (x *Quote) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the Quote from JSON, using protojson.
+func (x *Quote) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the SessionAudit as JSON, using protojson.
+func (x *SessionAudit) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the SessionAudit from JSON, using protojson.
+func (x *SessionAudit) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the KeyCertification as JSON, using protojson.
+func (x *KeyCertification) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the KeyCertification from JSON, using protojson.
+func (x *KeyCertification) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the CreationCertification as JSON, using protojson.
+func (x *CreationCertification) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the CreationCertification from JSON, using protojson.
+func (x *CreationCertification) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the AuditedCommand as JSON, using protojson.
+func (x *AuditedCommand) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the AuditedCommand from JSON, using protojson.
+func (x *AuditedCommand) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the PCRs as JSON, using protojson.
+func (x *PCRs) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the PCRs from JSON, using protojson.
+func (x *PCRs) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
@@ -0,0 +1,46 @@
+package tpm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	messages := []proto.Message{
+		&PCRs{Hash: HashAlgo_SHA256, Pcrs: map[uint32][]byte{0: {1, 2}, 7: {3}}},
+		&SealedBytes{
+			Priv:          []byte("priv"),
+			Pub:           []byte("pub"),
+			Pcrs:          []uint32{7},
+			Hash:          HashAlgo_SHA256,
+			Srk:           ObjectType_ECC,
+			CertifiedPcrs: &PCRs{Hash: HashAlgo_SHA256, Pcrs: map[uint32][]byte{7: {3}}},
+			Header:        &BlobHeader{Version: 1, Created: 1 << 40, Creator: "test", Checksum: []byte("sum")},
+		},
+		&ImportBlob{Duplicate: []byte("duplicate"), PublicArea: []byte("public"), Pcrs: &PCRs{Hash: HashAlgo_SHA1}},
+	}
+	for _, m := range messages {
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Fatalf("failed to encode %T: %v", m, err)
+		}
+		decoded := m.ProtoReflect().New().Interface()
+		if err := json.Unmarshal(data, decoded); err != nil {
+			t.Fatalf("failed to decode %T from %s: %v", m, data, err)
+		}
+		if !proto.Equal(m, decoded) {
+			t.Errorf("got %v after a JSON round trip, want %v", decoded, m)
+		}
+	}
+
+	// Enums are encoded by name, and unknown fields are ignored.
+	var pcrs PCRs
+	if err := json.Unmarshal([]byte(`{"hash": "SHA384", "unknown": 1}`), &pcrs); err != nil {
+		t.Fatal(err)
+	}
+	if pcrs.GetHash() != HashAlgo_SHA384 {
+		t.Errorf("got hash %v, want SHA384", pcrs.GetHash())
+	}
+}
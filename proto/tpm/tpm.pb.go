@@ -78,6 +78,7 @@ const (
 	HashAlgo_SHA256       HashAlgo = 11
 	HashAlgo_SHA384       HashAlgo = 12
 	HashAlgo_SHA512       HashAlgo = 13
+	HashAlgo_SM3_256      HashAlgo = 18
 )
 
 // Enum value maps for HashAlgo.
@@ -88,6 +89,7 @@ var (
 		11: "SHA256",
 		12: "SHA384",
 		13: "SHA512",
+		18: "SM3_256",
 	}
 	HashAlgo_value = map[string]int32{
 		"HASH_INVALID": 0,
@@ -95,6 +97,7 @@ var (
 		"SHA256":       11,
 		"SHA384":       12,
 		"SHA512":       13,
+		"SM3_256":      18,
 	}
 )
 
@@ -141,6 +144,16 @@ type SealedBytes struct {
 	CertifiedPcrs *PCRs      `protobuf:"bytes,6,opt,name=certified_pcrs,json=certifiedPcrs,proto3" json:"certified_pcrs,omitempty"`
 	CreationData  []byte     `protobuf:"bytes,7,opt,name=creation_data,json=creationData,proto3" json:"creation_data,omitempty"`
 	Ticket        []byte     `protobuf:"bytes,8,opt,name=ticket,proto3" json:"ticket,omitempty"`
+	// If data was sealed to multiple alternative PCR states, the PCR values of
+	// each state. In this case, pcrs and hash are unset.
+	AlternativePcrs []*PCRs `protobuf:"bytes,9,rep,name=alternative_pcrs,json=alternativePcrs,proto3" json:"alternative_pcrs,omitempty"`
+	// If true, an auth value must also be provided when unsealing.
+	AuthValue bool `protobuf:"varint,10,opt,name=auth_value,json=authValue,proto3" json:"auth_value,omitempty"`
+	// If set, the sealed data is a data key used to encrypt a stream (see
+	// StreamEnvelope), rather than the data itself.
+	Stream *StreamEnvelope `protobuf:"bytes,11,opt,name=stream,proto3" json:"stream,omitempty"`
+	// Format version, creation metadata and checksum of the sealed data.
+	Header *BlobHeader `protobuf:"bytes,12,opt,name=header,proto3" json:"header,omitempty"`
 }
 
 func (x *SealedBytes) Reset() {
@@ -231,6 +244,191 @@ func (x *SealedBytes) GetTicket() []byte {
 	return nil
 }
 
+func (x *SealedBytes) GetAlternativePcrs() []*PCRs {
+	if x != nil {
+		return x.AlternativePcrs
+	}
+	return nil
+}
+
+func (x *SealedBytes) GetAuthValue() bool {
+	if x != nil {
+		return x.AuthValue
+	}
+	return false
+}
+
+func (x *SealedBytes) GetStream() *StreamEnvelope {
+	if x != nil {
+		return x.Stream
+	}
+	return nil
+}
+
+func (x *SealedBytes) GetHeader() *BlobHeader {
+	if x != nil {
+		return x.Header
+	}
+	return nil
+}
+
+// BlobHeader describes the format and origin of a SealedBytes or ImportBlob,
+// so that long-lived blobs can be identified and migrated. Blobs created
+// before headers were added have no header, and are treated as version 0.
+type BlobHeader struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Format version of the blob (see notinternal.BlobVersion)
+	Version uint32 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	// Creation time, in seconds since the Unix epoch
+	Created int64 `protobuf:"varint,2,opt,name=created,proto3" json:"created,omitempty"`
+	// PCR bank of the blob's policy, if bound to PCRs
+	PcrBank HashAlgo `protobuf:"varint,3,opt,name=pcr_bank,json=pcrBank,proto3,enum=tpm.HashAlgo" json:"pcr_bank,omitempty"`
+	// Auth policy digest of the sealed or imported object, if any
+	PolicyDigest []byte `protobuf:"bytes,4,opt,name=policy_digest,json=policyDigest,proto3" json:"policy_digest,omitempty"`
+	// Description of the creator of the blob (such as the creating library)
+	Creator string `protobuf:"bytes,5,opt,name=creator,proto3" json:"creator,omitempty"`
+	// SHA256 digest of the deterministic encoding of the blob, with this
+	// checksum unset. It detects corrupted blobs, but is not a signature.
+	Checksum []byte `protobuf:"bytes,6,opt,name=checksum,proto3" json:"checksum,omitempty"`
+}
+
+func (x *BlobHeader) Reset() {
+	*x = BlobHeader{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tpm_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlobHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlobHeader) ProtoMessage() {}
+
+func (x *BlobHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_tpm_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlobHeader.ProtoReflect.Descriptor instead.
+func (*BlobHeader) Descriptor() ([]byte, []int) {
+	return file_tpm_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *BlobHeader) GetVersion() uint32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *BlobHeader) GetCreated() int64 {
+	if x != nil {
+		return x.Created
+	}
+	return 0
+}
+
+func (x *BlobHeader) GetPcrBank() HashAlgo {
+	if x != nil {
+		return x.PcrBank
+	}
+	return HashAlgo_HASH_INVALID
+}
+
+func (x *BlobHeader) GetPolicyDigest() []byte {
+	if x != nil {
+		return x.PolicyDigest
+	}
+	return nil
+}
+
+func (x *BlobHeader) GetCreator() string {
+	if x != nil {
+		return x.Creator
+	}
+	return ""
+}
+
+func (x *BlobHeader) GetChecksum() []byte {
+	if x != nil {
+		return x.Checksum
+	}
+	return nil
+}
+
+// StreamEnvelope describes data encrypted with a sealed AES-256-GCM key.
+// The plaintext is split into chunks of chunk_size bytes, each encrypted
+// separately. The nonce of the i-th chunk is the base nonce with its last 8
+// bytes XORed with i (big-endian). The additional data of each chunk is a
+// single byte, which is 1 for the final chunk and 0 otherwise.
+type StreamEnvelope struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Nonce     []byte `protobuf:"bytes,1,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	ChunkSize uint32 `protobuf:"varint,2,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"`
+}
+
+func (x *StreamEnvelope) Reset() {
+	*x = StreamEnvelope{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tpm_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamEnvelope) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEnvelope) ProtoMessage() {}
+
+func (x *StreamEnvelope) ProtoReflect() protoreflect.Message {
+	mi := &file_tpm_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamEnvelope.ProtoReflect.Descriptor instead.
+func (*StreamEnvelope) Descriptor() ([]byte, []int) {
+	return file_tpm_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *StreamEnvelope) GetNonce() []byte {
+	if x != nil {
+		return x.Nonce
+	}
+	return nil
+}
+
+func (x *StreamEnvelope) GetChunkSize() uint32 {
+	if x != nil {
+		return x.ChunkSize
+	}
+	return 0
+}
+
 type ImportBlob struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -240,12 +438,14 @@ type ImportBlob struct {
 	EncryptedSeed []byte `protobuf:"bytes,2,opt,name=encrypted_seed,json=encryptedSeed,proto3" json:"encrypted_seed,omitempty"`
 	PublicArea    []byte `protobuf:"bytes,3,opt,name=public_area,json=publicArea,proto3" json:"public_area,omitempty"`
 	Pcrs          *PCRs  `protobuf:"bytes,4,opt,name=pcrs,proto3" json:"pcrs,omitempty"`
+	// Format version, creation metadata and checksum of the blob.
+	Header *BlobHeader `protobuf:"bytes,5,opt,name=header,proto3" json:"header,omitempty"`
 }
 
 func (x *ImportBlob) Reset() {
 	*x = ImportBlob{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_tpm_proto_msgTypes[1]
+		mi := &file_tpm_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -258,7 +458,7 @@ func (x *ImportBlob) String() string {
 func (*ImportBlob) ProtoMessage() {}
 
 func (x *ImportBlob) ProtoReflect() protoreflect.Message {
-	mi := &file_tpm_proto_msgTypes[1]
+	mi := &file_tpm_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -271,7 +471,7 @@ func (x *ImportBlob) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ImportBlob.ProtoReflect.Descriptor instead.
 func (*ImportBlob) Descriptor() ([]byte, []int) {
-	return file_tpm_proto_rawDescGZIP(), []int{1}
+	return file_tpm_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *ImportBlob) GetDuplicate() []byte {
@@ -302,6 +502,73 @@ func (x *ImportBlob) GetPcrs() *PCRs {
 	return nil
 }
 
+func (x *ImportBlob) GetHeader() *BlobHeader {
+	if x != nil {
+		return x.Header
+	}
+	return nil
+}
+
+// KeyBlob stores a key created under a parent key (see client.Key.CreateChild).
+// The private area has been encrypted by the parent, and is not sensitive. The
+// key can only be loaded under the same parent.
+type KeyBlob struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Public area of the key, encoded as a TPMT_PUBLIC
+	PublicArea []byte `protobuf:"bytes,1,opt,name=public_area,json=publicArea,proto3" json:"public_area,omitempty"`
+	// Private area of the key, encoded as a TPM2B_PRIVATE
+	PrivateArea []byte `protobuf:"bytes,2,opt,name=private_area,json=privateArea,proto3" json:"private_area,omitempty"`
+}
+
+func (x *KeyBlob) Reset() {
+	*x = KeyBlob{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tpm_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *KeyBlob) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyBlob) ProtoMessage() {}
+
+func (x *KeyBlob) ProtoReflect() protoreflect.Message {
+	mi := &file_tpm_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyBlob.ProtoReflect.Descriptor instead.
+func (*KeyBlob) Descriptor() ([]byte, []int) {
+	return file_tpm_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *KeyBlob) GetPublicArea() []byte {
+	if x != nil {
+		return x.PublicArea
+	}
+	return nil
+}
+
+func (x *KeyBlob) GetPrivateArea() []byte {
+	if x != nil {
+		return x.PrivateArea
+	}
+	return nil
+}
+
 type Quote struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -318,7 +585,7 @@ type Quote struct {
 func (x *Quote) Reset() {
 	*x = Quote{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_tpm_proto_msgTypes[2]
+		mi := &file_tpm_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -331,7 +598,7 @@ func (x *Quote) String() string {
 func (*Quote) ProtoMessage() {}
 
 func (x *Quote) ProtoReflect() protoreflect.Message {
-	mi := &file_tpm_proto_msgTypes[2]
+	mi := &file_tpm_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -344,7 +611,7 @@ func (x *Quote) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Quote.ProtoReflect.Descriptor instead.
 func (*Quote) Descriptor() ([]byte, []int) {
-	return file_tpm_proto_rawDescGZIP(), []int{2}
+	return file_tpm_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *Quote) GetQuote() []byte {
@@ -368,6 +635,296 @@ func (x *Quote) GetPcrs() *PCRs {
 	return nil
 }
 
+// SessionAudit is a signed audit digest of a TPM audit session, together with
+// the log of the audited commands.
+type SessionAudit struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// TPM2 session audit digest, encoded as a TPMS_ATTEST
+	Audit []byte `protobuf:"bytes,1,opt,name=audit,proto3" json:"audit,omitempty"`
+	// TPM2 signature, encoded as a TPMT_SIGNATURE
+	RawSig []byte `protobuf:"bytes,2,opt,name=raw_sig,json=rawSig,proto3" json:"raw_sig,omitempty"`
+	// Hash algorithm of the session
+	Hash HashAlgo `protobuf:"varint,3,opt,name=hash,proto3,enum=tpm.HashAlgo" json:"hash,omitempty"`
+	// The audited commands, in the order they were executed
+	Commands []*AuditedCommand `protobuf:"bytes,4,rep,name=commands,proto3" json:"commands,omitempty"`
+}
+
+func (x *SessionAudit) Reset() {
+	*x = SessionAudit{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tpm_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SessionAudit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionAudit) ProtoMessage() {}
+
+func (x *SessionAudit) ProtoReflect() protoreflect.Message {
+	mi := &file_tpm_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionAudit.ProtoReflect.Descriptor instead.
+func (*SessionAudit) Descriptor() ([]byte, []int) {
+	return file_tpm_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SessionAudit) GetAudit() []byte {
+	if x != nil {
+		return x.Audit
+	}
+	return nil
+}
+
+func (x *SessionAudit) GetRawSig() []byte {
+	if x != nil {
+		return x.RawSig
+	}
+	return nil
+}
+
+func (x *SessionAudit) GetHash() HashAlgo {
+	if x != nil {
+		return x.Hash
+	}
+	return HashAlgo_HASH_INVALID
+}
+
+func (x *SessionAudit) GetCommands() []*AuditedCommand {
+	if x != nil {
+		return x.Commands
+	}
+	return nil
+}
+
+// KeyCertification is a TPM2_Certify attestation of a key, signed by an AK.
+// It proves that the key is loaded in the same TPM as the AK.
+type KeyCertification struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// TPM2 certify info, encoded as a TPMS_ATTEST
+	CertifyInfo []byte `protobuf:"bytes,1,opt,name=certify_info,json=certifyInfo,proto3" json:"certify_info,omitempty"`
+	// TPM2 signature, encoded as a TPMT_SIGNATURE
+	RawSig []byte `protobuf:"bytes,2,opt,name=raw_sig,json=rawSig,proto3" json:"raw_sig,omitempty"`
+	// Public area of the certified key, encoded as a TPMT_PUBLIC
+	PublicArea []byte `protobuf:"bytes,3,opt,name=public_area,json=publicArea,proto3" json:"public_area,omitempty"`
+}
+
+func (x *KeyCertification) Reset() {
+	*x = KeyCertification{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tpm_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *KeyCertification) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyCertification) ProtoMessage() {}
+
+func (x *KeyCertification) ProtoReflect() protoreflect.Message {
+	mi := &file_tpm_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyCertification.ProtoReflect.Descriptor instead.
+func (*KeyCertification) Descriptor() ([]byte, []int) {
+	return file_tpm_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *KeyCertification) GetCertifyInfo() []byte {
+	if x != nil {
+		return x.CertifyInfo
+	}
+	return nil
+}
+
+func (x *KeyCertification) GetRawSig() []byte {
+	if x != nil {
+		return x.RawSig
+	}
+	return nil
+}
+
+func (x *KeyCertification) GetPublicArea() []byte {
+	if x != nil {
+		return x.PublicArea
+	}
+	return nil
+}
+
+// CreationCertification is a TPM2_CertifyCreation attestation of a key's
+// creation data, signed by an AK. It proves that the key was created by the
+// AK's TPM, with the recorded creation data (such as the PCR values at the
+// time of creation).
+type CreationCertification struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// TPM2 creation info, encoded as a TPMS_ATTEST
+	CreationInfo []byte `protobuf:"bytes,1,opt,name=creation_info,json=creationInfo,proto3" json:"creation_info,omitempty"`
+	// TPM2 signature, encoded as a TPMT_SIGNATURE
+	RawSig []byte `protobuf:"bytes,2,opt,name=raw_sig,json=rawSig,proto3" json:"raw_sig,omitempty"`
+	// Public area of the certified key, encoded as a TPMT_PUBLIC
+	PublicArea []byte `protobuf:"bytes,3,opt,name=public_area,json=publicArea,proto3" json:"public_area,omitempty"`
+	// Creation data of the key, encoded as a TPMS_CREATION_DATA
+	CreationData []byte `protobuf:"bytes,4,opt,name=creation_data,json=creationData,proto3" json:"creation_data,omitempty"`
+}
+
+func (x *CreationCertification) Reset() {
+	*x = CreationCertification{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tpm_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CreationCertification) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreationCertification) ProtoMessage() {}
+
+func (x *CreationCertification) ProtoReflect() protoreflect.Message {
+	mi := &file_tpm_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreationCertification.ProtoReflect.Descriptor instead.
+func (*CreationCertification) Descriptor() ([]byte, []int) {
+	return file_tpm_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CreationCertification) GetCreationInfo() []byte {
+	if x != nil {
+		return x.CreationInfo
+	}
+	return nil
+}
+
+func (x *CreationCertification) GetRawSig() []byte {
+	if x != nil {
+		return x.RawSig
+	}
+	return nil
+}
+
+func (x *CreationCertification) GetPublicArea() []byte {
+	if x != nil {
+		return x.PublicArea
+	}
+	return nil
+}
+
+func (x *CreationCertification) GetCreationData() []byte {
+	if x != nil {
+		return x.CreationData
+	}
+	return nil
+}
+
+type AuditedCommand struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The command code (TPM_CC) of the command
+	CommandCode uint32 `protobuf:"varint,1,opt,name=command_code,json=commandCode,proto3" json:"command_code,omitempty"`
+	// Hash of the command code, handle Names and parameters
+	CpHash []byte `protobuf:"bytes,2,opt,name=cp_hash,json=cpHash,proto3" json:"cp_hash,omitempty"`
+	// Hash of the response code, command code and response parameters
+	RpHash []byte `protobuf:"bytes,3,opt,name=rp_hash,json=rpHash,proto3" json:"rp_hash,omitempty"`
+}
+
+func (x *AuditedCommand) Reset() {
+	*x = AuditedCommand{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tpm_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AuditedCommand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuditedCommand) ProtoMessage() {}
+
+func (x *AuditedCommand) ProtoReflect() protoreflect.Message {
+	mi := &file_tpm_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuditedCommand.ProtoReflect.Descriptor instead.
+func (*AuditedCommand) Descriptor() ([]byte, []int) {
+	return file_tpm_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *AuditedCommand) GetCommandCode() uint32 {
+	if x != nil {
+		return x.CommandCode
+	}
+	return 0
+}
+
+func (x *AuditedCommand) GetCpHash() []byte {
+	if x != nil {
+		return x.CpHash
+	}
+	return nil
+}
+
+func (x *AuditedCommand) GetRpHash() []byte {
+	if x != nil {
+		return x.RpHash
+	}
+	return nil
+}
+
 type PCRs struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -380,7 +937,7 @@ type PCRs struct {
 func (x *PCRs) Reset() {
 	*x = PCRs{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_tpm_proto_msgTypes[3]
+		mi := &file_tpm_proto_msgTypes[10]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -393,7 +950,7 @@ func (x *PCRs) String() string {
 func (*PCRs) ProtoMessage() {}
 
 func (x *PCRs) ProtoReflect() protoreflect.Message {
-	mi := &file_tpm_proto_msgTypes[3]
+	mi := &file_tpm_proto_msgTypes[10]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -406,7 +963,7 @@ func (x *PCRs) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PCRs.ProtoReflect.Descriptor instead.
 func (*PCRs) Descriptor() ([]byte, []int) {
-	return file_tpm_proto_rawDescGZIP(), []int{3}
+	return file_tpm_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *PCRs) GetHash() HashAlgo {
@@ -427,7 +984,7 @@ var File_tpm_proto protoreflect.FileDescriptor
 
 var file_tpm_proto_rawDesc = []byte{
 	0x0a, 0x09, 0x74, 0x70, 0x6d, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x03, 0x74, 0x70, 0x6d,
-	0x22, 0xfc, 0x01, 0x0a, 0x0b, 0x53, 0x65, 0x61, 0x6c, 0x65, 0x64, 0x42, 0x79, 0x74, 0x65, 0x73,
+	0x22, 0xa7, 0x03, 0x0a, 0x0b, 0x53, 0x65, 0x61, 0x6c, 0x65, 0x64, 0x42, 0x79, 0x74, 0x65, 0x73,
 	0x12, 0x12, 0x0a, 0x04, 0x70, 0x72, 0x69, 0x76, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04,
 	0x70, 0x72, 0x69, 0x76, 0x12, 0x10, 0x0a, 0x03, 0x70, 0x75, 0x62, 0x18, 0x02, 0x20, 0x01, 0x28,
 	0x0c, 0x52, 0x03, 0x70, 0x75, 0x62, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x63, 0x72, 0x73, 0x18, 0x03,
@@ -442,42 +999,111 @@ var file_tpm_proto_rawDesc = []byte{
 	0x72, 0x73, 0x12, 0x23, 0x0a, 0x0d, 0x63, 0x72, 0x65, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x64,
 	0x61, 0x74, 0x61, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0c, 0x63, 0x72, 0x65, 0x61, 0x74,
 	0x69, 0x6f, 0x6e, 0x44, 0x61, 0x74, 0x61, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x69, 0x63, 0x6b, 0x65,
-	0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x74, 0x69, 0x63, 0x6b, 0x65, 0x74, 0x22,
-	0x91, 0x01, 0x0a, 0x0a, 0x49, 0x6d, 0x70, 0x6f, 0x72, 0x74, 0x42, 0x6c, 0x6f, 0x62, 0x12, 0x1c,
-	0x0a, 0x09, 0x64, 0x75, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x0c, 0x52, 0x09, 0x64, 0x75, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x65, 0x12, 0x25, 0x0a, 0x0e,
-	0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x5f, 0x73, 0x65, 0x65, 0x64, 0x18, 0x02,
-	0x20, 0x01, 0x28, 0x0c, 0x52, 0x0d, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x53,
-	0x65, 0x65, 0x64, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x5f, 0x61, 0x72,
-	0x65, 0x61, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0a, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63,
-	0x41, 0x72, 0x65, 0x61, 0x12, 0x1d, 0x0a, 0x04, 0x70, 0x63, 0x72, 0x73, 0x18, 0x04, 0x20, 0x01,
-	0x28, 0x0b, 0x32, 0x09, 0x2e, 0x74, 0x70, 0x6d, 0x2e, 0x50, 0x43, 0x52, 0x73, 0x52, 0x04, 0x70,
-	0x63, 0x72, 0x73, 0x22, 0x55, 0x0a, 0x05, 0x51, 0x75, 0x6f, 0x74, 0x65, 0x12, 0x14, 0x0a, 0x05,
-	0x71, 0x75, 0x6f, 0x74, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x71, 0x75, 0x6f,
-	0x74, 0x65, 0x12, 0x17, 0x0a, 0x07, 0x72, 0x61, 0x77, 0x5f, 0x73, 0x69, 0x67, 0x18, 0x02, 0x20,
-	0x01, 0x28, 0x0c, 0x52, 0x06, 0x72, 0x61, 0x77, 0x53, 0x69, 0x67, 0x12, 0x1d, 0x0a, 0x04, 0x70,
-	0x63, 0x72, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x09, 0x2e, 0x74, 0x70, 0x6d, 0x2e,
-	0x50, 0x43, 0x52, 0x73, 0x52, 0x04, 0x70, 0x63, 0x72, 0x73, 0x22, 0x8b, 0x01, 0x0a, 0x04, 0x50,
-	0x43, 0x52, 0x73, 0x12, 0x21, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x0e, 0x32, 0x0d, 0x2e, 0x74, 0x70, 0x6d, 0x2e, 0x48, 0x61, 0x73, 0x68, 0x41, 0x6c, 0x67, 0x6f,
-	0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x12, 0x27, 0x0a, 0x04, 0x70, 0x63, 0x72, 0x73, 0x18, 0x02,
-	0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x74, 0x70, 0x6d, 0x2e, 0x50, 0x43, 0x52, 0x73, 0x2e,
-	0x50, 0x63, 0x72, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x04, 0x70, 0x63, 0x72, 0x73, 0x1a,
-	0x37, 0x0a, 0x09, 0x50, 0x63, 0x72, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03,
-	0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14,
-	0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x76,
-	0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x2a, 0x32, 0x0a, 0x0a, 0x4f, 0x62, 0x6a, 0x65,
-	0x63, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x12, 0x0a, 0x0e, 0x4f, 0x42, 0x4a, 0x45, 0x43, 0x54,
-	0x5f, 0x49, 0x4e, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x10, 0x00, 0x12, 0x07, 0x0a, 0x03, 0x52, 0x53,
-	0x41, 0x10, 0x01, 0x12, 0x07, 0x0a, 0x03, 0x45, 0x43, 0x43, 0x10, 0x23, 0x2a, 0x4a, 0x0a, 0x08,
-	0x48, 0x61, 0x73, 0x68, 0x41, 0x6c, 0x67, 0x6f, 0x12, 0x10, 0x0a, 0x0c, 0x48, 0x41, 0x53, 0x48,
-	0x5f, 0x49, 0x4e, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x10, 0x00, 0x12, 0x08, 0x0a, 0x04, 0x53, 0x48,
-	0x41, 0x31, 0x10, 0x04, 0x12, 0x0a, 0x0a, 0x06, 0x53, 0x48, 0x41, 0x32, 0x35, 0x36, 0x10, 0x0b,
-	0x12, 0x0a, 0x0a, 0x06, 0x53, 0x48, 0x41, 0x33, 0x38, 0x34, 0x10, 0x0c, 0x12, 0x0a, 0x0a, 0x06,
-	0x53, 0x48, 0x41, 0x35, 0x31, 0x32, 0x10, 0x0d, 0x42, 0x2a, 0x5a, 0x28, 0x67, 0x69, 0x74, 0x68,
-	0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x67, 0x6f,
-	0x2d, 0x74, 0x70, 0x6d, 0x2d, 0x74, 0x6f, 0x6f, 0x6c, 0x73, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x2f, 0x74, 0x70, 0x6d, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x74, 0x69, 0x63, 0x6b, 0x65, 0x74, 0x12,
+	0x34, 0x0a, 0x10, 0x61, 0x6c, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x69, 0x76, 0x65, 0x5f, 0x70,
+	0x63, 0x72, 0x73, 0x18, 0x09, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x09, 0x2e, 0x74, 0x70, 0x6d, 0x2e,
+	0x50, 0x43, 0x52, 0x73, 0x52, 0x0f, 0x61, 0x6c, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x74, 0x69, 0x76,
+	0x65, 0x50, 0x63, 0x72, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x61, 0x75, 0x74, 0x68, 0x5f, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x61, 0x75, 0x74, 0x68, 0x56,
+	0x61, 0x6c, 0x75, 0x65, 0x12, 0x2b, 0x0a, 0x06, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x18, 0x0b,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x74, 0x70, 0x6d, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61,
+	0x6d, 0x45, 0x6e, 0x76, 0x65, 0x6c, 0x6f, 0x70, 0x65, 0x52, 0x06, 0x73, 0x74, 0x72, 0x65, 0x61,
+	0x6d, 0x12, 0x27, 0x0a, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x18, 0x0c, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x0f, 0x2e, 0x74, 0x70, 0x6d, 0x2e, 0x42, 0x6c, 0x6f, 0x62, 0x48, 0x65, 0x61, 0x64,
+	0x65, 0x72, 0x52, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x22, 0xc5, 0x01, 0x0a, 0x0a, 0x42,
+	0x6c, 0x6f, 0x62, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x12, 0x28, 0x0a,
+	0x08, 0x70, 0x63, 0x72, 0x5f, 0x62, 0x61, 0x6e, 0x6b, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32,
+	0x0d, 0x2e, 0x74, 0x70, 0x6d, 0x2e, 0x48, 0x61, 0x73, 0x68, 0x41, 0x6c, 0x67, 0x6f, 0x52, 0x07,
+	0x70, 0x63, 0x72, 0x42, 0x61, 0x6e, 0x6b, 0x12, 0x23, 0x0a, 0x0d, 0x70, 0x6f, 0x6c, 0x69, 0x63,
+	0x79, 0x5f, 0x64, 0x69, 0x67, 0x65, 0x73, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0c,
+	0x70, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x12, 0x18, 0x0a, 0x07,
+	0x63, 0x72, 0x65, 0x61, 0x74, 0x6f, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63,
+	0x72, 0x65, 0x61, 0x74, 0x6f, 0x72, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73,
+	0x75, 0x6d, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73,
+	0x75, 0x6d, 0x22, 0x45, 0x0a, 0x0e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x45, 0x6e, 0x76, 0x65,
+	0x6c, 0x6f, 0x70, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x63, 0x68,
+	0x75, 0x6e, 0x6b, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09,
+	0x63, 0x68, 0x75, 0x6e, 0x6b, 0x53, 0x69, 0x7a, 0x65, 0x22, 0xba, 0x01, 0x0a, 0x0a, 0x49, 0x6d,
+	0x70, 0x6f, 0x72, 0x74, 0x42, 0x6c, 0x6f, 0x62, 0x12, 0x1c, 0x0a, 0x09, 0x64, 0x75, 0x70, 0x6c,
+	0x69, 0x63, 0x61, 0x74, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x64, 0x75, 0x70,
+	0x6c, 0x69, 0x63, 0x61, 0x74, 0x65, 0x12, 0x25, 0x0a, 0x0e, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70,
+	0x74, 0x65, 0x64, 0x5f, 0x73, 0x65, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0d,
+	0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x53, 0x65, 0x65, 0x64, 0x12, 0x1f, 0x0a,
+	0x0b, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x5f, 0x61, 0x72, 0x65, 0x61, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x0a, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x41, 0x72, 0x65, 0x61, 0x12, 0x1d,
+	0x0a, 0x04, 0x70, 0x63, 0x72, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x09, 0x2e, 0x74,
+	0x70, 0x6d, 0x2e, 0x50, 0x43, 0x52, 0x73, 0x52, 0x04, 0x70, 0x63, 0x72, 0x73, 0x12, 0x27, 0x0a,
+	0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e,
+	0x74, 0x70, 0x6d, 0x2e, 0x42, 0x6c, 0x6f, 0x62, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x06,
+	0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x22, 0x4d, 0x0a, 0x07, 0x4b, 0x65, 0x79, 0x42, 0x6c, 0x6f,
+	0x62, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x5f, 0x61, 0x72, 0x65, 0x61,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0a, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x41, 0x72,
+	0x65, 0x61, 0x12, 0x21, 0x0a, 0x0c, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x5f, 0x61, 0x72,
+	0x65, 0x61, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74,
+	0x65, 0x41, 0x72, 0x65, 0x61, 0x22, 0x55, 0x0a, 0x05, 0x51, 0x75, 0x6f, 0x74, 0x65, 0x12, 0x14,
+	0x0a, 0x05, 0x71, 0x75, 0x6f, 0x74, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x71,
+	0x75, 0x6f, 0x74, 0x65, 0x12, 0x17, 0x0a, 0x07, 0x72, 0x61, 0x77, 0x5f, 0x73, 0x69, 0x67, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x72, 0x61, 0x77, 0x53, 0x69, 0x67, 0x12, 0x1d, 0x0a,
+	0x04, 0x70, 0x63, 0x72, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x09, 0x2e, 0x74, 0x70,
+	0x6d, 0x2e, 0x50, 0x43, 0x52, 0x73, 0x52, 0x04, 0x70, 0x63, 0x72, 0x73, 0x22, 0x91, 0x01, 0x0a,
+	0x0c, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x41, 0x75, 0x64, 0x69, 0x74, 0x12, 0x14, 0x0a,
+	0x05, 0x61, 0x75, 0x64, 0x69, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x61, 0x75,
+	0x64, 0x69, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x72, 0x61, 0x77, 0x5f, 0x73, 0x69, 0x67, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x72, 0x61, 0x77, 0x53, 0x69, 0x67, 0x12, 0x21, 0x0a, 0x04,
+	0x68, 0x61, 0x73, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x74, 0x70, 0x6d,
+	0x2e, 0x48, 0x61, 0x73, 0x68, 0x41, 0x6c, 0x67, 0x6f, 0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x12,
+	0x2f, 0x0a, 0x08, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x13, 0x2e, 0x74, 0x70, 0x6d, 0x2e, 0x41, 0x75, 0x64, 0x69, 0x74, 0x65, 0x64, 0x43,
+	0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x52, 0x08, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x73,
+	0x22, 0x6f, 0x0a, 0x10, 0x4b, 0x65, 0x79, 0x43, 0x65, 0x72, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x21, 0x0a, 0x0c, 0x63, 0x65, 0x72, 0x74, 0x69, 0x66, 0x79, 0x5f,
+	0x69, 0x6e, 0x66, 0x6f, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b, 0x63, 0x65, 0x72, 0x74,
+	0x69, 0x66, 0x79, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x17, 0x0a, 0x07, 0x72, 0x61, 0x77, 0x5f, 0x73,
+	0x69, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x72, 0x61, 0x77, 0x53, 0x69, 0x67,
+	0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x5f, 0x61, 0x72, 0x65, 0x61, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0a, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x41, 0x72, 0x65,
+	0x61, 0x22, 0x9b, 0x01, 0x0a, 0x15, 0x43, 0x72, 0x65, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x43, 0x65,
+	0x72, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x23, 0x0a, 0x0d, 0x63,
+	0x72, 0x65, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x69, 0x6e, 0x66, 0x6f, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x0c, 0x63, 0x72, 0x65, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x6e, 0x66, 0x6f,
+	0x12, 0x17, 0x0a, 0x07, 0x72, 0x61, 0x77, 0x5f, 0x73, 0x69, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x06, 0x72, 0x61, 0x77, 0x53, 0x69, 0x67, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x75, 0x62,
+	0x6c, 0x69, 0x63, 0x5f, 0x61, 0x72, 0x65, 0x61, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0a,
+	0x70, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x41, 0x72, 0x65, 0x61, 0x12, 0x23, 0x0a, 0x0d, 0x63, 0x72,
+	0x65, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x64, 0x61, 0x74, 0x61, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x0c, 0x63, 0x72, 0x65, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x44, 0x61, 0x74, 0x61, 0x22,
+	0x65, 0x0a, 0x0e, 0x41, 0x75, 0x64, 0x69, 0x74, 0x65, 0x64, 0x43, 0x6f, 0x6d, 0x6d, 0x61, 0x6e,
+	0x64, 0x12, 0x21, 0x0a, 0x0c, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x5f, 0x63, 0x6f, 0x64,
+	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0b, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64,
+	0x43, 0x6f, 0x64, 0x65, 0x12, 0x17, 0x0a, 0x07, 0x63, 0x70, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x63, 0x70, 0x48, 0x61, 0x73, 0x68, 0x12, 0x17, 0x0a,
+	0x07, 0x72, 0x70, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06,
+	0x72, 0x70, 0x48, 0x61, 0x73, 0x68, 0x22, 0x8b, 0x01, 0x0a, 0x04, 0x50, 0x43, 0x52, 0x73, 0x12,
+	0x21, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e,
+	0x74, 0x70, 0x6d, 0x2e, 0x48, 0x61, 0x73, 0x68, 0x41, 0x6c, 0x67, 0x6f, 0x52, 0x04, 0x68, 0x61,
+	0x73, 0x68, 0x12, 0x27, 0x0a, 0x04, 0x70, 0x63, 0x72, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x13, 0x2e, 0x74, 0x70, 0x6d, 0x2e, 0x50, 0x43, 0x52, 0x73, 0x2e, 0x50, 0x63, 0x72, 0x73,
+	0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x04, 0x70, 0x63, 0x72, 0x73, 0x1a, 0x37, 0x0a, 0x09, 0x50,
+	0x63, 0x72, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x3a, 0x02, 0x38, 0x01, 0x2a, 0x32, 0x0a, 0x0a, 0x4f, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x54, 0x79,
+	0x70, 0x65, 0x12, 0x12, 0x0a, 0x0e, 0x4f, 0x42, 0x4a, 0x45, 0x43, 0x54, 0x5f, 0x49, 0x4e, 0x56,
+	0x41, 0x4c, 0x49, 0x44, 0x10, 0x00, 0x12, 0x07, 0x0a, 0x03, 0x52, 0x53, 0x41, 0x10, 0x01, 0x12,
+	0x07, 0x0a, 0x03, 0x45, 0x43, 0x43, 0x10, 0x23, 0x2a, 0x57, 0x0a, 0x08, 0x48, 0x61, 0x73, 0x68,
+	0x41, 0x6c, 0x67, 0x6f, 0x12, 0x10, 0x0a, 0x0c, 0x48, 0x41, 0x53, 0x48, 0x5f, 0x49, 0x4e, 0x56,
+	0x41, 0x4c, 0x49, 0x44, 0x10, 0x00, 0x12, 0x08, 0x0a, 0x04, 0x53, 0x48, 0x41, 0x31, 0x10, 0x04,
+	0x12, 0x0a, 0x0a, 0x06, 0x53, 0x48, 0x41, 0x32, 0x35, 0x36, 0x10, 0x0b, 0x12, 0x0a, 0x0a, 0x06,
+	0x53, 0x48, 0x41, 0x33, 0x38, 0x34, 0x10, 0x0c, 0x12, 0x0a, 0x0a, 0x06, 0x53, 0x48, 0x41, 0x35,
+	0x31, 0x32, 0x10, 0x0d, 0x12, 0x0b, 0x0a, 0x07, 0x53, 0x4d, 0x33, 0x5f, 0x32, 0x35, 0x36, 0x10,
+	0x12, 0x42, 0x2a, 0x5a, 0x28, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x67, 0x6f, 0x2d, 0x74, 0x70, 0x6d, 0x2d, 0x74, 0x6f,
+	0x6f, 0x6c, 0x73, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x74, 0x70, 0x6d, 0x62, 0x06, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -493,29 +1119,43 @@ func file_tpm_proto_rawDescGZIP() []byte {
 }
 
 var file_tpm_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_tpm_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_tpm_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
 var file_tpm_proto_goTypes = []interface{}{
-	(ObjectType)(0),     // 0: tpm.ObjectType
-	(HashAlgo)(0),       // 1: tpm.HashAlgo
-	(*SealedBytes)(nil), // 2: tpm.SealedBytes
-	(*ImportBlob)(nil),  // 3: tpm.ImportBlob
-	(*Quote)(nil),       // 4: tpm.Quote
-	(*PCRs)(nil),        // 5: tpm.PCRs
-	nil,                 // 6: tpm.PCRs.PcrsEntry
+	(ObjectType)(0),               // 0: tpm.ObjectType
+	(HashAlgo)(0),                 // 1: tpm.HashAlgo
+	(*SealedBytes)(nil),           // 2: tpm.SealedBytes
+	(*BlobHeader)(nil),            // 3: tpm.BlobHeader
+	(*StreamEnvelope)(nil),        // 4: tpm.StreamEnvelope
+	(*ImportBlob)(nil),            // 5: tpm.ImportBlob
+	(*KeyBlob)(nil),               // 6: tpm.KeyBlob
+	(*Quote)(nil),                 // 7: tpm.Quote
+	(*SessionAudit)(nil),          // 8: tpm.SessionAudit
+	(*KeyCertification)(nil),      // 9: tpm.KeyCertification
+	(*CreationCertification)(nil), // 10: tpm.CreationCertification
+	(*AuditedCommand)(nil),        // 11: tpm.AuditedCommand
+	(*PCRs)(nil),                  // 12: tpm.PCRs
+	nil,                           // 13: tpm.PCRs.PcrsEntry
 }
 var file_tpm_proto_depIdxs = []int32{
-	1, // 0: tpm.SealedBytes.hash:type_name -> tpm.HashAlgo
-	0, // 1: tpm.SealedBytes.srk:type_name -> tpm.ObjectType
-	5, // 2: tpm.SealedBytes.certified_pcrs:type_name -> tpm.PCRs
-	5, // 3: tpm.ImportBlob.pcrs:type_name -> tpm.PCRs
-	5, // 4: tpm.Quote.pcrs:type_name -> tpm.PCRs
-	1, // 5: tpm.PCRs.hash:type_name -> tpm.HashAlgo
-	6, // 6: tpm.PCRs.pcrs:type_name -> tpm.PCRs.PcrsEntry
-	7, // [7:7] is the sub-list for method output_type
-	7, // [7:7] is the sub-list for method input_type
-	7, // [7:7] is the sub-list for extension type_name
-	7, // [7:7] is the sub-list for extension extendee
-	0, // [0:7] is the sub-list for field type_name
+	1,  // 0: tpm.SealedBytes.hash:type_name -> tpm.HashAlgo
+	0,  // 1: tpm.SealedBytes.srk:type_name -> tpm.ObjectType
+	12, // 2: tpm.SealedBytes.certified_pcrs:type_name -> tpm.PCRs
+	12, // 3: tpm.SealedBytes.alternative_pcrs:type_name -> tpm.PCRs
+	4,  // 4: tpm.SealedBytes.stream:type_name -> tpm.StreamEnvelope
+	3,  // 5: tpm.SealedBytes.header:type_name -> tpm.BlobHeader
+	1,  // 6: tpm.BlobHeader.pcr_bank:type_name -> tpm.HashAlgo
+	12, // 7: tpm.ImportBlob.pcrs:type_name -> tpm.PCRs
+	3,  // 8: tpm.ImportBlob.header:type_name -> tpm.BlobHeader
+	12, // 9: tpm.Quote.pcrs:type_name -> tpm.PCRs
+	1,  // 10: tpm.SessionAudit.hash:type_name -> tpm.HashAlgo
+	11, // 11: tpm.SessionAudit.commands:type_name -> tpm.AuditedCommand
+	1,  // 12: tpm.PCRs.hash:type_name -> tpm.HashAlgo
+	13, // 13: tpm.PCRs.pcrs:type_name -> tpm.PCRs.PcrsEntry
+	14, // [14:14] is the sub-list for method output_type
+	14, // [14:14] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
 }
 
 func init() { file_tpm_proto_init() }
@@ -537,7 +1177,7 @@ func file_tpm_proto_init() {
 			}
 		}
 		file_tpm_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ImportBlob); i {
+			switch v := v.(*BlobHeader); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -549,7 +1189,7 @@ func file_tpm_proto_init() {
 			}
 		}
 		file_tpm_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Quote); i {
+			switch v := v.(*StreamEnvelope); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -561,6 +1201,90 @@ func file_tpm_proto_init() {
 			}
 		}
 		file_tpm_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ImportBlob); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tpm_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*KeyBlob); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tpm_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Quote); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tpm_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SessionAudit); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tpm_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*KeyCertification); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tpm_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CreationCertification); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tpm_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AuditedCommand); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tpm_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*PCRs); i {
 			case 0:
 				return &v.state
@@ -579,7 +1303,7 @@ func file_tpm_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_tpm_proto_rawDesc,
 			NumEnums:      2,
-			NumMessages:   5,
+			NumMessages:   12,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
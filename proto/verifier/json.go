@@ -0,0 +1,79 @@
+package verifier
+
+import (
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// marshalJSON encodes m with protojson, the canonical JSON mapping of protocol
+// buffers. The encoding is not byte-for-byte stable, but always decodes to the
+// same message.
+func marshalJSON(m proto.Message) ([]byte, error) {
+	return protojson.Marshal(m)
+}
+
+// unmarshalJSON decodes m with protojson, ignoring unknown fields so that JSON
+// produced by newer versions can still be decoded.
+func unmarshalJSON(data []byte, m proto.Message) error {
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, m)
+}
+
+// MarshalJSON encodes the ChallengeRequest as JSON, using protojson.
+func (x *ChallengeRequest) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the ChallengeRequest from JSON, using protojson.
+func (x *ChallengeRequest) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the ChallengeResponse as JSON, using protojson.
+func (x *ChallengeResponse) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the ChallengeResponse from JSON, using protojson.
+func (x *ChallengeResponse) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the SubmitEvidenceRequest as JSON, using protojson.
+func (x *SubmitEvidenceRequest) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the SubmitEvidenceRequest from JSON, using protojson.
+func (x *SubmitEvidenceRequest) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the SubmitEvidenceResponse as JSON, using protojson.
+func (x *SubmitEvidenceResponse) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the SubmitEvidenceResponse from JSON, using protojson.
+func (x *SubmitEvidenceResponse) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the GetResultRequest as JSON, using protojson.
+func (x *GetResultRequest) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the GetResultRequest from JSON, using protojson.
+func (x *GetResultRequest) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the GetResultResponse as JSON, using protojson.
+func (x *GetResultResponse) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the GetResultResponse from JSON, using protojson.
+func (x *GetResultResponse) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the GetCredentialRequest as JSON, using protojson.
+func (x *GetCredentialRequest) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the GetCredentialRequest from JSON, using protojson.
+func (x *GetCredentialRequest) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the GetCredentialResponse as JSON, using protojson.
+func (x *GetCredentialResponse) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the GetCredentialResponse from JSON, using protojson.
+func (x *GetCredentialResponse) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the ReleaseSecretRequest as JSON, using protojson.
+func (x *ReleaseSecretRequest) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the ReleaseSecretRequest from JSON, using protojson.
+func (x *ReleaseSecretRequest) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
+
+// MarshalJSON encodes the ReleaseSecretResponse as JSON, using protojson.
+func (x *ReleaseSecretResponse) MarshalJSON() ([]byte, error) { return marshalJSON(x) }
+
+// UnmarshalJSON decodes the ReleaseSecretResponse from JSON, using protojson.
+func (x *ReleaseSecretResponse) UnmarshalJSON(data []byte) error { return unmarshalJSON(data, x) }
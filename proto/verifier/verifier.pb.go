@@ -0,0 +1,925 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.27.1
+// 	protoc        v3.17.3
+// source: verifier.proto
+
+package verifier
+
+import (
+	attest "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+	tpm "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// The encodings of attestation results.
+type ResultFormat int32
+
+const (
+	// A CBOR Web Token, signed by the verifier in a COSE_Sign1 message.
+	ResultFormat_CWT ResultFormat = 0
+	// A JSON Web Token, signed by the verifier in a JWS.
+	ResultFormat_JWT ResultFormat = 1
+)
+
+// Enum value maps for ResultFormat.
+var (
+	ResultFormat_name = map[int32]string{
+		0: "CWT",
+		1: "JWT",
+	}
+	ResultFormat_value = map[string]int32{
+		"CWT": 0,
+		"JWT": 1,
+	}
+)
+
+func (x ResultFormat) Enum() *ResultFormat {
+	p := new(ResultFormat)
+	*p = x
+	return p
+}
+
+func (x ResultFormat) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ResultFormat) Descriptor() protoreflect.EnumDescriptor {
+	return file_verifier_proto_enumTypes[0].Descriptor()
+}
+
+func (ResultFormat) Type() protoreflect.EnumType {
+	return &file_verifier_proto_enumTypes[0]
+}
+
+func (x ResultFormat) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ResultFormat.Descriptor instead.
+func (ResultFormat) EnumDescriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{0}
+}
+
+// A request for a new challenge.
+type ChallengeRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ChallengeRequest) Reset() {
+	*x = ChallengeRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_verifier_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ChallengeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChallengeRequest) ProtoMessage() {}
+
+func (x *ChallengeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChallengeRequest.ProtoReflect.Descriptor instead.
+func (*ChallengeRequest) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{0}
+}
+
+// A challenge issued by the verifier, which must be answered with evidence
+// before it expires.
+type ChallengeResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Identifies the challenge in the SubmitEvidence and GetResult requests.
+	ChallengeId string `protobuf:"bytes,1,opt,name=challenge_id,json=challengeId,proto3" json:"challenge_id,omitempty"`
+	// The nonce to attest with (see client.Key.Attest and
+	// client.COSESigner.SignEAT).
+	Nonce []byte `protobuf:"bytes,2,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	// The time (in seconds since the Unix epoch) after which the challenge can
+	// no longer be answered.
+	Expires int64 `protobuf:"varint,3,opt,name=expires,proto3" json:"expires,omitempty"`
+}
+
+func (x *ChallengeResponse) Reset() {
+	*x = ChallengeResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_verifier_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ChallengeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChallengeResponse) ProtoMessage() {}
+
+func (x *ChallengeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChallengeResponse.ProtoReflect.Descriptor instead.
+func (*ChallengeResponse) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ChallengeResponse) GetChallengeId() string {
+	if x != nil {
+		return x.ChallengeId
+	}
+	return ""
+}
+
+func (x *ChallengeResponse) GetNonce() []byte {
+	if x != nil {
+		return x.Nonce
+	}
+	return nil
+}
+
+func (x *ChallengeResponse) GetExpires() int64 {
+	if x != nil {
+		return x.Expires
+	}
+	return 0
+}
+
+// The evidence answering a challenge. Each challenge can only be answered
+// once.
+type SubmitEvidenceRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ChallengeId string `protobuf:"bytes,1,opt,name=challenge_id,json=challengeId,proto3" json:"challenge_id,omitempty"`
+	// Types that are assignable to Evidence:
+	//	*SubmitEvidenceRequest_Attestation
+	//	*SubmitEvidenceRequest_Eat
+	Evidence isSubmitEvidenceRequest_Evidence `protobuf_oneof:"evidence"`
+}
+
+func (x *SubmitEvidenceRequest) Reset() {
+	*x = SubmitEvidenceRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_verifier_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SubmitEvidenceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitEvidenceRequest) ProtoMessage() {}
+
+func (x *SubmitEvidenceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitEvidenceRequest.ProtoReflect.Descriptor instead.
+func (*SubmitEvidenceRequest) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SubmitEvidenceRequest) GetChallengeId() string {
+	if x != nil {
+		return x.ChallengeId
+	}
+	return ""
+}
+
+func (m *SubmitEvidenceRequest) GetEvidence() isSubmitEvidenceRequest_Evidence {
+	if m != nil {
+		return m.Evidence
+	}
+	return nil
+}
+
+func (x *SubmitEvidenceRequest) GetAttestation() *attest.Attestation {
+	if x, ok := x.GetEvidence().(*SubmitEvidenceRequest_Attestation); ok {
+		return x.Attestation
+	}
+	return nil
+}
+
+func (x *SubmitEvidenceRequest) GetEat() []byte {
+	if x, ok := x.GetEvidence().(*SubmitEvidenceRequest_Eat); ok {
+		return x.Eat
+	}
+	return nil
+}
+
+type isSubmitEvidenceRequest_Evidence interface {
+	isSubmitEvidenceRequest_Evidence()
+}
+
+type SubmitEvidenceRequest_Attestation struct {
+	// An Attestation, as returned by client.Key.Attest.
+	Attestation *attest.Attestation `protobuf:"bytes,2,opt,name=attestation,proto3,oneof"`
+}
+
+type SubmitEvidenceRequest_Eat struct {
+	// An Entity Attestation Token, as returned by client.COSESigner.SignEAT.
+	Eat []byte `protobuf:"bytes,3,opt,name=eat,proto3,oneof"`
+}
+
+func (*SubmitEvidenceRequest_Attestation) isSubmitEvidenceRequest_Evidence() {}
+
+func (*SubmitEvidenceRequest_Eat) isSubmitEvidenceRequest_Evidence() {}
+
+// The outcome of the appraisal of the evidence.
+type SubmitEvidenceResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The overall trustworthiness tier of the appraisal ("affirming", "warning",
+	// "contraindicated" or "none").
+	Status string `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	// The reason the evidence was not verified, if it was not.
+	Error string `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *SubmitEvidenceResponse) Reset() {
+	*x = SubmitEvidenceResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_verifier_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SubmitEvidenceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitEvidenceResponse) ProtoMessage() {}
+
+func (x *SubmitEvidenceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitEvidenceResponse.ProtoReflect.Descriptor instead.
+func (*SubmitEvidenceResponse) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SubmitEvidenceResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *SubmitEvidenceResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// A request for the attestation result of a challenge.
+type GetResultRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ChallengeId string       `protobuf:"bytes,1,opt,name=challenge_id,json=challengeId,proto3" json:"challenge_id,omitempty"`
+	Format      ResultFormat `protobuf:"varint,2,opt,name=format,proto3,enum=verifier.ResultFormat" json:"format,omitempty"`
+}
+
+func (x *GetResultRequest) Reset() {
+	*x = GetResultRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_verifier_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetResultRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetResultRequest) ProtoMessage() {}
+
+func (x *GetResultRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetResultRequest.ProtoReflect.Descriptor instead.
+func (*GetResultRequest) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetResultRequest) GetChallengeId() string {
+	if x != nil {
+		return x.ChallengeId
+	}
+	return ""
+}
+
+func (x *GetResultRequest) GetFormat() ResultFormat {
+	if x != nil {
+		return x.Format
+	}
+	return ResultFormat_CWT
+}
+
+// The attestation result of a challenge, once evidence has been submitted.
+type GetResultResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The EAT Attestation Result (EAR) of the appraisal, signed by the verifier
+	// in the requested format (for a JWT, the compact serialization).
+	Token []byte `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+}
+
+func (x *GetResultResponse) Reset() {
+	*x = GetResultResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_verifier_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetResultResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetResultResponse) ProtoMessage() {}
+
+func (x *GetResultResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetResultResponse.ProtoReflect.Descriptor instead.
+func (*GetResultResponse) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetResultResponse) GetToken() []byte {
+	if x != nil {
+		return x.Token
+	}
+	return nil
+}
+
+// A request for a credential, proving that the AK of an affirmed challenge is
+// in the same TPM as the Endorsement Key (EK) certified by the EK certificate
+// of the attestation.
+type GetCredentialRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ChallengeId string `protobuf:"bytes,1,opt,name=challenge_id,json=challengeId,proto3" json:"challenge_id,omitempty"`
+}
+
+func (x *GetCredentialRequest) Reset() {
+	*x = GetCredentialRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_verifier_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetCredentialRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCredentialRequest) ProtoMessage() {}
+
+func (x *GetCredentialRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCredentialRequest.ProtoReflect.Descriptor instead.
+func (*GetCredentialRequest) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetCredentialRequest) GetChallengeId() string {
+	if x != nil {
+		return x.ChallengeId
+	}
+	return ""
+}
+
+// A credential for the AK of the attestation, encrypted to the EK (see
+// server.MakeCredential). It is activated with client.Key.ActivateCredential
+// on the AK, which the TPM only allows if the AK and the EK are both loaded
+// in it.
+type GetCredentialResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The TPM2B_ID_OBJECT and TPM2B_ENCRYPTED_SECRET of the credential.
+	CredBlob  []byte `protobuf:"bytes,1,opt,name=cred_blob,json=credBlob,proto3" json:"cred_blob,omitempty"`
+	EncSecret []byte `protobuf:"bytes,2,opt,name=enc_secret,json=encSecret,proto3" json:"enc_secret,omitempty"`
+}
+
+func (x *GetCredentialResponse) Reset() {
+	*x = GetCredentialResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_verifier_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetCredentialResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCredentialResponse) ProtoMessage() {}
+
+func (x *GetCredentialResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCredentialResponse.ProtoReflect.Descriptor instead.
+func (*GetCredentialResponse) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetCredentialResponse) GetCredBlob() []byte {
+	if x != nil {
+		return x.CredBlob
+	}
+	return nil
+}
+
+func (x *GetCredentialResponse) GetEncSecret() []byte {
+	if x != nil {
+		return x.EncSecret
+	}
+	return nil
+}
+
+// A request for a secret held by the verifier, to be released to the attester
+// of an answered challenge.
+type ReleaseSecretRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ChallengeId string `protobuf:"bytes,1,opt,name=challenge_id,json=challengeId,proto3" json:"challenge_id,omitempty"`
+	// Identifies the secret in the verifier's configuration.
+	SecretId string `protobuf:"bytes,2,opt,name=secret_id,json=secretId,proto3" json:"secret_id,omitempty"`
+	// The activated secret of the last credential of the challenge (see
+	// GetCredentialRequest).
+	Credential []byte `protobuf:"bytes,3,opt,name=credential,proto3" json:"credential,omitempty"`
+}
+
+func (x *ReleaseSecretRequest) Reset() {
+	*x = ReleaseSecretRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_verifier_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReleaseSecretRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseSecretRequest) ProtoMessage() {}
+
+func (x *ReleaseSecretRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseSecretRequest.ProtoReflect.Descriptor instead.
+func (*ReleaseSecretRequest) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ReleaseSecretRequest) GetChallengeId() string {
+	if x != nil {
+		return x.ChallengeId
+	}
+	return ""
+}
+
+func (x *ReleaseSecretRequest) GetSecretId() string {
+	if x != nil {
+		return x.SecretId
+	}
+	return ""
+}
+
+func (x *ReleaseSecretRequest) GetCredential() []byte {
+	if x != nil {
+		return x.Credential
+	}
+	return nil
+}
+
+// A secret released after a successful attestation.
+type ReleaseSecretResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The secret, encrypted to the Endorsement Key (EK) certified by the EK
+	// certificate of the attestation, so that it can only be decrypted with
+	// client.Key.Import by the attested TPM.
+	Blob *tpm.ImportBlob `protobuf:"bytes,1,opt,name=blob,proto3" json:"blob,omitempty"`
+}
+
+func (x *ReleaseSecretResponse) Reset() {
+	*x = ReleaseSecretResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_verifier_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReleaseSecretResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseSecretResponse) ProtoMessage() {}
+
+func (x *ReleaseSecretResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseSecretResponse.ProtoReflect.Descriptor instead.
+func (*ReleaseSecretResponse) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ReleaseSecretResponse) GetBlob() *tpm.ImportBlob {
+	if x != nil {
+		return x.Blob
+	}
+	return nil
+}
+
+var File_verifier_proto protoreflect.FileDescriptor
+
+var file_verifier_proto_rawDesc = []byte{
+	0x0a, 0x0e, 0x76, 0x65, 0x72, 0x69, 0x66, 0x69, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x12, 0x08, 0x76, 0x65, 0x72, 0x69, 0x66, 0x69, 0x65, 0x72, 0x1a, 0x0c, 0x61, 0x74, 0x74, 0x65,
+	0x73, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x09, 0x74, 0x70, 0x6d, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x22, 0x12, 0x0a, 0x10, 0x43, 0x68, 0x61, 0x6c, 0x6c, 0x65, 0x6e, 0x67, 0x65,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x66, 0x0a, 0x11, 0x43, 0x68, 0x61, 0x6c, 0x6c,
+	0x65, 0x6e, 0x67, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x21, 0x0a, 0x0c,
+	0x63, 0x68, 0x61, 0x6c, 0x6c, 0x65, 0x6e, 0x67, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0b, 0x63, 0x68, 0x61, 0x6c, 0x6c, 0x65, 0x6e, 0x67, 0x65, 0x49, 0x64, 0x12,
+	0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05,
+	0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73, 0x22,
+	0x93, 0x01, 0x0a, 0x15, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74, 0x45, 0x76, 0x69, 0x64, 0x65, 0x6e,
+	0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x63, 0x68, 0x61,
+	0x6c, 0x6c, 0x65, 0x6e, 0x67, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0b, 0x63, 0x68, 0x61, 0x6c, 0x6c, 0x65, 0x6e, 0x67, 0x65, 0x49, 0x64, 0x12, 0x37, 0x0a, 0x0b,
+	0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x13, 0x2e, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x2e, 0x41, 0x74, 0x74, 0x65, 0x73,
+	0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x48, 0x00, 0x52, 0x0b, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x03, 0x65, 0x61, 0x74, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0c, 0x48, 0x00, 0x52, 0x03, 0x65, 0x61, 0x74, 0x42, 0x0a, 0x0a, 0x08, 0x65, 0x76, 0x69,
+	0x64, 0x65, 0x6e, 0x63, 0x65, 0x22, 0x46, 0x0a, 0x16, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74, 0x45,
+	0x76, 0x69, 0x64, 0x65, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x16, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x65, 0x0a,
+	0x10, 0x47, 0x65, 0x74, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x21, 0x0a, 0x0c, 0x63, 0x68, 0x61, 0x6c, 0x6c, 0x65, 0x6e, 0x67, 0x65, 0x5f, 0x69,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x63, 0x68, 0x61, 0x6c, 0x6c, 0x65, 0x6e,
+	0x67, 0x65, 0x49, 0x64, 0x12, 0x2e, 0x0a, 0x06, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x16, 0x2e, 0x76, 0x65, 0x72, 0x69, 0x66, 0x69, 0x65, 0x72, 0x2e,
+	0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x46, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x52, 0x06, 0x66, 0x6f,
+	0x72, 0x6d, 0x61, 0x74, 0x22, 0x29, 0x0a, 0x11, 0x47, 0x65, 0x74, 0x52, 0x65, 0x73, 0x75, 0x6c,
+	0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x6b,
+	0x65, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x22,
+	0x39, 0x0a, 0x14, 0x47, 0x65, 0x74, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x63, 0x68, 0x61, 0x6c, 0x6c,
+	0x65, 0x6e, 0x67, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x63,
+	0x68, 0x61, 0x6c, 0x6c, 0x65, 0x6e, 0x67, 0x65, 0x49, 0x64, 0x22, 0x53, 0x0a, 0x15, 0x47, 0x65,
+	0x74, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x63, 0x72, 0x65, 0x64, 0x5f, 0x62, 0x6c, 0x6f, 0x62,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x63, 0x72, 0x65, 0x64, 0x42, 0x6c, 0x6f, 0x62,
+	0x12, 0x1d, 0x0a, 0x0a, 0x65, 0x6e, 0x63, 0x5f, 0x73, 0x65, 0x63, 0x72, 0x65, 0x74, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x65, 0x6e, 0x63, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x22,
+	0x76, 0x0a, 0x14, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x63, 0x68, 0x61, 0x6c, 0x6c,
+	0x65, 0x6e, 0x67, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x63,
+	0x68, 0x61, 0x6c, 0x6c, 0x65, 0x6e, 0x67, 0x65, 0x49, 0x64, 0x12, 0x1b, 0x0a, 0x09, 0x73, 0x65,
+	0x63, 0x72, 0x65, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x73,
+	0x65, 0x63, 0x72, 0x65, 0x74, 0x49, 0x64, 0x12, 0x1e, 0x0a, 0x0a, 0x63, 0x72, 0x65, 0x64, 0x65,
+	0x6e, 0x74, 0x69, 0x61, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0a, 0x63, 0x72, 0x65,
+	0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x22, 0x3c, 0x0a, 0x15, 0x52, 0x65, 0x6c, 0x65, 0x61,
+	0x73, 0x65, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x23, 0x0a, 0x04, 0x62, 0x6c, 0x6f, 0x62, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f,
+	0x2e, 0x74, 0x70, 0x6d, 0x2e, 0x49, 0x6d, 0x70, 0x6f, 0x72, 0x74, 0x42, 0x6c, 0x6f, 0x62, 0x52,
+	0x04, 0x62, 0x6c, 0x6f, 0x62, 0x2a, 0x20, 0x0a, 0x0c, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x46,
+	0x6f, 0x72, 0x6d, 0x61, 0x74, 0x12, 0x07, 0x0a, 0x03, 0x43, 0x57, 0x54, 0x10, 0x00, 0x12, 0x07,
+	0x0a, 0x03, 0x4a, 0x57, 0x54, 0x10, 0x01, 0x42, 0x2f, 0x5a, 0x2d, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x67, 0x6f, 0x2d,
+	0x74, 0x70, 0x6d, 0x2d, 0x74, 0x6f, 0x6f, 0x6c, 0x73, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f,
+	0x76, 0x65, 0x72, 0x69, 0x66, 0x69, 0x65, 0x72, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_verifier_proto_rawDescOnce sync.Once
+	file_verifier_proto_rawDescData = file_verifier_proto_rawDesc
+)
+
+func file_verifier_proto_rawDescGZIP() []byte {
+	file_verifier_proto_rawDescOnce.Do(func() {
+		file_verifier_proto_rawDescData = protoimpl.X.CompressGZIP(file_verifier_proto_rawDescData)
+	})
+	return file_verifier_proto_rawDescData
+}
+
+var file_verifier_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_verifier_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_verifier_proto_goTypes = []interface{}{
+	(ResultFormat)(0),              // 0: verifier.ResultFormat
+	(*ChallengeRequest)(nil),       // 1: verifier.ChallengeRequest
+	(*ChallengeResponse)(nil),      // 2: verifier.ChallengeResponse
+	(*SubmitEvidenceRequest)(nil),  // 3: verifier.SubmitEvidenceRequest
+	(*SubmitEvidenceResponse)(nil), // 4: verifier.SubmitEvidenceResponse
+	(*GetResultRequest)(nil),       // 5: verifier.GetResultRequest
+	(*GetResultResponse)(nil),      // 6: verifier.GetResultResponse
+	(*GetCredentialRequest)(nil),   // 7: verifier.GetCredentialRequest
+	(*GetCredentialResponse)(nil),  // 8: verifier.GetCredentialResponse
+	(*ReleaseSecretRequest)(nil),   // 9: verifier.ReleaseSecretRequest
+	(*ReleaseSecretResponse)(nil),  // 10: verifier.ReleaseSecretResponse
+	(*attest.Attestation)(nil),     // 11: attest.Attestation
+	(*tpm.ImportBlob)(nil),         // 12: tpm.ImportBlob
+}
+var file_verifier_proto_depIdxs = []int32{
+	11, // 0: verifier.SubmitEvidenceRequest.attestation:type_name -> attest.Attestation
+	0,  // 1: verifier.GetResultRequest.format:type_name -> verifier.ResultFormat
+	12, // 2: verifier.ReleaseSecretResponse.blob:type_name -> tpm.ImportBlob
+	3,  // [3:3] is the sub-list for method output_type
+	3,  // [3:3] is the sub-list for method input_type
+	3,  // [3:3] is the sub-list for extension type_name
+	3,  // [3:3] is the sub-list for extension extendee
+	0,  // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_verifier_proto_init() }
+func file_verifier_proto_init() {
+	if File_verifier_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_verifier_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ChallengeRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_verifier_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ChallengeResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_verifier_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SubmitEvidenceRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_verifier_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SubmitEvidenceResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_verifier_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetResultRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_verifier_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetResultResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_verifier_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetCredentialRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_verifier_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetCredentialResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_verifier_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReleaseSecretRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_verifier_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReleaseSecretResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_verifier_proto_msgTypes[2].OneofWrappers = []interface{}{
+		(*SubmitEvidenceRequest_Attestation)(nil),
+		(*SubmitEvidenceRequest_Eat)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_verifier_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_verifier_proto_goTypes,
+		DependencyIndexes: file_verifier_proto_depIdxs,
+		EnumInfos:         file_verifier_proto_enumTypes,
+		MessageInfos:      file_verifier_proto_msgTypes,
+	}.Build()
+	File_verifier_proto = out.File
+	file_verifier_proto_rawDesc = nil
+	file_verifier_proto_goTypes = nil
+	file_verifier_proto_depIdxs = nil
+}
+
@@ -0,0 +1,185 @@
+package proxy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// Command codes which are not defined by go-tpm, from "TPM 2.0 Part 2:
+// Structures", 6.5.2 TPM_CC.
+const (
+	cmdSelfTest          tpmutil.Command = 0x00000143
+	cmdStirRandom        tpmutil.Command = 0x00000146
+	cmdPolicyNV          tpmutil.Command = 0x00000149
+	cmdHMAC              tpmutil.Command = 0x00000155
+	cmdPolicySigned      tpmutil.Command = 0x00000160
+	cmdPolicyAuthorize   tpmutil.Command = 0x0000016A
+	cmdPolicyAuthValue   tpmutil.Command = 0x0000016B
+	cmdPolicyCpHash      tpmutil.Command = 0x0000016E
+	cmdPolicyLocality    tpmutil.Command = 0x0000016F
+	cmdPolicyNameHash    tpmutil.Command = 0x00000170
+	cmdPolicyRestart     tpmutil.Command = 0x00000180
+	cmdTestParms         tpmutil.Command = 0x0000018A
+	cmdGetTestResult     tpmutil.Command = 0x0000017C
+	cmdCertifyX509       tpmutil.Command = 0x00000197
+	cmdPolicyDuplication tpmutil.Command = 0x00000188
+)
+
+var commandNames = map[tpmutil.Command]string{
+	tpm2.CmdNVUndefineSpaceSpecial:     "NV_UndefineSpaceSpecial",
+	tpm2.CmdEvictControl:               "EvictControl",
+	tpm2.CmdUndefineSpace:              "NV_UndefineSpace",
+	tpm2.CmdClear:                      "Clear",
+	tpm2.CmdHierarchyChangeAuth:        "HierarchyChangeAuth",
+	tpm2.CmdDefineSpace:                "NV_DefineSpace",
+	tpm2.CmdCreatePrimary:              "CreatePrimary",
+	tpm2.CmdIncrementNVCounter:         "NV_Increment",
+	tpm2.CmdWriteNV:                    "NV_Write",
+	tpm2.CmdWriteLockNV:                "NV_WriteLock",
+	tpm2.CmdDictionaryAttackLockReset:  "DictionaryAttackLockReset",
+	tpm2.CmdDictionaryAttackParameters: "DictionaryAttackParameters",
+	tpm2.CmdPCREvent:                   "PCR_Event",
+	tpm2.CmdSequenceComplete:           "SequenceComplete",
+	cmdSelfTest:                        "SelfTest",
+	tpm2.CmdStartup:                    "Startup",
+	tpm2.CmdShutdown:                   "Shutdown",
+	cmdStirRandom:                      "StirRandom",
+	tpm2.CmdActivateCredential:         "ActivateCredential",
+	tpm2.CmdCertify:                    "Certify",
+	cmdPolicyNV:                        "PolicyNV",
+	tpm2.CmdCertifyCreation:            "CertifyCreation",
+	tpm2.CmdReadNV:                     "NV_Read",
+	tpm2.CmdReadLockNV:                 "NV_ReadLock",
+	tpm2.CmdPolicySecret:               "PolicySecret",
+	tpm2.CmdCreate:                     "Create",
+	tpm2.CmdECDHZGen:                   "ECDH_ZGen",
+	cmdHMAC:                            "HMAC",
+	tpm2.CmdImport:                     "Import",
+	tpm2.CmdLoad:                       "Load",
+	tpm2.CmdQuote:                      "Quote",
+	tpm2.CmdRSADecrypt:                 "RSA_Decrypt",
+	tpm2.CmdSequenceUpdate:             "SequenceUpdate",
+	tpm2.CmdSign:                       "Sign",
+	tpm2.CmdUnseal:                     "Unseal",
+	cmdPolicySigned:                    "PolicySigned",
+	tpm2.CmdContextLoad:                "ContextLoad",
+	tpm2.CmdContextSave:                "ContextSave",
+	tpm2.CmdECDHKeyGen:                 "ECDH_KeyGen",
+	tpm2.CmdEncryptDecrypt:             "EncryptDecrypt",
+	tpm2.CmdFlushContext:               "FlushContext",
+	tpm2.CmdLoadExternal:               "LoadExternal",
+	tpm2.CmdMakeCredential:             "MakeCredential",
+	tpm2.CmdReadPublicNV:               "NV_ReadPublic",
+	cmdPolicyAuthorize:                 "PolicyAuthorize",
+	cmdPolicyAuthValue:                 "PolicyAuthValue",
+	tpm2.CmdPolicyCommandCode:          "PolicyCommandCode",
+	cmdPolicyCpHash:                    "PolicyCpHash",
+	cmdPolicyLocality:                  "PolicyLocality",
+	cmdPolicyNameHash:                  "PolicyNameHash",
+	tpm2.CmdPolicyOr:                   "PolicyOR",
+	tpm2.CmdReadPublic:                 "ReadPublic",
+	tpm2.CmdRSAEncrypt:                 "RSA_Encrypt",
+	tpm2.CmdStartAuthSession:           "StartAuthSession",
+	tpm2.CmdGetCapability:              "GetCapability",
+	tpm2.CmdGetRandom:                  "GetRandom",
+	cmdGetTestResult:                   "GetTestResult",
+	tpm2.CmdHash:                       "Hash",
+	tpm2.CmdPCRRead:                    "PCR_Read",
+	tpm2.CmdPolicyPCR:                  "PolicyPCR",
+	cmdPolicyRestart:                   "PolicyRestart",
+	tpm2.CmdReadClock:                  "ReadClock",
+	tpm2.CmdPCRExtend:                  "PCR_Extend",
+	tpm2.CmdEventSequenceComplete:      "EventSequenceComplete",
+	tpm2.CmdHashSequenceStart:          "HashSequenceStart",
+	cmdPolicyDuplication:               "PolicyDuplicationSelect",
+	tpm2.CmdPolicyGetDigest:            "PolicyGetDigest",
+	cmdTestParms:                       "TestParms",
+	tpm2.CmdPolicyPassword:             "PolicyPassword",
+	tpm2.CmdEncryptDecrypt2:            "EncryptDecrypt2",
+	cmdCertifyX509:                     "CertifyX509",
+}
+
+// DefaultAllowedCommands are the commands allowed for clients which do not
+// have an explicit allow-list. They allow creating and using keys (including
+// attestation and sealing), reading PCRs and NV indices, and managing
+// sessions, but not changing the TPM's persistent state: commands which clear
+// or reconfigure the TPM, change hierarchy authorizations, persist or evict
+// objects, write NV indices, or extend PCRs are not allowed.
+var DefaultAllowedCommands = []tpmutil.Command{
+	tpm2.CmdGetCapability,
+	tpm2.CmdGetRandom,
+	cmdGetTestResult,
+	tpm2.CmdReadClock,
+	tpm2.CmdPCRRead,
+	tpm2.CmdReadPublic,
+	tpm2.CmdReadPublicNV,
+	tpm2.CmdReadNV,
+	tpm2.CmdCreatePrimary,
+	tpm2.CmdCreate,
+	tpm2.CmdLoad,
+	tpm2.CmdLoadExternal,
+	tpm2.CmdImport,
+	tpm2.CmdFlushContext,
+	tpm2.CmdContextSave,
+	tpm2.CmdContextLoad,
+	tpm2.CmdStartAuthSession,
+	tpm2.CmdSign,
+	tpm2.CmdQuote,
+	tpm2.CmdCertify,
+	tpm2.CmdCertifyCreation,
+	tpm2.CmdActivateCredential,
+	tpm2.CmdMakeCredential,
+	tpm2.CmdUnseal,
+	tpm2.CmdRSADecrypt,
+	tpm2.CmdRSAEncrypt,
+	tpm2.CmdECDHZGen,
+	tpm2.CmdECDHKeyGen,
+	cmdHMAC,
+	tpm2.CmdHash,
+	tpm2.CmdHashSequenceStart,
+	tpm2.CmdSequenceUpdate,
+	tpm2.CmdSequenceComplete,
+	tpm2.CmdPolicyPCR,
+	tpm2.CmdPolicySecret,
+	cmdPolicySigned,
+	tpm2.CmdPolicyOr,
+	tpm2.CmdPolicyCommandCode,
+	tpm2.CmdPolicyPassword,
+	cmdPolicyAuthValue,
+	cmdPolicyAuthorize,
+	cmdPolicyNV,
+	cmdPolicyCpHash,
+	cmdPolicyNameHash,
+	cmdPolicyLocality,
+	cmdPolicyRestart,
+	tpm2.CmdPolicyGetDigest,
+}
+
+// CommandName returns the name of a TPM command code without the "TPM2_"
+// prefix (for example, "PCR_Read"), or its hexadecimal value if it is unknown.
+func CommandName(cmd tpmutil.Command) string {
+	if name, ok := commandNames[cmd]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%08x", uint32(cmd))
+}
+
+// ParseCommand parses a TPM command, given by its name (with or without the
+// "TPM2_" prefix, ignoring case) or by its numeric command code.
+func ParseCommand(s string) (tpmutil.Command, error) {
+	name := strings.TrimPrefix(strings.ToLower(s), "tpm2_")
+	for cmd, cmdName := range commandNames {
+		if strings.ToLower(cmdName) == name {
+			return cmd, nil
+		}
+	}
+	code, err := strconv.ParseUint(s, 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("unknown TPM command %q", s)
+	}
+	return tpmutil.Command(code), nil
+}
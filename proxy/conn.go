@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/proxy"
+)
+
+// IsProxyURL reports whether path is the URL of a Server (an http:// or
+// https:// URL).
+func IsProxyURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// Conn is a TPM accessed through a Server. Each command is sent in its own
+// HTTP request, so a Conn does not hold a network connection open between
+// commands.
+type Conn struct {
+	url        string
+	token      string
+	httpClient *http.Client
+	resp       bytes.Reader
+}
+
+// Dial returns a Conn to the Server at rawURL (for example,
+// "https://host:8321"), authenticating with token. If httpClient is nil,
+// http.DefaultClient is used.
+func Dial(rawURL, token string, httpClient *http.Client) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TPM proxy URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported TPM proxy URL scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing host in TPM proxy URL %q", rawURL)
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + CommandPath
+	return &Conn{url: u.String(), token: token, httpClient: httpClient}, nil
+}
+
+// Write runs a command, which must be written in a single call to Write.
+func (c *Conn) Write(cmd []byte) (int, error) {
+	body, err := proto.Marshal(&pb.CommandRequest{Command: cmd})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	httpResp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("sending command to the TPM proxy: %w", err)
+	}
+	defer httpResp.Body.Close()
+	respBody, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("reading TPM proxy response: %w", err)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("TPM proxy returned %s: %s", httpResp.Status, bytes.TrimSpace(respBody))
+	}
+	var resp pb.CommandResponse
+	if err = proto.Unmarshal(respBody, &resp); err != nil {
+		return 0, fmt.Errorf("decoding TPM proxy response: %w", err)
+	}
+	c.resp.Reset(resp.GetResponse())
+	return len(cmd), nil
+}
+
+// Read reads the response to the last command, which is kept for the next
+// calls to Read if it is longer than p.
+func (c *Conn) Read(p []byte) (int, error) {
+	return c.resp.Read(p)
+}
+
+// Close closes the idle network connections to the Server.
+func (c *Conn) Close() error {
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,137 @@
+package proxy_test
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	"github.com/ThalesIgnite/go-tpm-tools/proxy"
+)
+
+// Serve tpm to the clients, returning the server's URL and the audit log.
+func serveProxy(t *testing.T, tpm io.ReadWriter, clients []proxy.Client) (string, *bytes.Buffer) {
+	t.Helper()
+	auditLog := &bytes.Buffer{}
+	server, err := proxy.NewServer(tpm, clients, log.New(auditLog, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+	return httpServer.URL, auditLog
+}
+
+func TestProxy(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	url, auditLog := serveProxy(t, rwc, []proxy.Client{
+		{Name: "default", Token: "default-token"},
+		{Name: "random", Token: "random-token", AllowedCommands: []tpmutil.Command{tpm2.CmdGetRandom}},
+	})
+
+	conn, err := proxy.Dial(url, "default-token", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ak, err := client.NewKey(conn, tpm2.HandleOwner, client.AKTemplateECC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ak.Quote(client.FullPcrSel(tpm2.AlgSHA256), []byte("nonce")); err != nil {
+		t.Errorf("Quote failed: %v", err)
+	}
+	ak.Close()
+	// Changing the TPM's state is not allowed by default.
+	if err = tpm2.PCRExtend(conn, tpmutil.Handle(test.DebugPCR), tpm2.AlgSHA256, make([]byte, 32), ""); err == nil {
+		t.Error("PCR_Extend should not be allowed by default")
+	}
+
+	randomConn, err := proxy.Dial(url, "random-token", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer randomConn.Close()
+	if _, err = tpm2.GetRandom(randomConn, 16); err != nil {
+		t.Errorf("GetRandom failed: %v", err)
+	}
+	if _, err = tpm2.ReadPCR(randomConn, test.DebugPCR, tpm2.AlgSHA256); err == nil {
+		t.Error("PCR_Read should not be allowed")
+	}
+
+	for _, entry := range []string{
+		`client "default": TPM2_Quote returned 0x0`,
+		`client "default": denied TPM2_PCR_Extend`,
+		`client "random": TPM2_GetRandom returned 0x0`,
+		`client "random": denied TPM2_PCR_Read`,
+	} {
+		if !strings.Contains(auditLog.String(), entry) {
+			t.Errorf("audit log does not contain %q:\n%s", entry, auditLog)
+		}
+	}
+}
+
+func TestProxyInvalidToken(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	url, auditLog := serveProxy(t, rwc, []proxy.Client{{Name: "client", Token: "token"}})
+
+	for _, token := range []string{"", "tok", "other-token"} {
+		conn, err := proxy.Dial(url, token, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = tpm2.GetRandom(conn, 16); err == nil {
+			t.Errorf("GetRandom with token %q should fail", token)
+		}
+		conn.Close()
+	}
+	if !strings.Contains(auditLog.String(), "invalid token") {
+		t.Errorf("audit log does not contain the rejected requests:\n%s", auditLog)
+	}
+}
+
+func TestNewServerInvalidClients(t *testing.T) {
+	for _, clients := range [][]proxy.Client{
+		{{Name: "no-token"}},
+		{{Name: "a", Token: "token"}, {Name: "b", Token: "token"}},
+	} {
+		if _, err := proxy.NewServer(nil, clients, nil); err == nil {
+			t.Errorf("NewServer(%v) should fail", clients)
+		}
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  tpmutil.Command
+	}{
+		{"PCR_Read", tpm2.CmdPCRRead},
+		{"TPM2_Quote", tpm2.CmdQuote},
+		{"tpm2_nv_read", tpm2.CmdReadNV},
+		{"0x17b", tpm2.CmdGetRandom},
+	}
+	for _, test := range tests {
+		cmd, err := proxy.ParseCommand(test.name)
+		if err != nil {
+			t.Errorf("ParseCommand(%q) failed: %v", test.name, err)
+		} else if cmd != test.cmd {
+			t.Errorf("ParseCommand(%q) = 0x%x, want 0x%x", test.name, cmd, test.cmd)
+		}
+	}
+	if _, err := proxy.ParseCommand("NotACommand"); err == nil {
+		t.Error("ParseCommand should fail for unknown commands")
+	}
+	if name := proxy.CommandName(tpm2.CmdEvictControl); name != "EvictControl" {
+		t.Errorf("got name %q, want EvictControl", name)
+	}
+}
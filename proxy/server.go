@@ -0,0 +1,212 @@
+// Package proxy exposes a TPM over the network, so that processes without
+// access to the TPM device (such as containers) can use a host TPM through a
+// narrow, audited channel.
+//
+// The Server speaks a plain HTTP protocol (not gRPC): clients POST a
+// CommandRequest (see proto/proxy.proto) to CommandPath, authenticated with a
+// bearer token, and receive a CommandResponse. Each client can only run the
+// TPM commands in its allow-list. Every command is audit logged. Conn is an
+// io.ReadWriteCloser using a Server, which can be used by this module (and
+// go-tpm) in place of a TPM device.
+//
+// The allow-list only restricts which commands can be run, not the objects
+// they use: a client allowed to run TPM2_Sign can sign with any loaded key it
+// can authorize. The Server should use the kernel's resource manager
+// (/dev/tpmrm0) or TBS so that clients do not exhaust the TPM's object slots,
+// and clients must flush the handles they create.
+package proxy
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/go-tpm/tpmutil"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/proxy"
+)
+
+// CommandPath is the HTTP path to which TPM commands are POSTed.
+const CommandPath = "/v1/command"
+
+// The content type of requests and responses: binary encoded protobufs.
+const contentType = "application/x-protobuf"
+
+// The maximum size of TPM commands and responses. TPMs usually limit them to
+// 4096 bytes (TPM_PT_MAX_COMMAND_SIZE), so this leaves plenty of room.
+const maxMessageSize = 1 << 16
+
+// The size of the TPM command and response header: tag, size and command or
+// response code.
+const headerSize = 10
+
+// Client is a client of the Server.
+type Client struct {
+	// Name identifies the client in the audit log.
+	Name string
+	// Token is the secret bearer token authenticating the client.
+	Token string
+	// AllowedCommands are the commands the client can run. If nil,
+	// DefaultAllowedCommands is used.
+	AllowedCommands []tpmutil.Command
+}
+
+type clientPolicy struct {
+	name    string
+	token   []byte
+	allowed map[tpmutil.Command]bool
+}
+
+// Server runs TPM commands for authorized clients. It implements http.Handler,
+// serving TPM commands at CommandPath. Commands are run
+// one at a time.
+type Server struct {
+	mu      sync.Mutex
+	rw      io.ReadWriter
+	clients []clientPolicy
+	logger  *log.Logger
+}
+
+// NewServer returns a Server running the commands of the clients on the TPM
+// rw. Commands are audit logged to logger, unless it is nil.
+func NewServer(rw io.ReadWriter, clients []Client, logger *log.Logger) (*Server, error) {
+	s := &Server{rw: rw, logger: logger}
+	tokens := map[string]bool{}
+	for _, c := range clients {
+		if c.Token == "" {
+			return nil, fmt.Errorf("client %q has no token", c.Name)
+		}
+		if tokens[c.Token] {
+			return nil, fmt.Errorf("client %q has the same token as another client", c.Name)
+		}
+		tokens[c.Token] = true
+
+		allowedCommands := c.AllowedCommands
+		if allowedCommands == nil {
+			allowedCommands = DefaultAllowedCommands
+		}
+		allowed := make(map[tpmutil.Command]bool, len(allowedCommands))
+		for _, cmd := range allowedCommands {
+			allowed[cmd] = true
+		}
+		s.clients = append(s.clients, clientPolicy{name: c.Name, token: []byte(c.Token), allowed: allowed})
+	}
+	return s, nil
+}
+
+// Get the client authenticated by the request's bearer token.
+func (s *Server) authenticate(r *http.Request) *clientPolicy {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return nil
+	}
+	token := []byte(strings.TrimPrefix(auth, "Bearer "))
+	var client *clientPolicy
+	// Compare all the tokens, so that the time taken does not depend on
+	// which token matched.
+	for i := range s.clients {
+		if subtle.ConstantTimeCompare(token, s.clients[i].token) == 1 {
+			client = &s.clients[i]
+		}
+	}
+	return client
+}
+
+func (s *Server) logf(format string, v ...interface{}) {
+	if s.logger != nil {
+		s.logger.Printf(format, v...)
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != CommandPath {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	client := s.authenticate(r)
+	if client == nil {
+		s.logf("rejected request from %s: invalid token", r.RemoteAddr)
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageSize))
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	var req pb.CommandRequest
+	if err = proto.Unmarshal(body, &req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	cmd, err := parseCommandHeader(req.GetCommand())
+	if err != nil {
+		s.logf("client %q: invalid command: %v", client.name, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !client.allowed[cmd] {
+		s.logf("client %q: denied TPM2_%s", client.name, CommandName(cmd))
+		http.Error(w, fmt.Sprintf("TPM2_%s is not allowed", CommandName(cmd)), http.StatusForbidden)
+		return
+	}
+
+	resp, err := s.runCommand(req.GetCommand())
+	if err != nil {
+		s.logf("client %q: TPM2_%s failed: %v", client.name, CommandName(cmd), err)
+		http.Error(w, "TPM failure", http.StatusBadGateway)
+		return
+	}
+	s.logf("client %q: TPM2_%s returned 0x%x", client.name, CommandName(cmd), binary.BigEndian.Uint32(resp[6:headerSize]))
+
+	out, err := proto.Marshal(&pb.CommandResponse{Response: resp})
+	if err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(out)
+}
+
+// Get the command code of a TPM command, checking the command's size.
+func parseCommandHeader(cmd []byte) (tpmutil.Command, error) {
+	if len(cmd) < headerSize {
+		return 0, errors.New("TPM command is too short")
+	}
+	if size := binary.BigEndian.Uint32(cmd[2:6]); size != uint32(len(cmd)) {
+		return 0, fmt.Errorf("TPM command size is %d, but the command is %d bytes", size, len(cmd))
+	}
+	return tpmutil.Command(binary.BigEndian.Uint32(cmd[6:headerSize])), nil
+}
+
+func (s *Server) runCommand(cmd []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.rw.Write(cmd); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, maxMessageSize)
+	n, err := s.rw.Read(resp)
+	if err != nil {
+		return nil, err
+	}
+	if n < headerSize {
+		return nil, fmt.Errorf("TPM response is too short (%d bytes)", n)
+	}
+	return resp[:n], nil
+}
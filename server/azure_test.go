@@ -0,0 +1,74 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestVerifyAttestationAzureAKCert(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ak, err := client.AttestationKeyRSA(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ak.Close()
+
+	// Like Azure's, the AK certificate has the critical TPM manufacturer
+	// Subject Alternative Name, and is issued by an intermediate CA.
+	root := newTestCA(t, "Test Azure vTPM Root CA", 1, nil)
+	intermediate := newTestCA(t, "Test Global Virtual TPM CA", 2, root)
+	manufacturer, err := asn1.Marshal(pkix.Name{ExtraNames: []pkix.AttributeTypeAndValue{
+		{Type: asn1.ObjectIdentifier{2, 23, 133, 2, 1}, Value: "id:4D534654"},
+	}}.ToRDNSequence())
+	if err != nil {
+		t.Fatal(err)
+	}
+	san, err := asn1.Marshal([]asn1.RawValue{{Class: asn1.ClassContextSpecific, Tag: 4, IsCompound: true, Bytes: manufacturer}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(100),
+		NotBefore:       time.Now().Add(-time.Hour),
+		NotAfter:        time.Now().Add(time.Hour),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtraExtensions: []pkix.Extension{{Id: oidSubjectAltName, Critical: true, Value: san}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, intermediate.cert, ak.PublicKey(), intermediate.priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	akCert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(akCert.UnhandledCriticalExtensions) != 1 {
+		t.Fatalf("got unhandled critical extensions %v, want the Subject Alternative Name", akCert.UnhandledCriticalExtensions)
+	}
+	if err = ak.SetCert(akCert); err != nil {
+		t.Fatal(err)
+	}
+
+	nonce := []byte("nonce")
+	attestation, err := ak.Attest(nonce, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := VerifyOpts{Nonce: nonce, TrustedRootCerts: []*x509.Certificate{root.cert}}
+	if _, err = VerifyAttestation(attestation, opts); err == nil {
+		t.Error("expected verification without the intermediate CA to fail")
+	}
+	attestation.IntermediateCerts = [][]byte{intermediate.cert.Raw}
+	if _, err = VerifyAttestation(attestation, opts); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ThalesIgnite/go-tpm-tools/cel"
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+// getContainerState parses the container launch events of a (replayed)
+// Canonical Event Log, or returns nil if the log has no container events. The
+// launch events must all be in cel.ContainerPCR, and end with the launch
+// separator. As the container could extend ContainerPCR after being started,
+// container events after the separator are rejected.
+func getContainerState(log *cel.CEL) (*attestpb.ContainerState, error) {
+	var state *attestpb.ContainerState
+	seenSeparator := false
+	for _, record := range log.Records {
+		if record.Content.Type != cel.ContainerContentType {
+			continue
+		}
+		if record.PCR != cel.ContainerPCR {
+			return nil, fmt.Errorf("CEL record %d: container event measured into PCR%d, expected PCR%d", record.RecNum, record.PCR, cel.ContainerPCR)
+		}
+		if seenSeparator {
+			return nil, fmt.Errorf("CEL record %d: container event after the launch separator", record.RecNum)
+		}
+		event, err := cel.ParseContainerEvent(record.Content)
+		if err != nil {
+			return nil, fmt.Errorf("CEL record %d: %w", record.RecNum, err)
+		}
+		if state == nil {
+			state = &attestpb.ContainerState{}
+		}
+
+		var single *string
+		value := string(event.Value)
+		switch event.Type {
+		case cel.ImageRefType:
+			single = &state.ImageReference
+		case cel.ImageDigestType:
+			single = &state.ImageDigest
+		case cel.EntrypointType:
+			state.Entrypoint = append(state.Entrypoint, value)
+		case cel.ArgType:
+			state.Args = append(state.Args, value)
+		case cel.EnvPolicyHashType:
+			if state.EnvPolicyHash != nil {
+				return nil, fmt.Errorf("CEL record %d: duplicate %v", record.RecNum, event.Type)
+			}
+			state.EnvPolicyHash = event.Value
+		case cel.LaunchSeparatorType:
+			seenSeparator = true
+		}
+		if single != nil {
+			if *single != "" {
+				return nil, fmt.Errorf("CEL record %d: duplicate %v", record.RecNum, event.Type)
+			}
+			*single = value
+		}
+	}
+
+	if state == nil {
+		return nil, nil
+	}
+	if !seenSeparator {
+		return nil, errors.New("container events without a launch separator")
+	}
+	if state.GetImageReference() == "" || state.GetImageDigest() == "" {
+		return nil, errors.New("container events without an image reference or digest")
+	}
+	return state, nil
+}
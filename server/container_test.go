@@ -0,0 +1,133 @@
+package server
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"strings"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/cel"
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestVerifyContainerLaunch(t *testing.T) {
+	// Replaying the Canonical Event Log starts from PCRs of all zeros.
+	test.SkipOnRealTPM(t)
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ak, err := client.AttestationKeyRSA(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer ak.Close()
+
+	envPolicy := sha256.Sum256([]byte("allow FOO"))
+	launch := cel.ContainerLaunch{
+		ImageRef:      "gcr.io/project/image:latest",
+		ImageDigest:   "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		Entrypoint:    []string{"/bin/server"},
+		Args:          []string{"--port", "8080"},
+		EnvPolicyHash: envPolicy[:],
+	}
+	var log cel.CEL
+	if err = log.AppendContainerLaunch(rwc, []tpm2.Algorithm{tpm2.AlgSHA1, tpm2.AlgSHA256}, launch); err != nil {
+		t.Fatal(err)
+	}
+	celLog, err := log.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce := []byte("super secret nonce")
+	attestation, err := ak.AttestWithConfig(nonce, client.AttestConfig{CanonicalEventLog: celLog})
+	if err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+	opts := VerifyOpts{Nonce: nonce, TrustedAKs: []crypto.PublicKey{ak.PublicKey()}}
+	state, err := VerifyAttestation(attestation, opts)
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	container := state.GetContainer()
+	if container.GetImageReference() != launch.ImageRef || container.GetImageDigest() != launch.ImageDigest {
+		t.Errorf("got image %q with digest %q", container.GetImageReference(), container.GetImageDigest())
+	}
+	if len(container.GetEntrypoint()) != 1 || container.GetEntrypoint()[0] != "/bin/server" || len(container.GetArgs()) != 2 {
+		t.Errorf("got entrypoint %q and args %q", container.GetEntrypoint(), container.GetArgs())
+	}
+	if !bytes.Equal(container.GetEnvPolicyHash(), envPolicy[:]) {
+		t.Errorf("got environment policy hash %x, want %x", container.GetEnvPolicyHash(), envPolicy)
+	}
+
+	// A container event measured after the launch invalidates the log.
+	content, err := cel.ContainerEvent{Type: cel.ArgType, Value: []byte("--debug")}.TLV()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = log.AppendEvent(rwc, cel.ContainerPCR, []tpm2.Algorithm{tpm2.AlgSHA1, tpm2.AlgSHA256}, content); err != nil {
+		t.Fatal(err)
+	}
+	if attestation.CanonicalEventLog, err = log.MarshalBinary(); err != nil {
+		t.Fatal(err)
+	}
+	if attestation, err = ak.AttestWithConfig(nonce, client.AttestConfig{CanonicalEventLog: attestation.CanonicalEventLog}); err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+	if _, err = VerifyAttestation(attestation, opts); err == nil {
+		t.Error("expected verification to fail with an event after the launch separator")
+	}
+	// The log must match the quoted PCRs.
+	attestation.CanonicalEventLog = celLog
+	if _, err = VerifyAttestation(attestation, opts); err == nil {
+		t.Error("expected verification to fail with an outdated Canonical Event Log")
+	}
+	attestation.CanonicalEventLog = []byte("not a CEL")
+	if _, err = VerifyAttestation(attestation, opts); err == nil || !strings.Contains(err.Error(), "failed to parse the Canonical Event Log") {
+		t.Errorf("got error %v, expected a malformed Canonical Event Log to fail parsing", err)
+	}
+}
+
+func containerRecord(pcr uint8, eventType cel.ContainerEventType, value string) cel.Record {
+	content, _ := cel.ContainerEvent{Type: eventType, Value: []byte(value)}.TLV()
+	return cel.Record{PCR: pcr, Content: content}
+}
+
+func TestGetContainerState(t *testing.T) {
+	imageRef := containerRecord(cel.ContainerPCR, cel.ImageRefType, "image")
+	imageDigest := containerRecord(cel.ContainerPCR, cel.ImageDigestType, "sha256:digest")
+	separator := containerRecord(cel.ContainerPCR, cel.LaunchSeparatorType, "")
+	other := cel.Record{PCR: cel.ContainerPCR, Content: cel.TLV{Type: 0x80, Value: []byte("other application")}}
+
+	state, err := getContainerState(&cel.CEL{Records: []cel.Record{other}})
+	if err != nil || state != nil {
+		t.Errorf("got container state %v and error %v, want none", state, err)
+	}
+	state, err = getContainerState(&cel.CEL{Records: []cel.Record{imageRef, other, imageDigest, separator, other}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.GetImageReference() != "image" || state.GetImageDigest() != "sha256:digest" {
+		t.Errorf("got container state %v", state)
+	}
+
+	unknown := cel.Record{PCR: cel.ContainerPCR, Content: cel.TLV{Type: cel.ContainerContentType, Value: []byte{0x7f, 0, 0, 0, 0}}}
+	invalid := map[string][]cel.Record{
+		"NoSeparator":     {imageRef, imageDigest},
+		"NoDigest":        {imageRef, separator},
+		"DuplicateImage":  {imageRef, imageRef, imageDigest, separator},
+		"AfterSeparator":  {imageRef, imageDigest, separator, imageRef},
+		"WrongPCR":        {containerRecord(uint8(test.ApplicationPCR), cel.ImageRefType, "image"), imageDigest, separator},
+		"UnknownType":     {imageRef, imageDigest, unknown, separator},
+		"DuplicatePolicy": {imageRef, imageDigest, containerRecord(cel.ContainerPCR, cel.EnvPolicyHashType, "1"), containerRecord(cel.ContainerPCR, cel.EnvPolicyHashType, "2"), separator},
+	}
+	for name, records := range invalid {
+		if _, err := getContainerState(&cel.CEL{Records: records}); err == nil {
+			t.Errorf("%s: expected parsing the container state to fail", name)
+		}
+	}
+}
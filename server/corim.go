@@ -0,0 +1,204 @@
+package server
+
+import (
+	"crypto"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+// The CBOR tags of CoRIMs and CoMIDs (draft-ietf-rats-corim).
+const (
+	corimTag = 501
+	comidTag = 506
+)
+
+// The map keys of the parts of CoRIMs and CoMIDs used for reference values.
+const (
+	corimTags            = 1
+	comidTriples         = 4
+	triplesReferenceVals = 0
+	measurementKey       = 0
+	measurementValues    = 1
+	measurementDigests   = 2
+)
+
+// The hash algorithms of CoRIM digests, by their IANA Named Information Hash
+// Algorithm identifier.
+var corimHashAlgs = map[int64]pb.HashAlgo{
+	1: pb.HashAlgo_SHA256,
+	7: pb.HashAlgo_SHA384,
+	8: pb.HashAlgo_SHA512,
+}
+
+// ParseCoRIM converts the reference values of a CoRIM (Concise Reference
+// Integrity Manifest, draft-ietf-rats-corim) endorsing TPM PCR values into a
+// PCRPolicy, which can be used with PCRPolicyVerifier. This allows reference
+// values published by supply chain actors in standardized formats to be used
+// as golden PCR values.
+//
+// The CoRIM can be signed (a COSE_Sign1) or unsigned. If signer is non-nil,
+// the CoRIM must be signed by it (using a COSE algorithm supported by the
+// TPM: ES256, ES384, ES512, PS256, PS384, PS512, RS256, RS384 or RS512).
+// Otherwise, the CoRIM must be unsigned.
+//
+// Each measurement of the reference triples of the CoMIDs must be keyed by a
+// PCR index, with the digests of the PCR (sha-256, sha-384 or sha-512). The
+// environments of the triples are not checked. The digests of a PCR, over all
+// the triples, are the allowed values of the PCR in their bank.
+func ParseCoRIM(data []byte, signer crypto.PublicKey) (*PCRPolicy, error) {
+	if signer != nil {
+		msg, err := notinternal.DecodeCOSESign1(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode signed CoRIM: %w", err)
+		}
+		if err = verifyCOSESign1(msg, signer); err != nil {
+			return nil, fmt.Errorf("failed to verify signed CoRIM: %w", err)
+		}
+		data = msg.Payload
+	}
+	decoded, err := notinternal.UnmarshalCBOR(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode CoRIM: %w", err)
+	}
+	if tag, ok := decoded.(notinternal.CBORTag); ok && tag.Number == notinternal.COSETagSign1 {
+		return nil, fmt.Errorf("CoRIM is signed, but no signer was provided")
+	}
+	corim, ok := untag(decoded, corimTag).(map[int64]interface{})
+	if !ok {
+		return nil, fmt.Errorf("CoRIM is not a tagged CoRIM map")
+	}
+	tags, ok := corim[corimTags].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("CoRIM has no tags")
+	}
+
+	values := map[uint32]map[pb.HashAlgo][]string{}
+	for _, tag := range tags {
+		// Tags other than CoMIDs (such as CoSWIDs) do not have reference values.
+		if t, ok := tag.(notinternal.CBORTag); !ok || t.Number != comidTag {
+			continue
+		}
+		if err = addCoMIDValues(untag(tag, comidTag), values); err != nil {
+			return nil, err
+		}
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("CoRIM has no PCR reference values")
+	}
+
+	policy := &PCRPolicy{}
+	for _, pcr := range sortedPCRIndices(values) {
+		banks := make([]int, 0, len(values[pcr]))
+		for bank := range values[pcr] {
+			banks = append(banks, int(bank))
+		}
+		sort.Ints(banks)
+		for _, bank := range banks {
+			hash := pb.HashAlgo(bank)
+			policy.PCRs = append(policy.PCRs, PCRRule{PCR: pcr, Bank: strings.ToLower(hash.String()), Values: values[pcr][hash]})
+		}
+	}
+	if err = policy.validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// untag returns the content of the item, if it has the tag.
+func untag(item interface{}, number uint64) interface{} {
+	if tag, ok := item.(notinternal.CBORTag); ok && tag.Number == number {
+		return tag.Content
+	}
+	return item
+}
+
+// addCoMIDValues adds the PCR reference values of a CoMID (encoded in a byte
+// string, or directly as a map in older drafts) to values.
+func addCoMIDValues(comid interface{}, values map[uint32]map[pb.HashAlgo][]string) error {
+	if encoded, ok := comid.([]byte); ok {
+		var err error
+		if comid, err = notinternal.UnmarshalCBOR(encoded); err != nil {
+			return fmt.Errorf("failed to decode CoMID: %w", err)
+		}
+	}
+	comidMap, ok := comid.(map[int64]interface{})
+	if !ok {
+		return fmt.Errorf("CoMID is not a map")
+	}
+	triples, ok := comidMap[comidTriples].(map[int64]interface{})
+	if !ok {
+		return fmt.Errorf("CoMID has no triples")
+	}
+	references, _ := triples[triplesReferenceVals].([]interface{})
+	for _, reference := range references {
+		triple, ok := reference.([]interface{})
+		if !ok || len(triple) != 2 {
+			return fmt.Errorf("CoMID reference triple is not an environment and measurements")
+		}
+		measurements, ok := triple[1].([]interface{})
+		if !ok {
+			return fmt.Errorf("CoMID reference triple has no measurements")
+		}
+		for _, measurement := range measurements {
+			if err := addMeasurementValues(measurement, values); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func addMeasurementValues(measurement interface{}, values map[uint32]map[pb.HashAlgo][]string) error {
+	m, ok := measurement.(map[int64]interface{})
+	if !ok {
+		return fmt.Errorf("CoMID measurement is not a map")
+	}
+	pcr, ok := m[measurementKey].(int64)
+	if !ok || pcr < 0 || pcr > math.MaxUint32 {
+		return fmt.Errorf("CoMID measurement is not keyed by a PCR index: %v", m[measurementKey])
+	}
+	measured, ok := m[measurementValues].(map[int64]interface{})
+	if !ok {
+		return fmt.Errorf("PCR %d measurement has no values", pcr)
+	}
+	digests, ok := measured[measurementDigests].([]interface{})
+	if !ok || len(digests) == 0 {
+		return fmt.Errorf("PCR %d measurement has no digests", pcr)
+	}
+	for _, digest := range digests {
+		d, ok := digest.([]interface{})
+		if !ok || len(d) != 2 {
+			return fmt.Errorf("PCR %d digest is not an algorithm and value", pcr)
+		}
+		alg, _ := d[0].(int64)
+		hash, ok := corimHashAlgs[alg]
+		if !ok {
+			return fmt.Errorf("PCR %d digest has unsupported algorithm %v", pcr, d[0])
+		}
+		value, ok := d[1].([]byte)
+		if !ok {
+			return fmt.Errorf("PCR %d digest value is not a byte string", pcr)
+		}
+		index := uint32(pcr)
+		if values[index] == nil {
+			values[index] = map[pb.HashAlgo][]string{}
+		}
+		values[index][hash] = append(values[index][hash], hex.EncodeToString(value))
+	}
+	return nil
+}
+
+func sortedPCRIndices(values map[uint32]map[pb.HashAlgo][]string) []uint32 {
+	indices := make([]uint32, 0, len(values))
+	for pcr := range values {
+		indices = append(indices, pcr)
+	}
+	sort.Slice(indices, func(i, j int) bool { return indices[i] < indices[j] })
+	return indices
+}
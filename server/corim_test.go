@@ -0,0 +1,157 @@
+package server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+// makeCoRIM returns a CoRIM with a CoMID containing a reference triple with
+// the sha-256 digests of the PCRs.
+func makeCoRIM(t *testing.T, digests map[int64][][]byte) []byte {
+	t.Helper()
+	var measurements []interface{}
+	for pcr, values := range digests {
+		var d []interface{}
+		for _, value := range values {
+			d = append(d, []interface{}{int64(1), value})
+		}
+		measurements = append(measurements, map[int64]interface{}{
+			measurementKey:    pcr,
+			measurementValues: map[int64]interface{}{measurementDigests: d},
+		})
+	}
+	environment := map[int64]interface{}{0: map[int64]interface{}{1: "ACME", 2: "TPM"}}
+	comid, err := notinternal.MarshalCBOR(map[int64]interface{}{
+		1:            map[int64]interface{}{0: "tag-id"},
+		comidTriples: map[int64]interface{}{triplesReferenceVals: []interface{}{[]interface{}{environment, measurements}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	corim, err := notinternal.MarshalCBOR(notinternal.CBORTag{Number: corimTag, Content: map[int64]interface{}{
+		0:         "corim-id",
+		corimTags: []interface{}{notinternal.CBORTag{Number: comidTag, Content: comid}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return corim
+}
+
+// signCoRIM signs the CoRIM in a COSE_Sign1 with ES256.
+func signCoRIM(t *testing.T, corim []byte, key *ecdsa.PrivateKey) []byte {
+	t.Helper()
+	protected, err := notinternal.COSEProtectedHeader(-7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	toBeSigned, err := notinternal.COSESign1ToBeSigned(protected, corim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(toBeSigned)
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	signed, err := notinternal.EncodeCOSESign1(&notinternal.COSESign1{Protected: protected, Algorithm: -7, Payload: corim, Signature: sig})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestParseCoRIM(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ak, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer ak.Close()
+	nonce := []byte("super secret nonce")
+	attestation, err := ak.Attest(nonce, nil)
+	if err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+	var pcrs *pb.PCRs
+	for _, quote := range attestation.GetQuotes() {
+		if quote.GetPcrs().GetHash() == pb.HashAlgo_SHA256 {
+			pcrs = quote.GetPcrs()
+		}
+	}
+	if pcrs == nil {
+		t.Fatal("no SHA-256 quote")
+	}
+
+	other := make([]byte, sha256.Size)
+	good := makeCoRIM(t, map[int64][][]byte{0: {other, pcrs.GetPcrs()[0]}, 7: {pcrs.GetPcrs()[7]}})
+	bad := makeCoRIM(t, map[int64][][]byte{0: {other}})
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		corim    []byte
+		signer   crypto.PublicKey
+		verified bool
+	}{
+		{"Unsigned", good, nil, true},
+		{"Signed", signCoRIM(t, good, key), key.Public(), true},
+		{"WrongValues", bad, nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy, err := ParseCoRIM(test.corim, test.signer)
+			if err != nil {
+				t.Fatalf("failed to parse CoRIM: %v", err)
+			}
+			opts := VerifyOpts{Nonce: nonce, TrustedAKs: []crypto.PublicKey{ak.PublicKey()}, Verifiers: []Verifier{PCRPolicyVerifier(policy)}}
+			_, err = VerifyAttestation(attestation, opts)
+			if test.verified && err != nil {
+				t.Errorf("failed to verify attestation with the CoRIM reference values: %v", err)
+			}
+			if !test.verified && err == nil {
+				t.Error("expected attestation verification with the CoRIM reference values to fail")
+			}
+		})
+	}
+
+	failures := []struct {
+		name   string
+		corim  []byte
+		signer crypto.PublicKey
+	}{
+		{"SignedWithoutSigner", signCoRIM(t, good, key), nil},
+		{"UnsignedWithSigner", good, key.Public()},
+		{"WrongSigner", signCoRIM(t, good, key), otherKey.Public()},
+		{"NoReferenceValues", makeCoRIM(t, nil), nil},
+		{"NotCBOR", []byte("not a CoRIM"), nil},
+	}
+	for _, f := range failures {
+		t.Run(f.name, func(t *testing.T) {
+			if _, err := ParseCoRIM(f.corim, f.signer); err == nil {
+				t.Error("expected parsing the CoRIM to fail")
+			}
+		})
+	}
+}
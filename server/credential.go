@@ -0,0 +1,65 @@
+package server
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// MakeCredential encrypts a secret to the provided public EK, such that it can
+// only be decrypted by the TPM holding the EK, and only if the key with name
+// akName is loaded on that same TPM. This implements the server side of the
+// TPM2_MakeCredential/TPM2_ActivateCredential protocol, without needing a TPM.
+//
+// The returned credBlob (a TPM2B_ID_OBJECT) and encSecret (a
+// TPM2B_ENCRYPTED_SECRET) are passed to the client Key.ActivateCredential()
+// method, which recovers the secret. The secret must be no longer than the
+// digest size of the EK's name algorithm (32 bytes for the default EKs).
+//
+// Note that the caller is responsible for checking that akName matches an AK
+// with the expected attributes (e.g. a restricted signing key), typically by
+// computing the name from the AK's public area.
+func MakeCredential(ekPub crypto.PublicKey, akName tpm2.Name, secret []byte) (credBlob, encSecret []byte, err error) {
+	ek, err := CreateEKPublicAreaFromKey(ekPub)
+	if err != nil {
+		return nil, nil, err
+	}
+	if akName.Digest == nil {
+		return nil, nil, errors.New("AK name must contain a digest")
+	}
+	if maxSize := getHash(ek.NameAlg).Size(); len(secret) > maxSize {
+		return nil, nil, fmt.Errorf("secret is %d bytes, but the maximum is %d", len(secret), maxSize)
+	}
+	nameEncoded, err := akName.Digest.Encode()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seed, encSecret, err := createSeed(ek, "IDENTITY")
+	if err != nil {
+		return nil, nil, err
+	}
+	packedSecret, err := tpmutil.Pack(tpmutil.U16Bytes(secret))
+	if err != nil {
+		return nil, nil, err
+	}
+	encIdentity, err := encryptSecret(packedSecret, seed, nameEncoded, ek)
+	if err != nil {
+		return nil, nil, err
+	}
+	macSum, err := createHMAC(encIdentity, nameEncoded, seed, ek.NameAlg)
+	if err != nil {
+		return nil, nil, err
+	}
+	credBlob, err = tpmutil.Pack(tpm2.IDObject{
+		IntegrityHMAC: macSum,
+		EncIdentity:   encIdentity,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return credBlob, encSecret, nil
+}
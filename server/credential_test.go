@@ -0,0 +1,28 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+)
+
+func TestMakeCredentialFailures(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := tpm2.Name{Digest: &tpm2.HashValue{Alg: tpm2.AlgSHA256, Value: make([]byte, 32)}}
+
+	if _, _, err = MakeCredential(priv.Public(), name, make([]byte, 32)); err != nil {
+		t.Errorf("MakeCredential failed: %v", err)
+	}
+	if _, _, err = MakeCredential(priv.Public(), name, make([]byte, 33)); err == nil {
+		t.Error("expected failure with a secret longer than the digest size")
+	}
+	if _, _, err = MakeCredential(priv.Public(), tpm2.Name{}, make([]byte, 32)); err == nil {
+		t.Error("expected failure with an empty AK name")
+	}
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+// The header of an EFI_VARIABLE_AUTHENTICATION_2 (an EFI_TIME followed by a
+// WIN_CERTIFICATE_UEFI_GUID), from the UEFI Specification.
+const (
+	efiTimeSize          = 16
+	winCertTypeEFIGUID   = 0x0EF1
+	winCertificateHeader = 4 + 2 + 2 + 16
+)
+
+var efiCertTypePKCS7 = newEFIGUID(0x4aafd29d, 0x68df, 0x49ee, [8]byte{0x8a, 0xa9, 0x34, 0x7d, 0x37, 0x56, 0x65, 0xa7})
+
+// ParseDbxUpdate parses the revoked hashes and certificates of a dbx update,
+// such as the DBXUpdate.bin files of the UEFI Revocation List published by
+// uefi.org. The update is either an authenticated variable (the
+// EFI_SIGNATURE_LISTs appended to an EFI_VARIABLE_AUTHENTICATION_2, as passed
+// to SetVariable), or only the EFI_SIGNATURE_LISTs.
+//
+// The signature of an authenticated update is not verified, as it is signed by
+// a KEK (and not by a root trusted by the verifier): the update must be
+// obtained from a trusted source.
+func ParseDbxUpdate(data []byte) (*attestpb.Database, error) {
+	if len(data) >= efiTimeSize+winCertificateHeader {
+		var header struct {
+			Length          uint32
+			Revision        uint16
+			CertificateType uint16
+			CertType        efiGUID
+		}
+		binary.Read(bytes.NewReader(data[efiTimeSize:]), binary.LittleEndian, &header)
+		if header.CertificateType == winCertTypeEFIGUID && header.CertType == efiCertTypePKCS7 {
+			start := uint64(efiTimeSize) + uint64(header.Length)
+			if header.Length < winCertificateHeader || start > uint64(len(data)) {
+				return nil, fmt.Errorf("EFI_VARIABLE_AUTHENTICATION_2 has invalid length %d", header.Length)
+			}
+			data = data[start:]
+		}
+	}
+	dbx, err := parseEFISignatureLists(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing dbx update: %w", err)
+	}
+	return dbx, nil
+}
+
+// CheckRevocations checks that none of the booted components of the machine
+// are revoked by the dbx measured in its event log, or by the additional dbx
+// (such as the dbx updates parsed by ParseDbxUpdate, newer than the dbx of the
+// machine). It fails if:
+//   - an authority used to verify the boot components (a certificate, or a
+//     hash) is in the dbx, including certificates revoked by the SHA-256
+//     digest of their TBSCertificate
+//   - the Authenticode digest of a UEFI image loaded by the firmware (such as
+//     the digest of an EV_EFI_BOOT_SERVICES_APPLICATION event) is in the dbx
+//
+// As the types of the events are untrusted, the digests of all the events are
+// checked. As the dbx contains SHA-256 digests, the MachineState must be from
+// a SHA-256 bank.
+func CheckRevocations(state *attestpb.MachineState, dbx *attestpb.Database) error {
+	if state.GetHash() != pb.HashAlgo_SHA256 {
+		return fmt.Errorf("the booted components can only be checked against the dbx with a SHA-256 bank, not %v", state.GetHash())
+	}
+	revoked := &attestpb.Database{
+		Certs:  append(append([][]byte{}, state.GetSecureBoot().GetDbx().GetCerts()...), dbx.GetCerts()...),
+		Hashes: append(append([][]byte{}, state.GetSecureBoot().GetDbx().GetHashes()...), dbx.GetHashes()...),
+	}
+	authority := state.GetSecureBoot().GetAuthority()
+	for _, der := range authority.GetCerts() {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return fmt.Errorf("parsing authority certificate: %w", err)
+		}
+		tbsDigest := sha256.Sum256(cert.RawTBSCertificate)
+		if contains(revoked.GetCerts(), der) || contains(revoked.GetHashes(), tbsDigest[:]) {
+			return fmt.Errorf("a boot component was verified by the revoked certificate %q", cert.Subject.CommonName)
+		}
+	}
+	for _, hash := range authority.GetHashes() {
+		if contains(revoked.GetHashes(), hash) {
+			return fmt.Errorf("a boot component was verified by the revoked hash %x", hash)
+		}
+	}
+
+	for i, event := range state.GetRawEvents() {
+		if contains(revoked.GetHashes(), event.GetDigest()) {
+			return fmt.Errorf("event %d: the digest %x is revoked", i, event.GetDigest())
+		}
+	}
+	return nil
+}
+
+// DbxVerifier returns a Verifier checking the booted components against the
+// dbx of the machine and the additional dbx, using CheckRevocations.
+func DbxVerifier(dbx *attestpb.Database) Verifier {
+	return VerifierFuncs{EventLog: func(state *attestpb.MachineState) error {
+		return CheckRevocations(state, dbx)
+	}}
+}
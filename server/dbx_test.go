@@ -0,0 +1,109 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"testing"
+
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+// Wraps the EFI_SIGNATURE_LISTs in an EFI_VARIABLE_AUTHENTICATION_2, with the
+// given PKCS#7 signature.
+func efiAuthenticatedVariable(signature, lists []byte) []byte {
+	var b bytes.Buffer
+	b.Write(make([]byte, efiTimeSize))
+	binary.Write(&b, binary.LittleEndian, uint32(winCertificateHeader+len(signature)))
+	binary.Write(&b, binary.LittleEndian, uint16(0x0200))
+	binary.Write(&b, binary.LittleEndian, uint16(winCertTypeEFIGUID))
+	b.Write(efiCertTypePKCS7[:])
+	b.Write(signature)
+	b.Write(lists)
+	return b.Bytes()
+}
+
+func TestParseDbxUpdate(t *testing.T) {
+	hash := sha256.Sum256([]byte("revoked"))
+	lists := append(efiSignatureList(efiCertSHA256, hash[:]), efiSignatureList(efiCertX509, RevokedCiscoCert)...)
+	for name, update := range map[string][]byte{
+		"SignatureLists": lists,
+		"Authenticated":  efiAuthenticatedVariable([]byte("PKCS#7 signature"), lists),
+	} {
+		dbx, err := ParseDbxUpdate(update)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if len(dbx.GetHashes()) != 1 || !bytes.Equal(dbx.GetHashes()[0], hash[:]) {
+			t.Errorf("%s: got hashes %x, want %x", name, dbx.GetHashes(), hash)
+		}
+		checkContainsCerts(t, name, dbx.GetCerts(), [][]byte{RevokedCiscoCert})
+	}
+
+	truncated := efiAuthenticatedVariable(make([]byte, 100), nil)
+	if _, err := ParseDbxUpdate(truncated[:efiTimeSize+winCertificateHeader+50]); err == nil {
+		t.Error("expected parsing a truncated dbx update to fail")
+	}
+}
+
+func TestCheckRevocations(t *testing.T) {
+	state, err := ParseMachineState(Rhel8GCE.RawLog, Rhel8GCE.Banks[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = CheckRevocations(state, nil); err != nil {
+		t.Fatalf("booted components are revoked by the dbx of the machine: %v", err)
+	}
+
+	var app []byte
+	for _, event := range state.GetRawEvents() {
+		if event.GetUntrustedType() == efiBootServicesApplication {
+			app = event.GetDigest()
+			break
+		}
+	}
+	authority, err := x509.ParseCertificate(MicrosoftUEFICA2011Cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbsDigest := sha256.Sum256(authority.RawTBSCertificate)
+	unrelated := sha256.Sum256([]byte("unrelated"))
+
+	tests := []struct {
+		name    string
+		dbx     *attestpb.Database
+		revoked bool
+	}{
+		{"Unrelated", &attestpb.Database{Hashes: [][]byte{unrelated[:]}, Certs: [][]byte{RevokedCiscoCert}}, false},
+		{"Image", &attestpb.Database{Hashes: [][]byte{unrelated[:], app}}, true},
+		{"AuthorityCert", &attestpb.Database{Certs: [][]byte{MicrosoftUEFICA2011Cert}}, true},
+		{"AuthorityTBSDigest", &attestpb.Database{Hashes: [][]byte{tbsDigest[:]}}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := DbxVerifier(test.dbx).VerifyEventLog(state)
+			if test.revoked && err == nil {
+				t.Error("expected the revoked boot components to fail verification")
+			}
+			if !test.revoked && err != nil {
+				t.Errorf("verification failed: %v", err)
+			}
+		})
+	}
+
+	sha1State, err := ParseMachineState(Rhel8GCE.RawLog, Rhel8GCE.Banks[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = CheckRevocations(sha1State, nil); err == nil {
+		t.Error("expected checking the revocations with a SHA-1 bank to fail")
+	}
+
+	// The dbx of the machine is also checked.
+	state.GetSecureBoot().GetDbx().Hashes = append(state.GetSecureBoot().GetDbx().GetHashes(), app)
+	if err = CheckRevocations(state, nil); err == nil {
+		t.Error("expected an image revoked by the dbx of the machine to fail verification")
+	}
+}
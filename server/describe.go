@@ -0,0 +1,93 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+// Event types whose data is text, or a UEFI_VARIABLE_DATA, from the TCG PC
+// Client Platform Firmware Profile Specification, Table 14 Events.
+const (
+	postCode             uint32 = 0x00000001
+	action               uint32 = 0x00000005
+	compactHash          uint32 = 0x0000000C
+	omitBootDeviceEvents uint32 = 0x00000012
+	efiVariableBoot      uint32 = 0x80000002
+	efiVariableBoot2     uint32 = 0x8000000C
+)
+
+// The vendor GUID of the variables of shim (such as MokList and SbatLevel).
+var shimLockGUID = newEFIGUID(0x605dab50, 0xe046, 0x4300, [8]byte{0xab, 0xb6, 0x3d, 0xd8, 0x10, 0xdd, 0x8b, 0x23})
+
+// The names of well-known vendor GUIDs of UEFI variables.
+var efiVendorNames = map[efiGUID]string{
+	efiGlobalVariable:        "EFI_GLOBAL_VARIABLE",
+	efiImageSecurityDatabase: "EFI_IMAGE_SECURITY_DATABASE",
+	shimLockGUID:             "SHIM_LOCK",
+}
+
+// String returns the registry format of the GUID.
+func (g efiGUID) String() string {
+	return fmt.Sprintf("%08x-%04x-%04x-%x-%x", binary.LittleEndian.Uint32(g[0:]),
+		binary.LittleEndian.Uint16(g[4:]), binary.LittleEndian.Uint16(g[6:]), g[8:10], g[10:])
+}
+
+// DescribeEvent returns a short description of the (untrusted) data of an
+// event, for display: the vendor and name of UEFI variables, the text of
+// events such as EV_EFI_ACTION and EV_IPL (the commands measured by GRUB), the
+// firmware version of EV_S_CRTM_VERSION, and the signature of EV_NO_ACTION
+// events. It returns an empty string for other events (such as the device
+// paths of EV_EFI_BOOT_SERVICES_APPLICATION).
+func DescribeEvent(event *attestpb.Event) string {
+	data := event.GetData()
+	switch event.GetUntrustedType() {
+	case EFIVariableDriverConfig, EFIVariableAuthority, efiVariableBoot, efiVariableBoot2:
+		variable, err := parseEFIVariable(data)
+		if err != nil {
+			return ""
+		}
+		vendor, ok := efiVendorNames[variable.vendor]
+		if !ok {
+			vendor = variable.vendor.String()
+		}
+		return fmt.Sprintf("%s (%s)", variable.name, vendor)
+	case SCRTMVersion:
+		if len(data)%2 != 0 {
+			return ""
+		}
+		chars := make([]uint16, len(data)/2)
+		binary.Read(bytes.NewReader(data), binary.LittleEndian, chars)
+		return printableText(string(utf16.Decode(chars)))
+	case NoAction:
+		if isStartupLocality(data) {
+			return fmt.Sprintf("StartupLocality %d", data[len(startupLocalitySignature)])
+		}
+		// The signatures of EV_NO_ACTION events (such as "Spec ID Event03")
+		// are NUL terminated.
+		if i := bytes.IndexByte(data, 0); i > 0 {
+			return printableText(string(data[:i]))
+		}
+		return ""
+	case postCode, action, compactHash, IPL, omitBootDeviceEvents, EFIAction:
+		return printableText(string(data))
+	default:
+		return ""
+	}
+}
+
+// printableText returns the text without trailing NUL characters, if it is
+// printable ASCII, or an empty string.
+func printableText(text string) string {
+	text = strings.TrimRight(text, "\x00")
+	for _, c := range text {
+		if c < ' ' || c > '~' {
+			return ""
+		}
+	}
+	return text
+}
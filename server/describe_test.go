@@ -0,0 +1,34 @@
+package server
+
+import (
+	"crypto"
+	"testing"
+
+	"github.com/google/go-attestation/attest"
+)
+
+func TestDescribeEvent(t *testing.T) {
+	eventLog, err := attest.ParseEventLog(Rhel8GCE.RawLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	descriptions := map[string]bool{}
+	for _, event := range convertToPbEvents(crypto.SHA1, eventLog.Events(attest.HashSHA1)) {
+		descriptions[DescribeEvent(event)] = true
+	}
+	for _, want := range []string{
+		"SecureBoot (EFI_GLOBAL_VARIABLE)",
+		"db (EFI_IMAGE_SECURITY_DATABASE)",
+		"Calling EFI Application from Boot Option",
+	} {
+		if !descriptions[want] {
+			t.Errorf("no event is described as %q", want)
+		}
+	}
+}
+
+func TestEFIGUIDString(t *testing.T) {
+	if got, want := efiGlobalVariable.String(), "8be4df61-93ca-11d2-aa0d-00e098032b8c"; got != want {
+		t.Errorf("got GUID %s, want %s", got, want)
+	}
+}
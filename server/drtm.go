@@ -0,0 +1,70 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+	"github.com/google/go-attestation/attest"
+	"github.com/google/go-tpm/tpm2"
+)
+
+// The DRTM PCRs, from the TCG PC Client Platform TPM Profile Specification.
+// TPM2_Startup sets these PCRs to all ones. They are only reset to zero by a
+// dynamic launch (_TPM_Hash_Start, issued from locality 4), so the DRTM event
+// log is replayed from zero.
+const (
+	firstDrtmPCR = 17
+	lastDrtmPCR  = 22
+)
+
+func isDrtmPCR(index uint32) bool {
+	return index >= firstDrtmPCR && index <= lastDrtmPCR
+}
+
+// ParseDrtmState parses a raw DRTM event log (such as the Intel TXT event log,
+// in the TCG crypto agile format) and replays it against the DRTM PCRs (17 to
+// 22) of the given PCR values. It returns an error if the log measures into
+// other PCRs, if the replay does not match the provided values, or if the DRTM
+// PCRs still have their TPM2_Startup value (i.e. no dynamic launch occurred).
+//
+// As with ParseMachineState, it is the caller's responsibility to ensure that
+// the passed PCR values can be trusted.
+func ParseDrtmState(rawDrtmLog []byte, pcrs *pb.PCRs) (*attestpb.DrtmState, error) {
+	drtmPCRs := &pb.PCRs{Hash: pcrs.GetHash(), Pcrs: map[uint32][]byte{}}
+	for index, value := range pcrs.GetPcrs() {
+		if !isDrtmPCR(index) {
+			continue
+		}
+		if isStartupDrtmValue(value) {
+			return nil, fmt.Errorf("PCR%d was not reset by a dynamic launch", index)
+		}
+		drtmPCRs.Pcrs[index] = value
+	}
+	attestPcrs, err := convertToAttestPcrs(drtmPCRs)
+	if err != nil {
+		return nil, fmt.Errorf("received bad DRTM PCRs: %w", err)
+	}
+	cryptoHash, _ := tpm2.Algorithm(pcrs.GetHash()).Hash()
+
+	eventLog, err := attest.ParseEventLog(rawDrtmLog)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DRTM event log: %w", err)
+	}
+	// Events in other PCRs would not be replayed, and so silently dropped.
+	for _, event := range eventLog.Events(attest.HashSHA1) {
+		if event.Type != attest.EventType(NoAction) && !isDrtmPCR(uint32(event.Index)) {
+			return nil, fmt.Errorf("DRTM event log measures into PCR%d", event.Index)
+		}
+	}
+	events, err := eventLog.Verify(attestPcrs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay DRTM event log: %w", err)
+	}
+	return &attestpb.DrtmState{RawEvents: convertToPbEvents(cryptoHash, events)}, nil
+}
+
+func isStartupDrtmValue(value []byte) bool {
+	return len(value) > 0 && bytes.Count(value, []byte{0xff}) == len(value)
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+type testEvent struct {
+	pcr       uint32
+	eventType uint32
+	data      []byte
+}
+
+// buildEventLog encodes the events as a crypto agile event log, with SHA1 and
+// SHA256 digests of the event data.
+func buildEventLog(events []testEvent) []byte {
+	var log bytes.Buffer
+	specID := append([]byte("Spec ID Event03\x00"), make([]byte, 4)...)
+	specID = append(specID, 0, 2, 0, 2) // Version 2.0, errata 0, UINTN size
+	specID = append(specID, 2, 0, 0, 0) // SHA1 and SHA256 digests
+	specID = append(specID, 0x04, 0, 20, 0, 0x0b, 0, 32, 0, 0)
+	binary.Write(&log, binary.LittleEndian, []uint32{0, NoAction})
+	log.Write(make([]byte, 20))
+	binary.Write(&log, binary.LittleEndian, uint32(len(specID)))
+	log.Write(specID)
+
+	for _, event := range events {
+		sha1Digest := sha1.Sum(event.data)
+		sha256Digest := sha256.Sum256(event.data)
+		binary.Write(&log, binary.LittleEndian, []uint32{event.pcr, event.eventType, 2})
+		binary.Write(&log, binary.LittleEndian, uint16(tpm2.AlgSHA1))
+		log.Write(sha1Digest[:])
+		binary.Write(&log, binary.LittleEndian, uint16(tpm2.AlgSHA256))
+		log.Write(sha256Digest[:])
+		binary.Write(&log, binary.LittleEndian, uint32(len(event.data)))
+		log.Write(event.data)
+	}
+	return log.Bytes()
+}
+
+// replaySHA256 computes the SHA256 PCR values of the events, with PCRs
+// starting at zero (or the startup locality, for PCR0).
+func replaySHA256(events []testEvent) map[uint32][]byte {
+	pcrs := map[uint32][]byte{}
+	for _, event := range events {
+		value, ok := pcrs[event.pcr]
+		if !ok {
+			value = make([]byte, sha256.Size)
+		}
+		if event.eventType == NoAction {
+			if isStartupLocality(event.data) {
+				value[sha256.Size-1] = event.data[len(startupLocalitySignature)]
+				pcrs[event.pcr] = value
+			}
+			continue
+		}
+		digest := sha256.Sum256(event.data)
+		extended := sha256.Sum256(append(value, digest[:]...))
+		pcrs[event.pcr] = extended[:]
+	}
+	return pcrs
+}
+
+// Intel TXT event types, from the Intel TXT Software Development Guide.
+const (
+	txtPCRMapping uint32 = 0x401
+	txtHashStart  uint32 = 0x402
+	txtMLEHash    uint32 = 0x404
+)
+
+func TestParseDrtmState(t *testing.T) {
+	drtmEvents := []testEvent{
+		{17, txtPCRMapping, []byte{1}},
+		{17, txtHashStart, []byte("SINIT ACM")},
+		{18, txtMLEHash, []byte("tboot")},
+	}
+	drtmLog := buildEventLog(drtmEvents)
+	values := replaySHA256(drtmEvents)
+	pcrs := &pb.PCRs{Hash: pb.HashAlgo_SHA256, Pcrs: map[uint32][]byte{}}
+	for i := uint32(0); i < 24; i++ {
+		pcrs.Pcrs[i] = make([]byte, sha256.Size)
+		if value, ok := values[i]; ok {
+			pcrs.Pcrs[i] = value
+		}
+	}
+
+	state, err := ParseDrtmState(drtmLog, pcrs)
+	if err != nil {
+		t.Fatalf("failed to parse DRTM state: %v", err)
+	}
+	if len(state.GetRawEvents()) != len(drtmEvents) {
+		t.Fatalf("got %d DRTM events, want %d", len(state.GetRawEvents()), len(drtmEvents))
+	}
+	for i, event := range state.GetRawEvents() {
+		if event.GetPcrIndex() != drtmEvents[i].pcr || !event.GetDigestVerified() {
+			t.Errorf("event %d: got PCR%d (digest verified: %v), want PCR%d", i, event.GetPcrIndex(), event.GetDigestVerified(), drtmEvents[i].pcr)
+		}
+	}
+
+	startup := bytes.Repeat([]byte{0xff}, sha256.Size)
+	withPCR := func(index uint32, value []byte) *pb.PCRs {
+		modified := &pb.PCRs{Hash: pcrs.GetHash(), Pcrs: map[uint32][]byte{}}
+		for i, v := range pcrs.GetPcrs() {
+			modified.Pcrs[i] = v
+		}
+		modified.Pcrs[index] = value
+		return modified
+	}
+	invalid := []struct {
+		name string
+		log  []byte
+		pcrs *pb.PCRs
+	}{
+		{"NoDynamicLaunch", drtmLog, withPCR(22, startup)},
+		{"WrongValue", drtmLog, withPCR(18, pcrs.GetPcrs()[17])},
+		{"NoDrtmPCRs", drtmLog, &pb.PCRs{Hash: pb.HashAlgo_SHA256, Pcrs: map[uint32][]byte{0: pcrs.GetPcrs()[0]}}},
+		{"StaticPCR", buildEventLog(append(drtmEvents, testEvent{0, Separator, []byte{0, 0, 0, 0}})), pcrs},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseDrtmState(tc.log, tc.pcrs); err == nil {
+				t.Error("expected parsing the DRTM state to fail")
+			}
+		})
+	}
+}
+
+func TestParseMachineStateHCRTM(t *testing.T) {
+	// With an H-CRTM, _TPM_Hash_Start is issued from locality 4 before
+	// TPM2_Startup, and PCR0 starts at 4.
+	locality := append(append([]byte{}, startupLocalitySignature...), 4)
+	events := []testEvent{
+		{0, NoAction, locality},
+		{0, 0x00000007, []byte("HCRTM")}, // EV_S_CRTM_CONTENTS
+		{0, Separator, []byte{0, 0, 0, 0}},
+		{7, Separator, []byte{0, 0, 0, 0}},
+	}
+	pcrs := &pb.PCRs{Hash: pb.HashAlgo_SHA256, Pcrs: replaySHA256(events)}
+	state, err := ParseMachineState(buildEventLog(events), pcrs)
+	if err != nil {
+		t.Fatalf("failed to parse machine state: %v", err)
+	}
+	predicted, err := PredictPCRs(pb.HashAlgo_SHA256, state.GetRawEvents())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(predicted.GetPcrs()[0], pcrs.GetPcrs()[0]) {
+		t.Errorf("PCR0: predicted %x, want %x", predicted.GetPcrs()[0], pcrs.GetPcrs()[0])
+	}
+
+	// The same measurements starting from locality 0 do not match.
+	if _, err = ParseMachineState(buildEventLog(events[1:]), pcrs); err == nil {
+		t.Error("expected the replay to fail without the startup locality")
+	}
+}
+
+func TestVerifyAttestationDrtmLog(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ak, err := client.AttestationKeyRSA(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer ak.Close()
+	nonce := []byte("super secret nonce")
+	opts := VerifyOpts{Nonce: nonce, TrustedAKs: []crypto.PublicKey{ak.PublicKey()}}
+
+	attestation, err := ak.Attest(nonce, nil)
+	if err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+	state, err := VerifyAttestation(attestation, opts)
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	if state.GetDrtm() != nil {
+		t.Errorf("got DRTM state %v without a DRTM event log", state.GetDrtm())
+	}
+
+	// The TPM was not started with a dynamic launch, so its DRTM PCRs still
+	// have their startup value.
+	drtmLog := buildEventLog([]testEvent{{17, txtHashStart, []byte("SINIT ACM")}})
+	if attestation, err = ak.AttestWithConfig(nonce, client.AttestConfig{DrtmEventLog: drtmLog}); err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+	if _, err = VerifyAttestation(attestation, opts); err == nil {
+		t.Error("expected verification to fail without a dynamic launch")
+	}
+}
@@ -0,0 +1,477 @@
+package server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+// EARProfile is the EAT profile of EAT Attestation Results (EAR), as defined
+// by draft-fv-rats-ear and used by the Veraison verifier.
+const EARProfile = "tag:github.com,2023:veraison/ear"
+
+// EARSubmodule is the name of the submodule containing the appraisal of the
+// TPM in the AttestationResults created by AppraiseAttestation.
+const EARSubmodule = "tpm"
+
+// The claim keys of EARs in CBOR. The submodule claims are keyed by the
+// EARSubmodule name.
+const (
+	earClaimExpiry      = 4
+	earClaimIssuedAt    = 6
+	earClaimNonce       = 10
+	earClaimProfile     = 265
+	earClaimSubmods     = 266
+	earClaimStatus      = 1000
+	earClaimTrustVector = 1001
+	earClaimPolicyID    = 1003
+	earClaimVerifierID  = 1004
+	// A private claim of the submodule, with the MachineClaims.
+	earClaimMachine = -70100
+)
+
+// TrustTier is a trustworthiness tier of the AR4SI information model
+// (draft-ietf-rats-ar4si), the overall status of an AttestationResult.
+type TrustTier int8
+
+// The trustworthiness tiers, by their AR4SI value.
+const (
+	TierNone            TrustTier = 0
+	TierAffirming       TrustTier = 2
+	TierWarning         TrustTier = 32
+	TierContraindicated TrustTier = 96
+)
+
+var trustTierNames = map[TrustTier]string{
+	TierNone:            "none",
+	TierAffirming:       "affirming",
+	TierWarning:         "warning",
+	TierContraindicated: "contraindicated",
+}
+
+func (t TrustTier) String() string {
+	if name, ok := trustTierNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("TrustTier(%d)", int8(t))
+}
+
+// MarshalJSON encodes the tier as its name, as in JSON EARs.
+func (t TrustTier) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// Tier returns the tier of a trustworthiness claim value.
+func Tier(value int8) TrustTier {
+	switch {
+	case value >= -1 && value <= 1:
+		return TierNone
+	case value >= -32 && value <= 31:
+		return TierAffirming
+	case value >= -96 && value <= 95:
+		return TierWarning
+	default:
+		return TierContraindicated
+	}
+}
+
+// The trustworthiness claim values set by AppraiseAttestation (see AR4SI).
+const (
+	ClaimNoClaim                int8 = 0
+	ClaimRecognizedInstance     int8 = 2
+	ClaimUnrecognizedInstance   int8 = 97
+	ClaimApprovedConfig         int8 = 2
+	ClaimApprovedRuntime        int8 = 2
+	ClaimContraindicatedRuntime int8 = 96
+	ClaimGenuineHardware        int8 = 2
+)
+
+// TrustVector is the AR4SI trustworthiness vector of an appraisal. Each claim
+// is a value whose tier is given by Tier, with ClaimNoClaim if the verifier
+// makes no claim.
+type TrustVector struct {
+	InstanceIdentity int8 `json:"instance-identity,omitempty"`
+	Configuration    int8 `json:"configuration,omitempty"`
+	Executables      int8 `json:"executables,omitempty"`
+	FileSystem       int8 `json:"file-system,omitempty"`
+	Hardware         int8 `json:"hardware,omitempty"`
+	RuntimeOpaque    int8 `json:"runtime-opaque,omitempty"`
+	StorageOpaque    int8 `json:"storage-opaque,omitempty"`
+	SourcedData      int8 `json:"sourced-data,omitempty"`
+}
+
+func (v TrustVector) claims() []int8 {
+	return []int8{v.InstanceIdentity, v.Configuration, v.Executables, v.FileSystem,
+		v.Hardware, v.RuntimeOpaque, v.StorageOpaque, v.SourcedData}
+}
+
+// Status returns the overall tier of the vector: the least trustworthy tier
+// of its claims (ignoring claims in TierNone).
+func (v TrustVector) Status() TrustTier {
+	status := TierNone
+	for _, claim := range v.claims() {
+		if tier := Tier(claim); tier > status {
+			status = tier
+		}
+	}
+	return status
+}
+
+// VerifierID identifies the verifier producing an AttestationResult.
+type VerifierID struct {
+	Build     string `json:"build"`
+	Developer string `json:"developer"`
+}
+
+// DefaultVerifierID is the VerifierID of the AttestationResults created by
+// AppraiseAttestation.
+var DefaultVerifierID = VerifierID{Build: "go-tpm-tools", Developer: "https://github.com/ThalesIgnite/go-tpm-tools"}
+
+// The TEE types of MachineClaims.
+const (
+	TEESevSnp = "sev-snp"
+	TEETdx    = "tdx"
+	TEESev    = "sev"
+	TEESevEs  = "sev-es"
+)
+
+// MachineClaims are the claims asserted about the verified MachineState of an
+// AttestationResult, on which relying parties can gate access. They are
+// encoded in the "machine" claim of the EAR submodule, in JSON as the
+// "secure-boot", "kernel-digest" (base64url encoded) and "tee" claims, and in
+// CBOR keyed by the index of the fields.
+type MachineClaims struct {
+	// Whether UEFI Secure Boot is enabled.
+	SecureBoot bool
+	// The digest of the Linux kernel booted (in the MachineState's hash
+	// algorithm), if any.
+	KernelDigest []byte
+	// The Trusted Execution Environment the machine runs in (TEESevSnp,
+	// TEETdx, TEESev or TEESevEs), or empty if none was attested.
+	TEE string
+}
+
+// machineClaims returns the claims about the verified MachineState.
+func machineClaims(state *attestpb.MachineState) *MachineClaims {
+	claims := &MachineClaims{
+		SecureBoot:   state.GetSecureBoot().GetEnabled(),
+		KernelDigest: state.GetLinuxKernel().GetKernelDigest(),
+	}
+	switch {
+	case state.GetSevSnp() != nil:
+		claims.TEE = TEESevSnp
+	case state.GetTdx() != nil:
+		claims.TEE = TEETdx
+	case state.GetPlatform().GetTechnology() == attestpb.GCEConfidentialTechnology_AMD_SEV_ES:
+		claims.TEE = TEESevEs
+	case state.GetPlatform().GetTechnology() == attestpb.GCEConfidentialTechnology_AMD_SEV:
+		claims.TEE = TEESev
+	}
+	return claims
+}
+
+// AttestationResult is the result of appraising an Attestation, which can be
+// encoded as an EAT Attestation Result (EAR) for relying parties following the
+// IETF RATS architecture (RFC 9334), such as the consumers of Veraison.
+type AttestationResult struct {
+	Status      TrustTier
+	TrustVector TrustVector
+	IssuedAt    time.Time
+	// The time after which relying parties must not accept the result, if
+	// non-zero.
+	Expiry time.Time
+	// The nonce of the Attestation.
+	Nonce      []byte
+	VerifierID VerifierID
+	// The identifier of the appraisal policy, if any.
+	PolicyID string
+	// The verified MachineState, if the Attestation was verified.
+	MachineState *attestpb.MachineState
+	// The claims about the MachineState, if the Attestation was verified.
+	Machine *MachineClaims
+	// The reason for the Status, if the Attestation was not verified. It is
+	// not part of the EAR.
+	Err error
+}
+
+// AppraiseAttestation verifies the Attestation with VerifyAttestation, and
+// returns the AttestationResult of the appraisal:
+//   - if the AK is not trusted, the instance identity is unrecognized
+//   - otherwise, the instance is recognized and the hardware is genuine (the
+//     AK being a trusted TPM key). If the Attestation fails verification, the
+//     executables are contraindicated. If it passes and opts.Verifiers is not
+//     empty, the configuration and executables are approved.
+//
+// If the Attestation is verified, the result contains the MachineClaims about
+// its MachineState. The policyID identifies the policy of the opts.Verifiers, and can be empty.
+func AppraiseAttestation(attestation *attestpb.Attestation, opts VerifyOpts, policyID string) *AttestationResult {
+	result := &AttestationResult{
+		IssuedAt:   time.Now(),
+		Nonce:      opts.Nonce,
+		VerifierID: DefaultVerifierID,
+		PolicyID:   policyID,
+	}
+	if _, _, err := trustedAKPublicKey(attestation, opts); err != nil {
+		result.TrustVector.InstanceIdentity = ClaimUnrecognizedInstance
+		result.Err = err
+	} else {
+		result.TrustVector.InstanceIdentity = ClaimRecognizedInstance
+		result.TrustVector.Hardware = ClaimGenuineHardware
+		result.MachineState, result.Err = VerifyAttestation(attestation, opts)
+		if result.Err != nil {
+			result.TrustVector.Executables = ClaimContraindicatedRuntime
+		} else {
+			result.Machine = machineClaims(result.MachineState)
+			if len(opts.Verifiers) > 0 {
+				result.TrustVector.Configuration = ClaimApprovedConfig
+				result.TrustVector.Executables = ClaimApprovedRuntime
+			}
+		}
+	}
+	result.Status = result.TrustVector.Status()
+	return result
+}
+
+// AppraiseEAT is like AppraiseAttestation, for an Entity Attestation Token
+// created by client.COSESigner.SignEAT (see VerifyEAT). If the AK is trusted,
+// but the token is not signed by it or does not contain opts.Nonce, the
+// executables are contraindicated. An error is only returned if the token
+// cannot be parsed.
+func AppraiseEAT(token []byte, opts VerifyOpts, policyID string) (*AttestationResult, error) {
+	eat, err := ParseEAT(token)
+	if err != nil {
+		return nil, err
+	}
+	result := AppraiseAttestation(eat.Attestation, opts, policyID)
+	akPub, _, err := trustedAKPublicKey(eat.Attestation, opts)
+	if err != nil {
+		return result, nil
+	}
+	if err = verifyCOSESign1(eat.Message, akPub); err == nil && subtle.ConstantTimeCompare(eat.Nonce, opts.Nonce) == 0 {
+		err = fmt.Errorf("EAT nonce does not match the expected nonce")
+	}
+	if err != nil {
+		result.TrustVector.Configuration = ClaimNoClaim
+		result.TrustVector.Executables = ClaimContraindicatedRuntime
+		result.Status = result.TrustVector.Status()
+		result.MachineState, result.Machine, result.Err = nil, nil, err
+	}
+	return result, nil
+}
+
+// MarshalJSON encodes the result as the claims of a JSON EAR (with the nonce
+// and kernel digest base64url encoded), which can be signed as a JWT with
+// SignJWT.
+func (r *AttestationResult) MarshalJSON() ([]byte, error) {
+	type machine struct {
+		SecureBoot   bool   `json:"secure-boot"`
+		KernelDigest string `json:"kernel-digest,omitempty"`
+		TEE          string `json:"tee,omitempty"`
+	}
+	type submodule struct {
+		Status      TrustTier   `json:"ear.status"`
+		TrustVector TrustVector `json:"ear.trustworthiness-vector"`
+		PolicyID    string      `json:"ear.appraisal-policy-id,omitempty"`
+		Machine     *machine    `json:"machine,omitempty"`
+	}
+	submod := submodule{Status: r.Status, TrustVector: r.TrustVector, PolicyID: r.PolicyID}
+	if r.Machine != nil {
+		submod.Machine = &machine{
+			SecureBoot:   r.Machine.SecureBoot,
+			KernelDigest: base64.RawURLEncoding.EncodeToString(r.Machine.KernelDigest),
+			TEE:          r.Machine.TEE,
+		}
+	}
+	var expiry int64
+	if !r.Expiry.IsZero() {
+		expiry = r.Expiry.Unix()
+	}
+	return json.Marshal(struct {
+		Profile    string               `json:"eat_profile"`
+		IssuedAt   int64                `json:"iat"`
+		Expiry     int64                `json:"exp,omitempty"`
+		Nonce      string               `json:"eat_nonce,omitempty"`
+		VerifierID VerifierID           `json:"ear.verifier-id"`
+		Submods    map[string]submodule `json:"submods"`
+	}{
+		Profile:    EARProfile,
+		IssuedAt:   r.IssuedAt.Unix(),
+		Expiry:     expiry,
+		Nonce:      base64.RawURLEncoding.EncodeToString(r.Nonce),
+		VerifierID: r.VerifierID,
+		Submods:    map[string]submodule{EARSubmodule: submod},
+	})
+}
+
+// MarshalCBOR encodes the result as the claims of a CBOR EAR.
+func (r *AttestationResult) MarshalCBOR() ([]byte, error) {
+	vector := map[int64]interface{}{}
+	for i, claim := range r.TrustVector.claims() {
+		if claim != ClaimNoClaim {
+			vector[int64(i)] = int64(claim)
+		}
+	}
+	submod := map[int64]interface{}{
+		earClaimStatus:      int64(r.Status),
+		earClaimTrustVector: vector,
+	}
+	if r.PolicyID != "" {
+		submod[earClaimPolicyID] = r.PolicyID
+	}
+	if r.Machine != nil {
+		machine := map[int64]interface{}{0: r.Machine.SecureBoot}
+		if len(r.Machine.KernelDigest) > 0 {
+			machine[1] = r.Machine.KernelDigest
+		}
+		if r.Machine.TEE != "" {
+			machine[2] = r.Machine.TEE
+		}
+		submod[earClaimMachine] = machine
+	}
+	claims := map[int64]interface{}{
+		earClaimProfile:  EARProfile,
+		earClaimIssuedAt: r.IssuedAt.Unix(),
+		earClaimVerifierID: map[int64]interface{}{
+			0: r.VerifierID.Build,
+			1: r.VerifierID.Developer,
+		},
+		earClaimSubmods: map[string]interface{}{EARSubmodule: submod},
+	}
+	if len(r.Nonce) > 0 {
+		claims[earClaimNonce] = r.Nonce
+	}
+	if !r.Expiry.IsZero() {
+		claims[earClaimExpiry] = r.Expiry.Unix()
+	}
+	return notinternal.MarshalCBOR(claims)
+}
+
+// SignCWT returns the result as a CBOR EAR, signed by the verifier's key in a
+// COSE_Sign1 message. The signer must have an ECDSA key on a NIST curve (using
+// ES256, ES384 or ES512), or an RSA key (using PS256).
+func (r *AttestationResult) SignCWT(signer crypto.Signer) ([]byte, error) {
+	alg, err := coseSignerAlgorithm(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	claims, err := r.MarshalCBOR()
+	if err != nil {
+		return nil, err
+	}
+	protected, err := notinternal.COSEProtectedHeader(alg)
+	if err != nil {
+		return nil, err
+	}
+	toBeSigned, err := notinternal.COSESign1ToBeSigned(protected, claims)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signConcat(signer, alg, toBeSigned)
+	if err != nil {
+		return nil, err
+	}
+	return notinternal.EncodeCOSESign1(&notinternal.COSESign1{
+		Protected: protected,
+		Algorithm: alg,
+		Payload:   claims,
+		Signature: sig,
+	})
+}
+
+// The JWS algorithms of the COSE algorithms used by the signers of results.
+var jwsSignerAlgorithms = map[int64]string{
+	-7:  "ES256",
+	-35: "ES384",
+	-36: "ES512",
+	-37: "PS256",
+}
+
+// SignJWT returns the result as a JSON EAR (see MarshalJSON), signed by the
+// verifier's key in a JWT, with the same algorithms as SignCWT. Relying
+// parties (such as service meshes or key management services) can verify it
+// with any JWT library, and gate access on its claims.
+func (r *AttestationResult) SignJWT(signer crypto.Signer) (string, error) {
+	alg, err := coseSignerAlgorithm(signer.Public())
+	if err != nil {
+		return "", err
+	}
+	claims, err := r.MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+	header, err := json.Marshal(map[string]string{"alg": jwsSignerAlgorithms[alg], "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	sig, err := signConcat(signer, alg, []byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// signConcat signs the data with the COSE algorithm, encoding ECDSA signatures
+// as the concatenation of R and S (as used by COSE and JWS).
+func signConcat(signer crypto.Signer, alg int64, data []byte) ([]byte, error) {
+	hash, err := notinternal.COSEAlgorithms[alg].Hash.Hash()
+	if err != nil {
+		return nil, err
+	}
+	hasher := hash.New()
+	hasher.Write(data)
+	digest := hasher.Sum(nil)
+
+	var opts crypto.SignerOpts = hash
+	if _, ok := signer.Public().(*rsa.PublicKey); ok {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hash}
+	}
+	sig, err := signer.Sign(rand.Reader, digest, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign EAR: %w", err)
+	}
+	if pub, ok := signer.Public().(*ecdsa.PublicKey); ok {
+		var rs struct{ R, S *big.Int }
+		if _, err = asn1.Unmarshal(sig, &rs); err != nil {
+			return nil, fmt.Errorf("failed to decode ECDSA signature: %w", err)
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		sig = make([]byte, 2*size)
+		rs.R.FillBytes(sig[:size])
+		rs.S.FillBytes(sig[size:])
+	}
+	return sig, nil
+}
+
+// coseSignerAlgorithm returns the COSE algorithm used by SignCWT for the
+// public key.
+func coseSignerAlgorithm(pub crypto.PublicKey) (int64, error) {
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		return -37, nil // PS256
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			return -7, nil // ES256
+		case elliptic.P384():
+			return -35, nil // ES384
+		case elliptic.P521():
+			return -36, nil // ES512
+		}
+		return 0, fmt.Errorf("unsupported curve for COSE: %s", pub.Curve.Params().Name)
+	default:
+		return 0, fmt.Errorf("unsupported public key type for COSE: %T", pub)
+	}
+}
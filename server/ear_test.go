@@ -0,0 +1,291 @@
+package server
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+func TestTrustVectorStatus(t *testing.T) {
+	tests := []struct {
+		vector TrustVector
+		status TrustTier
+	}{
+		{TrustVector{}, TierNone},
+		{TrustVector{InstanceIdentity: 2, Hardware: 2}, TierAffirming},
+		{TrustVector{InstanceIdentity: 2, Configuration: 32}, TierWarning},
+		{TrustVector{InstanceIdentity: 97, Configuration: 32}, TierContraindicated},
+		{TrustVector{Executables: -33}, TierWarning},
+	}
+	for _, test := range tests {
+		if status := test.vector.Status(); status != test.status {
+			t.Errorf("%+v has status %v, want %v", test.vector, status, test.status)
+		}
+	}
+}
+
+func TestAppraiseAttestation(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ak, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer ak.Close()
+	nonce := []byte("super secret nonce")
+	attestation, err := ak.Attest(nonce, nil)
+	if err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+
+	trusted := []crypto.PublicKey{ak.PublicKey()}
+	accept := VerifierFuncs{}
+	tests := []struct {
+		name   string
+		opts   VerifyOpts
+		vector TrustVector
+		status TrustTier
+	}{
+		{"NoVerifiers", VerifyOpts{Nonce: nonce, TrustedAKs: trusted}, TrustVector{InstanceIdentity: 2, Hardware: 2}, TierAffirming},
+		{"Verifiers", VerifyOpts{Nonce: nonce, TrustedAKs: trusted, Verifiers: []Verifier{accept}},
+			TrustVector{InstanceIdentity: 2, Configuration: 2, Executables: 2, Hardware: 2}, TierAffirming},
+		{"WrongNonce", VerifyOpts{Nonce: []byte("wrong nonce"), TrustedAKs: trusted}, TrustVector{InstanceIdentity: 2, Executables: 96, Hardware: 2}, TierContraindicated},
+		{"UntrustedAK", VerifyOpts{Nonce: nonce}, TrustVector{InstanceIdentity: 97}, TierContraindicated},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := AppraiseAttestation(attestation, test.opts, "policy")
+			if result.TrustVector != test.vector || result.Status != test.status {
+				t.Errorf("got vector %+v with status %v, want %+v with status %v", result.TrustVector, result.Status, test.vector, test.status)
+			}
+			if (result.Err == nil) != (test.status == TierAffirming) || (result.MachineState != nil) != (result.Err == nil) ||
+				(result.Machine != nil) != (result.Err == nil) {
+				t.Errorf("unexpected MachineState (%v), claims (%v) and error: %v", result.MachineState != nil, result.Machine, result.Err)
+			}
+		})
+	}
+}
+
+func TestAppraiseEAT(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ak, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer ak.Close()
+	nonce := []byte("super secret nonce")
+	attestation, err := ak.Attest(nonce, nil)
+	if err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+	signer, err := ak.COSESigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := signer.SignEAT(attestation, nonce)
+	if err != nil {
+		t.Fatalf("failed to sign EAT: %v", err)
+	}
+	tampered := append([]byte{}, token...)
+	tampered[len(tampered)-1] ^= 1
+
+	trusted := []crypto.PublicKey{ak.PublicKey()}
+	tests := []struct {
+		name   string
+		token  []byte
+		opts   VerifyOpts
+		status TrustTier
+	}{
+		{"Verified", token, VerifyOpts{Nonce: nonce, TrustedAKs: trusted}, TierAffirming},
+		{"InvalidSignature", tampered, VerifyOpts{Nonce: nonce, TrustedAKs: trusted}, TierContraindicated},
+		{"UntrustedAK", token, VerifyOpts{Nonce: nonce}, TierContraindicated},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := AppraiseEAT(test.token, test.opts, "")
+			if err != nil {
+				t.Fatalf("failed to appraise EAT: %v", err)
+			}
+			if result.Status != test.status || (result.Err == nil) != (test.status == TierAffirming) {
+				t.Errorf("got status %v (error: %v), want %v", result.Status, result.Err, test.status)
+			}
+		})
+	}
+	if _, err = AppraiseEAT([]byte("not an EAT"), VerifyOpts{Nonce: nonce}, ""); err == nil {
+		t.Error("expected appraising an invalid EAT to fail")
+	}
+}
+
+func TestMachineClaims(t *testing.T) {
+	tests := []struct {
+		name   string
+		state  *attestpb.MachineState
+		claims MachineClaims
+	}{
+		{"Empty", &attestpb.MachineState{}, MachineClaims{}},
+		{"SecureBoot", &attestpb.MachineState{
+			SecureBoot:  &attestpb.SecureBootState{Enabled: true},
+			LinuxKernel: &attestpb.LinuxKernelState{KernelDigest: []byte{1, 2}},
+		}, MachineClaims{SecureBoot: true, KernelDigest: []byte{1, 2}}},
+		{"SevSnp", &attestpb.MachineState{
+			SevSnp:   &attestpb.SevSnpState{},
+			Platform: &attestpb.PlatformState{Technology: attestpb.GCEConfidentialTechnology_AMD_SEV_ES},
+		}, MachineClaims{TEE: TEESevSnp}},
+		{"Tdx", &attestpb.MachineState{Tdx: &attestpb.TdxState{}}, MachineClaims{TEE: TEETdx}},
+		{"SevEs", &attestpb.MachineState{
+			Platform: &attestpb.PlatformState{Technology: attestpb.GCEConfidentialTechnology_AMD_SEV_ES},
+		}, MachineClaims{TEE: TEESevEs}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims := machineClaims(test.state)
+			if claims.SecureBoot != test.claims.SecureBoot || !bytes.Equal(claims.KernelDigest, test.claims.KernelDigest) || claims.TEE != test.claims.TEE {
+				t.Errorf("got claims %+v, want %+v", claims, test.claims)
+			}
+		})
+	}
+}
+
+func TestAttestationResultEncoding(t *testing.T) {
+	result := &AttestationResult{
+		Status:      TierAffirming,
+		TrustVector: TrustVector{InstanceIdentity: 2, Hardware: 2},
+		IssuedAt:    time.Unix(1000, 0),
+		Expiry:      time.Unix(2000, 0),
+		Nonce:       []byte("nonce"),
+		VerifierID:  DefaultVerifierID,
+		PolicyID:    "policy",
+		Machine:     &MachineClaims{SecureBoot: true, KernelDigest: []byte{0xfb, 0xff}, TEE: TEETdx},
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims struct {
+		Profile string `json:"eat_profile"`
+		Expiry  int64  `json:"exp"`
+		Nonce   string `json:"eat_nonce"`
+		Submods map[string]struct {
+			Status      string         `json:"ear.status"`
+			TrustVector map[string]int `json:"ear.trustworthiness-vector"`
+			Machine     struct {
+				SecureBoot   bool   `json:"secure-boot"`
+				KernelDigest string `json:"kernel-digest"`
+				TEE          string `json:"tee"`
+			} `json:"machine"`
+		} `json:"submods"`
+	}
+	if err = json.Unmarshal(encoded, &claims); err != nil {
+		t.Fatal(err)
+	}
+	submod := claims.Submods[EARSubmodule]
+	if claims.Profile != EARProfile || claims.Expiry != 2000 || claims.Nonce != "bm9uY2U" || submod.Status != "affirming" ||
+		len(submod.TrustVector) != 2 || submod.TrustVector["hardware"] != 2 ||
+		!submod.Machine.SecureBoot || submod.Machine.KernelDigest != "-_8" || submod.Machine.TEE != "tdx" {
+		t.Errorf("unexpected JSON EAR: %s", encoded)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eccKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, signer := range []crypto.Signer{rsaKey, eccKey} {
+		token, err := result.SignCWT(signer)
+		if err != nil {
+			t.Fatalf("failed to sign EAR: %v", err)
+		}
+		msg, err := notinternal.DecodeCOSESign1(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = verifyCOSESign1(msg, signer.Public()); err != nil {
+			t.Errorf("failed to verify EAR signed with %T: %v", signer, err)
+		}
+		decoded, err := notinternal.UnmarshalCBOR(msg.Payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cborClaims := decoded.(map[int64]interface{})
+		cborSubmod := cborClaims[earClaimSubmods].(map[string]interface{})[EARSubmodule].(map[int64]interface{})
+		machine, _ := cborSubmod[earClaimMachine].(map[int64]interface{})
+		if cborClaims[earClaimProfile] != EARProfile || !bytes.Equal(cborClaims[earClaimNonce].([]byte), result.Nonce) ||
+			cborClaims[earClaimExpiry] != int64(2000) || cborSubmod[earClaimStatus] != int64(TierAffirming) ||
+			cborSubmod[earClaimPolicyID] != "policy" || machine[0] != true || machine[2] != TEETdx {
+			t.Errorf("unexpected CBOR EAR claims: %v", cborClaims)
+		}
+
+		jwt, err := result.SignJWT(signer)
+		if err != nil {
+			t.Fatalf("failed to sign JWT EAR: %v", err)
+		}
+		parts := strings.Split(jwt, ".")
+		if len(parts) != 3 {
+			t.Fatalf("JWT has %d parts", len(parts))
+		}
+		header, err := base64.RawURLEncoding.DecodeString(parts[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			t.Fatal(err)
+		}
+		alg, err := coseSignerAlgorithm(signer.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var jwsHeader map[string]string
+		if err = json.Unmarshal(header, &jwsHeader); err != nil {
+			t.Fatal(err)
+		}
+		if jwsHeader["alg"] != jwsSignerAlgorithms[alg] || jwsHeader["typ"] != "JWT" || !bytes.Equal(payload, encoded) {
+			t.Errorf("unexpected JWT header %s or payload %s", header, payload)
+		}
+		hash, err := notinternal.COSEAlgorithms[alg].Hash.Hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		hasher := hash.New()
+		hasher.Write([]byte(parts[0] + "." + parts[1]))
+		digest := hasher.Sum(nil)
+		switch pub := signer.Public().(type) {
+		case *rsa.PublicKey:
+			err = rsa.VerifyPSS(pub, hash, digest, sig, nil)
+		case *ecdsa.PublicKey:
+			half := len(sig) / 2
+			if !ecdsa.Verify(pub, digest, new(big.Int).SetBytes(sig[:half]), new(big.Int).SetBytes(sig[half:])) {
+				err = errors.New("invalid ECDSA signature")
+			}
+		}
+		if err != nil {
+			t.Errorf("failed to verify JWT EAR signed with %T: %v", signer, err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/subtle"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+	"github.com/google/go-tpm/tpm2"
+	"google.golang.org/protobuf/proto"
+)
+
+// EAT is an Entity Attestation Token, as created by client.COSESigner.SignEAT.
+type EAT struct {
+	// The claims of the token.
+	Nonce       []byte
+	IssuedAt    time.Time
+	Profile     string
+	Attestation *attestpb.Attestation
+	// The COSE_Sign1 message containing the claims.
+	Message *notinternal.COSESign1
+}
+
+// ParseEAT decodes an Entity Attestation Token created by
+// client.COSESigner.SignEAT, without verifying it. Use VerifyEAT to verify the
+// token and its Attestation.
+func ParseEAT(token []byte) (*EAT, error) {
+	msg, err := notinternal.DecodeCOSESign1(token)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := notinternal.UnmarshalCBOR(msg.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode EAT claims: %w", err)
+	}
+	claims, ok := decoded.(map[int64]interface{})
+	if !ok {
+		return nil, fmt.Errorf("EAT claims are not a map")
+	}
+
+	eat := &EAT{Message: msg, Attestation: &attestpb.Attestation{}}
+	var iat int64
+	var encoded []byte
+	var ok1, ok2, ok3, ok4 bool
+	eat.Nonce, ok1 = claims[client.EATClaimNonce].([]byte)
+	iat, ok2 = claims[client.EATClaimIssuedAt].(int64)
+	eat.Profile, ok3 = claims[client.EATClaimProfile].(string)
+	encoded, ok4 = claims[client.EATClaimAttestation].([]byte)
+	if !ok1 || !ok2 || !ok3 || !ok4 {
+		return nil, fmt.Errorf("EAT is missing the nonce, issued at, profile or attestation claims")
+	}
+	if eat.Profile != client.EATProfile {
+		return nil, fmt.Errorf("unsupported EAT profile %q, expected %q", eat.Profile, client.EATProfile)
+	}
+	eat.IssuedAt = time.Unix(iat, 0)
+	if err = proto.Unmarshal(encoded, eat.Attestation); err != nil {
+		return nil, fmt.Errorf("failed to decode EAT attestation: %w", err)
+	}
+	return eat, nil
+}
+
+// VerifyEAT verifies an Entity Attestation Token created by
+// client.COSESigner.SignEAT, returning the MachineState of its Attestation. It
+// checks that:
+//   - the token is signed by the AK of the Attestation, which is trusted
+//     according to the opts (see VerifyAttestation)
+//   - the token contains opts.Nonce
+//   - the Attestation passes VerifyAttestation with the opts
+//
+// The time at which the token was issued is not checked, as freshness is given
+// by the nonce. Callers can check it with ParseEAT.
+func VerifyEAT(token []byte, opts VerifyOpts) (*attestpb.MachineState, error) {
+	eat, err := ParseEAT(token)
+	if err != nil {
+		return nil, err
+	}
+	akPub, _, err := trustedAKPublicKey(eat.Attestation, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = verifyCOSESign1(eat.Message, akPub); err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(eat.Nonce, opts.Nonce) == 0 {
+		return nil, fmt.Errorf("EAT nonce does not match the expected nonce")
+	}
+	return VerifyAttestation(eat.Attestation, opts)
+}
+
+// verifyCOSESign1 verifies the signature of a COSE_Sign1 message with the
+// public key, which must match the message's algorithm.
+func verifyCOSESign1(msg *notinternal.COSESign1, pubKey crypto.PublicKey) error {
+	alg, ok := notinternal.COSEAlgorithms[msg.Algorithm]
+	if !ok {
+		return fmt.Errorf("unsupported COSE algorithm %d", msg.Algorithm)
+	}
+	hash, err := alg.Hash.Hash()
+	if err != nil {
+		return err
+	}
+	toBeSigned, err := notinternal.COSESign1ToBeSigned(msg.Protected, msg.Payload)
+	if err != nil {
+		return err
+	}
+	hasher := hash.New()
+	hasher.Write(toBeSigned)
+	digest := hasher.Sum(nil)
+
+	switch pub := pubKey.(type) {
+	case *rsa.PublicKey:
+		if alg.Curve != 0 {
+			return fmt.Errorf("COSE algorithm %d cannot be used with an RSA key", msg.Algorithm)
+		}
+		if alg.Scheme == tpm2.AlgRSAPSS {
+			err = rsa.VerifyPSS(pub, hash, digest, msg.Signature, nil)
+		} else {
+			err = rsa.VerifyPKCS1v15(pub, hash, digest, msg.Signature)
+		}
+		if err != nil {
+			return fmt.Errorf("COSE signature verification failed: %w", err)
+		}
+	case *ecdsa.PublicKey:
+		curve, err := goCurveToCurveID(pub.Curve)
+		if alg.Curve == 0 || err != nil || curve != alg.Curve {
+			return fmt.Errorf("COSE algorithm %d cannot be used with an ECC key on curve %s", msg.Algorithm, pub.Curve.Params().Name)
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(msg.Signature) != 2*size {
+			return fmt.Errorf("COSE ECDSA signature has length %d, expected %d", len(msg.Signature), 2*size)
+		}
+		r := new(big.Int).SetBytes(msg.Signature[:size])
+		s := new(big.Int).SetBytes(msg.Signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return fmt.Errorf("COSE signature verification failed")
+		}
+	default:
+		return fmt.Errorf("unsupported public key type: %T", pub)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"bytes"
+	"crypto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+func TestVerifyEAT(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	nonce := []byte("super secret nonce")
+	for _, newAK := range []func() (*client.Key, error){
+		func() (*client.Key, error) { return client.AttestationKeyRSA(rwc) },
+		func() (*client.Key, error) { return client.AttestationKeyECC(rwc) },
+	} {
+		ak, err := newAK()
+		if err != nil {
+			t.Fatalf("failed to generate AK: %v", err)
+		}
+		defer ak.Close()
+		attestation, err := ak.Attest(nonce, nil)
+		if err != nil {
+			t.Fatalf("failed to attest: %v", err)
+		}
+		signer, err := ak.COSESigner()
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := signer.SignEAT(attestation, nonce)
+		if err != nil {
+			t.Fatalf("failed to sign EAT: %v", err)
+		}
+
+		eat, err := ParseEAT(token)
+		if err != nil {
+			t.Fatalf("failed to parse EAT: %v", err)
+		}
+		if !bytes.Equal(eat.Nonce, nonce) || time.Since(eat.IssuedAt) > time.Minute {
+			t.Errorf("unexpected EAT claims: nonce %q issued at %v", eat.Nonce, eat.IssuedAt)
+		}
+		kid, err := signer.KeyID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if eat.Message.Algorithm != signer.Algorithm() || !bytes.Equal(eat.Message.KeyID, kid) {
+			t.Errorf("EAT has algorithm %d and key ID %x, expected %d and %x", eat.Message.Algorithm, eat.Message.KeyID, signer.Algorithm(), kid)
+		}
+
+		opts := VerifyOpts{Nonce: nonce, TrustedAKs: []crypto.PublicKey{ak.PublicKey()}}
+		state, err := VerifyEAT(token, opts)
+		if err != nil {
+			t.Fatalf("failed to verify EAT: %v", err)
+		}
+		if len(state.GetRawEvents()) == 0 {
+			t.Error("expected MachineState to contain events")
+		}
+
+		tampered := append([]byte{}, token...)
+		tampered[len(tampered)-1] ^= 1
+		if _, err := VerifyEAT(tampered, opts); err == nil {
+			t.Error("expected verifying an EAT with an invalid signature to fail")
+		}
+		if _, err := VerifyEAT(token, VerifyOpts{Nonce: []byte("wrong nonce"), TrustedAKs: opts.TrustedAKs}); err == nil {
+			t.Error("expected verifying an EAT with the wrong nonce to fail")
+		}
+	}
+}
+
+func TestVerifyEATOtherSigner(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ak, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer ak.Close()
+	nonce := []byte("super secret nonce")
+	attestation, err := ak.Attest(nonce, nil)
+	if err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+	otherAK, err := client.AttestationKeyRSA(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer otherAK.Close()
+	signer, err := otherAK.COSESigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := signer.SignEAT(attestation, nonce)
+	if err != nil {
+		t.Fatalf("failed to sign EAT: %v", err)
+	}
+	opts := VerifyOpts{Nonce: nonce, TrustedAKs: []crypto.PublicKey{ak.PublicKey(), otherAK.PublicKey()}}
+	if _, err := VerifyEAT(token, opts); err == nil {
+		t.Error("expected verifying an EAT not signed by the attestation's AK to fail")
+	}
+
+	// Changing the profile invalidates the token.
+	msg, err := notinternal.DecodeCOSESign1(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg.Payload = bytes.Replace(msg.Payload, []byte(client.EATProfile), []byte(strings.Repeat("x", len(client.EATProfile))), 1)
+	other, err := notinternal.EncodeCOSESign1(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseEAT(other); err == nil {
+		t.Error("expected parsing an EAT with another profile to fail")
+	}
+}
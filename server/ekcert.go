@@ -0,0 +1,107 @@
+package server
+
+import (
+	"crypto/x509"
+	_ "embed" // Necessary to use go:embed
+	"encoding/asn1"
+	"fmt"
+)
+
+// TPM manufacturer EK root CA certificates (DER encoded), trusted by default
+// by VerifyEKCert (see DefaultEKRoots).
+var (
+	//go:embed ek-roots/NTCTPMEKRootCA01.crt
+	NuvotonEKRootCA01Cert []byte
+	//go:embed ek-roots/NTCTPMEKRootCA02.crt
+	NuvotonEKRootCA02Cert []byte
+)
+
+// DefaultEKRoots returns the TPM manufacturer root CAs embedded in this
+// package, which VerifyEKCert trusts in addition to VerifyEKOpts.Roots: the
+// Nuvoton NTC TPM EK Root CAs 01 and 02. The roots of other manufacturers
+// (such as Infineon, STMicroelectronics or NationZ) must be added with
+// VerifyEKOpts.Roots; the published STMicroelectronics roots cannot be used,
+// as crypto/x509 rejects their negative serial numbers.
+func DefaultEKRoots() []*x509.Certificate {
+	var roots []*x509.Certificate
+	for _, der := range [][]byte{NuvotonEKRootCA01Cert, NuvotonEKRootCA02Cert} {
+		root, err := x509.ParseCertificate(der)
+		if err != nil {
+			panic(fmt.Sprintf("failed to parse embedded EK root: %v", err))
+		}
+		roots = append(roots, root)
+	}
+	return roots
+}
+
+// Extension identifiers used in the "TCG EK Credential Profile" - v2.3 - Section 3.
+var (
+	// Subject Directory Attributes, holding the TPM specification version
+	oidSubjectDirectoryAttributes = asn1.ObjectIdentifier{2, 5, 29, 9}
+	// Subject Alternative Name, holding the TPM manufacturer, model and version
+	oidSubjectAltName = asn1.ObjectIdentifier{2, 5, 29, 17}
+)
+
+// VerifyEKOpts holds the certificates used when verifying an EK certificate.
+type VerifyEKOpts struct {
+	// Roots are TPM manufacturer root CAs trusted to issue EK certificates,
+	// in addition to DefaultEKRoots. This allows additional manufacturers to
+	// be supported.
+	Roots []*x509.Certificate
+	// Intermediates are untrusted certificates which may be used to build a
+	// chain from the EK certificate to a trusted root.
+	Intermediates []*x509.Certificate
+	// If true, only Roots are trusted, not DefaultEKRoots.
+	ExcludeDefaultRoots bool
+}
+
+// VerifyEKCert checks that the EK certificate (as returned by
+// client.GetEKCert) chains up to a trusted TPM manufacturer root CA. This
+// allows a verifier to check that an EK belongs to a genuine TPM. A nil
+// VerifyEKOpts is equivalent to the zero value.
+//
+// EK certificates often mark TCG-specific extensions as critical, and use the
+// tcg-kp-EKCertificate extended key usage. These are accepted by VerifyEKCert,
+// but the contents of these extensions are not checked.
+func VerifyEKCert(ekCert *x509.Certificate, opts *VerifyEKOpts) error {
+	if opts == nil {
+		opts = &VerifyEKOpts{}
+	}
+	roots := x509.NewCertPool()
+	if !opts.ExcludeDefaultRoots {
+		for _, root := range DefaultEKRoots() {
+			roots.AddCert(root)
+		}
+	}
+	for _, root := range opts.Roots {
+		roots.AddCert(root)
+	}
+	intermediates := x509.NewCertPool()
+	for _, intermediate := range opts.Intermediates {
+		intermediates.AddCert(intermediate)
+	}
+
+	_, err := withTCGExtensionsHandled(ekCert).Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to verify EK certificate: %w", err)
+	}
+	return nil
+}
+
+// withTCGExtensionsHandled returns a copy of the certificate, with the critical
+// TCG extensions (of EK certificates, and of the AK certificates of some vTPMs)
+// marked as handled.
+func withTCGExtensionsHandled(tpmCert *x509.Certificate) *x509.Certificate {
+	cert := *tpmCert
+	cert.UnhandledCriticalExtensions = nil
+	for _, oid := range tpmCert.UnhandledCriticalExtensions {
+		if !oid.Equal(oidSubjectDirectoryAttributes) && !oid.Equal(oidSubjectAltName) {
+			cert.UnhandledCriticalExtensions = append(cert.UnhandledCriticalExtensions, oid)
+		}
+	}
+	return &cert
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	priv crypto.Signer
+}
+
+func newTestCert(t *testing.T, template *x509.Certificate, parent *testCA) *testCA {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	parentCert, parentPriv := template, crypto.Signer(priv)
+	if parent != nil {
+		parentCert, parentPriv = parent.cert, parent.priv
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, priv.Public(), parentPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert, priv}
+}
+
+func newTestCA(t *testing.T, name string, serial int64, parent *testCA) *testCA {
+	return newTestCert(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: name},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, parent)
+}
+
+func newTestEKCert(t *testing.T, parent *testCA) *x509.Certificate {
+	// TPM specification version, as in the TCG EK Credential Profile.
+	attrs, err := asn1.Marshal([]struct {
+		Type   asn1.ObjectIdentifier
+		Values []asn1.RawValue `asn1:"set"`
+	}{{
+		Type:   asn1.ObjectIdentifier{2, 23, 133, 2, 16},
+		Values: []asn1.RawValue{{FullBytes: []byte{0x30, 0x03, 0x0c, 0x01, '2'}}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newTestCert(t, &x509.Certificate{
+		SerialNumber:       big.NewInt(100),
+		KeyUsage:           x509.KeyUsageKeyEncipherment,
+		UnknownExtKeyUsage: []asn1.ObjectIdentifier{{2, 23, 133, 8, 1}},
+		ExtraExtensions: []pkix.Extension{{
+			Id:       oidSubjectDirectoryAttributes,
+			Critical: true,
+			Value:    attrs,
+		}},
+	}, parent).cert
+}
+
+func TestVerifyEKCert(t *testing.T) {
+	root := newTestCA(t, "Test TPM Root CA", 1, nil)
+	intermediate := newTestCA(t, "Test TPM Intermediate CA", 2, root)
+	ekCert := newTestEKCert(t, intermediate)
+
+	opts := &VerifyEKOpts{
+		Roots:         []*x509.Certificate{root.cert},
+		Intermediates: []*x509.Certificate{intermediate.cert},
+	}
+	if err := VerifyEKCert(ekCert, opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(ekCert.UnhandledCriticalExtensions) != 1 {
+		t.Error("VerifyEKCert modified the provided certificate")
+	}
+}
+
+func TestVerifyEKCertFailures(t *testing.T) {
+	root := newTestCA(t, "Test TPM Root CA", 1, nil)
+	intermediate := newTestCA(t, "Test TPM Intermediate CA", 2, root)
+	otherRoot := newTestCA(t, "Other Root CA", 3, nil)
+	ekCert := newTestEKCert(t, intermediate)
+
+	tests := []struct {
+		name string
+		opts *VerifyEKOpts
+	}{
+		{"NilOpts", nil},
+		{"NoRoots", &VerifyEKOpts{Intermediates: []*x509.Certificate{root.cert, intermediate.cert}}},
+		{"ExcludeDefaultRoots", &VerifyEKOpts{
+			Intermediates:       []*x509.Certificate{intermediate.cert},
+			Roots:               DefaultEKRoots(),
+			ExcludeDefaultRoots: true,
+		}},
+		{"MissingIntermediate", &VerifyEKOpts{Roots: []*x509.Certificate{root.cert}}},
+		{"WrongRoot", &VerifyEKOpts{
+			Roots:         []*x509.Certificate{otherRoot.cert},
+			Intermediates: []*x509.Certificate{intermediate.cert},
+		}},
+		{"IntermediateAsRoot", &VerifyEKOpts{
+			Intermediates: []*x509.Certificate{root.cert, intermediate.cert},
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := VerifyEKCert(ekCert, tc.opts); err == nil {
+				t.Error("expected EK certificate verification to fail")
+			}
+		})
+	}
+}
+
+func TestDefaultEKRoots(t *testing.T) {
+	// The SHA-256 fingerprints of the certificates published by Nuvoton.
+	want := map[string]string{
+		"NTC TPM EK Root CA 01": "56b67007f448bd5c5746299fcdea9323971bbdaaefd8e3b9b84773abc888c90e",
+		"NTC TPM EK Root CA 02": "8fac94b462b37acb84431bdc71395585caf518c17f656051ff9c4347a4726ed9",
+	}
+	roots := DefaultEKRoots()
+	if len(roots) != len(want) {
+		t.Fatalf("got %d default EK roots, want %d", len(roots), len(want))
+	}
+	for _, root := range roots {
+		fingerprint := sha256.Sum256(root.Raw)
+		if got := hex.EncodeToString(fingerprint[:]); got != want[root.Subject.CommonName] {
+			t.Errorf("got fingerprint %s for %q", got, root.Subject.CommonName)
+		}
+		if !root.IsCA || root.Subject.String() != root.Issuer.String() {
+			t.Errorf("%q is not a self-signed CA", root.Subject.CommonName)
+		}
+
+		// The default roots are trusted without any options, and only then.
+		if err := VerifyEKCert(root, nil); err != nil {
+			t.Errorf("default root %q is not trusted: %v", root.Subject.CommonName, err)
+		}
+		if err := VerifyEKCert(root, &VerifyEKOpts{ExcludeDefaultRoots: true}); err == nil {
+			t.Errorf("default root %q is trusted with ExcludeDefaultRoots", root.Subject.CommonName)
+		}
+	}
+
+	// Roots provided by the caller are trusted alongside the default roots.
+	root := newTestCA(t, "Test TPM Root CA", 1, nil)
+	if err := VerifyEKCert(newTestEKCert(t, root), &VerifyEKOpts{Roots: []*x509.Certificate{root.cert}}); err != nil {
+		t.Errorf("failed to verify with additional roots: %v", err)
+	}
+}
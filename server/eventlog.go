@@ -1,15 +1,15 @@
 package server
 
 import (
+	"bytes"
+	"crypto"
 	"errors"
 	"fmt"
-	"crypto"
 	"strconv"
-	"bytes"
 
-	"github.com/google/go-attestation/attest"
-	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
 	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+	"github.com/google/go-attestation/attest"
 	"github.com/google/go-tpm/tpm2"
 )
 
@@ -24,11 +24,11 @@ import (
 func ParseAndVerifyEventLog(rawEventLog []byte, pcrs *pb.PCRs) ([]attest.Event, error) {
 	attestPcrs, err := convertToAttestPcrs(pcrs)
 	if err != nil {
-		return nil, fmt.Errorf("received bad PCR proto: %v", err)
+		return nil, fmt.Errorf("received bad PCR proto: %w", err)
 	}
 	eventLog, err := attest.ParseEventLog(rawEventLog)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse event log: %v", err)
+		return nil, fmt.Errorf("failed to parse event log: %w", err)
 	}
 	return eventLog.Verify(attestPcrs)
 }
@@ -36,15 +36,26 @@ func ParseAndVerifyEventLog(rawEventLog []byte, pcrs *pb.PCRs) ([]attest.Event,
 func parseReplayHelper(rawEventLog []byte, pcrs *pb.PCRs) ([]attest.Event, error) {
 	attestPcrs, err := convertToAttestPcrs(pcrs)
 	if err != nil {
-		return nil, fmt.Errorf("received bad PCR proto: %v", err)
+		return nil, fmt.Errorf("received bad PCR proto: %w", err)
 	}
 	eventLog, err := attest.ParseEventLog(rawEventLog)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse event log: %v", err)
+		return nil, fmt.Errorf("failed to parse event log: %w", err)
 	}
 	events, err := eventLog.Verify(attestPcrs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to replay event log: %v", err)
+		return nil, fmt.Errorf("failed to replay event log: %w", err)
+	}
+	// The StartupLocality event is not extended, so it is not returned by
+	// Verify. However, it sets the initial value of PCR0 (e.g. to 4 with an
+	// H-CRTM), so keep it for PredictPCRs if PCR0 was replayed.
+	if _, ok := pcrs.GetPcrs()[0]; ok {
+		for _, event := range eventLog.Events(attest.HashSHA1) {
+			if event.Index == 0 && event.Type == attest.EventType(NoAction) && isStartupLocality(event.Data) {
+				events = append([]attest.Event{event}, events...)
+				break
+			}
+		}
 	}
 	return events, nil
 }
@@ -67,6 +78,19 @@ func convertToPbEvents(hash crypto.Hash, events []attest.Event) []*attestpb.Even
 	return pbEvents
 }
 
+// ParseMachineState parses a raw event log and replays the parsed event log
+// against the given PCR values. It returns the corresponding MachineState
+// containing the events verified by particular PCR indexes/digests. It
+// returns an error if the replay for any PCR index does not match the
+// provided value.
+//
+// The returned MachineState may be a partial MachineState where fields can
+// be empty if the corresponding events could not be parsed or verified. The
+// SecureBootState is only set if the PCRs include PCR7.
+//
+// It is the caller's responsibility to ensure that the passed PCR values can
+// be trusted. Users can establish trust in PCR values by either calling
+// client.ReadPCRs() themselves or by verifying the values via a PCR quote.
 func ParseMachineState(rawEventLog []byte, pcrs *pb.PCRs) (*attestpb.MachineState, error) {
 	events, err := parseReplayHelper(rawEventLog, pcrs)
 	if err != nil {
@@ -83,15 +107,33 @@ func ParseMachineState(rawEventLog []byte, pcrs *pb.PCRs) (*attestpb.MachineStat
 		// those individually fail parsing. The error will contain suberrors
 		// for the fields in MachineState that failed parsing.
 		//
-		// For now, since the MachineState only comprises PlatformState, we
-		// return an empty MachineState with empty platform state and the error.
+		// For now, we return an empty MachineState and the error if any of
+		// the states fails parsing.
 		return &attestpb.MachineState{}, err
 	}
+	// The Secure Boot state can only be known if PCR7 was replayed.
+	var secureBoot *attestpb.SecureBootState
+	if _, ok := pcrs.GetPcrs()[7]; ok {
+		if secureBoot, err = getSecureBootState(cryptoHash, rawEvents); err != nil {
+			return &attestpb.MachineState{}, fmt.Errorf("failed to parse the Secure Boot state: %w", err)
+		}
+	}
+	// The GRUB commands are measured into PCR8 (and the files into PCR9).
+	var grub *attestpb.GrubState
+	var linuxKernel *attestpb.LinuxKernelState
+	if _, ok := pcrs.GetPcrs()[8]; ok {
+		if grub, linuxKernel, err = getGrubState(cryptoHash, rawEvents); err != nil {
+			return &attestpb.MachineState{}, fmt.Errorf("failed to parse the GRUB state: %w", err)
+		}
+	}
 
 	return &attestpb.MachineState{
-		Platform:  platform,
-		RawEvents: rawEvents,
-		Hash:      pcrs.GetHash(),
+		Platform:    platform,
+		SecureBoot:  secureBoot,
+		RawEvents:   rawEvents,
+		Hash:        pcrs.GetHash(),
+		Grub:        grub,
+		LinuxKernel: linuxKernel,
 	}, nil
 }
 
@@ -116,19 +158,10 @@ func convertToAttestPcrs(pcrProto *pb.PCRs) ([]attest.PCR, error) {
 	return attestPcrs, nil
 }
 
-
 func getPlatformState(hash crypto.Hash, events []*attestpb.Event) (*attestpb.PlatformState, error) {
 	// We pre-compute the separator event hash, and check if the event type has
 	// been modified. We only trust events that come before a valid separator.
-	hasher := hash.New()
-	// From the PC Client Firmware Profile spec, on the separator event:
-	// The event field MUST contain the hex value 00000000h or FFFFFFFFh.
-	separatorData := [][]byte{{0, 0, 0, 0}, {0xff, 0xff, 0xff, 0xff}}
-	separatorDigests := make([][]byte, 0, len(separatorData))
-	for _, value := range separatorData {
-		hasher.Write(value)
-		separatorDigests = append(separatorDigests, hasher.Sum(nil))
-	}
+	separatorDigests := getSeparatorDigests(hash)
 
 	var versionString []byte
 	var nonHostInfo []byte
@@ -139,20 +172,11 @@ func getPlatformState(hash crypto.Hash, events []*attestpb.Event) (*attestpb.Pla
 		}
 		evtType := event.GetUntrustedType()
 
-		// Make sure we have a valid separator event, we check any event that
-		// claims to be a Separator or "looks like" a separator to prevent
-		// certain vulnerabilities in event parsing. For more info see:
-		// https://github.com/google/go-attestation/blob/master/docs/event-log-disclosure.md
-		if (evtType == Separator) || contains(separatorDigests, event.GetDigest()) {
-			if evtType != Separator {
-				return nil, fmt.Errorf("PCR%d event contains separator data but non-separator type %d", index, evtType)
-			}
-			if !event.GetDigestVerified() {
-				return nil, fmt.Errorf("unverified separator digest for PCR%d", index)
-			}
-			if !contains(separatorData, event.GetData()) {
-				return nil, fmt.Errorf("invalid separator data for PCR%d", index)
-			}
+		isSeparator, err := checkIfValidSeparator(event, separatorDigests)
+		if err != nil {
+			return nil, err
+		}
+		if isSeparator {
 			// Don't trust any PCR0 events after the separator
 			break
 		}
@@ -186,6 +210,42 @@ func getPlatformState(hash crypto.Hash, events []*attestpb.Event) (*attestpb.Pla
 	return state, nil
 }
 
+// From the PC Client Firmware Profile spec, on the separator event:
+// The event field MUST contain the hex value 00000000h or FFFFFFFFh.
+var separatorData = [][]byte{{0, 0, 0, 0}, {0xff, 0xff, 0xff, 0xff}}
+
+func getSeparatorDigests(hash crypto.Hash) [][]byte {
+	separatorDigests := make([][]byte, 0, len(separatorData))
+	for _, value := range separatorData {
+		hasher := hash.New()
+		hasher.Write(value)
+		separatorDigests = append(separatorDigests, hasher.Sum(nil))
+	}
+	return separatorDigests
+}
+
+// Make sure we have a valid separator event, we check any event that claims to
+// be a Separator or "looks like" a separator to prevent certain
+// vulnerabilities in event parsing. For more info see:
+// https://github.com/google/go-attestation/blob/master/docs/event-log-disclosure.md
+func checkIfValidSeparator(event *attestpb.Event, separatorDigests [][]byte) (bool, error) {
+	evtType := event.GetUntrustedType()
+	index := event.GetPcrIndex()
+	if (evtType != Separator) && !contains(separatorDigests, event.GetDigest()) {
+		return false, nil
+	}
+	if evtType != Separator {
+		return false, fmt.Errorf("PCR%d event contains separator data but non-separator type %d", index, evtType)
+	}
+	if !event.GetDigestVerified() {
+		return false, fmt.Errorf("unverified separator digest for PCR%d", index)
+	}
+	if !contains(separatorData, event.GetData()) {
+		return false, fmt.Errorf("invalid separator data for PCR%d", index)
+	}
+	return true, nil
+}
+
 // ConvertSCRTMVersionToGCEFirmwareVersion attempts to parse the Firmware
 // Version of a GCE VM from the bytes of the version string of the SCRTM. This
 // data should come from a valid and verified EV_S_CRTM_VERSION event.
@@ -217,8 +277,6 @@ func ConvertSCRTMVersionToGCEFirmwareVersion(version []byte) (uint32, error) {
 	return uint32(versionNum), nil
 }
 
-
-
 // Expected Firmware/PCR0 Event Types.
 //
 // Taken from TCG PC Client Platform Firmware Profile Specification,
@@ -230,6 +288,24 @@ const (
 	NonhostInfo  uint32 = 0x00000011
 )
 
+// Expected Secure Boot/PCR7 Event Types.
+//
+// Taken from TCG PC Client Platform Firmware Profile Specification,
+// Table 14 Events.
+const (
+	EFIVariableDriverConfig uint32 = 0x80000001
+	EFIAction               uint32 = 0x80000007
+	EFIVariableAuthority    uint32 = 0x800000E0
+)
+
+// Expected GRUB/PCR8 and PCR9 Event Types.
+//
+// Taken from TCG PC Client Platform Firmware Profile Specification,
+// Table 14 Events.
+const (
+	IPL uint32 = 0x0000000D
+)
+
 var (
 	// GCENonHostInfoSignature identifies the GCE Non-Host info event, which
 	// indicates if memory encryption is enabled. This event is 32-bytes consisting
@@ -274,4 +350,3 @@ func contains(set [][]byte, value []byte) bool {
 	}
 	return false
 }
-
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"bytes"
 	"encoding/hex"
 	"fmt"
 	"testing"
@@ -232,6 +233,64 @@ func TestParseEventLogs(t *testing.T) {
 	}
 }
 
+func TestParseMachineState(t *testing.T) {
+	logs := []struct {
+		eventLog
+		name string
+	}{
+		{Debian10GCE, "Debian10GCE"},
+		{Rhel8GCE, "Rhel8GCE"},
+		{Ubuntu2104NoDbxGCE, "Ubuntu2104NoDbxGCE"},
+		{Ubuntu2104NoSecureBootGCE, "Ubuntu2104NoSecureBootGCE"},
+		{GlinuxNoSecureBootLaptop, "GlinuxNoSecureBootLaptop"},
+		{ArchLinuxWorkstation, "ArchLinuxWorkstation"},
+	}
+
+	for _, log := range logs {
+		rawLog := log.RawLog
+		for _, bank := range log.Banks {
+			hashName := pb.HashAlgo_name[int32(bank.Hash)]
+			subtestName := fmt.Sprintf("%s-%s", log.name, hashName)
+			t.Run(subtestName, func(t *testing.T) {
+				state, err := ParseMachineState(rawLog, bank)
+				if err != nil {
+					t.Fatalf("failed to parse machine state: %v", err)
+				}
+				if state.GetHash() != bank.GetHash() {
+					t.Errorf("got machine state hash %v, want %v", state.GetHash(), bank.GetHash())
+				}
+				if len(state.GetRawEvents()) == 0 {
+					t.Error("expected machine state to contain events")
+				}
+				for _, event := range state.GetRawEvents() {
+					if _, ok := bank.GetPcrs()[event.GetPcrIndex()]; !ok {
+						t.Errorf("got event for PCR%d, which is not in the replayed bank", event.GetPcrIndex())
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestParseEventLogModifiedPCR(t *testing.T) {
+	bank := Rhel8GCE.Banks[1]
+	modified := &pb.PCRs{Hash: bank.GetHash(), Pcrs: map[uint32][]byte{}}
+	for idx, digest := range bank.GetPcrs() {
+		modified.Pcrs[idx] = digest
+	}
+	modified.Pcrs[4] = make([]byte, len(bank.GetPcrs()[4]))
+
+	if _, err := ParseAndVerifyEventLog(Rhel8GCE.RawLog, modified); err == nil {
+		t.Error("expected replay failure when a PCR does not match the event log")
+	}
+	if _, err := ParseMachineState(Rhel8GCE.RawLog, modified); err == nil {
+		t.Error("expected machine state failure when a PCR does not match the event log")
+	}
+	if _, err := ParseAndVerifyEventLog(Rhel8GCE.RawLog, &pb.PCRs{Hash: bank.GetHash()}); err == nil {
+		t.Error("expected failure when no PCRs are provided")
+	}
+}
+
 func TestSystemParseEventLog(t *testing.T) {
 	rwc := test.GetTPM(t)
 	defer client.CheckedClose(t, rwc)
@@ -252,6 +311,50 @@ func TestSystemParseEventLog(t *testing.T) {
 	}
 }
 
+func TestSimulatedEventLogs(t *testing.T) {
+	logs := []struct {
+		eventLog
+		name string
+	}{
+		{Debian10GCE, "Debian10GCE"},
+		{Rhel8GCE, "Rhel8GCE"},
+		{Ubuntu2104NoDbxGCE, "Ubuntu2104NoDbxGCE"},
+		{Ubuntu2104NoSecureBootGCE, "Ubuntu2104NoSecureBootGCE"},
+		{GlinuxNoSecureBootLaptop, "GlinuxNoSecureBootLaptop"},
+		{ArchLinuxWorkstation, "ArchLinuxWorkstation"},
+	}
+
+	for _, log := range logs {
+		t.Run(log.name, func(t *testing.T) {
+			rwc := test.GetTPMWithEventLog(t, log.RawLog)
+			defer client.CheckedClose(t, rwc)
+
+			evtLog, err := client.GetEventLog(rwc)
+			if err != nil {
+				t.Fatalf("failed to retrieve Event Log: %v", err)
+			}
+			for _, bank := range log.Banks {
+				sel := tpm2.PCRSelection{Hash: tpm2.Algorithm(bank.GetHash())}
+				for idx := range bank.GetPcrs() {
+					sel.PCRs = append(sel.PCRs, int(idx))
+				}
+				pcrs, err := client.ReadPCRs(rwc, sel)
+				if err != nil {
+					t.Fatalf("failed to read PCRs: %v", err)
+				}
+				for idx, want := range bank.GetPcrs() {
+					if got := pcrs.GetPcrs()[idx]; !bytes.Equal(got, want) {
+						t.Errorf("%v PCR%d: got %x, want %x", bank.GetHash(), idx, got, want)
+					}
+				}
+				if _, err = ParseMachineState(evtLog, pcrs); err != nil {
+					t.Errorf("failed to parse machine state: %v", err)
+				}
+			}
+		})
+	}
+}
+
 func decodeHex(hexStr string) []byte {
 	bytes, err := hex.DecodeString(hexStr)
 	if err != nil {
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"strings"
+
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+// The prefixes of the events GRUB measures into PCR8. The digest of an event
+// is the hash of the text after its prefix. Older GRUBs (such as Red Hat's)
+// use the prefixes without a colon, and also measure the NUL terminator.
+var grubCommandPrefixes = []string{
+	"grub_cmd: ",
+	"kernel_cmdline: ",
+	"module_cmdline: ",
+	"grub_cmd ",
+	"grub_kernel_cmdline ",
+}
+
+// The GRUB commands loading the kernel and the initrds.
+var (
+	grubLinuxCommands  = []string{"linux", "linuxefi", "linux16"}
+	grubInitrdCommands = []string{"initrd", "initrdefi", "initrd16"}
+)
+
+// What the PCR9 events following a GRUB command are.
+type grubLoading int
+
+const (
+	loadingOther grubLoading = iota
+	loadingKernel
+	loadingInitrd
+)
+
+// getGrubState parses the GRUB commands measured into PCR8 and the files
+// measured into PCR9. It also returns the state of the Linux kernel booted by
+// GRUB, or nil if no kernel was booted. If GRUB did not boot the machine (no
+// GRUB command was measured), both states are nil.
+//
+// The digests of the commands are verified, but the digests of the files are
+// the hashes of the files, so their (file name) data is untrusted.
+func getGrubState(hash crypto.Hash, events []*attestpb.Event) (*attestpb.GrubState, *attestpb.LinuxKernelState, error) {
+	grub := &attestpb.GrubState{}
+	var kernel *attestpb.LinuxKernelState
+	var unknownEvent error
+	loading := loadingOther
+
+	for i, event := range events {
+		index := event.GetPcrIndex()
+		if (index != 8 && index != 9) || event.GetUntrustedType() != IPL {
+			continue
+		}
+		if index == 9 {
+			file := &attestpb.GrubFile{
+				Digest:            event.GetDigest(),
+				UntrustedFilename: event.GetData(),
+			}
+			grub.Files = append(grub.Files, file)
+			switch loading {
+			case loadingKernel:
+				kernel.KernelDigest = file.GetDigest()
+				loading = loadingOther
+			case loadingInitrd:
+				kernel.InitrdDigests = append(kernel.InitrdDigests, file.GetDigest())
+			}
+			continue
+		}
+
+		command, err := parseGrubCommand(hash, event)
+		if err != nil {
+			// Other boot loaders (such as systemd-boot) also measure into PCR8,
+			// so this is only an error if GRUB booted the machine.
+			if unknownEvent == nil {
+				unknownEvent = fmt.Errorf("PCR8 event %d: %w", i, err)
+			}
+			continue
+		}
+		grub.Commands = append(grub.Commands, command)
+
+		if cmdline, ok := grubKernelCmdline(command); ok {
+			if kernel == nil {
+				kernel = &attestpb.LinuxKernelState{}
+			}
+			if kernel.GetCommandLine() != "" {
+				return nil, nil, errors.New("more than one kernel command line was measured")
+			}
+			kernel.CommandLine = cmdline
+			continue
+		}
+		if strings.HasPrefix(command, "module_cmdline") {
+			continue
+		}
+		loading = loadingOther
+		args := strings.Fields(trimGrubPrefix(command))
+		if len(args) < 2 {
+			continue
+		}
+		switch {
+		case containsString(grubLinuxCommands, args[0]):
+			if kernel == nil {
+				kernel = &attestpb.LinuxKernelState{}
+			}
+			if kernel.GetKernelPath() != "" {
+				return nil, nil, errors.New("more than one kernel was loaded")
+			}
+			kernel.KernelPath = args[1]
+			loading = loadingKernel
+		case containsString(grubInitrdCommands, args[0]) && kernel != nil:
+			loading = loadingInitrd
+		}
+	}
+
+	if len(grub.GetCommands()) == 0 {
+		return nil, nil, nil
+	}
+	if unknownEvent != nil {
+		return nil, nil, unknownEvent
+	}
+	return grub, kernel, nil
+}
+
+// Returns the measured command (with its prefix and without its NUL
+// terminator), after checking the event digest.
+func parseGrubCommand(hash crypto.Hash, event *attestpb.Event) (string, error) {
+	data := event.GetData()
+	for _, prefix := range grubCommandPrefixes {
+		if !strings.HasPrefix(string(data), prefix) {
+			continue
+		}
+		measured := data[len(prefix):]
+		if data[len(data)-1] == 0 {
+			if !digestEquals(hash, measured, event.GetDigest()) {
+				measured = measured[:len(measured)-1]
+			}
+			data = data[:len(data)-1]
+		}
+		if !digestEquals(hash, measured, event.GetDigest()) {
+			return "", fmt.Errorf("GRUB command %q has an unverified digest", data)
+		}
+		return string(data), nil
+	}
+	return "", errors.New("not a GRUB command")
+}
+
+func trimGrubPrefix(command string) string {
+	for _, prefix := range grubCommandPrefixes {
+		if strings.HasPrefix(command, prefix) {
+			return command[len(prefix):]
+		}
+	}
+	return command
+}
+
+func grubKernelCmdline(command string) (string, bool) {
+	for _, prefix := range []string{"kernel_cmdline: ", "grub_kernel_cmdline "} {
+		if strings.HasPrefix(command, prefix) {
+			return command[len(prefix):], true
+		}
+	}
+	return "", false
+}
+
+func containsString(set []string, value string) bool {
+	for _, s := range set {
+		if s == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"testing"
+
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+func TestParseGrubState(t *testing.T) {
+	tests := []struct {
+		name        string
+		log         eventLog
+		commands    int
+		files       int
+		kernelPath  string
+		cmdlineArgs string
+		initrds     int
+	}{
+		{
+			name:        "Rhel8GCE",
+			log:         Rhel8GCE,
+			commands:    50,
+			files:       2,
+			kernelPath:  "(hd0,gpt2)/boot/vmlinuz-4.18.0-240.22.1.el8_3.x86_64",
+			cmdlineArgs: "root=UUID=f3948fb4-cce7-4193-940a-c50052e93bf3 ro net.ifnames=0 biosdevname=0 scsi_mod.use_blk_mq=Y crashkernel=auto console=ttyS0,38400n8",
+			initrds:     1,
+		},
+		{
+			name:        "Ubuntu2104NoDbxGCE",
+			log:         Ubuntu2104NoDbxGCE,
+			commands:    73,
+			files:       9,
+			kernelPath:  "/boot/vmlinuz-5.11.0-1008-gcp",
+			cmdlineArgs: "root=PARTUUID=bf817bdf-6a3a-4221-8edb-2c1ca7c5537f ro scsi_mod.use_blk_mq=Y ima_hash=sha256 console=ttyS0 panic=-1",
+		},
+		{
+			name:        "Ubuntu2104NoSecureBootGCE",
+			log:         Ubuntu2104NoSecureBootGCE,
+			commands:    67,
+			files:       9,
+			kernelPath:  "/boot/vmlinuz-5.11.0-1006-gcp",
+			cmdlineArgs: "root=PARTUUID=6443a6ae-e5e9-4df7-9a06-d1329e50f33c ro console=ttyS0 panic=-1",
+		},
+		// These machines were not booted by GRUB.
+		{name: "Debian10GCE", log: Debian10GCE},
+		{name: "GlinuxNoSecureBootLaptop", log: GlinuxNoSecureBootLaptop},
+		{name: "ArchLinuxWorkstation", log: ArchLinuxWorkstation},
+	}
+	for _, test := range tests {
+		for _, bank := range test.log.Banks {
+			t.Run(fmt.Sprintf("%s-%v", test.name, bank.GetHash()), func(t *testing.T) {
+				state, err := ParseMachineState(test.log.RawLog, bank)
+				if err != nil {
+					t.Fatal(err)
+				}
+				grub := state.GetGrub()
+				if test.commands == 0 {
+					if grub != nil || state.GetLinuxKernel() != nil {
+						t.Errorf("got GRUB state %v and kernel state %v, want none", grub, state.GetLinuxKernel())
+					}
+					return
+				}
+				if len(grub.GetCommands()) != test.commands {
+					t.Errorf("got %d GRUB commands, want %d", len(grub.GetCommands()), test.commands)
+				}
+				if len(grub.GetFiles()) != test.files {
+					t.Errorf("got %d GRUB files, want %d", len(grub.GetFiles()), test.files)
+				}
+
+				kernel := state.GetLinuxKernel()
+				if kernel.GetKernelPath() != test.kernelPath {
+					t.Errorf("got kernel path %q, want %q", kernel.GetKernelPath(), test.kernelPath)
+				}
+				wantCmdline := test.kernelPath + " " + test.cmdlineArgs
+				if kernel.GetCommandLine() != wantCmdline {
+					t.Errorf("got kernel command line %q, want %q", kernel.GetCommandLine(), wantCmdline)
+				}
+				if len(kernel.GetKernelDigest()) != len(bank.GetPcrs()[9]) {
+					t.Errorf("got kernel digest %x, want a digest of size %d", kernel.GetKernelDigest(), len(bank.GetPcrs()[9]))
+				}
+				if len(kernel.GetInitrdDigests()) != test.initrds {
+					t.Errorf("got %d initrd digests, want %d", len(kernel.GetInitrdDigests()), test.initrds)
+				}
+			})
+		}
+	}
+}
+
+// Creates the PCR8 event measuring a GRUB command.
+func grubCommandEvent(prefix, command string) *attestpb.Event {
+	digest := sha256.Sum256([]byte(command))
+	return &attestpb.Event{PcrIndex: 8, UntrustedType: IPL, Data: []byte(prefix + command + "\x00"), Digest: digest[:]}
+}
+
+// Creates the PCR9 event measuring a file read by GRUB.
+func grubFileEvent(filename string, contents string) *attestpb.Event {
+	digest := sha256.Sum256([]byte(contents))
+	return &attestpb.Event{PcrIndex: 9, UntrustedType: IPL, Data: []byte(filename + "\x00"), Digest: digest[:]}
+}
+
+func TestGetGrubState(t *testing.T) {
+	kernel := sha256.Sum256([]byte("kernel"))
+	initrd1 := sha256.Sum256([]byte("initrd 1"))
+	initrd2 := sha256.Sum256([]byte("initrd 2"))
+	events := []*attestpb.Event{
+		grubFileEvent("(hd0,gpt1)/boot/grub/grub.cfg", "config"),
+		grubCommandEvent("grub_cmd: ", "set root=hd0,gpt1"),
+		grubCommandEvent("grub_cmd: ", "linux /vmlinuz root=/dev/sda1 ro"),
+		grubFileEvent("/vmlinuz", "kernel"),
+		grubCommandEvent("kernel_cmdline: ", "/vmlinuz root=/dev/sda1 ro"),
+		grubCommandEvent("grub_cmd: ", "initrd /microcode.img /initrd.img"),
+		grubFileEvent("/microcode.img", "initrd 1"),
+		grubFileEvent("/initrd.img", "initrd 2"),
+		// Measured by the kernel's EFI stub, not by GRUB.
+		{PcrIndex: 9, UntrustedType: 0x6, Data: []byte("Linux initrd"), Digest: initrd2[:]},
+	}
+	grub, linux, err := getGrubState(crypto.SHA256, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(grub.GetCommands()) != 4 || len(grub.GetFiles()) != 4 {
+		t.Errorf("got %d commands and %d files, want 4 of each", len(grub.GetCommands()), len(grub.GetFiles()))
+	}
+	if grub.GetCommands()[0] != "grub_cmd: set root=hd0,gpt1" {
+		t.Errorf("got first command %q", grub.GetCommands()[0])
+	}
+	if linux.GetKernelPath() != "/vmlinuz" || linux.GetCommandLine() != "/vmlinuz root=/dev/sda1 ro" {
+		t.Errorf("got kernel %q with command line %q", linux.GetKernelPath(), linux.GetCommandLine())
+	}
+	if !bytes.Equal(linux.GetKernelDigest(), kernel[:]) {
+		t.Errorf("got kernel digest %x, want %x", linux.GetKernelDigest(), kernel)
+	}
+	initrds := linux.GetInitrdDigests()
+	if len(initrds) != 2 || !bytes.Equal(initrds[0], initrd1[:]) || !bytes.Equal(initrds[1], initrd2[:]) {
+		t.Errorf("got initrd digests %x, want [%x %x]", initrds, initrd1, initrd2)
+	}
+
+	// The command line measured by systemd-boot is not a GRUB command.
+	sdBoot := &attestpb.Event{PcrIndex: 8, UntrustedType: IPL, Data: []byte("i\x00n\x00i\x00t\x00r\x00d\x00\x00\x00")}
+	if grub, linux, err = getGrubState(crypto.SHA256, []*attestpb.Event{sdBoot}); err != nil || grub != nil || linux != nil {
+		t.Errorf("got GRUB state %v, kernel state %v and error %v, want none", grub, linux, err)
+	}
+
+	unverified := grubCommandEvent("grub_cmd: ", "set root=hd0,gpt1")
+	unverified.Data = []byte(strings.Replace(string(unverified.Data), "gpt1", "gpt2", 1))
+	invalid := map[string][]*attestpb.Event{
+		"Unverified":      {events[1], unverified},
+		"UnknownEvent":    {events[1], sdBoot},
+		"TwoCommandLines": {events[4], events[4]},
+		"TwoKernels":      {events[2], events[3], events[2]},
+	}
+	for name, events := range invalid {
+		if _, _, err := getGrubState(crypto.SHA256, events); err == nil {
+			t.Errorf("%s: expected parsing the GRUB state to fail", name)
+		}
+	}
+}
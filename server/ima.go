@@ -0,0 +1,393 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+	"github.com/google/go-tpm/tpm2"
+)
+
+// IMAPCR is the PCR used by default by the Linux Integrity Measurement
+// Architecture (IMA).
+const IMAPCR = 10
+
+// IMA template names with a known layout, as defined by the Linux kernel in
+// Documentation/security/IMA-templates.rst.
+const (
+	IMATemplate    = "ima"
+	IMANGTemplate  = "ima-ng"
+	IMASigTemplate = "ima-sig"
+	IMABufTemplate = "ima-buf"
+)
+
+// The "ima" template pads the file name to this length (plus a null
+// terminator) when computing its template digest.
+const imaEventNameLenMax = 255
+
+// Sanity limit on the size of a template name or template data.
+const imaMaxFieldSize = 1 << 20
+
+// IMAEvent is a single entry of the IMA runtime measurement list.
+type IMAEvent struct {
+	// The PCR this entry was extended into (usually IMAPCR).
+	PCR uint32
+	// The SHA-1 template digest recorded in the measurement list. For
+	// measurement violations, this digest is all zeros.
+	TemplateDigest []byte
+	// The name of the template describing TemplateData (e.g. "ima-ng").
+	TemplateName string
+	// The raw template data, as found in the binary measurement list. For
+	// templates other than "ima", this is a sequence of fields, each prefixed
+	// with a little-endian uint32 length.
+	TemplateData []byte
+
+	// The following fields are parsed from the TemplateData for templates
+	// with a known layout, and are left empty otherwise.
+
+	// The algorithm used to compute FileDigest (e.g. "sha256").
+	FileDigestAlg string
+	// The digest of the measured file (or buffer, for "ima-buf").
+	FileDigest []byte
+	// The path of the measured file, or a description of the measured buffer.
+	FileName string
+	// The file signature, only present for "ima-sig".
+	Signature []byte
+	// The measured buffer, only present for "ima-buf".
+	Buffer []byte
+}
+
+// Violation returns true if this entry records a measurement violation (such
+// as a file being measured while it was open for writing). In this case, the
+// PCR was extended with a digest of all 0xFF bytes.
+func (e *IMAEvent) Violation() bool {
+	return bytes.Equal(e.TemplateDigest, make([]byte, len(e.TemplateDigest)))
+}
+
+// ParseIMALog parses the binary IMA runtime measurement list, as found in
+// /sys/kernel/security/ima/binary_runtime_measurements. Only little-endian
+// lists are supported (i.e. lists from little-endian hosts, or lists
+// exported with ima_canonical_fmt).
+func ParseIMALog(rawLog []byte) ([]IMAEvent, error) {
+	var events []IMAEvent
+	r := bytes.NewReader(rawLog)
+	for r.Len() > 0 {
+		event, err := parseIMAEvent(r)
+		if err != nil {
+			return nil, fmt.Errorf("IMA event %d: %w", len(events), err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func parseIMAEvent(r io.Reader) (event IMAEvent, err error) {
+	if err = binary.Read(r, binary.LittleEndian, &event.PCR); err != nil {
+		return event, fmt.Errorf("reading PCR: %w", err)
+	}
+	event.TemplateDigest = make([]byte, crypto.SHA1.Size())
+	if _, err = io.ReadFull(r, event.TemplateDigest); err != nil {
+		return event, fmt.Errorf("reading template digest: %w", err)
+	}
+	name, err := readIMAField(r)
+	if err != nil {
+		return event, fmt.Errorf("reading template name: %w", err)
+	}
+	event.TemplateName = string(name)
+	if event.TemplateData, err = readIMAField(r); err != nil {
+		return event, fmt.Errorf("reading template data: %w", err)
+	}
+	if err = event.parseTemplateData(); err != nil {
+		return event, fmt.Errorf("parsing %q template data: %w", event.TemplateName, err)
+	}
+	return event, nil
+}
+
+// Reads a field prefixed with a little-endian uint32 length.
+func readIMAField(r io.Reader) ([]byte, error) {
+	var size uint32
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return nil, err
+	}
+	if size > imaMaxFieldSize {
+		return nil, fmt.Errorf("field size (%d) is too large", size)
+	}
+	field := make([]byte, size)
+	if _, err := io.ReadFull(r, field); err != nil {
+		return nil, err
+	}
+	return field, nil
+}
+
+func (e *IMAEvent) parseTemplateData() error {
+	r := bytes.NewReader(e.TemplateData)
+	if e.TemplateName == IMATemplate {
+		e.FileDigestAlg = "sha1"
+		e.FileDigest = make([]byte, crypto.SHA1.Size())
+		if _, err := io.ReadFull(r, e.FileDigest); err != nil {
+			return fmt.Errorf("reading file digest: %w", err)
+		}
+		name, err := readIMAField(r)
+		if err != nil {
+			return fmt.Errorf("reading file name: %w", err)
+		}
+		e.FileName = string(name)
+		return nil
+	}
+
+	var fields [][]byte
+	for r.Len() > 0 {
+		field, err := readIMAField(r)
+		if err != nil {
+			return fmt.Errorf("reading field %d: %w", len(fields), err)
+		}
+		fields = append(fields, field)
+	}
+
+	var numFields int
+	switch e.TemplateName {
+	case IMANGTemplate:
+		numFields = 2
+	case IMASigTemplate, IMABufTemplate:
+		numFields = 3
+	default:
+		// Unknown templates are only replayed, not parsed.
+		return nil
+	}
+	if len(fields) != numFields {
+		return fmt.Errorf("got %d fields, expected %d", len(fields), numFields)
+	}
+
+	// The d-ng field is "<algo>:\x00" followed by the digest.
+	sep := bytes.IndexByte(fields[0], 0)
+	if sep < 1 || fields[0][sep-1] != ':' {
+		return errors.New("invalid d-ng field")
+	}
+	e.FileDigestAlg = string(fields[0][:sep-1])
+	e.FileDigest = fields[0][sep+1:]
+	// The n-ng field is a null-terminated string.
+	e.FileName = string(bytes.TrimSuffix(fields[1], []byte{0}))
+	switch e.TemplateName {
+	case IMASigTemplate:
+		e.Signature = fields[2]
+	case IMABufTemplate:
+		e.Buffer = fields[2]
+	}
+	return nil
+}
+
+// ParseIMAASCIILog parses the ASCII IMA runtime measurement list, as found in
+// /sys/kernel/security/ima/ascii_runtime_measurements. Only the "ima",
+// "ima-ng" and "ima-sig" templates are supported, as the template data of
+// other templates cannot be reconstructed from the ASCII list.
+func ParseIMAASCIILog(rawLog []byte) ([]IMAEvent, error) {
+	var events []IMAEvent
+	scanner := bufio.NewScanner(bytes.NewReader(rawLog))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		event, err := parseIMAASCIIEvent(line)
+		if err != nil {
+			return nil, fmt.Errorf("IMA event %d: %w", len(events), err)
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func parseIMAASCIIEvent(line string) (event IMAEvent, err error) {
+	// <pcr> <template digest> <template name> <file digest> <file name> [<sig>]
+	parts := strings.SplitN(line, " ", 5)
+	if len(parts) != 5 {
+		return event, fmt.Errorf("got %d fields, expected at least 5", len(parts))
+	}
+	pcr, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return event, fmt.Errorf("invalid PCR: %w", err)
+	}
+	event.PCR = uint32(pcr)
+	if event.TemplateDigest, err = hex.DecodeString(parts[1]); err != nil {
+		return event, fmt.Errorf("invalid template digest: %w", err)
+	}
+	if len(event.TemplateDigest) != crypto.SHA1.Size() {
+		return event, fmt.Errorf("template digest has length %d, expected %d", len(event.TemplateDigest), crypto.SHA1.Size())
+	}
+	event.TemplateName = parts[2]
+	event.FileName = parts[4]
+
+	switch event.TemplateName {
+	case IMATemplate:
+		event.FileDigestAlg = "sha1"
+		if event.FileDigest, err = hex.DecodeString(parts[3]); err != nil {
+			return event, fmt.Errorf("invalid file digest: %w", err)
+		}
+		if len(event.FileDigest) != crypto.SHA1.Size() {
+			return event, fmt.Errorf("file digest has length %d, expected %d", len(event.FileDigest), crypto.SHA1.Size())
+		}
+		data := append([]byte{}, event.FileDigest...)
+		event.TemplateData = appendIMAField(data, []byte(event.FileName))
+		return event, nil
+	case IMANGTemplate, IMASigTemplate:
+	default:
+		return event, fmt.Errorf("unsupported template %q", event.TemplateName)
+	}
+
+	sep := strings.LastIndexByte(parts[3], ':')
+	if sep < 1 {
+		return event, errors.New("invalid file digest")
+	}
+	event.FileDigestAlg = parts[3][:sep]
+	if event.FileDigest, err = hex.DecodeString(parts[3][sep+1:]); err != nil {
+		return event, fmt.Errorf("invalid file digest: %w", err)
+	}
+	if event.TemplateName == IMASigTemplate {
+		if i := strings.LastIndexByte(event.FileName, ' '); i >= 0 {
+			if event.Signature, err = hex.DecodeString(event.FileName[i+1:]); err != nil {
+				return event, fmt.Errorf("invalid signature: %w", err)
+			}
+			event.FileName = event.FileName[:i]
+		}
+	}
+
+	digestField := append([]byte(event.FileDigestAlg+":\x00"), event.FileDigest...)
+	data := appendIMAField(nil, digestField)
+	data = appendIMAField(data, append([]byte(event.FileName), 0))
+	if event.TemplateName == IMASigTemplate {
+		data = appendIMAField(data, event.Signature)
+	}
+	event.TemplateData = data
+	return event, nil
+}
+
+func appendIMAField(data, field []byte) []byte {
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(len(field)))
+	return append(append(data, size[:]...), field...)
+}
+
+// templateDigest computes the digest of the event's template data, using the
+// provided hash algorithm. This is the value extended into a PCR bank using
+// that algorithm.
+func (e *IMAEvent) templateDigest(hash crypto.Hash) ([]byte, error) {
+	if e.Violation() {
+		return bytes.Repeat([]byte{0xff}, hash.Size()), nil
+	}
+	hasher := hash.New()
+	if e.TemplateName != IMATemplate {
+		hasher.Write(e.TemplateData)
+		return hasher.Sum(nil), nil
+	}
+	// The "ima" template hashes the file digest and the padded file name,
+	// without any field lengths.
+	if len(e.FileName) > imaEventNameLenMax {
+		return nil, fmt.Errorf("file name length (%d) is too large", len(e.FileName))
+	}
+	hasher.Write(e.FileDigest)
+	name := make([]byte, imaEventNameLenMax+1)
+	copy(name, e.FileName)
+	hasher.Write(name)
+	return hasher.Sum(nil), nil
+}
+
+// VerifyIMALog checks that the IMA events match their recorded template
+// digests, and replays the events against the given PCR values. All PCRs
+// measured by the events must be present in pcrs.
+//
+// For the SHA-1 bank, the recorded template digests are replayed. For other
+// banks, the template data is hashed using the bank's algorithm (as done by
+// Linux 5.11 and later). If that replay does not match, the SHA-1 template
+// digests padded to the bank's digest size are replayed instead (as done by
+// older kernels).
+//
+// It is the caller's responsibility to ensure that the passed PCR values can
+// be trusted, e.g. by verifying them with a quote. Also note that the IMA
+// measurement list is only trustworthy if the boot events measured in the
+// boot_aggregate entry are verified as well.
+func VerifyIMALog(events []IMAEvent, pcrs *pb.PCRs) error {
+	hash, err := tpm2.Algorithm(pcrs.GetHash()).Hash()
+	if err != nil {
+		return fmt.Errorf("received bad PCR proto: %w", err)
+	}
+
+	for i, event := range events {
+		if event.Violation() {
+			continue
+		}
+		digest, err := event.templateDigest(crypto.SHA1)
+		if err != nil {
+			return fmt.Errorf("IMA event %d: %w", i, err)
+		}
+		if !bytes.Equal(digest, event.TemplateDigest) {
+			return fmt.Errorf("IMA event %d (%s): template digest does not match template data", i, event.FileName)
+		}
+	}
+
+	replayed, err := replayIMALog(events, hash, func(e *IMAEvent) ([]byte, error) {
+		return e.templateDigest(hash)
+	})
+	if err != nil {
+		return err
+	}
+	err = checkIMAReplay(replayed, pcrs)
+	if err == nil || hash == crypto.SHA1 {
+		return err
+	}
+
+	replayed, err = replayIMALog(events, hash, func(e *IMAEvent) ([]byte, error) {
+		if e.Violation() {
+			return bytes.Repeat([]byte{0xff}, hash.Size()), nil
+		}
+		padded := make([]byte, hash.Size())
+		copy(padded, e.TemplateDigest)
+		return padded, nil
+	})
+	if err != nil {
+		return err
+	}
+	return checkIMAReplay(replayed, pcrs)
+}
+
+func replayIMALog(events []IMAEvent, hash crypto.Hash, digest func(*IMAEvent) ([]byte, error)) (map[uint32][]byte, error) {
+	replayed := make(map[uint32][]byte)
+	for i := range events {
+		d, err := digest(&events[i])
+		if err != nil {
+			return nil, fmt.Errorf("IMA event %d: %w", i, err)
+		}
+		pcr, ok := replayed[events[i].PCR]
+		if !ok {
+			pcr = make([]byte, hash.Size())
+		}
+		hasher := hash.New()
+		hasher.Write(pcr)
+		hasher.Write(d)
+		replayed[events[i].PCR] = hasher.Sum(nil)
+	}
+	return replayed, nil
+}
+
+func checkIMAReplay(replayed map[uint32][]byte, pcrs *pb.PCRs) error {
+	for idx, value := range replayed {
+		expected, ok := pcrs.GetPcrs()[idx]
+		if !ok {
+			return fmt.Errorf("IMA events measured into PCR%d, which was not provided", idx)
+		}
+		if !bytes.Equal(value, expected) {
+			return fmt.Errorf("IMA replay for PCR%d does not match the provided value", idx)
+		}
+	}
+	return nil
+}
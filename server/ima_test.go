@@ -0,0 +1,208 @@
+package server
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+type imaTestEntry struct {
+	template  string
+	fileAlg   string
+	digest    []byte
+	name      string
+	sig       []byte
+	violation bool
+}
+
+var imaTestEntries = []imaTestEntry{
+	{template: IMANGTemplate, fileAlg: "sha256", digest: bytes.Repeat([]byte{0x01}, 32), name: "boot_aggregate"},
+	{template: IMANGTemplate, fileAlg: "sha256", digest: bytes.Repeat([]byte{0x02}, 32), name: "/usr/bin/bash"},
+	{template: IMASigTemplate, fileAlg: "sha256", digest: bytes.Repeat([]byte{0x03}, 32), name: "/usr/lib/my lib.so", sig: []byte{0x03, 0x02, 0x04}},
+	{template: IMASigTemplate, fileAlg: "sha256", digest: bytes.Repeat([]byte{0x04}, 32), name: "/usr/bin/unsigned"},
+	{template: IMATemplate, fileAlg: "sha1", digest: bytes.Repeat([]byte{0x05}, 20), name: "/etc/passwd"},
+	{template: IMANGTemplate, fileAlg: "sha256", digest: make([]byte, 32), name: "/var/log/violation", violation: true},
+}
+
+func imaField(field []byte) []byte {
+	return appendIMAField(nil, field)
+}
+
+// Builds the template data and SHA1 and SHA256 template digests of an entry.
+func (e imaTestEntry) build() (data []byte, sha1Digest []byte, sha256Digest []byte) {
+	if e.template == IMATemplate {
+		data = append(append([]byte{}, e.digest...), imaField([]byte(e.name))...)
+		padded := append(append([]byte{}, e.digest...), make([]byte, 256)...)
+		copy(padded[len(e.digest):], e.name)
+		d1 := sha1.Sum(padded)
+		d256 := sha256.Sum256(padded)
+		sha1Digest, sha256Digest = d1[:], d256[:]
+	} else {
+		data = imaField(append([]byte(e.fileAlg+":\x00"), e.digest...))
+		data = append(data, imaField(append([]byte(e.name), 0))...)
+		if e.template == IMASigTemplate {
+			data = append(data, imaField(e.sig)...)
+		}
+		d1 := sha1.Sum(data)
+		d256 := sha256.Sum256(data)
+		sha1Digest, sha256Digest = d1[:], d256[:]
+	}
+	if e.violation {
+		return data, make([]byte, 20), bytes.Repeat([]byte{0xff}, 32)
+	}
+	return data, sha1Digest, sha256Digest
+}
+
+// Builds binary and ASCII IMA logs, and the resulting SHA1 and SHA256 PCRs.
+func buildIMALogs(entries []imaTestEntry) (binaryLog []byte, asciiLog []byte, sha1PCRs *pb.PCRs, sha256PCRs *pb.PCRs) {
+	pcr1 := make([]byte, 20)
+	pcr256 := make([]byte, 32)
+	var bin, ascii bytes.Buffer
+	for _, e := range entries {
+		data, d1, d256 := e.build()
+		binary.Write(&bin, binary.LittleEndian, uint32(IMAPCR))
+		bin.Write(d1)
+		bin.Write(imaField([]byte(e.template)))
+		bin.Write(imaField(data))
+
+		switch e.template {
+		case IMATemplate:
+			fmt.Fprintf(&ascii, "%d %x %s %x %s\n", IMAPCR, d1, e.template, e.digest, e.name)
+		case IMANGTemplate:
+			fmt.Fprintf(&ascii, "%d %x %s %s:%x %s\n", IMAPCR, d1, e.template, e.fileAlg, e.digest, e.name)
+		case IMASigTemplate:
+			fmt.Fprintf(&ascii, "%d %x %s %s:%x %s %x\n", IMAPCR, d1, e.template, e.fileAlg, e.digest, e.name, e.sig)
+		}
+
+		if e.violation {
+			d1 = bytes.Repeat([]byte{0xff}, 20)
+		}
+		h1 := sha1.Sum(append(pcr1, d1...))
+		h256 := sha256.Sum256(append(pcr256, d256...))
+		pcr1, pcr256 = h1[:], h256[:]
+	}
+	sha1PCRs = &pb.PCRs{Hash: pb.HashAlgo_SHA1, Pcrs: map[uint32][]byte{IMAPCR: pcr1}}
+	sha256PCRs = &pb.PCRs{Hash: pb.HashAlgo_SHA256, Pcrs: map[uint32][]byte{IMAPCR: pcr256}}
+	return bin.Bytes(), ascii.Bytes(), sha1PCRs, sha256PCRs
+}
+
+func TestParseIMALogs(t *testing.T) {
+	binaryLog, asciiLog, _, _ := buildIMALogs(imaTestEntries)
+	parsers := []struct {
+		name  string
+		parse func([]byte) ([]IMAEvent, error)
+		log   []byte
+	}{
+		{"Binary", ParseIMALog, binaryLog},
+		{"ASCII", ParseIMAASCIILog, asciiLog},
+	}
+	for _, p := range parsers {
+		t.Run(p.name, func(t *testing.T) {
+			events, err := p.parse(p.log)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != len(imaTestEntries) {
+				t.Fatalf("got %d events, expected %d", len(events), len(imaTestEntries))
+			}
+			for i, e := range imaTestEntries {
+				data, _, _ := e.build()
+				got := events[i]
+				if got.PCR != IMAPCR || got.TemplateName != e.template {
+					t.Errorf("event %d: got PCR%d and template %q, expected PCR%d and %q", i, got.PCR, got.TemplateName, IMAPCR, e.template)
+				}
+				if got.FileName != e.name || got.FileDigestAlg != e.fileAlg || !bytes.Equal(got.FileDigest, e.digest) {
+					t.Errorf("event %d: got file %q (%s:%x), expected %q (%s:%x)", i, got.FileName, got.FileDigestAlg, got.FileDigest, e.name, e.fileAlg, e.digest)
+				}
+				if !bytes.Equal(got.Signature, e.sig) {
+					t.Errorf("event %d: got signature %x, expected %x", i, got.Signature, e.sig)
+				}
+				if !bytes.Equal(got.TemplateData, data) {
+					t.Errorf("event %d: got template data %x, expected %x", i, got.TemplateData, data)
+				}
+				if got.Violation() != e.violation {
+					t.Errorf("event %d: got violation %v, expected %v", i, got.Violation(), e.violation)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyIMALog(t *testing.T) {
+	binaryLog, _, sha1PCRs, sha256PCRs := buildIMALogs(imaTestEntries)
+	events, err := ParseIMALog(binaryLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pcrs := range []*pb.PCRs{sha1PCRs, sha256PCRs} {
+		if err := VerifyIMALog(events, pcrs); err != nil {
+			t.Errorf("failed to verify %v IMA log: %v", pcrs.GetHash(), err)
+		}
+	}
+}
+
+// Older kernels extend the SHA-1 template digest into all PCR banks.
+func TestVerifyIMALogPaddedDigests(t *testing.T) {
+	binaryLog, _, _, _ := buildIMALogs(imaTestEntries)
+	events, err := ParseIMALog(binaryLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pcr := make([]byte, crypto.SHA256.Size())
+	for _, event := range events {
+		digest := make([]byte, crypto.SHA256.Size())
+		copy(digest, event.TemplateDigest)
+		if event.Violation() {
+			digest = bytes.Repeat([]byte{0xff}, crypto.SHA256.Size())
+		}
+		h := sha256.Sum256(append(pcr, digest...))
+		pcr = h[:]
+	}
+	pcrs := &pb.PCRs{Hash: pb.HashAlgo_SHA256, Pcrs: map[uint32][]byte{IMAPCR: pcr}}
+	if err := VerifyIMALog(events, pcrs); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVerifyIMALogFailures(t *testing.T) {
+	binaryLog, asciiLog, sha1PCRs, sha256PCRs := buildIMALogs(imaTestEntries)
+
+	// Changing the file digest in the ASCII log changes the template data,
+	// which no longer matches the recorded template digest.
+	modified := strings.Replace(string(asciiLog), hex.EncodeToString(imaTestEntries[1].digest), hex.EncodeToString(imaTestEntries[2].digest), 1)
+	events, err := ParseIMAASCIILog([]byte(modified))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifyIMALog(events, sha256PCRs); err == nil {
+		t.Error("expected failure for a modified file digest")
+	}
+
+	// Dropping an event changes the replayed PCR value.
+	events, err = ParseIMALog(binaryLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pcrs := range []*pb.PCRs{sha1PCRs, sha256PCRs} {
+		if err = VerifyIMALog(events[1:], pcrs); err == nil {
+			t.Errorf("expected %v replay failure for a truncated log", pcrs.GetHash())
+		}
+	}
+
+	// Events must be replayed against a provided PCR.
+	if err = VerifyIMALog(events, &pb.PCRs{Hash: pb.HashAlgo_SHA256, Pcrs: map[uint32][]byte{}}); err == nil {
+		t.Error("expected failure when PCR10 is missing")
+	}
+
+	if _, err = ParseIMALog(binaryLog[:len(binaryLog)-1]); err == nil {
+		t.Error("expected failure when parsing a truncated binary log")
+	}
+}
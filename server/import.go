@@ -21,6 +21,10 @@ import (
 	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
 )
 
+// The creator recorded in the headers of the import blobs created by this
+// package.
+const blobCreator = "github.com/ThalesIgnite/go-tpm-tools/server"
+
 // CreateImportBlob uses the provided public EK to encrypt the sensitive data.
 // The returned ImportBlob can then be decrypted and imported using the
 // client Key.Import() method. A non-nil pcrs parameter adds a requirement
@@ -36,8 +40,8 @@ func CreateImportBlob(ekPub crypto.PublicKey, sensitive []byte, pcrs *pb.PCRs) (
 	return createImportBlobHelper(ek, public, private, pcrs)
 }
 
-// CreateSigningKeyImportBlob uses the provided public EK to encrypt the signing
-// key into import blob format. The returned import blob can be used to import
+// CreateSigningKeyImportBlob uses the provided public EK to encrypt the RSA or
+// ECDSA signing key into import blob format. The returned import blob can be used to import
 // the signing key into the TPM associated with the provided EK without exposing
 // the private area to the TPM's OS using the client Key.ImportSigningKey()
 // method. A non-nil pcrs parameter adds a requirement that the TPM must have
@@ -55,24 +59,50 @@ func CreateSigningKeyImportBlob(ekPub crypto.PublicKey, signingKey crypto.Privat
 	return createImportBlobHelper(ek, public, private, pcrs)
 }
 
+// CreateDecryptionKeyImportBlob uses the provided public EK to encrypt an RSA
+// or ECC decryption key into import blob format. The returned import blob can
+// be imported using the client Key.ImportKey() method, and the imported key
+// used with Key.GetDecrypter() (for RSA keys) or Key.ECDH() (for ECC keys). A
+// non-nil pcrs parameter adds a requirement that the TPM must have specific PCR
+// values to use the decryption key.
+func CreateDecryptionKeyImportBlob(ekPub crypto.PublicKey, decryptionKey crypto.PrivateKey, pcrs *pb.PCRs) (*pb.ImportBlob, error) {
+	ek, err := CreateEKPublicAreaFromKey(ekPub)
+	if err != nil {
+		return nil, err
+	}
+	public, private, err := createPublicPrivateDecrypt(decryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return createImportBlobHelper(ek, public, private, pcrs)
+}
+
+// CreateSymmetricKeyImportBlob uses the provided public EK to encrypt an AES
+// key (of 128, 192 or 256 bits) into import blob format. The returned import
+// blob can be imported using the client Key.ImportKey() method, and the
+// imported key used (in CFB mode) with Key.EncryptSymmetric() and
+// Key.DecryptSymmetric(). A non-nil pcrs parameter adds a requirement that the
+// TPM must have specific PCR values to use the AES key.
+func CreateSymmetricKeyImportBlob(ekPub crypto.PublicKey, aesKey []byte, pcrs *pb.PCRs) (*pb.ImportBlob, error) {
+	ek, err := CreateEKPublicAreaFromKey(ekPub)
+	if err != nil {
+		return nil, err
+	}
+	public, private, err := createPublicPrivateSymmetric(aesKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return createImportBlobHelper(ek, public, private, pcrs)
+}
+
 func createImportBlobHelper(ek, public tpm2.Public, private tpm2.Private, pcrs *pb.PCRs) (*pb.ImportBlob, error) {
 	setPublicAuth(&public, pcrs)
 
-	var seed, encryptedSeed []byte
-	var err error
-	switch ek.Type {
-	case tpm2.AlgRSA:
-		seed, encryptedSeed, err = createRSASeed(ek)
-		if err != nil {
-			return nil, err
-		}
-	case tpm2.AlgECC:
-		seed, encryptedSeed, err = createECCSeed(ek)
-		if err != nil {
-			return nil, err
-		}
-	default:
-		return nil, fmt.Errorf("unsupported EK type: %v", ek.Type)
+	seed, encryptedSeed, err := createSeed(ek, "DUPLICATE")
+	if err != nil {
+		return nil, err
 	}
 	duplicate, err := createDuplicate(private, seed, public, ek)
 	if err != nil {
@@ -83,12 +113,14 @@ func createImportBlobHelper(ek, public tpm2.Public, private tpm2.Private, pcrs *
 		return nil, err
 	}
 
-	return &pb.ImportBlob{
+	blob := &pb.ImportBlob{
 		Duplicate:     duplicate,
 		EncryptedSeed: encryptedSeed,
 		PublicArea:    pubEncoded,
 		Pcrs:          pcrs,
-	}, nil
+		Header:        notinternal.NewBlobHeader(blobCreator, pcrs.GetHash(), public.AuthPolicy),
+	}
+	return blob, notinternal.SetBlobChecksum(blob)
 }
 
 func setPublicAuth(public *tpm2.Public, pcrs *pb.PCRs) {
@@ -102,7 +134,20 @@ func setPublicAuth(public *tpm2.Public, pcrs *pb.PCRs) {
 	}
 }
 
-func createRSASeed(ek tpm2.Public) (seed, encryptedSeed []byte, err error) {
+// createSeed generates a random seed, and encrypts it to the EK using the
+// secret sharing scheme for the provided label (e.g. "DUPLICATE" or "IDENTITY").
+func createSeed(ek tpm2.Public, label string) (seed, encryptedSeed []byte, err error) {
+	switch ek.Type {
+	case tpm2.AlgRSA:
+		return createRSASeed(ek, label)
+	case tpm2.AlgECC:
+		return createECCSeed(ek, label)
+	default:
+		return nil, nil, fmt.Errorf("unsupported EK type: %v", ek.Type)
+	}
+}
+
+func createRSASeed(ek tpm2.Public, label string) (seed, encryptedSeed []byte, err error) {
 	seedSize := ek.RSAParameters.Symmetric.KeyBits / 8
 	seed = make([]byte, seedSize)
 	if _, err := io.ReadFull(rand.Reader, seed); err != nil {
@@ -118,7 +163,7 @@ func createRSASeed(ek tpm2.Public) (seed, encryptedSeed []byte, err error) {
 		rand.Reader,
 		ekPub.(*rsa.PublicKey),
 		seed,
-		[]byte("DUPLICATE\x00"))
+		[]byte(label+"\x00"))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -126,7 +171,7 @@ func createRSASeed(ek tpm2.Public) (seed, encryptedSeed []byte, err error) {
 	return seed, encryptedSeed, err
 }
 
-func createECCSeed(ek tpm2.Public) (seed, encryptedSeed []byte, err error) {
+func createECCSeed(ek tpm2.Public, label string) (seed, encryptedSeed []byte, err error) {
 	curve, err := curveIDToGoCurve(ek.ECCParameters.CurveID)
 	if err != nil {
 		return nil, nil, err
@@ -142,7 +187,7 @@ func createECCSeed(ek tpm2.Public) (seed, encryptedSeed []byte, err error) {
 	seed, err = tpm2.KDFe(
 		ek.NameAlg,
 		eccIntToBytes(curve, z),
-		"DUPLICATE",
+		label,
 		xBytes,
 		eccIntToBytes(curve, ekPoint.X()),
 		getHash(ek.NameAlg).Size()*8)
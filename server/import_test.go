@@ -3,12 +3,19 @@ package server
 import (
 	"bytes"
 	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"errors"
 	"testing"
 
 	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
 	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
 	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
 	"github.com/google/go-tpm/tpm2"
@@ -127,8 +134,16 @@ func TestBadImport(t *testing.T) {
 				t.Errorf("got error: %v, expected: %v", err, k.wrongKeyErrs)
 			}
 
-			// Try to import a corrupted blob
+			// Try to import a corrupted blob, which fails the checksum of its
+			// header before reaching the TPM.
 			blob.EncryptedSeed[10] ^= 0xFF
+			if _, err = ek.Import(blob); err == nil || isExpectedError(err, k.corruptedErrs) {
+				t.Errorf("got error: %v, expected a checksum error", err)
+			}
+			// With a valid checksum, the TPM detects the corruption.
+			if err = notinternal.SetBlobChecksum(blob); err != nil {
+				t.Fatal(err)
+			}
 			if _, err = ek.Import(blob); !isExpectedError(err, k.corruptedErrs) {
 				t.Errorf("got error: %v, expected: %v", err, k.corruptedErrs)
 			}
@@ -247,3 +262,217 @@ func TestSigningKeyImport(t *testing.T) {
 		})
 	}
 }
+
+func TestECCSigningKeyImport(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ek, err := client.EndorsementKeyECC(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ek.Close()
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blob, err := CreateSigningKeyImportBlob(ek.PublicKey(), signingKey, nil)
+	if err != nil {
+		t.Fatalf("creating import blob failed: %v", err)
+	}
+	importedKey, err := ek.ImportKey(blob)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	defer importedKey.Close()
+	signer, err := importedKey.GetSigner()
+	if err != nil {
+		t.Fatalf("could not create signer: %v", err)
+	}
+	digest := sha256.Sum256([]byte("message"))
+	sig, err := signer.Sign(nil, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatalf("signing failed: %v", err)
+	}
+	if !ecdsa.VerifyASN1(&signingKey.PublicKey, digest[:], sig) {
+		t.Error("signature verification failed")
+	}
+}
+
+func TestDecryptionKeyImport(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ek, err := client.EndorsementKeyRSA(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ek.Close()
+	decryptionKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pcr0, err := tpm2.ReadPCR(rwc, 0, tpm2.AlgSHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	badPCR := append(make([]byte, 0), pcr0...)
+	badPCR[0]++
+	secret := []byte("imported key secret")
+	ciphertext, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, &decryptionKey.PublicKey, secret, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subtests := []struct {
+		name          string
+		pcrs          *pb.PCRs
+		expectSuccess bool
+	}{
+		{"No-PCR", nil, true},
+		{"Good-PCR", &pb.PCRs{Hash: pb.HashAlgo_SHA256, Pcrs: map[uint32][]byte{0: pcr0}}, true},
+		{"Bad-PCR", &pb.PCRs{Hash: pb.HashAlgo_SHA256, Pcrs: map[uint32][]byte{0: badPCR}}, false},
+	}
+	for _, subtest := range subtests {
+		t.Run(subtest.name, func(t *testing.T) {
+			blob, err := CreateDecryptionKeyImportBlob(ek.PublicKey(), decryptionKey, subtest.pcrs)
+			if err != nil {
+				t.Fatalf("creating import blob failed: %v", err)
+			}
+			importedKey, err := ek.ImportKey(blob)
+			if err != nil {
+				t.Fatalf("import failed: %v", err)
+			}
+			defer importedKey.Close()
+			decrypter, err := importedKey.GetDecrypter()
+			if err != nil {
+				t.Fatalf("could not create decrypter: %v", err)
+			}
+
+			plaintext, err := decrypter.Decrypt(nil, ciphertext, &rsa.OAEPOptions{Hash: crypto.SHA256})
+			if !subtest.expectSuccess {
+				if err == nil {
+					t.Error("expected decryption to fail but it did not")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decryption failed: %v", err)
+			}
+			if !bytes.Equal(plaintext, secret) {
+				t.Errorf("got decrypted data %q, want %q", plaintext, secret)
+			}
+		})
+	}
+
+	if _, err = CreateDecryptionKeyImportBlob(ek.PublicKey(), ed25519.PrivateKey{}, nil); err == nil {
+		t.Error("expected failure creating an import blob for an Ed25519 decryption key")
+	}
+}
+
+func TestECCDecryptionKeyImport(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ek, err := client.EndorsementKeyECC(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ek.Close()
+
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384()} {
+		t.Run(curve.Params().Name, func(t *testing.T) {
+			decryptionKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			blob, err := CreateDecryptionKeyImportBlob(ek.PublicKey(), decryptionKey, nil)
+			if err != nil {
+				t.Fatalf("creating import blob failed: %v", err)
+			}
+			importedKey, err := ek.ImportKey(blob)
+			if err != nil {
+				t.Fatalf("import failed: %v", err)
+			}
+			defer importedKey.Close()
+
+			peer, err := ecdsa.GenerateKey(curve, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			secret, err := importedKey.ECDH(&peer.PublicKey)
+			if err != nil {
+				t.Fatalf("ECDH failed: %v", err)
+			}
+			x, _ := curve.ScalarMult(peer.X, peer.Y, decryptionKey.D.Bytes())
+			if want := x.FillBytes(make([]byte, (curve.Params().BitSize+7)/8)); !bytes.Equal(secret, want) {
+				t.Errorf("got shared secret %x, want %x", secret, want)
+			}
+			if _, err = importedKey.GetSigner(); err == nil {
+				t.Error("expected the imported decryption key to not be usable for signing")
+			}
+		})
+	}
+}
+
+func TestSymmetricKeyImport(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ek, err := client.EndorsementKeyECC(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ek.Close()
+	pcr0, err := tpm2.ReadPCR(rwc, 0, tpm2.AlgSHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aesKey := make([]byte, 32)
+	if _, err := rand.Read(aesKey); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pcrs := range []*pb.PCRs{nil, {Hash: pb.HashAlgo_SHA256, Pcrs: map[uint32][]byte{0: pcr0}}} {
+		blob, err := CreateSymmetricKeyImportBlob(ek.PublicKey(), aesKey, pcrs)
+		if err != nil {
+			t.Fatalf("creating import blob failed: %v", err)
+		}
+		importedKey, err := ek.ImportKey(blob)
+		if err != nil {
+			t.Fatalf("import failed: %v", err)
+		}
+		defer importedKey.Close()
+
+		// Use more data than fits in a single TPM command
+		data := bytes.Repeat([]byte("symmetric data "), 200)
+		iv := make([]byte, aes.BlockSize)
+		ciphertext, err := importedKey.EncryptSymmetric(iv, data)
+		if err != nil {
+			t.Fatalf("encryption failed: %v", err)
+		}
+		block, err := aes.NewCipher(aesKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := make([]byte, len(data))
+		cipher.NewCFBEncrypter(block, iv).XORKeyStream(expected, data)
+		if !bytes.Equal(ciphertext, expected) {
+			t.Error("TPM ciphertext does not match AES-CFB encryption")
+		}
+
+		plaintext, err := importedKey.DecryptSymmetric(iv, ciphertext)
+		if err != nil {
+			t.Fatalf("decryption failed: %v", err)
+		}
+		if !bytes.Equal(plaintext, data) {
+			t.Error("decrypted data does not match")
+		}
+	}
+
+	if _, err = CreateSymmetricKeyImportBlob(ek.PublicKey(), make([]byte, 10), nil); err == nil {
+		t.Error("expected failure creating an import blob for an invalid AES key")
+	}
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+func TestVerifyAttestationInstanceInfo(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ak, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ak.Close()
+
+	// An AK certificate from a vTPM CA, identifying the instance.
+	caPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test vTPM CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caPriv.Public(), caPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extension, err := asn1.Marshal(struct {
+		Zone          string `asn1:"utf8"`
+		ProjectNumber int64
+		ProjectID     string `asn1:"utf8"`
+		InstanceID    int64
+		InstanceName  string `asn1:"utf8"`
+	}{"us-central1-a", 1234, "test-project", 5678, "test-instance"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &attestpb.GCEInstanceInfo{Zone: "us-central1-a", ProjectNumber: 1234, ProjectId: "test-project", InstanceId: 5678, InstanceName: "test-instance"}
+	akTemplate := &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		Subject:         pkix.Name{CommonName: "test-instance"},
+		NotBefore:       time.Now().Add(-time.Hour),
+		NotAfter:        time.Now().Add(time.Hour),
+		ExtraExtensions: []pkix.Extension{{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 1, 21}, Value: extension}},
+	}
+	akDER, err := x509.CreateCertificate(rand.Reader, akTemplate, ca, ak.PublicKey(), caPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	akCert, err := x509.ParseCertificate(akDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ak.SetCert(akCert); err != nil {
+		t.Fatal(err)
+	}
+
+	nonce := []byte("nonce")
+	attestation, err := ak.Attest(nonce, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := VerifyAttestation(attestation, VerifyOpts{Nonce: nonce, TrustedRootCerts: []*x509.Certificate{ca}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := state.GetPlatform().GetInstanceInfo(); !proto.Equal(got, want) {
+		t.Errorf("got instance info %v, want %v", got, want)
+	}
+
+	// The instance is not trusted if the AK is not trusted by its certificate.
+	state, err = VerifyAttestation(attestation, VerifyOpts{Nonce: nonce, TrustedAKs: []crypto.PublicKey{ak.PublicKey()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := state.GetPlatform().GetInstanceInfo(); got != nil {
+		t.Errorf("got instance info %v for an AK trusted without its certificate", got)
+	}
+
+	attestation.InstanceInfo = &attestpb.GCEInstanceInfo{Zone: "us-central1-a", ProjectId: "other-project", InstanceName: "test-instance"}
+	if _, err = VerifyAttestation(attestation, VerifyOpts{Nonce: nonce, TrustedRootCerts: []*x509.Certificate{ca}}); err == nil {
+		t.Error("expected verifying an attestation with another instance to fail")
+	}
+}
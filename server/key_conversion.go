@@ -77,24 +77,44 @@ func createPrivate(sensitive []byte) tpm2.Private {
 }
 
 func createPublicPrivateSign(signingKey crypto.PrivateKey) (tpm2.Public, tpm2.Private, error) {
-	rsaPriv, ok := signingKey.(*rsa.PrivateKey)
-	if !ok {
+	switch key := signingKey.(type) {
+	case *rsa.PrivateKey:
+		scheme := &tpm2.SigScheme{Alg: tpm2.AlgRSASSA, Hash: tpm2.AlgSHA256}
+		public, private := createPublicPrivateRSA(key, tpm2.FlagSign, scheme)
+		return public, private, nil
+	case *ecdsa.PrivateKey:
+		scheme := &tpm2.SigScheme{Alg: tpm2.AlgECDSA, Hash: tpm2.AlgSHA256}
+		return createPublicPrivateECC(key, tpm2.FlagSign, scheme)
+	default:
 		return tpm2.Public{}, tpm2.Private{}, fmt.Errorf("unsupported signing key type: %T", signingKey)
 	}
+}
+
+func createPublicPrivateDecrypt(decryptionKey crypto.PrivateKey) (tpm2.Public, tpm2.Private, error) {
+	// A null scheme allows the key to be used with any decryption scheme (or,
+	// for ECC keys, with any key exchange scheme, such as ECDH).
+	switch key := decryptionKey.(type) {
+	case *rsa.PrivateKey:
+		public, private := createPublicPrivateRSA(key, tpm2.FlagDecrypt, nil)
+		return public, private, nil
+	case *ecdsa.PrivateKey:
+		return createPublicPrivateECC(key, tpm2.FlagDecrypt, nil)
+	default:
+		return tpm2.Public{}, tpm2.Private{}, fmt.Errorf("unsupported decryption key type: %T", decryptionKey)
+	}
+}
 
+func createPublicPrivateRSA(rsaPriv *rsa.PrivateKey, attributes tpm2.KeyProp, scheme *tpm2.SigScheme) (tpm2.Public, tpm2.Private) {
 	rsaPub := rsaPriv.PublicKey
 	public := tpm2.Public{
 		Type:       tpm2.AlgRSA,
 		NameAlg:    defaultNameAlg,
-		Attributes: tpm2.FlagSign,
+		Attributes: attributes,
 		RSAParameters: &tpm2.RSAParams{
 			KeyBits:     uint16(rsaPub.N.BitLen()),
 			ExponentRaw: uint32(rsaPub.E),
 			ModulusRaw:  rsaPub.N.Bytes(),
-			Sign: &tpm2.SigScheme{
-				Alg:  tpm2.AlgRSASSA,
-				Hash: tpm2.AlgSHA256,
-			},
+			Sign:        scheme,
 		},
 	}
 	private := tpm2.Private{
@@ -103,6 +123,67 @@ func createPublicPrivateSign(signingKey crypto.PrivateKey) (tpm2.Public, tpm2.Pr
 		SeedValue: nil, // Only Storage Keys need a seed value. See part 3 TPM2_CREATE b.3.
 		Sensitive: rsaPriv.Primes[0].Bytes(),
 	}
+	return public, private
+}
+
+func createPublicPrivateECC(eccPriv *ecdsa.PrivateKey, attributes tpm2.KeyProp, scheme *tpm2.SigScheme) (tpm2.Public, tpm2.Private, error) {
+	curveID, err := goCurveToCurveID(eccPriv.Curve)
+	if err != nil {
+		return tpm2.Public{}, tpm2.Private{}, err
+	}
+	public := tpm2.Public{
+		Type:       tpm2.AlgECC,
+		NameAlg:    defaultNameAlg,
+		Attributes: attributes,
+		ECCParameters: &tpm2.ECCParams{
+			CurveID: curveID,
+			Point: tpm2.ECPoint{
+				XRaw: eccIntToBytes(eccPriv.Curve, eccPriv.X),
+				YRaw: eccIntToBytes(eccPriv.Curve, eccPriv.Y),
+			},
+			Sign: scheme,
+		},
+	}
+	private := tpm2.Private{
+		Type:      tpm2.AlgECC,
+		AuthValue: nil,
+		SeedValue: nil,
+		Sensitive: eccIntToBytes(eccPriv.Curve, eccPriv.D),
+	}
+	return public, private, nil
+}
 
+func createPublicPrivateSymmetric(aesKey []byte) (tpm2.Public, tpm2.Private, error) {
+	switch len(aesKey) {
+	case 16, 24, 32:
+	default:
+		return tpm2.Public{}, tpm2.Private{}, fmt.Errorf("invalid AES key size: %d bytes", len(aesKey))
+	}
+	private := tpm2.Private{
+		Type:      tpm2.AlgSymCipher,
+		AuthValue: nil,
+		SeedValue: make([]byte, getHash(defaultNameAlg).Size()),
+		Sensitive: aesKey,
+	}
+	if _, err := io.ReadFull(rand.Reader, private.SeedValue); err != nil {
+		panic(err)
+	}
+	// The unique field of a symmetric key is H(seedValue || sensitive).
+	publicHash := getHash(defaultNameAlg)
+	publicHash.Write(private.SeedValue)
+	publicHash.Write(private.Sensitive)
+	public := tpm2.Public{
+		Type:       tpm2.AlgSymCipher,
+		NameAlg:    defaultNameAlg,
+		Attributes: tpm2.FlagDecrypt | tpm2.FlagSign,
+		SymCipherParameters: &tpm2.SymCipherParams{
+			Symmetric: &tpm2.SymScheme{
+				Alg:     tpm2.AlgAES,
+				KeyBits: uint16(len(aesKey) * 8),
+				Mode:    tpm2.AlgCFB,
+			},
+			Unique: publicHash.Sum(nil),
+		},
+	}
 	return public, private, nil
 }
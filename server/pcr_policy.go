@@ -0,0 +1,266 @@
+package server
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+	"github.com/google/go-tpm/tpm2"
+)
+
+// AnyBank is the PCRRule bank matching the PCR in any bank.
+const AnyBank = "*"
+
+// PCRPolicy is a golden value policy for PCRs, such as "PCR0 is A or B, and
+// PCR7 is X". It is typically stored as JSON, for example:
+//
+//	{
+//	  "pcrs": [
+//	    {"pcr": 0, "bank": "sha256", "values": ["<hex digest A>", "<hex digest B>"]},
+//	    {"pcr": 7, "bank": "*", "values": ["<hex SHA-1 digest X>", "<hex SHA-256 digest X>"]}
+//	  ],
+//	  "any_of": [
+//	    [{"pcr": 4, "bank": "sha256", "values": ["<old kernel>"]}],
+//	    [{"pcr": 4, "bank": "sha256", "values": ["<new kernel>"]}, {"pcr": 9, "bank": "sha256", "values": ["<new initrd>"]}]
+//	  ]
+//	}
+//
+// The policy is satisfied if all the PCRs rules, and all the rules of at least
+// one of the AnyOf groups (if any), are satisfied.
+type PCRPolicy struct {
+	PCRs  []PCRRule   `json:"pcrs,omitempty"`
+	AnyOf [][]PCRRule `json:"any_of,omitempty"`
+}
+
+// PCRRule requires a PCR to have one of the allowed values.
+type PCRRule struct {
+	PCR uint32 `json:"pcr"`
+	// The PCR bank, as the name of its hash algorithm (such as "sha256"). With
+	// AnyBank (or if empty), each allowed value is compared to the PCR in the
+	// bank of the value's digest size, and the PCR can be in any bank.
+	Bank string `json:"bank,omitempty"`
+	// The allowed values, hex encoded.
+	Values []string `json:"values"`
+}
+
+// ParsePCRPolicy decodes a JSON encoded PCRPolicy, checking that the rules
+// are valid. Unknown fields are rejected, so that typos do not silently
+// weaken the policy.
+func ParsePCRPolicy(data []byte) (*PCRPolicy, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var policy PCRPolicy
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("decoding PCR policy: %w", err)
+	}
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (p *PCRPolicy) validate() error {
+	for _, rule := range p.PCRs {
+		if _, err := rule.compile(); err != nil {
+			return err
+		}
+	}
+	for i, group := range p.AnyOf {
+		if len(group) == 0 {
+			return fmt.Errorf("any_of group %d is empty", i)
+		}
+		for _, rule := range group {
+			if _, err := rule.compile(); err != nil {
+				return fmt.Errorf("any_of group %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// A PCRRule with its bank and values decoded.
+type compiledPCRRule struct {
+	anyBank bool
+	hash    pb.HashAlgo
+	values  [][]byte
+}
+
+func (r PCRRule) compile() (*compiledPCRRule, error) {
+	if len(r.Values) == 0 {
+		return nil, fmt.Errorf("PCR %d rule has no allowed values", r.PCR)
+	}
+	c := &compiledPCRRule{anyBank: r.Bank == "" || r.Bank == AnyBank}
+	if !c.anyBank {
+		hash, ok := pb.HashAlgo_value[strings.ToUpper(r.Bank)]
+		if !ok || hash == 0 {
+			return nil, fmt.Errorf("PCR %d rule has unknown bank %q", r.PCR, r.Bank)
+		}
+		c.hash = pb.HashAlgo(hash)
+	}
+	for _, value := range r.Values {
+		digest, err := hex.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("PCR %d rule has invalid value %q: %w", r.PCR, value, err)
+		}
+		if size := digestSize(c.hash); !c.anyBank && len(digest) != size {
+			return nil, fmt.Errorf("PCR %d rule has value %q of size %d, expected %d for the %s bank", r.PCR, value, len(digest), size, r.Bank)
+		}
+		c.values = append(c.values, digest)
+	}
+	return c, nil
+}
+
+func digestSize(hash pb.HashAlgo) int {
+	cryptoHash, err := tpm2.Algorithm(hash).Hash()
+	if err != nil {
+		return 0
+	}
+	return cryptoHash.Size()
+}
+
+// PCRPolicyResult is the result of EvaluatePCRPolicy, with a PCRCheck for each
+// rule of the policy.
+type PCRPolicyResult struct {
+	// Whether the PCRs satisfy the policy.
+	Passed bool
+	// The checks of the PCRs rules.
+	Checks []PCRCheck
+	// The checks of the rules of each AnyOf group.
+	AnyOf [][]PCRCheck
+}
+
+// PCRCheck is the result of checking a PCR against a PCRRule.
+type PCRCheck struct {
+	Rule   PCRRule
+	Passed bool
+	// The bank and value of the PCR which was compared to the allowed values.
+	// Hash is unset if the PCR was not found.
+	Hash  pb.HashAlgo
+	Value []byte
+	// Why the check failed, if it did.
+	Reason string
+}
+
+// Err returns an error describing the failed checks, or nil if the policy
+// passed.
+func (r *PCRPolicyResult) Err() error {
+	if r.Passed {
+		return nil
+	}
+	var reasons []string
+	for _, check := range r.Checks {
+		if !check.Passed {
+			reasons = append(reasons, check.Reason)
+		}
+	}
+	if len(r.AnyOf) > 0 && !anyGroupPassed(r.AnyOf) {
+		groups := make([]string, len(r.AnyOf))
+		for i, group := range r.AnyOf {
+			var groupReasons []string
+			for _, check := range group {
+				if !check.Passed {
+					groupReasons = append(groupReasons, check.Reason)
+				}
+			}
+			groups[i] = fmt.Sprintf("group %d: %s", i, strings.Join(groupReasons, ", "))
+		}
+		reasons = append(reasons, "no any_of group passed ("+strings.Join(groups, "; ")+")")
+	}
+	return errors.New("PCR policy failed: " + strings.Join(reasons, "; "))
+}
+
+// EvaluatePCRPolicy checks the PCRs of one or more banks (as returned by
+// client.ReadPCRBanks or found in an attestation's quotes) against the policy.
+// The returned result contains the per-PCR diagnostics, even if the policy
+// failed. An error is only returned if the policy is invalid. A nil policy
+// allows any PCRs.
+func EvaluatePCRPolicy(banks []*pb.PCRs, policy *PCRPolicy) (*PCRPolicyResult, error) {
+	if policy == nil {
+		policy = &PCRPolicy{}
+	}
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+	// Use a deterministic order for AnyBank rules.
+	sorted := append([]*pb.PCRs(nil), banks...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].GetHash() < sorted[j].GetHash() })
+
+	result := &PCRPolicyResult{Passed: true}
+	for _, rule := range policy.PCRs {
+		check := checkPCRRule(sorted, rule)
+		result.Passed = result.Passed && check.Passed
+		result.Checks = append(result.Checks, check)
+	}
+	for _, group := range policy.AnyOf {
+		var checks []PCRCheck
+		for _, rule := range group {
+			checks = append(checks, checkPCRRule(sorted, rule))
+		}
+		result.AnyOf = append(result.AnyOf, checks)
+	}
+	if len(result.AnyOf) > 0 && !anyGroupPassed(result.AnyOf) {
+		result.Passed = false
+	}
+	return result, nil
+}
+
+func anyGroupPassed(groups [][]PCRCheck) bool {
+	for _, group := range groups {
+		passed := true
+		for _, check := range group {
+			passed = passed && check.Passed
+		}
+		if passed {
+			return true
+		}
+	}
+	return false
+}
+
+func checkPCRRule(banks []*pb.PCRs, rule PCRRule) PCRCheck {
+	check := PCRCheck{Rule: rule}
+	// The rule was validated by EvaluatePCRPolicy.
+	compiled, _ := rule.compile()
+	for _, bank := range banks {
+		if !compiled.anyBank && bank.GetHash() != compiled.hash {
+			continue
+		}
+		value, ok := bank.GetPcrs()[rule.PCR]
+		if !ok {
+			continue
+		}
+		sameSize := false
+		for _, allowed := range compiled.values {
+			if len(allowed) != len(value) {
+				continue
+			}
+			sameSize = true
+			if bytes.Equal(allowed, value) {
+				check.Passed = true
+				check.Hash = bank.GetHash()
+				check.Value = value
+				return check
+			}
+		}
+		// Report the first bank with an allowed value of the same size.
+		if sameSize && check.Hash == pb.HashAlgo_HASH_INVALID {
+			check.Hash = bank.GetHash()
+			check.Value = value
+		}
+	}
+
+	switch {
+	case check.Hash != pb.HashAlgo_HASH_INVALID:
+		check.Reason = fmt.Sprintf("PCR %d (%v) has value %x, which is not allowed", rule.PCR, check.Hash, check.Value)
+	case compiled.anyBank:
+		check.Reason = fmt.Sprintf("PCR %d is missing from the banks of the allowed values", rule.PCR)
+	default:
+		check.Reason = fmt.Sprintf("PCR %d (%v) is missing", rule.PCR, compiled.hash)
+	}
+	return check
+}
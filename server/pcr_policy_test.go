@@ -0,0 +1,115 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+func TestEvaluatePCRPolicy(t *testing.T) {
+	a := sha256.Sum256([]byte("A"))
+	b := sha256.Sum256([]byte("B"))
+	x := sha256.Sum256([]byte("X"))
+	x1 := sha1.Sum([]byte("X"))
+	banks := []*pb.PCRs{
+		{Hash: pb.HashAlgo_SHA256, Pcrs: map[uint32][]byte{0: b[:], 4: a[:], 7: x[:]}},
+		{Hash: pb.HashAlgo_SHA1, Pcrs: map[uint32][]byte{7: x1[:], 8: x1[:]}},
+	}
+	hexA, hexB, hexX, hexX1 := hex.EncodeToString(a[:]), hex.EncodeToString(b[:]), hex.EncodeToString(x[:]), hex.EncodeToString(x1[:])
+
+	tests := []struct {
+		name   string
+		policy string
+		passed bool
+		reason string
+	}{
+		{"Empty", `{}`, true, ""},
+		{"AllowedValues", `{"pcrs": [
+			{"pcr": 0, "bank": "sha256", "values": ["` + hexA + `", "` + hexB + `"]},
+			{"pcr": 7, "bank": "sha256", "values": ["` + hexX + `"]}]}`, true, ""},
+		{"WrongValue", `{"pcrs": [
+			{"pcr": 0, "bank": "sha256", "values": ["` + hexA + `"]},
+			{"pcr": 7, "bank": "sha256", "values": ["` + hexX + `"]}]}`, false, "PCR 0 (SHA256) has value " + hexB},
+		{"MissingPCR", `{"pcrs": [{"pcr": 8, "bank": "sha256", "values": ["` + hexX + `"]}]}`, false, "PCR 8 (SHA256) is missing"},
+		{"AnyBankSHA1", `{"pcrs": [{"pcr": 8, "bank": "*", "values": ["` + hexX + `", "` + hexX1 + `"]}]}`, true, ""},
+		{"AnyBankDefault", `{"pcrs": [{"pcr": 7, "values": ["` + hexX1 + `"]}]}`, true, ""},
+		{"AnyBankWrongValue", `{"pcrs": [{"pcr": 8, "values": ["` + hexX + `", "` + hexA[:40] + `"]}]}`, false, "PCR 8 (SHA1) has value " + hexX1},
+		{"AnyBankMissing", `{"pcrs": [{"pcr": 4, "values": ["` + hexX1 + `"]}]}`, false, "PCR 4 is missing"},
+		{"AnyOfPassed", `{"any_of": [
+			[{"pcr": 4, "bank": "sha256", "values": ["` + hexB + `"]}],
+			[{"pcr": 4, "bank": "sha256", "values": ["` + hexA + `"]}, {"pcr": 7, "values": ["` + hexX1 + `"]}]]}`, true, ""},
+		{"AnyOfFailed", `{"pcrs": [{"pcr": 0, "values": ["` + hexB + `"]}], "any_of": [
+			[{"pcr": 4, "bank": "sha256", "values": ["` + hexB + `"]}],
+			[{"pcr": 4, "bank": "sha256", "values": ["` + hexA + `"]}, {"pcr": 7, "values": ["` + hexA + `"]}]]}`, false, "no any_of group passed"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy, err := ParsePCRPolicy([]byte(test.policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := EvaluatePCRPolicy(banks, policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Passed != test.passed {
+				t.Errorf("got Passed %v, want %v (%v)", result.Passed, test.passed, result.Err())
+			}
+			if err = result.Err(); (err == nil) != test.passed {
+				t.Errorf("got Err() %v, want an error: %v", err, !test.passed)
+			} else if err != nil && !strings.Contains(err.Error(), test.reason) {
+				t.Errorf("got Err() %v, want an error containing %q", err, test.reason)
+			}
+			if len(result.Checks) != len(policy.PCRs) || len(result.AnyOf) != len(policy.AnyOf) {
+				t.Errorf("got %d checks and %d groups, want one per rule", len(result.Checks), len(result.AnyOf))
+			}
+		})
+	}
+}
+
+func TestPCRCheckDiagnostics(t *testing.T) {
+	a := sha256.Sum256([]byte("A"))
+	b := sha256.Sum256([]byte("B"))
+	banks := []*pb.PCRs{{Hash: pb.HashAlgo_SHA256, Pcrs: map[uint32][]byte{0: a[:], 1: b[:]}}}
+	policy := &PCRPolicy{PCRs: []PCRRule{
+		{PCR: 0, Bank: "sha256", Values: []string{hex.EncodeToString(a[:])}},
+		{PCR: 1, Bank: "SHA256", Values: []string{hex.EncodeToString(a[:])}},
+	}}
+	result, err := EvaluatePCRPolicy(banks, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Checks[0].Passed || result.Checks[0].Hash != pb.HashAlgo_SHA256 || !bytes.Equal(result.Checks[0].Value, a[:]) {
+		t.Errorf("unexpected check of PCR 0: %+v", result.Checks[0])
+	}
+	if result.Checks[1].Passed || !bytes.Equal(result.Checks[1].Value, b[:]) || result.Checks[1].Reason == "" {
+		t.Errorf("unexpected check of PCR 1: %+v", result.Checks[1])
+	}
+
+	if result, err = EvaluatePCRPolicy(banks, nil); err != nil || !result.Passed {
+		t.Errorf("EvaluatePCRPolicy(nil) = %+v, %v, want a passed result", result, err)
+	}
+}
+
+func TestParsePCRPolicyFail(t *testing.T) {
+	digest := hex.EncodeToString(make([]byte, sha256.Size))
+	for _, policy := range []string{
+		`not json`,
+		`{"pcr": [{"pcr": 0, "values": ["` + digest + `"]}]}`,
+		`{"pcrs": [{"pcr": 0, "values": []}]}`,
+		`{"pcrs": [{"pcr": 0, "values": ["0xzz"]}]}`,
+		`{"pcrs": [{"pcr": 0, "bank": "md5", "values": ["` + digest + `"]}]}`,
+		`{"pcrs": [{"pcr": 0, "bank": "sha1", "values": ["` + digest + `"]}]}`,
+		`{"any_of": [[]]}`,
+		`{"any_of": [[{"pcr": 0, "bank": "sha384", "values": ["` + digest + `"]}]]}`,
+	} {
+		if _, err := ParsePCRPolicy([]byte(policy)); err == nil {
+			t.Errorf("ParsePCRPolicy(%s) should fail", policy)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/go-attestation/attest"
+	"github.com/google/go-tpm/tpm2"
+
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+// PCRDiff explains the change of a PCR between two boots, using the event log
+// of the later boot.
+type PCRDiff struct {
+	Index         uint32
+	Before, After []byte
+	// The events of the event log extended into the PCR (which are not
+	// verified, unless Replayed is set).
+	Events []*attestpb.Event
+	// Whether replaying the Events gives After. If not, the event log is not
+	// the log of the boot After was read in.
+	Replayed bool
+	// The number of Events whose replay gives Before, or -1 if no prefix of
+	// the Events does. If set, Events[Common:] account for the change (they
+	// were all measured after the PCR had its Before value). Otherwise, one of
+	// the events of the earlier boot was measured differently (such as an
+	// updated firmware or boot loader), which the change cannot tell apart
+	// from the other events.
+	Common int
+}
+
+// DiffPCRs compares the PCR values of two boots (of the same bank), returning
+// a PCRDiff for each PCR with different values, ordered by index. The
+// rawEventLog must be the event log of the boot of after. PCRs which are
+// missing from either before or after are not compared.
+//
+// This explains why data sealed to the PCRs of before (see client.SealCurrent)
+// can no longer be unsealed, for example after a firmware update.
+func DiffPCRs(before, after *pb.PCRs, rawEventLog []byte) ([]*PCRDiff, error) {
+	if before.GetHash() != after.GetHash() {
+		return nil, fmt.Errorf("cannot compare PCRs of the %v and %v banks", before.GetHash(), after.GetHash())
+	}
+	cryptoHash, err := tpm2.Algorithm(after.GetHash()).Hash()
+	if err != nil {
+		return nil, fmt.Errorf("unsupported hash algorithm %v: %w", after.GetHash(), err)
+	}
+	eventLog, err := attest.ParseEventLog(rawEventLog)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse event log: %w", err)
+	}
+	events := convertToPbEvents(cryptoHash, eventLog.Events(attest.HashAlg(after.GetHash())))
+	if len(events) == 0 {
+		return nil, errors.New("event log has no events for the PCR bank")
+	}
+
+	var diffs []*PCRDiff
+	for index, afterValue := range after.GetPcrs() {
+		beforeValue, ok := before.GetPcrs()[index]
+		if !ok || bytes.Equal(beforeValue, afterValue) {
+			continue
+		}
+		diff := &PCRDiff{Index: index, Before: beforeValue, After: afterValue, Common: -1}
+		for _, event := range events {
+			if event.GetPcrIndex() == index {
+				diff.Events = append(diff.Events, event)
+			}
+		}
+		for i := 0; i <= len(diff.Events); i++ {
+			replayed, err := replayPCR(after.GetHash(), index, diff.Events[:i])
+			if err != nil {
+				return nil, fmt.Errorf("PCR%d: %w", index, err)
+			}
+			if diff.Common < 0 && bytes.Equal(replayed, beforeValue) {
+				diff.Common = i
+			}
+			if i == len(diff.Events) {
+				diff.Replayed = bytes.Equal(replayed, afterValue)
+			}
+		}
+		diffs = append(diffs, diff)
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Index < diffs[j].Index })
+	return diffs, nil
+}
+
+// replayPCR returns the value of the PCR after the events (see PredictPCRs).
+func replayPCR(hash pb.HashAlgo, index uint32, events []*attestpb.Event) ([]byte, error) {
+	predicted, err := PredictPCRs(hash, events)
+	if err != nil {
+		return nil, err
+	}
+	if value, ok := predicted.GetPcrs()[index]; ok {
+		return value, nil
+	}
+	cryptoHash, err := tpm2.Algorithm(hash).Hash()
+	if err != nil {
+		return nil, err
+	}
+	return make([]byte, cryptoHash.Size()), nil
+}
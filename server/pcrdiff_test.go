@@ -0,0 +1,72 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+func TestDiffPCRs(t *testing.T) {
+	after := Debian10GCE.Banks[0]
+	state, err := ParseMachineState(Debian10GCE.RawLog, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Before: the state of an earlier boot without the last event of PCR4 (as
+	// if a boot loader was added), and with a different PCR0 (as if the
+	// firmware was updated).
+	var pcr4Events int
+	last := -1
+	for i, event := range state.GetRawEvents() {
+		if event.GetPcrIndex() == 4 {
+			pcr4Events++
+			last = i
+		}
+	}
+	events := append(state.GetRawEvents()[:last:last], state.GetRawEvents()[last+1:]...)
+	predicted, err := PredictPCRs(after.GetHash(), events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := &pb.PCRs{Hash: after.GetHash(), Pcrs: map[uint32][]byte{}}
+	for index, value := range after.GetPcrs() {
+		before.Pcrs[index] = value
+	}
+	before.Pcrs[4] = predicted.GetPcrs()[4]
+	before.Pcrs[0] = bytes.Repeat([]byte{1}, len(after.GetPcrs()[0]))
+
+	diffs, err := DiffPCRs(before, after, Debian10GCE.RawLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 2 || diffs[0].Index != 0 || diffs[1].Index != 4 {
+		t.Fatalf("got diffs %v, want PCR0 and PCR4", diffs)
+	}
+	for _, diff := range diffs {
+		if !diff.Replayed {
+			t.Errorf("PCR%d: event log does not replay", diff.Index)
+		}
+	}
+	if diffs[0].Common != -1 {
+		t.Errorf("PCR0: got %d common events, want -1", diffs[0].Common)
+	}
+	if diffs[1].Common != pcr4Events-1 || len(diffs[1].Events) != pcr4Events {
+		t.Errorf("PCR4: got %d common events of %d, want %d of %d", diffs[1].Common, len(diffs[1].Events), pcr4Events-1, pcr4Events)
+	}
+
+	// With the log of another boot (with the same firmware), the events of
+	// the boot loader do not replay.
+	diffs, err = DiffPCRs(before, after, Rhel8GCE.RawLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 2 || diffs[1].Replayed {
+		t.Error("expected the event log of another boot not to replay")
+	}
+
+	sha256 := &pb.PCRs{Hash: pb.HashAlgo_SHA256, Pcrs: before.GetPcrs()}
+	if _, err = DiffPCRs(sha256, after, Debian10GCE.RawLog); err == nil {
+		t.Error("expected comparing different PCR banks to fail")
+	}
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"fmt"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+// EvaluatePolicy checks that the MachineState (as returned by
+// VerifyAttestation or ParseMachineState) satisfies the provided Policy. A nil
+// Policy (or one with all fields unset) allows any MachineState.
+func EvaluatePolicy(state *pb.MachineState, policy *pb.Policy) error {
+	if err := evaluatePlatformPolicy(state.GetPlatform(), policy.GetPlatform()); err != nil {
+		return fmt.Errorf("platform policy failed: %w", err)
+	}
+	return nil
+}
+
+func evaluatePlatformPolicy(state *pb.PlatformState, policy *pb.PlatformPolicy) error {
+	allowedVersions := policy.GetAllowedScrtmVersionIds()
+	minGceVersion := policy.GetMinimumGceFirmwareVersion()
+	switch fw := state.GetFirmware().(type) {
+	case *pb.PlatformState_ScrtmVersionId:
+		if minGceVersion != 0 && len(allowedVersions) == 0 {
+			return fmt.Errorf("expected GCE firmware version >= %d, but got non-GCE firmware", minGceVersion)
+		}
+		if len(allowedVersions) > 0 && !contains(allowedVersions, fw.ScrtmVersionId) {
+			return fmt.Errorf("SCRTM version ID %x is not in the allowed list", fw.ScrtmVersionId)
+		}
+	case *pb.PlatformState_GceVersion:
+		if fw.GceVersion < minGceVersion {
+			return fmt.Errorf("expected GCE firmware version >= %d, got %d", minGceVersion, fw.GceVersion)
+		}
+	default:
+		if minGceVersion != 0 || len(allowedVersions) > 0 {
+			return fmt.Errorf("platform firmware version is unknown")
+		}
+	}
+
+	if minTech := policy.GetMinimumTechnology(); state.GetTechnology() < minTech {
+		return fmt.Errorf("expected confidential technology >= %v, got %v", minTech, state.GetTechnology())
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"testing"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+func TestEvaluatePolicy(t *testing.T) {
+	gceState := &pb.MachineState{Platform: &pb.PlatformState{
+		Firmware:   &pb.PlatformState_GceVersion{GceVersion: 1},
+		Technology: pb.GCEConfidentialTechnology_AMD_SEV,
+	}}
+	scrtmState := &pb.MachineState{Platform: &pb.PlatformState{
+		Firmware: &pb.PlatformState_ScrtmVersionId{ScrtmVersionId: []byte("version 1")},
+	}}
+
+	tests := []struct {
+		name    string
+		state   *pb.MachineState
+		policy  *pb.Policy
+		allowed bool
+	}{
+		{"NilPolicy", gceState, nil, true},
+		{"EmptyPolicy", scrtmState, &pb.Policy{}, true},
+		{"MinimumGCEVersion", gceState, &pb.Policy{Platform: &pb.PlatformPolicy{MinimumGceFirmwareVersion: 1}}, true},
+		{"GCEVersionTooOld", gceState, &pb.Policy{Platform: &pb.PlatformPolicy{MinimumGceFirmwareVersion: 2}}, false},
+		{"NonGCEFirmware", scrtmState, &pb.Policy{Platform: &pb.PlatformPolicy{MinimumGceFirmwareVersion: 1}}, false},
+		{"AllowedSCRTMVersion", scrtmState, &pb.Policy{Platform: &pb.PlatformPolicy{
+			AllowedScrtmVersionIds: [][]byte{[]byte("version 0"), []byte("version 1")},
+		}}, true},
+		{"DisallowedSCRTMVersion", scrtmState, &pb.Policy{Platform: &pb.PlatformPolicy{
+			AllowedScrtmVersionIds: [][]byte{[]byte("version 0")},
+		}}, false},
+		{"MinimumTechnology", gceState, &pb.Policy{Platform: &pb.PlatformPolicy{
+			MinimumTechnology: pb.GCEConfidentialTechnology_AMD_SEV,
+		}}, true},
+		{"TechnologyTooWeak", gceState, &pb.Policy{Platform: &pb.PlatformPolicy{
+			MinimumTechnology: pb.GCEConfidentialTechnology_AMD_SEV_ES,
+		}}, false},
+		{"EmptyState", &pb.MachineState{}, &pb.Policy{Platform: &pb.PlatformPolicy{MinimumGceFirmwareVersion: 1}}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := EvaluatePolicy(tc.state, tc.policy)
+			if tc.allowed && err != nil {
+				t.Errorf("expected policy to pass, got: %v", err)
+			}
+			if !tc.allowed && err == nil {
+				t.Error("expected policy to fail")
+			}
+		})
+	}
+}
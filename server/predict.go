@@ -0,0 +1,60 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+	"github.com/google/go-tpm/tpm2"
+)
+
+// The EV_NO_ACTION event indicating the locality of TPM2_Startup, from the
+// TCG PC Client Platform Firmware Profile Specification, Section 9.4.5.3.
+var startupLocalitySignature = []byte("StartupLocality\x00")
+
+// PredictPCRs computes the PCR values that would result from a TPM (with all
+// PCRs reset) having each of the events extended into the PCR bank for hash.
+// The returned PCRs contain a value for every PCR index used by an event, and
+// can be passed to client.SealTarget to seal data to a future PCR state.
+//
+// The events would typically be the verified events of the current boot (i.e.
+// MachineState.RawEvents), with some events replaced by (or followed by) the
+// events expected in the future boot. For example, after installing a new
+// kernel, its expected measurement would replace the current kernel's event.
+// The event Digests must be for the given hash. EV_NO_ACTION events are not
+// extended, but are used to determine the locality of TPM2_Startup.
+func PredictPCRs(hash pb.HashAlgo, events []*attestpb.Event) (*pb.PCRs, error) {
+	cryptoHash, err := tpm2.Algorithm(hash).Hash()
+	if err != nil {
+		return nil, fmt.Errorf("unsupported hash algorithm %v: %w", hash, err)
+	}
+
+	pcrs := &pb.PCRs{Hash: hash, Pcrs: map[uint32][]byte{}}
+	for i, event := range events {
+		index := event.GetPcrIndex()
+		value, ok := pcrs.Pcrs[index]
+		if !ok {
+			value = make([]byte, cryptoHash.Size())
+		}
+		if event.GetUntrustedType() == NoAction {
+			if index == 0 && isStartupLocality(event.GetData()) {
+				value[len(value)-1] = event.GetData()[len(startupLocalitySignature)]
+				pcrs.Pcrs[index] = value
+			}
+			continue
+		}
+		if len(event.GetDigest()) != cryptoHash.Size() {
+			return nil, fmt.Errorf("event %d has a digest of size %d, expected %d", i, len(event.GetDigest()), cryptoHash.Size())
+		}
+		hasher := cryptoHash.New()
+		hasher.Write(value)
+		hasher.Write(event.GetDigest())
+		pcrs.Pcrs[index] = hasher.Sum(nil)
+	}
+	return pcrs, nil
+}
+
+func isStartupLocality(data []byte) bool {
+	return len(data) == len(startupLocalitySignature)+1 && bytes.HasPrefix(data, startupLocalitySignature)
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+func TestPredictPCRsMatchesEventLog(t *testing.T) {
+	logs := []struct {
+		eventLog
+		name string
+	}{
+		{Debian10GCE, "Debian10GCE"},
+		{Rhel8GCE, "Rhel8GCE"},
+		{Ubuntu2104NoDbxGCE, "Ubuntu2104NoDbxGCE"},
+		{Ubuntu2104NoSecureBootGCE, "Ubuntu2104NoSecureBootGCE"},
+		{GlinuxNoSecureBootLaptop, "GlinuxNoSecureBootLaptop"},
+		{ArchLinuxWorkstation, "ArchLinuxWorkstation"},
+	}
+	for _, log := range logs {
+		for _, bank := range log.Banks {
+			hashName := pb.HashAlgo_name[int32(bank.Hash)]
+			t.Run(fmt.Sprintf("%s-%s", log.name, hashName), func(t *testing.T) {
+				state, err := ParseMachineState(log.RawLog, bank)
+				if err != nil {
+					t.Fatal(err)
+				}
+				predicted, err := PredictPCRs(bank.GetHash(), state.GetRawEvents())
+				if err != nil {
+					t.Fatal(err)
+				}
+				for index, value := range predicted.GetPcrs() {
+					if !bytes.Equal(value, bank.GetPcrs()[index]) {
+						t.Errorf("PCR%d: predicted %x, expected %x", index, value, bank.GetPcrs()[index])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestPredictPCRsReplacedEvent(t *testing.T) {
+	oldKernel := sha256.Sum256([]byte("old kernel"))
+	newKernel := sha256.Sum256([]byte("new kernel"))
+	separator := sha256.Sum256([]byte{0, 0, 0, 0})
+	locality := append(append([]byte{}, startupLocalitySignature...), 3)
+
+	events := []*attestpb.Event{
+		{PcrIndex: 0, UntrustedType: NoAction, Data: locality},
+		{PcrIndex: 0, UntrustedType: Separator, Digest: separator[:]},
+		{PcrIndex: 4, UntrustedType: 0x80000003, Digest: oldKernel[:]},
+	}
+	current, err := PredictPCRs(pb.HashAlgo_SHA256, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events[2] = &attestpb.Event{PcrIndex: 4, UntrustedType: 0x80000003, Digest: newKernel[:]}
+	future, err := PredictPCRs(pb.HashAlgo_SHA256, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initial := make([]byte, sha256.Size)
+	initial[sha256.Size-1] = 3
+	want0 := sha256.Sum256(append(initial, separator[:]...))
+	want4 := sha256.Sum256(append(make([]byte, sha256.Size), newKernel[:]...))
+	if !bytes.Equal(future.GetPcrs()[0], want0[:]) {
+		t.Errorf("PCR0: got %x, want %x", future.GetPcrs()[0], want0)
+	}
+	if !bytes.Equal(future.GetPcrs()[4], want4[:]) {
+		t.Errorf("PCR4: got %x, want %x", future.GetPcrs()[4], want4)
+	}
+	if bytes.Equal(current.GetPcrs()[4], future.GetPcrs()[4]) {
+		t.Error("expected replacing an event to change the predicted PCR")
+	}
+	if len(future.GetPcrs()) != 2 {
+		t.Errorf("got %d predicted PCRs, want 2", len(future.GetPcrs()))
+	}
+
+	badEvents := []*attestpb.Event{{PcrIndex: 4, Digest: []byte("short")}}
+	if _, err = PredictPCRs(pb.HashAlgo_SHA256, badEvents); err == nil {
+		t.Error("expected failure with a digest of the wrong size")
+	}
+}
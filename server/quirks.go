@@ -0,0 +1,242 @@
+package server
+
+import (
+	"bytes"
+	"crypto"
+	"fmt"
+	"sort"
+
+	"github.com/google/go-attestation/attest"
+	"github.com/google/go-tpm/tpm2"
+
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+// ReplayExplanation explains why the events of a PCR do not replay to its
+// value.
+type ReplayExplanation struct {
+	Index uint32
+	// The value of the PCR, and the value the events replay to.
+	Value, Replayed []byte
+	// A description of the known quirk (of firmware, or of the event log)
+	// with which the events replay to Value, or an empty string if none does.
+	Quirk string
+}
+
+// quirkEvents are the events of a PCR, with the inputs of the quirks.
+type quirkEvents struct {
+	hash   crypto.Hash
+	index  uint32
+	events []*attestpb.Event
+	// The SHA-1 digests of the events, if the log has them for all events.
+	sha1Digests [][]byte
+}
+
+// A replayQuirk returns the values of the PCR for each variation of the quirk,
+// with their descriptions, or nothing if the quirk does not apply to the
+// events.
+type replayQuirk func(q *quirkEvents) ([]string, [][]byte)
+
+// The known quirks of event logs, tried in order.
+var replayQuirks = []replayQuirk{
+	startupLocalityQuirk,
+	allOnesQuirk,
+	noActionQuirk,
+	separatorQuirk,
+	paddedSHA1Quirk,
+	truncatedQuirk,
+}
+
+// ExplainReplay finds the PCRs whose events in the event log do not replay to
+// their values in pcrs, and tries known quirks of event logs for each of them:
+// the locality of TPM2_Startup, PCRs starting with all bits set, extended
+// EV_NO_ACTION events, separator variations, SHA-1 digests extended into
+// other banks, and events which were logged but not extended. It returns an
+// explanation for each PCR which does not replay (with events in the log),
+// ordered by index.
+//
+// As the quirks can make a log look consistent with almost any PCR value
+// given enough variations, the explanations are only meant for debugging:
+// the result of a quirk must not be used to trust an event log.
+func ExplainReplay(rawEventLog []byte, pcrs *pb.PCRs) ([]*ReplayExplanation, error) {
+	cryptoHash, err := tpm2.Algorithm(pcrs.GetHash()).Hash()
+	if err != nil {
+		return nil, fmt.Errorf("unsupported hash algorithm %v: %w", pcrs.GetHash(), err)
+	}
+	eventLog, err := attest.ParseEventLog(rawEventLog)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse event log: %w", err)
+	}
+	events := convertToPbEvents(cryptoHash, eventLog.Events(attest.HashAlg(pcrs.GetHash())))
+	sha1Events := eventLog.Events(attest.HashSHA1)
+
+	var explanations []*ReplayExplanation
+	for index, value := range pcrs.GetPcrs() {
+		q := &quirkEvents{hash: cryptoHash, index: index}
+		var sha1Digests [][]byte
+		for i, event := range events {
+			if event.GetPcrIndex() != index {
+				continue
+			}
+			q.events = append(q.events, event)
+			if len(sha1Events) == len(events) && sha1Events[i].Index == int(index) {
+				sha1Digests = append(sha1Digests, sha1Events[i].Digest)
+			}
+		}
+		if len(q.events) == 0 {
+			continue
+		}
+		if len(sha1Digests) == len(q.events) {
+			q.sha1Digests = sha1Digests
+		}
+		replayed, err := replayPCR(pcrs.GetHash(), index, q.events)
+		if err != nil {
+			return nil, fmt.Errorf("PCR%d: %w", index, err)
+		}
+		if bytes.Equal(replayed, value) {
+			continue
+		}
+		explanation := &ReplayExplanation{Index: index, Value: value, Replayed: replayed}
+	quirks:
+		for _, quirk := range replayQuirks {
+			descriptions, values := quirk(q)
+			for i, quirkValue := range values {
+				if bytes.Equal(quirkValue, value) {
+					explanation.Quirk = descriptions[i]
+					break quirks
+				}
+			}
+		}
+		explanations = append(explanations, explanation)
+	}
+	sort.Slice(explanations, func(i, j int) bool { return explanations[i].Index < explanations[j].Index })
+	return explanations, nil
+}
+
+// extend returns the value of a PCR with the initial value, after the digests
+// are extended into it.
+func (q *quirkEvents) extend(initial []byte, digests [][]byte) []byte {
+	value := initial
+	for _, digest := range digests {
+		hasher := q.hash.New()
+		hasher.Write(value)
+		hasher.Write(digest)
+		value = hasher.Sum(nil)
+	}
+	return value
+}
+
+// digests returns the digests of the events which are extended (all but the
+// EV_NO_ACTION events).
+func (q *quirkEvents) digests() [][]byte {
+	var digests [][]byte
+	for _, event := range q.events {
+		if event.GetUntrustedType() != NoAction {
+			digests = append(digests, event.GetDigest())
+		}
+	}
+	return digests
+}
+
+// startupLocalityQuirk tries the initial values of PCR0 for the localities of
+// TPM2_Startup (0, or 3 and 4 with an H-CRTM), ignoring the StartupLocality
+// event of the log (which may be missing, or wrong).
+func startupLocalityQuirk(q *quirkEvents) ([]string, [][]byte) {
+	if q.index != 0 {
+		return nil, nil
+	}
+	var descriptions []string
+	var values [][]byte
+	for _, locality := range []byte{0, 3, 4} {
+		initial := make([]byte, q.hash.Size())
+		initial[len(initial)-1] = locality
+		descriptions = append(descriptions, fmt.Sprintf("TPM2_Startup was at locality %d, whatever the StartupLocality event of the log", locality))
+		values = append(values, q.extend(initial, q.digests()))
+	}
+	return descriptions, values
+}
+
+// allOnesQuirk tries an initial value with all bits set, as for the PCRs of
+// dynamic launches (17 to 22) before they are reset.
+func allOnesQuirk(q *quirkEvents) ([]string, [][]byte) {
+	initial := bytes.Repeat([]byte{0xff}, q.hash.Size())
+	return []string{"the PCR started with all bits set (as the PCRs of a DRTM)"}, [][]byte{q.extend(initial, q.digests())}
+}
+
+// noActionQuirk extends the EV_NO_ACTION events too.
+func noActionQuirk(q *quirkEvents) ([]string, [][]byte) {
+	var digests [][]byte
+	found := false
+	for _, event := range q.events {
+		if event.GetUntrustedType() == NoAction {
+			if len(event.GetDigest()) != q.hash.Size() {
+				continue
+			}
+			found = true
+		}
+		digests = append(digests, event.GetDigest())
+	}
+	if !found {
+		return nil, nil
+	}
+	return []string{"the EV_NO_ACTION events were extended"}, [][]byte{q.extend(make([]byte, q.hash.Size()), digests)}
+}
+
+// separatorQuirk extends the separators with the digest of each of the valid
+// separator values (instead of their logged digests).
+func separatorQuirk(q *quirkEvents) ([]string, [][]byte) {
+	var descriptions []string
+	var values [][]byte
+	for i, separatorDigest := range getSeparatorDigests(q.hash) {
+		var digests [][]byte
+		found := false
+		for _, event := range q.events {
+			switch {
+			case event.GetUntrustedType() == NoAction:
+				continue
+			case event.GetUntrustedType() == Separator:
+				found = true
+				digests = append(digests, separatorDigest)
+			default:
+				digests = append(digests, event.GetDigest())
+			}
+		}
+		if found {
+			descriptions = append(descriptions, fmt.Sprintf("the EV_SEPARATOR events were extended with the digest of %x, not their logged digest", separatorData[i]))
+			values = append(values, q.extend(make([]byte, q.hash.Size()), digests))
+		}
+	}
+	return descriptions, values
+}
+
+// paddedSHA1Quirk extends the SHA-1 digests of the events, zero-padded to the
+// size of the bank, as by some firmware with only SHA-1 support.
+func paddedSHA1Quirk(q *quirkEvents) ([]string, [][]byte) {
+	if q.hash == crypto.SHA1 || q.sha1Digests == nil {
+		return nil, nil
+	}
+	var digests [][]byte
+	for i, event := range q.events {
+		if event.GetUntrustedType() != NoAction {
+			padded := make([]byte, q.hash.Size())
+			copy(padded, q.sha1Digests[i])
+			digests = append(digests, padded)
+		}
+	}
+	return []string{"the SHA-1 digests of the events were extended (zero-padded) into this bank"}, [][]byte{q.extend(make([]byte, q.hash.Size()), digests)}
+}
+
+// truncatedQuirk replays the first events only, for logs with events which
+// were not extended (such as when extending failed, or the log was captured
+// from another boot with the same prefix).
+func truncatedQuirk(q *quirkEvents) ([]string, [][]byte) {
+	digests := q.digests()
+	var descriptions []string
+	var values [][]byte
+	for i := len(digests) - 1; i >= 0; i-- {
+		descriptions = append(descriptions, fmt.Sprintf("only the first %d of the %d events were extended", i, len(digests)))
+		values = append(values, q.extend(make([]byte, q.hash.Size()), digests[:i]))
+	}
+	return descriptions, values
+}
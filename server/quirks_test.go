@@ -0,0 +1,106 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-attestation/attest"
+
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+func TestExplainReplay(t *testing.T) {
+	explanations, err := ExplainReplay(Rhel8GCE.RawLog, Rhel8GCE.Banks[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(explanations) != 0 {
+		t.Fatalf("got explanations %v for an event log which replays", explanations)
+	}
+
+	eventLog, err := attest.ParseEventLog(Rhel8GCE.RawLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sha1Events := eventLog.Events(attest.HashSHA1)
+	// digests returns the logged SHA-256 (or SHA-1) digests of the events of
+	// the PCR which are extended.
+	digests := func(index int, sha1 bool) [][]byte {
+		var digests [][]byte
+		for i, event := range eventLog.Events(attest.HashSHA256) {
+			if event.Index != index || event.Type == attest.EventType(NoAction) {
+				continue
+			}
+			if sha1 {
+				digests = append(digests, sha1Events[i].Digest)
+			} else {
+				digests = append(digests, event.Digest)
+			}
+		}
+		return digests
+	}
+	extend := func(initial []byte, digests [][]byte) []byte {
+		value := initial
+		for _, digest := range digests {
+			padded := make([]byte, sha256.Size)
+			copy(padded, digest)
+			sum := sha256.Sum256(append(value, padded...))
+			value = sum[:]
+		}
+		return value
+	}
+
+	pcrs := &pb.PCRs{Hash: pb.HashAlgo_SHA256, Pcrs: map[uint32][]byte{}}
+	for index, value := range Rhel8GCE.Banks[1].GetPcrs() {
+		pcrs.Pcrs[index] = value
+	}
+	locality3 := make([]byte, sha256.Size)
+	locality3[sha256.Size-1] = 3
+	pcrs.Pcrs[0] = extend(locality3, digests(0, false))
+	separator := sha256.Sum256([]byte{0xff, 0xff, 0xff, 0xff})
+	pcr1 := digests(1, false)
+	// The separator is the last event of PCR1.
+	pcr1[len(pcr1)-1] = separator[:]
+	pcrs.Pcrs[1] = extend(make([]byte, sha256.Size), pcr1)
+	pcr4 := digests(4, false)
+	pcrs.Pcrs[4] = extend(make([]byte, sha256.Size), pcr4[:len(pcr4)-2])
+	pcrs.Pcrs[7] = extend(make([]byte, sha256.Size), digests(7, true))
+	pcrs.Pcrs[9] = bytes.Repeat([]byte{1}, sha256.Size)
+
+	explanations, err = ExplainReplay(Rhel8GCE.RawLog, pcrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		index uint32
+		quirk string
+	}{
+		{0, "locality 3"},
+		{1, "digest of ffffffff"},
+		{4, "first " + strconv.Itoa(len(pcr4)-2) + " of"},
+		{7, "SHA-1 digests"},
+		{9, ""},
+	}
+	if len(explanations) != len(want) {
+		t.Fatalf("got %d explanations, want %d", len(explanations), len(want))
+	}
+	for i, explanation := range explanations {
+		if explanation.Index != want[i].index {
+			t.Errorf("got explanation for PCR%d, want PCR%d", explanation.Index, want[i].index)
+			continue
+		}
+		if !bytes.Equal(explanation.Value, pcrs.GetPcrs()[explanation.Index]) || !bytes.Equal(explanation.Replayed, Rhel8GCE.Banks[1].GetPcrs()[explanation.Index]) {
+			t.Errorf("PCR%d: unexpected values in explanation", explanation.Index)
+		}
+		if want[i].quirk == "" {
+			if explanation.Quirk != "" {
+				t.Errorf("PCR%d: got quirk %q, want none", explanation.Index, explanation.Quirk)
+			}
+		} else if !strings.Contains(explanation.Quirk, want[i].quirk) {
+			t.Errorf("PCR%d: got quirk %q, want %q", explanation.Index, explanation.Quirk, want[i].quirk)
+		}
+	}
+}
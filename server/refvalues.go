@@ -0,0 +1,221 @@
+package server
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+// ReferenceValue is a known digest of an event of the event log, such as the
+// Authenticode digest of a release of shim, or the digest of a firmware
+// volume.
+type ReferenceValue struct {
+	// The digest of the event, in any PCR bank.
+	Digest []byte
+	// The type of the event, or 0 for an event of any type.
+	EventType uint32
+	// The PCR of the event, or nil for an event in any PCR.
+	PCR *uint32
+	// A description of the measured component, such as "shim 15.7".
+	Label string
+}
+
+// matches returns whether the event matches the reference value.
+func (v *ReferenceValue) matches(event *attestpb.Event) bool {
+	return bytes.Equal(v.Digest, event.GetDigest()) &&
+		(v.EventType == 0 || v.EventType == event.GetUntrustedType()) &&
+		(v.PCR == nil || *v.PCR == event.GetPcrIndex())
+}
+
+// ReferenceValueProvider is a database of reference values, for labeling the
+// events of event logs.
+type ReferenceValueProvider interface {
+	// LookupDigest returns the reference values with the digest, if any.
+	LookupDigest(digest []byte) ([]*ReferenceValue, error)
+	// LookupEventType returns the reference values of events of the type
+	// (including the reference values for events of any type), if any.
+	LookupEventType(eventType uint32) ([]*ReferenceValue, error)
+}
+
+// LabelEvents returns the label of the reference value matching each event
+// (by its digest, type and PCR), or an empty string for events without a
+// known digest. The first matching reference value is used.
+//
+// The labels describe the digests of the events, not their (untrusted) data.
+// They are only meaningful for events whose digests are trusted, such as the
+// events of a log which replays to quoted PCRs.
+func LabelEvents(provider ReferenceValueProvider, events []*attestpb.Event) ([]string, error) {
+	labels := make([]string, len(events))
+	for i, event := range events {
+		values, err := provider.LookupDigest(event.GetDigest())
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up event %d: %w", i, err)
+		}
+		for _, value := range values {
+			if value.matches(event) {
+				labels[i] = value.Label
+				break
+			}
+		}
+	}
+	return labels, nil
+}
+
+// The JSON encoding of a reference value, in the files of the filesystem
+// database and the responses of the HTTP database.
+type referenceValueJSON struct {
+	Digest    hexBytes `json:"digest"`
+	EventType uint32   `json:"event-type,omitempty"`
+	PCR       *uint32  `json:"pcr,omitempty"`
+	Label     string   `json:"label"`
+}
+
+func parseReferenceValues(data []byte) ([]*ReferenceValue, error) {
+	var encoded []referenceValueJSON
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return nil, err
+	}
+	values := make([]*ReferenceValue, 0, len(encoded))
+	for i, value := range encoded {
+		if len(value.Digest) == 0 {
+			return nil, fmt.Errorf("reference value %d has no digest", i)
+		}
+		values = append(values, &ReferenceValue{
+			Digest:    value.Digest,
+			EventType: value.EventType,
+			PCR:       value.PCR,
+			Label:     value.Label,
+		})
+	}
+	return values, nil
+}
+
+// ReferenceValues is an in-memory database of reference values.
+type ReferenceValues []*ReferenceValue
+
+// LoadReferenceValues reads a database of reference values from a JSON file,
+// or from all the JSON files (with a .json extension) of a directory. Each
+// file is an array of reference values, such as:
+//
+//	[{"digest": "<hex>", "event-type": 2147483651, "pcr": 4, "label": "shim 15.7"}]
+//
+// where event-type and pcr are optional.
+func LoadReferenceValues(path string) (ReferenceValues, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(path, "*.json")); err != nil {
+			return nil, err
+		}
+	}
+	var values ReferenceValues
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := parseReferenceValues(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reference values in %s: %w", file, err)
+		}
+		values = append(values, parsed...)
+	}
+	return values, nil
+}
+
+// LookupDigest implements ReferenceValueProvider.
+func (r ReferenceValues) LookupDigest(digest []byte) ([]*ReferenceValue, error) {
+	var values []*ReferenceValue
+	for _, value := range r {
+		if bytes.Equal(value.Digest, digest) {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+// LookupEventType implements ReferenceValueProvider.
+func (r ReferenceValues) LookupEventType(eventType uint32) ([]*ReferenceValue, error) {
+	var values []*ReferenceValue
+	for _, value := range r {
+		if value.EventType == 0 || value.EventType == eventType {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+// HTTPReferenceValues is a database of reference values served over HTTP. The
+// reference values are fetched from <base URL>/digests/<hex digest> and
+// <base URL>/event-types/<event type>, which return a JSON array of reference
+// values (as for LoadReferenceValues), or 404 Not Found if there are none.
+// The responses are cached.
+type HTTPReferenceValues struct {
+	client  *http.Client
+	baseURL string
+
+	mu    sync.Mutex
+	cache map[string][]*ReferenceValue
+}
+
+// NewHTTPReferenceValues returns a database of reference values served at the
+// base URL, fetched with the given HTTP client.
+func NewHTTPReferenceValues(client *http.Client, baseURL string) *HTTPReferenceValues {
+	return &HTTPReferenceValues{
+		client:  client,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		cache:   map[string][]*ReferenceValue{},
+	}
+}
+
+// LookupDigest implements ReferenceValueProvider.
+func (h *HTTPReferenceValues) LookupDigest(digest []byte) ([]*ReferenceValue, error) {
+	return h.fetch("/digests/" + hex.EncodeToString(digest))
+}
+
+// LookupEventType implements ReferenceValueProvider.
+func (h *HTTPReferenceValues) LookupEventType(eventType uint32) ([]*ReferenceValue, error) {
+	return h.fetch("/event-types/" + strconv.FormatUint(uint64(eventType), 10))
+}
+
+func (h *HTTPReferenceValues) fetch(path string) ([]*ReferenceValue, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if values, ok := h.cache[path]; ok {
+		return values, nil
+	}
+	resp, err := h.client.Get(h.baseURL + path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var values []*ReferenceValue
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+	case http.StatusOK:
+		if values, err = parseReferenceValues(body); err != nil {
+			return nil, fmt.Errorf("invalid reference values from %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("got HTTP status %q", resp.Status)
+	}
+	h.cache[path] = values
+	return values, nil
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"crypto"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-attestation/attest"
+)
+
+const efiBootServicesApplication uint32 = 0x80000003
+
+func TestLabelEvents(t *testing.T) {
+	eventLog, err := attest.ParseEventLog(Rhel8GCE.RawLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := convertToPbEvents(crypto.SHA256, eventLog.Events(attest.HashSHA256))
+	app, separator := -1, -1
+	for i, event := range events {
+		if app < 0 && event.GetUntrustedType() == efiBootServicesApplication && event.GetPcrIndex() == 4 {
+			app = i
+		}
+		if separator < 0 && event.GetUntrustedType() == Separator && event.GetPcrIndex() == 7 {
+			separator = i
+		}
+	}
+	if app < 0 || separator < 0 {
+		t.Fatal("event log has no boot application or separator")
+	}
+
+	dir, err := ioutil.TempDir("", "refvalues")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// The separator is only labeled in PCR7, and the application only as an
+	// EV_EFI_BOOT_SERVICES_APPLICATION.
+	shim := fmt.Sprintf(`[{"digest": %q, "event-type": %d, "label": "shim 15.7"}]`,
+		hex.EncodeToString(events[app].GetDigest()), efiBootServicesApplication)
+	separators := fmt.Sprintf(`[{"digest": %q, "pcr": 7, "label": "separator"}]`,
+		hex.EncodeToString(events[separator].GetDigest()))
+	for name, contents := range map[string]string{"shim.json": shim, "separators.json": separators, "README": "not JSON"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	values, err := LoadReferenceValues(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 {
+		t.Fatalf("got %d reference values, want 2", len(values))
+	}
+	if byType, err := values.LookupEventType(efiBootServicesApplication); err != nil || len(byType) != 2 {
+		t.Errorf("got %d reference values of the type (%v), want 2", len(byType), err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var found []*ReferenceValue
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/refs/digests/"):
+			digest, err := hex.DecodeString(strings.TrimPrefix(r.URL.Path, "/refs/digests/"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			found, _ = values.LookupDigest(digest)
+		case strings.HasPrefix(r.URL.Path, "/refs/event-types/"):
+			eventType, err := strconv.ParseUint(strings.TrimPrefix(r.URL.Path, "/refs/event-types/"), 10, 32)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			found, _ = values.LookupEventType(uint32(eventType))
+		}
+		if len(found) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		var encoded []string
+		for _, value := range found {
+			pcr := "null"
+			if value.PCR != nil {
+				pcr = strconv.Itoa(int(*value.PCR))
+			}
+			encoded = append(encoded, fmt.Sprintf(`{"digest": %q, "event-type": %d, "pcr": %s, "label": %q}`,
+				hex.EncodeToString(value.Digest), value.EventType, pcr, value.Label))
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(encoded, ","))
+	}))
+	defer server.Close()
+
+	for _, provider := range []struct {
+		name     string
+		provider ReferenceValueProvider
+	}{
+		{"filesystem", values},
+		{"HTTP", NewHTTPReferenceValues(server.Client(), server.URL+"/refs/")},
+	} {
+		t.Run(provider.name, func(t *testing.T) {
+			labels, err := LabelEvents(provider.provider, events)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, label := range labels {
+				var want string
+				switch {
+				case i == app:
+					want = "shim 15.7"
+				case i == separator:
+					want = "separator"
+				}
+				if label != want {
+					t.Errorf("event %d (PCR%d): got label %q, want %q", i, events[i].GetPcrIndex(), label, want)
+				}
+			}
+			if byType, err := provider.provider.LookupEventType(Separator); err != nil || len(byType) != 1 {
+				t.Errorf("got %d reference values of the type (%v), want 1", len(byType), err)
+			}
+		})
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	if _, err := LabelEvents(NewHTTPReferenceValues(failing.Client(), failing.URL), events); err == nil {
+		t.Error("expected labeling with an unavailable database to fail")
+	}
+	if _, err := LoadReferenceValues(filepath.Join(dir, "README")); err == nil {
+		t.Error("expected loading invalid reference values to fail")
+	}
+}
@@ -0,0 +1,278 @@
+package server
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+// An EFI_GUID, in its in-memory (mixed-endian) encoding.
+type efiGUID [16]byte
+
+func newEFIGUID(data1 uint32, data2, data3 uint16, data4 [8]byte) efiGUID {
+	var g efiGUID
+	binary.LittleEndian.PutUint32(g[0:], data1)
+	binary.LittleEndian.PutUint16(g[4:], data2)
+	binary.LittleEndian.PutUint16(g[6:], data3)
+	copy(g[8:], data4[:])
+	return g
+}
+
+// Vendor GUIDs of the Secure Boot variables, from the UEFI Specification.
+var (
+	efiGlobalVariable        = newEFIGUID(0x8be4df61, 0x93ca, 0x11d2, [8]byte{0xaa, 0x0d, 0x00, 0xe0, 0x98, 0x03, 0x2b, 0x8c})
+	efiImageSecurityDatabase = newEFIGUID(0xd719b2cb, 0x3d3a, 0x4596, [8]byte{0xa3, 0xbc, 0xda, 0xd0, 0x0e, 0x67, 0x65, 0x6f})
+)
+
+// Signature types of EFI_SIGNATURE_LISTs, from the UEFI Specification.
+var (
+	efiCertX509   = newEFIGUID(0xa5c059a1, 0x94e4, 0x4aa7, [8]byte{0x87, 0xb5, 0xab, 0x15, 0x5c, 0x2b, 0xf0, 0x72})
+	efiCertSHA1   = newEFIGUID(0x826ca512, 0xcf10, 0x4ac9, [8]byte{0xb1, 0x87, 0xbe, 0x01, 0x49, 0x66, 0x31, 0xbd})
+	efiCertSHA224 = newEFIGUID(0x0b6e5233, 0xa65c, 0x44c9, [8]byte{0x94, 0x07, 0xd9, 0xab, 0x83, 0xbf, 0xc8, 0xbd})
+	efiCertSHA256 = newEFIGUID(0xc1c41626, 0x504c, 0x4092, [8]byte{0xac, 0xa9, 0x41, 0xf9, 0x36, 0x93, 0x43, 0x28})
+	efiCertSHA384 = newEFIGUID(0xff3e5307, 0x9fd0, 0x48c9, [8]byte{0x85, 0xf1, 0x8a, 0xd5, 0x6c, 0x70, 0x1e, 0x01})
+	efiCertSHA512 = newEFIGUID(0x093e0fae, 0xa6c4, 0x4f50, [8]byte{0x9f, 0x1b, 0xd4, 0x1e, 0x2b, 0x89, 0xc1, 0x9a})
+	// The entries are the SHA-256 digest of the TBSCertificate of a revoked
+	// certificate, followed by the time of revocation.
+	efiCertX509SHA256 = newEFIGUID(0x3bd2a492, 0x96c0, 0x4079, [8]byte{0xb4, 0x20, 0xfc, 0xf9, 0x8e, 0xf1, 0x03, 0xed})
+)
+
+// The hash signature types, with the size of their digests.
+var efiCertHashSizes = map[efiGUID]int{
+	efiCertSHA1:       crypto.SHA1.Size(),
+	efiCertSHA224:     crypto.SHA224.Size(),
+	efiCertSHA256:     crypto.SHA256.Size(),
+	efiCertSHA384:     crypto.SHA384.Size(),
+	efiCertSHA512:     crypto.SHA512.Size(),
+	efiCertX509SHA256: crypto.SHA256.Size(),
+}
+
+// The data of the EV_EFI_ACTION event measured if a UEFI debugger is enabled.
+var efiDebugMode = []byte("UEFI Debug Mode")
+
+// The size of the owner GUID at the start of an EFI_SIGNATURE_DATA.
+const efiSignatureOwnerSize = 16
+
+// A parsed UEFI_VARIABLE_DATA structure, from the TCG PC Client Platform
+// Firmware Profile Specification.
+type efiVariable struct {
+	vendor efiGUID
+	name   string
+	data   []byte
+}
+
+func parseEFIVariable(b []byte) (*efiVariable, error) {
+	var header struct {
+		Vendor     efiGUID
+		NameLength uint64
+		DataLength uint64
+	}
+	r := bytes.NewReader(b)
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("reading UEFI_VARIABLE_DATA header: %w", err)
+	}
+	// Some versions of shim measure additional bytes after the variable data.
+	if header.NameLength > uint64(r.Len())/2 || header.DataLength > uint64(r.Len())-2*header.NameLength {
+		return nil, fmt.Errorf("UEFI_VARIABLE_DATA of size %d has invalid name length %d or data length %d",
+			len(b), header.NameLength, header.DataLength)
+	}
+	name := make([]uint16, header.NameLength)
+	if err := binary.Read(r, binary.LittleEndian, name); err != nil {
+		return nil, fmt.Errorf("reading variable name: %w", err)
+	}
+	start := len(b) - r.Len()
+	return &efiVariable{
+		vendor: header.Vendor,
+		name:   string(utf16.Decode(name)),
+		data:   b[start : start+int(header.DataLength)],
+	}, nil
+}
+
+// parseEFISignatureLists parses the certificates and hashes of a sequence of
+// EFI_SIGNATURE_LISTs, such as the contents of the db variable. Signature
+// types which are not certificates or hashes are ignored.
+func parseEFISignatureLists(b []byte) (*attestpb.Database, error) {
+	db := &attestpb.Database{}
+	for len(b) > 0 {
+		var header struct {
+			SignatureType       efiGUID
+			SignatureListSize   uint32
+			SignatureHeaderSize uint32
+			SignatureSize       uint32
+		}
+		headerSize := binary.Size(header)
+		if err := binary.Read(bytes.NewReader(b), binary.LittleEndian, &header); err != nil {
+			return nil, fmt.Errorf("reading EFI_SIGNATURE_LIST header: %w", err)
+		}
+		listSize := uint64(header.SignatureListSize)
+		start := uint64(headerSize) + uint64(header.SignatureHeaderSize)
+		sigSize := uint64(header.SignatureSize)
+		if listSize > uint64(len(b)) || start > listSize || sigSize <= efiSignatureOwnerSize || (listSize-start)%sigSize != 0 {
+			return nil, fmt.Errorf("EFI_SIGNATURE_LIST has invalid size %d, header size %d or signature size %d",
+				header.SignatureListSize, header.SignatureHeaderSize, header.SignatureSize)
+		}
+
+		hashSize, isHash := efiCertHashSizes[header.SignatureType]
+		if isHash && sigSize < efiSignatureOwnerSize+uint64(hashSize) {
+			return nil, fmt.Errorf("EFI_SIGNATURE_LIST has signature size %d, too small for its hash", header.SignatureSize)
+		}
+		for offset := start; offset < listSize; offset += sigSize {
+			data := b[offset+efiSignatureOwnerSize : offset+sigSize]
+			switch {
+			case header.SignatureType == efiCertX509:
+				if _, err := x509.ParseCertificate(data); err != nil {
+					return nil, fmt.Errorf("parsing certificate in EFI_SIGNATURE_LIST: %w", err)
+				}
+				db.Certs = append(db.Certs, data)
+			case isHash:
+				db.Hashes = append(db.Hashes, data[:hashSize])
+			}
+		}
+		b = b[listSize:]
+	}
+	return db, nil
+}
+
+// addAuthority adds the certificate or hash used to verify a boot component
+// (from an EV_EFI_VARIABLE_AUTHORITY event) to authority. If the variable is
+// a signature database (such as db), the data is an EFI_SIGNATURE_DATA,
+// otherwise (for the Shim and MokList variables of shim) it can also be a
+// certificate. Other authority events (such as shim's SbatLevel) are ignored.
+func addAuthority(authority *attestpb.Database, variable *efiVariable) {
+	if _, err := x509.ParseCertificate(variable.data); err == nil {
+		authority.Certs = append(authority.Certs, variable.data)
+		return
+	}
+	if len(variable.data) <= efiSignatureOwnerSize {
+		return
+	}
+	data := variable.data[efiSignatureOwnerSize:]
+	if _, err := x509.ParseCertificate(data); err == nil {
+		authority.Certs = append(authority.Certs, data)
+		return
+	}
+	if variable.vendor != efiImageSecurityDatabase {
+		return
+	}
+	for _, hashSize := range efiCertHashSizes {
+		if len(data) == hashSize {
+			authority.Hashes = append(authority.Hashes, data)
+			return
+		}
+	}
+}
+
+// getSecureBootState parses the Secure Boot variables (SecureBoot, PK, KEK,
+// db and dbx) measured into PCR7 before the separator, and the authorities
+// used to verify the boot components. All these events must have verified
+// digests, as their digests are the hash of their (UEFI_VARIABLE_DATA) data.
+func getSecureBootState(hash crypto.Hash, events []*attestpb.Event) (*attestpb.SecureBootState, error) {
+	separatorDigests := getSeparatorDigests(hash)
+	state := &attestpb.SecureBootState{
+		Db:        &attestpb.Database{},
+		Dbx:       &attestpb.Database{},
+		Authority: &attestpb.Database{},
+		Pk:        &attestpb.Database{},
+		Kek:       &attestpb.Database{},
+	}
+	seenSeparator := false
+	seenVariables := map[string]bool{}
+
+	for i, event := range events {
+		if event.GetPcrIndex() != 7 {
+			continue
+		}
+		isSeparator, err := checkIfValidSeparator(event, separatorDigests)
+		if err != nil {
+			return nil, err
+		}
+		if isSeparator {
+			seenSeparator = true
+			continue
+		}
+		evtType := event.GetUntrustedType()
+		if evtType == EFIAction && bytes.Equal(event.GetData(), efiDebugMode) {
+			return nil, errors.New("a UEFI debugger was present during boot")
+		}
+		if evtType != EFIVariableDriverConfig && evtType != EFIVariableAuthority {
+			continue
+		}
+
+		data := event.GetData()
+		if !event.GetDigestVerified() {
+			// Versions of shim without
+			// https://github.com/rhboot/shim/commit/8a27a4809a6a2b40fb6a4049071bf96d6ad71b50
+			// measure an additional byte in their authority events.
+			if evtType != EFIVariableAuthority || len(data) == 0 || !digestEquals(hash, data[:len(data)-1], event.GetDigest()) {
+				return nil, fmt.Errorf("PCR7 event %d of type 0x%x has an unverified digest", i, evtType)
+			}
+			data = data[:len(data)-1]
+		}
+		variable, err := parseEFIVariable(data)
+		if err != nil {
+			return nil, fmt.Errorf("PCR7 event %d: %w", i, err)
+		}
+		if evtType == EFIVariableAuthority {
+			addAuthority(state.Authority, variable)
+			continue
+		}
+
+		if seenSeparator {
+			return nil, fmt.Errorf("PCR7 event %d: variable %s measured after the separator", i, variable.name)
+		}
+		if seenVariables[variable.name] {
+			return nil, fmt.Errorf("PCR7 event %d: variable %s measured more than once", i, variable.name)
+		}
+		seenVariables[variable.name] = true
+		if err = setSecureBootVariable(state, variable); err != nil {
+			return nil, fmt.Errorf("PCR7 event %d: %w", i, err)
+		}
+	}
+	return state, nil
+}
+
+func digestEquals(hash crypto.Hash, data, digest []byte) bool {
+	hasher := hash.New()
+	hasher.Write(data)
+	return bytes.Equal(hasher.Sum(nil), digest)
+}
+
+func setSecureBootVariable(state *attestpb.SecureBootState, variable *efiVariable) error {
+	var db **attestpb.Database
+	switch {
+	case variable.vendor == efiGlobalVariable && variable.name == "SecureBoot":
+		// Some firmware measures an empty variable if Secure Boot is disabled.
+		switch {
+		case len(variable.data) == 0:
+			state.Enabled = false
+		case len(variable.data) == 1 && variable.data[0] <= 1:
+			state.Enabled = variable.data[0] == 1
+		default:
+			return errors.New("invalid SecureBoot variable")
+		}
+		return nil
+	case variable.vendor == efiGlobalVariable && variable.name == "PK":
+		db = &state.Pk
+	case variable.vendor == efiGlobalVariable && variable.name == "KEK":
+		db = &state.Kek
+	case variable.vendor == efiImageSecurityDatabase && variable.name == "db":
+		db = &state.Db
+	case variable.vendor == efiImageSecurityDatabase && variable.name == "dbx":
+		db = &state.Dbx
+	default:
+		return nil
+	}
+
+	parsed, err := parseEFISignatureLists(variable.data)
+	if err != nil {
+		return fmt.Errorf("parsing variable %s: %w", variable.name, err)
+	}
+	*db = parsed
+	return nil
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"unicode/utf16"
+
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+func TestParseSecureBootState(t *testing.T) {
+	tests := []struct {
+		name      string
+		log       eventLog
+		enabled   bool
+		pk        [][]byte
+		kek       [][]byte
+		db        [][]byte
+		dbx       [][]byte
+		dbxHashes int
+		authority [][]byte
+	}{
+		{
+			name:      "Rhel8GCE",
+			log:       Rhel8GCE,
+			enabled:   true,
+			pk:        [][]byte{GceDefaultPKCert},
+			kek:       [][]byte{MicrosoftKEKCA2011Cert},
+			db:        [][]byte{MicrosoftUEFICA2011Cert, WindowsProductionPCA2011Cert},
+			dbx:       [][]byte{RevokedCanonicalBootholeCert, RevokedCiscoCert, RevokedDebianBootholeCert},
+			dbxHashes: 183,
+			authority: [][]byte{MicrosoftUEFICA2011Cert},
+		},
+		{
+			name:      "Debian10GCE",
+			log:       Debian10GCE,
+			enabled:   true,
+			pk:        [][]byte{GceDefaultPKCert},
+			kek:       [][]byte{MicrosoftKEKCA2011Cert},
+			db:        [][]byte{MicrosoftUEFICA2011Cert, WindowsProductionPCA2011Cert},
+			dbx:       [][]byte{RevokedCanonicalBootholeCert, RevokedCiscoCert, RevokedDebianBootholeCert},
+			dbxHashes: 183,
+			authority: [][]byte{MicrosoftUEFICA2011Cert},
+		},
+		{
+			name: "Ubuntu2104NoDbxGCE",
+			log:  Ubuntu2104NoDbxGCE,
+			pk:   [][]byte{GceDefaultPKCert},
+			kek:  [][]byte{MicrosoftKEKCA2011Cert},
+			db:   [][]byte{MicrosoftUEFICA2011Cert, WindowsProductionPCA2011Cert},
+		},
+		{
+			name:      "Ubuntu2104NoSecureBootGCE",
+			log:       Ubuntu2104NoSecureBootGCE,
+			pk:        [][]byte{GceDefaultPKCert},
+			kek:       [][]byte{MicrosoftKEKCA2011Cert},
+			db:        [][]byte{MicrosoftUEFICA2011Cert, WindowsProductionPCA2011Cert},
+			dbx:       [][]byte{RevokedCanonicalBootholeCert, RevokedCiscoCert, RevokedDebianBootholeCert},
+			dbxHashes: 183,
+		},
+		{
+			name:      "GlinuxNoSecureBootLaptop",
+			log:       GlinuxNoSecureBootLaptop,
+			kek:       [][]byte{MicrosoftKEKCA2011Cert},
+			db:        [][]byte{MicrosoftUEFICA2011Cert, WindowsProductionPCA2011Cert},
+			dbxHashes: 77,
+		},
+		{
+			// The SecureBoot variable is empty.
+			name:      "ArchLinuxWorkstation",
+			log:       ArchLinuxWorkstation,
+			kek:       [][]byte{MicrosoftKEKCA2011Cert},
+			db:        [][]byte{MicrosoftUEFICA2011Cert, WindowsProductionPCA2011Cert},
+			dbxHashes: 77,
+		},
+	}
+	for _, test := range tests {
+		for _, bank := range test.log.Banks {
+			t.Run(fmt.Sprintf("%s-%v", test.name, bank.GetHash()), func(t *testing.T) {
+				state, err := ParseMachineState(test.log.RawLog, bank)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sb := state.GetSecureBoot()
+				if sb.GetEnabled() != test.enabled {
+					t.Errorf("got Secure Boot enabled %v, want %v", sb.GetEnabled(), test.enabled)
+				}
+				checkContainsCerts(t, "PK", sb.GetPk().GetCerts(), test.pk)
+				checkContainsCerts(t, "KEK", sb.GetKek().GetCerts(), test.kek)
+				checkContainsCerts(t, "db", sb.GetDb().GetCerts(), test.db)
+				checkContainsCerts(t, "dbx", sb.GetDbx().GetCerts(), test.dbx)
+				checkContainsCerts(t, "authority", sb.GetAuthority().GetCerts(), test.authority)
+				if len(sb.GetDbx().GetHashes()) != test.dbxHashes {
+					t.Errorf("got %d dbx hashes, want %d", len(sb.GetDbx().GetHashes()), test.dbxHashes)
+				}
+				for _, hash := range sb.GetDbx().GetHashes() {
+					if len(hash) != sha256.Size {
+						t.Errorf("got dbx hash of size %d, want %d", len(hash), sha256.Size)
+					}
+				}
+				if !test.enabled && len(sb.GetAuthority().GetCerts()) != 0 {
+					t.Error("got authority certificates with Secure Boot disabled")
+				}
+			})
+		}
+	}
+}
+
+func checkContainsCerts(t *testing.T, name string, got, want [][]byte) {
+	t.Helper()
+	for _, cert := range want {
+		if !contains(got, cert) {
+			t.Errorf("%s does not contain an expected certificate", name)
+		}
+	}
+}
+
+func TestParseMachineStateNoPCR7(t *testing.T) {
+	bank := &pb.PCRs{Hash: Rhel8GCE.Banks[1].GetHash(), Pcrs: map[uint32][]byte{0: Rhel8GCE.Banks[1].GetPcrs()[0]}}
+	state, err := ParseMachineState(Rhel8GCE.RawLog, bank)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.GetSecureBoot() != nil {
+		t.Errorf("got Secure Boot state %v without PCR7, want none", state.GetSecureBoot())
+	}
+}
+
+// Creates the PCR7 event measuring an EFI variable.
+func efiVariableEvent(evtType uint32, vendor efiGUID, name string, data []byte) *attestpb.Event {
+	var b bytes.Buffer
+	b.Write(vendor[:])
+	nameUTF16 := utf16.Encode([]rune(name))
+	binary.Write(&b, binary.LittleEndian, uint64(len(nameUTF16)))
+	binary.Write(&b, binary.LittleEndian, uint64(len(data)))
+	binary.Write(&b, binary.LittleEndian, nameUTF16)
+	b.Write(data)
+	digest := sha256.Sum256(b.Bytes())
+	return &attestpb.Event{PcrIndex: 7, UntrustedType: evtType, Data: b.Bytes(), Digest: digest[:], DigestVerified: true}
+}
+
+// Creates an EFI_SIGNATURE_LIST with the given signatures.
+func efiSignatureList(sigType efiGUID, sigs ...[]byte) []byte {
+	var b bytes.Buffer
+	b.Write(sigType[:])
+	binary.Write(&b, binary.LittleEndian, uint32(28+len(sigs)*(16+len(sigs[0]))))
+	binary.Write(&b, binary.LittleEndian, uint32(0))
+	binary.Write(&b, binary.LittleEndian, uint32(16+len(sigs[0])))
+	for _, sig := range sigs {
+		b.Write(make([]byte, efiSignatureOwnerSize))
+		b.Write(sig)
+	}
+	return b.Bytes()
+}
+
+func separatorEvent() *attestpb.Event {
+	digest := sha256.Sum256([]byte{0, 0, 0, 0})
+	return &attestpb.Event{PcrIndex: 7, UntrustedType: Separator, Data: []byte{0, 0, 0, 0}, Digest: digest[:], DigestVerified: true}
+}
+
+func TestGetSecureBootState(t *testing.T) {
+	hash1 := sha256.Sum256([]byte("revoked 1"))
+	hash2 := sha256.Sum256([]byte("revoked 2"))
+	dbx := append(efiSignatureList(efiCertSHA256, hash1[:], hash2[:]), efiSignatureList(efiCertX509, RevokedCiscoCert)...)
+	events := []*attestpb.Event{
+		efiVariableEvent(EFIVariableDriverConfig, efiGlobalVariable, "SecureBoot", []byte{1}),
+		efiVariableEvent(EFIVariableDriverConfig, efiGlobalVariable, "PK", efiSignatureList(efiCertX509, GceDefaultPKCert)),
+		efiVariableEvent(EFIVariableDriverConfig, efiGlobalVariable, "KEK", efiSignatureList(efiCertX509, MicrosoftKEKCA2011Cert)),
+		efiVariableEvent(EFIVariableDriverConfig, efiImageSecurityDatabase, "db", efiSignatureList(efiCertX509, MicrosoftUEFICA2011Cert)),
+		efiVariableEvent(EFIVariableDriverConfig, efiImageSecurityDatabase, "dbx", dbx),
+		separatorEvent(),
+		efiVariableEvent(EFIVariableAuthority, efiImageSecurityDatabase, "db",
+			append(make([]byte, efiSignatureOwnerSize), MicrosoftUEFICA2011Cert...)),
+		efiVariableEvent(EFIVariableAuthority, efiImageSecurityDatabase, "db",
+			append(make([]byte, efiSignatureOwnerSize), hash1[:]...)),
+		efiVariableEvent(EFIVariableAuthority, newEFIGUID(0x605dab50, 0xe046, 0x4300, [8]byte{0xab, 0xb6, 0x3d, 0xd8, 0x10, 0xdd, 0x8b, 0x23}),
+			"SbatLevel", []byte("sbat,1,2021030218\n")),
+	}
+	state, err := getSecureBootState(crypto.SHA256, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.GetEnabled() {
+		t.Error("expected Secure Boot to be enabled")
+	}
+	checkContainsCerts(t, "PK", state.GetPk().GetCerts(), [][]byte{GceDefaultPKCert})
+	checkContainsCerts(t, "KEK", state.GetKek().GetCerts(), [][]byte{MicrosoftKEKCA2011Cert})
+	checkContainsCerts(t, "db", state.GetDb().GetCerts(), [][]byte{MicrosoftUEFICA2011Cert})
+	checkContainsCerts(t, "dbx", state.GetDbx().GetCerts(), [][]byte{RevokedCiscoCert})
+	if !contains(state.GetDbx().GetHashes(), hash1[:]) || !contains(state.GetDbx().GetHashes(), hash2[:]) {
+		t.Errorf("got dbx hashes %x, want %x and %x", state.GetDbx().GetHashes(), hash1, hash2)
+	}
+	if len(state.GetAuthority().GetCerts()) != 1 || len(state.GetAuthority().GetHashes()) != 1 {
+		t.Errorf("got authority %v, want 1 certificate and 1 hash", state.GetAuthority())
+	}
+
+	unverified := efiVariableEvent(EFIVariableDriverConfig, efiGlobalVariable, "SecureBoot", []byte{0})
+	unverified.DigestVerified = false
+	debugger := &attestpb.Event{PcrIndex: 7, UntrustedType: EFIAction, Data: efiDebugMode}
+	truncated := efiVariableEvent(EFIVariableDriverConfig, efiImageSecurityDatabase, "db", efiSignatureList(efiCertX509, MicrosoftUEFICA2011Cert))
+	truncated.Data = truncated.Data[:len(truncated.Data)-1]
+	invalid := map[string][]*attestpb.Event{
+		"Duplicate":       {events[0], events[0]},
+		"AfterSeparator":  {separatorEvent(), events[0]},
+		"Unverified":      {unverified},
+		"Debugger":        {events[0], debugger},
+		"SecureBootValue": {efiVariableEvent(EFIVariableDriverConfig, efiGlobalVariable, "SecureBoot", []byte{2})},
+		"Truncated":       {truncated},
+		"BadCert":         {efiVariableEvent(EFIVariableDriverConfig, efiGlobalVariable, "PK", efiSignatureList(efiCertX509, []byte("not a certificate")))},
+	}
+	for name, events := range invalid {
+		if _, err := getSecureBootState(crypto.SHA256, events); err == nil {
+			t.Errorf("%s: expected parsing the Secure Boot state to fail", name)
+		}
+	}
+}
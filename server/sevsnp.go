@@ -0,0 +1,219 @@
+package server
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+// Offsets of the fields of the ATTESTATION_REPORT structure, from the SEV
+// Secure Nested Paging Firmware ABI Specification.
+const (
+	snpReportSize          = 0x4a0
+	snpGuestSVNOffset      = 0x04
+	snpPolicyOffset        = 0x08
+	snpVMPLOffset          = 0x30
+	snpSignatureAlgoOffset = 0x34
+	snpReportDataOffset    = 0x50
+	snpMeasurementOffset   = 0x90
+	snpHostDataOffset      = 0xc0
+	snpIDKeyDigestOffset   = 0xe0
+	snpReportedTCBOffset   = 0x180
+	snpChipIDOffset        = 0x1a0
+	// The report is signed up to the signature.
+	snpSignatureOffset = 0x2a0
+	// The R and S components of the signature are little-endian, and zero
+	// extended to 72 bytes.
+	snpSignatureComponentSize = 72
+	// SIGNATURE_ALGO for ECDSA P-384 with SHA-384.
+	snpSignatureAlgoECDSAP384 = 1
+	// The guest policy bit allowing the hypervisor to debug the guest.
+	snpPolicyDebug = 1 << 19
+)
+
+// Extensions of the VCEK certificates, from the AMD Versioned Chip Endorsement
+// Key (VCEK) Certificate and KDS Interface Specification.
+var (
+	oidBootloaderSPL = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 3704, 1, 3, 1}
+	oidTeeSPL        = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 3704, 1, 3, 2}
+	oidSnpSPL        = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 3704, 1, 3, 3}
+	oidMicrocodeSPL  = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 3704, 1, 3, 8}
+	oidHardwareID    = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 3704, 1, 4}
+)
+
+// The byte of the TCB_VERSION holding the security patch level (SPL) of each
+// component.
+var tcbComponents = []struct {
+	oid   asn1.ObjectIdentifier
+	name  string
+	index int
+}{
+	{oidBootloaderSPL, "bootloader", 0},
+	{oidTeeSPL, "TEE", 1},
+	{oidSnpSPL, "SNP", 6},
+	{oidMicrocodeSPL, "microcode", 7},
+}
+
+// SevSnpOpts configures the verification of AMD SEV-SNP attestation reports.
+type SevSnpOpts struct {
+	// The AMD Root Keys (ARKs) trusted to certify the AMD SEV Keys (ASKs),
+	// such as the self-signed ARK of the processor family's certificate chain
+	// from the AMD Key Distribution Service.
+	TrustedRoots []*x509.Certificate
+	// ASK certificates, used if the SevSnpAttestation does not contain the
+	// ASK certificate.
+	Intermediates []*x509.Certificate
+	// If true, reports from guests whose policy allows the hypervisor to debug
+	// them (and so to read their memory) are accepted.
+	AllowDebug bool
+}
+
+// VerifySevSnpAttestation verifies that the SEV-SNP attestation report is
+// signed by a VCEK certified by one of the TrustedRoots, that the VCEK
+// certificate is for the chip and TCB version of the report, and that the
+// report contains the expected REPORT_DATA (such as client.TEEReportData).
+// It returns the state of the guest from the report.
+func VerifySevSnpAttestation(attestation *attestpb.SevSnpAttestation, reportData []byte, opts SevSnpOpts) (*attestpb.SevSnpState, error) {
+	if len(opts.TrustedRoots) == 0 {
+		return nil, errors.New("no trusted AMD root certificates provided")
+	}
+	report := attestation.GetReport()
+	if len(report) != snpReportSize {
+		return nil, fmt.Errorf("got a report of %d bytes, expected %d", len(report), snpReportSize)
+	}
+	vcek, err := verifyVCEKCert(attestation, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = verifySevSnpSignature(report, vcek); err != nil {
+		return nil, err
+	}
+
+	state := &attestpb.SevSnpState{
+		Measurement: report[snpMeasurementOffset:snpHostDataOffset],
+		Policy:      binary.LittleEndian.Uint64(report[snpPolicyOffset:]),
+		Vmpl:        binary.LittleEndian.Uint32(report[snpVMPLOffset:]),
+		HostData:    report[snpHostDataOffset:snpIDKeyDigestOffset],
+		ReportedTcb: binary.LittleEndian.Uint64(report[snpReportedTCBOffset:]),
+		ChipId:      report[snpChipIDOffset : snpChipIDOffset+64],
+		IdKeyDigest: report[snpIDKeyDigestOffset : snpIDKeyDigestOffset+48],
+		GuestSvn:    binary.LittleEndian.Uint32(report[snpGuestSVNOffset:]),
+	}
+	if err = checkVCEKExtensions(vcek, state); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(report[snpReportDataOffset:snpMeasurementOffset], reportData) {
+		return nil, errors.New("report data does not match")
+	}
+	if state.GetPolicy()&snpPolicyDebug != 0 && !opts.AllowDebug {
+		return nil, errors.New("the guest policy allows debugging")
+	}
+	return state, nil
+}
+
+func verifyVCEKCert(attestation *attestpb.SevSnpAttestation, opts SevSnpOpts) (*x509.Certificate, error) {
+	if len(attestation.GetVcekCert()) == 0 {
+		return nil, errors.New("no VCEK certificate")
+	}
+	vcek, err := x509.ParseCertificate(attestation.GetVcekCert())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse VCEK certificate: %w", err)
+	}
+	roots := x509.NewCertPool()
+	for _, root := range opts.TrustedRoots {
+		roots.AddCert(root)
+	}
+	intermediates := x509.NewCertPool()
+	for _, ask := range opts.Intermediates {
+		intermediates.AddCert(ask)
+	}
+	if der := attestation.GetAskCert(); len(der) > 0 {
+		ask, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ASK certificate: %w", err)
+		}
+		intermediates.AddCert(ask)
+	}
+	if _, err = vcek.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to verify VCEK certificate: %w", err)
+	}
+	return vcek, nil
+}
+
+func verifySevSnpSignature(report []byte, vcek *x509.Certificate) error {
+	if algo := binary.LittleEndian.Uint32(report[snpSignatureAlgoOffset:]); algo != snpSignatureAlgoECDSAP384 {
+		return fmt.Errorf("unsupported report signature algorithm %d", algo)
+	}
+	pub, ok := vcek.PublicKey.(*ecdsa.PublicKey)
+	if !ok || pub.Curve != elliptic.P384() {
+		return errors.New("VCEK is not an ECDSA P-384 key")
+	}
+	signature := report[snpSignatureOffset:]
+	r := littleEndianInt(signature[:snpSignatureComponentSize])
+	s := littleEndianInt(signature[snpSignatureComponentSize : 2*snpSignatureComponentSize])
+	digest := sha512.Sum384(report[:snpSignatureOffset])
+	if !ecdsa.Verify(pub, digest[:], r, s) {
+		return errors.New("report signature does not match the VCEK")
+	}
+	return nil
+}
+
+func littleEndianInt(data []byte) *big.Int {
+	bigEndian := make([]byte, len(data))
+	for i, b := range data {
+		bigEndian[len(data)-1-i] = b
+	}
+	return new(big.Int).SetBytes(bigEndian)
+}
+
+// checkVCEKExtensions checks that the VCEK was derived for the chip and the
+// reported TCB version of the report.
+func checkVCEKExtensions(vcek *x509.Certificate, state *attestpb.SevSnpState) error {
+	extensions := map[string][]byte{}
+	for _, ext := range vcek.Extensions {
+		extensions[ext.Id.String()] = ext.Value
+	}
+
+	hwid, ok := extensions[oidHardwareID.String()]
+	if !ok {
+		return errors.New("VCEK certificate has no hardware ID")
+	}
+	// Some certificates contain the raw hardware ID, instead of an OCTET STRING.
+	var octets []byte
+	if rest, err := asn1.Unmarshal(hwid, &octets); err == nil && len(rest) == 0 {
+		hwid = octets
+	}
+	if !bytes.Equal(hwid, state.GetChipId()) {
+		return errors.New("VCEK certificate is for a different chip")
+	}
+
+	var tcb [8]byte
+	binary.LittleEndian.PutUint64(tcb[:], state.GetReportedTcb())
+	for _, component := range tcbComponents {
+		value, ok := extensions[component.oid.String()]
+		if !ok {
+			return fmt.Errorf("VCEK certificate has no %s SPL", component.name)
+		}
+		var spl int
+		if _, err := asn1.Unmarshal(value, &spl); err != nil {
+			return fmt.Errorf("invalid %s SPL in VCEK certificate: %w", component.name, err)
+		}
+		if spl != int(tcb[component.index]) {
+			return fmt.Errorf("VCEK certificate is for %s SPL %d, but the report has %d", component.name, spl, tcb[component.index])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package server
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+// A reported TCB version: bootloader 3, TEE 0, SNP 8 and microcode 115.
+const testReportedTCB uint64 = 0x7308000000000003
+
+type sevSnpChain struct {
+	ark, ask, vcek *x509.Certificate
+	vcekKey        *ecdsa.PrivateKey
+}
+
+func createTestCert(t *testing.T, template, parent *x509.Certificate, pub crypto.PublicKey, priv crypto.Signer) *x509.Certificate {
+	t.Helper()
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent = template
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func mustMarshal(t *testing.T, value interface{}) []byte {
+	t.Helper()
+	der, err := asn1.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// newSevSnpChain creates an AMD-like ARK, ASK and VCEK, for the chip ID and
+// TCB version.
+func newSevSnpChain(t *testing.T, chipID []byte, tcb uint64) *sevSnpChain {
+	t.Helper()
+	arkKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	askKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vcekKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ca := func(serial int64, name string) *x509.Certificate {
+		return &x509.Certificate{
+			SerialNumber:          big.NewInt(serial),
+			Subject:               pkix.Name{CommonName: name},
+			SignatureAlgorithm:    x509.SHA384WithRSAPSS,
+			KeyUsage:              x509.KeyUsageCertSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+		}
+	}
+	chain := &sevSnpChain{vcekKey: vcekKey}
+	chain.ark = createTestCert(t, ca(1, "ARK-Test"), nil, arkKey.Public(), arkKey)
+	chain.ask = createTestCert(t, ca(2, "SEV-Test"), chain.ark, askKey.Public(), arkKey)
+
+	var tcbBytes [8]byte
+	binary.LittleEndian.PutUint64(tcbBytes[:], tcb)
+	extensions := []pkix.Extension{{Id: oidHardwareID, Value: mustMarshal(t, chipID)}}
+	for _, component := range tcbComponents {
+		extensions = append(extensions, pkix.Extension{
+			Id:    component.oid,
+			Value: mustMarshal(t, int(tcbBytes[component.index])),
+		})
+	}
+	chain.vcek = createTestCert(t, &x509.Certificate{
+		SerialNumber:       big.NewInt(3),
+		Subject:            pkix.Name{CommonName: "SEV-VCEK"},
+		SignatureAlgorithm: x509.SHA384WithRSAPSS,
+		ExtraExtensions:    extensions,
+	}, chain.ask, vcekKey.Public(), askKey)
+	return chain
+}
+
+func littleEndian72(i *big.Int) []byte {
+	bigEndian := i.FillBytes(make([]byte, snpSignatureComponentSize))
+	out := make([]byte, len(bigEndian))
+	for j, b := range bigEndian {
+		out[len(bigEndian)-1-j] = b
+	}
+	return out
+}
+
+// signSevSnpReport fills in and signs an ATTESTATION_REPORT.
+func signSevSnpReport(t *testing.T, key *ecdsa.PrivateKey, reportData, chipID []byte, tcb, policy uint64) []byte {
+	t.Helper()
+	report := make([]byte, snpReportSize)
+	binary.LittleEndian.PutUint32(report[0:], 2) // VERSION
+	binary.LittleEndian.PutUint64(report[snpPolicyOffset:], policy)
+	binary.LittleEndian.PutUint32(report[snpSignatureAlgoOffset:], snpSignatureAlgoECDSAP384)
+	copy(report[snpReportDataOffset:], reportData)
+	copy(report[snpMeasurementOffset:snpHostDataOffset], bytes.Repeat([]byte{0x11}, 48))
+	binary.LittleEndian.PutUint64(report[snpReportedTCBOffset:], tcb)
+	copy(report[snpChipIDOffset:], chipID)
+
+	digest := sha512.Sum384(report[:snpSignatureOffset])
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(report[snpSignatureOffset:], littleEndian72(r))
+	copy(report[snpSignatureOffset+snpSignatureComponentSize:], littleEndian72(s))
+	return report
+}
+
+func TestVerifySevSnpAttestation(t *testing.T) {
+	chipID := bytes.Repeat([]byte{0xc1}, 64)
+	chain := newSevSnpChain(t, chipID, testReportedTCB)
+	reportData := client.TEEReportData([]byte("nonce"), []byte("AK"))
+	opts := SevSnpOpts{TrustedRoots: []*x509.Certificate{chain.ark}}
+	attestation := &attestpb.SevSnpAttestation{
+		Report:   signSevSnpReport(t, chain.vcekKey, reportData, chipID, testReportedTCB, 0x30000),
+		VcekCert: chain.vcek.Raw,
+		AskCert:  chain.ask.Raw,
+	}
+
+	state, err := VerifySevSnpAttestation(attestation, reportData, opts)
+	if err != nil {
+		t.Fatalf("failed to verify SEV-SNP attestation: %v", err)
+	}
+	if !bytes.Equal(state.GetChipId(), chipID) || state.GetReportedTcb() != testReportedTCB || state.GetPolicy() != 0x30000 {
+		t.Errorf("got SEV-SNP state %v", state)
+	}
+	if !bytes.Equal(state.GetMeasurement(), bytes.Repeat([]byte{0x11}, 48)) {
+		t.Errorf("got measurement %x", state.GetMeasurement())
+	}
+
+	// The ASK can also be provided by the verifier.
+	withoutASK := &attestpb.SevSnpAttestation{Report: attestation.Report, VcekCert: attestation.VcekCert}
+	askOpts := SevSnpOpts{TrustedRoots: opts.TrustedRoots, Intermediates: []*x509.Certificate{chain.ask}}
+	if _, err = VerifySevSnpAttestation(withoutASK, reportData, askOpts); err != nil {
+		t.Errorf("failed to verify SEV-SNP attestation with a provided ASK: %v", err)
+	}
+	debug := &attestpb.SevSnpAttestation{
+		Report:   signSevSnpReport(t, chain.vcekKey, reportData, chipID, testReportedTCB, 0x30000|snpPolicyDebug),
+		VcekCert: chain.vcek.Raw,
+		AskCert:  chain.ask.Raw,
+	}
+	if _, err = VerifySevSnpAttestation(debug, reportData, SevSnpOpts{TrustedRoots: opts.TrustedRoots, AllowDebug: true}); err != nil {
+		t.Errorf("failed to verify SEV-SNP attestation of a debug guest: %v", err)
+	}
+
+	tampered := append([]byte{}, attestation.Report...)
+	tampered[snpMeasurementOffset] ^= 1
+	otherChip := newSevSnpChain(t, bytes.Repeat([]byte{0xc2}, 64), testReportedTCB)
+	otherTCB := newSevSnpChain(t, chipID, testReportedTCB+1)
+	invalid := []struct {
+		name        string
+		attestation *attestpb.SevSnpAttestation
+		reportData  []byte
+		opts        SevSnpOpts
+	}{
+		{"WrongReportData", attestation, client.TEEReportData([]byte("other nonce"), []byte("AK")), opts},
+		{"Tampered", &attestpb.SevSnpAttestation{Report: tampered, VcekCert: chain.vcek.Raw, AskCert: chain.ask.Raw}, reportData, opts},
+		{"NoTrustedRoots", attestation, reportData, SevSnpOpts{}},
+		{"UntrustedRoot", attestation, reportData, SevSnpOpts{TrustedRoots: []*x509.Certificate{otherChip.ark}}},
+		{"NoASK", withoutASK, reportData, opts},
+		{"NoVCEK", &attestpb.SevSnpAttestation{Report: attestation.Report, AskCert: chain.ask.Raw}, reportData, opts},
+		{"Truncated", &attestpb.SevSnpAttestation{Report: attestation.Report[:snpSignatureOffset], VcekCert: chain.vcek.Raw, AskCert: chain.ask.Raw}, reportData, opts},
+		{"Debug", debug, reportData, opts},
+		{"OtherChip", &attestpb.SevSnpAttestation{
+			Report:   signSevSnpReport(t, otherChip.vcekKey, reportData, chipID, testReportedTCB, 0),
+			VcekCert: otherChip.vcek.Raw,
+			AskCert:  otherChip.ask.Raw,
+		}, reportData, SevSnpOpts{TrustedRoots: []*x509.Certificate{otherChip.ark}}},
+		{"OtherTCB", &attestpb.SevSnpAttestation{
+			Report:   signSevSnpReport(t, otherTCB.vcekKey, reportData, chipID, testReportedTCB, 0),
+			VcekCert: otherTCB.vcek.Raw,
+			AskCert:  otherTCB.ask.Raw,
+		}, reportData, SevSnpOpts{TrustedRoots: []*x509.Certificate{otherTCB.ark}}},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := VerifySevSnpAttestation(tc.attestation, tc.reportData, tc.opts); err == nil {
+				t.Error("expected SEV-SNP verification to fail")
+			}
+		})
+	}
+}
+
+func TestVerifyAttestationSevSnp(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ak, err := client.AttestationKeyRSA(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer ak.Close()
+	nonce := []byte("super secret nonce")
+	attestation, err := ak.Attest(nonce, nil)
+	if err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+
+	chipID := bytes.Repeat([]byte{0xc1}, 64)
+	chain := newSevSnpChain(t, chipID, testReportedTCB)
+	opts := VerifyOpts{
+		Nonce:      nonce,
+		TrustedAKs: []crypto.PublicKey{ak.PublicKey()},
+		SevSnp:     SevSnpOpts{TrustedRoots: []*x509.Certificate{chain.ark}},
+	}
+	reportData := client.TEEReportData(nonce, attestation.GetAkPub())
+	attestation.SevSnpAttestation = &attestpb.SevSnpAttestation{
+		Report:   signSevSnpReport(t, chain.vcekKey, reportData, chipID, testReportedTCB, 0),
+		VcekCert: chain.vcek.Raw,
+		AskCert:  chain.ask.Raw,
+	}
+	state, err := VerifyAttestation(attestation, opts)
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	if !bytes.Equal(state.GetSevSnp().GetChipId(), chipID) {
+		t.Errorf("got SEV-SNP state %v", state.GetSevSnp())
+	}
+
+	// A report bound to another AK cannot be combined with the quotes.
+	otherAK, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer otherAK.Close()
+	otherAttestation, err := otherAK.Attest(nonce, nil)
+	if err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+	otherAttestation.SevSnpAttestation = attestation.SevSnpAttestation
+	opts.TrustedAKs = []crypto.PublicKey{otherAK.PublicKey()}
+	if _, err = VerifyAttestation(otherAttestation, opts); err == nil {
+		t.Error("expected verification to fail with the SEV-SNP report of another AK")
+	}
+}
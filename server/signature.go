@@ -0,0 +1,134 @@
+package server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
+	"github.com/google/go-tpm/tpm2"
+)
+
+// KeySignatureOpts allows for customizing the checks of VerifyKeySignature.
+type KeySignatureOpts struct {
+	// RequireRestricted requires the key to be a restricted signing key (such
+	// as an AK), which can only sign data hashed by the TPM that does not start
+	// with TPM_GENERATED_VALUE, so its signatures can never be mistaken for
+	// attestations.
+	RequireRestricted bool
+}
+
+// VerifyKeySignature verifies sig, a signature of data as generated by
+// client.Key.SignData, against the TPM key with the encoded public area (a
+// TPMT_PUBLIC) and the expected Name. It checks that:
+//   - the public area matches the Name
+//   - the key is a signing key generated by a TPM, which can never leave that
+//     TPM (FlagFixedTPM and FlagSensitiveDataOrigin)
+//   - if opts.RequireRestricted is set, the key is restricted
+//   - the signature was generated by the key, with the key's signing scheme
+//
+// The key must have an RSASSA, RSAPSS, ECDSA, ECSchnorr or SM2 signing scheme.
+// The public key of the key is returned. Note that this only proves the
+// signature came from a TPM if the caller already trusts the Name (for
+// example, from a KeyCertification verified with VerifyKeyCertification).
+func VerifyKeySignature(publicArea []byte, name tpm2.Name, data, sig []byte, opts KeySignatureOpts) (crypto.PublicKey, error) {
+	pub, err := tpm2.DecodePublic(publicArea)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode public area: %w", err)
+	}
+	matches, err := name.MatchesPublic(pub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute the name of the public area: %w", err)
+	}
+	if !matches {
+		return nil, fmt.Errorf("public area does not match the expected name")
+	}
+
+	required := tpm2.FlagSign | tpm2.FlagFixedTPM | tpm2.FlagSensitiveDataOrigin
+	if opts.RequireRestricted {
+		required |= tpm2.FlagRestricted
+	}
+	if pub.Attributes&required != required {
+		return nil, fmt.Errorf("key attributes 0x%x are missing the required attributes 0x%x", uint32(pub.Attributes), uint32(required&^pub.Attributes))
+	}
+
+	pubKey, err := notinternal.PublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the public key: %w", err)
+	}
+	var scheme *tpm2.SigScheme
+	switch pub.Type {
+	case tpm2.AlgRSA:
+		scheme = pub.RSAParameters.Sign
+	case tpm2.AlgECC:
+		scheme = pub.ECCParameters.Sign
+	default:
+		return nil, fmt.Errorf("unsupported key type: %v", pub.Type)
+	}
+	if scheme == nil {
+		return nil, fmt.Errorf("key has no signing scheme")
+	}
+	if err := verifySchemeSignature(pubKey, scheme, data, sig); err != nil {
+		return nil, err
+	}
+	return pubKey, nil
+}
+
+// verifySchemeSignature verifies a signature of data (in the format returned
+// by client.Key.SignData) generated with the scheme.
+func verifySchemeSignature(pubKey crypto.PublicKey, scheme *tpm2.SigScheme, data, sig []byte) error {
+	if scheme.Alg == client.AlgSM2 {
+		ecdsaPub, ok := pubKey.(*ecdsa.PublicKey)
+		if !ok || ecdsaPub.Curve != notinternal.SM2P256() {
+			return fmt.Errorf("SM2 signatures require a key on the SM2 curve")
+		}
+		var rs struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(sig, &rs); err != nil {
+			return fmt.Errorf("failed to decode SM2 signature: %w", err)
+		}
+		if !notinternal.VerifySM2(ecdsaPub, notinternal.SM2Digest(ecdsaPub, "", data), rs.R, rs.S) {
+			return fmt.Errorf("SM2 signature verification failed")
+		}
+		return nil
+	}
+
+	hash, err := scheme.Hash.Hash()
+	if err != nil {
+		return fmt.Errorf("unsupported signature hash algorithm: %w", err)
+	}
+	hasher := hash.New()
+	hasher.Write(data)
+	digest := hasher.Sum(nil)
+
+	rsaPub, isRSA := pubKey.(*rsa.PublicKey)
+	ecdsaPub, isECDSA := pubKey.(*ecdsa.PublicKey)
+	switch {
+	case scheme.Alg == tpm2.AlgRSASSA && isRSA:
+		if err := rsa.VerifyPKCS1v15(rsaPub, hash, digest, sig); err != nil {
+			return fmt.Errorf("RSASSA signature verification failed: %w", err)
+		}
+	case scheme.Alg == tpm2.AlgRSAPSS && isRSA:
+		if err := rsa.VerifyPSS(rsaPub, hash, digest, sig, nil); err != nil {
+			return fmt.Errorf("RSAPSS signature verification failed: %w", err)
+		}
+	case scheme.Alg == tpm2.AlgECDSA && isECDSA:
+		if !ecdsa.VerifyASN1(ecdsaPub, digest, sig) {
+			return fmt.Errorf("ECDSA signature verification failed")
+		}
+	case scheme.Alg == client.AlgECSchnorr && isECDSA:
+		var rs struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(sig, &rs); err != nil {
+			return fmt.Errorf("failed to decode ECSchnorr signature: %w", err)
+		}
+		if !notinternal.VerifyECSchnorr(ecdsaPub, hash, digest, rs.R, rs.S) {
+			return fmt.Errorf("ECSchnorr signature verification failed")
+		}
+	default:
+		return fmt.Errorf("unsupported signing scheme for %T: %v", pubKey, scheme.Alg)
+	}
+	return nil
+}
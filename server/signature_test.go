@@ -0,0 +1,138 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	"github.com/google/go-tpm/tpm2"
+)
+
+func unrestrictedTemplate(template tpm2.Public) tpm2.Public {
+	template.Attributes &^= tpm2.FlagRestricted
+	return template
+}
+
+func TestVerifyKeySignature(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	pssTemplate := unrestrictedTemplate(client.AKTemplateRSA())
+	pssTemplate.RSAParameters.Sign.Alg = tpm2.AlgRSAPSS
+	p384Template, err := client.AKTemplateECCWithCurve(tpm2.CurveNISTP384)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schnorrTemplate := client.AKTemplateECC()
+	schnorrTemplate.ECCParameters.Sign.Alg = client.AlgECSchnorr
+	tests := []struct {
+		name     string
+		template tpm2.Public
+		opts     KeySignatureOpts
+	}{
+		{"AK-RSA", client.AKTemplateRSA(), KeySignatureOpts{RequireRestricted: true}},
+		{"AK-ECC", client.AKTemplateECC(), KeySignatureOpts{RequireRestricted: true}},
+		{"RSASSA", unrestrictedTemplate(client.AKTemplateRSA()), KeySignatureOpts{}},
+		{"RSAPSS", pssTemplate, KeySignatureOpts{}},
+		{"ECDSA-P384", unrestrictedTemplate(p384Template), KeySignatureOpts{}},
+		{"AK-ECSchnorr", schnorrTemplate, KeySignatureOpts{RequireRestricted: true}},
+		{"ECSchnorr", unrestrictedTemplate(schnorrTemplate), KeySignatureOpts{}},
+	}
+	data := []byte("data")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key, err := client.NewKey(rwc, tpm2.HandleOwner, test.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer key.Close()
+			sig, err := key.SignData(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			publicArea, err := key.PublicArea().Encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pub, err := VerifyKeySignature(publicArea, key.Name(), data, sig, test.opts)
+			if err != nil {
+				t.Fatalf("failed to verify signature: %v", err)
+			}
+			if !reflect.DeepEqual(pub, key.PublicKey()) {
+				t.Error("returned public key does not match the key")
+			}
+			if _, err := VerifyKeySignature(publicArea, key.Name(), []byte("other data"), sig, test.opts); err == nil {
+				t.Error("expected verifying a signature of other data to fail")
+			}
+		})
+	}
+}
+
+func TestVerifyKeySignatureFails(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	data := []byte("data")
+	newSignedKey := func(t *testing.T, template tpm2.Public) (*client.Key, []byte, []byte) {
+		key, err := client.NewKey(rwc, tpm2.HandleOwner, template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := key.SignData(data)
+		if err != nil {
+			key.Close()
+			t.Fatal(err)
+		}
+		publicArea, err := key.PublicArea().Encode()
+		if err != nil {
+			key.Close()
+			t.Fatal(err)
+		}
+		return key, publicArea, sig
+	}
+
+	t.Run("OtherName", func(t *testing.T) {
+		key, publicArea, sig := newSignedKey(t, client.AKTemplateECC())
+		defer key.Close()
+		other, err := client.AttestationKeyRSA(rwc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer other.Close()
+		if _, err := VerifyKeySignature(publicArea, other.Name(), data, sig, KeySignatureOpts{}); err == nil {
+			t.Error("expected verification with another key's name to fail")
+		}
+	})
+	t.Run("Unrestricted", func(t *testing.T) {
+		key, publicArea, sig := newSignedKey(t, unrestrictedTemplate(client.AKTemplateECC()))
+		defer key.Close()
+		if _, err := VerifyKeySignature(publicArea, key.Name(), data, sig, KeySignatureOpts{RequireRestricted: true}); err == nil {
+			t.Error("expected verification of an unrestricted key to fail with RequireRestricted")
+		}
+	})
+	t.Run("Exportable", func(t *testing.T) {
+		template := unrestrictedTemplate(client.AKTemplateECC())
+		template.Attributes &^= tpm2.FlagFixedTPM | tpm2.FlagFixedParent
+		key, publicArea, sig := newSignedKey(t, template)
+		defer key.Close()
+		if _, err := VerifyKeySignature(publicArea, key.Name(), data, sig, KeySignatureOpts{}); err == nil {
+			t.Error("expected verification of an exportable key to fail")
+		}
+	})
+	t.Run("OtherKeySignature", func(t *testing.T) {
+		key, publicArea, _ := newSignedKey(t, client.AKTemplateECC())
+		defer key.Close()
+		other, _, otherSig := newSignedKey(t, unrestrictedTemplate(client.AKTemplateECC()))
+		defer other.Close()
+		if _, err := VerifyKeySignature(publicArea, key.Name(), data, otherSig, KeySignatureOpts{}); err == nil {
+			t.Error("expected verification of another key's signature to fail")
+		}
+	})
+	t.Run("BadPublicArea", func(t *testing.T) {
+		if _, err := VerifyKeySignature([]byte("not a public area"), tpm2.Name{}, data, nil, KeySignatureOpts{}); err == nil {
+			t.Error("expected decoding an invalid public area to fail")
+		}
+	})
+}
@@ -0,0 +1,371 @@
+package server
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+)
+
+// The layout of a TDX quote (version 4), from the Intel TDX DCAP Quoting
+// Library API documentation.
+const (
+	tdxQuoteVersion              = 4
+	tdxAttestationKeyECDSAP256   = 2
+	tdxTeeType                   = 0x81
+	tdxHeaderSize                = 48
+	tdxBodySize                  = 584
+	tdxECDSAP256SignatureSize    = 64
+	tdxECDSAP256PublicKeySize    = 64
+	tdxPCKCertChainType          = 5
+	tdxQEReportCertificationType = 6
+	// The QE report is an SGX report body.
+	sgxReportBodySize = 384
+)
+
+// Offsets of the fields of the TD quote body.
+const (
+	tdxTeeTCBSVNOffset      = 0
+	tdxMRSeamOffset         = 16
+	tdxMRSignerSeamOffset   = 64
+	tdxSeamAttributesOffset = 112
+	tdxTDAttributesOffset   = 120
+	tdxXFAMOffset           = 128
+	tdxMRTDOffset           = 136
+	tdxMRConfigIDOffset     = 184
+	tdxMROwnerOffset        = 232
+	tdxMROwnerConfigOffset  = 280
+	tdxRTMRsOffset          = 328
+	tdxReportDataOffset     = 520
+	tdxMeasurementSize      = 48
+	tdxNumRTMRs             = 4
+	// The TD attribute allowing the TD to be debugged by the host.
+	tdxAttributeDebug = 1
+)
+
+// Offsets of the fields of an SGX report body.
+const (
+	sgxMiscSelectOffset = 16
+	sgxAttributesOffset = 48
+	sgxMRSignerOffset   = 128
+	sgxISVProdIDOffset  = 256
+	sgxISVSVNOffset     = 258
+	sgxReportDataOffset = 320
+)
+
+// The SGX extensions of the PCK certificates, from the Intel SGX PCK
+// Certificate and Certificate Revocation List Profile Specification.
+var (
+	oidSGXExtensions = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1}
+	oidSGXTCB        = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 2}
+	oidSGXPCESVN     = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 2, 17}
+	oidSGXFMSPC      = asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 4}
+)
+
+// The number of SGX and TDX TCB components (SVNs).
+const tcbComponentCount = 16
+
+// TdxOpts configures the verification of Intel TDX quotes.
+type TdxOpts struct {
+	// The Intel SGX Root CA certificates, trusted to issue the PCK certificates
+	// and to sign the collateral.
+	TrustedRoots []*x509.Certificate
+	// The collateral for the platform which generated the quote (see
+	// FetchTdxCollateral).
+	Collateral *TdxCollateral
+	// The accepted TCB statuses of the platform and Quoting Enclave, from the
+	// collateral. If empty, only "UpToDate" is accepted.
+	AcceptedTCBStatuses []string
+	// If true, quotes from TDs which can be debugged by the host (and so whose
+	// memory can be read) are accepted.
+	AllowDebug bool
+}
+
+func (o TdxOpts) acceptsStatus(status string) bool {
+	if len(o.AcceptedTCBStatuses) == 0 {
+		return status == "UpToDate"
+	}
+	return containsString(o.AcceptedTCBStatuses, status)
+}
+
+// tdxQuote is a parsed TDX quote.
+type tdxQuote struct {
+	// The header and TD quote body, signed by the attestation key.
+	signed            []byte
+	body              []byte
+	signature         []byte
+	attestationKey    []byte
+	qeReport          []byte
+	qeReportSignature []byte
+	qeAuthData        []byte
+	pckChain          []*x509.Certificate
+}
+
+// quoteReader reads the fields of a quote, failing if it is too short.
+type quoteReader struct {
+	data []byte
+	err  error
+}
+
+func (r *quoteReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.data) {
+		r.err = errors.New("TDX quote is truncated")
+		return nil
+	}
+	field := r.data[:n]
+	r.data = r.data[n:]
+	return field
+}
+
+func (r *quoteReader) uint16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *quoteReader) uint32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func parseTdxQuote(quote []byte) (*tdxQuote, error) {
+	r := &quoteReader{data: quote}
+	version := r.uint16()
+	keyType := r.uint16()
+	teeType := r.uint32()
+	if r.err == nil && (version != tdxQuoteVersion || keyType != tdxAttestationKeyECDSAP256 || teeType != tdxTeeType) {
+		return nil, fmt.Errorf("unsupported quote (version %d, attestation key type %d, TEE type %#x)", version, keyType, teeType)
+	}
+	r.bytes(tdxHeaderSize - 8)
+	parsed := &tdxQuote{body: r.bytes(tdxBodySize)}
+	parsed.signed = quote[:len(quote)-len(r.data)]
+
+	signatureData := &quoteReader{data: r.bytes(int(r.uint32()))}
+	parsed.signature = signatureData.bytes(tdxECDSAP256SignatureSize)
+	parsed.attestationKey = signatureData.bytes(tdxECDSAP256PublicKeySize)
+	if certType := signatureData.uint16(); signatureData.err == nil && certType != tdxQEReportCertificationType {
+		return nil, fmt.Errorf("unsupported certification data type %d", certType)
+	}
+	qeData := &quoteReader{data: signatureData.bytes(int(signatureData.uint32()))}
+	parsed.qeReport = qeData.bytes(sgxReportBodySize)
+	parsed.qeReportSignature = qeData.bytes(tdxECDSAP256SignatureSize)
+	parsed.qeAuthData = qeData.bytes(int(qeData.uint16()))
+	if certType := qeData.uint16(); qeData.err == nil && certType != tdxPCKCertChainType {
+		return nil, fmt.Errorf("unsupported QE certification data type %d", certType)
+	}
+	pemChain := qeData.bytes(int(qeData.uint32()))
+	for _, reader := range []*quoteReader{r, signatureData, qeData} {
+		if reader.err != nil {
+			return nil, reader.err
+		}
+	}
+
+	for block, rest := pem.Decode(pemChain); block != nil; block, rest = pem.Decode(rest) {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse PCK certificate chain: %w", err)
+		}
+		parsed.pckChain = append(parsed.pckChain, cert)
+	}
+	if len(parsed.pckChain) == 0 {
+		return nil, errors.New("TDX quote has no PCK certificate")
+	}
+	return parsed, nil
+}
+
+// pckExtensions are the SGX extensions of a PCK certificate.
+type pckExtensions struct {
+	fmspc         []byte
+	componentSVNs [tcbComponentCount]int
+	pceSVN        int
+}
+
+type sgxExtension struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+func parseSGXExtensions(der []byte) ([]sgxExtension, error) {
+	var extensions []sgxExtension
+	rest, err := asn1.Unmarshal(der, &extensions)
+	if err == nil && len(rest) > 0 {
+		err = errors.New("trailing data")
+	}
+	return extensions, err
+}
+
+func parsePCKExtensions(pck *x509.Certificate) (*pckExtensions, error) {
+	var sgx []sgxExtension
+	for _, ext := range pck.Extensions {
+		if ext.Id.Equal(oidSGXExtensions) {
+			var err error
+			if sgx, err = parseSGXExtensions(ext.Value); err != nil {
+				return nil, fmt.Errorf("invalid SGX extensions in PCK certificate: %w", err)
+			}
+		}
+	}
+	parsed := &pckExtensions{}
+	foundTCB := false
+	for _, ext := range sgx {
+		switch {
+		case ext.ID.Equal(oidSGXFMSPC):
+			parsed.fmspc = ext.Value.Bytes
+		case ext.ID.Equal(oidSGXTCB):
+			components, err := parseSGXExtensions(ext.Value.FullBytes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid SGX TCB in PCK certificate: %w", err)
+			}
+			for _, component := range components {
+				var svn int
+				id := component.ID
+				if len(id) != len(oidSGXTCB)+1 || !id[:len(oidSGXTCB)].Equal(oidSGXTCB) {
+					continue
+				}
+				index := id[len(id)-1]
+				if index < 1 || index > tcbComponentCount && !id.Equal(oidSGXPCESVN) {
+					// The CPUSVN, which also contains the component SVNs.
+					continue
+				}
+				if _, err := asn1.Unmarshal(component.Value.FullBytes, &svn); err != nil {
+					return nil, fmt.Errorf("invalid SGX TCB component in PCK certificate: %w", err)
+				}
+				if id.Equal(oidSGXPCESVN) {
+					parsed.pceSVN = svn
+				} else {
+					parsed.componentSVNs[index-1] = svn
+				}
+			}
+			foundTCB = true
+		}
+	}
+	if len(parsed.fmspc) == 0 || !foundTCB {
+		return nil, errors.New("PCK certificate has no FMSPC or TCB")
+	}
+	return parsed, nil
+}
+
+func parseP256PublicKey(raw []byte) *ecdsa.PublicKey {
+	return &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(raw[:32]),
+		Y:     new(big.Int).SetBytes(raw[32:]),
+	}
+}
+
+func verifyP256Signature(pub *ecdsa.PublicKey, data, signature []byte) bool {
+	digest := sha256.Sum256(data)
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	return ecdsa.Verify(pub, digest[:], r, s)
+}
+
+// VerifyTdxQuote verifies that the TDX quote was generated by a TDX Quoting
+// Enclave (QE) on a genuine Intel platform, and that it contains the expected
+// report data (such as client.TEEReportData). It checks that:
+//   - the PCK certificate chains up to one of the TrustedRoots, and is not
+//     revoked by the CRLs of the collateral
+//   - the QE report is signed by the PCK, and binds the attestation key
+//   - the quote is signed by the attestation key
+//   - the QE matches the QE identity of the collateral
+//   - the TCB statuses of the platform (from the TCB info of the collateral)
+//     and of the QE are accepted
+//
+// It returns the state of the TD from the quote.
+func VerifyTdxQuote(quote []byte, reportData []byte, opts TdxOpts) (*attestpb.TdxState, error) {
+	if len(opts.TrustedRoots) == 0 {
+		return nil, errors.New("no trusted Intel root certificates provided")
+	}
+	if opts.Collateral == nil {
+		return nil, errors.New("no TDX collateral provided")
+	}
+	parsed, err := parseTdxQuote(quote)
+	if err != nil {
+		return nil, err
+	}
+	pck := parsed.pckChain[0]
+	if _, err = verifyIntelChain(parsed.pckChain, opts.TrustedRoots, opts.Collateral.CRLs); err != nil {
+		return nil, fmt.Errorf("failed to verify PCK certificate: %w", err)
+	}
+	pckPub, ok := pck.PublicKey.(*ecdsa.PublicKey)
+	if !ok || pckPub.Curve != elliptic.P256() {
+		return nil, errors.New("PCK is not an ECDSA P-256 key")
+	}
+	if !verifyP256Signature(pckPub, parsed.qeReport, parsed.qeReportSignature) {
+		return nil, errors.New("QE report signature does not match the PCK")
+	}
+	// The QE report data binds the attestation key (and QE authentication data).
+	binding := sha256.Sum256(append(append([]byte{}, parsed.attestationKey...), parsed.qeAuthData...))
+	qeReportData := parsed.qeReport[sgxReportDataOffset:]
+	if !bytes.Equal(qeReportData[:sha256.Size], binding[:]) || !bytes.Equal(qeReportData[sha256.Size:], make([]byte, sha256.Size)) {
+		return nil, errors.New("QE report does not bind the attestation key")
+	}
+	if !verifyP256Signature(parseP256PublicKey(parsed.attestationKey), parsed.signed, parsed.signature) {
+		return nil, errors.New("quote signature does not match the attestation key")
+	}
+
+	tcbInfo, err := opts.Collateral.verifiedTCBInfo(opts.TrustedRoots)
+	if err != nil {
+		return nil, err
+	}
+	qeIdentity, err := opts.Collateral.verifiedQEIdentity(opts.TrustedRoots)
+	if err != nil {
+		return nil, err
+	}
+	qeStatus, err := qeIdentity.status(parsed.qeReport)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.acceptsStatus(qeStatus) {
+		return nil, fmt.Errorf("QE TCB status %q is not accepted", qeStatus)
+	}
+	extensions, err := parsePCKExtensions(pck)
+	if err != nil {
+		return nil, err
+	}
+	tcbStatus, err := tcbInfo.status(extensions, parsed.body)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.acceptsStatus(tcbStatus) {
+		return nil, fmt.Errorf("platform TCB status %q is not accepted", tcbStatus)
+	}
+
+	body := parsed.body
+	state := &attestpb.TdxState{
+		MrTd:          body[tdxMRTDOffset:tdxMRConfigIDOffset],
+		MrConfigId:    body[tdxMRConfigIDOffset:tdxMROwnerOffset],
+		MrOwner:       body[tdxMROwnerOffset:tdxMROwnerConfigOffset],
+		MrOwnerConfig: body[tdxMROwnerConfigOffset:tdxRTMRsOffset],
+		TdAttributes:  binary.LittleEndian.Uint64(body[tdxTDAttributesOffset:]),
+		Xfam:          binary.LittleEndian.Uint64(body[tdxXFAMOffset:]),
+		MrSeam:        body[tdxMRSeamOffset:tdxMRSignerSeamOffset],
+		TeeTcbSvn:     body[tdxTeeTCBSVNOffset:tdxMRSeamOffset],
+		TcbStatus:     tcbStatus,
+	}
+	for i := 0; i < tdxNumRTMRs; i++ {
+		offset := tdxRTMRsOffset + i*tdxMeasurementSize
+		state.Rtmrs = append(state.Rtmrs, body[offset:offset+tdxMeasurementSize])
+	}
+	if !bytes.Equal(body[tdxReportDataOffset:], reportData) {
+		return nil, errors.New("report data does not match")
+	}
+	if state.GetTdAttributes()&tdxAttributeDebug != 0 && !opts.AllowDebug {
+		return nil, errors.New("the TD can be debugged")
+	}
+	return state, nil
+}
@@ -0,0 +1,378 @@
+package server
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The Intel Provisioning Certification Service (PCS), serving the collateral
+// for verifying TDX quotes.
+const (
+	intelPCSURL                 = "https://api.trustedservices.intel.com"
+	tcbInfoIssuerChainHeader    = "TCB-Info-Issuer-Chain"
+	qeIdentityIssuerChainHeader = "SGX-Enclave-Identity-Issuer-Chain"
+	pckCRLIssuerChainHeader     = "SGX-PCK-CRL-Issuer-Chain"
+)
+
+// TdxCollateral is the collateral from the Intel PCS needed to verify TDX
+// quotes from a platform.
+type TdxCollateral struct {
+	// The signed TDX TCB info for the platform's FMSPC, as returned by the PCS.
+	TCBInfo []byte
+	// The certificate chain which signed the TCB info, leaf first.
+	TCBInfoIssuerChain []*x509.Certificate
+	// The signed identity of the TDX Quoting Enclave, as returned by the PCS.
+	QEIdentity []byte
+	// The certificate chain which signed the QE identity, leaf first.
+	QEIdentityIssuerChain []*x509.Certificate
+	// The CRLs of the Intel SGX Root CA and PCK CAs.
+	CRLs []*pkix.CertificateList
+}
+
+// hexBytes is a hex encoded JSON string.
+type hexBytes []byte
+
+func (h *hexBytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	*h = decoded
+	return nil
+}
+
+type tcbLevelStatus struct {
+	TCBStatus string `json:"tcbStatus"`
+}
+
+type tdxTCBInfo struct {
+	ID         string    `json:"id"`
+	NextUpdate time.Time `json:"nextUpdate"`
+	FMSPC      hexBytes  `json:"fmspc"`
+	TDXModule  struct {
+		MRSigner       hexBytes `json:"mrsigner"`
+		Attributes     hexBytes `json:"attributes"`
+		AttributesMask hexBytes `json:"attributesMask"`
+	} `json:"tdxModule"`
+	TCBLevels []struct {
+		TCB struct {
+			SGXComponents []struct {
+				SVN int `json:"svn"`
+			} `json:"sgxtcbcomponents"`
+			PCESVN        int `json:"pcesvn"`
+			TDXComponents []struct {
+				SVN int `json:"svn"`
+			} `json:"tdxtcbcomponents"`
+		} `json:"tcb"`
+		tcbLevelStatus
+	} `json:"tcbLevels"`
+}
+
+type qeIdentity struct {
+	ID             string    `json:"id"`
+	NextUpdate     time.Time `json:"nextUpdate"`
+	MiscSelect     hexBytes  `json:"miscselect"`
+	MiscSelectMask hexBytes  `json:"miscselectMask"`
+	Attributes     hexBytes  `json:"attributes"`
+	AttributesMask hexBytes  `json:"attributesMask"`
+	MRSigner       hexBytes  `json:"mrsigner"`
+	ISVProdID      uint16    `json:"isvprodid"`
+	TCBLevels      []struct {
+		TCB struct {
+			ISVSVN uint16 `json:"isvsvn"`
+		} `json:"tcb"`
+		tcbLevelStatus
+	} `json:"tcbLevels"`
+}
+
+// verifySignedCollateral verifies the signature of the body (the raw JSON of
+// field) of the signed collateral, by the leaf of the issuer chain.
+func verifySignedCollateral(signed []byte, field string, chain []*x509.Certificate, roots []*x509.Certificate, crls []*pkix.CertificateList, body interface{}) error {
+	if len(chain) == 0 {
+		return errors.New("no issuer certificate chain")
+	}
+	if _, err := verifyIntelChain(chain, roots, crls); err != nil {
+		return fmt.Errorf("failed to verify issuer certificate: %w", err)
+	}
+	var parsed map[string]json.RawMessage
+	if err := json.Unmarshal(signed, &parsed); err != nil {
+		return err
+	}
+	var signature hexBytes
+	if err := json.Unmarshal(parsed["signature"], &signature); err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	pub, ok := chain[0].PublicKey.(*ecdsa.PublicKey)
+	if !ok || pub.Curve != elliptic.P256() || len(signature) != tdxECDSAP256SignatureSize {
+		return errors.New("signature is not an ECDSA P-256 signature")
+	}
+	if !verifyP256Signature(pub, parsed[field], signature) {
+		return errors.New("signature does not match the issuer certificate")
+	}
+	if err := json.Unmarshal(parsed[field], body); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *TdxCollateral) verifiedTCBInfo(roots []*x509.Certificate) (*tdxTCBInfo, error) {
+	info := &tdxTCBInfo{}
+	if err := verifySignedCollateral(c.TCBInfo, "tcbInfo", c.TCBInfoIssuerChain, roots, c.CRLs, info); err != nil {
+		return nil, fmt.Errorf("invalid TCB info: %w", err)
+	}
+	if info.ID != "TDX" {
+		return nil, fmt.Errorf("TCB info is for %q, not TDX", info.ID)
+	}
+	if time.Now().After(info.NextUpdate) {
+		return nil, errors.New("TCB info has expired")
+	}
+	return info, nil
+}
+
+func (c *TdxCollateral) verifiedQEIdentity(roots []*x509.Certificate) (*qeIdentity, error) {
+	identity := &qeIdentity{}
+	if err := verifySignedCollateral(c.QEIdentity, "enclaveIdentity", c.QEIdentityIssuerChain, roots, c.CRLs, identity); err != nil {
+		return nil, fmt.Errorf("invalid QE identity: %w", err)
+	}
+	if identity.ID != "TD_QE" {
+		return nil, fmt.Errorf("QE identity is for %q, not TD_QE", identity.ID)
+	}
+	if time.Now().After(identity.NextUpdate) {
+		return nil, errors.New("QE identity has expired")
+	}
+	return identity, nil
+}
+
+// maskedEqual returns whether value, masked with mask, equals expected.
+func maskedEqual(value, mask, expected []byte) bool {
+	if len(value) != len(mask) || len(value) != len(expected) {
+		return false
+	}
+	for i := range value {
+		if value[i]&mask[i] != expected[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// status returns the TCB status of the QE (an SGX report body), after checking
+// it is the TDX Quoting Enclave.
+func (q *qeIdentity) status(report []byte) (string, error) {
+	if !maskedEqual(report[sgxMiscSelectOffset:sgxMiscSelectOffset+4], q.MiscSelectMask, q.MiscSelect) {
+		return "", errors.New("QE MISCSELECT does not match the QE identity")
+	}
+	if !maskedEqual(report[sgxAttributesOffset:sgxAttributesOffset+16], q.AttributesMask, q.Attributes) {
+		return "", errors.New("QE attributes do not match the QE identity")
+	}
+	if !bytes.Equal(report[sgxMRSignerOffset:sgxMRSignerOffset+32], q.MRSigner) {
+		return "", errors.New("QE MRSIGNER does not match the QE identity")
+	}
+	if prodID := binary.LittleEndian.Uint16(report[sgxISVProdIDOffset:]); prodID != q.ISVProdID {
+		return "", fmt.Errorf("QE ISVPRODID %d does not match the QE identity", prodID)
+	}
+	svn := binary.LittleEndian.Uint16(report[sgxISVSVNOffset:])
+	for _, level := range q.TCBLevels {
+		if svn >= level.TCB.ISVSVN {
+			return level.TCBStatus, nil
+		}
+	}
+	return "", fmt.Errorf("QE ISVSVN %d is below all TCB levels", svn)
+}
+
+// status returns the TCB status of the platform, from the PCK certificate and
+// TD quote body, after checking they match the TCB info.
+func (info *tdxTCBInfo) status(pck *pckExtensions, body []byte) (string, error) {
+	if !bytes.Equal(pck.fmspc, info.FMSPC) {
+		return "", errors.New("TCB info is for a different FMSPC")
+	}
+	module := info.TDXModule
+	if !bytes.Equal(body[tdxMRSignerSeamOffset:tdxSeamAttributesOffset], module.MRSigner) {
+		return "", errors.New("TDX module MRSIGNERSEAM does not match the TCB info")
+	}
+	if !maskedEqual(body[tdxSeamAttributesOffset:tdxTDAttributesOffset], module.AttributesMask, module.Attributes) {
+		return "", errors.New("TDX module attributes do not match the TCB info")
+	}
+	teeTCBSVN := body[tdxTeeTCBSVNOffset:tdxMRSeamOffset]
+	for _, level := range info.TCBLevels {
+		tcb := level.TCB
+		if len(tcb.SGXComponents) != tcbComponentCount || len(tcb.TDXComponents) != tcbComponentCount {
+			return "", errors.New("TCB info has an invalid TCB level")
+		}
+		matches := pck.pceSVN >= tcb.PCESVN
+		for i := 0; i < tcbComponentCount; i++ {
+			matches = matches && pck.componentSVNs[i] >= tcb.SGXComponents[i].SVN &&
+				int(teeTCBSVN[i]) >= tcb.TDXComponents[i].SVN
+		}
+		if matches {
+			return level.TCBStatus, nil
+		}
+	}
+	return "", errors.New("platform TCB is below all TCB levels")
+}
+
+// verifyIntelChain verifies the certificate chain (leaf first) to the roots,
+// and checks that none of its certificates are revoked. A CRL from the issuer
+// of each certificate is required.
+func verifyIntelChain(chain []*x509.Certificate, roots []*x509.Certificate, crls []*pkix.CertificateList) ([]*x509.Certificate, error) {
+	rootPool := x509.NewCertPool()
+	for _, root := range roots {
+		rootPool.AddCert(root)
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	chains, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         rootPool,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, err
+	}
+	verified := chains[0]
+	now := time.Now()
+	for i, cert := range verified[:len(verified)-1] {
+		issuer := verified[i+1]
+		var crl *pkix.CertificateList
+		for _, candidate := range crls {
+			if issuer.CheckCRLSignature(candidate) == nil {
+				crl = candidate
+				break
+			}
+		}
+		if crl == nil {
+			return nil, fmt.Errorf("no CRL from %q", issuer.Subject.CommonName)
+		}
+		if crl.HasExpired(now) {
+			return nil, fmt.Errorf("the CRL from %q has expired", issuer.Subject.CommonName)
+		}
+		for _, revoked := range crl.TBSCertList.RevokedCertificates {
+			if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				return nil, fmt.Errorf("certificate %q is revoked", cert.Subject.CommonName)
+			}
+		}
+	}
+	return verified, nil
+}
+
+// FetchTdxCollateral fetches the collateral for verifying the TDX quote from
+// the Intel PCS, using the given HTTP client. The collateral is not verified
+// by this function, but by VerifyTdxQuote.
+func FetchTdxCollateral(client *http.Client, quote []byte) (*TdxCollateral, error) {
+	parsed, err := parseTdxQuote(quote)
+	if err != nil {
+		return nil, err
+	}
+	extensions, err := parsePCKExtensions(parsed.pckChain[0])
+	if err != nil {
+		return nil, err
+	}
+	var ca string
+	switch issuer := parsed.pckChain[0].Issuer.CommonName; {
+	case strings.Contains(issuer, "Platform"):
+		ca = "platform"
+	case strings.Contains(issuer, "Processor"):
+		ca = "processor"
+	default:
+		return nil, fmt.Errorf("unknown PCK CA %q", issuer)
+	}
+
+	collateral := &TdxCollateral{}
+	if collateral.TCBInfo, collateral.TCBInfoIssuerChain, err = fetchPCS(client,
+		intelPCSURL+"/tdx/certification/v4/tcb?fmspc="+hex.EncodeToString(extensions.fmspc), tcbInfoIssuerChainHeader); err != nil {
+		return nil, fmt.Errorf("failed to fetch TCB info: %w", err)
+	}
+	if collateral.QEIdentity, collateral.QEIdentityIssuerChain, err = fetchPCS(client,
+		intelPCSURL+"/tdx/certification/v4/qe/identity", qeIdentityIssuerChainHeader); err != nil {
+		return nil, fmt.Errorf("failed to fetch QE identity: %w", err)
+	}
+	pckCRL, crlChain, err := fetchPCS(client,
+		intelPCSURL+"/sgx/certification/v4/pckcrl?ca="+ca+"&encoding=der", pckCRLIssuerChainHeader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch PCK CRL: %w", err)
+	}
+	crl, err := x509.ParseCRL(pckCRL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PCK CRL: %w", err)
+	}
+	collateral.CRLs = append(collateral.CRLs, crl)
+
+	// The Root CA CRL is distributed at the CRL distribution point of the CAs
+	// it issued.
+	if len(crlChain) == 0 || len(crlChain[0].CRLDistributionPoints) == 0 {
+		return nil, errors.New("PCK CRL issuer has no CRL distribution point")
+	}
+	rootCRL, _, err := fetchPCS(client, crlChain[0].CRLDistributionPoints[0], "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch root CA CRL: %w", err)
+	}
+	if crl, err = x509.ParseCRL(rootCRL); err != nil {
+		return nil, fmt.Errorf("failed to parse root CA CRL: %w", err)
+	}
+	collateral.CRLs = append(collateral.CRLs, crl)
+	return collateral, nil
+}
+
+// fetchPCS gets the target URL, returning the body and the certificate chain in the
+// (URL encoded PEM) chainHeader, if not empty.
+func fetchPCS(client *http.Client, target string, chainHeader string) ([]byte, []*x509.Certificate, error) {
+	resp, err := client.Get(target)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("got HTTP status %q", resp.Status)
+	}
+	if chainHeader == "" {
+		return body, nil, nil
+	}
+	chain, err := parseChainHeader(resp.Header.Get(chainHeader))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s header: %w", chainHeader, err)
+	}
+	return body, chain, nil
+}
+
+func parseChainHeader(header string) ([]*x509.Certificate, error) {
+	decoded, err := url.PathUnescape(header)
+	if err != nil {
+		return nil, err
+	}
+	var chain []*x509.Certificate
+	for block, rest := pem.Decode([]byte(decoded)); block != nil; block, rest = pem.Decode(rest) {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("no certificates")
+	}
+	return chain, nil
+}
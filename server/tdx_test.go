@@ -0,0 +1,495 @@
+package server
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+)
+
+const testRootCRLURL = "https://certificates.trustedservices.intel.com/IntelSGXRootCA.der"
+
+var (
+	testFMSPC         = []byte{0x00, 0x80, 0x6f, 0x05, 0x00, 0x00}
+	testQEMRSigner    = bytes.Repeat([]byte{0xdc}, 32)
+	testSeamMRSigner  = make([]byte, tdxMeasurementSize)
+	testTeeTCBSVN     = []byte{3, 0, 5, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	testPCKComponents = []int{2, 2, 2, 2, 3, 1, 0, 3, 0, 0, 0, 0, 0, 0, 0, 0}
+	testPCKPCESVN     = 11
+	testQEISVProdID   = 2
+	testQEISVSVN      = 4
+	testQEAttributes  = []byte{0x11, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	testQEAttrMask    = []byte{0xfb, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0, 0, 0, 0, 0}
+	testMRTD          = bytes.Repeat([]byte{0x7d}, tdxMeasurementSize)
+	testRTMR3         = bytes.Repeat([]byte{0x33}, tdxMeasurementSize)
+	testQEAuthData    = []byte("QE authentication data")
+)
+
+// tdxPlatform is an Intel-like PKI and TDX platform, issuing quotes and their
+// collateral.
+type tdxPlatform struct {
+	root, pckCA, pck, tcbSigner       *x509.Certificate
+	rootKey, pckCAKey, pckKey, tcbKey *ecdsa.PrivateKey
+	attestationKey                    *ecdsa.PrivateKey
+	tcbStatus                         string
+	nextUpdate                        time.Time
+	revokedSerials                    []int64
+}
+
+func generateP256Key(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newTdxPlatform(t *testing.T) *tdxPlatform {
+	t.Helper()
+	p := &tdxPlatform{
+		rootKey:        generateP256Key(t),
+		pckCAKey:       generateP256Key(t),
+		pckKey:         generateP256Key(t),
+		tcbKey:         generateP256Key(t),
+		attestationKey: generateP256Key(t),
+		tcbStatus:      "UpToDate",
+		nextUpdate:     time.Now().Add(time.Hour),
+	}
+	ca := func(serial int64, name string) *x509.Certificate {
+		return &x509.Certificate{
+			SerialNumber:          big.NewInt(serial),
+			Subject:               pkix.Name{CommonName: name},
+			KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+			CRLDistributionPoints: []string{testRootCRLURL},
+		}
+	}
+	p.root = createTestCert(t, ca(1, "Intel SGX Root CA"), nil, p.rootKey.Public(), p.rootKey)
+	p.pckCA = createTestCert(t, ca(2, "Intel SGX PCK Platform CA"), p.root, p.pckCAKey.Public(), p.rootKey)
+	p.tcbSigner = createTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "Intel SGX TCB Signing"},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}, p.root, p.tcbKey.Public(), p.rootKey)
+
+	type extension struct {
+		ID    asn1.ObjectIdentifier
+		Value asn1.RawValue
+	}
+	var tcb []extension
+	for i, svn := range testPCKComponents {
+		tcb = append(tcb, extension{append(append(asn1.ObjectIdentifier{}, oidSGXTCB...), i+1), asn1.RawValue{FullBytes: mustMarshal(t, svn)}})
+	}
+	tcb = append(tcb,
+		extension{oidSGXPCESVN, asn1.RawValue{FullBytes: mustMarshal(t, testPCKPCESVN)}},
+		extension{append(append(asn1.ObjectIdentifier{}, oidSGXTCB...), 18), asn1.RawValue{FullBytes: mustMarshal(t, make([]byte, 16))}},
+	)
+	sgx := []extension{
+		{asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1, 1}, asn1.RawValue{FullBytes: mustMarshal(t, make([]byte, 16))}},
+		{oidSGXTCB, asn1.RawValue{FullBytes: mustMarshal(t, tcb)}},
+		{oidSGXFMSPC, asn1.RawValue{FullBytes: mustMarshal(t, testFMSPC)}},
+	}
+	p.pck = createTestCert(t, &x509.Certificate{
+		SerialNumber:    big.NewInt(4),
+		Subject:         pkix.Name{CommonName: "Intel SGX PCK Certificate"},
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtraExtensions: []pkix.Extension{{Id: oidSGXExtensions, Value: mustMarshal(t, sgx)}},
+	}, p.pckCA, p.pckKey.Public(), p.pckCAKey)
+	return p
+}
+
+func signP256(t *testing.T, key *ecdsa.PrivateKey, data []byte) []byte {
+	t.Helper()
+	digest := sha256.Sum256(data)
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return signature
+}
+
+// quote creates a TDX quote for the report data and TD attributes.
+func (p *tdxPlatform) quote(t *testing.T, reportData []byte, tdAttributes uint64) []byte {
+	t.Helper()
+	header := make([]byte, tdxHeaderSize)
+	binary.LittleEndian.PutUint16(header[0:], tdxQuoteVersion)
+	binary.LittleEndian.PutUint16(header[2:], tdxAttestationKeyECDSAP256)
+	binary.LittleEndian.PutUint32(header[4:], tdxTeeType)
+	body := make([]byte, tdxBodySize)
+	copy(body[tdxTeeTCBSVNOffset:], testTeeTCBSVN)
+	copy(body[tdxMRSignerSeamOffset:], testSeamMRSigner)
+	binary.LittleEndian.PutUint64(body[tdxTDAttributesOffset:], tdAttributes)
+	copy(body[tdxMRTDOffset:], testMRTD)
+	copy(body[tdxRTMRsOffset+3*tdxMeasurementSize:], testRTMR3)
+	copy(body[tdxReportDataOffset:], reportData)
+	signed := append(header, body...)
+
+	attestationKey := make([]byte, 64)
+	p.attestationKey.X.FillBytes(attestationKey[:32])
+	p.attestationKey.Y.FillBytes(attestationKey[32:])
+	qeReport := make([]byte, sgxReportBodySize)
+	copy(qeReport[sgxAttributesOffset:], testQEAttributes)
+	copy(qeReport[sgxMRSignerOffset:], testQEMRSigner)
+	binary.LittleEndian.PutUint16(qeReport[sgxISVProdIDOffset:], uint16(testQEISVProdID))
+	binary.LittleEndian.PutUint16(qeReport[sgxISVSVNOffset:], uint16(testQEISVSVN))
+	binding := sha256.Sum256(append(append([]byte{}, attestationKey...), testQEAuthData...))
+	copy(qeReport[sgxReportDataOffset:], binding[:])
+
+	var pemChain []byte
+	for _, cert := range []*x509.Certificate{p.pck, p.pckCA, p.root} {
+		pemChain = append(pemChain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+	qeData := append(qeReport, signP256(t, p.pckKey, qeReport)...)
+	qeData = appendUint16(qeData, uint16(len(testQEAuthData)))
+	qeData = append(qeData, testQEAuthData...)
+	qeData = appendUint16(qeData, tdxPCKCertChainType)
+	qeData = appendUint32(qeData, uint32(len(pemChain)))
+	qeData = append(qeData, pemChain...)
+
+	signatureData := append(signP256(t, p.attestationKey, signed), attestationKey...)
+	signatureData = appendUint16(signatureData, tdxQEReportCertificationType)
+	signatureData = appendUint32(signatureData, uint32(len(qeData)))
+	signatureData = append(signatureData, qeData...)
+	quote := appendUint32(signed, uint32(len(signatureData)))
+	return append(quote, signatureData...)
+}
+
+func appendUint16(data []byte, value uint16) []byte {
+	var b [2]byte
+	binary.LittleEndian.PutUint16(b[:], value)
+	return append(data, b[:]...)
+}
+
+func appendUint32(data []byte, value uint32) []byte {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], value)
+	return append(data, b[:]...)
+}
+
+// signCollateral signs the JSON body as the field of the collateral, as the
+// Intel PCS does.
+func (p *tdxPlatform) signCollateral(t *testing.T, field string, body map[string]interface{}) []byte {
+	t.Helper()
+	raw, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := json.Marshal(map[string]interface{}{
+		field:       json.RawMessage(raw),
+		"signature": hex.EncodeToString(signP256(t, p.tcbKey, raw)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func (p *tdxPlatform) tcbInfo(t *testing.T) []byte {
+	t.Helper()
+	components := func(svns []int) []map[string]int {
+		var out []map[string]int
+		for _, svn := range svns {
+			out = append(out, map[string]int{"svn": svn})
+		}
+		return out
+	}
+	teeSVNs := make([]int, tcbComponentCount)
+	for i, svn := range testTeeTCBSVN {
+		teeSVNs[i] = int(svn)
+	}
+	newerTeeSVNs := append([]int{}, teeSVNs...)
+	newerTeeSVNs[2]++
+	level := func(sgx []int, tdx []int, status string) map[string]interface{} {
+		return map[string]interface{}{
+			"tcb": map[string]interface{}{
+				"sgxtcbcomponents": components(sgx),
+				"pcesvn":           testPCKPCESVN,
+				"tdxtcbcomponents": components(tdx),
+			},
+			"tcbDate":   "2024-03-13T00:00:00Z",
+			"tcbStatus": status,
+		}
+	}
+	return p.signCollateral(t, "tcbInfo", map[string]interface{}{
+		"id":         "TDX",
+		"version":    3,
+		"issueDate":  time.Now().Format(time.RFC3339),
+		"nextUpdate": p.nextUpdate.Format(time.RFC3339),
+		"fmspc":      hex.EncodeToString(testFMSPC),
+		"pceId":      "0000",
+		"tdxModule": map[string]string{
+			"mrsigner":       hex.EncodeToString(testSeamMRSigner),
+			"attributes":     "0000000000000000",
+			"attributesMask": "ffffffffffffffff",
+		},
+		"tcbLevels": []interface{}{
+			level(testPCKComponents, newerTeeSVNs, "UpToDate"),
+			level(testPCKComponents, teeSVNs, p.tcbStatus),
+		},
+	})
+}
+
+func (p *tdxPlatform) qeIdentity(t *testing.T) []byte {
+	t.Helper()
+	return p.signCollateral(t, "enclaveIdentity", map[string]interface{}{
+		"id":             "TD_QE",
+		"version":        2,
+		"issueDate":      time.Now().Format(time.RFC3339),
+		"nextUpdate":     p.nextUpdate.Format(time.RFC3339),
+		"miscselect":     "00000000",
+		"miscselectMask": "ffffffff",
+		"attributes":     hex.EncodeToString(testQEAttributes),
+		"attributesMask": hex.EncodeToString(testQEAttrMask),
+		"mrsigner":       hex.EncodeToString(testQEMRSigner),
+		"isvprodid":      testQEISVProdID,
+		"tcbLevels": []interface{}{
+			map[string]interface{}{"tcb": map[string]int{"isvsvn": testQEISVSVN}, "tcbStatus": "UpToDate"},
+			map[string]interface{}{"tcb": map[string]int{"isvsvn": 0}, "tcbStatus": "OutOfDate"},
+		},
+	})
+}
+
+func (p *tdxPlatform) crls(t *testing.T) (pckCRL, rootCRL []byte) {
+	t.Helper()
+	var revoked []pkix.RevokedCertificate
+	for _, serial := range p.revokedSerials {
+		revoked = append(revoked, pkix.RevokedCertificate{SerialNumber: big.NewInt(serial), RevocationTime: time.Now()})
+	}
+	create := func(issuer *x509.Certificate, key crypto.Signer) []byte {
+		crl, err := issuer.CreateCRL(rand.Reader, key, revoked, time.Now(), p.nextUpdate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return crl
+	}
+	return create(p.pckCA, p.pckCAKey), create(p.root, p.rootKey)
+}
+
+func (p *tdxPlatform) collateral(t *testing.T) *TdxCollateral {
+	t.Helper()
+	collateral := &TdxCollateral{
+		TCBInfo:               p.tcbInfo(t),
+		TCBInfoIssuerChain:    []*x509.Certificate{p.tcbSigner, p.root},
+		QEIdentity:            p.qeIdentity(t),
+		QEIdentityIssuerChain: []*x509.Certificate{p.tcbSigner, p.root},
+	}
+	pckCRL, rootCRL := p.crls(t)
+	for _, der := range [][]byte{pckCRL, rootCRL} {
+		crl, err := x509.ParseCRL(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		collateral.CRLs = append(collateral.CRLs, crl)
+	}
+	return collateral
+}
+
+func (p *tdxPlatform) opts(t *testing.T) TdxOpts {
+	return TdxOpts{TrustedRoots: []*x509.Certificate{p.root}, Collateral: p.collateral(t)}
+}
+
+func TestVerifyTdxQuote(t *testing.T) {
+	platform := newTdxPlatform(t)
+	reportData := client.TEEReportData([]byte("nonce"), []byte("AK"))
+	quote := platform.quote(t, reportData, 0)
+	opts := platform.opts(t)
+
+	state, err := VerifyTdxQuote(quote, reportData, opts)
+	if err != nil {
+		t.Fatalf("failed to verify TDX quote: %v", err)
+	}
+	if !bytes.Equal(state.GetMrTd(), testMRTD) || len(state.GetRtmrs()) != tdxNumRTMRs || !bytes.Equal(state.GetRtmrs()[3], testRTMR3) {
+		t.Errorf("got TDX state %v", state)
+	}
+	if !bytes.Equal(state.GetTeeTcbSvn(), testTeeTCBSVN) || state.GetTcbStatus() != "UpToDate" {
+		t.Errorf("got TEE TCB SVN %x and status %q", state.GetTeeTcbSvn(), state.GetTcbStatus())
+	}
+
+	debug := platform.quote(t, reportData, tdxAttributeDebug)
+	if _, err = VerifyTdxQuote(debug, reportData, TdxOpts{TrustedRoots: opts.TrustedRoots, Collateral: opts.Collateral, AllowDebug: true}); err != nil {
+		t.Errorf("failed to verify TDX quote of a debug TD: %v", err)
+	}
+
+	outOfDate := newTdxPlatform(t)
+	outOfDate.tcbStatus = "OutOfDate"
+	outOfDateQuote := outOfDate.quote(t, reportData, 0)
+	outOfDateOpts := outOfDate.opts(t)
+	outOfDateOpts.AcceptedTCBStatuses = []string{"UpToDate", "OutOfDate"}
+	if state, err = VerifyTdxQuote(outOfDateQuote, reportData, outOfDateOpts); err != nil {
+		t.Errorf("failed to verify TDX quote with an accepted TCB status: %v", err)
+	} else if state.GetTcbStatus() != "OutOfDate" {
+		t.Errorf("got TCB status %q", state.GetTcbStatus())
+	}
+
+	tampered := append([]byte{}, quote...)
+	tampered[tdxHeaderSize+tdxMRTDOffset] ^= 1
+	revoked := newTdxPlatform(t)
+	revoked.revokedSerials = []int64{revoked.pck.SerialNumber.Int64()}
+	expired := newTdxPlatform(t)
+	expired.nextUpdate = time.Now().Add(-time.Minute)
+	other := newTdxPlatform(t)
+	noCRLs := platform.opts(t)
+	noCRLs.Collateral.CRLs = noCRLs.Collateral.CRLs[:1]
+	wrongSigner := platform.opts(t)
+	wrongSigner.Collateral.TCBInfo = other.tcbInfo(t)
+
+	invalid := []struct {
+		name       string
+		quote      []byte
+		reportData []byte
+		opts       TdxOpts
+	}{
+		{"WrongReportData", quote, client.TEEReportData([]byte("other nonce"), []byte("AK")), opts},
+		{"Tampered", tampered, reportData, opts},
+		{"Truncated", quote[:len(quote)-1], reportData, opts},
+		{"Debug", debug, reportData, opts},
+		{"NoTrustedRoots", quote, reportData, TdxOpts{Collateral: opts.Collateral}},
+		{"NoCollateral", quote, reportData, TdxOpts{TrustedRoots: opts.TrustedRoots}},
+		{"UntrustedRoot", quote, reportData, TdxOpts{TrustedRoots: []*x509.Certificate{other.root}, Collateral: opts.Collateral}},
+		{"OtherCollateral", quote, reportData, TdxOpts{TrustedRoots: []*x509.Certificate{other.root}, Collateral: other.collateral(t)}},
+		{"WrongCollateralSigner", quote, reportData, wrongSigner},
+		{"MissingCRL", quote, reportData, noCRLs},
+		{"OutOfDate", outOfDateQuote, reportData, outOfDate.opts(t)},
+		{"RevokedPCK", revoked.quote(t, reportData, 0), reportData, revoked.opts(t)},
+		{"ExpiredCollateral", expired.quote(t, reportData, 0), reportData, expired.opts(t)},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := VerifyTdxQuote(tc.quote, tc.reportData, tc.opts); err == nil {
+				t.Error("expected TDX verification to fail")
+			}
+		})
+	}
+}
+
+// rewriteTransport sends all requests to the test server.
+type rewriteTransport struct {
+	server *url.URL
+}
+
+func (r rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme = r.server.Scheme
+	req.URL.Host = r.server.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func encodeChainHeader(chain ...*x509.Certificate) string {
+	var pemChain []byte
+	for _, cert := range chain {
+		pemChain = append(pemChain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+	return url.PathEscape(string(pemChain))
+}
+
+func TestFetchTdxCollateral(t *testing.T) {
+	platform := newTdxPlatform(t)
+	reportData := client.TEEReportData([]byte("nonce"), []byte("AK"))
+	quote := platform.quote(t, reportData, 0)
+	pckCRL, rootCRL := platform.crls(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tdx/certification/v4/tcb":
+			if fmspc := r.URL.Query().Get("fmspc"); !strings.EqualFold(fmspc, hex.EncodeToString(testFMSPC)) {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set(tcbInfoIssuerChainHeader, encodeChainHeader(platform.tcbSigner, platform.root))
+			w.Write(platform.tcbInfo(t))
+		case "/tdx/certification/v4/qe/identity":
+			w.Header().Set(qeIdentityIssuerChainHeader, encodeChainHeader(platform.tcbSigner, platform.root))
+			w.Write(platform.qeIdentity(t))
+		case "/sgx/certification/v4/pckcrl":
+			if r.URL.Query().Get("ca") != "platform" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set(pckCRLIssuerChainHeader, encodeChainHeader(platform.pckCA, platform.root))
+			w.Write(pckCRL)
+		case "/IntelSGXRootCA.der":
+			w.Write(rootCRL)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpClient := &http.Client{Transport: rewriteTransport{serverURL}}
+
+	collateral, err := FetchTdxCollateral(httpClient, quote)
+	if err != nil {
+		t.Fatalf("failed to fetch TDX collateral: %v", err)
+	}
+	if _, err = VerifyTdxQuote(quote, reportData, TdxOpts{TrustedRoots: []*x509.Certificate{platform.root}, Collateral: collateral}); err != nil {
+		t.Errorf("failed to verify TDX quote with the fetched collateral: %v", err)
+	}
+
+	server.Close()
+	if _, err = FetchTdxCollateral(httpClient, quote); err == nil {
+		t.Error("expected fetching the TDX collateral to fail")
+	}
+}
+
+func TestVerifyAttestationTdx(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ak, err := client.AttestationKeyRSA(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer ak.Close()
+	nonce := []byte("super secret nonce")
+	attestation, err := ak.Attest(nonce, nil)
+	if err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+
+	platform := newTdxPlatform(t)
+	attestation.TdxQuote = platform.quote(t, client.TEEReportData(nonce, attestation.GetAkPub()), 0)
+	opts := VerifyOpts{
+		Nonce:      nonce,
+		TrustedAKs: []crypto.PublicKey{ak.PublicKey()},
+		Tdx:        platform.opts(t),
+	}
+	state, err := VerifyAttestation(attestation, opts)
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	if !bytes.Equal(state.GetTdx().GetMrTd(), testMRTD) {
+		t.Errorf("got TDX state %v", state.GetTdx())
+	}
+
+	// A quote for another nonce cannot be combined with the TPM quotes.
+	attestation.TdxQuote = platform.quote(t, client.TEEReportData([]byte("other nonce"), attestation.GetAkPub()), 0)
+	if _, err = VerifyAttestation(attestation, opts); err == nil {
+		t.Error("expected verification to fail with the TDX quote for another nonce")
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"crypto/x509"
+	"fmt"
+
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+// Verifier is a policy backend evaluating the parts of an Attestation, so that
+// users can enforce their own policies (written in Go, or evaluated by a
+// policy engine such as OPA) as part of VerifyAttestation. The methods are only
+// called with data that VerifyAttestation has already authenticated, and
+// should return an error if the policy is not satisfied.
+type Verifier interface {
+	// VerifyCerts is called once the AK is trusted, with the certificates
+	// contained in the Attestation.
+	VerifyCerts(certs *AttestationCerts) error
+	// VerifyPCRs is called with the PCRs of each quote whose signature was
+	// verified. If it fails, the next quote (i.e. PCR bank) is tried.
+	VerifyPCRs(pcrs *pb.PCRs) error
+	// VerifyEventLog is called with the MachineState parsed from the event
+	// logs, which were replayed against the PCRs passed to VerifyPCRs. If it
+	// fails, the next quote is tried.
+	VerifyEventLog(state *attestpb.MachineState) error
+}
+
+// AttestationCerts are the parsed certificates of an Attestation. Fields are
+// nil if the corresponding certificates are not in the Attestation.
+type AttestationCerts struct {
+	AK            *x509.Certificate
+	Intermediates []*x509.Certificate
+	// The EK certificate is not bound to the AK by the Attestation.
+	EK *x509.Certificate
+}
+
+func parseAttestationCerts(attestation *attestpb.Attestation) (*AttestationCerts, error) {
+	certs := &AttestationCerts{}
+	var err error
+	if der := attestation.GetAkCert(); len(der) > 0 {
+		if certs.AK, err = x509.ParseCertificate(der); err != nil {
+			return nil, fmt.Errorf("failed to parse AK certificate: %w", err)
+		}
+	}
+	for _, der := range attestation.GetIntermediateCerts() {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse intermediate certificate: %w", err)
+		}
+		certs.Intermediates = append(certs.Intermediates, cert)
+	}
+	if der := attestation.GetEkCert(); len(der) > 0 {
+		if certs.EK, err = x509.ParseCertificate(der); err != nil {
+			return nil, fmt.Errorf("failed to parse EK certificate: %w", err)
+		}
+	}
+	return certs, nil
+}
+
+// VerifierFuncs is a Verifier calling the non-nil functions, allowing a
+// policy to only implement some of the checks.
+type VerifierFuncs struct {
+	Certs    func(certs *AttestationCerts) error
+	PCRs     func(pcrs *pb.PCRs) error
+	EventLog func(state *attestpb.MachineState) error
+}
+
+// VerifyCerts calls f.Certs, if set.
+func (f VerifierFuncs) VerifyCerts(certs *AttestationCerts) error {
+	if f.Certs == nil {
+		return nil
+	}
+	return f.Certs(certs)
+}
+
+// VerifyPCRs calls f.PCRs, if set.
+func (f VerifierFuncs) VerifyPCRs(pcrs *pb.PCRs) error {
+	if f.PCRs == nil {
+		return nil
+	}
+	return f.PCRs(pcrs)
+}
+
+// VerifyEventLog calls f.EventLog, if set.
+func (f VerifierFuncs) VerifyEventLog(state *attestpb.MachineState) error {
+	if f.EventLog == nil {
+		return nil
+	}
+	return f.EventLog(state)
+}
+
+// PolicyVerifier returns a Verifier checking the MachineState against the
+// Policy, using EvaluatePolicy.
+func PolicyVerifier(policy *attestpb.Policy) Verifier {
+	return VerifierFuncs{EventLog: func(state *attestpb.MachineState) error {
+		return EvaluatePolicy(state, policy)
+	}}
+}
+
+// PCRPolicyVerifier returns a Verifier checking the quoted PCRs against the
+// PCRPolicy, using EvaluatePCRPolicy. As the PCRs of each quote are checked
+// separately, the rules of the policy should be satisfiable by a single bank.
+func PCRPolicyVerifier(policy *PCRPolicy) Verifier {
+	return VerifierFuncs{PCRs: func(pcrs *pb.PCRs) error {
+		result, err := EvaluatePCRPolicy([]*pb.PCRs{pcrs}, policy)
+		if err != nil {
+			return err
+		}
+		return result.Err()
+	}}
+}
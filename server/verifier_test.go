@@ -0,0 +1,101 @@
+package server
+
+import (
+	"crypto"
+	"encoding/hex"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+func TestVerifyAttestationVerifiers(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ak, err := client.AttestationKeyRSA(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer ak.Close()
+	nonce := []byte("super secret nonce")
+	attestation, err := ak.Attest(nonce, nil)
+	if err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+	opts := VerifyOpts{Nonce: nonce, TrustedAKs: []crypto.PublicKey{ak.PublicKey()}}
+
+	var pcrBanks []pb.HashAlgo
+	var certs *AttestationCerts
+	var state *attestpb.MachineState
+	recorder := VerifierFuncs{
+		Certs: func(c *AttestationCerts) error {
+			certs = c
+			return nil
+		},
+		PCRs: func(pcrs *pb.PCRs) error {
+			pcrBanks = append(pcrBanks, pcrs.GetHash())
+			return nil
+		},
+		EventLog: func(s *attestpb.MachineState) error {
+			state = s
+			return nil
+		},
+	}
+	opts.Verifiers = []Verifier{recorder}
+	got, err := VerifyAttestation(attestation, opts)
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	if certs == nil || certs.AK != nil || certs.EK != nil {
+		t.Errorf("got certificates %v, want none", certs)
+	}
+	if len(pcrBanks) != 1 || state != got {
+		t.Errorf("got PCRs verified for banks %v and machine state %p (returned %p), want one bank and the returned state", pcrBanks, state, got)
+	}
+
+	// Only the SHA256 quote satisfies the PCR policy.
+	sel := tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{0}}
+	pcr0, err := client.ReadPCRs(rwc, sel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pcrPolicy := &PCRPolicy{PCRs: []PCRRule{{PCR: 0, Bank: "sha256", Values: []string{hex.EncodeToString(pcr0.GetPcrs()[0])}}}}
+	pcrBanks = nil
+	opts.Verifiers = []Verifier{PCRPolicyVerifier(pcrPolicy), recorder}
+	if got, err = VerifyAttestation(attestation, opts); err != nil {
+		t.Fatalf("failed to verify with a PCR policy: %v", err)
+	}
+	if got.GetHash() != pb.HashAlgo_SHA256 || len(pcrBanks) != 1 || pcrBanks[0] != pb.HashAlgo_SHA256 {
+		t.Errorf("got machine state for bank %v after verifying PCR banks %v, want SHA256", got.GetHash(), pcrBanks)
+	}
+	opts.Verifiers = []Verifier{PolicyVerifier(&attestpb.Policy{})}
+	if _, err = VerifyAttestation(attestation, opts); err != nil {
+		t.Errorf("failed to verify with an empty policy: %v", err)
+	}
+
+	errRejected := errors.New("rejected")
+	failures := map[string]Verifier{
+		"Certs":     VerifierFuncs{Certs: func(*AttestationCerts) error { return errRejected }},
+		"PCRs":      VerifierFuncs{PCRs: func(*pb.PCRs) error { return errRejected }},
+		"EventLog":  VerifierFuncs{EventLog: func(*attestpb.MachineState) error { return errRejected }},
+		"PCRPolicy": PCRPolicyVerifier(&PCRPolicy{PCRs: []PCRRule{{PCR: 0, Values: []string{hex.EncodeToString(make([]byte, 32))}}}}),
+		"Policy": PolicyVerifier(&attestpb.Policy{Platform: &attestpb.PlatformPolicy{
+			MinimumGceFirmwareVersion: math.MaxUint32,
+		}}),
+	}
+	for name, verifier := range failures {
+		t.Run(name, func(t *testing.T) {
+			opts.Verifiers = []Verifier{recorder, verifier}
+			if _, err := VerifyAttestation(attestation, opts); err == nil {
+				t.Error("expected verification to fail")
+			}
+		})
+	}
+}
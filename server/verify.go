@@ -0,0 +1,385 @@
+package server
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/google/go-tpm/tpm2"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ThalesIgnite/go-tpm-tools/cel"
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+// VerifyQuote validates a Quote (as generated by client.Key.Quote) using a
+// trusted AK public key and the nonce supplied by the verifier. It checks that:
+//   - the signature over the quote data was generated by the AK
+//   - the quote data is a TPMS_ATTEST starting with TPM_GENERATED_VALUE
+//   - the qualifying data (extraData) of the quote matches the nonce
+//   - the quoted PCR selection and digest match the PCR values in the Quote
+//
+// Note that the caller must have already established trust in the AK public
+// key (for example, by certifying it) before calling VerifyQuote. VerifyQuote
+// does not check the PCR values themselves; use VerifyQuoteWithGoldenPCRs to
+// also compare them against known-good values.
+func VerifyQuote(quote *pb.Quote, akPub crypto.PublicKey, nonce []byte) error {
+	return notinternal.VerifyQuote(quote, akPub, nonce)
+}
+
+// VerifySessionAudit validates a SessionAudit (as generated by
+// client.AuditSession.Certify) using a trusted AK public key and the nonce
+// supplied by the verifier. It checks that the audit digest was signed by the
+// AK, and that it matches the log of audited commands in the SessionAudit.
+// The caller must then check that the logged commands are the expected ones
+// (for example, by comparing each cp_hash against a hash computed from the
+// expected command parameters).
+func VerifySessionAudit(audit *pb.SessionAudit, akPub crypto.PublicKey, nonce []byte) error {
+	return notinternal.VerifySessionAudit(audit, akPub, nonce)
+}
+
+// VerifyKeyCertification validates a KeyCertification (as generated by
+// client.Key.CertifyWith) using a trusted AK public key and the nonce supplied
+// by the verifier, returning the public key of the certified key. It checks
+// that:
+//   - the signature over the certify info was generated by the AK
+//   - the qualifying data (extraData) of the certify info matches the nonce
+//   - the certified Name matches the public area in the KeyCertification
+//   - the certified key was generated by the TPM, and cannot be duplicated
+//     (it has FlagFixedTPM, FlagFixedParent and FlagSensitiveDataOrigin)
+//
+// Together, these prove that the private key is only usable in the AK's TPM.
+// The caller must still check that the key has the expected usage (for
+// example, that it is an unrestricted signing key).
+func VerifyKeyCertification(certification *pb.KeyCertification, akPub crypto.PublicKey, nonce []byte) (crypto.PublicKey, error) {
+	pub, err := notinternal.VerifyKeyCertification(certification, akPub, nonce)
+	if err != nil {
+		return nil, err
+	}
+	required := tpm2.FlagFixedTPM | tpm2.FlagFixedParent | tpm2.FlagSensitiveDataOrigin
+	if pub.Attributes&required != required {
+		return nil, fmt.Errorf("certified key may be exportable or not generated by the TPM (attributes 0x%x)", uint32(pub.Attributes))
+	}
+	return notinternal.PublicKey(pub)
+}
+
+// VerifyCreationCertification validates a CreationCertification (as
+// generated by client.Key.CertifyCreation) using a trusted AK public key and
+// the nonce supplied by the verifier, returning the public key of the
+// certified key. It checks that:
+//   - the signature over the creation info was generated by the AK
+//   - the qualifying data (extraData) of the creation info matches the nonce
+//   - the certified Name matches the public area in the CreationCertification
+//   - the certified creation hash matches the creation data
+//   - if pcrs is not nil, the key was created while the TPM had exactly these
+//     PCR values (selected with client.KeyOpts.CreationPCRs)
+func VerifyCreationCertification(certification *pb.CreationCertification, akPub crypto.PublicKey, nonce []byte, pcrs *pb.PCRs) (crypto.PublicKey, error) {
+	pub, creationData, err := notinternal.VerifyCreationCertification(certification, akPub, nonce)
+	if err != nil {
+		return nil, err
+	}
+	if pcrs != nil {
+		if !notinternal.SamePCRSelection(pcrs, creationData.PCRSelection) {
+			return nil, fmt.Errorf("creation PCR selection %v does not match the expected PCRs", creationData.PCRSelection)
+		}
+		// The creation PCR digest uses the name algorithm of the key.
+		hash, err := pub.NameAlg.Hash()
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(creationData.PCRDigest, notinternal.PCRDigest(pcrs, hash)) {
+			return nil, fmt.Errorf("key was not created with the expected PCR values")
+		}
+	}
+	return notinternal.PublicKey(pub)
+}
+
+// VerifyQuoteWithGoldenPCRs validates a Quote as in VerifyQuote, and then
+// checks that every PCR in golden was quoted with exactly the golden value.
+// The quote may contain additional PCRs not present in golden.
+func VerifyQuoteWithGoldenPCRs(quote *pb.Quote, akPub crypto.PublicKey, nonce []byte, golden *pb.PCRs) error {
+	if err := VerifyQuote(quote, akPub, nonce); err != nil {
+		return err
+	}
+	if err := notinternal.CheckSubset(golden, quote.GetPcrs()); err != nil {
+		return fmt.Errorf("quoted PCRs do not match golden values: %w", err)
+	}
+	return nil
+}
+
+// VerifyOpts allows for customizing the functionality of VerifyAttestation.
+type VerifyOpts struct {
+	// The nonce used when calling client.Attest
+	Nonce []byte
+	// If true, the evidence must be bound with nonces derived from Nonce (see
+	// client.DeriveAttestationNonces), as done by client.AttestWithConfig
+	// with AttestConfig.DeriveNonces. The quotes then also bind the event logs.
+	DerivedNonces bool
+	// Trusted public keys that can be used to directly verify the key used for
+	// attestation. This option should be used if you already know the AK.
+	TrustedAKs []crypto.PublicKey
+	// Root certificates trusted to issue AK certificates. If the Attestation
+	// contains an AK certificate (and intermediates) chaining up to one of
+	// these roots, the AK is trusted. No roots are trusted by default: the
+	// vTPM root CAs of cloud platforms (such as GCE and Azure) are not bundled
+	// with this module, and must be obtained from the platform.
+	TrustedRootCerts []*x509.Certificate
+	// Policy backends evaluating the verified parts of the Attestation. The
+	// Attestation is only accepted if all the Verifiers accept it.
+	Verifiers []Verifier
+	// Configures the verification of the SEV-SNP attestation report, if the
+	// Attestation contains one.
+	SevSnp SevSnpOpts
+	// Configures the verification of the TDX quote, if the Attestation
+	// contains one.
+	Tdx TdxOpts
+}
+
+// VerifyAttestation performs the following checks on an Attestation:
+//   - the AK used to generate the attestation is trusted (based on VerifyOpts),
+//     and is a restricted signing key which cannot leave its TPM
+//   - the provided signature is generated by the trusted AK public key
+//   - the signature signs the provided quote data
+//   - the quote data starts with TPM_GENERATED_VALUE
+//   - the quote data is a valid TPMS_QUOTE_INFO
+//   - the quote data was taken over the provided PCRs
+//   - the provided PCR values match the quote data internal digest
+//   - the provided nonce (or the nonce derived from it, with DerivedNonces)
+//     matches the quote data qualifying data
+//   - the provided PCR values match the event log (and IMA log, Canonical
+//     Event Log and DRTM event log, if present)
+//   - the SEV-SNP attestation report (if present) is signed by a VCEK from
+//     the trusted AMD roots, and bound to the nonce and the AK
+//   - the TDX quote (if present) is from a genuine Intel platform with an
+//     accepted TCB status (per the collateral), and bound to the nonce and AK
+//   - the certificates, PCRs and MachineState satisfy the Verifiers, if any
+//
+// The container launched on the machine, if any, is parsed from the Canonical
+// Event Log (see cel.AppendContainerLaunch). If the AK is trusted because of its
+// certificate, the GCE instance identified by the certificate (see
+// client.GetGCEInstanceInfo), if any, is set in the PlatformState. The
+// InstanceInfo of the Attestation must then match it, and is otherwise
+// ignored. The instance is only verified if Google's vTPM root CA is passed
+// in TrustedRootCerts, as it is not used by default.
+//
+// The IMA log and Canonical Event Log are parsed first, and an error is
+// returned if either is malformed. The quotes are then checked in turn, and the
+// first quote to pass all checks is used to construct the returned
+// MachineState. It is the caller's responsibility to then evaluate the
+// MachineState (for example, using EvaluatePolicy), or to pass Verifiers doing
+// so (such as a PolicyVerifier).
+func VerifyAttestation(attestation *attestpb.Attestation, opts VerifyOpts) (*attestpb.MachineState, error) {
+	akPub, akCert, err := trustedAKPublicKey(attestation, opts)
+	if err != nil {
+		return nil, err
+	}
+	instanceInfo, err := verifiedInstanceInfo(attestation, akCert)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.Verifiers) > 0 {
+		certs, err := parseAttestationCerts(attestation)
+		if err != nil {
+			return nil, err
+		}
+		for _, verifier := range opts.Verifiers {
+			if err = verifier.VerifyCerts(certs); err != nil {
+				return nil, fmt.Errorf("certificates rejected by verifier: %w", err)
+			}
+		}
+	}
+	quoteNonce, reportData := opts.Nonce, client.TEEReportData(opts.Nonce, attestation.GetAkPub())
+	if opts.DerivedNonces {
+		nonces, err := client.DeriveAttestationNonces(opts.Nonce, attestation)
+		if err != nil {
+			return nil, err
+		}
+		quoteNonce, reportData = nonces.TPMQuote, nonces.TEEReport
+	}
+	var sevSnp *attestpb.SevSnpState
+	if snp := attestation.GetSevSnpAttestation(); snp != nil {
+		if sevSnp, err = VerifySevSnpAttestation(snp, reportData, opts.SevSnp); err != nil {
+			return nil, fmt.Errorf("failed to verify the SEV-SNP attestation report: %w", err)
+		}
+	}
+	var tdx *attestpb.TdxState
+	if quote := attestation.GetTdxQuote(); len(quote) > 0 {
+		if tdx, err = VerifyTdxQuote(quote, reportData, opts.Tdx); err != nil {
+			return nil, fmt.Errorf("failed to verify the TDX quote: %w", err)
+		}
+	}
+
+	// The logs are parsed once, as they do not depend on the quote. Only the
+	// checks against the PCRs of each quote are repeated for every quote.
+	var imaEvents []IMAEvent
+	if imaLog := attestation.GetImaLog(); len(imaLog) > 0 {
+		if imaEvents, err = ParseIMALog(imaLog); err != nil {
+			return nil, fmt.Errorf("failed to parse the IMA log: %w", err)
+		}
+	}
+	var celLog *cel.CEL
+	var container *attestpb.ContainerState
+	if data := attestation.GetCanonicalEventLog(); len(data) > 0 {
+		if celLog, err = cel.Parse(data); err != nil {
+			return nil, fmt.Errorf("failed to parse the Canonical Event Log: %w", err)
+		}
+		if container, err = getContainerState(celLog); err != nil {
+			return nil, err
+		}
+	}
+
+	var lastErr error
+quotes:
+	for _, quote := range attestation.GetQuotes() {
+		if err = VerifyQuote(quote, akPub, quoteNonce); err != nil {
+			lastErr = err
+			continue
+		}
+		for _, verifier := range opts.Verifiers {
+			if err = verifier.VerifyPCRs(quote.GetPcrs()); err != nil {
+				lastErr = fmt.Errorf("PCRs rejected by verifier: %w", err)
+				continue quotes
+			}
+		}
+		machineState, err := ParseMachineState(attestation.GetEventLog(), quote.GetPcrs())
+		if err != nil {
+			lastErr = fmt.Errorf("failed to validate the event log: %w", err)
+			continue
+		}
+		if instanceInfo != nil {
+			if machineState.Platform == nil {
+				machineState.Platform = &attestpb.PlatformState{}
+			}
+			machineState.Platform.InstanceInfo = instanceInfo
+		}
+		machineState.SevSnp = sevSnp
+		machineState.Tdx = tdx
+		if drtmLog := attestation.GetDrtmEventLog(); len(drtmLog) > 0 {
+			if machineState.Drtm, err = ParseDrtmState(drtmLog, quote.GetPcrs()); err != nil {
+				lastErr = fmt.Errorf("failed to validate the DRTM event log: %w", err)
+				continue
+			}
+		}
+		if len(attestation.GetImaLog()) > 0 {
+			if err = VerifyIMALog(imaEvents, quote.GetPcrs()); err != nil {
+				lastErr = fmt.Errorf("failed to validate the IMA log: %w", err)
+				continue
+			}
+		}
+		if celLog != nil {
+			if err = celLog.Replay(quote.GetPcrs()); err != nil {
+				lastErr = fmt.Errorf("failed to validate the Canonical Event Log: %w", err)
+				continue
+			}
+			machineState.Container = container
+		}
+		for _, verifier := range opts.Verifiers {
+			if err = verifier.VerifyEventLog(machineState); err != nil {
+				lastErr = fmt.Errorf("machine state rejected by verifier: %w", err)
+				continue quotes
+			}
+		}
+		return machineState, nil
+	}
+	if lastErr == nil {
+		return nil, errors.New("attestation does not contain any quotes")
+	}
+	return nil, fmt.Errorf("attestation does not contain a valid quote: %w", lastErr)
+}
+
+// trustedAKPublicKey returns the AK public key, and its certificate if the AK
+// is trusted because of its certificate.
+func trustedAKPublicKey(attestation *attestpb.Attestation, opts VerifyOpts) (crypto.PublicKey, *x509.Certificate, error) {
+	if len(opts.TrustedAKs) == 0 && len(opts.TrustedRootCerts) == 0 {
+		return nil, nil, errors.New("no mechanism for AK verification provided")
+	}
+	akPubArea, err := tpm2.DecodePublic(attestation.GetAkPub())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode AK public area: %w", err)
+	}
+	// Quotes only prove the PCR values if the AK is a restricted signing key
+	// which cannot leave its TPM.
+	required := tpm2.FlagRestricted | tpm2.FlagSign | tpm2.FlagFixedTPM
+	if akPubArea.Attributes&required != required {
+		return nil, nil, fmt.Errorf("AK attributes 0x%x are missing the required attributes 0x%x", uint32(akPubArea.Attributes), uint32(required&^akPubArea.Attributes))
+	}
+	if akPubArea.Attributes&tpm2.FlagDecrypt != 0 {
+		return nil, nil, errors.New("AK must not be a decryption key")
+	}
+	akPub, err := notinternal.PublicKey(akPubArea)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get AK public key: %w", err)
+	}
+	akPubDER, err := x509.MarshalPKIXPublicKey(akPub)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, trusted := range opts.TrustedAKs {
+		trustedDER, err := x509.MarshalPKIXPublicKey(trusted)
+		if err == nil && bytes.Equal(trustedDER, akPubDER) {
+			return akPub, nil, nil
+		}
+	}
+	if len(opts.TrustedRootCerts) == 0 || len(attestation.GetAkCert()) == 0 {
+		return nil, nil, errors.New("AK public key is not trusted")
+	}
+
+	akCert, err := x509.ParseCertificate(attestation.GetAkCert())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse AK certificate: %w", err)
+	}
+	certDER, err := x509.MarshalPKIXPublicKey(akCert.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !bytes.Equal(certDER, akPubDER) {
+		return nil, nil, errors.New("AK certificate does not match the AK public key")
+	}
+	roots := x509.NewCertPool()
+	for _, root := range opts.TrustedRootCerts {
+		roots.AddCert(root)
+	}
+	intermediates := x509.NewCertPool()
+	for _, der := range attestation.GetIntermediateCerts() {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse intermediate certificate: %w", err)
+		}
+		intermediates.AddCert(cert)
+	}
+	// Some vTPMs (such as Azure's) issue AK certificates with the critical TPM
+	// manufacturer Subject Alternative Name of EK certificates.
+	if _, err = withTCGExtensionsHandled(akCert).Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, nil, fmt.Errorf("failed to verify AK certificate: %w", err)
+	}
+	return akPub, akCert, nil
+}
+
+// verifiedInstanceInfo returns the GCE instance identified by the verified AK
+// certificate, if any. The instance information of the Attestation is not
+// trusted by itself, but must match the certificate if it is set. The
+// certificate was verified with the caller's TrustedRootCerts only: there are
+// no default GCE roots.
+func verifiedInstanceInfo(attestation *attestpb.Attestation, akCert *x509.Certificate) (*attestpb.GCEInstanceInfo, error) {
+	if akCert == nil {
+		return nil, nil
+	}
+	info, err := client.GetGCEInstanceInfo(akCert)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AK certificate: %w", err)
+	}
+	if claimed := attestation.GetInstanceInfo(); claimed != nil && !proto.Equal(claimed, info) {
+		return nil, errors.New("the GCE instance information does not match the AK certificate")
+	}
+	return info, nil
+}
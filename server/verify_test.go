@@ -1,17 +1,25 @@
 package server
 
 import (
+	"bytes"
+	"crypto"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"fmt"
 	"io"
+	"math/big"
 	"testing"
+	"time"
 
 	"github.com/ThalesIgnite/go-tpm-tools/client"
 	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
 	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
 	"github.com/google/go-tpm/tpm2"
 	"github.com/google/go-tpm/tpmutil"
+	"google.golang.org/protobuf/proto"
 )
 
 func getDigestHash(input string) []byte {
@@ -177,3 +185,285 @@ func TestVerifyUsingDifferentPCR(t *testing.T) {
 		t.Errorf("Verify should fail as Verify read a different PCR")
 	}
 }
+
+func TestVerifyQuote(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	keys := []struct {
+		name   string
+		getKey func(io.ReadWriter) (*client.Key, error)
+	}{
+		{"AK-ECC", client.AttestationKeyECC},
+		{"AK-RSA", client.AttestationKeyRSA},
+		{"AK-ECSchnorr", func(rw io.ReadWriter) (*client.Key, error) {
+			template := client.AKTemplateECC()
+			template.ECCParameters.Sign.Alg = client.AlgECSchnorr
+			return client.NewKey(rw, tpm2.HandleOwner, template)
+		}},
+	}
+	sel := tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: []int{test.DebugPCR, test.ApplicationPCR}}
+	nonce := getDigestHash("test")
+	for _, key := range keys {
+		t.Run(key.name, func(t *testing.T) {
+			ak, err := key.getKey(rwc)
+			if err != nil {
+				t.Fatalf("failed to generate AK: %v", err)
+			}
+			defer ak.Close()
+
+			quote, err := ak.Quote(sel, nonce)
+			if err != nil {
+				t.Fatalf("failed to quote: %v", err)
+			}
+			if err = VerifyQuote(quote, ak.PublicKey(), nonce); err != nil {
+				t.Errorf("failed to verify: %v", err)
+			}
+			if err = VerifyQuote(quote, ak.PublicKey(), getDigestHash("other")); err == nil {
+				t.Error("Verify should fail with a different nonce")
+			}
+			otherAK, err := client.NewKey(rwc, tpm2.HandleEndorsement, ak.PublicArea())
+			if err != nil {
+				t.Fatalf("failed to generate AK: %v", err)
+			}
+			defer otherAK.Close()
+			if err = VerifyQuote(quote, otherAK.PublicKey(), nonce); err == nil {
+				t.Error("Verify should fail with a different AK")
+			}
+
+			// The quoted PCR values must match the quoted digest.
+			quote.GetPcrs().GetPcrs()[uint32(test.DebugPCR)][0] ^= 1
+			if err = VerifyQuote(quote, ak.PublicKey(), nonce); err == nil {
+				t.Error("Verify should fail with modified PCR values")
+			}
+		})
+	}
+}
+
+func TestVerifyWithGoldenPCRs(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ak, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer ak.Close()
+
+	selpcr := tpm2.PCRSelection{
+		Hash: tpm2.AlgSHA256,
+		PCRs: []int{test.DebugPCR, test.ApplicationPCR},
+	}
+	if err = extendPCRsRandomly(rwc, selpcr); err != nil {
+		t.Fatalf("failed to extend test PCRs: %v", err)
+	}
+	golden, err := client.ReadPCRs(rwc, tpm2.PCRSelection{
+		Hash: tpm2.AlgSHA256,
+		PCRs: []int{test.DebugPCR},
+	})
+	if err != nil {
+		t.Fatalf("failed to read PCRs: %v", err)
+	}
+
+	nonce := getDigestHash("test")
+	quote, err := ak.Quote(selpcr, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifyQuoteWithGoldenPCRs(quote, ak.PublicKey(), nonce, golden); err != nil {
+		t.Errorf("failed to verify: %v", err)
+	}
+
+	// A quote taken after the PCRs change no longer matches the golden values
+	if err = extendPCRsRandomly(rwc, selpcr); err != nil {
+		t.Fatalf("failed to extend test PCRs: %v", err)
+	}
+	quote, err = ak.Quote(selpcr, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifyQuote(quote, ak.PublicKey(), nonce); err != nil {
+		t.Errorf("failed to verify: %v", err)
+	}
+	if err = VerifyQuoteWithGoldenPCRs(quote, ak.PublicKey(), nonce, golden); err == nil {
+		t.Error("Verify should fail as the PCRs do not match the golden values")
+	}
+}
+
+func TestVerifyAttestation(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ak, err := client.AttestationKeyRSA(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer ak.Close()
+	otherAK, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer otherAK.Close()
+
+	nonce := []byte("super secret nonce")
+	attestation, err := ak.Attest(nonce, nil)
+	if err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+
+	opts := VerifyOpts{Nonce: nonce, TrustedAKs: []crypto.PublicKey{ak.PublicKey()}}
+	state, err := VerifyAttestation(attestation, opts)
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	if len(state.GetRawEvents()) == 0 {
+		t.Error("expected MachineState to contain events")
+	}
+
+	failures := []struct {
+		name string
+		opts VerifyOpts
+	}{
+		{"NoTrustedAKs", VerifyOpts{Nonce: nonce}},
+		{"WrongAK", VerifyOpts{Nonce: nonce, TrustedAKs: []crypto.PublicKey{otherAK.PublicKey()}}},
+		{"WrongNonce", VerifyOpts{Nonce: []byte("wrong nonce"), TrustedAKs: opts.TrustedAKs}},
+	}
+	for _, f := range failures {
+		t.Run(f.name, func(t *testing.T) {
+			if _, err := VerifyAttestation(attestation, f.opts); err == nil {
+				t.Error("expected verification to fail")
+			}
+		})
+	}
+}
+
+func TestVerifyAttestationAKAttributes(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ak, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer ak.Close()
+
+	ca := newTestCA(t, "Test AK CA", 1, nil)
+	akTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Test AK"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	akCert, err := x509.CreateCertificate(rand.Reader, akTemplate, ca.cert, ak.PublicKey(), ca.priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := []byte("super secret nonce")
+	attestation, err := ak.Attest(nonce, nil)
+	if err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+	attestation.AkCert = akCert
+	opts := VerifyOpts{Nonce: nonce, TrustedRootCerts: []*x509.Certificate{ca.cert}}
+	if _, err := VerifyAttestation(attestation, opts); err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+
+	// The quotes are still signed by the certified key, but the attributes no
+	// longer prove that the key only signs quotes of its own TPM.
+	failures := []struct {
+		name   string
+		modify func(attrs tpm2.KeyProp) tpm2.KeyProp
+	}{
+		{"Unrestricted", func(attrs tpm2.KeyProp) tpm2.KeyProp { return attrs &^ tpm2.FlagRestricted }},
+		{"NotFixedTPM", func(attrs tpm2.KeyProp) tpm2.KeyProp { return attrs &^ tpm2.FlagFixedTPM }},
+		{"Decrypt", func(attrs tpm2.KeyProp) tpm2.KeyProp { return attrs | tpm2.FlagDecrypt }},
+	}
+	for _, f := range failures {
+		t.Run(f.name, func(t *testing.T) {
+			pub := ak.PublicArea()
+			pub.Attributes = f.modify(pub.Attributes)
+			akPub, err := pub.Encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			modified := proto.Clone(attestation).(*attestpb.Attestation)
+			modified.AkPub = akPub
+			if _, err := VerifyAttestation(modified, opts); err == nil {
+				t.Error("expected verification to fail")
+			}
+			trustedOpts := VerifyOpts{Nonce: nonce, TrustedAKs: []crypto.PublicKey{ak.PublicKey()}}
+			if _, err := VerifyAttestation(modified, trustedOpts); err == nil {
+				t.Error("expected verification with a trusted AK to fail")
+			}
+		})
+	}
+}
+
+func TestVerifyAttestationDerivedNonces(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+
+	ak, err := client.AttestationKeyRSA(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer ak.Close()
+
+	nonce := []byte("super secret nonce")
+	attestation, err := ak.AttestWithConfig(nonce, client.AttestConfig{DeriveNonces: true})
+	if err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+	opts := VerifyOpts{Nonce: nonce, TrustedAKs: []crypto.PublicKey{ak.PublicKey()}, DerivedNonces: true}
+	if _, err = VerifyAttestation(attestation, opts); err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	// The quotes do not contain the verifier's nonce itself.
+	if _, err = VerifyAttestation(attestation, VerifyOpts{Nonce: nonce, TrustedAKs: opts.TrustedAKs}); err == nil {
+		t.Error("expected verification to fail without DerivedNonces")
+	}
+	legacy, err := ak.Attest(nonce, nil)
+	if err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+	if _, err = VerifyAttestation(legacy, opts); err == nil {
+		t.Error("expected verification to fail for an attestation without derived nonces")
+	}
+
+	// A TEE attestation must be bound to the same event logs as the quotes.
+	chipID := bytes.Repeat([]byte{0xc1}, 64)
+	chain := newSevSnpChain(t, chipID, testReportedTCB)
+	opts.SevSnp = SevSnpOpts{TrustedRoots: []*x509.Certificate{chain.ark}}
+	withSevSnp := func(reportData []byte) *attestpb.Attestation {
+		bundle := proto.Clone(attestation).(*attestpb.Attestation)
+		bundle.SevSnpAttestation = &attestpb.SevSnpAttestation{
+			Report:   signSevSnpReport(t, chain.vcekKey, reportData, chipID, testReportedTCB, 0),
+			VcekCert: chain.vcek.Raw,
+			AskCert:  chain.ask.Raw,
+		}
+		return bundle
+	}
+	nonces, err := client.DeriveAttestationNonces(nonce, attestation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = VerifyAttestation(withSevSnp(nonces.TEEReport), opts); err != nil {
+		t.Errorf("failed to verify with an SEV-SNP attestation report: %v", err)
+	}
+	otherLogs := proto.Clone(attestation).(*attestpb.Attestation)
+	otherLogs.DrtmEventLog = []byte("other DRTM event log")
+	otherNonces, err := client.DeriveAttestationNonces(nonce, otherLogs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, reportData := range map[string][]byte{
+		"OtherEventLogs": otherNonces.TEEReport,
+		"TEEReportData":  client.TEEReportData(nonce, attestation.GetAkPub()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := VerifyAttestation(withSevSnp(reportData), opts); err == nil {
+				t.Error("expected verification to fail with a TEE attestation bound to other evidence")
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package simulator_test
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/google/go-tpm/tpm2"
+
+	"github.com/ThalesIgnite/go-tpm-tools/simulator"
+)
+
+func ExampleGet() {
+	sim, err := simulator.Get()
+	if err != nil {
+		log.Fatalf("failed to initialize simulator: %v", err)
+	}
+	defer sim.Close()
+
+	// The simulator can be used like any other TPM.
+	random, err := tpm2.GetRandom(sim, 16)
+	if err != nil {
+		log.Fatalf("GetRandom failed: %v", err)
+	}
+	fmt.Println(len(random))
+	// Output: 16
+}
@@ -30,7 +30,9 @@ package internal
 // #cgo LDFLAGS: -lcrypto
 //
 // #include <stdlib.h>
+// #include <string.h>
 // #include "Platform.h"
+// #include "PlatformData.h"
 // #include "Tpm.h"
 //
 // void sync_seeds() {
@@ -38,6 +40,13 @@ package internal
 //     NV_SYNC_PERSISTENT(SPSeed);
 //     NV_SYNC_PERSISTENT(PPSeed);
 // }
+//
+// // TPM_Manufacture() does not reset all of the persistent data, so erase the
+// // NV memory (and its copy in RAM) to its initial state.
+// void erase_nv() {
+//     memset(s_NV, 0, sizeof(s_NV));
+//     memset(&gp, 0, sizeof(gp));
+// }
 import "C"
 import (
 	"errors"
@@ -53,9 +62,30 @@ func SetSeeds(r io.Reader) {
 	r.Read(C.gp.PPSeed[2:])
 }
 
+// SetFixedEntropy makes the simulator's entropy source deterministic, deriving
+// the entropy from seed. If seed is nil, the system's entropy is used again.
+func SetFixedEntropy(seed []byte) {
+	if seed == nil {
+		C._plat__SetFixedEntropy(nil, 0)
+		return
+	}
+	cSeed := C.CBytes(seed)
+	defer C.free(cSeed)
+	C._plat__SetFixedEntropy((*C.uint8_t)(cSeed), C.uint32_t(len(seed)))
+}
+
+// SetFixedClock stops the simulator's clock if fixed is true, and restarts it
+// otherwise.
+func SetFixedClock(fixed bool) {
+	C._plat__SetFixedClock(C.bool(fixed))
+}
+
 // Reset simulates toggling the power the the TPM. If forceManufacture is true,
 // the reset will be a manufacturer reset.
 func Reset(forceManufacture bool) {
+	if forceManufacture {
+		C.erase_nv()
+	}
 	C._plat__Reset(C.bool(forceManufacture))
 }
 
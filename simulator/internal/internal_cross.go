@@ -11,6 +11,12 @@ import (
 // SetSeeds does nothing
 func SetSeeds(r io.Reader) {}
 
+// SetFixedEntropy does nothing
+func SetFixedEntropy(seed []byte) {}
+
+// SetFixedClock does nothing
+func SetFixedClock(fixed bool) {}
+
 // Reset does nothing
 func Reset(forceManufacture bool) {}
 
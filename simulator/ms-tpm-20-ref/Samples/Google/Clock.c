@@ -55,6 +55,11 @@ clock64_t s_tpmTime;
 clock64_t s_lastSystemTime;
 clock64_t s_lastReportedTime;
 
+// When the clock is fixed, the real time never advances.
+static bool s_fixedClock;
+
+void _plat__SetFixedClock(bool fixed) { s_fixedClock = fixed; }
+
 void _plat__TimerReset() {
   s_lastSystemTime = 0;
   s_tpmTime = 0;
@@ -65,6 +70,7 @@ void _plat__TimerReset() {
 
 static uint64_t _plat__RealTime() {
   struct timespec systime;
+  if (s_fixedClock) return 0;
   clock_gettime(CLOCK_MONOTONIC, &systime);
   return (clock64_t)systime.tv_sec * 1000 + (systime.tv_nsec / 1000000);
 }
@@ -1,9 +1,51 @@
 #include <openssl/rand.h>
+#include <openssl/sha.h>
+#include <string.h>
 
 #include "Platform_fp.h"
 
-// We get entropy from OpenSSL which gets its entropy from the OS.
+// When fixed entropy is used, the entropy is the concatenation of
+// SHA-256(SHA-256(seed) || counter), for a big-endian 64-bit counter.
+static bool s_fixedEntropy;
+static uint8_t s_entropySeed[SHA256_DIGEST_LENGTH];
+static uint64_t s_entropyCounter;
+
+void _plat__SetFixedEntropy(const uint8_t *seed, uint32_t size) {
+  if (seed == NULL) {
+    s_fixedEntropy = false;
+    return;
+  }
+  SHA256(seed, size, s_entropySeed);
+  s_entropyCounter = 0;
+  s_fixedEntropy = true;
+}
+
+static int32_t fixedEntropy(uint8_t *entropy, uint32_t amount) {
+  uint8_t block[SHA256_DIGEST_LENGTH + 8];
+  uint8_t digest[SHA256_DIGEST_LENGTH];
+  uint32_t done, size;
+  int i;
+
+  memcpy(block, s_entropySeed, SHA256_DIGEST_LENGTH);
+  for (done = 0; done < amount; done += size) {
+    for (i = 0; i < 8; i++) {
+      block[SHA256_DIGEST_LENGTH + i] = (uint8_t)(s_entropyCounter >> (56 - 8 * i));
+    }
+    s_entropyCounter++;
+    SHA256(block, sizeof(block), digest);
+    size = amount - done;
+    if (size > sizeof(digest)) size = sizeof(digest);
+    memcpy(&entropy[done], digest, size);
+  }
+  return amount;
+}
+
+// We get entropy from OpenSSL which gets its entropy from the OS, unless fixed
+// entropy is used.
 int32_t _plat__GetEntropy(uint8_t *entropy, uint32_t amount) {
+  if (s_fixedEntropy) {
+    return fixedEntropy(entropy, amount);
+  }
   if (RAND_bytes(entropy, amount) != 1) {
     return -1;
   }
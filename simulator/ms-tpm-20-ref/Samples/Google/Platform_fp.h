@@ -97,6 +97,17 @@ int32_t _plat__GetEntropy(uint8_t *entropy,  // output buffer
                           uint32_t amount    // amount requested
 );
 
+//*** _plat__SetFixedEntropy()
+// This function makes _plat__GetEntropy() return deterministic data derived
+// from the seed, for reproducible tests. If seed is NULL, the entropy is
+// obtained from the system again.
+void _plat__SetFixedEntropy(const uint8_t *seed, uint32_t size);
+
+//*** _plat__SetFixedClock()
+// This function stops the clock used by _plat__TimerRead() if fixed is true,
+// for reproducible tests.
+void _plat__SetFixedClock(bool fixed);
+
 //***_plat__LocalityGet()
 // We do not support non-zero localities, so just always return 0.
 static inline uint8_t _plat__LocalityGet() { return 0; }
@@ -15,10 +15,20 @@
  */
 
 // Package simulator provides a go interface to the Microsoft TPM2 simulator.
+//
+// The simulator runs in-process: the reference implementation is vendored in
+// this module and built with CGO (which requires the OpenSSL headers), so no
+// TPM device or external simulator process is needed. A Simulator can be used
+// wherever a TPM's io.ReadWriteCloser is expected, which allows unit testing
+// code using this module (or go-tpm) without TPM hardware. In tests, the
+// test.GetSimulator helper also closes the simulator when the test finishes.
+//
+// Without CGO this package still builds, but all TPM commands fail.
 package simulator
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"math/rand"
@@ -49,9 +59,30 @@ var lock sync.Mutex
 // one simulator may be running at a time, a second call to Get() block until
 // the first Simulator is Closed.
 func Get() (*Simulator, error) {
+	return get(nil)
+}
+
+// GetWithFixedSeed behaves like Get() except that the simulator is
+// deterministic: its entropy source is derived from the input seed, and its
+// clock is stopped. Running the same TPM commands on simulators with the same
+// seed gives the same results, including the EK, SRK and other primary keys,
+// random numbers, signatures, sealed blobs, and the clock info of attestations,
+// so golden-file tests are stable from one run to the next. Note that as the
+// TPM's secrets are derived from the seed, this function should only be used
+// for tests.
+func GetWithFixedSeed(seed int64) (*Simulator, error) {
+	seedBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(seedBytes, uint64(seed))
+	return get(seedBytes)
+}
+
+// Get a simulator, using fixed entropy derived from seed if it is not nil.
+func get(seed []byte) (*Simulator, error) {
 	lock.Lock()
 
 	simulator := &Simulator{}
+	internal.SetFixedEntropy(seed)
+	internal.SetFixedClock(seed != nil)
 	internal.Reset(true)
 	if err := simulator.on(true); err != nil {
 		lock.Unlock()
@@ -64,6 +95,8 @@ func Get() (*Simulator, error) {
 // GetWithFixedSeedInsecure behaves like Get() expect that all of the notinternal
 // hierarchy seeds are derived from the input seed. Note that this function
 // compromises the security of the keys/seeds and should only be used for tests.
+// Unlike GetWithFixedSeed, other random values (and the clock) still differ
+// from one run to the next.
 func GetWithFixedSeedInsecure(seed int64) (*Simulator, error) {
 	s, err := Get()
 	if err != nil {
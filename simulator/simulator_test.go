@@ -17,6 +17,7 @@
 package simulator
 
 import (
+	"bytes"
 	"crypto/rsa"
 	"io"
 	"math/big"
@@ -117,3 +118,53 @@ func TestDifferentSeedDifferentModulus(t *testing.T) {
 		t.Fatalf("Moduli should not be equal when using different seeds")
 	}
 }
+
+// Run TPM commands on a simulator with a fixed seed, returning their outputs.
+func fixedSeedOutputs(t *testing.T, seed int64) [][]byte {
+	t.Helper()
+	s, err := GetWithFixedSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.CheckedClose(t, s)
+
+	random, err := tpm2.GetRandom(s, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ak, err := client.AttestationKeyECC(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ak.Close()
+	quote, err := ak.Quote(client.FullPcrSel(tpm2.AlgSHA256), []byte("nonce"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srk, err := client.StorageRootKeyECC(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srk.Close()
+	sealed, err := srk.Seal([]byte("secret"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return [][]byte{getEKModulus(t, s).Bytes(), random, quote.GetQuote(), quote.GetRawSig(), sealed.GetPriv()}
+}
+
+func TestFixedSeedReproducible(t *testing.T) {
+	outputs1 := fixedSeedOutputs(t, 42)
+	outputs2 := fixedSeedOutputs(t, 42)
+	for i := range outputs1 {
+		if !bytes.Equal(outputs1[i], outputs2[i]) {
+			t.Errorf("output %d differs between runs: %x != %x", i, outputs1[i], outputs2[i])
+		}
+	}
+	outputs3 := fixedSeedOutputs(t, 43)
+	for i := range outputs1 {
+		if bytes.Equal(outputs1[i], outputs3[i]) {
+			t.Errorf("output %d should differ for different seeds", i)
+		}
+	}
+}
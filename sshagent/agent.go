@@ -0,0 +1,311 @@
+// Package sshagent implements an SSH agent whose keys are stored in a TPM.
+//
+// Keys are created as children of the TPM's ECC SRK (see
+// client.StorageRootKeyECC), and their (encrypted) key blobs are stored in a
+// directory. The private keys never leave the TPM, and the key blobs can only
+// be used on the TPM that created them. The Agent can be served to OpenSSH
+// clients with agent.ServeAgent (see "gotpm ssh-agent").
+package sshagent
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/google/go-tpm/tpm2"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+)
+
+// KeyExtension is the file extension of the key blobs stored by the Agent.
+const KeyExtension = ".tpmkey"
+
+// Attributes of the keys created by GenerateKey: unrestricted signing keys
+// bound to the TPM.
+const keyAttributes = tpm2.FlagSign | tpm2.FlagFixedTPM | tpm2.FlagFixedParent |
+	tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9._@-]+$`)
+
+// Agent is an agent.ExtendedAgent signing with keys stored in a TPM. It is
+// safe for concurrent use, as all TPM operations are serialized.
+type Agent struct {
+	mu         sync.Mutex
+	srk        *client.Key
+	dir        string
+	locked     bool
+	passphrase []byte
+}
+
+var _ agent.ExtendedAgent = (*Agent)(nil)
+
+// A key stored by the Agent.
+type storedKey struct {
+	name string
+	blob *pb.KeyBlob
+	pub  ssh.PublicKey
+}
+
+// New returns an Agent using the keys stored in dir, which is created if
+// needed. The Agent keeps the SRK loaded in the TPM until Close is called.
+func New(rw io.ReadWriter, dir string) (*Agent, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create key directory: %w", err)
+	}
+	srk, err := client.StorageRootKeyECC(rw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SRK: %w", err)
+	}
+	return &Agent{srk: srk, dir: dir}, nil
+}
+
+// Close flushes the SRK from the TPM.
+func (a *Agent) Close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.srk.Close()
+}
+
+// GenerateKey creates a new key with the given name and public key algorithm
+// (tpm2.AlgRSA or tpm2.AlgECC), and stores it in the Agent's directory as
+// name+KeyExtension. The name is used as the comment of the key, and can only
+// contain letters, digits and the characters ".", "_", "@" and "-".
+func (a *Agent) GenerateKey(name string, algo tpm2.Algorithm) (ssh.PublicKey, error) {
+	if !validName.MatchString(name) {
+		return nil, fmt.Errorf("invalid key name %q", name)
+	}
+	path := filepath.Join(a.dir, name+KeyExtension)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("key %q already exists", name)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	blob, err := a.srk.CreateChild(client.KeyOpts{Algorithm: algo, Attributes: keyAttributes})
+	if err != nil {
+		return nil, err
+	}
+	key, err := parseKey(name, blob)
+	if err != nil {
+		return nil, err
+	}
+	data, err := proto.Marshal(blob)
+	if err != nil {
+		return nil, err
+	}
+	if err = ioutil.WriteFile(path, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write key: %w", err)
+	}
+	return key.pub, nil
+}
+
+func parseKey(name string, blob *pb.KeyBlob) (*storedKey, error) {
+	pubArea, err := tpm2.DecodePublic(blob.GetPublicArea())
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode public area: %w", err)
+	}
+	pubKey, err := pubArea.Key()
+	if err != nil {
+		return nil, err
+	}
+	pub, err := ssh.NewPublicKey(pubKey)
+	if err != nil {
+		return nil, err
+	}
+	return &storedKey{name: name, blob: blob, pub: pub}, nil
+}
+
+// Read the keys stored in the Agent's directory. Files which are not valid
+// key blobs are skipped.
+func (a *Agent) keys() ([]*storedKey, error) {
+	files, err := ioutil.ReadDir(a.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key directory: %w", err)
+	}
+	var keys []*storedKey
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), KeyExtension) {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(a.dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var blob pb.KeyBlob
+		if err = proto.Unmarshal(data, &blob); err != nil {
+			continue
+		}
+		key, err := parseKey(strings.TrimSuffix(file.Name(), KeyExtension), &blob)
+		if err != nil {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (a *Agent) findKey(pub ssh.PublicKey) (*storedKey, error) {
+	keys, err := a.keys()
+	if err != nil {
+		return nil, err
+	}
+	wire := pub.Marshal()
+	for _, key := range keys {
+		if bytes.Equal(key.pub.Marshal(), wire) {
+			return key, nil
+		}
+	}
+	return nil, errors.New("key not found")
+}
+
+// List returns the keys stored in the Agent's directory.
+func (a *Agent) List() ([]*agent.Key, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.locked {
+		return nil, nil
+	}
+	keys, err := a.keys()
+	if err != nil {
+		return nil, err
+	}
+	var list []*agent.Key
+	for _, key := range keys {
+		list = append(list, &agent.Key{Format: key.pub.Type(), Blob: key.pub.Marshal(), Comment: key.name})
+	}
+	return list, nil
+}
+
+// Sign signs data with the key matching pub, using the default signature
+// algorithm of the key type.
+func (a *Agent) Sign(pub ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return a.SignWithFlags(pub, data, 0)
+}
+
+// SignWithFlags signs data with the key matching pub. As TPM RSA keys sign
+// with a single hash algorithm (SHA-256), RSA keys require the
+// agent.SignatureFlagRsaSha256 flag (the "rsa-sha2-256" algorithm).
+func (a *Agent) SignWithFlags(pub ssh.PublicKey, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.locked {
+		return nil, errors.New("agent is locked")
+	}
+	stored, err := a.findKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	if pub.Type() == ssh.KeyAlgoRSA && flags&agent.SignatureFlagRsaSha256 == 0 {
+		return nil, fmt.Errorf("RSA keys can only sign using %s", ssh.SigAlgoRSASHA2256)
+	}
+
+	key, err := a.srk.LoadChild(stored.blob, "")
+	if err != nil {
+		return nil, err
+	}
+	defer key.Close()
+	cryptoSigner, err := key.GetSigner()
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.NewSignerFromSigner(cryptoSigner)
+	if err != nil {
+		return nil, err
+	}
+	if pub.Type() == ssh.KeyAlgoRSA {
+		return signer.(ssh.AlgorithmSigner).SignWithAlgorithm(rand.Reader, data, ssh.SigAlgoRSASHA2256)
+	}
+	return signer.Sign(rand.Reader, data)
+}
+
+// Add is not supported, as the Agent only uses keys created in the TPM.
+func (a *Agent) Add(key agent.AddedKey) error {
+	return errors.New("adding keys is not supported, keys must be generated in the TPM")
+}
+
+// Remove is not supported, as the keys are stored in the Agent's directory. To
+// remove a key, delete its file.
+func (a *Agent) Remove(key ssh.PublicKey) error {
+	return errors.New("removing keys is not supported, delete the key file instead")
+}
+
+// RemoveAll is not supported, see Remove.
+func (a *Agent) RemoveAll() error {
+	return errors.New("removing keys is not supported, delete the key files instead")
+}
+
+// Lock locks the Agent with passphrase: no keys are listed and signing fails
+// until Unlock is called with the same passphrase.
+func (a *Agent) Lock(passphrase []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.locked {
+		return errors.New("agent is already locked")
+	}
+	a.locked = true
+	a.passphrase = passphrase
+	return nil
+}
+
+// Unlock unlocks an Agent locked with Lock.
+func (a *Agent) Unlock(passphrase []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.locked {
+		return errors.New("agent is not locked")
+	}
+	if subtle.ConstantTimeCompare(passphrase, a.passphrase) != 1 {
+		return errors.New("incorrect passphrase")
+	}
+	a.locked = false
+	a.passphrase = nil
+	return nil
+}
+
+// Signers returns an ssh.Signer for each key, signing using the Agent.
+func (a *Agent) Signers() ([]ssh.Signer, error) {
+	keys, err := a.List()
+	if err != nil {
+		return nil, err
+	}
+	var signers []ssh.Signer
+	for _, key := range keys {
+		signers = append(signers, &agentSigner{a, key})
+	}
+	return signers, nil
+}
+
+// Extension is not supported.
+func (a *Agent) Extension(extensionType string, contents []byte) ([]byte, error) {
+	return nil, agent.ErrExtensionUnsupported
+}
+
+type agentSigner struct {
+	agent *Agent
+	pub   ssh.PublicKey
+}
+
+func (s *agentSigner) PublicKey() ssh.PublicKey {
+	return s.pub
+}
+
+func (s *agentSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	var flags agent.SignatureFlags
+	if s.pub.Type() == ssh.KeyAlgoRSA {
+		flags = agent.SignatureFlagRsaSha256
+	}
+	return s.agent.SignWithFlags(s.pub, data, flags)
+}
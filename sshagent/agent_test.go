@@ -0,0 +1,187 @@
+package sshagent_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-tpm/tpm2"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	"github.com/ThalesIgnite/go-tpm-tools/sshagent"
+)
+
+func newAgent(t *testing.T) (*sshagent.Agent, string) {
+	t.Helper()
+	rwc := test.GetTPM(t)
+	t.Cleanup(func() { client.CheckedClose(t, rwc) })
+	dir, err := ioutil.TempDir("", "sshagent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	a, err := sshagent.New(rwc, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(a.Close)
+	return a, dir
+}
+
+// Serve the agent over a pipe, and return a client connected to it.
+func agentClient(t *testing.T, a agent.ExtendedAgent) agent.ExtendedAgent {
+	t.Helper()
+	serverConn, clientConn := net.Pipe()
+	go agent.ServeAgent(a, serverConn)
+	t.Cleanup(func() { clientConn.Close() })
+	return agent.NewClient(clientConn)
+}
+
+func TestAgentSign(t *testing.T) {
+	a, _ := newAgent(t)
+	data := []byte("session data")
+
+	for name, algo := range map[string]tpm2.Algorithm{"rsa-key": tpm2.AlgRSA, "ecc-key": tpm2.AlgECC} {
+		pub, err := a.GenerateKey(name, algo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Use the agent through the ssh-agent protocol.
+		c := agentClient(t, a)
+		keys, err := c.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, key := range keys {
+			if key.Comment == name && bytes.Equal(key.Marshal(), pub.Marshal()) {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("key %q not listed by the agent", name)
+		}
+
+		var sig *ssh.Signature
+		if pub.Type() == ssh.KeyAlgoRSA {
+			if _, err = c.Sign(pub, data); err == nil {
+				t.Error("signing with ssh-rsa should fail")
+			}
+			sig, err = c.SignWithFlags(pub, data, agent.SignatureFlagRsaSha256)
+		} else {
+			sig, err = c.Sign(pub, data)
+		}
+		if err != nil {
+			t.Fatalf("failed to sign with %q: %v", name, err)
+		}
+		if err = pub.Verify(data, sig); err != nil {
+			t.Errorf("signature of %q does not verify: %v", name, err)
+		}
+	}
+}
+
+func TestAgentSigners(t *testing.T) {
+	a, _ := newAgent(t)
+	if _, err := a.GenerateKey("rsa", tpm2.AlgRSA); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.GenerateKey("ecc", tpm2.AlgECC); err != nil {
+		t.Fatal(err)
+	}
+	signers, err := a.Signers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signers) != 2 {
+		t.Fatalf("got %d signers, want 2", len(signers))
+	}
+	data := []byte("session data")
+	for _, signer := range signers {
+		sig, err := signer.Sign(nil, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = signer.PublicKey().Verify(data, sig); err != nil {
+			t.Errorf("signature of %v key does not verify: %v", signer.PublicKey().Type(), err)
+		}
+	}
+}
+
+func TestAgentLock(t *testing.T) {
+	a, _ := newAgent(t)
+	pub, err := a.GenerateKey("key", tpm2.AlgECC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := agentClient(t, a)
+	if err = c.Lock([]byte("passphrase")); err != nil {
+		t.Fatal(err)
+	}
+	if keys, err := c.List(); err != nil || len(keys) != 0 {
+		t.Errorf("locked agent listed keys: %v, %v", keys, err)
+	}
+	if _, err = c.Sign(pub, []byte("data")); err == nil {
+		t.Error("signing with a locked agent should fail")
+	}
+	if err = c.Unlock([]byte("wrong")); err == nil {
+		t.Error("unlocking with the wrong passphrase should fail")
+	}
+	if err = c.Unlock([]byte("passphrase")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.Sign(pub, []byte("data")); err != nil {
+		t.Errorf("signing with an unlocked agent failed: %v", err)
+	}
+}
+
+func TestAgentKeyStorage(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	dir, err := ioutil.TempDir("", "sshagent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a, err := sshagent.New(rwc, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := a.GenerateKey("key", tpm2.AlgECC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.GenerateKey("key", tpm2.AlgECC); err == nil {
+		t.Error("generating a key with an existing name should fail")
+	}
+	if _, err = a.GenerateKey("../key", tpm2.AlgECC); err == nil {
+		t.Error("generating a key with an invalid name should fail")
+	}
+	a.Close()
+	if _, err = os.Stat(filepath.Join(dir, "key"+sshagent.KeyExtension)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new agent uses the stored key.
+	a, err = sshagent.New(rwc, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	sig, err := a.Sign(pub, []byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pub.Verify([]byte("data"), sig); err != nil {
+		t.Error(err)
+	}
+	if err = a.RemoveAll(); err == nil {
+		t.Error("removing keys should fail")
+	}
+}
@@ -0,0 +1,148 @@
+package verifier
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+	tpmpb "github.com/ThalesIgnite/go-tpm-tools/proto/tpm"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/verifier"
+)
+
+// HTTPError is the error returned by a Client when the Server responds with an
+// HTTP error status.
+type HTTPError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("verifier returned %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Client sends the requests of the verifier protocol to a Server.
+type Client struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewClient returns a Client of the Server at rawURL (for example,
+// "https://host:8322"). If httpClient is nil, http.DefaultClient is used.
+func NewClient(rawURL string, httpClient *http.Client) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid verifier URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported verifier URL scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing host in verifier URL %q", rawURL)
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{url: strings.TrimSuffix(u.String(), "/"), httpClient: httpClient}, nil
+}
+
+// Challenge gets a new challenge, whose nonce must be used for the evidence.
+func (c *Client) Challenge() (*pb.ChallengeResponse, error) {
+	resp := &pb.ChallengeResponse{}
+	if err := c.call(ChallengePath, &pb.ChallengeRequest{}, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SubmitAttestation answers the challenge with an Attestation.
+func (c *Client) SubmitAttestation(challengeID string, attestation *attestpb.Attestation) (*pb.SubmitEvidenceResponse, error) {
+	return c.SubmitEvidence(&pb.SubmitEvidenceRequest{
+		ChallengeId: challengeID,
+		Evidence:    &pb.SubmitEvidenceRequest_Attestation{Attestation: attestation},
+	})
+}
+
+// SubmitEAT answers the challenge with an Entity Attestation Token.
+func (c *Client) SubmitEAT(challengeID string, token []byte) (*pb.SubmitEvidenceResponse, error) {
+	return c.SubmitEvidence(&pb.SubmitEvidenceRequest{
+		ChallengeId: challengeID,
+		Evidence:    &pb.SubmitEvidenceRequest_Eat{Eat: token},
+	})
+}
+
+// SubmitEvidence answers a challenge, returning the status of the appraisal.
+// It does not fail if the evidence could not be verified.
+func (c *Client) SubmitEvidence(req *pb.SubmitEvidenceRequest) (*pb.SubmitEvidenceResponse, error) {
+	resp := &pb.SubmitEvidenceResponse{}
+	if err := c.call(SubmitEvidencePath, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetResult returns the signed attestation result of an answered challenge,
+// in the format (see server.AttestationResult.SignCWT and SignJWT).
+func (c *Client) GetResult(challengeID string, format pb.ResultFormat) ([]byte, error) {
+	resp := &pb.GetResultResponse{}
+	if err := c.call(GetResultPath, &pb.GetResultRequest{ChallengeId: challengeID, Format: format}, resp); err != nil {
+		return nil, err
+	}
+	return resp.GetToken(), nil
+}
+
+// GetCredential returns a credential for the AK of an affirmed challenge,
+// encrypted to the EK of the attester (see Secret). Its secret is decrypted
+// with client.Key.ActivateCredential on the AK, and passed to ReleaseSecret.
+func (c *Client) GetCredential(challengeID string) (*pb.GetCredentialResponse, error) {
+	resp := &pb.GetCredentialResponse{}
+	if err := c.call(GetCredentialPath, &pb.GetCredentialRequest{ChallengeId: challengeID}, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ReleaseSecret returns the secret of the verifier with the identifier, as an
+// import blob for the EK of the attester of an affirmed challenge (see
+// Secret). The credential is the activated secret of the last credential of
+// the challenge (see GetCredential). The secret is decrypted with
+// client.Key.Import on the EK.
+func (c *Client) ReleaseSecret(challengeID, secretID string, credential []byte) (*tpmpb.ImportBlob, error) {
+	resp := &pb.ReleaseSecretResponse{}
+	req := &pb.ReleaseSecretRequest{ChallengeId: challengeID, SecretId: secretID, Credential: credential}
+	if err := c.call(ReleaseSecretPath, req, resp); err != nil {
+		return nil, err
+	}
+	if resp.GetBlob() == nil {
+		return nil, fmt.Errorf("verifier returned no secret")
+	}
+	return resp.GetBlob(), nil
+}
+
+func (c *Client) call(path string, req, resp proto.Message) error {
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	httpResp, err := c.httpClient.Post(c.url+path, contentType, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("sending request to the verifier: %w", err)
+	}
+	defer httpResp.Body.Close()
+	respBody, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return fmt.Errorf("reading verifier response: %w", err)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return &HTTPError{StatusCode: httpResp.StatusCode, Message: string(bytes.TrimSpace(respBody))}
+	}
+	if err = proto.Unmarshal(respBody, resp); err != nil {
+		return fmt.Errorf("decoding verifier response: %w", err)
+	}
+	return nil
+}
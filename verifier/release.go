@@ -0,0 +1,176 @@
+package verifier
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-tpm/tpm2"
+	"google.golang.org/protobuf/proto"
+
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/verifier"
+	"github.com/ThalesIgnite/go-tpm-tools/server"
+)
+
+// The maximum size of the sensitive data of sealed TPM objects (MAX_SYM_DATA).
+const maxSecretSize = 128
+
+// Secret is a secret (such as a LUKS passphrase) which the Server only
+// releases to attesters whose appraisal is affirming. It is released as an
+// import blob encrypted to the attester's Endorsement Key (EK), whose
+// certificate must be in the attestation and pass server.VerifyEKCert with
+// Config.EKOpts. The attester decrypts it with client.Key.Import on the EK
+// (see agent.Agent.ReleaseSecret), so that it is only exposed to the attested
+// TPM.
+//
+// The attestation does not prove that the EK is in the same TPM as the AK, so
+// before releasing a secret, the Server issues a credential for the name of
+// the AK, encrypted to the EK (see server.MakeCredential). The secret is only
+// released if the attester returns the secret of the credential, which the
+// TPM only decrypts (with client.Key.ActivateCredential) if the AK is loaded
+// in the TPM of the EK. Otherwise, an attester with an affirmed AK could get
+// secrets released to another TPM.
+type Secret struct {
+	// The secret, of at most 128 bytes (the maximum size of the data sealed
+	// in TPM objects).
+	Value []byte
+	// If non-nil, the secret is only released if the verified MachineState
+	// also satisfies the Policy (see server.EvaluatePolicy), in addition to
+	// the Config.Opts.
+	Policy *attestpb.Policy
+}
+
+// The size of the secrets of credentials, which must be at most the digest
+// size of the name algorithm of the EKs (SHA-256).
+const credentialSize = 32
+
+// Get the result of the challenge with the id, the affirmed attestation (if
+// any), and the secret of its last credential.
+func (s *Server) lookupResult(id string) (*server.AttestationResult, *attestpb.Attestation, []byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, err := s.lookupLocked(id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if c.result == nil {
+		return nil, nil, nil, &statusError{http.StatusConflict, fmt.Sprintf("challenge %q has no result yet", id)}
+	}
+	return c.result, c.attestation, c.credential, nil
+}
+
+func (s *Server) getCredential(body []byte) (proto.Message, error) {
+	var req pb.GetCredentialRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		return nil, &statusError{http.StatusBadRequest, "invalid request"}
+	}
+	result, attestation, _, err := s.lookupResult(req.GetChallengeId())
+	if err != nil {
+		return nil, err
+	}
+	ekPub, err := s.verifiedEK(result, attestation)
+	if err != nil {
+		s.logf("challenge %s: not issuing credential: %v", req.GetChallengeId(), err)
+		return nil, &statusError{http.StatusForbidden, fmt.Sprintf("credential cannot be issued: %v", err)}
+	}
+	// The AK public area was verified by the appraisal.
+	akPub, err := tpm2.DecodePublic(attestation.GetAkPub())
+	if err != nil {
+		return nil, err
+	}
+	akName, err := akPub.Name()
+	if err != nil {
+		return nil, err
+	}
+	secret := make([]byte, credentialSize)
+	if _, err = rand.Read(secret); err != nil {
+		return nil, err
+	}
+	credBlob, encSecret, err := server.MakeCredential(ekPub, akName, secret)
+	if err != nil {
+		return nil, fmt.Errorf("making credential: %w", err)
+	}
+
+	s.mu.Lock()
+	c, err := s.lookupLocked(req.GetChallengeId())
+	if err == nil {
+		c.credential = secret
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetCredentialResponse{CredBlob: credBlob, EncSecret: encSecret}, nil
+}
+
+func (s *Server) releaseSecret(body []byte) (proto.Message, error) {
+	var req pb.ReleaseSecretRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		return nil, &statusError{http.StatusBadRequest, "invalid request"}
+	}
+	secret, ok := s.config.Secrets[req.GetSecretId()]
+	if !ok {
+		return nil, &statusError{http.StatusNotFound, fmt.Sprintf("unknown secret %q", req.GetSecretId())}
+	}
+	result, attestation, credential, err := s.lookupResult(req.GetChallengeId())
+	if err != nil {
+		return nil, err
+	}
+
+	ekPub, err := s.checkRelease(result, attestation, credential, req.GetCredential(), secret)
+	if err != nil {
+		s.logf("challenge %s: not releasing secret %q: %v", req.GetChallengeId(), req.GetSecretId(), err)
+		return nil, &statusError{http.StatusForbidden, fmt.Sprintf("secret %q cannot be released: %v", req.GetSecretId(), err)}
+	}
+	blob, err := server.CreateImportBlob(ekPub, secret.Value, nil)
+	if err != nil {
+		return nil, fmt.Errorf("wrapping secret %q: %w", req.GetSecretId(), err)
+	}
+	s.logf("challenge %s: released secret %q", req.GetChallengeId(), req.GetSecretId())
+	return &pb.ReleaseSecretResponse{Blob: blob}, nil
+}
+
+// Check that the secret can be released to the attester of the result, which
+// activated its credential, returning the public key of its verified EK.
+func (s *Server) checkRelease(result *server.AttestationResult, attestation *attestpb.Attestation, credential, activated []byte, secret Secret) (crypto.PublicKey, error) {
+	ekPub, err := s.verifiedEK(result, attestation)
+	if err != nil {
+		return nil, err
+	}
+	if secret.Policy != nil {
+		if err := server.EvaluatePolicy(result.MachineState, secret.Policy); err != nil {
+			return nil, err
+		}
+	}
+	if credential == nil {
+		return nil, errors.New("no credential was issued for the AK")
+	}
+	if subtle.ConstantTimeCompare(credential, activated) != 1 {
+		return nil, errors.New("credential was not activated by the AK in the TPM of the EK")
+	}
+	return ekPub, nil
+}
+
+// Check that the result is affirming, returning the public key of the EK
+// certified by the verified EK certificate of the attestation.
+func (s *Server) verifiedEK(result *server.AttestationResult, attestation *attestpb.Attestation) (crypto.PublicKey, error) {
+	if result.Status != server.TierAffirming || attestation == nil {
+		return nil, fmt.Errorf("appraisal is %v, not affirming", result.Status)
+	}
+	if len(attestation.GetEkCert()) == 0 {
+		return nil, errors.New("attestation has no EK certificate")
+	}
+	ekCert, err := x509.ParseCertificate(attestation.GetEkCert())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse EK certificate: %w", err)
+	}
+	if err = server.VerifyEKCert(ekCert, s.config.EKOpts); err != nil {
+		return nil, err
+	}
+	return ekCert.PublicKey, nil
+}
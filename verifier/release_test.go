@@ -0,0 +1,195 @@
+package verifier_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+	"github.com/ThalesIgnite/go-tpm-tools/server"
+	"github.com/ThalesIgnite/go-tpm-tools/verifier"
+)
+
+// Issue an EK certificate for ekPub from a new test CA, returning the
+// certificate and the CA.
+func issueEKCert(t *testing.T, ekPub crypto.PublicKey) (*x509.Certificate, *x509.Certificate) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test TPM Manufacturer CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	ekTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageKeyEncipherment,
+	}
+	var certs []*x509.Certificate
+	for _, cert := range []struct {
+		template *x509.Certificate
+		pub      crypto.PublicKey
+	}{{caTemplate, caKey.Public()}, {ekTemplate, ekPub}} {
+		der, err := x509.CreateCertificate(rand.Reader, cert.template, caTemplate, cert.pub, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		certs = append(certs, parsed)
+	}
+	return certs[1], certs[0]
+}
+
+func TestVerifierReleaseSecret(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ak, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer ak.Close()
+	ek, err := client.EndorsementKeyRSA(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate EK: %v", err)
+	}
+	defer ek.Close()
+	ekCert, ca := issueEKCert(t, ek.PublicKey())
+	otherCert, _ := issueEKCert(t, ek.PublicKey())
+	// The EK of another TPM, whose certificate is trusted.
+	otherEK, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherEKCert, otherCA := issueEKCert(t, otherEK.Public())
+	resultKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	passphrase := []byte("luks passphrase")
+	c, logs := serveVerifier(t, verifier.Config{
+		Opts:   server.VerifyOpts{TrustedAKs: []crypto.PublicKey{ak.PublicKey()}},
+		Signer: resultKey,
+		Secrets: map[string]verifier.Secret{
+			"disk": {Value: passphrase},
+			"sev-disk": {Value: passphrase, Policy: &attestpb.Policy{Platform: &attestpb.PlatformPolicy{
+				MinimumTechnology: attestpb.GCEConfidentialTechnology_AMD_SEV,
+			}}},
+		},
+		EKOpts: &server.VerifyEKOpts{Roots: []*x509.Certificate{ca, otherCA}},
+	})
+
+	tests := []struct {
+		name     string
+		ekCert   []byte
+		nonce    []byte
+		secretID string
+		status   int
+	}{
+		{"Released", ekCert.Raw, nil, "disk", http.StatusOK},
+		{"UnknownSecret", ekCert.Raw, nil, "unknown", http.StatusNotFound},
+		{"PolicyNotSatisfied", ekCert.Raw, nil, "sev-disk", http.StatusForbidden},
+		{"NoEKCert", nil, nil, "disk", http.StatusForbidden},
+		{"UntrustedEKCert", otherCert.Raw, nil, "disk", http.StatusForbidden},
+		{"EKOfAnotherTPM", otherEKCert.Raw, nil, "disk", http.StatusForbidden},
+		{"NotAffirming", ekCert.Raw, []byte("some other nonce"), "disk", http.StatusForbidden},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			challenge, err := c.Challenge()
+			if err != nil {
+				t.Fatalf("failed to get challenge: %v", err)
+			}
+			if _, err = c.ReleaseSecret(challenge.GetChallengeId(), tc.secretID, nil); err == nil {
+				t.Error("expected releasing a secret for an unanswered challenge to fail")
+			}
+			nonce := tc.nonce
+			if nonce == nil {
+				nonce = challenge.GetNonce()
+			}
+			attestation, err := ak.Attest(nonce, nil)
+			if err != nil {
+				t.Fatalf("failed to attest: %v", err)
+			}
+			attestation.EkCert = tc.ekCert
+			if _, err = c.SubmitAttestation(challenge.GetChallengeId(), attestation); err != nil {
+				t.Fatalf("failed to submit evidence: %v", err)
+			}
+
+			if _, err = c.ReleaseSecret(challenge.GetChallengeId(), tc.secretID, nil); err == nil {
+				t.Error("expected releasing a secret without a credential to fail")
+			}
+			// The credential can only be activated if the AK is in the TPM
+			// of the EK.
+			var activated []byte
+			if credential, err := c.GetCredential(challenge.GetChallengeId()); err == nil {
+				activated, err = ak.ActivateCredential(ek, credential.GetCredBlob(), credential.GetEncSecret())
+				if err != nil && tc.status == http.StatusOK {
+					t.Fatalf("failed to activate credential: %v", err)
+				}
+			}
+
+			blob, err := c.ReleaseSecret(challenge.GetChallengeId(), tc.secretID, activated)
+			if tc.status != http.StatusOK {
+				var httpErr *verifier.HTTPError
+				if !errors.As(err, &httpErr) || httpErr.StatusCode != tc.status {
+					t.Errorf("got error %v, want HTTP status %d", err, tc.status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to release secret: %v", err)
+			}
+			secret, err := ek.Import(blob)
+			if err != nil {
+				t.Fatalf("failed to import secret: %v", err)
+			}
+			if !bytes.Equal(secret, passphrase) {
+				t.Errorf("got secret %q, want %q", secret, passphrase)
+			}
+		})
+	}
+	if !strings.Contains(logs.String(), `released secret "disk"`) || !strings.Contains(logs.String(), `not releasing secret "sev-disk"`) {
+		t.Errorf("log does not contain the releases:\n%s", logs)
+	}
+	if !strings.Contains(logs.String(), "credential was not activated by the AK in the TPM of the EK") {
+		t.Errorf("log does not contain the refusal for the EK of another TPM:\n%s", logs)
+	}
+}
+
+func TestNewServerSecretTooLarge(t *testing.T) {
+	resultKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = verifier.NewServer(verifier.Config{
+		Signer:  resultKey,
+		Secrets: map[string]verifier.Secret{"large": {Value: make([]byte, 129)}},
+	})
+	if err == nil {
+		t.Error("expected NewServer with a too large secret to fail")
+	}
+}
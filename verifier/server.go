@@ -0,0 +1,331 @@
+// Package verifier runs a remote attestation verifier service, so that
+// attesters can be appraised by a central verifier built on the server
+// package, and present its signed attestation results to relying parties.
+//
+// The Server speaks a plain HTTP protocol (not gRPC), with the same conventions
+// as the proxy package: each request message (see proto/verifier.proto) is
+// POSTed to its path, and the reply is the matching response message. An
+// attester first gets a Challenge, containing a fresh nonce. It then answers
+// the challenge with SubmitEvidence, sending an Attestation (from
+// client.Key.Attest) or an Entity Attestation Token (from
+// client.COSESigner.SignEAT) bound to the nonce. Finally, GetResult returns
+// the EAT Attestation Result (EAR) of the appraisal, signed by the verifier as
+// a CWT or a JWT (see server.AttestationResult).
+//
+// The Server can also release secrets (such as disk encryption passphrases)
+// to the attesters it affirms, by GetCredential and ReleaseSecret (see
+// Secret). Client sends requests to a Server.
+package verifier
+
+import (
+	"crypto"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	attestpb "github.com/ThalesIgnite/go-tpm-tools/proto/attest"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/verifier"
+	"github.com/ThalesIgnite/go-tpm-tools/server"
+)
+
+// The HTTP paths of the verifier's requests.
+const (
+	ChallengePath      = "/v1/challenge"
+	SubmitEvidencePath = "/v1/evidence"
+	GetResultPath      = "/v1/result"
+	GetCredentialPath  = "/v1/credential"
+	ReleaseSecretPath  = "/v1/secret"
+)
+
+// The content type of requests and responses.
+const contentType = "application/x-protobuf"
+
+// The maximum size of requests. Attestations contain the event logs, which
+// can be large (in particular IMA logs).
+const maxMessageSize = 1 << 24
+
+// The size of the nonces of challenges, and of their identifiers.
+const (
+	nonceSize       = 32
+	challengeIDSize = 16
+)
+
+// DefaultChallengeTTL is the time for which challenges and their results are
+// kept, if Config.ChallengeTTL is not set.
+const DefaultChallengeTTL = 5 * time.Minute
+
+// Config configures a Server.
+type Config struct {
+	// Opts are the options used to verify the evidence (see
+	// server.AppraiseAttestation). The Nonce is set to the nonce of each
+	// challenge.
+	Opts server.VerifyOpts
+	// PolicyID identifies the policy of Opts.Verifiers in the results, and
+	// can be empty.
+	PolicyID string
+	// Signer signs the attestation results, and must be supported by
+	// server.AttestationResult.SignCWT.
+	Signer crypto.Signer
+	// ResultLifetime is the time for which relying parties can accept an
+	// attestation result (its expiry claim). If zero, results do not expire.
+	ResultLifetime time.Duration
+	// ChallengeTTL is the time for which a challenge can be answered, and for
+	// which its result can then be retrieved. If zero, DefaultChallengeTTL is
+	// used.
+	ChallengeTTL time.Duration
+	// Secrets are the secrets which can be released to attesters, by their
+	// identifier. If empty, ReleaseSecret always fails.
+	Secrets map[string]Secret
+	// EKOpts are the options used to verify the EK certificates of attesters
+	// before releasing secrets to them (see server.VerifyEKCert).
+	EKOpts *server.VerifyEKOpts
+	// Logger logs the appraisals and the released secrets, if non-nil.
+	Logger *log.Logger
+}
+
+type challenge struct {
+	nonce    []byte
+	expires  time.Time
+	answered bool
+	// The result, once the evidence has been appraised.
+	result *server.AttestationResult
+	// The appraised attestation, if it was affirmed and secrets can be
+	// released.
+	attestation *attestpb.Attestation
+	// The secret of the last credential issued for the AK of the attestation.
+	credential []byte
+}
+
+// Server appraises the evidence of attesters. It implements http.Handler,
+// serving the requests at their paths. Challenges are kept in memory,
+// so they are lost when the Server is restarted.
+type Server struct {
+	config     Config
+	mu         sync.Mutex
+	challenges map[string]*challenge
+}
+
+// NewServer returns a Server appraising evidence with the config.
+func NewServer(config Config) (*Server, error) {
+	if config.Signer == nil {
+		return nil, errors.New("verifier has no signer for the attestation results")
+	}
+	// Check the signer now, rather than when the first result is signed.
+	if _, err := (&server.AttestationResult{}).SignCWT(config.Signer); err != nil {
+		return nil, fmt.Errorf("verifier cannot sign attestation results: %w", err)
+	}
+	for id, secret := range config.Secrets {
+		if len(secret.Value) > maxSecretSize {
+			return nil, fmt.Errorf("secret %q has %d bytes, the maximum is %d", id, len(secret.Value), maxSecretSize)
+		}
+	}
+	if config.ChallengeTTL == 0 {
+		config.ChallengeTTL = DefaultChallengeTTL
+	}
+	return &Server{config: config, challenges: map[string]*challenge{}}, nil
+}
+
+func (s *Server) logf(format string, v ...interface{}) {
+	if s.config.Logger != nil {
+		s.config.Logger.Printf(format, v...)
+	}
+}
+
+// An error returned by a method, with its HTTP status.
+type statusError struct {
+	status int
+	msg    string
+}
+
+func (e *statusError) Error() string { return e.msg }
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var call func([]byte) (proto.Message, error)
+	switch r.URL.Path {
+	case ChallengePath:
+		call = s.challenge
+	case SubmitEvidencePath:
+		call = s.submitEvidence
+	case GetResultPath:
+		call = s.getResult
+	case GetCredentialPath:
+		call = s.getCredential
+	case ReleaseSecretPath:
+		call = s.releaseSecret
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageSize))
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := call(body)
+	if err != nil {
+		var statusErr *statusError
+		if errors.As(err, &statusErr) {
+			http.Error(w, statusErr.msg, statusErr.status)
+		} else {
+			s.logf("%s failed: %v", r.URL.Path, err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
+	out, err := proto.Marshal(resp)
+	if err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(out)
+}
+
+func (s *Server) challenge(body []byte) (proto.Message, error) {
+	var req pb.ChallengeRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		return nil, &statusError{http.StatusBadRequest, "invalid request"}
+	}
+	id := make([]byte, challengeIDSize)
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	c := &challenge{nonce: nonce, expires: time.Now().Add(s.config.ChallengeTTL)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	s.challenges[hex.EncodeToString(id)] = c
+	return &pb.ChallengeResponse{ChallengeId: hex.EncodeToString(id), Nonce: nonce, Expires: c.expires.Unix()}, nil
+}
+
+// Remove the expired challenges, with s.mu held.
+func (s *Server) pruneLocked() {
+	now := time.Now()
+	for id, c := range s.challenges {
+		if now.After(c.expires) {
+			delete(s.challenges, id)
+		}
+	}
+}
+
+// Get the challenge with the id, with s.mu held.
+func (s *Server) lookupLocked(id string) (*challenge, error) {
+	c, ok := s.challenges[id]
+	if !ok || time.Now().After(c.expires) {
+		return nil, &statusError{http.StatusNotFound, fmt.Sprintf("unknown or expired challenge %q", id)}
+	}
+	return c, nil
+}
+
+func (s *Server) submitEvidence(body []byte) (proto.Message, error) {
+	var req pb.SubmitEvidenceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		return nil, &statusError{http.StatusBadRequest, "invalid request"}
+	}
+	if req.GetEvidence() == nil {
+		return nil, &statusError{http.StatusBadRequest, "no evidence in request"}
+	}
+	s.mu.Lock()
+	c, err := s.lookupLocked(req.GetChallengeId())
+	if err == nil && c.answered {
+		err = &statusError{http.StatusConflict, fmt.Sprintf("challenge %q was already answered", req.GetChallengeId())}
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	// Mark the challenge as answered before the (slow) appraisal, so that it
+	// cannot be answered concurrently.
+	c.answered = true
+	s.mu.Unlock()
+
+	opts := s.config.Opts
+	opts.Nonce = c.nonce
+	var result *server.AttestationResult
+	attestation := req.GetAttestation()
+	if eat := req.GetEat(); eat != nil {
+		if result, err = server.AppraiseEAT(eat, opts, s.config.PolicyID); err != nil {
+			return nil, &statusError{http.StatusBadRequest, fmt.Sprintf("invalid EAT: %v", err)}
+		}
+		// AppraiseEAT succeeded, so the token can be parsed.
+		if parsed, err := server.ParseEAT(eat); err == nil {
+			attestation = parsed.Attestation
+		}
+	} else {
+		result = server.AppraiseAttestation(attestation, opts, s.config.PolicyID)
+	}
+	if s.config.ResultLifetime != 0 {
+		result.Expiry = result.IssuedAt.Add(s.config.ResultLifetime)
+	}
+
+	s.mu.Lock()
+	c.result = result
+	if result.Status == server.TierAffirming && len(s.config.Secrets) > 0 {
+		c.attestation = attestation
+	}
+	c.expires = time.Now().Add(s.config.ChallengeTTL)
+	s.mu.Unlock()
+
+	resp := &pb.SubmitEvidenceResponse{Status: result.Status.String()}
+	if result.Err != nil {
+		resp.Error = result.Err.Error()
+		s.logf("challenge %s: %v: %v", req.GetChallengeId(), result.Status, result.Err)
+	} else {
+		s.logf("challenge %s: %v", req.GetChallengeId(), result.Status)
+	}
+	return resp, nil
+}
+
+func (s *Server) getResult(body []byte) (proto.Message, error) {
+	var req pb.GetResultRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		return nil, &statusError{http.StatusBadRequest, "invalid request"}
+	}
+	s.mu.Lock()
+	c, err := s.lookupLocked(req.GetChallengeId())
+	var result *server.AttestationResult
+	if err == nil {
+		if result = c.result; result == nil {
+			err = &statusError{http.StatusConflict, fmt.Sprintf("challenge %q has no result yet", req.GetChallengeId())}
+		}
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var token []byte
+	switch req.GetFormat() {
+	case pb.ResultFormat_CWT:
+		token, err = result.SignCWT(s.config.Signer)
+	case pb.ResultFormat_JWT:
+		var jwt string
+		jwt, err = result.SignJWT(s.config.Signer)
+		token = []byte(jwt)
+	default:
+		return nil, &statusError{http.StatusBadRequest, fmt.Sprintf("unsupported result format %v", req.GetFormat())}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetResultResponse{Token: token}, nil
+}
@@ -0,0 +1,235 @@
+package verifier_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ThalesIgnite/go-tpm-tools/client"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal"
+	"github.com/ThalesIgnite/go-tpm-tools/notinternal/test"
+	pb "github.com/ThalesIgnite/go-tpm-tools/proto/verifier"
+	"github.com/ThalesIgnite/go-tpm-tools/server"
+	"github.com/ThalesIgnite/go-tpm-tools/verifier"
+)
+
+// Serve a verifier with the config, returning a Client of it and the log.
+func serveVerifier(t *testing.T, config verifier.Config) (*verifier.Client, *bytes.Buffer) {
+	t.Helper()
+	logs := &bytes.Buffer{}
+	config.Logger = log.New(logs, "", 0)
+	s, err := verifier.NewServer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(s)
+	t.Cleanup(httpServer.Close)
+	c, err := verifier.NewClient(httpServer.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, logs
+}
+
+func TestVerifier(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ak, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer ak.Close()
+	signer, err := ak.COSESigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resultKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, logs := serveVerifier(t, verifier.Config{
+		Opts:           server.VerifyOpts{TrustedAKs: []crypto.PublicKey{ak.PublicKey()}},
+		PolicyID:       "policy",
+		Signer:         resultKey,
+		ResultLifetime: time.Hour,
+	})
+
+	for _, useEAT := range []bool{false, true} {
+		challenge, err := c.Challenge()
+		if err != nil {
+			t.Fatalf("failed to get challenge: %v", err)
+		}
+		if _, err = c.GetResult(challenge.GetChallengeId(), pb.ResultFormat_CWT); err == nil {
+			t.Error("expected getting the result of an unanswered challenge to fail")
+		}
+		attestation, err := ak.Attest(challenge.GetNonce(), nil)
+		if err != nil {
+			t.Fatalf("failed to attest: %v", err)
+		}
+		submit := func() error {
+			_, err := c.SubmitAttestation(challenge.GetChallengeId(), attestation)
+			return err
+		}
+		if useEAT {
+			token, err := signer.SignEAT(attestation, challenge.GetNonce())
+			if err != nil {
+				t.Fatalf("failed to sign EAT: %v", err)
+			}
+			submit = func() error {
+				resp, err := c.SubmitEAT(challenge.GetChallengeId(), token)
+				if err == nil && resp.GetStatus() != "affirming" {
+					t.Errorf("got status %q (error: %s), want affirming", resp.GetStatus(), resp.GetError())
+				}
+				return err
+			}
+		}
+		if err = submit(); err != nil {
+			t.Fatalf("failed to submit evidence: %v", err)
+		}
+		if err = submit(); err == nil {
+			t.Error("expected answering a challenge twice to fail")
+		}
+
+		token, err := c.GetResult(challenge.GetChallengeId(), pb.ResultFormat_CWT)
+		if err != nil {
+			t.Fatalf("failed to get result: %v", err)
+		}
+		msg, err := notinternal.DecodeCOSESign1(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		claims, err := notinternal.UnmarshalCBOR(msg.Payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		submod := claims.(map[int64]interface{})[266].(map[string]interface{})[server.EARSubmodule].(map[int64]interface{})
+		if submod[1000] != int64(server.TierAffirming) || submod[1003] != "policy" {
+			t.Errorf("unexpected result claims: %v", claims)
+		}
+
+		jwt, err := c.GetResult(challenge.GetChallengeId(), pb.ResultFormat_JWT)
+		if err != nil {
+			t.Fatalf("failed to get JWT result: %v", err)
+		}
+		var jwtClaims struct {
+			Expiry  int64 `json:"exp"`
+			Submods map[string]struct {
+				Status  string `json:"ear.status"`
+				Machine *struct {
+					KernelDigest string `json:"kernel-digest"`
+				} `json:"machine"`
+			} `json:"submods"`
+		}
+		verifyJWT(t, string(jwt), &resultKey.PublicKey, &jwtClaims)
+		if jwtSubmod := jwtClaims.Submods[server.EARSubmodule]; jwtSubmod.Status != "affirming" || jwtSubmod.Machine == nil ||
+			time.Until(time.Unix(jwtClaims.Expiry, 0)) > time.Hour || time.Until(time.Unix(jwtClaims.Expiry, 0)) < 59*time.Minute {
+			t.Errorf("unexpected JWT result claims: %+v", jwtClaims)
+		}
+	}
+	if !strings.Contains(logs.String(), ": affirming") {
+		t.Errorf("log does not contain the appraisals:\n%s", logs)
+	}
+}
+
+// verifyJWT verifies the ES256 signature of a JWT, and decodes its claims.
+func verifyJWT(t *testing.T, jwt string, pub *ecdsa.PublicKey, claims interface{}) {
+	t.Helper()
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("JWT has %d parts", len(parts))
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(sig) != 64 {
+		t.Fatalf("invalid JWT signature: %v", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if !ecdsa.Verify(pub, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+		t.Fatal("JWT signature verification failed")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal(payload, claims); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifierWrongNonce(t *testing.T) {
+	rwc := test.GetTPM(t)
+	defer client.CheckedClose(t, rwc)
+	ak, err := client.AttestationKeyECC(rwc)
+	if err != nil {
+		t.Fatalf("failed to generate AK: %v", err)
+	}
+	defer ak.Close()
+	resultKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := serveVerifier(t, verifier.Config{
+		Opts:   server.VerifyOpts{TrustedAKs: []crypto.PublicKey{ak.PublicKey()}},
+		Signer: resultKey,
+	})
+
+	challenge, err := c.Challenge()
+	if err != nil {
+		t.Fatalf("failed to get challenge: %v", err)
+	}
+	attestation, err := ak.Attest([]byte("some other nonce"), nil)
+	if err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+	resp, err := c.SubmitAttestation(challenge.GetChallengeId(), attestation)
+	if err != nil {
+		t.Fatalf("failed to submit evidence: %v", err)
+	}
+	if resp.GetStatus() != "contraindicated" || resp.GetError() == "" {
+		t.Errorf("got status %q (error: %q), want contraindicated with an error", resp.GetStatus(), resp.GetError())
+	}
+	if _, err = c.GetResult(challenge.GetChallengeId(), pb.ResultFormat_CWT); err != nil {
+		t.Errorf("failed to get result: %v", err)
+	}
+	if _, err = c.SubmitAttestation("unknown", attestation); err == nil {
+		t.Error("expected answering an unknown challenge to fail")
+	}
+}
+
+func TestVerifierExpiredChallenge(t *testing.T) {
+	resultKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := serveVerifier(t, verifier.Config{Signer: resultKey, ChallengeTTL: time.Nanosecond})
+	challenge, err := c.Challenge()
+	if err != nil {
+		t.Fatalf("failed to get challenge: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, err = c.SubmitEAT(challenge.GetChallengeId(), []byte("token")); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("expected answering an expired challenge to fail, got %v", err)
+	}
+}
+
+func TestNewServerInvalidSigner(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, signer := range []crypto.Signer{nil, key} {
+		if _, err := verifier.NewServer(verifier.Config{Signer: signer}); err == nil {
+			t.Errorf("NewServer with signer %T should fail", signer)
+		}
+	}
+}